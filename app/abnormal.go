@@ -0,0 +1,109 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	neturl "net/url"
+	"sort"
+	"time"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// abnormalWindow is how far back the abnormal results report looks.
+const abnormalWindow = 30 * 24 * time.Hour
+
+// abnormalResults finds the observations in the last abnormalWindow that are
+// outside their reference range, for the patients in scope. They are sorted
+// by patient name, newest first for each patient, and names maps patient IDs
+// to names.
+func (a *App) abnormalResults(ctx context.Context, now time.Time, scope cohortScope) (results []fhir.AbnormalResult, names map[string]string, err error) {
+	observations, err := a.searchAllPages(ctx, "Observation", 100, neturl.Values{
+		fhir.SearchObservationDate: {"ge" + now.Add(-abnormalWindow).UTC().Format(time.RFC3339)},
+	}, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	results = fhir.AbnormalResults(scope.filter(observations))
+	names = make(map[string]string)
+	for _, r := range results {
+		if _, ok := names[r.PatientID]; !ok {
+			names[r.PatientID] = a.resolvePatientName(ctx, r.PatientID)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return names[results[i].PatientID] < names[results[j].PatientID]
+	})
+	return results, names, nil
+}
+
+var abnormalHeaders = []string{"Date", "Result", "Value", "Flag", "Reference range"}
+
+func abnormalRow(r fhir.AbnormalResult) []string {
+	date := ""
+	if !r.Time.IsZero() {
+		date = r.Time.Local().Format("2006-01-02")
+	}
+	return []string{date, r.Name, r.Value, r.Flag, r.Range}
+}
+
+// abnormalTables lists the abnormal results of the last 30 days, one row
+// per result, by patient.
+func (a *App) abnormalTables(ctx context.Context, now time.Time, scope cohortScope) ([]reportTable, error) {
+	results, names, err := a.abnormalResults(ctx, now, scope)
+	if err != nil {
+		return nil, err
+	}
+	t := reportTable{Title: "Abnormal results", Headers: append([]string{"Patient", "Patient ID"}, abnormalHeaders...)}
+	for _, r := range results {
+		t.Rows = append(t.Rows, append([]string{names[r.PatientID], r.PatientID}, abnormalRow(r)...))
+	}
+	return []reportTable{t}, nil
+}
+
+// AbnormalResultsReport lists every result of the last 30 days outside its
+// reference range, grouped by patient, for triaging follow-ups.
+func (a *App) AbnormalResultsReport() {
+	var results []fhir.AbnormalResult
+	var names map[string]string
+	var fetchErr error
+	var elapsed time.Duration
+
+	err := runSpinner("Checking recent results...", func(ctx context.Context) {
+		start := time.Now()
+		results, names, fetchErr = a.abnormalResults(ctx, time.Now(), nil)
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	if len(results) == 0 {
+		fmt.Println("  No abnormal results in the last 30 days.")
+	}
+	var t *reportTable
+	for i, r := range results {
+		if i == 0 || r.PatientID != results[i-1].PatientID {
+			if t != nil {
+				printReportTable(*t)
+				fmt.Println()
+			}
+			t = &reportTable{Title: names[r.PatientID], Headers: abnormalHeaders}
+		}
+		t.Rows = append(t.Rows, abnormalRow(r))
+	}
+	if t != nil {
+		printReportTable(*t)
+	}
+	showTiming(fmt.Sprintf("Found %d abnormal results", len(results)), elapsed)
+	PressEnter()
+}
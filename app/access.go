@@ -0,0 +1,148 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// accessLogLimit caps the entries kept, so API and daemon mode do not grow
+// the log without bound. The oldest entries are dropped first.
+const accessLogLimit = 10000
+
+// accessEntry is one access to a patient's data during this session.
+type accessEntry struct {
+	Time        time.Time
+	Interaction string // FHIR restful interaction, e.g. "read" or "search-type"
+	Entity      string // reference or resource type
+	PatientID   string
+	Success     bool
+	Reason      string // break-the-glass reason
+}
+
+// accessLog is the session's local record of which patients' data was
+// accessed. It is kept whether or not PHENOSTORE_AUDIT is set, and only in
+// memory.
+type accessLog struct {
+	mu      sync.Mutex
+	started time.Time
+	entries []accessEntry
+}
+
+func (l *accessLog) record(e accessEntry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.started.IsZero() {
+		l.started = e.Time
+	}
+	l.entries = append(l.entries, e)
+	if len(l.entries) > accessLogLimit {
+		l.entries = l.entries[len(l.entries)-accessLogLimit:]
+	}
+}
+
+// forPatient returns the session's accesses to a patient, oldest first, and
+// when the session started.
+func (l *accessLog) forPatient(patientID string) ([]accessEntry, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var out []accessEntry
+	for _, e := range l.entries {
+		if e.PatientID == patientID {
+			out = append(out, e)
+		}
+	}
+	return out, l.started
+}
+
+// logResultAccess records an access for each patient whose data came back
+// from a search that was not limited to one patient, such as a clinic-wide
+// CarePlan search or the patient list.
+func (a *App) logResultAccess(resourceType string, resources []json.RawMessage, opErr error) {
+	seen := make(map[string]bool)
+	for _, raw := range resources {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		patientID := resourcePatient(m)
+		if resourceType == "Patient" {
+			patientID = mapStr(m, "id")
+		}
+		if patientID == "" || seen[patientID] {
+			continue
+		}
+		seen[patientID] = true
+		a.session.access.record(accessEntry{Time: time.Now(), Interaction: "search-type", Entity: resourceType, PatientID: patientID, Success: opErr == nil})
+	}
+}
+
+// PatientAccessLog shows every time this session accessed a patient's data,
+// from the local access log, followed by the AuditEvents recorded for the
+// patient since the session started when PHENOSTORE_AUDIT is set.
+func (a *App) PatientAccessLog() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	entries, started := a.session.access.forPatient(patientID)
+	var events []json.RawMessage
+	var fetchErr error
+	var elapsed time.Duration
+	if a.Audit {
+		err = runSpinner("Loading audit events...", func(ctx context.Context) {
+			start := time.Now()
+			events, fetchErr = a.searchResources(ctx, "AuditEvent", 100, map[string]string{
+				fhir.SearchAuditEventPatient: patientID,
+				fhir.SearchAuditEventDate:    "ge" + started.UTC().Format(time.RFC3339),
+				fhir.SearchSort:              "-date",
+			})
+			elapsed = time.Since(start)
+		})
+		if err != nil {
+			ShowError(err)
+			PressEnter()
+			return
+		}
+	}
+
+	name := a.resolvePatientName(context.Background(), patientID)
+	fmt.Println()
+	fmt.Println(barStyle.Bold(true).Render(fmt.Sprintf("Access to %s this session (%d)", name, len(entries))))
+	for _, e := range entries {
+		outcome := ""
+		if !e.Success {
+			outcome = errorStyle.Render(" (failed)")
+		}
+		if e.Reason != "" {
+			outcome += fmt.Sprintf(" %q", e.Reason)
+		}
+		fmt.Printf("  %s  %-12s %s%s\n", e.Time.Format("15:04:05"), e.Interaction, e.Entity, outcome)
+	}
+	if len(entries) == 0 {
+		fmt.Println("  None.")
+	}
+
+	fmt.Println()
+	switch {
+	case !a.Audit:
+		fmt.Println(timingStyle.Render("  Set PHENOSTORE_AUDIT=true to also record AuditEvents on the server."))
+	case fetchErr != nil:
+		ShowError(fetchErr)
+	case len(events) == 0:
+		fmt.Println("  No audit events recorded for this patient since the session started.")
+	default:
+		fhir.PrintAuditEventList(events)
+		showTiming(fmt.Sprintf("Fetched %d audit events since %s", len(events), started.Format("15:04:05")), elapsed)
+	}
+	PressEnter()
+}
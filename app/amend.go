@@ -0,0 +1,289 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// observationOptions loads a patient's observations and returns an option
+// for each, labelled with its value and date, and the observations by ID.
+func (a *App) observationOptions(ctx context.Context, patientID string) ([]huh.Option[string], map[string]json.RawMessage, error) {
+	var observations []json.RawMessage
+	var fetchErr error
+
+	err := runSpinner("Loading observations...", func(ctx context.Context) {
+		observations, fetchErr = a.searchByPatient(ctx, "Observation", patientID, sortNewest)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if fetchErr != nil {
+		return nil, nil, fetchErr
+	}
+
+	byID := make(map[string]json.RawMessage)
+	var options []huh.Option[string]
+	for _, raw := range observations {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		id := mapStr(m, "id")
+		byID[id] = raw
+		options = append(options, huh.NewOption(fhir.ObservationLabel(m), id))
+	}
+	return options, byID, nil
+}
+
+// EditObservation lets the user pick one of a patient's observations and
+// change its value or status, e.g. to amend a mistyped weight or mark a
+// reading entered-in-error. The changed fields are shown for confirmation
+// before the observation is updated.
+func (a *App) EditObservation() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	ctx := context.Background()
+	options, byID, err := a.observationOptions(ctx, patientID)
+	if err != nil || len(options) == 0 {
+		if err != nil {
+			ShowError(err)
+		} else {
+			fmt.Println("\n  No observations found.")
+		}
+		PressEnter()
+		return
+	}
+
+	var obsID string
+	err = huh.NewSelect[string]().
+		Title("Select an observation").
+		Options(options...).
+		Value(&obsID).
+		Run()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	before, _ := fhir.Parse(byID[obsID])
+	var after map[string]any
+	value := fhir.ObservationValueInput(before)
+	status := mapStr(before, "status")
+	if status == "" || status == "preliminary" || status == "registered" {
+		status = "final"
+	}
+
+	for {
+		var fields []huh.Field
+		if value != "" {
+			fields = append(fields, huh.NewInput().
+				Title("Value").
+				Description("Leave unchanged to change only the status.").
+				Value(&value).
+				Validate(func(s string) error {
+					probe, _ := fhir.Parse(byID[obsID])
+					return fhir.SetObservationValue(probe, s)
+				}))
+		}
+		fields = append(fields, huh.NewSelect[string]().
+			Title("Status").
+			Description("Use amended or corrected when changing a value, entered-in-error if it should not have been recorded.").
+			Options(huh.NewOptions(fhir.ObservationStatuses...)...).
+			Value(&status))
+
+		if err := huh.NewForm(huh.NewGroup(fields...)).Run(); err != nil {
+			if !isAbort(err) {
+				ShowError(err)
+				PressEnter()
+			}
+			return
+		}
+
+		after, _ = fhir.Parse(byID[obsID])
+		if value == fhir.ObservationValueInput(before) {
+			break
+		}
+		_ = fhir.SetObservationValue(after, value)
+		ok, err := confirmPlausible(a.implausibleObservation(after)...)
+		if err != nil {
+			if !isAbort(err) {
+				ShowError(err)
+				PressEnter()
+			}
+			return
+		}
+		if ok {
+			break
+		}
+	}
+
+	after["status"] = status
+	changes := fhir.DiffResources(before, after)
+	// A new value left as final is recorded as amended, so readers can tell
+	// the result changed after it was reported.
+	if len(changes) > 0 && status == "final" && fhir.ObservationValue(before) != fhir.ObservationValue(after) {
+		after["status"] = "amended"
+		changes = fhir.DiffResources(before, after)
+	}
+	if len(changes) == 0 {
+		fmt.Println("\n  No changes.")
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	fmt.Println(barStyle.Bold(true).Render(fhir.ObservationLabel(before)))
+	fhir.PrintDiff(changes)
+	fmt.Println()
+
+	var confirm bool
+	err = huh.NewConfirm().
+		Title("Save these changes?").
+		Value(&confirm).
+		Run()
+	if err != nil || !confirm {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	body, _ := json.Marshal(after)
+	var apiErr error
+	var elapsed time.Duration
+
+	err = runSpinner("Updating observation...", func(ctx context.Context) {
+		start := time.Now()
+		_, apiErr = a.updateResource(ctx, "Observation", obsID, body)
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(fmt.Errorf("updating observation: %w", apiErr))
+		PressEnter()
+		return
+	}
+
+	a.emit(ctx, EventObservationUpdated, "Observation", obsID, patientID)
+	fmt.Printf("\n  Updated observation %s (%s)\n", obsID, mapStr(after, "status"))
+	showTiming("Updated via UpdateResource", elapsed)
+	PressEnter()
+}
+
+// DeleteObservations lets the user pick any of a patient's observations,
+// e.g. vitals recorded against the wrong patient, and deletes each one with
+// DeleteResource. Deleting a panel leaves its results in place.
+func (a *App) DeleteObservations() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	ctx := context.Background()
+	options, byID, err := a.observationOptions(ctx, patientID)
+	if err != nil || len(options) == 0 {
+		if err != nil {
+			ShowError(err)
+		} else {
+			fmt.Println("\n  No observations found.")
+		}
+		PressEnter()
+		return
+	}
+
+	var chosen []string
+	err = huh.NewMultiSelect[string]().
+		Title("Delete Observations").
+		Description("Space toggles an observation, enter continues.").
+		Options(options...).
+		Value(&chosen).
+		Filterable(true).
+		Run()
+	if err != nil || len(chosen) == 0 {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	fmt.Println()
+	for _, id := range chosen {
+		if m, err := fhir.Parse(byID[id]); err == nil {
+			fmt.Printf("  %s\n", fhir.ObservationLabel(m))
+		}
+	}
+	fmt.Println()
+
+	var confirm bool
+	err = huh.NewConfirm().
+		Title(fmt.Sprintf("Delete %d observations?", len(chosen))).
+		Description("This action cannot be undone. Use Edit Observation to mark a result entered-in-error instead.").
+		Value(&confirm).
+		Run()
+	if err != nil || !confirm {
+		return
+	}
+
+	var deleted []string
+	var apiErr error
+	var elapsed time.Duration
+
+	err = runSpinner("Deleting observations...", func(ctx context.Context) {
+		start := time.Now()
+		for _, id := range chosen {
+			if err := a.deleteResource(ctx, "Observation", id); err != nil {
+				apiErr = fmt.Errorf("deleting Observation/%s: %w", id, err)
+				return
+			}
+			deleted = append(deleted, id)
+		}
+		elapsed = time.Since(start)
+	})
+
+	for _, id := range deleted {
+		a.emit(ctx, EventObservationDeleted, "Observation", id, patientID)
+	}
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+		if len(deleted) > 0 {
+			fmt.Printf("  Deleted %d of %d observations before the error.\n", len(deleted), len(chosen))
+		}
+		PressEnter()
+		return
+	}
+
+	fmt.Printf("\n  Deleted %d observations\n", len(deleted))
+	showTiming(fmt.Sprintf("Deleted %d resources via DeleteResource", len(deleted)), elapsed)
+	PressEnter()
+}
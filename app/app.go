@@ -7,7 +7,9 @@ import (
 	"net/http"
 	neturl "net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/phenoml/phenostore-example-go/fhir"
@@ -16,8 +18,70 @@ import (
 )
 
 // App holds the shared client and configuration.
+//
+// One App may be used from several goroutines: API mode handles each request
+// on its own goroutine, and LoadSummary runs its searches in parallel. The
+// exported fields are configuration; set them before the App is first used
+// and only read them afterwards. What changes while the app runs, such as
+// the access log and break-the-glass reasons, is in session, which locks it.
+// The offline queue file is shared by the whole process and guarded by
+// queueMu. The interactive menus run on a single goroutine.
 type App struct {
 	Client *phenostore.Client
+	// Hooks are notified after each successful create, update, or delete.
+	Hooks []Hook
+	// Actions are custom entries shown in the Plugins menu.
+	Actions []MenuAction
+	// ProvenanceAgent, when set, is recorded as the agent of a Provenance
+	// resource written in the same transaction as every mutation.
+	ProvenanceAgent string
+	// Audit, when true, records an AuditEvent for every read, search, and
+	// write the app performs.
+	Audit bool
+	// DashboardNarrative, when true, shows a prose summary above the clinic
+	// dashboard.
+	DashboardNarrative bool
+	// Role, when set, limits the menus to the entries that role uses:
+	// front-desk, nurse, provider, or admin.
+	Role string
+	// UCUM is how unit codes are checked before a change is sent: UCUMWarn
+	// (the default when empty), UCUMStrict, or UCUMOff.
+	UCUM string
+	// VitalRanges overrides the plausible ranges of vital signs, by key
+	// ("systolic", "temperature", ...). Recording a reading outside its
+	// range takes an explicit override.
+	VitalRanges map[string]VitalRange
+	// GoalTargets are the default goal targets, by measure key ("hba1c",
+	// "bp"), for patients with no Goal of their own for that measure.
+	GoalTargets map[string]fhir.GoalTarget
+	// EscalationDays is how many days past due a care plan activity may be
+	// before it is escalated with a Task. 0 turns escalation off.
+	EscalationDays int
+	// DeleteConcurrency is how many resources Delete Seed Data deletes at
+	// once. 0 means defaultDeleteConcurrency.
+	DeleteConcurrency int
+	// ChunkSize is how many entries each transaction of a seed or snapshot
+	// import holds, and ChunkConcurrency how many of those transactions are
+	// sent at once. 0 means transactionChunkSize and defaultChunkConcurrency.
+	ChunkSize        int
+	ChunkConcurrency int
+	// Retries is how many times a request that hit rate limiting or a
+	// transient server or network error is retried (PHENOSTORE_RETRIES).
+	Retries int
+	// Gzip asks for gzip-compressed responses. Initialize turns it on unless
+	// PHENOSTORE_GZIP is false.
+	Gzip bool
+	// Transport holds the HTTP timeouts and connection settings.
+	Transport TransportSettings
+	// PatientPicker is how PickPatient finds a patient: PatientPickerList,
+	// PatientPickerSearch, or PatientPickerAuto (the default when empty).
+	PatientPicker string
+	// SummarySource is how PatientSummary loads a patient's resources:
+	// SummarySearches (the default when empty), SummaryEverything,
+	// SummaryRevinclude, or SummaryCompare.
+	SummarySource string
+
+	session session
 }
 
 // Initialize loads environment variables and creates the PhenoStore client.
@@ -37,12 +101,85 @@ func (a *App) Initialize() error {
 		return err
 	}
 
-	client, err := phenostore.NewClient(url, clientID, clientSecret, tenant, store)
+	retries, err := parseRetries(os.Getenv("PHENOSTORE_RETRIES"))
+	if err != nil {
+		return err
+	}
+	a.Retries = retries
+	a.Gzip = true
+	if v, err := strconv.ParseBool(os.Getenv("PHENOSTORE_GZIP")); err == nil {
+		a.Gzip = v
+	}
+	if a.Transport, err = loadTransportSettings(); err != nil {
+		return err
+	}
+	client, err := phenostore.NewClient(url, clientID, clientSecret, tenant, store,
+		phenostore.WithHTTPClient(newHTTPClient(a.Retries, a.Gzip, a.Transport)))
 	if err != nil {
 		return fmt.Errorf("creating client: %w", err)
 	}
 
 	a.Client = client
+	a.session.started = time.Now()
+	a.ProvenanceAgent = os.Getenv("PHENOSTORE_PROVENANCE_AGENT")
+	a.Audit, _ = strconv.ParseBool(os.Getenv("PHENOSTORE_AUDIT"))
+	a.DashboardNarrative, _ = strconv.ParseBool(os.Getenv("PHENOSTORE_DASHBOARD_NARRATIVE"))
+	a.Role = os.Getenv("PHENOSTORE_ROLE")
+	if err := validateRole(a.Role); err != nil {
+		return err
+	}
+	switch units := fhir.Units(os.Getenv("PHENOSTORE_UNITS")); units {
+	case "":
+	case fhir.MetricUnits, fhir.USUnits:
+		fhir.DisplayUnits = units
+	default:
+		return fmt.Errorf("unknown PHENOSTORE_UNITS %q (use one of: %s, %s)", units, fhir.MetricUnits, fhir.USUnits)
+	}
+	switch a.UCUM = os.Getenv("PHENOSTORE_UCUM"); a.UCUM {
+	case "", UCUMWarn, UCUMStrict, UCUMOff:
+	default:
+		return fmt.Errorf("unknown PHENOSTORE_UCUM %q (use one of: %s, %s, %s)", a.UCUM, UCUMWarn, UCUMStrict, UCUMOff)
+	}
+	switch a.PatientPicker = os.Getenv("PHENOSTORE_PATIENT_PICKER"); a.PatientPicker {
+	case "", PatientPickerAuto, PatientPickerList, PatientPickerSearch:
+	default:
+		return fmt.Errorf("unknown PHENOSTORE_PATIENT_PICKER %q (use one of: %s, %s, %s)", a.PatientPicker, PatientPickerAuto, PatientPickerList, PatientPickerSearch)
+	}
+	switch a.SummarySource = os.Getenv("PHENOSTORE_SUMMARY"); a.SummarySource {
+	case "", SummarySearches, SummaryEverything, SummaryRevinclude, SummaryCompare:
+	default:
+		return fmt.Errorf("unknown PHENOSTORE_SUMMARY %q (use one of: %s, %s, %s, %s)", a.SummarySource, SummarySearches, SummaryEverything, SummaryRevinclude, SummaryCompare)
+	}
+	ranges, err := parseVitalRanges(os.Getenv("PHENOSTORE_VITAL_RANGES"))
+	if err != nil {
+		return err
+	}
+	a.VitalRanges = ranges
+	if a.GoalTargets, err = parseGoalTargets(os.Getenv("PHENOSTORE_GOAL_TARGETS")); err != nil {
+		return err
+	}
+	if a.EscalationDays, err = parseEscalationDays(os.Getenv("PHENOSTORE_ESCALATION_DAYS")); err != nil {
+		return err
+	}
+	if a.DeleteConcurrency, err = parseDeleteConcurrency(os.Getenv("PHENOSTORE_DELETE_CONCURRENCY")); err != nil {
+		return err
+	}
+	if a.ChunkSize, err = parseChunkSize(os.Getenv("PHENOSTORE_CHUNK_SIZE")); err != nil {
+		return err
+	}
+	if a.ChunkConcurrency, err = parseChunkConcurrency(os.Getenv("PHENOSTORE_CHUNK_CONCURRENCY")); err != nil {
+		return err
+	}
+	if err := configureLock(clientSecret); err != nil {
+		return err
+	}
+	configureDetail(url, tenant, store)
+	if cmd := os.Getenv("PHENOSTORE_HOOK_COMMAND"); cmd != "" {
+		a.Hooks = append(a.Hooks, CommandHook{Command: cmd})
+	}
+	if dir := os.Getenv("PHENOSTORE_PLUGIN_DIR"); dir != "" {
+		a.loadPlugins(dir)
+	}
 	return nil
 }
 
@@ -59,16 +196,30 @@ func extractResources(bundle gen.Bundle) []json.RawMessage {
 	return resources
 }
 
-func (a *App) fetchAllPatients(ctx context.Context) ([]json.RawMessage, error) {
-	count := gen.SearchCount(100)
-	params := &gen.SearchResourcesParams{
-		UnderscoreCount: &count,
-	}
-	bundle, err := a.Client.SearchResources(ctx, "Patient", params)
-	if err != nil {
-		return nil, fmt.Errorf("searching patients: %w", err)
+// includedPatients separates the Patient resources a search returned for
+// _include from the resources it matched, and returns the patients' names
+// by ID.
+func includedPatients(resources []json.RawMessage) (matches []json.RawMessage, names map[string]string) {
+	names = make(map[string]string)
+	for _, raw := range resources {
+		m, err := fhir.Parse(raw)
+		if err == nil && mapStr(m, "resourceType") == "Patient" {
+			names[mapStr(m, "id")] = fhir.PatientName(m)
+			continue
+		}
+		matches = append(matches, raw)
 	}
-	return extractResources(*bundle), nil
+	return matches, names
+}
+
+// fetchAllPatients returns every patient in the store, following the
+// search's next links to the last page.
+func (a *App) fetchAllPatients(ctx context.Context) (patients []json.RawMessage, err error) {
+	defer func() {
+		a.audit(ctx, fhir.AuditRead, "search-type", "Patient", "Patient", "", err)
+		a.logResultAccess("Patient", patients, err)
+	}()
+	return a.searchPages(ctx, "Patient", 100, 0, nil, nil)
 }
 
 func validatePhenoStoreURL(rawURL string) error {
@@ -90,106 +241,225 @@ func validatePhenoStoreURL(rawURL string) error {
 	return fmt.Errorf("invalid PHENOSTORE_URL: must use https (http is only allowed for localhost)")
 }
 
-func (a *App) searchByPatient(ctx context.Context, resourceType, patientID string) ([]json.RawMessage, error) {
-	count := gen.SearchCount(50)
-	params := &gen.SearchResourcesParams{
-		UnderscoreCount: &count,
+// Sort orders for search results, as _sort values.
+const (
+	sortNewest        = "-date"         // newest first by the resource's clinical date
+	sortRecentlySaved = "-_lastUpdated" // most recently created or changed first
+)
+
+// searchByPatient returns all of a patient's resources of one type, in the
+// server's order sort (a _sort value such as sortNewest), or unsorted if
+// sort is "".
+func (a *App) searchByPatient(ctx context.Context, resourceType, patientID, sort string) (resources []json.RawMessage, err error) {
+	defer func() { a.audit(ctx, fhir.AuditRead, "search-type", resourceType, resourceType, patientID, err) }()
+	return a.searchPages(ctx, resourceType, 100, 0, withSort(neturl.Values{"patient": {patientID}}, sort), nil)
+}
+
+// searchCarePlans returns a patient's active care plans, sorted as
+// searchByPatient sorts.
+func (a *App) searchCarePlans(ctx context.Context, patientID, sort string) (plans []json.RawMessage, err error) {
+	defer func() { a.audit(ctx, fhir.AuditRead, "search-type", "CarePlan", "CarePlan", patientID, err) }()
+	return a.searchPages(ctx, "CarePlan", 100, 0, withSort(neturl.Values{
+		fhir.SearchCarePlanPatient: {patientID},
+		fhir.SearchCarePlanStatus:  {"active"},
+	}, sort), nil)
+}
+
+// withSort adds a _sort parameter to query, unless sort is "".
+func withSort(query neturl.Values, sort string) neturl.Values {
+	if sort != "" {
+		query.Set(fhir.SearchSort, sort)
 	}
-	resp, err := a.Client.Inner().SearchResourcesWithResponse(
-		ctx, a.Client.Tenant(), a.Client.Store(),
-		gen.ResourceType(resourceType), params,
-		func(ctx context.Context, req *http.Request) error {
-			q := req.URL.Query()
-			q.Set("patient", patientID)
-			req.URL.RawQuery = q.Encode()
-			return nil
-		},
-	)
+	return query
+}
+
+// patientNameTTL is how long resolvePatientName reuses a name it has read.
+// Patients updated or deleted through the app are forgotten at once; the
+// TTL bounds how stale a name changed by another client can be.
+const patientNameTTL = 5 * time.Minute
+
+// resolvePatientName returns a patient's name, or their ID if they cannot be
+// read. Names are cached for patientNameTTL.
+func (a *App) resolvePatientName(ctx context.Context, patientID string) string {
+	if name, ok := a.session.patientName(patientID, time.Now()); ok {
+		return name
+	}
+	raw, err := a.readResource(ctx, "Patient", patientID)
 	if err != nil {
-		return nil, fmt.Errorf("searching %s: %w", resourceType, err)
+		return patientID
 	}
-	if resp.HTTPResponse.StatusCode >= 400 {
-		return nil, fmt.Errorf("search %s failed: HTTP %d", resourceType, resp.HTTPResponse.StatusCode)
+	m, err := fhir.Parse(raw)
+	if err != nil {
+		return patientID
 	}
-	var bundle gen.Bundle
-	if err := json.Unmarshal(resp.Body, &bundle); err != nil {
-		return nil, fmt.Errorf("parsing %s response: %w", resourceType, err)
+	name := fhir.PatientName(m)
+	a.session.setPatientName(patientID, name, time.Now())
+	return name
+}
+
+// searchByTag finds resource IDs tagged with the given _tag value.
+func (a *App) searchByTag(ctx context.Context, resourceType, tag string) (ids []string, err error) {
+	defer func() { a.audit(ctx, fhir.AuditRead, "search-type", resourceType, resourceType, "", err) }()
+	resources, err := a.searchPages(ctx, resourceType, 200, 0, neturl.Values{fhir.SearchTag: {tag}}, nil)
+	if err != nil {
+		return nil, err
 	}
-	return extractResources(bundle), nil
+	for _, raw := range resources {
+		if id := fhir.ResourceID(raw); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
 }
 
-func (a *App) searchCarePlans(ctx context.Context, patientID string) ([]json.RawMessage, error) {
-	count := gen.SearchCount(50)
+// searchResources runs a search with arbitrary FHIR query parameters and
+// returns at most count results. Use searchAllPages for every result.
+func (a *App) searchResources(ctx context.Context, resourceType string, count int, query map[string]string) ([]json.RawMessage, error) {
+	values := make(neturl.Values, len(query))
+	for k, v := range query {
+		values.Set(k, v)
+	}
+	return a.searchValues(ctx, resourceType, count, values)
+}
+
+// searchValues is searchResources for queries that repeat a parameter, such
+// as two _has filters that must both match. Parameter names are checked with
+// fhir.CheckSearchParams before anything is sent. A _count in the query
+// replaces count. If the server pages results in smaller pages than count,
+// further pages are fetched until there are count.
+func (a *App) searchValues(ctx context.Context, resourceType string, count int, query neturl.Values) (resources []json.RawMessage, err error) {
+	if err := fhir.CheckSearchParams(resourceType, query); err != nil {
+		return nil, err
+	}
+	if n, err := strconv.Atoi(query.Get("_count")); err == nil && n > 0 {
+		count = n
+	}
+	defer func() {
+		patientID := strings.TrimPrefix(query.Get("patient"), "Patient/")
+		a.audit(ctx, fhir.AuditRead, "search-type", resourceType, resourceType, patientID, err)
+		if patientID == "" {
+			a.logResultAccess(resourceType, resources, err)
+		}
+	}()
+	return a.searchPages(ctx, resourceType, count, count, query, nil)
+}
+
+// searchAllPages runs a search and follows the bundle's "next" links until
+// the last page, calling progress (if set) with the running total.
+func (a *App) searchAllPages(ctx context.Context, resourceType string, count int, query neturl.Values, progress func(int)) (resources []json.RawMessage, err error) {
+	if err := fhir.CheckSearchParams(resourceType, query); err != nil {
+		return nil, err
+	}
+	defer func() {
+		a.audit(ctx, fhir.AuditRead, "search-type", resourceType, resourceType, "", err)
+		a.logResultAccess(resourceType, resources, err)
+	}()
+	return a.searchPages(ctx, resourceType, count, 0, query, progress)
+}
+
+// searchPages runs a search count results to a page and follows each
+// bundle's "next" link until there is none, or until max results have been
+// fetched when max is positive. progress, if set, is called with the
+// running total after each page. Auditing and parameter checks are left to
+// the callers.
+func (a *App) searchPages(ctx context.Context, resourceType string, count, max int, query neturl.Values, progress func(int)) ([]json.RawMessage, error) {
+	var resources []json.RawMessage
+	next := ""
+	for {
+		page, link, err := a.searchPage(ctx, resourceType, count, query, next)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, page...)
+		if max > 0 && len(resources) >= max {
+			resources = resources[:max]
+		}
+		if progress != nil {
+			progress(len(resources))
+		}
+
+		next = link
+		if next == "" || len(page) == 0 || (max > 0 && len(resources) >= max) {
+			return resources, nil
+		}
+	}
+}
+
+// searchPage fetches one page of a search: the first, count results to a
+// page, when next is empty, or the page a previous one's next link points
+// to. It returns the page's resources and its own next link, "" on the
+// last page.
+func (a *App) searchPage(ctx context.Context, resourceType string, count int, query neturl.Values, next string) ([]json.RawMessage, string, error) {
+	c := gen.SearchCount(count)
 	params := &gen.SearchResourcesParams{
-		UnderscoreCount: &count,
+		UnderscoreCount: &c,
 	}
 	resp, err := a.Client.Inner().SearchResourcesWithResponse(
 		ctx, a.Client.Tenant(), a.Client.Store(),
-		gen.ResourceType("CarePlan"), params,
+		gen.ResourceType(resourceType), params,
 		func(ctx context.Context, req *http.Request) error {
+			if next != "" {
+				u, err := neturl.Parse(next)
+				if err != nil {
+					return fmt.Errorf("invalid next link: %w", err)
+				}
+				req.URL.RawQuery = u.RawQuery
+				return nil
+			}
 			q := req.URL.Query()
-			q.Set("patient", patientID)
-			q.Set("status", "active")
+			for k, vs := range query {
+				q[k] = vs
+			}
 			req.URL.RawQuery = q.Encode()
 			return nil
 		},
 	)
 	if err != nil {
-		return nil, fmt.Errorf("searching care plans: %w", err)
+		return nil, "", fmt.Errorf("searching %s: %w", resourceType, err)
 	}
 	if resp.HTTPResponse.StatusCode >= 400 {
-		return nil, fmt.Errorf("search failed: HTTP %d", resp.HTTPResponse.StatusCode)
+		return nil, "", fmt.Errorf("search %s failed: HTTP %d", resourceType, resp.HTTPResponse.StatusCode)
 	}
 	var bundle gen.Bundle
 	if err := json.Unmarshal(resp.Body, &bundle); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
+		return nil, "", fmt.Errorf("parsing %s response: %w", resourceType, err)
 	}
-	return extractResources(bundle), nil
+	return extractResources(bundle), nextLink(bundle), nil
 }
 
-func (a *App) resolvePatientName(ctx context.Context, patientID string) string {
-	raw, err := a.Client.ReadResource(ctx, "Patient", patientID)
-	if err != nil {
-		return patientID
+// nextLink returns the URL of a search bundle's next page, or "" on the
+// last page.
+func nextLink(bundle gen.Bundle) string {
+	if bundle.Link == nil {
+		return ""
 	}
-	m, err := fhir.Parse(raw)
-	if err != nil {
-		return patientID
+	for _, l := range *bundle.Link {
+		if l.Relation == "next" {
+			return l.Url
+		}
 	}
-	return fhir.PatientName(m)
+	return ""
 }
 
-// searchByTag finds resource IDs tagged with the given _tag value.
-func (a *App) searchByTag(ctx context.Context, resourceType, tag string) ([]string, error) {
-	count := gen.SearchCount(200)
-	params := &gen.SearchResourcesParams{
-		UnderscoreCount: &count,
-	}
+// invokeOperation calls a FHIR instance operation such as
+// PlanDefinition/{id}/$apply with GET. The SDK has no operation helper, so
+// the search endpoint's request is re-pointed at the operation with a
+// request editor.
+func (a *App) invokeOperation(ctx context.Context, resourceType, id, operation string, query neturl.Values) (json.RawMessage, error) {
 	resp, err := a.Client.Inner().SearchResourcesWithResponse(
 		ctx, a.Client.Tenant(), a.Client.Store(),
-		gen.ResourceType(resourceType), params,
+		gen.ResourceType(resourceType), &gen.SearchResourcesParams{},
 		func(ctx context.Context, req *http.Request) error {
-			q := req.URL.Query()
-			q.Set("_tag", tag)
-			req.URL.RawQuery = q.Encode()
+			req.URL.Path = strings.TrimSuffix(req.URL.Path, "/") + "/" + id + "/" + operation
+			req.URL.RawQuery = query.Encode()
 			return nil
 		},
 	)
 	if err != nil {
-		return nil, fmt.Errorf("searching %s: %w", resourceType, err)
+		return nil, fmt.Errorf("%s: %w", operation, err)
 	}
 	if resp.HTTPResponse.StatusCode >= 400 {
-		return nil, fmt.Errorf("search %s failed: HTTP %d", resourceType, resp.HTTPResponse.StatusCode)
-	}
-	var bundle gen.Bundle
-	if err := json.Unmarshal(resp.Body, &bundle); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
+		return nil, fmt.Errorf("%s failed: HTTP %d", operation, resp.HTTPResponse.StatusCode)
 	}
-	var ids []string
-	for _, raw := range extractResources(bundle) {
-		if id := fhir.ResourceID(raw); id != "" {
-			ids = append(ids, id)
-		}
-	}
-	return ids, nil
+	return resp.Body, nil
 }
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/phenoml/phenostore-sdk-go/phenostore"
+)
+
+// newTestApp returns an App whose store is handler, behind a fake OAuth
+// token endpoint. Responses are typed application/fhir+json unless handler
+// sets another type, and the offline queue is kept in a temporary file so
+// writes that fail are never queued in the user's.
+func newTestApp(t *testing.T, handler http.HandlerFunc) *App {
+	t.Helper()
+	t.Setenv("PHENOSTORE_QUEUE_FILE", filepath.Join(t.TempDir(), "queue.json"))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/oauth/token" {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"access_token":"t","token_type":"bearer","expires_in":3600}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/fhir+json")
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	client, err := phenostore.NewClient(srv.URL, "id", "secret", "tenant", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &App{Client: client}
+}
+
+// pagedHandler serves a search of total patients, size to a page, linking
+// each page to the next with a page parameter.
+func pagedHandler(total, size int, requests *int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		var entries []map[string]any
+		for i := page * size; i < total && i < (page+1)*size; i++ {
+			entries = append(entries, map[string]any{"resource": map[string]any{"resourceType": "Patient", "id": strconv.Itoa(i)}})
+		}
+		bundle := map[string]any{"resourceType": "Bundle", "type": "searchset", "entry": entries}
+		if (page+1)*size < total {
+			bundle["link"] = []map[string]any{{"relation": "next", "url": fmt.Sprintf("http://%s%s?page=%d", r.Host, r.URL.Path, page+1)}}
+		}
+		_ = json.NewEncoder(w).Encode(bundle)
+	}
+}
+
+func TestSearchPages(t *testing.T) {
+	var requests int
+	a := newTestApp(t, pagedHandler(5, 2, &requests))
+
+	all, err := a.searchPages(context.Background(), "Patient", 2, 0, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 5 || requests != 3 {
+		t.Errorf("all pages: got %d patients in %d requests, want 5 in 3", len(all), requests)
+	}
+
+	requests = 0
+	capped, err := a.searchPages(context.Background(), "Patient", 2, 3, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(capped) != 3 || requests != 2 {
+		t.Errorf("max 3: got %d patients in %d requests, want 3 in 2", len(capped), requests)
+	}
+}
+
+func TestIncludedPatients(t *testing.T) {
+	resources := []json.RawMessage{
+		json.RawMessage(`{"resourceType":"CarePlan","id":"cp1","subject":{"reference":"Patient/p1"}}`),
+		json.RawMessage(`{"resourceType":"Patient","id":"p1","name":[{"given":["Ana"],"family":"Ruiz"}]}`),
+		json.RawMessage(`{"resourceType":"CarePlan","id":"cp2","subject":{"reference":"Patient/p2"}}`),
+	}
+	matches, names := includedPatients(resources)
+	if len(matches) != 2 {
+		t.Errorf("got %d matches, want the 2 care plans", len(matches))
+	}
+	if names["p1"] != "Ana Ruiz" || len(names) != 1 {
+		t.Errorf("names = %v, want p1: Ana Ruiz", names)
+	}
+}
+
+func TestWithSort(t *testing.T) {
+	if got := withSort(neturl.Values{"patient": {"p1"}}, sortNewest).Encode(); got != "_sort=-date&patient=p1" {
+		t.Errorf("withSort = %s", got)
+	}
+	if got := withSort(neturl.Values{"patient": {"p1"}}, "").Encode(); got != "patient=p1" {
+		t.Errorf("withSort with no sort = %s", got)
+	}
+}
+
+func TestResolvePatientNameCached(t *testing.T) {
+	var reads int
+	family := "Garcia"
+	a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			reads++
+			fmt.Fprintf(w, `{"resourceType":"Patient","id":"p1","name":[{"given":["Maria"],"family":%q}]}`, family)
+			return
+		}
+		family = "Lopez"
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	})
+	ctx := context.Background()
+
+	for range 3 {
+		if name := a.resolvePatientName(ctx, "p1"); name != "Maria Garcia" {
+			t.Fatalf("resolvePatientName = %q", name)
+		}
+	}
+	if reads != 1 {
+		t.Errorf("3 lookups read the patient %d times, want 1", reads)
+	}
+
+	if _, err := a.updateResource(ctx, "Patient", "p1", json.RawMessage(`{"resourceType":"Patient","id":"p1"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if name := a.resolvePatientName(ctx, "p1"); name != "Maria Lopez" || reads != 2 {
+		t.Errorf("after an update: %q in %d reads, want Maria Lopez in 2", name, reads)
+	}
+}
@@ -0,0 +1,271 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// maxAttachmentSize keeps uploads small enough for a single Binary create.
+const maxAttachmentSize = 10 << 20
+
+// AttachFile lets the user pick a patient and upload a local file as a
+// Binary indexed by a DocumentReference. Images can also be set as the
+// patient's photo.
+func (a *App) AttachFile() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var path, title string
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().Title("File path").Value(&path).Validate(func(s string) error {
+				info, err := os.Stat(s)
+				if err != nil {
+					return fmt.Errorf("file not found")
+				}
+				if info.IsDir() {
+					return fmt.Errorf("not a file")
+				}
+				if info.Size() > maxAttachmentSize {
+					return fmt.Errorf("file is larger than %s", fhir.FormatBytes(maxAttachmentSize))
+				}
+				return nil
+			}),
+			huh.NewInput().Title("Title (leave blank for the file name)").Value(&title),
+		),
+	)
+	if err := form.Run(); err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		ShowError(fmt.Errorf("reading file: %w", err))
+		PressEnter()
+		return
+	}
+	if title == "" {
+		title = filepath.Base(path)
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	contentType, _, _ = strings.Cut(contentType, ";")
+
+	setPhoto := false
+	if strings.HasPrefix(contentType, "image/") {
+		err = huh.NewConfirm().
+			Title("Use as the patient's photo?").
+			Value(&setPhoto).
+			Run()
+		if err != nil {
+			if !isAbort(err) {
+				ShowError(err)
+				PressEnter()
+			}
+			return
+		}
+	}
+
+	ctx := context.Background()
+	var docID string
+	var apiErr error
+
+	err = runSpinner("Uploading attachment...", func(ctx context.Context) {
+		binary, err := a.createResource(ctx, "Binary", fhir.NewBinary(contentType, data))
+		if err != nil {
+			apiErr = fmt.Errorf("uploading binary: %w", err)
+			return
+		}
+		attachment := fhir.NewAttachment("Binary/"+fhir.ResourceID(binary), contentType, title, len(data))
+
+		doc, err := a.createResource(ctx, "DocumentReference", fhir.NewDocumentReference(patientID, attachment, time.Now()))
+		if err != nil {
+			apiErr = fmt.Errorf("creating document reference: %w", err)
+			return
+		}
+		docID = fhir.ResourceID(doc)
+
+		if setPhoto {
+			apiErr = a.setPatientPhoto(ctx, patientID, attachment)
+		}
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if docID != "" {
+		a.emit(ctx, EventDocumentReferenceCreated, "DocumentReference", docID, patientID)
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+		PressEnter()
+		return
+	}
+	if setPhoto {
+		a.emit(ctx, EventPatientUpdated, "Patient", patientID, patientID)
+	}
+
+	fmt.Printf("\n  Attached %q (%s, %s) (ID: %s)\n", title, contentType, fhir.FormatBytes(int64(len(data))), docID)
+	if setPhoto {
+		fmt.Println("  Set as patient photo")
+	}
+	PressEnter()
+}
+
+// setPatientPhoto replaces the patient's photo with attachment
+// (read-modify-write).
+func (a *App) setPatientPhoto(ctx context.Context, patientID string, attachment map[string]any) error {
+	raw, err := a.readResource(ctx, "Patient", patientID)
+	if err != nil {
+		return fmt.Errorf("reading patient: %w", err)
+	}
+	var patient map[string]any
+	if err := json.Unmarshal(raw, &patient); err != nil {
+		return fmt.Errorf("parsing patient: %w", err)
+	}
+	patient["photo"] = []any{attachment}
+	updated, err := json.Marshal(patient)
+	if err != nil {
+		return fmt.Errorf("marshaling patient: %w", err)
+	}
+	if _, err := a.updateResource(ctx, "Patient", patientID, updated); err != nil {
+		return fmt.Errorf("updating patient: %w", err)
+	}
+	return nil
+}
+
+// DownloadAttachment lets the user pick a patient and one of their
+// attachments, then saves the Binary content to a local file.
+func (a *App) DownloadAttachment() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var docs []json.RawMessage
+	var fetchErr error
+
+	err = runSpinner("Loading attachments...", func(ctx context.Context) {
+		docs, fetchErr = a.searchByPatient(ctx, "DocumentReference", patientID, "")
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	if len(docs) == 0 {
+		fmt.Println("  No attachments found.")
+		PressEnter()
+		return
+	}
+	fhir.PrintDocumentReferenceList(docs)
+	fmt.Println()
+
+	var options []huh.Option[int]
+	for i, raw := range docs {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		options = append(options, huh.NewOption(fhir.DocumentReferenceDisplay(m), i))
+	}
+
+	var choice int
+	err = huh.NewSelect[int]().
+		Title("Select attachment").
+		Options(options...).
+		Value(&choice).
+		Run()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	doc, _ := fhir.Parse(docs[choice])
+	url, _ := fhir.Path(doc, "content.attachment.url").(string)
+	binaryID, ok := strings.CutPrefix(url, "Binary/")
+	if !ok {
+		ShowError(fmt.Errorf("attachment is not stored as a Binary (%s)", url))
+		PressEnter()
+		return
+	}
+
+	dest := filepath.Base(fhir.DocumentReferenceDisplay(doc))
+	if err := huh.NewInput().Title("Save to").Value(&dest).Run(); err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var data []byte
+	var apiErr error
+
+	err = runSpinner("Downloading attachment...", func(ctx context.Context) {
+		raw, err := a.readResource(ctx, "Binary", binaryID)
+		if err != nil {
+			apiErr = fmt.Errorf("reading binary: %w", err)
+			return
+		}
+		data, apiErr = fhir.BinaryContent(raw)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+		PressEnter()
+		return
+	}
+	if err := os.WriteFile(dest, data, 0o644); err != nil {
+		ShowError(fmt.Errorf("writing file: %w", err))
+		PressEnter()
+		return
+	}
+
+	fmt.Printf("\n  Saved %s to %s\n", fhir.FormatBytes(int64(len(data))), dest)
+	PressEnter()
+}
@@ -0,0 +1,167 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// defaultAuditAgent is recorded on AuditEvents when no ProvenanceAgent is set.
+const defaultAuditAgent = "phenostore-example"
+
+// audit records an AuditEvent for an operation when a.Audit is enabled.
+// entity is a reference ("Patient/123") or a resource type for searches.
+// Auditing is best effort: a failure to write the event is ignored so it never
+// blocks the operation being audited. Operations on AuditEvent itself are not
+// audited, so viewing the trail does not add to it. Operations on a patient's
+// data also go in the session's local access log, with or without a.Audit.
+func (a *App) audit(ctx context.Context, action, interaction, resourceType, entity, patientID string, opErr error) {
+	if resourceType == "AuditEvent" {
+		return
+	}
+	if patientID != "" {
+		a.session.access.record(accessEntry{Time: time.Now(), Interaction: interaction, Entity: entity, PatientID: patientID, Success: opErr == nil})
+	}
+	if !a.Audit {
+		return
+	}
+	agent := a.ProvenanceAgent
+	if agent == "" {
+		agent = defaultAuditAgent
+	}
+	event := fhir.NewAuditEvent(action, interaction, entity, patientID, agent, opErr == nil)
+	a.Client.CreateResource(ctx, "AuditEvent", event, nil)
+}
+
+// readResource reads a resource by ID and audits the read.
+func (a *App) readResource(ctx context.Context, resourceType, id string) (json.RawMessage, error) {
+	raw, err := a.Client.ReadResource(ctx, resourceType, id)
+	patientID := ""
+	if resourceType == "Patient" {
+		patientID = id
+	} else if m, perr := fhir.Parse(raw); err == nil && perr == nil {
+		patientID = resourcePatient(m)
+	}
+	a.audit(ctx, fhir.AuditRead, "read", resourceType, resourceType+"/"+id, patientID, err)
+	return raw, err
+}
+
+// resourcePatient returns the patient a resource belongs to, from its
+// subject, patient, or (for a Task) for reference.
+func resourcePatient(m map[string]any) string {
+	if id := fhir.PatientRef(m); id != "" {
+		return id
+	}
+	for _, key := range []string{"patient", "for"} {
+		if p, ok := m[key].(map[string]any); ok {
+			ref, _ := p["reference"].(string)
+			if id, ok := strings.CutPrefix(ref, "Patient/"); ok {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+// bodyPatient is resourcePatient for a raw resource body.
+func bodyPatient(resourceType, id string, body json.RawMessage) string {
+	if resourceType == "Patient" {
+		return id
+	}
+	m, err := fhir.Parse(body)
+	if err != nil {
+		return ""
+	}
+	return resourcePatient(m)
+}
+
+// AuditTrail lets the user search AuditEvents by patient or by date.
+func (a *App) AuditTrail() {
+	by := "patient"
+	err := huh.NewSelect[string]().
+		Title("Audit Trail").
+		Options(
+			huh.NewOption("By patient", "patient"),
+			huh.NewOption("By date", "date"),
+		).
+		Value(&by).
+		Run()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	query := map[string]string{fhir.SearchSort: "-date"}
+	if by == "patient" {
+		patientID, err := a.PickPatient()
+		if err != nil || patientID == "" {
+			if err != nil && !isAbort(err) {
+				ShowError(err)
+				PressEnter()
+			}
+			return
+		}
+		query[fhir.SearchAuditEventPatient] = patientID
+	} else {
+		date := time.Now().Format("2006-01-02")
+		err := huh.NewInput().
+			Title("Date (YYYY-MM-DD)").
+			Value(&date).
+			Validate(func(s string) error {
+				if _, err := time.Parse("2006-01-02", s); err != nil {
+					return fmt.Errorf("use YYYY-MM-DD")
+				}
+				return nil
+			}).
+			Run()
+		if err != nil {
+			if !isAbort(err) {
+				ShowError(err)
+				PressEnter()
+			}
+			return
+		}
+		query[fhir.SearchAuditEventDate] = date
+	}
+
+	var events []json.RawMessage
+	var fetchErr error
+	var elapsed time.Duration
+
+	err = runSpinner("Loading audit events...", func(ctx context.Context) {
+		start := time.Now()
+		events, fetchErr = a.searchResources(ctx, "AuditEvent", 100, query)
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	if len(events) == 0 {
+		fmt.Println("  No audit events found.")
+		if !a.Audit {
+			fmt.Println("  Set PHENOSTORE_AUDIT=true to record them.")
+		}
+	} else {
+		fhir.PrintAuditEventList(events)
+		showTiming(fmt.Sprintf("Fetched %d audit events", len(events)), elapsed)
+	}
+	PressEnter()
+}
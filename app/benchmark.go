@@ -0,0 +1,265 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// Benchmark operations, each a single request of the kind the app sends
+// most.
+const (
+	benchRead   = "read"   // read one Patient by ID
+	benchSearch = "search" // search Observations, 20 to a page
+	benchCreate = "create" // create a tagged Patient, deleted afterwards
+)
+
+// benchStats summarizes one operation's run. Percentiles are of the
+// requests that succeeded.
+type benchStats struct {
+	Op            string
+	Requests      int
+	Errors        int
+	P50, P95, P99 time.Duration
+	Elapsed       time.Duration
+}
+
+// throughput is how many requests a second the run completed.
+func (s benchStats) throughput() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Requests) / s.Elapsed.Seconds()
+}
+
+// errorRate is the percentage of requests that failed.
+func (s benchStats) errorRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Errors) * 100 / float64(s.Requests)
+}
+
+// percentile returns the p-th percentile of sorted latencies by the
+// nearest-rank method, or 0 if there are none.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// runBenchmark calls fn n times, at most concurrency at a time, and times
+// each call.
+func runBenchmark(ctx context.Context, op string, n, concurrency int, fn func(ctx context.Context) error) benchStats {
+	var mu sync.Mutex
+	var latencies []time.Duration
+	failed := 0
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for range n {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			t := time.Now()
+			err := fn(ctx)
+			d := time.Since(t)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed++
+				return
+			}
+			latencies = append(latencies, d)
+		}()
+	}
+	wg.Wait()
+	slices.Sort(latencies)
+	return benchStats{
+		Op:       op,
+		Requests: n,
+		Errors:   failed,
+		P50:      percentile(latencies, 50),
+		P95:      percentile(latencies, 95),
+		P99:      percentile(latencies, 99),
+		Elapsed:  time.Since(start),
+	}
+}
+
+// benchPatient is the Patient a create benchmark writes. It carries the
+// seed tag, so Delete Seed Data removes any that cleanup leaves behind.
+func benchPatient() json.RawMessage {
+	b, _ := json.Marshal(map[string]any{
+		"resourceType": "Patient",
+		"meta":         seedMeta,
+		"name":         []map[string]any{{"family": "Benchmark", "given": []string{"Load"}}},
+	})
+	return b
+}
+
+// benchOperation returns the request an operation times. Creates record the
+// new patient's ID in created, for cleanup. Requests go straight to the
+// SDK, without the audit events, provenance, and offline queue the rest of
+// the app adds, so the timings are the store's.
+func (a *App) benchOperation(op, patientID string, mu *sync.Mutex, created *[]string) func(ctx context.Context) error {
+	switch op {
+	case benchRead:
+		return func(ctx context.Context) error {
+			_, err := a.Client.ReadResource(ctx, "Patient", patientID)
+			return err
+		}
+	case benchSearch:
+		return func(ctx context.Context) error {
+			_, _, err := a.searchPage(ctx, "Observation", 20, nil, "")
+			return err
+		}
+	default:
+		body := benchPatient()
+		return func(ctx context.Context) error {
+			raw, err := a.Client.CreateResource(ctx, "Patient", body, nil)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			*created = append(*created, fhir.ResourceID(raw))
+			mu.Unlock()
+			return nil
+		}
+	}
+}
+
+// Benchmark sends a chosen number of reads, searches, and creates to the
+// store, a few at a time, and reports each operation's latency
+// percentiles, throughput, and error rate. Patients the creates wrote are
+// deleted afterwards.
+func (a *App) Benchmark() {
+	requests := "50"
+	concurrency := 4
+	ops := []string{benchRead, benchSearch, benchCreate}
+	err := huh.NewForm(huh.NewGroup(
+		huh.NewMultiSelect[string]().
+			Title("Operations").
+			Options(
+				huh.NewOption("Read a patient", benchRead),
+				huh.NewOption("Search observations", benchSearch),
+				huh.NewOption("Create a patient (deleted afterwards)", benchCreate),
+			).
+			Value(&ops).
+			Validate(func(s []string) error {
+				if len(s) == 0 {
+					return fmt.Errorf("choose at least one operation")
+				}
+				return nil
+			}),
+		huh.NewInput().
+			Title("Requests per operation").
+			Value(&requests).
+			Validate(func(s string) error {
+				if n, err := strconv.Atoi(s); err != nil || n < 1 || n > 1000 {
+					return fmt.Errorf("enter a whole number from 1 to 1000")
+				}
+				return nil
+			}),
+		huh.NewSelect[int]().
+			Title("Concurrent requests").
+			Options(huh.NewOption("1", 1), huh.NewOption("4", 4), huh.NewOption("8", 8), huh.NewOption("16", 16)).
+			Value(&concurrency),
+	)).Run()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	n, _ := strconv.Atoi(requests)
+
+	var patientID string
+	if slices.Contains(ops, benchRead) {
+		var apiErr error
+		err := runSpinner("Finding a patient to read...", func(ctx context.Context) {
+			var patients []json.RawMessage
+			patients, _, apiErr = a.searchPage(ctx, "Patient", 1, nil, "")
+			if apiErr == nil && len(patients) > 0 {
+				patientID = fhir.ResourceID(patients[0])
+			}
+		})
+		if err != nil || apiErr != nil {
+			ShowError(errors.Join(err, apiErr))
+			PressEnter()
+			return
+		}
+		if patientID == "" {
+			fmt.Println("\n  Reads need a patient to read. Seed sample data first, or leave reads out.")
+			PressEnter()
+			return
+		}
+	}
+
+	var results []benchStats
+	var mu sync.Mutex
+	var created []string
+	for _, op := range ops {
+		var stats benchStats
+		err := runSpinner(fmt.Sprintf("Benchmarking %ss (%d requests, %d at a time)...", op, n, concurrency), func(ctx context.Context) {
+			stats = runBenchmark(ctx, op, n, concurrency, a.benchOperation(op, patientID, &mu, &created))
+		})
+		if err != nil {
+			ShowError(err)
+			break
+		}
+		results = append(results, stats)
+	}
+	if len(created) > 0 {
+		var deleteErr error
+		err := runSpinner(fmt.Sprintf("Deleting %d benchmark patients...", len(created)), func(ctx context.Context) {
+			_, deleteErr = a.deleteAll(ctx, "Patient", created, cmp.Or(a.DeleteConcurrency, defaultDeleteConcurrency))
+		})
+		if err != nil || deleteErr != nil {
+			ShowError(fmt.Errorf("deleting benchmark patients (Delete Seed Data removes the rest): %w", errors.Join(err, deleteErr)))
+		}
+	}
+	if len(results) > 0 {
+		fmt.Println()
+		printBenchmark(results)
+	}
+	PressEnter()
+}
+
+// printBenchmark lists each operation's latency percentiles, throughput,
+// and error rate. Operations with errors are shown in the error color.
+func printBenchmark(results []benchStats) {
+	fmt.Println(barStyle.Bold(true).Render("Benchmark"))
+	fmt.Println(timingStyle.Render(fmt.Sprintf("  %-8s %8s %8s %8s %8s %9s %7s", "OP", "REQUESTS", "P50", "P95", "P99", "REQ/S", "ERRORS")))
+	for _, s := range results {
+		line := fmt.Sprintf("  %-8s %8d %8s %8s %8s %9.1f %6.1f%%", s.Op, s.Requests,
+			s.P50.Round(time.Millisecond), s.P95.Round(time.Millisecond), s.P99.Round(time.Millisecond),
+			s.throughput(), s.errorRate())
+		if s.Errors > 0 {
+			line = errorStyle.Render(line)
+		}
+		fmt.Println(line)
+	}
+	var total time.Duration
+	requests := 0
+	for _, s := range results {
+		total += s.Elapsed
+		requests += s.Requests
+	}
+	fmt.Println()
+	showTiming(fmt.Sprintf("Sent %d requests", requests), total)
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 100; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+	for p, want := range map[float64]time.Duration{50: 50 * time.Millisecond, 95: 95 * time.Millisecond, 99: 99 * time.Millisecond, 100: 100 * time.Millisecond} {
+		if got := percentile(latencies, p); got != want {
+			t.Errorf("p%v = %s, want %s", p, got, want)
+		}
+	}
+	if got := percentile(latencies[:1], 99); got != time.Millisecond {
+		t.Errorf("p99 of one = %s, want 1ms", got)
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("p50 of none = %s, want 0", got)
+	}
+}
+
+func TestRunBenchmark(t *testing.T) {
+	var calls, inFlight, maxInFlight atomic.Int64
+	stats := runBenchmark(context.Background(), benchRead, 20, 4, func(ctx context.Context) error {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for m := maxInFlight.Load(); n > m && !maxInFlight.CompareAndSwap(m, n); m = maxInFlight.Load() {
+		}
+		time.Sleep(time.Millisecond)
+		if calls.Add(1)%5 == 0 {
+			return errors.New("HTTP 503")
+		}
+		return nil
+	})
+	if stats.Requests != 20 || stats.Errors != 4 {
+		t.Errorf("got %d requests with %d errors, want 20 with 4", stats.Requests, stats.Errors)
+	}
+	if rate := stats.errorRate(); rate != 20 {
+		t.Errorf("error rate = %v%%, want 20%%", rate)
+	}
+	if stats.P50 < time.Millisecond || stats.P99 < stats.P50 || stats.throughput() <= 0 {
+		t.Errorf("implausible stats: %+v", stats)
+	}
+	if m := maxInFlight.Load(); m > 4 {
+		t.Errorf("%d requests in flight, want at most 4", m)
+	}
+}
@@ -0,0 +1,367 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// officeVisit is billed on every claim.
+var officeVisit = fhir.ClaimItem{Code: "99213", Display: "Office visit, established patient, low complexity", Price: 120.00}
+
+// labFees maps the LOINC codes of billable lab results to CPT services.
+var labFees = map[string]fhir.ClaimItem{
+	"4548-4": {Code: "83036", Display: "Hemoglobin A1c", Price: 35.00},
+	"2345-7": {Code: "82947", Display: "Glucose, quantitative, blood", Price: 12.00},
+	"2093-3": {Code: "82465", Display: "Cholesterol, serum, total", Price: 18.00},
+	"2160-0": {Code: "82565", Display: "Creatinine, blood", Price: 14.00},
+}
+
+// defaultProcedureFee is charged for recorded procedures with no lab fee.
+const defaultProcedureFee = 80.00
+
+// billableServices is what a claim is built from: a patient's conditions,
+// lab results, and procedures, their encounters, and the claims already
+// made for them.
+type billableServices struct {
+	conditions   []json.RawMessage
+	observations []json.RawMessage
+	procedures   []json.RawMessage
+	encounters   []json.RawMessage
+	claims       []json.RawMessage
+}
+
+func (a *App) loadBillableServices(ctx context.Context, patientID string) (*billableServices, error) {
+	var s billableServices
+	var err error
+	if s.encounters, err = a.searchByPatient(ctx, "Encounter", patientID, ""); err != nil {
+		return nil, err
+	}
+	if s.conditions, err = a.searchByPatient(ctx, "Condition", patientID, ""); err != nil {
+		return nil, err
+	}
+	if s.observations, err = a.searchByPatient(ctx, "Observation", patientID, ""); err != nil {
+		return nil, err
+	}
+	if s.procedures, err = a.searchByPatient(ctx, "Procedure", patientID, ""); err != nil {
+		return nil, err
+	}
+	if s.claims, err = a.searchByPatient(ctx, "Claim", patientID, ""); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// claimScope is what one claim bills for: the services of one encounter,
+// or else those of one date of service (YYYY-MM-DD).
+type claimScope struct {
+	EncounterID string
+	Date        string
+}
+
+// visitKey identifies the office visit billed for a scope.
+func (c claimScope) visitKey() string {
+	if c.EncounterID != "" {
+		return "visit Encounter/" + c.EncounterID
+	}
+	return "visit " + c.Date
+}
+
+// serviceDate returns the local YYYY-MM-DD date of a FHIR date or dateTime,
+// or "" if there is none.
+func serviceDate(s string) string {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.Local().Format("2006-01-02")
+	}
+	if len(s) >= len("2006-01-02") {
+		return s[:len("2006-01-02")]
+	}
+	return ""
+}
+
+// billedServices returns what earlier claims billed: the references of the
+// resources they list as supporting information, and a visitKey for each
+// office visit. Cancelled claims and those entered in error are left out.
+func billedServices(claims []json.RawMessage) map[string]bool {
+	billed := make(map[string]bool)
+	for _, raw := range claims {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		if status := mapStr(m, "status"); status == "cancelled" || status == "entered-in-error" {
+			continue
+		}
+		infos, _ := m["supportingInfo"].([]any)
+		for _, info := range infos {
+			info, _ := info.(map[string]any)
+			if ref, _ := fhir.Path(info, "valueReference.reference").(string); ref != "" {
+				billed[ref] = true
+			}
+		}
+		items, _ := m["item"].([]any)
+		for _, item := range items {
+			item, _ := item.(map[string]any)
+			if code, _ := fhir.Path(item, "productOrService.coding.code").(string); code != officeVisit.Code {
+				continue
+			}
+			if ref, _ := fhir.Path(item, "encounter.reference").(string); ref != "" {
+				billed["visit "+ref] = true
+			} else if date, _ := fhir.Path(item, "servicedDate").(string); date != "" {
+				billed["visit "+date] = true
+			}
+		}
+	}
+	return billed
+}
+
+// claimLines turns the billable services in scope into claim diagnoses and
+// items. A service is in scope when it references the encounter, or else
+// when it happened on the date of service. Diagnoses are the active
+// conditions in scope. Items are the office visit, completed procedures,
+// and billable lab results, leaving out any an earlier claim billed.
+// Procedures with no CPT code cannot be billed; they are listed by name in
+// unbillable instead.
+func (s *billableServices) claimLines(scope claimScope) (diagnoses []fhir.ClaimDiagnosis, items []fhir.ClaimItem, unbillable []string) {
+	inScope := func(m map[string]any, dates ...string) bool {
+		if scope.EncounterID != "" {
+			ref, _ := fhir.Path(m, "encounter.reference").(string)
+			return ref == "Encounter/"+scope.EncounterID
+		}
+		for _, d := range dates {
+			if d != "" {
+				return serviceDate(d) == scope.Date
+			}
+		}
+		return false
+	}
+	billed := billedServices(s.claims)
+
+	for _, raw := range s.conditions {
+		m, err := fhir.Parse(raw)
+		if err != nil || !fhir.ConditionActive(m) || !inScope(m, mapStr(m, "recordedDate"), mapStr(m, "onsetDateTime")) {
+			continue
+		}
+		code := fhir.ConditionICD10(m)
+		if code == "" {
+			continue
+		}
+		display := fhir.ConditionDisplay(m)
+		if display == "" {
+			display, _ = fhir.Path(m, "code.coding.display").(string)
+		}
+		diagnoses = append(diagnoses, fhir.ClaimDiagnosis{Code: code, Display: display})
+	}
+
+	if !billed[scope.visitKey()] {
+		items = append(items, officeVisit)
+	}
+	for _, raw := range s.procedures {
+		m, err := fhir.Parse(raw)
+		if err != nil || mapStr(m, "status") != "completed" {
+			continue
+		}
+		performed, _ := fhir.Path(m, "performedPeriod.start").(string)
+		ref := "Procedure/" + mapStr(m, "id")
+		if billed[ref] || !inScope(m, mapStr(m, "performedDateTime"), performed) {
+			continue
+		}
+		display, _ := fhir.Path(m, "code.text").(string)
+		code := fhir.ProcedureCPT(m)
+		if code == "" {
+			if display == "" {
+				display = ref
+			}
+			unbillable = append(unbillable, display)
+			continue
+		}
+		items = append(items, fhir.ClaimItem{Code: code, Display: display, Price: defaultProcedureFee, Source: ref})
+	}
+	for _, raw := range s.observations {
+		m, err := fhir.Parse(raw)
+		if err != nil || mapStr(m, "status") == "entered-in-error" {
+			continue
+		}
+		ref := "Observation/" + mapStr(m, "id")
+		if billed[ref] || !inScope(m, mapStr(m, "effectiveDateTime")) {
+			continue
+		}
+		code, _ := fhir.Path(m, "code.coding.code").(string)
+		if fee, ok := labFees[code]; ok {
+			fee.Source = ref
+			items = append(items, fee)
+		}
+	}
+	return diagnoses, items, unbillable
+}
+
+// askServiceDate asks for the date of service a claim bills, defaulting to
+// today.
+func askServiceDate() (string, error) {
+	s := time.Now().Format("2006-01-02")
+	err := huh.NewInput().
+		Title("Date of service (YYYY-MM-DD)").
+		Value(&s).
+		Validate(func(s string) error {
+			t, err := time.ParseInLocation("2006-01-02", strings.TrimSpace(s), time.Local)
+			if err != nil {
+				return fmt.Errorf("use YYYY-MM-DD")
+			}
+			if t.After(time.Now()) {
+				return fmt.Errorf("cannot be in the future")
+			}
+			return nil
+		}).
+		Run()
+	return strings.TrimSpace(s), err
+}
+
+// GenerateClaim lets the user pick a patient and an encounter or a date of
+// service, and submits a Claim for the diagnoses, procedures, and labs of
+// that visit that no earlier claim billed.
+func (a *App) GenerateClaim() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	ctx := context.Background()
+	var services *billableServices
+	var fetchErr error
+
+	err = runSpinner("Loading billable services...", func(ctx context.Context) {
+		services, fetchErr = a.loadBillableServices(ctx, patientID)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	var scope claimScope
+	if len(services.encounters) > 0 {
+		scope.EncounterID, err = pickEncounter("Bill for encounter", "A date of service", services.encounters)
+		if err != nil {
+			if !isAbort(err) {
+				ShowError(err)
+				PressEnter()
+			}
+			return
+		}
+	}
+	encounterRef := ""
+	if scope.EncounterID != "" {
+		encounterRef = "Encounter/" + scope.EncounterID
+	} else if scope.Date, err = askServiceDate(); err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	diagnoses, items, unbillable := services.claimLines(scope)
+	if len(unbillable) > 0 {
+		fmt.Printf("\n  Not billed, no CPT code: %s\n", strings.Join(unbillable, ", "))
+	}
+	if len(items) == 0 {
+		fmt.Println("\n  Nothing left to bill: earlier claims cover every service in scope.")
+		PressEnter()
+		return
+	}
+	body := fhir.NewClaim(patientID, encounterRef, scope.Date, diagnoses, items)
+
+	preview, _ := fhir.Parse(body)
+	fmt.Println()
+	fhir.PrintClaim(preview)
+	fmt.Println()
+
+	var confirm bool
+	err = huh.NewConfirm().
+		Title("Submit this claim?").
+		Value(&confirm).
+		Run()
+	if err != nil || !confirm {
+		return
+	}
+
+	var created json.RawMessage
+	var apiErr error
+
+	err = runSpinner("Submitting claim...", func(ctx context.Context) {
+		created, apiErr = a.createResource(ctx, "Claim", body)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(fmt.Errorf("creating claim: %w", apiErr))
+		PressEnter()
+		return
+	}
+
+	id := fhir.ResourceID(created)
+	a.emit(ctx, EventClaimCreated, "Claim", id, patientID)
+	fmt.Printf("\n  Submitted claim with %d items (ID: %s)\n", len(items), id)
+	PressEnter()
+}
+
+// ViewClaims lets the user pick a patient and view their claims.
+func (a *App) ViewClaims() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var claims []json.RawMessage
+	var fetchErr error
+	var elapsed time.Duration
+
+	err = runSpinner("Loading claims...", func(ctx context.Context) {
+		start := time.Now()
+		claims, fetchErr = a.searchByPatient(ctx, "Claim", patientID, "")
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	if len(claims) == 0 {
+		fmt.Println("  No claims found.")
+	} else {
+		fhir.PrintClaimList(claims)
+		showTiming(fmt.Sprintf("Fetched %d claims", len(claims)), elapsed)
+	}
+	PressEnter()
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+func TestClaimLines(t *testing.T) {
+	s := &billableServices{
+		conditions: []json.RawMessage{
+			json.RawMessage(`{"resourceType":"Condition","id":"c1","recordedDate":"2026-03-05","code":{"coding":[{"system":"http://snomed.info/sct","code":"44054006"},{"system":"http://hl7.org/fhir/sid/icd-10-cm","code":"E11.9"}],"text":"Type 2 diabetes"}}`),
+			json.RawMessage(`{"resourceType":"Condition","id":"c2","recordedDate":"2026-03-05","verificationStatus":{"coding":[{"code":"entered-in-error"}]},"code":{"coding":[{"system":"http://hl7.org/fhir/sid/icd-10-cm","code":"I10"}]}}`),
+			json.RawMessage(`{"resourceType":"Condition","id":"c3","recordedDate":"2025-01-10","code":{"coding":[{"system":"http://hl7.org/fhir/sid/icd-10-cm","code":"J45.909"}]}}`),
+		},
+		procedures: []json.RawMessage{
+			json.RawMessage(`{"resourceType":"Procedure","id":"pr1","status":"completed","performedDateTime":"2026-03-05","code":{"coding":[{"system":"http://www.ama-assn.org/go/cpt","code":"93000"}],"text":"Electrocardiogram"}}`),
+			json.RawMessage(`{"resourceType":"Procedure","id":"pr2","status":"completed","performedDateTime":"2026-03-05","code":{"coding":[{"system":"http://snomed.info/sct","code":"71651007"}],"text":"Mammography"}}`),
+		},
+		observations: []json.RawMessage{
+			json.RawMessage(`{"resourceType":"Observation","id":"o1","status":"final","effectiveDateTime":"2026-03-05","code":{"coding":[{"code":"4548-4"}]}}`),
+			json.RawMessage(`{"resourceType":"Observation","id":"o2","status":"final","effectiveDateTime":"2026-03-05","code":{"coding":[{"code":"2345-7"}]}}`),
+			json.RawMessage(`{"resourceType":"Observation","id":"o3","status":"final","effectiveDateTime":"2025-01-10","code":{"coding":[{"code":"2093-3"}]}}`),
+		},
+	}
+	scope := claimScope{Date: "2026-03-05"}
+
+	diagnoses, items, unbillable := s.claimLines(scope)
+	if len(diagnoses) != 1 || diagnoses[0].Code != "E11.9" {
+		t.Errorf("diagnoses = %+v, want E11.9 alone", diagnoses)
+	}
+	if len(items) != 4 || items[0].Code != officeVisit.Code || items[1].Code != "93000" || items[2].Source != "Observation/o1" || items[3].Source != "Observation/o2" {
+		t.Fatalf("items = %+v, want the visit, pr1, o1, and o2", items)
+	}
+	if len(unbillable) != 1 || unbillable[0] != "Mammography" {
+		t.Errorf("unbillable = %q, want the SNOMED-coded procedure alone", unbillable)
+	}
+
+	// A claim for the visit, pr1, and o1 leaves only o2 to bill.
+	s.claims = []json.RawMessage{fhir.NewClaim("p1", "", scope.Date, diagnoses, items[:3])}
+	if _, items, _ := s.claimLines(scope); len(items) != 1 || items[0].Source != "Observation/o2" {
+		t.Errorf("after a claim: items = %+v, want o2 alone", items)
+	}
+
+	// A cancelled claim bills nothing.
+	m, _ := fhir.Parse(s.claims[0])
+	m["status"] = "cancelled"
+	s.claims[0], _ = json.Marshal(m)
+	if _, items, _ := s.claimLines(scope); len(items) != 4 {
+		t.Errorf("after a cancelled claim: got %d items, want 4", len(items))
+	}
+}
@@ -0,0 +1,229 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// breakGlassTTL is how long a break-the-glass reason opens a restricted
+// chart before it is asked for again.
+const breakGlassTTL = 30 * time.Minute
+
+var breakGlassReasons = []string{
+	"Emergency treatment",
+	"Covering for the patient's clinician",
+	"Patient request",
+}
+
+// breakGlass asks for the reason to open a restricted patient's chart and
+// records it as an AuditEvent, whether or not PHENOSTORE_AUDIT is set. The
+// chart is not opened if the reason cannot be recorded. A reason already
+// given within breakGlassTTL is not asked for again.
+func (a *App) breakGlass(patientID, name string) error {
+	if a.session.glassOpen(patientID, time.Now()) {
+		return nil
+	}
+
+	options := huh.NewOptions(breakGlassReasons...)
+	options = append(options, huh.NewOption("Other (free text)", ""))
+
+	var reason string
+	err := huh.NewSelect[string]().
+		Title(fmt.Sprintf("%s's chart is restricted", name)).
+		Description("Give a reason to open it. The reason is recorded in the audit log.").
+		Options(options...).
+		Value(&reason).
+		Run()
+	if err == nil && reason == "" {
+		err = huh.NewInput().
+			Title("Reason").
+			Value(&reason).
+			Validate(func(s string) error {
+				if strings.TrimSpace(s) == "" {
+					return fmt.Errorf("a reason is required")
+				}
+				return nil
+			}).
+			Run()
+	}
+	if err != nil {
+		return err
+	}
+	reason = strings.TrimSpace(reason)
+
+	var apiErr error
+	err = runSpinner("Recording access reason...", func(ctx context.Context) {
+		apiErr = a.recordBreakGlass(ctx, patientID, reason)
+	})
+	if err != nil {
+		return err
+	}
+	if apiErr != nil {
+		return fmt.Errorf("%w; the chart was not opened", apiErr)
+	}
+	a.session.openGlass(patientID, time.Now())
+	return nil
+}
+
+// recordBreakGlass writes the reason for opening a restricted patient's
+// chart to an AuditEvent, whether or not PHENOSTORE_AUDIT is set, and to the
+// access log. The chart must not be shown if it returns an error.
+func (a *App) recordBreakGlass(ctx context.Context, patientID, reason string) error {
+	agent := a.ProvenanceAgent
+	if agent == "" {
+		agent = defaultAuditAgent
+	}
+	created, err := a.Client.CreateResource(ctx, "AuditEvent", fhir.NewBreakGlassAuditEvent(patientID, agent, reason), nil)
+	if err != nil {
+		return fmt.Errorf("recording break-the-glass reason: %w", err)
+	}
+	a.session.access.record(accessEntry{Time: time.Now(), Interaction: "break-glass", Entity: "Patient/" + patientID, PatientID: patientID, Success: true, Reason: reason})
+	a.emit(context.Background(), EventPatientBreakGlass, "AuditEvent", fhir.ResourceID(created), patientID)
+	return nil
+}
+
+// chartPatient returns the ID of the patient whose chart a resource is part
+// of: the Patient itself, or the patient it belongs to.
+func chartPatient(m map[string]any) string {
+	if mapStr(m, "resourceType") == "Patient" {
+		return mapStr(m, "id")
+	}
+	return resourcePatient(m)
+}
+
+// openChartOf asks for a break-the-glass reason before a resource from a
+// restricted patient's chart is shown by a screen that did not pick the
+// patient first. It reads the patient to check, unless the resource is the
+// patient.
+func (a *App) openChartOf(m map[string]any) error {
+	patientID := chartPatient(m)
+	if patientID == "" || a.session.glassOpen(patientID, time.Now()) {
+		return nil
+	}
+	patient := m
+	if mapStr(m, "resourceType") != "Patient" {
+		var raw json.RawMessage
+		var apiErr error
+		err := runSpinner("Checking patient...", func(ctx context.Context) {
+			raw, apiErr = a.Client.ReadResource(ctx, "Patient", patientID)
+		})
+		if err != nil {
+			return err
+		}
+		if apiErr != nil {
+			return fmt.Errorf("checking whether %s's chart is restricted: %w", patientID, apiErr)
+		}
+		if patient, err = fhir.Parse(raw); err != nil {
+			return fmt.Errorf("parsing patient: %w", err)
+		}
+	}
+	if !fhir.IsRestricted(patient) {
+		return nil
+	}
+	return a.breakGlass(patientID, fhir.PatientName(patient))
+}
+
+// restrictedPatients returns the IDs of every patient whose chart is
+// restricted. The search is not audited, since it only reads IDs.
+func (a *App) restrictedPatients(ctx context.Context) (map[string]bool, error) {
+	patients, err := a.searchPages(ctx, "Patient", 1000, 0, neturl.Values{
+		fhir.SearchSecurity: {fhir.ConfidentialitySystem + "|R"},
+		fhir.SearchElements: {"id"},
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("finding restricted charts: %w", err)
+	}
+	ids := make(map[string]bool, len(patients))
+	for _, raw := range patients {
+		ids[fhir.ResourceID(raw)] = true
+	}
+	return ids, nil
+}
+
+// RestrictChart marks a patient's chart restricted, so picking the patient
+// requires a break-the-glass reason, or lifts the restriction. Explore
+// Resource asks for the reason too, API mode requires one per request, and
+// Search Console hides restricted patients' resources. Ask a Question, the
+// clinic and cohort dashboards, the digest, and reports show restricted
+// patients' data without one.
+func (a *App) RestrictChart() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	ctx := context.Background()
+	var raw json.RawMessage
+	var apiErr error
+	err = runSpinner("Loading patient...", func(ctx context.Context) {
+		raw, apiErr = a.readResource(ctx, "Patient", patientID)
+	})
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(fmt.Errorf("reading patient: %w", apiErr))
+		PressEnter()
+		return
+	}
+	m, err := fhir.Parse(raw)
+	if err != nil {
+		ShowError(fmt.Errorf("parsing patient: %w", err))
+		PressEnter()
+		return
+	}
+
+	restrict := !fhir.IsRestricted(m)
+	title := fmt.Sprintf("Restrict %s's chart?", fhir.PatientName(m))
+	if !restrict {
+		title = fmt.Sprintf("Lift the restriction on %s's chart?", fhir.PatientName(m))
+	}
+	var confirm bool
+	err = huh.NewConfirm().
+		Title(title).
+		Value(&confirm).
+		Run()
+	if err != nil || !confirm {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	err = runSpinner("Updating patient...", func(ctx context.Context) {
+		_, apiErr = a.updateResource(ctx, "Patient", patientID, fhir.WithRestricted(raw, restrict))
+	})
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(fmt.Errorf("updating patient: %w", apiErr))
+		PressEnter()
+		return
+	}
+
+	a.emit(ctx, EventPatientUpdated, "Patient", patientID, patientID)
+	if restrict {
+		fmt.Printf("\n  Restricted chart of %s\n", fhir.PatientName(m))
+	} else {
+		a.session.closeGlass(patientID)
+		fmt.Printf("\n  Lifted restriction on chart of %s\n", fhir.PatientName(m))
+	}
+	PressEnter()
+}
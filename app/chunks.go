@@ -0,0 +1,469 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// Large imports (seeding, snapshot restores) are sent as a series of
+// transactions of App.ChunkSize entries rather than one bundle, several at
+// a time. A chunk is sent once the chunks it refers to have been committed.
+// After each chunk, a manifest beside the offline queue records which
+// chunks went through and the server IDs of the entries they created. If
+// the run is interrupted or a chunk fails, the next run of the same import
+// sends only the chunks that did not go through, and points references to
+// the others at those IDs. An unreachable store stops the import instead of
+// queueing the chunk, since the manifest already makes it resumable.
+
+const (
+	// transactionChunkSize is how many entries each chunk transaction holds
+	// when PHENOSTORE_CHUNK_SIZE is not set.
+	transactionChunkSize = 100
+	// defaultChunkConcurrency is how many chunks are sent at once when
+	// PHENOSTORE_CHUNK_CONCURRENCY is not set.
+	defaultChunkConcurrency = 4
+)
+
+// parseChunkSize parses PHENOSTORE_CHUNK_SIZE, returning the default when
+// it is empty.
+func parseChunkSize(v string) (int, error) {
+	if v == "" {
+		return transactionChunkSize, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > 1000 {
+		return 0, fmt.Errorf("invalid PHENOSTORE_CHUNK_SIZE %q: must be a whole number of entries from 1 to 1000", v)
+	}
+	return n, nil
+}
+
+// parseChunkConcurrency parses PHENOSTORE_CHUNK_CONCURRENCY, returning the
+// default when it is empty.
+func parseChunkConcurrency(v string) (int, error) {
+	if v == "" {
+		return defaultChunkConcurrency, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > 32 {
+		return 0, fmt.Errorf("invalid PHENOSTORE_CHUNK_CONCURRENCY %q: must be a whole number from 1 to 32", v)
+	}
+	return n, nil
+}
+
+// importManifest is the progress of one chunked import.
+type importManifest struct {
+	// Fingerprint identifies the entries being imported; a manifest for
+	// other entries is not resumed.
+	Fingerprint string `json:"fingerprint"`
+	ChunkSize   int    `json:"chunkSize,omitempty"` // 0 in manifests from before it was configurable
+	Chunks      int    `json:"chunks"`
+	Done        int    `json:"done"`               // chunks committed
+	Finished    []int  `json:"finished,omitempty"` // which chunks, by index
+	Created     int    `json:"created"`
+	// IDs maps the fullUrl of each committed entry to its "Type/id".
+	IDs       map[string]string `json:"ids"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+}
+
+// importManifestPath is where the manifest of the named import is kept,
+// next to the offline queue file.
+func importManifestPath(name string) string {
+	return filepath.Join(filepath.Dir(queuePath()), name+"-import.json")
+}
+
+// loadImportManifest returns the saved progress of the named import, or nil
+// if there is none for these entries.
+func loadImportManifest(name string, entries []map[string]any) (*importManifest, error) {
+	data, err := os.ReadFile(importManifestPath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m importManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", importManifestPath(name), err)
+	}
+	if m.Fingerprint != importFingerprint(entries) {
+		return nil, nil
+	}
+	return &m, nil
+}
+
+// committed reports which chunks have gone through. Manifests written
+// before chunks were sent concurrently only count them, and the chunks
+// then went in order.
+func (m *importManifest) committed() []bool {
+	done := make([]bool, m.Chunks)
+	if len(m.Finished) == 0 {
+		for c := range min(m.Done, m.Chunks) {
+			done[c] = true
+		}
+	}
+	for _, c := range m.Finished {
+		if c >= 0 && c < m.Chunks {
+			done[c] = true
+		}
+	}
+	return done
+}
+
+func (m *importManifest) save(name string) error {
+	m.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(importManifestPath(name), data)
+}
+
+// importFingerprint hashes the type, fullUrl, and request of each entry, so
+// a manifest only resumes the import it was written for. The chunk size is
+// kept in the manifest instead, so a resumed import is split the way it was
+// first. Resource contents are left out: seed data is timestamped when it
+// is built, and would never match a manifest from an earlier run.
+func importFingerprint(entries []map[string]any) string {
+	h := sha256.New()
+	for _, e := range entries {
+		request, _ := e["request"].(map[string]any)
+		fullURL, _ := e["fullUrl"].(string)
+		fmt.Fprintf(h, "%s %s %s\n", mapStr(request, "method"), mapStr(request, "url"), fullURL)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// askResume asks whether to resume an interrupted import. Starting over
+// sends the committed chunks again, creating duplicates.
+func askResume(m *importManifest) (bool, error) {
+	resume := true
+	err := huh.NewConfirm().
+		Title(fmt.Sprintf("Resume the import with %d of %d chunks done?", m.Done, m.Chunks)).
+		Description(fmt.Sprintf("%d resources were created %s. Starting over creates them again.",
+			m.Created, m.UpdatedAt.Local().Format("2006-01-02 15:04"))).
+		Affirmative("Resume").
+		Negative("Start over").
+		Value(&resume).
+		Run()
+	return resume, err
+}
+
+// importInChunks runs the named import with processInChunks, first asking
+// whether to resume if an earlier run of it was interrupted.
+func (a *App) importInChunks(ctx context.Context, name string, entries []map[string]any, limit *rateLimiter) (created int, err error) {
+	m, err := loadImportManifest(name, entries)
+	if err != nil {
+		return 0, err
+	}
+	if m != nil {
+		resume, err := askResume(m)
+		if err != nil {
+			return 0, err
+		}
+		if !resume {
+			m = nil
+		}
+	}
+	return a.processInChunks(ctx, name, entries, m, limit)
+}
+
+// processInChunks creates entries in transactions of App.ChunkSize, up to
+// App.ChunkConcurrency at a time, printing a line per chunk, and returns how
+// many resources were created, counting those from earlier runs. m is the
+// progress to resume from, or nil to start over, and limit, if not nil,
+// paces the chunks to a number of resources a second. A chunk is only sent once
+// every chunk it refers to has gone through; if one of those failed, it is
+// skipped. Failed and skipped chunks are reported together at the end, and
+// the manifest is left in place so the import can be run again to resume.
+func (a *App) processInChunks(ctx context.Context, name string, entries []map[string]any, m *importManifest, limit *rateLimiter) (created int, err error) {
+	if m == nil {
+		size := cmp.Or(a.ChunkSize, transactionChunkSize)
+		m = &importManifest{
+			Fingerprint: importFingerprint(entries),
+			ChunkSize:   size,
+			Chunks:      (len(entries) + size - 1) / size,
+			IDs:         make(map[string]string),
+		}
+	} else {
+		fmt.Printf("  Resuming with %d/%d chunks done\n", m.Done, m.Chunks)
+	}
+	size := cmp.Or(m.ChunkSize, transactionChunkSize)
+	entries = dependenciesFirst(entries)
+	deps, err := chunkDependencies(entries, size)
+	if err != nil {
+		return m.Created, err
+	}
+	if err := a.replayBeforeImport(ctx); err != nil {
+		return m.Created, err
+	}
+
+	ok := m.committed()
+	done := make([]chan struct{}, m.Chunks)
+	sem := make(chan struct{}, cmp.Or(a.ChunkConcurrency, defaultChunkConcurrency))
+	var mu sync.Mutex // guards m, ok, failures, and unreachable, and keeps progress lines whole
+	var failures []chunkFailure
+	var unreachable bool
+	var wg sync.WaitGroup
+	start, resumed := time.Now(), m.Created
+	for c := range m.Chunks {
+		done[c] = make(chan struct{})
+		if ok[c] {
+			close(done[c])
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[c])
+			fail := func(err error) {
+				failures = append(failures, chunkFailure{c, err})
+				fmt.Printf("  Chunk %d/%d: %v\n", c+1, m.Chunks, err)
+			}
+
+			for _, d := range deps[c] {
+				select {
+				case <-done[d]:
+				case <-ctx.Done():
+				}
+			}
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			mu.Lock()
+			for _, d := range deps[c] {
+				if !ok[d] {
+					fail(fmt.Errorf("skipped: it refers to chunk %d", d+1))
+					mu.Unlock()
+					return
+				}
+			}
+			if unreachable {
+				fail(fmt.Errorf("not sent: the store is unreachable"))
+				mu.Unlock()
+				return
+			}
+			if ctx.Err() != nil {
+				fail(fmt.Errorf("not sent: the import was cancelled"))
+				mu.Unlock()
+				return
+			}
+			committed := maps.Clone(m.IDs)
+			mu.Unlock()
+
+			lo, hi := c*size, min((c+1)*size, len(entries))
+			if err := limit.wait(ctx, hi-lo); err != nil {
+				mu.Lock()
+				fail(fmt.Errorf("not sent: the import was cancelled"))
+				mu.Unlock()
+				return
+			}
+			n, ids, err := a.createChunk(ctx, entries[lo:hi], committed)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				unreachable = unreachable || isUnreachable(err)
+				fail(err)
+				return
+			}
+			maps.Copy(m.IDs, ids)
+			m.Done, m.Created = m.Done+1, m.Created+n
+			m.Finished = append(m.Finished, c)
+			if err := m.save(name); err != nil {
+				fail(fmt.Errorf("saving import progress: %w", err))
+				return
+			}
+			ok[c] = true
+			rate := float64(m.Created-resumed) / time.Since(start).Seconds()
+			fmt.Printf("  Chunk %d/%d: %d created (%d/%d chunks done, %.0f resources/s)\n", c+1, m.Chunks, n, m.Done, m.Chunks, rate)
+		}()
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return m.Created, fmt.Errorf("%w with %d of %d chunks done (run the import again to resume)", errCancelled, m.Done, m.Chunks)
+	}
+	if len(failures) > 0 {
+		sort.Slice(failures, func(i, j int) bool { return failures[i].Chunk < failures[j].Chunk })
+		msgs := make([]string, len(failures))
+		for i, f := range failures {
+			msgs[i] = fmt.Sprintf("chunk %d/%d: %v", f.Chunk+1, m.Chunks, f.Err)
+		}
+		return m.Created, fmt.Errorf("%d of %d chunks did not go through (run the import again to resume): %s", len(failures), m.Chunks, strings.Join(msgs, "; "))
+	}
+	if err := os.Remove(importManifestPath(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return m.Created, err
+	}
+	return m.Created, nil
+}
+
+// chunkFailure is a chunk that did not go through, and why.
+type chunkFailure struct {
+	Chunk int
+	Err   error
+}
+
+// chunkDependencies returns, for each chunk of size entries, the other
+// chunks holding entries it refers to by fullUrl. entries must be in
+// dependenciesFirst order, so those are earlier chunks, unless entries that
+// refer to each other fall in different chunks. Neither could then go first,
+// so that is an error.
+func chunkDependencies(entries []map[string]any, size int) ([][]int, error) {
+	chunkOf := make(map[string]int)
+	for i, e := range entries {
+		if fullURL, _ := e["fullUrl"].(string); fullURL != "" {
+			chunkOf[fullURL] = i / size
+		}
+	}
+	deps := make([][]int, (len(entries)+size-1)/size)
+	seen := make(map[[2]int]bool)
+	refs, _ := fhir.BundleReferences(entries)
+	for _, r := range refs {
+		from := r.Entry / size
+		to, ok := chunkOf[r.Reference]
+		if !ok || to == from || seen[[2]int{from, to}] {
+			continue
+		}
+		if to > from {
+			return nil, fmt.Errorf("%s and %s refer to each other but fall in different chunks (set a larger PHENOSTORE_CHUNK_SIZE)", r.Source, r.Reference)
+		}
+		seen[[2]int{from, to}] = true
+		deps[from] = append(deps[from], to)
+	}
+	return deps, nil
+}
+
+// replayBeforeImport replays the offline queue, so an import does not
+// overtake changes waiting in it, and fails if any are still waiting.
+func (a *App) replayBeforeImport(ctx context.Context) error {
+	ops, err := loadQueue()
+	if err != nil {
+		return err
+	}
+	if pending, _ := queueCounts(ops); pending == 0 {
+		return nil
+	}
+	result, err := a.replayQueue(ctx)
+	if err != nil {
+		return err
+	}
+	if result.Remaining > 0 {
+		return fmt.Errorf("%d queued changes are still waiting for the store: %w", result.Remaining, result.Cause)
+	}
+	return nil
+}
+
+// createChunk submits one chunk as a transaction. References to entries of
+// earlier chunks are pointed at their server IDs first. It returns how many
+// resources were created and the "Type/id" of each entry by fullUrl.
+func (a *App) createChunk(ctx context.Context, chunk []map[string]any, committed map[string]string) (created int, ids map[string]string, err error) {
+	entries := make([]map[string]any, len(chunk))
+	targets := make([]string, len(chunk))
+	for i, e := range chunk {
+		var m map[string]any
+		b, _ := json.Marshal(e["resource"])
+		if err := json.Unmarshal(b, &m); err != nil {
+			return 0, nil, fmt.Errorf("entry %d: %w", i, err)
+		}
+		rewriteReferences(m, committed)
+		copied := make(map[string]any, len(e))
+		for k, v := range e {
+			copied[k] = v
+		}
+		copied["resource"] = m
+		if _, ok := copied["fullUrl"].(string); !ok {
+			copied["fullUrl"] = "urn:uuid:" + newUUID()
+		}
+		entries[i], targets[i] = copied, copied["fullUrl"].(string)
+	}
+
+	if err := a.checkUnits(entries); err != nil {
+		return 0, nil, err
+	}
+	if err := a.checkReferences(ctx, entries); err != nil {
+		return 0, nil, err
+	}
+	defer func() { a.audit(ctx, fhir.AuditExecute, "transaction", "Bundle", "Bundle", "", err) }()
+	result, err := a.transaction(ctx, entries, targets, "CREATE")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	ids = make(map[string]string)
+	for i, e := range chunk {
+		if result.Entry == nil || i >= len(*result.Entry) {
+			break
+		}
+		if resp := (*result.Entry)[i].Response; resp != nil && resp.Status != nil && strings.HasPrefix(*resp.Status, "201") {
+			created++
+		}
+		// Only entries that came with a fullUrl can be referenced.
+		fullURL, _ := e["fullUrl"].(string)
+		if fullURL == "" {
+			continue
+		}
+		raw, err := entryResource(result, i)
+		if err != nil {
+			continue
+		}
+		if r, err := fhir.Parse(raw); err == nil && mapStr(r, "id") != "" {
+			ids[fullURL] = mapStr(r, "resourceType") + "/" + mapStr(r, "id")
+		}
+	}
+	return created, ids, nil
+}
+
+// dependenciesFirst orders entries so that each comes after the entries its
+// urn: references point at, keeping the original order otherwise. Chunks can
+// then only refer back to entries that are already on the server. Entries in
+// a reference cycle keep their order.
+func dependenciesFirst(entries []map[string]any) []map[string]any {
+	byURL := make(map[string]int)
+	for i, e := range entries {
+		if fullURL, _ := e["fullUrl"].(string); fullURL != "" {
+			byURL[fullURL] = i
+		}
+	}
+	deps := make([][]int, len(entries))
+	refs, _ := fhir.BundleReferences(entries)
+	for _, r := range refs {
+		if j, ok := byURL[r.Reference]; ok && j != r.Entry {
+			deps[r.Entry] = append(deps[r.Entry], j)
+		}
+	}
+
+	ordered := make([]map[string]any, 0, len(entries))
+	state := make([]int, len(entries)) // 0 unvisited, 1 visiting, 2 placed
+	var visit func(i int)
+	visit = func(i int) {
+		if state[i] != 0 {
+			return
+		}
+		state[i] = 1
+		for _, j := range deps[i] {
+			visit(j)
+		}
+		state[i] = 2
+		ordered = append(ordered, entries[i])
+	}
+	for i := range entries {
+		visit(i)
+	}
+	return ordered
+}
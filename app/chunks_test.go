@@ -0,0 +1,246 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+func TestDependenciesFirst(t *testing.T) {
+	entries := buildSeedBundle(len(seedPatients), seedFullCharts)
+	ordered := dependenciesFirst(entries)
+	if len(ordered) != len(entries) {
+		t.Fatalf("got %d entries, want %d", len(ordered), len(entries))
+	}
+
+	seen := make(map[string]bool)
+	for _, e := range ordered {
+		if fullURL, _ := e["fullUrl"].(string); fullURL != "" {
+			seen[fullURL] = true
+		}
+		refs, local := fhir.BundleReferences([]map[string]any{e})
+		for _, r := range refs {
+			if !local[r.Reference] && strings.HasPrefix(r.Reference, "urn:") && !seen[r.Reference] {
+				t.Errorf("%s refers to %s before it is created", r.Source, r.Reference)
+			}
+		}
+	}
+}
+
+func TestImportFingerprint(t *testing.T) {
+	a := importFingerprint(buildSeedBundle(3, seedFullCharts))
+	if b := importFingerprint(buildSeedBundle(3, seedFullCharts)); a != b {
+		t.Error("fingerprint of the same seed changed between builds")
+	}
+	if b := importFingerprint(buildSeedBundle(2, seedFullCharts)); a == b {
+		t.Error("fingerprints of different seeds match")
+	}
+}
+
+func TestSnapshotBundleEntriesStable(t *testing.T) {
+	dir := t.TempDir()
+	data := `{"resourceType":"Patient","id":"p1"}
+{"resourceType":"Patient"}
+`
+	if err := os.WriteFile(filepath.Join(dir, "Patient.ndjson"), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "Condition.ndjson"), []byte(`{"resourceType":"Condition","id":"c1","subject":{"reference":"Patient/p1"}}`+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := snapshotBundleEntries(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := snapshotBundleEntries(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if importFingerprint(first) != importFingerprint(second) {
+		t.Error("restoring the same snapshot built different entries")
+	}
+	if first[0]["fullUrl"] == first[1]["fullUrl"] {
+		t.Errorf("entries share fullUrl %v", first[0]["fullUrl"])
+	}
+}
+
+func TestImportManifestRoundTrip(t *testing.T) {
+	t.Setenv("PHENOSTORE_QUEUE_FILE", filepath.Join(t.TempDir(), "queue.json"))
+	entries := buildSeedBundle(1, seedProblemLists)
+
+	if m, err := loadImportManifest("seed", entries); err != nil || m != nil {
+		t.Fatalf("loadImportManifest with no manifest = %v, %v", m, err)
+	}
+	saved := &importManifest{
+		Fingerprint: importFingerprint(entries),
+		Chunks:      2,
+		Done:        1,
+		Created:     3,
+		IDs:         map[string]string{"urn:uuid:patient-1": "Patient/123"},
+	}
+	if err := saved.save("seed"); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := loadImportManifest("seed", entries)
+	if err != nil || m == nil {
+		t.Fatalf("loadImportManifest = %v, %v", m, err)
+	}
+	if m.Done != 1 || m.Created != 3 || m.IDs["urn:uuid:patient-1"] != "Patient/123" {
+		t.Errorf("loaded %+v, want %+v", m, saved)
+	}
+	if m, err := loadImportManifest("seed", buildSeedBundle(2, seedProblemLists)); err != nil || m != nil {
+		t.Errorf("manifest for other entries resumed: %v, %v", m, err)
+	}
+}
+
+func TestChunkDependencies(t *testing.T) {
+	entries := dependenciesFirst(buildSeedBundle(2, seedProblemLists))
+	deps, err := chunkDependencies(entries, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deps) != (len(entries)+1)/2 {
+		t.Fatalf("got %d chunks, want %d", len(deps), (len(entries)+1)/2)
+	}
+	if len(deps[0]) != 0 {
+		t.Errorf("chunk 1 depends on %v, want nothing", deps[0])
+	}
+	for c, ds := range deps {
+		for _, d := range ds {
+			if d >= c {
+				t.Errorf("chunk %d depends on later chunk %d", c+1, d+1)
+			}
+		}
+	}
+}
+
+func TestChunkDependenciesCycle(t *testing.T) {
+	linked := func(urn, other string) map[string]any {
+		return map[string]any{
+			"fullUrl": urn,
+			"resource": map[string]any{
+				"resourceType": "Patient",
+				"link":         []any{map[string]any{"other": map[string]any{"reference": other}, "type": "seealso"}},
+			},
+			"request": map[string]any{"method": "POST", "url": "Patient"},
+		}
+	}
+	entries := dependenciesFirst([]map[string]any{
+		linked("urn:uuid:a", "urn:uuid:b"),
+		linked("urn:uuid:b", "urn:uuid:a"),
+	})
+
+	if deps, err := chunkDependencies(entries, 2); err != nil || len(deps) != 1 || len(deps[0]) != 0 {
+		t.Errorf("in one chunk: deps = %v, err = %v", deps, err)
+	}
+	if deps, err := chunkDependencies(entries, 1); err == nil {
+		t.Errorf("across chunks: deps = %v, want an error", deps)
+	}
+
+	var maxInFlight atomic.Int64
+	a := newTestApp(t, transactionHandler(t, &maxInFlight))
+	a.ChunkSize = 1
+	if _, err := a.processInChunks(context.Background(), "cycle", entries, nil, nil); err == nil {
+		t.Error("processInChunks imported a cycle split across chunks")
+	}
+	if maxInFlight.Load() != 0 {
+		t.Error("a chunk was sent for a cycle split across chunks")
+	}
+}
+
+// transactionHandler accepts transactions, giving each entry an ID, and
+// answers _id searches as if every ID exists. It fails the test if a
+// transaction refers by urn: to an entry it does not hold, and records the
+// most transactions it saw at once.
+func transactionHandler(t *testing.T, maxInFlight *atomic.Int64) http.HandlerFunc {
+	var inFlight, nextID atomic.Int64
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			var entries []map[string]any
+			for _, id := range strings.Split(r.URL.Query().Get("_id"), ",") {
+				entries = append(entries, map[string]any{"resource": map[string]any{"resourceType": path.Base(r.URL.Path), "id": id}})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"resourceType": "Bundle", "type": "searchset", "entry": entries})
+		default:
+			n := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for m := maxInFlight.Load(); n > m && !maxInFlight.CompareAndSwap(m, n); m = maxInFlight.Load() {
+			}
+			time.Sleep(5 * time.Millisecond)
+
+			var bundle struct{ Entry []map[string]any }
+			if err := json.NewDecoder(r.Body).Decode(&bundle); err != nil {
+				t.Error(err)
+			}
+			refs, local := fhir.BundleReferences(bundle.Entry)
+			for _, ref := range refs {
+				if strings.HasPrefix(ref.Reference, "urn:") && !local[ref.Reference] {
+					t.Errorf("%s refers to %s, which is not in its transaction", ref.Source, ref.Reference)
+				}
+			}
+			var response []map[string]any
+			for _, e := range bundle.Entry {
+				res, _ := e["resource"].(map[string]any)
+				res["id"] = strconv.FormatInt(nextID.Add(1), 10)
+				response = append(response, map[string]any{"resource": res, "response": map[string]any{"status": "201 Created"}})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"resourceType": "Bundle", "type": "transaction-response", "entry": response})
+		}
+	}
+}
+
+func TestProcessInChunksConcurrently(t *testing.T) {
+	var maxInFlight atomic.Int64
+	a := newTestApp(t, transactionHandler(t, &maxInFlight))
+	a.ChunkSize = 3
+	a.ChunkConcurrency = 4
+
+	entries := buildSeedBundle(3, seedFullCharts)
+	created, err := a.processInChunks(context.Background(), "seed", entries, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created != len(entries) {
+		t.Errorf("created %d, want %d", created, len(entries))
+	}
+	if m := maxInFlight.Load(); m < 2 || m > 4 {
+		t.Errorf("max transactions in flight = %d, want 2 to 4", m)
+	}
+	if _, err := os.Stat(importManifestPath("seed")); !os.IsNotExist(err) {
+		t.Errorf("manifest left behind after a finished import: %v", err)
+	}
+}
+
+func TestProcessInChunksCancelled(t *testing.T) {
+	var maxInFlight atomic.Int64
+	a := newTestApp(t, transactionHandler(t, &maxInFlight))
+	a.ChunkSize = 3
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := a.processInChunks(ctx, "seed", buildSeedBundle(1, seedProblemLists), nil, nil)
+	if !errors.Is(err, errCancelled) {
+		t.Fatalf("got %v, want a cancellation", err)
+	}
+	if maxInFlight.Load() != 0 {
+		t.Error("a chunk was sent after the import was cancelled")
+	}
+	if isUnreachable(&neturl.Error{Op: "Post", URL: "http://phenostore.test/fhir", Err: context.Canceled}) {
+		t.Error("a cancelled request counted as the store being unreachable")
+	}
+}
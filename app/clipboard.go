@@ -0,0 +1,172 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	neturl "net/url"
+	"os"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+var copyHelpStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+
+// copyToClipboard puts text on the system clipboard. Where there is no
+// clipboard tool (a server over SSH, a container), it falls back to an OSC 52
+// escape sequence, which most terminals turn into a copy on the local
+// machine. It returns where the text went, for the confirmation message.
+func copyToClipboard(text string) string {
+	if err := clipboard.WriteAll(text); err == nil {
+		return "clipboard"
+	}
+	seq := osc52.New(text)
+	if os.Getenv("TMUX") != "" {
+		seq = seq.Tmux()
+	}
+	_, _ = seq.WriteTo(os.Stderr)
+	return "terminal clipboard"
+}
+
+// detail is the resource the current detail view shows, which the next
+// PressEnter offers to copy. Like screenLock it is package state because
+// PressEnter is not a method.
+var detail struct {
+	base string // {PHENOSTORE_URL}/v1/tenants/{tenant}/stores/{store}
+	raw  json.RawMessage
+}
+
+// configureDetail records where resources live on the server, for the
+// full URLs the copy keys put on the clipboard.
+func configureDetail(baseURL, tenant, store string) {
+	detail.base, _ = neturl.JoinPath(baseURL, "v1", "tenants", tenant, "stores", store)
+}
+
+// showingResource marks raw as the resource on screen. The next PressEnter
+// lets the user copy its ID, URL, or JSON before continuing.
+func showingResource(raw json.RawMessage) {
+	detail.raw = raw
+}
+
+// clipTargets are what the copy keys put on the clipboard for one resource.
+// Fields are empty when the resource does not have them.
+type clipTargets struct {
+	id   string
+	url  string
+	json string
+}
+
+func newClipTargets(raw json.RawMessage) clipTargets {
+	var t clipTargets
+	m, err := fhir.Parse(raw)
+	if err != nil {
+		return t
+	}
+	t.id = mapStr(m, "id")
+	if rt := mapStr(m, "resourceType"); rt != "" && t.id != "" && detail.base != "" {
+		t.url, _ = neturl.JoinPath(detail.base, rt, t.id)
+	}
+	var b bytes.Buffer
+	if json.Indent(&b, raw, "", "  ") == nil {
+		t.json = b.String()
+	}
+	return t
+}
+
+// copy handles a copy key: i for the ID, u for the full URL, and r for the
+// raw JSON. It returns the message to show, or ok=false for any other key.
+func (t clipTargets) copy(key string) (status string, ok bool) {
+	var what, text string
+	switch key {
+	case "i":
+		what, text = "ID", t.id
+	case "u":
+		what, text = "URL", t.url
+	case "r":
+		what, text = "JSON", t.json
+	default:
+		return "", false
+	}
+	if text == "" {
+		return fmt.Sprintf("This resource has no %s to copy", what), true
+	}
+	shown := text
+	if what == "JSON" {
+		shown = fmt.Sprintf("%d bytes of JSON", len(text))
+	}
+	return fmt.Sprintf("Copied %s to the %s", shown, copyToClipboard(text)), true
+}
+
+const copyKeysHelp = "i copy ID  u copy URL  r copy JSON"
+
+// copyPrompt is PressEnter after a detail view: it waits for enter while
+// the copy keys put parts of the resource on the clipboard. Under the
+// inactivity lock it gives up after screenLock.after without a key.
+type copyPrompt struct {
+	targets  clipTargets
+	after    time.Duration
+	keys     int // key presses so far, so stale idle ticks are ignored
+	status   string
+	timedOut bool
+}
+
+// copyIdle is sent when the prompt has been idle for copyPrompt.after; it
+// carries the key count at the time the wait started.
+type copyIdle int
+
+func (p *copyPrompt) idle() tea.Cmd {
+	if p.after == 0 {
+		return nil
+	}
+	keys := p.keys
+	return tea.Tick(p.after, func(time.Time) tea.Msg { return copyIdle(keys) })
+}
+
+func (p *copyPrompt) Init() tea.Cmd { return p.idle() }
+
+func (p *copyPrompt) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case copyIdle:
+		if int(msg) == p.keys {
+			p.timedOut = true
+			return p, tea.Quit
+		}
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "enter", "ctrl+c", "esc":
+			return p, tea.Quit
+		}
+		if status, ok := p.targets.copy(msg.String()); ok {
+			p.status = status
+		}
+		p.keys++
+		return p, p.idle()
+	}
+	return p, nil
+}
+
+func (p *copyPrompt) View() string {
+	s := "\nPress enter to continue  " + copyHelpStyle.Render(copyKeysHelp)
+	if p.status != "" {
+		s += "\n" + p.status
+	}
+	return s + "\n"
+}
+
+// waitWithCopyKeys is PressEnter after showingResource. It returns false
+// when there is no terminal to read keys from, so the caller waits as usual.
+func waitWithCopyKeys(raw json.RawMessage) bool {
+	p := &copyPrompt{targets: newClipTargets(raw), after: screenLock.after}
+	if _, err := tea.NewProgram(p).Run(); err != nil {
+		return false
+	}
+	if p.timedOut {
+		lockScreen()
+	}
+	return true
+}
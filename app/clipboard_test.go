@@ -0,0 +1,36 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClipTargets(t *testing.T) {
+	base := detail.base
+	defer func() { detail.base = base }()
+	configureDetail("https://phenostore.example.com/", "acme", "main")
+
+	raw := []byte(`{"resourceType":"Patient","id":"p1","active":true}`)
+	got := newClipTargets(raw)
+	if got.id != "p1" {
+		t.Errorf("id = %q", got.id)
+	}
+	if want := "https://phenostore.example.com/v1/tenants/acme/stores/main/Patient/p1"; got.url != want {
+		t.Errorf("url = %q, want %q", got.url, want)
+	}
+	if !strings.Contains(got.json, "\n  \"id\": \"p1\"") {
+		t.Errorf("json is not indented: %q", got.json)
+	}
+
+	// Without an ID there is no ID or URL, and the keys say so.
+	got = newClipTargets([]byte(`{"resourceType":"Patient"}`))
+	if got.id != "" || got.url != "" {
+		t.Errorf("targets for a resource without an ID = %+v", got)
+	}
+	if status, ok := got.copy("u"); !ok || status != "This resource has no URL to copy" {
+		t.Errorf("copy(u) = %q, %v", status, ok)
+	}
+	if _, ok := got.copy("x"); ok {
+		t.Error("copy(x) handled a key that is not a copy key")
+	}
+}
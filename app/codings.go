@@ -0,0 +1,196 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// otherCodeSystem is the system picker's value for a system typed as a URI.
+const otherCodeSystem = "other"
+
+// ViewCodings lets the user pick one of a patient's conditions or
+// observations and shows every coding of its code, in all its code systems,
+// then offers to add another, such as a SNOMED CT or local code.
+func (a *App) ViewCodings() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	ctx := context.Background()
+	var conditions, observations []json.RawMessage
+	var fetchErr error
+
+	err = runSpinner("Loading conditions and observations...", func(ctx context.Context) {
+		if conditions, fetchErr = a.searchByPatient(ctx, "Condition", patientID, ""); fetchErr != nil {
+			return
+		}
+		observations, fetchErr = a.searchByPatient(ctx, "Observation", patientID, sortNewest)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	byRef := make(map[string]json.RawMessage)
+	var options []huh.Option[string]
+	for _, raw := range conditions {
+		if m, err := fhir.Parse(raw); err == nil {
+			ref := "Condition/" + mapStr(m, "id")
+			byRef[ref] = raw
+			options = append(options, huh.NewOption("Condition: "+fhir.ConditionDisplay(m), ref))
+		}
+	}
+	for _, raw := range observations {
+		if m, err := fhir.Parse(raw); err == nil {
+			ref := "Observation/" + mapStr(m, "id")
+			byRef[ref] = raw
+			options = append(options, huh.NewOption("Observation: "+fhir.ObservationLabel(m), ref))
+		}
+	}
+	if len(options) == 0 {
+		fmt.Println("\n  No conditions or observations for this patient.")
+		PressEnter()
+		return
+	}
+
+	var ref string
+	err = huh.NewSelect[string]().
+		Title("Select condition or observation").
+		Options(options...).
+		Value(&ref).
+		Filtering(true).
+		Run()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	m, _ := fhir.Parse(byRef[ref])
+	fmt.Println()
+	fhir.PrintCodings(m)
+	fmt.Println()
+
+	var add bool
+	err = huh.NewConfirm().
+		Title("Add a coding?").
+		Affirmative("Yes").
+		Negative("No").
+		Value(&add).
+		Run()
+	if err != nil || !add {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	coding, err := askCoding()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	resourceType, id, _ := strings.Cut(ref, "/")
+	body := fhir.WithCoding(byRef[ref], coding)
+	var apiErr error
+	err = runSpinner("Saving coding...", func(ctx context.Context) {
+		if _, err := a.updateResource(ctx, resourceType, id, body); err != nil {
+			apiErr = fmt.Errorf("updating %s: %w", strings.ToLower(resourceType), err)
+		}
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+		PressEnter()
+		return
+	}
+
+	event := EventObservationUpdated
+	if resourceType == "Condition" {
+		event = EventConditionUpdated
+	}
+	a.emit(ctx, event, resourceType, id, patientID)
+
+	updated, _ := fhir.Parse(body)
+	fmt.Println()
+	fhir.PrintCodings(updated)
+	PressEnter()
+}
+
+// askCoding asks for a coding: its code system, picked from those the app
+// knows or typed as a URI, its code, and an optional display.
+func askCoding() (fhir.Coding, error) {
+	options := make([]huh.Option[string], 0, len(fhir.CodeSystems)+1)
+	for _, cs := range fhir.CodeSystems {
+		options = append(options, huh.NewOption(cs.Name, cs.System))
+	}
+	options = append(options, huh.NewOption("Other (enter URI)", otherCodeSystem))
+
+	var c fhir.Coding
+	var uri string
+	err := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Code system").
+				Options(options...).
+				Value(&c.System),
+		),
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Code system URI").
+				Value(&uri).
+				Validate(func(s string) error {
+					if !strings.Contains(s, ":") {
+						return fmt.Errorf("enter a URI, e.g. http://example.org/codes")
+					}
+					return nil
+				}),
+		).WithHideFunc(func() bool { return c.System != otherCodeSystem }),
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Code").
+				Value(&c.Code).
+				Validate(func(s string) error {
+					if strings.TrimSpace(s) == "" {
+						return fmt.Errorf("required")
+					}
+					return nil
+				}),
+			huh.NewInput().Title("Display (optional)").Value(&c.Display),
+		),
+	).Run()
+	if c.System == otherCodeSystem {
+		c.System = strings.TrimSpace(uri)
+	}
+	c.Code, c.Display = strings.TrimSpace(c.Code), strings.TrimSpace(c.Display)
+	return c, err
+}
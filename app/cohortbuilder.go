@@ -0,0 +1,372 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	neturl "net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// Set operations that combine a criterion with the ones before it.
+const (
+	cohortAnd    = "and"
+	cohortOr     = "or"
+	cohortExcept = "except"
+)
+
+var cohortOpLabels = map[string]string{
+	cohortAnd:    "AND",
+	cohortOr:     "OR",
+	cohortExcept: "AND NOT",
+}
+
+// cohortCriterion is one search in a cohort definition. It matches the
+// patients its search returns resources for.
+type cohortCriterion struct {
+	op           string // how it combines with the criteria before it
+	label        string // e.g. "HbA1c > 8"
+	resourceType string
+	query        neturl.Values
+}
+
+// patientSet is a set of patient IDs.
+type patientSet map[string]bool
+
+// combine applies op to two sets and returns the result as a new set.
+func (s patientSet) combine(op string, other patientSet) patientSet {
+	out := make(patientSet)
+	switch op {
+	case cohortOr:
+		for id := range s {
+			out[id] = true
+		}
+		for id := range other {
+			out[id] = true
+		}
+	case cohortAnd:
+		for id := range s {
+			if other[id] {
+				out[id] = true
+			}
+		}
+	case cohortExcept:
+		for id := range s {
+			if !other[id] {
+				out[id] = true
+			}
+		}
+	}
+	return out
+}
+
+// cohortExpression renders the criteria as they are evaluated, left to
+// right, e.g. "(Condition E11.9 AND HbA1c > 8) OR Age 65+".
+func cohortExpression(criteria []cohortCriterion) string {
+	var expr string
+	for i, c := range criteria {
+		switch {
+		case i == 0:
+			expr = c.label
+		case i == 1:
+			expr += " " + cohortOpLabels[c.op] + " " + c.label
+		default:
+			expr = "(" + expr + ") " + cohortOpLabels[c.op] + " " + c.label
+		}
+	}
+	return expr
+}
+
+// matchPatients runs a criterion's search and returns the patients it matches.
+func (a *App) matchPatients(ctx context.Context, c cohortCriterion) (patientSet, error) {
+	resources, err := a.searchAllPages(ctx, c.resourceType, 100, c.query, nil)
+	if err != nil {
+		return nil, err
+	}
+	set := make(patientSet)
+	for _, raw := range resources {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		patientID := resourcePatient(m)
+		if c.resourceType == "Patient" {
+			patientID = mapStr(m, "id")
+		}
+		if patientID != "" {
+			set[patientID] = true
+		}
+	}
+	return set, nil
+}
+
+// askCohortCriterion asks for one criterion of the given kind.
+func askCohortCriterion(kind string, now time.Time) (cohortCriterion, error) {
+	switch kind {
+	case "condition":
+		var code string
+		err := huh.NewInput().
+			Title("ICD-10 code").
+			Description("e.g. E11.9 (type 2 diabetes), I10 (hypertension), N18.3 (CKD stage 3)").
+			Value(&code).
+			Validate(func(s string) error {
+				if strings.TrimSpace(s) == "" {
+					return fmt.Errorf("required")
+				}
+				return nil
+			}).
+			Run()
+		code = strings.TrimSpace(code)
+		return cohortCriterion{
+			label:        "Condition " + code,
+			resourceType: "Condition",
+			query:        neturl.Values{fhir.SearchConditionCode: {code}},
+		}, err
+
+	case "lab":
+		test, err := pickLabTest()
+		if err != nil {
+			return cohortCriterion{}, err
+		}
+		comparator := "gt"
+		var valueStr string
+		err = huh.NewForm(
+			huh.NewGroup(
+				huh.NewSelect[string]().Title(fmt.Sprintf("Any %s result", test.Text)).Options(
+					huh.NewOption(">", "gt"),
+					huh.NewOption(">=", "ge"),
+					huh.NewOption("<", "lt"),
+					huh.NewOption("<=", "le"),
+				).Value(&comparator),
+				huh.NewInput().Title(fmt.Sprintf("Value (%s)", test.Unit)).Value(&valueStr).Validate(func(s string) error {
+					if _, err := strconv.ParseFloat(s, 64); err != nil {
+						return fmt.Errorf("must be a number")
+					}
+					return nil
+				}),
+			),
+		).Run()
+		symbols := map[string]string{"gt": ">", "ge": ">=", "lt": "<", "le": "<="}
+		return cohortCriterion{
+			label:        fmt.Sprintf("%s %s %s %s", test.Text, symbols[comparator], valueStr, test.Unit),
+			resourceType: "Observation",
+			query:        neturl.Values{fhir.SearchObservationCodeValueQuantity: {test.Code + "$" + comparator + valueStr}},
+		}, err
+
+	default: // age
+		var minStr, maxStr string
+		err := huh.NewForm(
+			huh.NewGroup(
+				huh.NewInput().Title("Minimum age (blank for none)").Value(&minStr),
+				huh.NewInput().Title("Maximum age (blank for none)").Value(&maxStr).Validate(func(s string) error {
+					if strings.TrimSpace(s) == "" && strings.TrimSpace(minStr) == "" {
+						return fmt.Errorf("enter a minimum, a maximum, or both")
+					}
+					for _, v := range []string{minStr, s} {
+						if n, err := strconv.Atoi(strings.TrimSpace(v)); strings.TrimSpace(v) != "" && (err != nil || n < 0) {
+							return fmt.Errorf("ages must be whole numbers")
+						}
+					}
+					return nil
+				}),
+			),
+		).Run()
+		if err != nil {
+			return cohortCriterion{}, err
+		}
+		c := cohortCriterion{resourceType: "Patient", query: neturl.Values{}}
+		minAge, minErr := strconv.Atoi(strings.TrimSpace(minStr))
+		maxAge, maxErr := strconv.Atoi(strings.TrimSpace(maxStr))
+		// Age at least min: born on or before now minus min years. Age at
+		// most max: born after now minus max+1 years.
+		if minErr == nil {
+			c.query.Add(fhir.SearchPatientBirthdate, "le"+now.AddDate(-minAge, 0, 0).Format("2006-01-02"))
+		}
+		if maxErr == nil {
+			c.query.Add(fhir.SearchPatientBirthdate, "gt"+now.AddDate(-(maxAge+1), 0, 0).Format("2006-01-02"))
+		}
+		switch {
+		case minErr == nil && maxErr == nil:
+			c.label = fmt.Sprintf("Age %d-%d", minAge, maxAge)
+		case minErr == nil:
+			c.label = fmt.Sprintf("Age %d+", minAge)
+		default:
+			c.label = fmt.Sprintf("Age up to %d", maxAge)
+		}
+		return c, nil
+	}
+}
+
+// BuildCohort builds a cohort from criteria on conditions, lab results, and
+// age. Each criterion is its own search; the matching patients are combined
+// client-side with AND, OR, or AND NOT, left to right, and the result can be
+// saved as a Group.
+func (a *App) BuildCohort() {
+	now := time.Now()
+	var criteria []cohortCriterion
+	for {
+		options := []huh.Option[string]{
+			huh.NewOption("Condition (ICD-10 code)", "condition"),
+			huh.NewOption("Lab result threshold", "lab"),
+			huh.NewOption("Age range", "age"),
+		}
+		if len(criteria) > 0 {
+			options = append(options, huh.NewOption(fmt.Sprintf("Done (%s)", cohortExpression(criteria)), "done"))
+		}
+		var kind string
+		err := huh.NewSelect[string]().
+			Title(fmt.Sprintf("Criterion %d", len(criteria)+1)).
+			Options(options...).
+			Value(&kind).
+			Run()
+		if err != nil {
+			if !isAbort(err) {
+				ShowError(err)
+				PressEnter()
+			}
+			return
+		}
+		if kind == "done" {
+			break
+		}
+
+		op := cohortAnd
+		if len(criteria) > 0 {
+			err = huh.NewSelect[string]().
+				Title("Combine with the criteria so far").
+				Options(
+					huh.NewOption("AND: patients matching both", cohortAnd),
+					huh.NewOption("OR: patients matching either", cohortOr),
+					huh.NewOption("AND NOT: remove patients matching this", cohortExcept),
+				).
+				Value(&op).
+				Run()
+		}
+		var c cohortCriterion
+		if err == nil {
+			c, err = askCohortCriterion(kind, now)
+		}
+		if err != nil {
+			if !isAbort(err) {
+				ShowError(err)
+				PressEnter()
+			}
+			return
+		}
+		c.op = op
+		criteria = append(criteria, c)
+	}
+
+	ctx := context.Background()
+	counts := make([]int, len(criteria))
+	var result patientSet
+	names := make(map[string]string)
+	var apiErr error
+	var elapsed time.Duration
+
+	err := runSpinner("Searching...", func(ctx context.Context) {
+		start := time.Now()
+		for i, c := range criteria {
+			set, err := a.matchPatients(ctx, c)
+			if err != nil {
+				apiErr = fmt.Errorf("%s: %w", c.label, err)
+				return
+			}
+			counts[i] = len(set)
+			if i == 0 {
+				result = set
+			} else {
+				result = result.combine(c.op, set)
+			}
+		}
+		var patients []json.RawMessage
+		patients, apiErr = a.fetchAllPatients(ctx)
+		for _, raw := range patients {
+			if m, err := fhir.Parse(raw); err == nil {
+				names[mapStr(m, "id")] = fhir.PatientName(m)
+			}
+		}
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+		PressEnter()
+		return
+	}
+
+	members := make([]string, 0, len(result))
+	for id := range result {
+		members = append(members, id)
+	}
+	sort.Slice(members, func(i, j int) bool { return names[members[i]] < names[members[j]] })
+
+	fmt.Println()
+	fmt.Println(barStyle.Bold(true).Render(cohortExpression(criteria)))
+	for i, c := range criteria {
+		op := ""
+		if i > 0 {
+			op = cohortOpLabels[c.op] + " "
+		}
+		fmt.Printf("  %-8s %-36s %d patients\n", op, c.label, counts[i])
+	}
+	fmt.Printf("\n  %d matching patients\n", len(members))
+	for _, id := range members {
+		fmt.Printf("    %s\n", cmp.Or(names[id], "Patient/"+id))
+	}
+	showTiming(fmt.Sprintf("Ran %d searches", len(criteria)+1), elapsed)
+	if len(members) == 0 {
+		PressEnter()
+		return
+	}
+
+	var name string
+	err = huh.NewInput().
+		Title("Save as cohort (blank to skip)").
+		Value(&name).
+		Run()
+	if err != nil || strings.TrimSpace(name) == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	group, _ := fhir.Parse(fhir.NewGroup(strings.TrimSpace(name), ""))
+	fhir.SetGroupMembers(group, members, names)
+	body, _ := json.Marshal(group)
+
+	var created json.RawMessage
+	err = runSpinner("Creating cohort...", func(ctx context.Context) {
+		created, apiErr = a.createResource(ctx, "Group", body)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(fmt.Errorf("creating cohort: %w", apiErr))
+		PressEnter()
+		return
+	}
+
+	id := fhir.ResourceID(created)
+	a.emit(ctx, EventGroupCreated, "Group", id, "")
+	fmt.Printf("\n  Created cohort %q with %d members (ID: %s)\n", strings.TrimSpace(name), len(members), id)
+	PressEnter()
+}
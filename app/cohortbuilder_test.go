@@ -0,0 +1,55 @@
+package app
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestPatientSetCombine(t *testing.T) {
+	set := func(ids ...string) patientSet {
+		s := make(patientSet)
+		for _, id := range ids {
+			s[id] = true
+		}
+		return s
+	}
+	a, b := set("p1", "p2", "p3"), set("p2", "p3", "p4")
+	tests := []struct {
+		op    string
+		left  patientSet
+		right patientSet
+		want  []string
+	}{
+		{cohortAnd, a, b, []string{"p2", "p3"}},
+		{cohortOr, a, b, []string{"p1", "p2", "p3", "p4"}},
+		{cohortExcept, a, b, []string{"p1"}},
+		{cohortExcept, b, a, []string{"p4"}},
+		{cohortAnd, a, set(), nil},
+		{cohortOr, set(), b, []string{"p2", "p3", "p4"}},
+		{cohortExcept, a, set(), []string{"p1", "p2", "p3"}},
+		{"xor", a, b, nil},
+	}
+	for _, tt := range tests {
+		got := slices.Sorted(maps.Keys(tt.left.combine(tt.op, tt.right)))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%v %s %v = %v, want %v", slices.Sorted(maps.Keys(tt.left)), tt.op, slices.Sorted(maps.Keys(tt.right)), got, tt.want)
+		}
+	}
+	if len(a) != 3 || len(b) != 3 {
+		t.Errorf("combine changed its operands: %v, %v", a, b)
+	}
+}
+
+func TestCohortExpression(t *testing.T) {
+	criteria := []cohortCriterion{
+		{label: "Condition E11.9"},
+		{op: cohortAnd, label: "HbA1c > 8"},
+		{op: cohortOr, label: "Age 65+"},
+		{op: cohortExcept, label: "Deceased"},
+	}
+	want := "((Condition E11.9 AND HbA1c > 8) OR Age 65+) AND NOT Deceased"
+	if got := cohortExpression(criteria); got != want {
+		t.Errorf("cohortExpression = %q, want %q", got, want)
+	}
+}
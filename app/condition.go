@@ -1,13 +1,16 @@
 package app
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/huh"
-	"github.com/charmbracelet/huh/spinner"
 	"github.com/phenoml/phenostore-example-go/fhir"
 )
 
@@ -22,33 +25,223 @@ func (a *App) RecordDiagnosis() {
 		return
 	}
 
-	var code, display string
-	form := huh.NewForm(
+	code, display, err := pickICD10()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	a.createCondition(patientID, code, display)
+}
+
+// icd10Matches is how many codes the ICD-10 picker lists at once.
+const icd10Matches = 30
+
+// pickICD10 searches the bundled ICD-10-CM subset by code or description as
+// the user types, and returns the picked code with a display name, which
+// starts as the code's description.
+func pickICD10() (code, display string, err error) {
+	var query string
+	err = huh.NewForm(huh.NewGroup(
+		huh.NewInput().
+			Title("Search ICD-10 (code or words, e.g., I10 or high cholesterol)").
+			Value(&query),
+		huh.NewSelect[string]().
+			Title("ICD-10 code").
+			OptionsFunc(func() []huh.Option[string] {
+				var options []huh.Option[string]
+				for _, c := range fhir.SearchICD10(query, icd10Matches) {
+					options = append(options, huh.NewOption(c.Code+"  "+c.Description, c.Code))
+				}
+				return options
+			}, &query).
+			Value(&code).
+			Validate(func(s string) error {
+				if _, ok := fhir.LookupICD10(s); !ok {
+					return fmt.Errorf("search for a code and pick one")
+				}
+				return nil
+			}),
+	)).Run()
+	if err != nil {
+		return "", "", err
+	}
+	c, _ := fhir.LookupICD10(code)
+	display = c.Description
+	err = huh.NewInput().
+		Title("Display name").
+		Value(&display).
+		Validate(func(s string) error {
+			if strings.TrimSpace(s) == "" {
+				return fmt.Errorf("required")
+			}
+			return nil
+		}).
+		Run()
+	return c.Code, strings.TrimSpace(display), err
+}
+
+// snomedConcept is a SNOMED CT concept: its ID and preferred term.
+type snomedConcept struct {
+	code string
+	term string
+}
+
+// snomedForICD10 maps the ICD-10-CM codes the demo uses (seed data and
+// diagnosis suggestions) to the SNOMED CT concepts offered alongside them.
+var snomedForICD10 = map[string]snomedConcept{
+	"I10":     {"38341003", "Hypertensive disorder, systemic arterial"},
+	"E11.9":   {"44054006", "Diabetes mellitus type 2"},
+	"E78.5":   {"55822004", "Hyperlipidemia"},
+	"E66.01":  {"238136002", "Morbid obesity"},
+	"E66.9":   {"414916001", "Obesity"},
+	"F41.1":   {"21897009", "Generalized anxiety disorder"},
+	"F32.A":   {"35489007", "Depressive disorder"},
+	"J06.9":   {"54150009", "Upper respiratory infection"},
+	"J02.9":   {"405737000", "Pharyngitis"},
+	"J20.9":   {"10509002", "Acute bronchitis"},
+	"J30.2":   {"21719001", "Allergic rhinitis due to pollen"},
+	"J45.909": {"195967001", "Asthma"},
+	"J45.990": {"31387002", "Exercise-induced asthma"},
+	"G43.909": {"37796009", "Migraine"},
+	"G47.00":  {"193462001", "Insomnia"},
+	"K21.9":   {"235595009", "Gastroesophageal reflux disease"},
+	"N18.3":   {"433144002", "Chronic kidney disease stage 3"},
+	"N18.30":  {"433144002", "Chronic kidney disease stage 3"},
+	"N39.0":   {"68566005", "Urinary tract infectious disease"},
+	"M54.50":  {"279039007", "Low back pain"},
+	"H10.9":   {"9826008", "Conjunctivitis"},
+	"H66.90":  {"65363002", "Otitis media"},
+}
+
+// snomedID matches a SNOMED CT concept ID: 6 to 18 digits, not starting
+// with 0.
+var snomedID = regexp.MustCompile(`^[1-9][0-9]{5,17}$`)
+
+// askSNOMED asks whether to code a diagnosis in SNOMED CT as well, and for
+// the concept. When the ICD-10 code has a known concept it is filled in and
+// the toggle starts on. It returns an empty code when the toggle is off.
+func askSNOMED(icd10Code string) (code, term string, err error) {
+	concept, known := snomedForICD10[strings.ToUpper(strings.TrimSpace(icd10Code))]
+	add := known
+	code, term = concept.code, concept.term
+	err = huh.NewForm(
 		huh.NewGroup(
-			huh.NewInput().Title("ICD-10 code (e.g., I10)").Value(&code),
-			huh.NewInput().Title("Display name (e.g., Hypertension)").Value(&display),
+			huh.NewConfirm().Title("Also code with SNOMED CT?").Value(&add),
 		),
-	)
+		huh.NewGroup(
+			huh.NewInput().
+				Title("SNOMED CT concept ID (e.g., 38341003)").
+				Value(&code).
+				Validate(func(s string) error {
+					if !snomedID.MatchString(strings.TrimSpace(s)) {
+						return fmt.Errorf("a concept ID is 6 to 18 digits")
+					}
+					return nil
+				}),
+			huh.NewInput().Title("SNOMED CT term (optional)").Value(&term),
+		).WithHideFunc(func() bool { return !add }),
+	).Run()
+	if err != nil || !add {
+		return "", "", err
+	}
+	return strings.TrimSpace(code), strings.TrimSpace(term), nil
+}
+
+// askLocalCode asks for the clinic's own code for a diagnosis, such as a
+// problem-list or billing code, recorded in fhir.LocalCodeSystem. It
+// returns "" when left blank.
+func askLocalCode() (string, error) {
+	var code string
+	err := huh.NewInput().
+		Title("Local code (optional)").
+		Value(&code).
+		Run()
+	return strings.TrimSpace(code), err
+}
 
-	if err := form.Run(); err != nil {
+// conditionDateLayout is how onset and abatement dates are entered.
+const conditionDateLayout = "2006-01-02"
+
+// askConditionDate asks for a date no later than today and, when notBefore is
+// set, no earlier than it. value is the initial input. It returns the zero
+// time when left blank.
+func askConditionDate(title string, value string, notBefore time.Time) (time.Time, error) {
+	err := huh.NewInput().
+		Title(title).
+		Value(&value).
+		Validate(func(s string) error {
+			if strings.TrimSpace(s) == "" {
+				return nil
+			}
+			t, err := time.ParseInLocation(conditionDateLayout, strings.TrimSpace(s), time.Local)
+			if err != nil {
+				return fmt.Errorf("use YYYY-MM-DD")
+			}
+			if t.After(time.Now()) {
+				return fmt.Errorf("cannot be in the future")
+			}
+			if t.Before(notBefore) {
+				return fmt.Errorf("cannot be before the onset, %s", notBefore.Format(conditionDateLayout))
+			}
+			return nil
+		}).
+		Run()
+	if err != nil || strings.TrimSpace(value) == "" {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation(conditionDateLayout, strings.TrimSpace(value), time.Local)
+}
+
+// createCondition asks for SNOMED CT and local codes to record alongside the
+// ICD-10 one and when the condition began, then records it for a patient and
+// reports the result.
+func (a *App) createCondition(patientID, code, display string) {
+	snomedCode, snomedTerm, err := askSNOMED(code)
+	if err != nil {
 		if !isAbort(err) {
 			ShowError(err)
 			PressEnter()
 		}
 		return
 	}
-
-	body := fhir.NewCondition(patientID, code, display)
+	onset, err := askConditionDate("Onset date (YYYY-MM-DD, blank if unknown)", "", time.Time{})
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	localCode, err := askLocalCode()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	var extra []fhir.Coding
+	if snomedCode != "" {
+		extra = append(extra, fhir.Coding{System: fhir.SNOMEDSystem, Code: snomedCode, Display: snomedTerm})
+	}
+	if localCode != "" {
+		extra = append(extra, fhir.Coding{System: fhir.LocalCodeSystem, Code: localCode, Display: display})
+	}
+	body := fhir.NewCondition(patientID, code, display, extra...)
+	if !onset.IsZero() {
+		body = fhir.WithOnset(body, onset)
+	}
 
 	var created json.RawMessage
 	var apiErr error
 
-	err = spinner.New().
-		Title("Recording diagnosis...").
-		Action(func() {
-			created, apiErr = a.Client.CreateResource(context.Background(), "Condition", body, nil)
-		}).
-		Run()
+	err = runSpinner("Recording diagnosis...", func(ctx context.Context) {
+		created, apiErr = a.createResource(ctx, "Condition", body)
+	})
 
 	if err != nil {
 		ShowError(err)
@@ -62,6 +255,13 @@ func (a *App) RecordDiagnosis() {
 	}
 
 	id := fhir.ResourceID(created)
+	a.emit(context.Background(), EventConditionCreated, "Condition", id, patientID)
+	if snomedCode != "" {
+		code += " / SNOMED " + snomedCode
+	}
+	if localCode != "" {
+		code += " / local " + localCode
+	}
 	fmt.Printf("\n  Recorded condition %s \u2014 %s (ID: %s)\n", code, display, id)
 	PressEnter()
 }
@@ -81,14 +281,11 @@ func (a *App) ViewDiagnoses() {
 	var fetchErr error
 	var elapsed time.Duration
 
-	err = spinner.New().
-		Title("Loading diagnoses...").
-		Action(func() {
-			start := time.Now()
-			conditions, fetchErr = a.searchByPatient(context.Background(), "Condition", patientID)
-			elapsed = time.Since(start)
-		}).
-		Run()
+	err = runSpinner("Loading diagnoses...", func(ctx context.Context) {
+		start := time.Now()
+		conditions, fetchErr = a.searchByPatient(ctx, "Condition", patientID, "")
+		elapsed = time.Since(start)
+	})
 
 	if err != nil {
 		ShowError(err)
@@ -110,3 +307,294 @@ func (a *App) ViewDiagnoses() {
 	}
 	PressEnter()
 }
+
+// ResolveCondition marks one of a patient's active conditions resolved, with
+// the date it abated.
+func (a *App) ResolveCondition() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	ctx := context.Background()
+	var conditions []json.RawMessage
+	var fetchErr error
+
+	err = runSpinner("Loading diagnoses...", func(ctx context.Context) {
+		conditions, fetchErr = a.searchByPatient(ctx, "Condition", patientID, "")
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	active := make(map[string]map[string]any)
+	var options []huh.Option[string]
+	for _, raw := range conditions {
+		m, err := fhir.Parse(raw)
+		if err != nil || !fhir.ConditionActive(m) {
+			continue
+		}
+		id := mapStr(m, "id")
+		active[id] = m
+		options = append(options, huh.NewOption(fhir.ConditionDisplay(m), id))
+	}
+	if len(options) == 0 {
+		fmt.Println("\n  No active conditions for this patient.")
+		PressEnter()
+		return
+	}
+
+	var conditionID string
+	err = huh.NewSelect[string]().
+		Title("Select condition to resolve").
+		Options(options...).
+		Value(&conditionID).
+		Run()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	condition := active[conditionID]
+	var onset time.Time
+	if s := mapStr(condition, "onsetDateTime"); len(s) >= 10 {
+		onset, _ = time.ParseInLocation(conditionDateLayout, s[:10], time.Local)
+	}
+	abated, err := askConditionDate("Abatement date (YYYY-MM-DD)", time.Now().Format(conditionDateLayout), onset)
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	if abated.IsZero() {
+		abated = time.Now()
+	}
+	fhir.WithResolved(condition, abated)
+
+	var apiErr error
+	err = runSpinner("Resolving condition...", func(ctx context.Context) {
+		body, _ := json.Marshal(condition)
+		if _, err := a.updateResource(ctx, "Condition", conditionID, body); err != nil {
+			apiErr = fmt.Errorf("updating condition: %w", err)
+		}
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+		PressEnter()
+		return
+	}
+
+	a.emit(ctx, EventConditionResolved, "Condition", conditionID, patientID)
+	fmt.Printf("\n  Resolved %s as of %s\n", fhir.ConditionDisplay(condition), abated.Format(conditionDateLayout))
+	PressEnter()
+}
+
+// EditCondition lets the user pick one of a patient's conditions and change
+// its name, its ICD-10 code, or its status, e.g. to mark a problem inactive
+// or a diagnosis recorded against the wrong patient entered-in-error. The
+// changed fields are shown for confirmation before the condition is updated.
+func (a *App) EditCondition() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	ctx := context.Background()
+	var conditions []json.RawMessage
+	var fetchErr error
+
+	err = runSpinner("Loading diagnoses...", func(ctx context.Context) {
+		conditions, fetchErr = a.searchByPatient(ctx, "Condition", patientID, "")
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	byID := make(map[string]json.RawMessage)
+	var options []huh.Option[string]
+	for _, raw := range conditions {
+		m, err := fhir.Parse(raw)
+		if err != nil || fhir.ConditionStatus(m) == "entered-in-error" {
+			continue
+		}
+		id := mapStr(m, "id")
+		byID[id] = raw
+		label := fhir.ConditionDisplay(m)
+		if status := fhir.ConditionStatus(m); status != "" && status != "active" {
+			label += " [" + status + "]"
+		}
+		options = append(options, huh.NewOption(label, id))
+	}
+	if len(options) == 0 {
+		fmt.Println("\n  No conditions for this patient.")
+		PressEnter()
+		return
+	}
+
+	var conditionID string
+	err = huh.NewSelect[string]().
+		Title("Select condition to edit").
+		Options(options...).
+		Value(&conditionID).
+		Run()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	before, _ := fhir.Parse(byID[conditionID])
+	after, _ := fhir.Parse(byID[conditionID])
+	code := fhir.ConditionICD10(before)
+	display := fhir.ConditionDisplay(before)
+	status := fhir.ConditionStatus(before)
+	if status == "" {
+		status = "active"
+	}
+
+	var recode bool
+	err = huh.NewConfirm().
+		Title(fmt.Sprintf("Change the ICD-10 code (%s)?", cmp.Or(code, "none"))).
+		Affirmative("Yes").
+		Negative("No").
+		Value(&recode).
+		Run()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	if recode {
+		if code, display, err = pickICD10(); err != nil {
+			if !isAbort(err) {
+				ShowError(err)
+				PressEnter()
+			}
+			return
+		}
+	}
+
+	statuses := fhir.ConditionStatuses
+	if !slices.Contains(statuses, status) {
+		statuses = append([]string{status}, statuses...)
+	}
+	err = huh.NewForm(huh.NewGroup(
+		huh.NewInput().
+			Title("Name").
+			Value(&display).
+			Validate(func(s string) error {
+				if strings.TrimSpace(s) == "" {
+					return fmt.Errorf("name is required")
+				}
+				return nil
+			}),
+		huh.NewSelect[string]().
+			Title("Status").
+			Description("Use inactive for a problem that no longer needs attention, entered-in-error if it should not have been recorded.").
+			Options(huh.NewOptions(statuses...)...).
+			Value(&status),
+	)).Run()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	display = strings.TrimSpace(display)
+	if recode {
+		fhir.WithConditionCode(after, code, display)
+	} else if display != fhir.ConditionDisplay(before) {
+		fhir.WithConditionText(after, display)
+	}
+	if status != fhir.ConditionStatus(before) {
+		fhir.WithConditionStatus(after, status)
+	}
+	changes := fhir.DiffResources(before, after)
+	if len(changes) == 0 {
+		fmt.Println("\n  No changes.")
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	fmt.Println(barStyle.Bold(true).Render(fhir.ConditionDisplay(before)))
+	fhir.PrintDiff(changes)
+	fmt.Println()
+
+	var confirm bool
+	err = huh.NewConfirm().
+		Title("Save these changes?").
+		Value(&confirm).
+		Run()
+	if err != nil || !confirm {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	body, _ := json.Marshal(after)
+	var apiErr error
+	err = runSpinner("Updating condition...", func(ctx context.Context) {
+		if _, err := a.updateResource(ctx, "Condition", conditionID, body); err != nil {
+			apiErr = fmt.Errorf("updating condition: %w", err)
+		}
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+		PressEnter()
+		return
+	}
+
+	a.emit(ctx, EventConditionUpdated, "Condition", conditionID, patientID)
+	fmt.Printf("\n  Updated %s (%s)\n", fhir.ConditionDisplay(after), status)
+	PressEnter()
+}
@@ -0,0 +1,21 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// The codes the app offers must be ones RecordDiagnosis accepts.
+func TestDiagnosisCodesAreBundled(t *testing.T) {
+	for _, entry := range icd10Index {
+		if _, ok := fhir.LookupICD10(entry.code); !ok {
+			t.Errorf("suggestion %s is not in the bundled ICD-10-CM codes", entry.code)
+		}
+	}
+	for code := range snomedForICD10 {
+		if _, ok := fhir.LookupICD10(code); !ok {
+			t.Errorf("SNOMED CT mapping for %s is not in the bundled ICD-10-CM codes", code)
+		}
+	}
+}
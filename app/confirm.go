@@ -0,0 +1,81 @@
+package app
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+)
+
+// confirmCodeTTL is how long a two-person confirmation code is accepted.
+const confirmCodeTTL = 5 * time.Minute
+
+// confirmCodePath is the file confirmation codes are written to, from
+// PHENOSTORE_CONFIRM_FILE. When it is empty, store-wide destructive actions
+// need only the usual yes/no confirmation.
+func confirmCodePath() string {
+	return os.Getenv("PHENOSTORE_CONFIRM_FILE")
+}
+
+// newConfirmCode returns a random six-digit code.
+func newConfirmCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// confirmWithCode requires a second person to approve a store-wide
+// destructive action when PHENOSTORE_CONFIRM_FILE is set. A one-time code is
+// appended to that file, which the person at the keyboard is not expected to
+// read, and must be typed back within confirmCodeTTL. It reports whether the
+// action may go ahead; a wrong or late code is an error.
+func confirmWithCode(action string) (bool, error) {
+	path := confirmCodePath()
+	if path == "" {
+		return true, nil
+	}
+
+	code, err := newConfirmCode()
+	if err != nil {
+		return false, fmt.Errorf("generating confirmation code: %w", err)
+	}
+	issued := time.Now()
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return false, fmt.Errorf("writing confirmation code: %w", err)
+	}
+	_, err = fmt.Fprintf(f, "%s  %s  code %s (valid until %s)\n",
+		issued.Format(time.RFC3339), action, code, issued.Add(confirmCodeTTL).Format("15:04:05"))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return false, fmt.Errorf("writing confirmation code: %w", err)
+	}
+
+	var entered string
+	err = huh.NewInput().
+		Title("Confirmation code").
+		Description(fmt.Sprintf("A second person must read the code written to %s.", path)).
+		Value(&entered).
+		Run()
+	if err != nil {
+		if isAbort(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	if time.Since(issued) > confirmCodeTTL {
+		return false, fmt.Errorf("confirmation code expired after %s; nothing was changed", confirmCodeTTL)
+	}
+	if strings.TrimSpace(entered) != code {
+		return false, fmt.Errorf("confirmation code did not match; nothing was changed")
+	}
+	return true, nil
+}
@@ -0,0 +1,329 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+	"github.com/phenoml/phenostore-sdk-go/phenostore/gen"
+)
+
+// consoleResourceType matches the resource type a console query starts with.
+var consoleResourceType = regexp.MustCompile(`^[A-Z][A-Za-z]+$`)
+
+// parseConsoleQuery splits a relative FHIR search such as
+// "Observation?code=4548-4&value-quantity=gt7" into its resource type and
+// parameters. A leading "GET" or "/" is ignored, so a query copied from a
+// log or the Ask a Question screen can be pasted as is.
+func parseConsoleQuery(text string) (string, neturl.Values, error) {
+	s := strings.TrimSpace(text)
+	s = strings.TrimSpace(strings.TrimPrefix(s, "GET "))
+	s = strings.TrimPrefix(s, "/")
+	resourceType, rawQuery, _ := strings.Cut(s, "?")
+	if strings.Contains(resourceType, "/") {
+		return "", nil, fmt.Errorf("%s is not a search; enter a resource type and parameters, e.g. Patient?name=ruiz", resourceType)
+	}
+	if !consoleResourceType.MatchString(resourceType) {
+		return "", nil, fmt.Errorf("start with a resource type, e.g. Observation?code=4548-4")
+	}
+	query, err := neturl.ParseQuery(rawQuery)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid query: %w", err)
+	}
+	return resourceType, query, nil
+}
+
+// consoleResult is one page of a console search as the server returned it,
+// less the entries hidden from restricted charts.
+type consoleResult struct {
+	Status  int
+	Body    []byte
+	Bundle  gen.Bundle
+	Elapsed time.Duration
+	Hidden  int // entries dropped by hideRestricted
+}
+
+// consoleSearch sends a search exactly as typed, or the page next points
+// to, and returns the response whatever its status, so errors the server
+// reports can be shown rather than summarized.
+func (a *App) consoleSearch(ctx context.Context, resourceType string, query neturl.Values, next string) (result consoleResult, err error) {
+	defer func() {
+		a.audit(ctx, fhir.AuditRead, "search-type", resourceType, resourceType, "", err)
+		a.logResultAccess(resourceType, extractResources(result.Bundle), err)
+	}()
+	start := time.Now()
+	resp, err := a.Client.Inner().SearchResourcesWithResponse(
+		ctx, a.Client.Tenant(), a.Client.Store(),
+		gen.ResourceType(resourceType), &gen.SearchResourcesParams{},
+		func(ctx context.Context, req *http.Request) error {
+			if next != "" {
+				u, err := neturl.Parse(next)
+				if err != nil {
+					return fmt.Errorf("invalid next link: %w", err)
+				}
+				req.URL.RawQuery = u.RawQuery
+				return nil
+			}
+			req.URL.RawQuery = query.Encode()
+			return nil
+		},
+	)
+	result.Elapsed = time.Since(start)
+	if err != nil {
+		return result, fmt.Errorf("searching %s: %w", resourceType, err)
+	}
+	result.Status, result.Body = resp.HTTPResponse.StatusCode, resp.Body
+	if result.Status < 400 {
+		if err := json.Unmarshal(resp.Body, &result.Bundle); err != nil {
+			return result, fmt.Errorf("parsing %s response: %w", resourceType, err)
+		}
+		if err := a.hideRestricted(ctx, &result); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// hideRestricted drops the entries of a console result that belong to a
+// restricted patient's chart, unless a break-the-glass reason for it is
+// still open. A search can match any number of patients, so the console
+// cannot ask for a reason for each; their charts are opened by picking the
+// patient instead.
+func (a *App) hideRestricted(ctx context.Context, result *consoleResult) error {
+	if result.Bundle.Entry == nil || len(*result.Bundle.Entry) == 0 {
+		return nil
+	}
+	restricted, err := a.restrictedPatients(ctx)
+	if err != nil || len(restricted) == 0 {
+		return err
+	}
+	now := time.Now()
+	var kept []gen.BundleEntry
+	for _, entry := range *result.Bundle.Entry {
+		if entry.Resource != nil {
+			if m, err := fhir.Parse(*entry.Resource); err == nil {
+				if id := chartPatient(m); restricted[id] && !a.session.glassOpen(id, now) {
+					result.Hidden++
+					continue
+				}
+			}
+		}
+		kept = append(kept, entry)
+	}
+	if result.Hidden == 0 {
+		return nil
+	}
+	result.Bundle.Entry = &kept
+	body, err := json.Marshal(result.Bundle)
+	if err != nil {
+		return fmt.Errorf("hiding restricted charts: %w", err)
+	}
+	result.Body = body
+	return nil
+}
+
+// outcomeMessages returns the diagnostics, or failing that the details
+// text, of each issue in an OperationOutcome.
+func outcomeMessages(body []byte) []string {
+	m, err := fhir.Parse(body)
+	if err != nil || mapStr(m, "resourceType") != "OperationOutcome" {
+		return nil
+	}
+	var msgs []string
+	issues, _ := m["issue"].([]any)
+	for _, v := range issues {
+		issue, _ := v.(map[string]any)
+		msg := mapStr(issue, "diagnostics")
+		if msg == "" {
+			msg, _ = fhir.Path(issue, "details.text").(string)
+		}
+		if msg != "" {
+			msgs = append(msgs, mapStr(issue, "severity")+": "+msg)
+		}
+	}
+	return msgs
+}
+
+// consoleEntryLabel describes a search result in a word or two, for the
+// resource types the app knows how to display.
+func consoleEntryLabel(m map[string]any) string {
+	switch mapStr(m, "resourceType") {
+	case "Patient":
+		return fhir.PatientName(m)
+	case "Observation":
+		return fhir.ObservationLabel(m)
+	case "Condition":
+		return fhir.ConditionDisplay(m)
+	case "CarePlan":
+		return mapStr(m, "title")
+	case "Flag":
+		s, _ := fhir.Path(m, "code.text").(string)
+		return s
+	case "NutritionOrder":
+		s, _ := fhir.Path(m, "oralDiet.type.text").(string)
+		return s
+	case "Device":
+		return fhir.DeviceDisplay(m)
+	case "ImagingStudy":
+		return fhir.ImagingStudyDisplay(m)
+	case "DocumentReference":
+		return fhir.DocumentReferenceDisplay(m)
+	case "EpisodeOfCare":
+		return fhir.EpisodeDisplay(m)
+	case "DetectedIssue":
+		return fhir.DetectedIssueDisplay(m)
+	case "Group":
+		return mapStr(m, "name")
+	}
+	return ""
+}
+
+// printConsoleResult shows a console search's status, then each entry of
+// the bundle with its search mode, then the match total and timing.
+func printConsoleResult(r consoleResult) {
+	fmt.Println(barStyle.Bold(true).Render(fmt.Sprintf("HTTP %d %s", r.Status, http.StatusText(r.Status))))
+	if r.Status >= 400 {
+		for _, msg := range outcomeMessages(r.Body) {
+			fmt.Printf("  %s\n", msg)
+		}
+		showTiming(fmt.Sprintf("Received %s", fhir.FormatBytes(int64(len(r.Body)))), r.Elapsed)
+		return
+	}
+
+	matches, included := 0, 0
+	if r.Bundle.Entry != nil {
+		for _, entry := range *r.Bundle.Entry {
+			if entry.Resource == nil {
+				continue
+			}
+			mode := "match"
+			if entry.Search != nil && entry.Search.Mode != nil {
+				mode = string(*entry.Search.Mode)
+			}
+			if mode == "include" {
+				included++
+			} else {
+				matches++
+			}
+			m, err := fhir.Parse(*entry.Resource)
+			if err != nil {
+				continue
+			}
+			line := fmt.Sprintf("  %-8s %s/%s", mode, mapStr(m, "resourceType"), mapStr(m, "id"))
+			if label := consoleEntryLabel(m); label != "" {
+				line += "  " + timingStyle.Render(label)
+			}
+			fmt.Println(line)
+		}
+	}
+	if matches+included == 0 {
+		fmt.Println("  No matches.")
+	}
+
+	count := fmt.Sprintf("%d matches", matches)
+	if included > 0 {
+		count += fmt.Sprintf(", %d included", included)
+	}
+	if r.Bundle.Total != nil {
+		count += fmt.Sprintf(" (total %d)", *r.Bundle.Total)
+	}
+	if r.Hidden > 0 {
+		fmt.Printf("  %d from restricted charts hidden; pick the patient to open one\n", r.Hidden)
+	}
+	fmt.Println()
+	showTiming(fmt.Sprintf("%s bundle, %s, %s", r.Bundle.Type, count, fhir.FormatBytes(int64(len(r.Body)))), r.Elapsed)
+}
+
+// SearchConsole runs FHIR searches typed as relative URLs, such as
+// Observation?code=4548-4&value-quantity=gt7, and shows the bundle the
+// server returns with its status, entry count, and timing. Parameter names
+// are checked first, as everywhere else in the app. Resources from
+// restricted charts are left out; see hideRestricted.
+func (a *App) SearchConsole() {
+	var text string
+	for {
+		err := huh.NewInput().
+			Title("FHIR search").
+			Description("A relative search URL, e.g. Observation?code=4548-4&value-quantity=gt7").
+			Value(&text).
+			Validate(func(s string) error {
+				rt, query, err := parseConsoleQuery(s)
+				if err != nil {
+					return err
+				}
+				return fhir.CheckSearchParams(rt, query)
+			}).
+			Run()
+		if err != nil {
+			if !isAbort(err) {
+				ShowError(err)
+				PressEnter()
+			}
+			return
+		}
+		resourceType, query, _ := parseConsoleQuery(text)
+
+		next := ""
+	pages:
+		for {
+			var result consoleResult
+			var apiErr error
+			err = runSpinner("Searching...", func(ctx context.Context) {
+				result, apiErr = a.consoleSearch(ctx, resourceType, query, next)
+			})
+			if err != nil {
+				ShowError(err)
+				PressEnter()
+				return
+			}
+			if apiErr != nil {
+				ShowError(apiErr)
+				break
+			}
+
+			fmt.Printf("\n  GET %s\n\n", strings.TrimSpace(text))
+			printConsoleResult(result)
+			next = nextLink(result.Bundle)
+
+			var options []huh.Option[string]
+			if next != "" {
+				options = append(options, huh.NewOption("Next page", "next"))
+			}
+			options = append(options,
+				huh.NewOption("Explore response JSON", "explore"),
+				huh.NewOption("New search", "new"),
+				huh.NewOption("\u2190 Back", "back"),
+			)
+			for {
+				var choice string
+				err = huh.NewSelect[string]().
+					Title("Next").
+					Options(options...).
+					Value(&choice).
+					Run()
+				if err != nil {
+					return
+				}
+				switch choice {
+				case "explore":
+					if err := exploreJSON("GET "+strings.TrimSpace(text), result.Body); err != nil {
+						ShowError(err)
+					}
+				case "next":
+					continue pages
+				case "new":
+					break pages
+				default:
+					return
+				}
+			}
+		}
+	}
+}
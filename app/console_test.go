@@ -0,0 +1,54 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"testing"
+)
+
+func TestParseConsoleQuery(t *testing.T) {
+	tests := []struct {
+		text, resourceType, query, wantErr string
+	}{
+		{"Observation?code=4548-4&value-quantity=gt7", "Observation", "code=4548-4&value-quantity=gt7", ""},
+		{"GET /Patient?name=ruiz", "Patient", "name=ruiz", ""},
+		{"Condition", "Condition", "", ""},
+		{"Patient/123", "", "", "Patient/123 is not a search; enter a resource type and parameters, e.g. Patient?name=ruiz"},
+		{"?name=ruiz", "", "", "start with a resource type, e.g. Observation?code=4548-4"},
+	}
+	for _, tt := range tests {
+		rt, query, err := parseConsoleQuery(tt.text)
+		if tt.wantErr != "" {
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("parseConsoleQuery(%q) error = %v, want %q", tt.text, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || rt != tt.resourceType || query.Encode() != tt.query {
+			t.Errorf("parseConsoleQuery(%q) = %s, %q, %v; want %s, %q", tt.text, rt, query.Encode(), err, tt.resourceType, tt.query)
+		}
+	}
+}
+
+func TestConsoleSearchError(t *testing.T) {
+	a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.RawQuery != "value-quantity=gt7" {
+			t.Errorf("query sent = %q", r.URL.RawQuery)
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"resourceType":"OperationOutcome","issue":[{"severity":"error","diagnostics":"value-quantity needs a unit"}]}`)
+	})
+
+	result, err := a.consoleSearch(context.Background(), "Observation", neturl.Values{"value-quantity": {"gt7"}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", result.Status)
+	}
+	if got := outcomeMessages(result.Body); len(got) != 1 || got[0] != "error: value-quantity needs a unit" {
+		t.Errorf("outcomeMessages = %q", got)
+	}
+}
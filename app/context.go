@@ -0,0 +1,122 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// LoadChartContext loads a patient's summary and flattens it into a
+// fhir.ChartContext. If deid is non-nil, every resource is de-identified first.
+func (a *App) LoadChartContext(ctx context.Context, patientID string, deid *fhir.Deidentifier) (*fhir.ChartContext, error) {
+	s, err := a.LoadSummary(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+	if deid != nil {
+		s.deidentify(deid)
+	}
+	c := fhir.BuildChartContext(time.Now(), s.Patient, s.Flags, s.Observations, s.Conditions, s.Plans)
+	return &c, nil
+}
+
+// ExportChartContext lets the user pick a patient and writes their chart as a
+// compact text or JSON context document for LLM pipelines.
+func (a *App) ExportChartContext() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	format := "text"
+	path := ""
+	var deidentify bool
+
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Format").
+				Options(
+					huh.NewOption("Plain text", "text"),
+					huh.NewOption("JSON", "json"),
+				).
+				Value(&format),
+			huh.NewInput().
+				Title("Output file (leave blank to print)").
+				Value(&path),
+			huh.NewConfirm().
+				Title("De-identify?").
+				Description("Replace the name and shift dates.").
+				Value(&deidentify),
+		),
+	)
+	if err := form.Run(); err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var deid *fhir.Deidentifier
+	if deidentify {
+		deid = newDeidentifier()
+	}
+
+	var chart *fhir.ChartContext
+	var apiErr error
+	var elapsed time.Duration
+
+	err = runSpinner("Building chart context...", func(ctx context.Context) {
+		start := time.Now()
+		chart, apiErr = a.LoadChartContext(ctx, patientID, deid)
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+		PressEnter()
+		return
+	}
+
+	var out []byte
+	if format == "json" {
+		out, err = json.MarshalIndent(chart, "", "  ")
+		if err != nil {
+			ShowError(err)
+			PressEnter()
+			return
+		}
+		out = append(out, '\n')
+	} else {
+		out = []byte(chart.Text())
+	}
+
+	fmt.Println()
+	if path == "" {
+		fmt.Print(string(out))
+	} else {
+		if err := os.WriteFile(path, out, 0o644); err != nil {
+			ShowError(fmt.Errorf("writing %s: %w", path, err))
+			PressEnter()
+			return
+		}
+		fmt.Printf("  Wrote %s\n", path)
+	}
+	showTiming(fmt.Sprintf("Built chart context (%d bytes)", len(out)), elapsed)
+	PressEnter()
+}
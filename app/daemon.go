@@ -0,0 +1,219 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// DaemonConfig controls headless care-gap reporting.
+type DaemonConfig struct {
+	Interval   time.Duration
+	ReportPath string
+	WebhookURL string
+	Deidentify bool // pseudonymize patients and shift dates in each report
+}
+
+// CareGapReport is the JSON document produced by each daemon run.
+type CareGapReport struct {
+	GeneratedAt     time.Time            `json:"generatedAt"`
+	Patients        int                  `json:"patients"`
+	ActivePlans     int                  `json:"activePlans"`
+	Overdue         []OverdueActivity    `json:"overdue"`
+	PatientsWithout []PatientWithoutPlan `json:"patientsWithoutActivePlan"`
+}
+
+// OverdueActivity is an incomplete care plan activity past its due date.
+type OverdueActivity struct {
+	PatientID   string `json:"patientId,omitempty"`
+	PatientName string `json:"patientName"`
+	Plan        string `json:"plan"`
+	Activity    string `json:"activity"`
+	Status      string `json:"status"`
+	Due         string `json:"due"`
+}
+
+// PatientWithoutPlan is a patient with no active care plan.
+type PatientWithoutPlan struct {
+	PatientID   string `json:"patientId,omitempty"`
+	PatientName string `json:"patientName"`
+}
+
+// RunDaemon evaluates care gaps on every interval until ctx is cancelled,
+// writing each report to cfg.ReportPath and/or POSTing it to cfg.WebhookURL.
+func (a *App) RunDaemon(ctx context.Context, cfg DaemonConfig) error {
+	if cfg.ReportPath == "" && cfg.WebhookURL == "" {
+		return fmt.Errorf("daemon needs a report path or webhook URL")
+	}
+	if cfg.Interval <= 0 {
+		return fmt.Errorf("daemon interval must be positive")
+	}
+
+	// One Deidentifier for the daemon's lifetime keeps pseudonyms and the
+	// date shift the same from one report to the next.
+	var deid *fhir.Deidentifier
+	if cfg.Deidentify {
+		deid = newDeidentifier()
+	}
+
+	log.Printf("daemon started (interval %s)", cfg.Interval)
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		a.runDaemonOnce(ctx, cfg, deid)
+		select {
+		case <-ctx.Done():
+			log.Printf("daemon stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *App) runDaemonOnce(ctx context.Context, cfg DaemonConfig, deid *fhir.Deidentifier) {
+	start := time.Now()
+	report, err := a.buildCareGapReport(ctx, start, nil)
+	if err != nil {
+		log.Printf("evaluating care gaps: %s", err)
+		return
+	}
+	if deid != nil {
+		report.deidentify(deid)
+	}
+	body, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		log.Printf("marshaling report: %s", err)
+		return
+	}
+
+	if cfg.ReportPath != "" {
+		if err := writeFileAtomic(cfg.ReportPath, body); err != nil {
+			log.Printf("writing report: %s", err)
+		}
+	}
+	if cfg.WebhookURL != "" {
+		if err := postWebhook(ctx, cfg.WebhookURL, body); err != nil {
+			log.Printf("posting report: %s", err)
+		}
+	}
+	if escalations, err := a.escalate(ctx, start, nil); err != nil {
+		log.Printf("escalating overdue activities: %s", err)
+	} else if escalations.Created > 0 || escalations.Closed > 0 {
+		log.Printf("escalated %d overdue activities, closed %d escalations", escalations.Created, escalations.Closed)
+	}
+	if _, err := a.recordStoreMetrics(ctx, start); err != nil {
+		log.Printf("recording store metrics: %s", err)
+	}
+	log.Printf("%d overdue activities, %d patients without an active plan (%s)",
+		len(report.Overdue), len(report.PatientsWithout), time.Since(start).Round(time.Millisecond))
+}
+
+// buildCareGapReport finds overdue activities on active care plans and
+// patients who have no active plan at all, among the patients in scope (all
+// of them when scope is nil).
+func (a *App) buildCareGapReport(ctx context.Context, now time.Time, scope cohortScope) (*CareGapReport, error) {
+	plans, err := a.searchAllPages(ctx, "CarePlan", 100, neturl.Values{fhir.SearchCarePlanStatus: {"active"}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	patients, err := a.fetchAllPatients(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plans, patients = scope.filter(plans), scope.filter(patients)
+
+	names := make(map[string]string)
+	for _, raw := range patients {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		names[mapStr(m, "id")] = fhir.PatientName(m)
+	}
+
+	report := &CareGapReport{GeneratedAt: now.UTC(), Patients: len(patients), ActivePlans: len(plans)}
+	covered := make(map[string]bool)
+	for _, raw := range plans {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		patientID := fhir.PatientRef(m)
+		covered[patientID] = true
+		name, ok := names[patientID]
+		if !ok {
+			name = a.resolvePatientName(ctx, patientID)
+			names[patientID] = name
+		}
+		dp := fhir.GetDashboardPlan(m, name)
+		for _, item := range dp.OverdueItems(now) {
+			report.Overdue = append(report.Overdue, OverdueActivity{
+				PatientID:   patientID,
+				PatientName: name,
+				Plan:        dp.Title,
+				Activity:    item.Description,
+				Status:      item.Status,
+				Due:         item.ScheduleNote,
+			})
+		}
+	}
+
+	for _, raw := range patients {
+		id := fhir.ResourceID(raw)
+		if id != "" && !covered[id] {
+			report.PatientsWithout = append(report.PatientsWithout, PatientWithoutPlan{
+				PatientID:   id,
+				PatientName: names[id],
+			})
+		}
+	}
+	return report, nil
+}
+
+// writeFileAtomic writes via a temp file and rename so readers never see a
+// partial report.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".report-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func postWebhook(ctx context.Context, url string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
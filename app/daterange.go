@@ -0,0 +1,139 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// DateRange limits observations to those taken from From up to, but not
+// including, To. A zero bound is open, and the zero DateRange is every date.
+type DateRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// LastDays is the range of the n days up to now.
+func LastDays(now time.Time, n int) DateRange {
+	return DateRange{From: now.AddDate(0, 0, -n)}
+}
+
+// All reports whether the range is every date.
+func (r DateRange) All() bool {
+	return r.From.IsZero() && r.To.IsZero()
+}
+
+// String describes the range, e.g. "since 2026-09-16" or "2026-01-01 to
+// 2026-03-31".
+func (r DateRange) String() string {
+	const day = "2006-01-02"
+	switch {
+	case r.All():
+		return "all dates"
+	case r.To.IsZero():
+		return "since " + r.From.Local().Format(day)
+	case r.From.IsZero():
+		return "through " + r.To.Local().AddDate(0, 0, -1).Format(day)
+	}
+	return r.From.Local().Format(day) + " to " + r.To.Local().AddDate(0, 0, -1).Format(day)
+}
+
+// query returns the Observation date parameters for the range, or nil for
+// every date.
+func (r DateRange) query() neturl.Values {
+	var dates []string
+	if !r.From.IsZero() {
+		dates = append(dates, "ge"+r.From.UTC().Format(time.RFC3339))
+	}
+	if !r.To.IsZero() {
+		dates = append(dates, "lt"+r.To.UTC().Format(time.RFC3339))
+	}
+	if dates == nil {
+		return nil
+	}
+	return neturl.Values{fhir.SearchObservationDate: dates}
+}
+
+// searchObservations returns a patient's observations taken within r,
+// newest first. With
+// a range, the server filters by date, so observations without one are
+// left out.
+func (a *App) searchObservations(ctx context.Context, patientID string, r DateRange) (observations []json.RawMessage, err error) {
+	if r.All() {
+		return a.searchByPatient(ctx, "Observation", patientID, sortNewest)
+	}
+	defer func() { a.audit(ctx, fhir.AuditRead, "search-type", "Observation", "Observation", patientID, err) }()
+	query := r.query()
+	query.Set(fhir.SearchObservationPatient, patientID)
+	return a.searchPages(ctx, "Observation", 100, 0, withSort(query, sortNewest), nil)
+}
+
+// askDateRange asks which observations to show: all of them, the last 7,
+// 30, or 90 days, or a custom range of dates.
+func askDateRange() (DateRange, error) {
+	const custom = -1
+	days := 0
+	var from, to string
+	parse := func(s string) (time.Time, error) {
+		return time.ParseInLocation("2006-01-02", strings.TrimSpace(s), time.Local)
+	}
+	optional := func(s string) error {
+		if strings.TrimSpace(s) == "" {
+			return nil
+		}
+		if _, err := parse(s); err != nil {
+			return fmt.Errorf("use YYYY-MM-DD")
+		}
+		return nil
+	}
+	err := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[int]().
+				Title("Dates").
+				Options(
+					huh.NewOption("All dates", 0),
+					huh.NewOption("Last 7 days", 7),
+					huh.NewOption("Last 30 days", 30),
+					huh.NewOption("Last 90 days", 90),
+					huh.NewOption("Custom range", custom),
+				).
+				Value(&days),
+		),
+		huh.NewGroup(
+			huh.NewInput().Title("From (YYYY-MM-DD, blank for the earliest)").Value(&from).Validate(optional),
+			huh.NewInput().Title("To (YYYY-MM-DD, blank for today)").Value(&to).Validate(func(s string) error {
+				if err := optional(s); err != nil {
+					return err
+				}
+				start, errFrom := parse(from)
+				end, errTo := parse(s)
+				if errFrom == nil && errTo == nil && end.Before(start) {
+					return fmt.Errorf("cannot be before the start date")
+				}
+				return nil
+			}),
+		).WithHideFunc(func() bool { return days != custom }),
+	).Run()
+	if err != nil {
+		return DateRange{}, err
+	}
+
+	switch days {
+	case 0:
+		return DateRange{}, nil
+	case custom:
+		var r DateRange
+		r.From, _ = parse(from)
+		if end, err := parse(to); err == nil {
+			r.To = end.AddDate(0, 0, 1)
+		}
+		return r, nil
+	}
+	return LastDays(time.Now(), days), nil
+}
@@ -0,0 +1,23 @@
+package app
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDateRange(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	if q := LastDays(now, 7).query(); q.Encode() != "date=ge2026-10-09T12%3A00%3A00Z" {
+		t.Errorf("last 7 days: %s", q.Encode())
+	}
+	r := DateRange{From: time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local), To: time.Date(2026, 4, 1, 0, 0, 0, 0, time.Local)}
+	if got := r.String(); got != "2026-01-01 to 2026-03-31" {
+		t.Errorf("String = %q", got)
+	}
+	if q := r.query(); len(q["date"]) != 2 {
+		t.Errorf("custom range: %v", q)
+	}
+	if q := (DateRange{}).query(); q != nil {
+		t.Errorf("all dates: %v", q)
+	}
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"encoding/json"
+	mrand "math/rand/v2"
+	"strings"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// newDeidentifier returns a Deidentifier that moves dates back by a random
+// 30 to 364 days, so shifted dates cannot be lined up with the calendar.
+func newDeidentifier() *fhir.Deidentifier {
+	return fhir.NewDeidentifier(-(30 + mrand.IntN(335)))
+}
+
+// deidentify replaces every resource in the summary with its de-identified
+// copy.
+func (s *Summary) deidentify(d *fhir.Deidentifier) {
+	s.Patient = d.Resource(s.Patient)
+	for _, list := range [][]json.RawMessage{s.Flags, s.Observations, s.Conditions, s.Plans, s.ImagingStudies, s.Goals} {
+		for i, raw := range list {
+			list[i] = d.Resource(raw)
+		}
+	}
+}
+
+// deidentify replaces the report's patient names with pseudonyms, drops
+// patient IDs, and shifts due dates. Counts are left as they are.
+func (r *CareGapReport) deidentify(d *fhir.Deidentifier) {
+	for i := range r.Overdue {
+		o := &r.Overdue[i]
+		o.PatientName = d.Pseudonym(o.PatientID)
+		o.PatientID = ""
+		o.Due = d.Date(o.Due)
+	}
+	for i := range r.PatientsWithout {
+		p := &r.PatientsWithout[i]
+		p.PatientName = d.Pseudonym(p.PatientID)
+		p.PatientID = ""
+	}
+}
+
+// deidentifyTables rewrites report tables for sharing outside the clinic:
+// "Patient" cells and patient references become pseudonyms, "Patient ID"
+// columns are dropped, and dates anywhere in the tables are shifted.
+// Pseudonyms are keyed on patient ID, taken from the row's "Patient ID"
+// cell or the reference, so a patient is named the same way in every
+// table. A "Patient" cell in a table with no IDs falls back to the name.
+func deidentifyTables(tables []reportTable, d *fhir.Deidentifier) []reportTable {
+	out := make([]reportTable, len(tables))
+	for i, t := range tables {
+		var keep []int
+		idCol := -1
+		dt := reportTable{Title: d.Date(t.Title)}
+		for j, h := range t.Headers {
+			if h == "Patient ID" {
+				idCol = j
+				continue
+			}
+			keep = append(keep, j)
+			dt.Headers = append(dt.Headers, h)
+		}
+		for _, row := range t.Rows {
+			var cells []string
+			for _, j := range keep {
+				if j >= len(row) {
+					continue
+				}
+				c := row[j]
+				switch {
+				case strings.HasPrefix(c, "Patient/"):
+					c = d.Pseudonym(strings.TrimPrefix(c, "Patient/"))
+				case t.Headers[j] == "Patient" && idCol >= 0 && idCol < len(row):
+					c = d.Pseudonym(row[idCol])
+				case t.Headers[j] == "Patient":
+					c = d.Pseudonym(c)
+				default:
+					c = d.Date(c)
+				}
+				cells = append(cells, c)
+			}
+			dt.Rows = append(dt.Rows, cells)
+		}
+		out[i] = dt
+	}
+	return out
+}
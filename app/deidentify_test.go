@@ -0,0 +1,58 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+func TestDeidentifyTables(t *testing.T) {
+	tables := []reportTable{{
+		Title:   "Registry",
+		Headers: []string{"Patient", "Patient ID", "Birth date", "Active conditions"},
+		Rows: [][]string{
+			{"Maria Garcia", "p1", "1980-03-15", "Hypertension"},
+			{"Maria Garcia", "p2", "1975-01-02", ""},
+		},
+	}, {
+		Title:   "By subject",
+		Headers: []string{"subject.reference", "Count"},
+		Rows:    [][]string{{"Patient/p2", "3"}, {"Patient/p1", "1"}},
+	}}
+
+	got := deidentifyTables(tables, fhir.NewDeidentifier(-10))
+	want := []reportTable{{
+		Title:   "Registry",
+		Headers: []string{"Patient", "Birth date", "Active conditions"},
+		Rows: [][]string{
+			{"Patient 001", "1980-03-05", "Hypertension"},
+			{"Patient 002", "1974-12-23", ""},
+		},
+	}, {
+		Title:   "By subject",
+		Headers: []string{"subject.reference", "Count"},
+		Rows:    [][]string{{"Patient 002", "3"}, {"Patient 001", "1"}},
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("deidentifyTables = %+v, want %+v", got, want)
+	}
+	if tables[0].Rows[0][0] != "Maria Garcia" {
+		t.Errorf("deidentifyTables changed its input: %v", tables[0].Rows[0])
+	}
+}
+
+func TestCareGapReportDeidentify(t *testing.T) {
+	r := &CareGapReport{
+		Overdue:         []OverdueActivity{{PatientID: "p1", PatientName: "Maria Garcia", Plan: "Diabetes", Due: "2026-05-10"}},
+		PatientsWithout: []PatientWithoutPlan{{PatientID: "p2", PatientName: "John Smith"}},
+	}
+	r.deidentify(fhir.NewDeidentifier(-10))
+
+	if got := r.Overdue[0]; got.PatientID != "" || got.PatientName != "Patient 001" || got.Due != "2026-04-30" || got.Plan != "Diabetes" {
+		t.Errorf("overdue = %+v", got)
+	}
+	if got := r.PatientsWithout[0]; got.PatientID != "" || got.PatientName != "Patient 002" {
+		t.Errorf("without a plan = %+v", got)
+	}
+}
@@ -0,0 +1,246 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// deviceKind is a home monitoring device type with its SNOMED CT code.
+type deviceKind struct {
+	code    string
+	display string
+}
+
+var deviceKinds = []deviceKind{
+	{code: "70665002", display: "Blood pressure cuff"},
+	{code: "337414009", display: "Blood glucose meter"},
+}
+
+// RegisterDevice lets the user pick a patient and register a home monitoring
+// device for them.
+func (a *App) RegisterDevice() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var options []huh.Option[int]
+	for i, k := range deviceKinds {
+		options = append(options, huh.NewOption(k.display, i))
+	}
+	options = append(options, huh.NewOption("Other (free text)", -1))
+
+	var kindIdx int
+	var serial string
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[int]().
+				Title("Device type").
+				Options(options...).
+				Value(&kindIdx),
+			huh.NewInput().Title("Serial number (optional)").Value(&serial),
+		),
+	)
+	if err := form.Run(); err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var kind deviceKind
+	if kindIdx >= 0 {
+		kind = deviceKinds[kindIdx]
+	} else {
+		if err := huh.NewInput().Title("Device name").Value(&kind.display).Run(); err != nil {
+			if !isAbort(err) {
+				ShowError(err)
+				PressEnter()
+			}
+			return
+		}
+	}
+
+	body := fhir.NewDevice(patientID, kind.code, kind.display, serial)
+
+	var created json.RawMessage
+	var apiErr error
+
+	err = runSpinner("Registering device...", func(ctx context.Context) {
+		created, apiErr = a.createResource(ctx, "Device", body)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(fmt.Errorf("creating device: %w", apiErr))
+		PressEnter()
+		return
+	}
+
+	id := fhir.ResourceID(created)
+	a.emit(context.Background(), EventDeviceCreated, "Device", id, patientID)
+	fmt.Printf("\n  Registered %s (ID: %s)\n", kind.display, id)
+	PressEnter()
+}
+
+// ViewDevices lets the user pick a patient and view their devices.
+func (a *App) ViewDevices() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var devices []json.RawMessage
+	var fetchErr error
+	var elapsed time.Duration
+
+	err = runSpinner("Loading devices...", func(ctx context.Context) {
+		start := time.Now()
+		devices, fetchErr = a.searchByPatient(ctx, "Device", patientID, "")
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	if len(devices) == 0 {
+		fmt.Println("  No devices registered.")
+	} else {
+		fhir.PrintDeviceList(devices)
+		showTiming(fmt.Sprintf("Fetched %d devices", len(devices)), elapsed)
+	}
+	PressEnter()
+}
+
+// ViewDeviceReadings lets the user pick a patient and one of their devices,
+// then shows only the observations that device recorded.
+func (a *App) ViewDeviceReadings() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	deviceID, err := a.pickDevice(patientID, false)
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	if deviceID == "" {
+		fmt.Println("\n  No devices registered for this patient.")
+		PressEnter()
+		return
+	}
+
+	var observations []json.RawMessage
+	var fetchErr error
+	var elapsed time.Duration
+
+	err = runSpinner("Loading readings...", func(ctx context.Context) {
+		start := time.Now()
+		observations, fetchErr = a.searchResources(ctx, "Observation", 100, map[string]string{
+			fhir.SearchObservationPatient: patientID,
+			fhir.SearchObservationDevice:  "Device/" + deviceID,
+			fhir.SearchSort:               sortNewest,
+		})
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	if len(observations) == 0 {
+		fmt.Println("  No readings from this device.")
+	} else {
+		fhir.PrintObservationList(observations)
+		showTiming(fmt.Sprintf("Fetched %d readings", len(observations)), elapsed)
+	}
+	PressEnter()
+}
+
+// pickDevice loads a patient's active devices and lets the user choose one.
+// It returns "" without prompting if the patient has none. With allowNone, a
+// "None" option is offered and also returns "".
+func (a *App) pickDevice(patientID string, allowNone bool) (string, error) {
+	var devices []json.RawMessage
+	var fetchErr error
+
+	err := runSpinner("Loading devices...", func(ctx context.Context) {
+		devices, fetchErr = a.searchByPatient(ctx, "Device", patientID, "")
+	})
+	if err != nil {
+		return "", err
+	}
+	if fetchErr != nil {
+		return "", fetchErr
+	}
+
+	var options []huh.Option[string]
+	for _, raw := range devices {
+		m, err := fhir.Parse(raw)
+		if err != nil || mapStr(m, "status") != "active" {
+			continue
+		}
+		label := fhir.DeviceDisplay(m)
+		if serial := mapStr(m, "serialNumber"); serial != "" {
+			label += " (" + serial + ")"
+		}
+		options = append(options, huh.NewOption(label, mapStr(m, "id")))
+	}
+	if len(options) == 0 {
+		return "", nil
+	}
+	if allowNone {
+		options = append(options, huh.NewOption("None (entered manually)", ""))
+	}
+
+	var deviceID string
+	err = huh.NewSelect[string]().
+		Title("Select device").
+		Options(options...).
+		Value(&deviceID).
+		Run()
+	return deviceID, err
+}
@@ -0,0 +1,322 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// vitalDraft is an observation parsed from dictation, awaiting confirmation.
+type vitalDraft struct {
+	label string
+	body  json.RawMessage
+}
+
+var (
+	numberUnits = map[string]int{
+		"zero": 0, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9,
+		"ten": 10, "eleven": 11, "twelve": 12, "thirteen": 13, "fourteen": 14, "fifteen": 15, "sixteen": 16,
+		"seventeen": 17, "eighteen": 18, "nineteen": 19,
+	}
+	numberTens = map[string]int{
+		"twenty": 20, "thirty": 30, "forty": 40, "fifty": 50, "sixty": 60, "seventy": 70, "eighty": 80, "ninety": 90,
+	}
+)
+
+// spokenNumber reads a number spelled out in words from the start of words,
+// returning it and the number of words used (0 if there is none). Besides
+// "one hundred forty two" and "one hundred and five" it understands the
+// clinical shorthand "one forty two", "one oh five", and decimals such as
+// "ninety eight point six".
+func spokenNumber(words []string) (float64, int) {
+	n, i := -1, 0
+	hundreds := false // n is a whole number of hundreds and takes a tens/units part
+	for ; i < len(words); i++ {
+		w := words[i]
+		if u, ok := numberUnits[w]; ok {
+			switch {
+			case n < 0:
+				n = u
+			case hundreds:
+				n, hundreds = n+u, false
+			case u < 10 && n >= 20 && n%10 == 0 && n%100 >= 20:
+				n += u
+			case u >= 10 && n > 0 && n < 10:
+				n = n*100 + u
+			default:
+				return finishNumber(words, n, i)
+			}
+			continue
+		}
+		if t, ok := numberTens[w]; ok {
+			switch {
+			case n < 0:
+				n = t
+			case hundreds:
+				n, hundreds = n+t, false
+			case n > 0 && n < 10:
+				n = n*100 + t
+			default:
+				return finishNumber(words, n, i)
+			}
+			continue
+		}
+		switch {
+		case w == "and" && hundreds && i+1 < len(words) && isNumberWord(words[i+1]):
+			// "one hundred and five"
+		case w == "hundred" && n > 0 && n < 10:
+			n, hundreds = n*100, true
+		case (w == "oh" || w == "o") && n > 0 && n < 10:
+			n, hundreds = n*100, true
+		default:
+			return finishNumber(words, n, i)
+		}
+	}
+	return finishNumber(words, n, i)
+}
+
+// isNumberWord reports whether w is a spelled-out number below 100.
+func isNumberWord(w string) bool {
+	_, unit := numberUnits[w]
+	_, tens := numberTens[w]
+	return unit || tens
+}
+
+// finishNumber reads an optional "point" and decimal digits after n.
+func finishNumber(words []string, n, i int) (float64, int) {
+	if n < 0 {
+		return 0, 0
+	}
+	value := float64(n)
+	if i+1 < len(words) && words[i] == "point" {
+		scale := 0.1
+		j := i + 1
+		for ; j < len(words); j++ {
+			d, ok := numberUnits[words[j]]
+			if !ok || d > 9 {
+				break
+			}
+			value += float64(d) * scale
+			scale /= 10
+		}
+		if j > i+1 {
+			i = j
+		}
+	}
+	return value, i
+}
+
+var dictationPunct = regexp.MustCompile(`[^a-z0-9./% ]+`)
+
+// digitizeDictation lowercases text and rewrites spelled-out numbers as
+// digits, so "BP one forty two over ninety one" becomes "bp 142 over 91".
+func digitizeDictation(text string) string {
+	text = strings.ToLower(text)
+	text = strings.ReplaceAll(text, "-", " ")
+	text = dictationPunct.ReplaceAllString(text, " ")
+	words := strings.Fields(text)
+	for i, w := range words {
+		words[i] = strings.TrimRight(w, ".")
+	}
+
+	var out []string
+	for i := 0; i < len(words); {
+		if v, n := spokenNumber(words[i:]); n > 0 {
+			out = append(out, strconv.FormatFloat(v, 'f', -1, 64))
+			i += n
+			continue
+		}
+		out = append(out, words[i])
+		i++
+	}
+	return strings.Join(out, " ")
+}
+
+var (
+	dictBP          = regexp.MustCompile(`\b(?:blood pressure|bp)\b\D{0,15}?(\d{2,3})\s*(?:over|/)\s*(\d{2,3})`)
+	dictPulse       = regexp.MustCompile(`\b(?:pulse|heart rate|hr)\b\D{0,15}?(\d{2,3})`)
+	dictRespiratory = regexp.MustCompile(`\b(?:respiratory rate|respirations|resp rate|rr)\b\D{0,15}?(\d{1,2})`)
+	dictSaturation  = regexp.MustCompile(`\b(?:oxygen saturation|o2 sat(?:uration)?|spo2|sats?|saturation)\b\D{0,15}?(\d{2,3})`)
+	dictTemperature = regexp.MustCompile(`\b(?:temperature|temp)\b\D{0,15}?(\d{2,3}(?:\.\d+)?)(?:\s*(?:degrees\s*)?(fahrenheit|celsius|f|c)\b)?`)
+	dictWeight      = regexp.MustCompile(`\bweight\b\D{0,15}?(\d{2,3}(?:\.\d+)?)(?:\s*(kilograms|kilos|kg|pounds|lbs|lb)\b)?`)
+	dictGlucose     = regexp.MustCompile(`\b(?:glucose|blood sugar|sugar)\b\D{0,15}?(\d{2,3})`)
+)
+
+// parseDictation extracts vital sign observations from a dictated snippet.
+// Temperatures without a unit are read as Fahrenheit above 45 and weights
+// without a unit as kilograms; both are stored in metric units.
+func parseDictation(patientID, text string) []vitalDraft {
+	t := digitizeDictation(text)
+	num := func(s string) float64 {
+		v, _ := strconv.ParseFloat(s, 64)
+		return v
+	}
+
+	var drafts []vitalDraft
+	if m := dictBP.FindStringSubmatch(t); m != nil {
+		sys, dia := int(num(m[1])), int(num(m[2]))
+		drafts = append(drafts, vitalDraft{fmt.Sprintf("Blood pressure %d/%d mmHg", sys, dia),
+			fhir.NewBloodPressureObservation(patientID, sys, dia)})
+	}
+	if m := dictPulse.FindStringSubmatch(t); m != nil {
+		bpm := int(num(m[1]))
+		drafts = append(drafts, vitalDraft{fmt.Sprintf("Heart rate %d bpm", bpm),
+			fhir.NewHeartRateObservation(patientID, bpm)})
+	}
+	if m := dictRespiratory.FindStringSubmatch(t); m != nil {
+		rate := int(num(m[1]))
+		drafts = append(drafts, vitalDraft{fmt.Sprintf("Respiratory rate %d /min", rate),
+			fhir.NewRespiratoryRateObservation(patientID, rate)})
+	}
+	if m := dictSaturation.FindStringSubmatch(t); m != nil {
+		pct := int(num(m[1]))
+		drafts = append(drafts, vitalDraft{fmt.Sprintf("O2 saturation %d%%", pct),
+			fhir.NewOxygenSaturationObservation(patientID, pct)})
+	}
+	if m := dictTemperature.FindStringSubmatch(t); m != nil {
+		v := num(m[1])
+		label := fmt.Sprintf("Temperature %.1f °C", v)
+		if m[2] == "fahrenheit" || m[2] == "f" || (m[2] == "" && v > 45) {
+			c := fhir.FahrenheitToCelsius(v)
+			label = fmt.Sprintf("Temperature %.1f °C (dictated %g °F)", c, v)
+			v = c
+		}
+		drafts = append(drafts, vitalDraft{label, fhir.NewTemperatureObservation(patientID, v)})
+	}
+	if m := dictWeight.FindStringSubmatch(t); m != nil {
+		v := num(m[1])
+		label := fmt.Sprintf("Weight %g kg", v)
+		if m[2] == "pounds" || m[2] == "lbs" || m[2] == "lb" {
+			kg := fhir.PoundsToKg(v)
+			label = fmt.Sprintf("Weight %.1f kg (dictated %g lb)", kg, v)
+			v = kg
+		}
+		drafts = append(drafts, vitalDraft{label, fhir.NewWeightObservation(patientID, v)})
+	}
+	if m := dictGlucose.FindStringSubmatch(t); m != nil {
+		v := num(m[1])
+		drafts = append(drafts, vitalDraft{fmt.Sprintf("Blood glucose %g mg/dL", v),
+			fhir.NewBloodGlucoseObservation(patientID, v)})
+	}
+	return drafts
+}
+
+// DictateVitals lets the user pick a patient and paste a dictated snippet,
+// then records the vital signs parsed from it after confirmation.
+func (a *App) DictateVitals() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var text string
+	var drafts []vitalDraft
+	var chosen []int
+	for {
+		err = huh.NewText().
+			Title("Dictation").
+			Placeholder("BP one forty two over ninety one, pulse seventy eight, temp ninety eight point six").
+			Value(&text).
+			Run()
+		if err != nil || strings.TrimSpace(text) == "" {
+			if err != nil && !isAbort(err) {
+				ShowError(err)
+				PressEnter()
+			}
+			return
+		}
+
+		drafts = parseDictation(patientID, text)
+		if len(drafts) == 0 {
+			fmt.Println("\n  No vital signs recognized in the dictation.")
+			PressEnter()
+			return
+		}
+
+		var options []huh.Option[int]
+		for i, d := range drafts {
+			options = append(options, huh.NewOption(d.label, i).Selected(true))
+		}
+		chosen = nil
+		err = huh.NewMultiSelect[int]().
+			Title("Record these observations?").
+			Options(options...).
+			Value(&chosen).
+			Run()
+		if err != nil || len(chosen) == 0 {
+			if err != nil && !isAbort(err) {
+				ShowError(err)
+				PressEnter()
+			}
+			return
+		}
+
+		// Speech recognition mishears numbers as easily as people mistype
+		// them, so dictated readings get the same range check.
+		var problems []string
+		for _, i := range chosen {
+			if m, err := fhir.Parse(drafts[i].body); err == nil {
+				problems = append(problems, a.implausibleObservation(m)...)
+			}
+		}
+		ok, err := confirmPlausible(problems...)
+		if err != nil {
+			if !isAbort(err) {
+				ShowError(err)
+				PressEnter()
+			}
+			return
+		}
+		if ok {
+			break
+		}
+	}
+
+	ctx := context.Background()
+	var ids []string
+	var issues []json.RawMessage
+	var apiErr, issueErr error
+
+	err = runSpinner("Recording observations...", func(ctx context.Context) {
+		var observations []json.RawMessage
+		defer func() { issues, issueErr = a.raiseIssues(ctx, observations) }()
+		for _, i := range chosen {
+			created, err := a.createResource(ctx, "Observation", drafts[i].body)
+			if err != nil {
+				apiErr = fmt.Errorf("creating %s: %w", drafts[i].label, err)
+				return
+			}
+			ids = append(ids, fhir.ResourceID(created))
+			observations = append(observations, created)
+		}
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	for _, id := range ids {
+		a.emit(ctx, EventObservationCreated, "Observation", id, patientID)
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+	}
+
+	fmt.Printf("\n  Recorded %d of %d observations from dictation\n", len(ids), len(chosen))
+	a.reportIssues(ctx, issues)
+	if issueErr != nil {
+		ShowError(issueErr)
+	}
+	PressEnter()
+}
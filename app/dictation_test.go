@@ -0,0 +1,39 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSpokenNumber(t *testing.T) {
+	tests := []struct {
+		words string
+		want  float64
+		used  int
+	}{
+		{"one hundred forty two", 142, 4},
+		{"one hundred and five", 105, 4},
+		{"one hundred and forty two over", 142, 5},
+		{"one hundred and", 100, 2},
+		{"one hundred and counting", 100, 2},
+		{"one forty two", 142, 3},
+		{"one oh five", 105, 3},
+		{"ninety eight point six", 98.6, 4},
+		{"seventy eight and", 78, 2},
+		{"pulse", 0, 0},
+	}
+	for _, tt := range tests {
+		got, used := spokenNumber(strings.Fields(tt.words))
+		if got != tt.want || used != tt.used {
+			t.Errorf("spokenNumber(%q) = %g, %d words; want %g, %d", tt.words, got, used, tt.want, tt.used)
+		}
+	}
+}
+
+func TestDigitizeDictation(t *testing.T) {
+	got := digitizeDictation("BP one hundred and forty over ninety, pulse seventy-eight and temp ninety eight point six.")
+	want := "bp 140 over 90 pulse 78 and temp 98.6"
+	if got != want {
+		t.Errorf("digitizeDictation = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,383 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// DigestConfig controls a weekly digest run.
+type DigestConfig struct {
+	OutDir string    // directory the digest is written to
+	Week   time.Time // any time in the week; the digest covers Monday to Sunday
+
+	// Deidentify lists patients by pseudonym. Dates are left alone: the
+	// digest only names the week and its days, never a patient's dates.
+	Deidentify bool
+}
+
+// weeklyDigest is a week of clinic activity, from store queries, the
+// AuditEvents the app wrote (when PHENOSTORE_AUDIT is set), and the local
+// store metrics file.
+type weeklyDigest struct {
+	Start time.Time // Monday, 00:00 local time
+
+	NewPatients      []string
+	Observations     [7]int // recorded each day, Monday first
+	ObservationKinds map[string]int
+	PlansCompleted   []string
+	GapsClosed       []string
+
+	Audited       bool // whether the app was writing AuditEvents
+	AuditByDay    [7]int
+	AuditByAction map[string]int
+	AuditFailures int
+
+	Growth []metricsSample // samples recorded during the week
+}
+
+// auditActionNames are the digest's names for AuditEvent actions.
+var auditActionNames = map[string]string{
+	fhir.AuditCreate:  "Creates",
+	fhir.AuditRead:    "Reads and searches",
+	fhir.AuditUpdate:  "Updates",
+	fhir.AuditDelete:  "Deletes",
+	fhir.AuditExecute: "Transactions",
+}
+
+// weekStart returns midnight on the Monday of t's week, in t's location.
+func weekStart(t time.Time) time.Time {
+	y, m, d := t.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// day returns which day of the digest's week an RFC 3339 timestamp falls
+// on, 0 for Monday, or ok=false if it is outside the week.
+func (d *weeklyDigest) day(ts string) (int, bool) {
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil || t.Before(d.Start) {
+		return 0, false
+	}
+	for i := range 7 {
+		if t.Before(d.Start.AddDate(0, 0, i+1)) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// buildDigest compiles the week starting at start, naming patients by
+// pseudonym when deidentify is set.
+func (a *App) buildDigest(ctx context.Context, start time.Time, deidentify bool) (*weeklyDigest, error) {
+	d := &weeklyDigest{Start: start, ObservationKinds: make(map[string]int), AuditByAction: make(map[string]int), Audited: a.Audit}
+	end := start.AddDate(0, 0, 7)
+	inWeek := func(param string, extra neturl.Values) neturl.Values {
+		q := neturl.Values{param: {"ge" + start.UTC().Format(time.RFC3339), "lt" + end.UTC().Format(time.RFC3339)}}
+		for k, v := range extra {
+			q[k] = v
+		}
+		return q
+	}
+	var deid *fhir.Deidentifier
+	if deidentify {
+		deid = newDeidentifier()
+	}
+	names := make(map[string]string)
+	patientName := func(id string) string {
+		if deid != nil {
+			return deid.Pseudonym(id)
+		}
+		if _, ok := names[id]; !ok {
+			names[id] = a.resolvePatientName(ctx, id)
+		}
+		return names[id]
+	}
+
+	// A patient last updated this week at version 1 was created this week.
+	// Servers that do not version resources report no versionId, and then
+	// the last update is the best there is.
+	patients, err := a.searchAllPages(ctx, "Patient", 100, inWeek(fhir.SearchLastUpdated, nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, raw := range patients {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		meta, _ := m["meta"].(map[string]any)
+		if v := mapStr(meta, "versionId"); v == "" || v == "1" {
+			name := fhir.PatientName(m)
+			if deid != nil {
+				name = deid.Pseudonym(mapStr(m, "id"))
+			}
+			d.NewPatients = append(d.NewPatients, name)
+		}
+	}
+	sort.Strings(d.NewPatients)
+
+	observations, err := a.searchAllPages(ctx, "Observation", 100, inWeek(fhir.SearchLastUpdated, nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, raw := range observations {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		meta, _ := m["meta"].(map[string]any)
+		if i, ok := d.day(mapStr(meta, "lastUpdated")); ok {
+			d.Observations[i]++
+		}
+		code, _ := m["code"].(map[string]any)
+		kind := mapStr(code, "text")
+		if kind == "" {
+			kind = "Other"
+		}
+		d.ObservationKinds[kind]++
+	}
+
+	plans, err := a.searchAllPages(ctx, "CarePlan", 100, inWeek(fhir.SearchLastUpdated, neturl.Values{fhir.SearchCarePlanStatus: {"completed"}}), nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, raw := range plans {
+		if m, err := fhir.Parse(raw); err == nil {
+			d.PlansCompleted = append(d.PlansCompleted, fmt.Sprintf("%s — %s", mapStr(m, "title"), patientName(fhir.PatientRef(m))))
+		}
+	}
+
+	gapQuery := escalationQuery()
+	gapQuery.Set(fhir.SearchTaskStatus, "completed")
+	tasks, err := a.searchAllPages(ctx, "Task", 100, inWeek(fhir.SearchLastUpdated, gapQuery), nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, raw := range tasks {
+		if m, err := fhir.Parse(raw); err == nil {
+			d.GapsClosed = append(d.GapsClosed, fmt.Sprintf("%s — %s", mapStr(m, "description"), patientName(fhir.TaskPatient(m))))
+		}
+	}
+
+	if a.Audit {
+		events, err := a.searchAllPages(ctx, "AuditEvent", 200, inWeek(fhir.SearchAuditEventDate, nil), nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, raw := range events {
+			m, err := fhir.Parse(raw)
+			if err != nil {
+				continue
+			}
+			if i, ok := d.day(mapStr(m, "recorded")); ok {
+				d.AuditByDay[i]++
+			}
+			d.AuditByAction[mapStr(m, "action")]++
+			if mapStr(m, "outcome") != "0" {
+				d.AuditFailures++
+			}
+		}
+	}
+
+	samples, err := loadMetrics(metricsPath())
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range samples {
+		if s.Date >= start.Format("2006-01-02") && s.Date < end.Format("2006-01-02") {
+			d.Growth = append(d.Growth, s)
+		}
+	}
+	return d, nil
+}
+
+// digestBarWidth is the length of the longest bar in a digest chart.
+const digestBarWidth = 30
+
+// barChart draws a horizontal bar chart as text, one labelled row per value,
+// scaled so the largest value is digestBarWidth long.
+func barChart(labels []string, values []int) string {
+	most, labelWidth := 0, 0
+	for i, v := range values {
+		most = max(most, v)
+		labelWidth = max(labelWidth, len(labels[i]))
+	}
+	var b strings.Builder
+	for i, v := range values {
+		bar := 0
+		if most > 0 {
+			bar = (v*digestBarWidth + most - 1) / most
+		}
+		fmt.Fprintf(&b, "%-*s  %s %d\n", labelWidth, labels[i], strings.Repeat("█", bar), v)
+	}
+	return b.String()
+}
+
+// dayLabels labels the days of the digest's week, e.g. "Mon 12 Oct".
+func (d *weeklyDigest) dayLabels() []string {
+	labels := make([]string, 7)
+	for i := range labels {
+		labels[i] = d.Start.AddDate(0, 0, i).Format("Mon 02 Jan")
+	}
+	return labels
+}
+
+func sum(values []int) int {
+	n := 0
+	for _, v := range values {
+		n += v
+	}
+	return n
+}
+
+// markdown renders the digest as a Markdown document.
+func (d *weeklyDigest) markdown(generated time.Time) []byte {
+	var b bytes.Buffer
+	chart := func(labels []string, values []int) {
+		b.WriteString("```text\n" + barChart(labels, values) + "```\n")
+	}
+	list := func(items []string) {
+		if len(items) == 0 {
+			b.WriteString("None.\n")
+		}
+		for _, item := range items {
+			b.WriteString("- " + item + "\n")
+		}
+	}
+	last := d.Start.AddDate(0, 0, 6)
+
+	fmt.Fprintf(&b, "# Clinic digest: week of %s\n\n", d.Start.Format("2 January 2006"))
+	fmt.Fprintf(&b, "Monday %s to Sunday %s. Generated %s.\n", d.Start.Format("2 Jan"), last.Format("2 Jan 2006"), generated.Format("2006-01-02 15:04 MST"))
+
+	b.WriteString("\n## At a glance\n\n| | This week |\n|---|---|\n")
+	fmt.Fprintf(&b, "| New patients | %d |\n", len(d.NewPatients))
+	fmt.Fprintf(&b, "| Observations recorded | %d |\n", sum(d.Observations[:]))
+	fmt.Fprintf(&b, "| Care plans completed | %d |\n", len(d.PlansCompleted))
+	fmt.Fprintf(&b, "| Care gaps closed | %d |\n", len(d.GapsClosed))
+
+	b.WriteString("\n## Observations recorded\n\n")
+	chart(d.dayLabels(), d.Observations[:])
+	if len(d.ObservationKinds) > 0 {
+		kinds := make([]string, 0, len(d.ObservationKinds))
+		for k := range d.ObservationKinds {
+			kinds = append(kinds, k)
+		}
+		sort.Slice(kinds, func(i, j int) bool {
+			if d.ObservationKinds[kinds[i]] != d.ObservationKinds[kinds[j]] {
+				return d.ObservationKinds[kinds[i]] > d.ObservationKinds[kinds[j]]
+			}
+			return kinds[i] < kinds[j]
+		})
+		b.WriteString("\n| Kind | Recorded |\n|---|---|\n")
+		for _, k := range kinds {
+			fmt.Fprintf(&b, "| %s | %d |\n", strings.ReplaceAll(k, "|", `\|`), d.ObservationKinds[k])
+		}
+	}
+
+	b.WriteString("\n## New patients\n\n")
+	list(d.NewPatients)
+	b.WriteString("\n## Care plans completed\n\n")
+	list(d.PlansCompleted)
+	b.WriteString("\n## Care gaps closed\n\n")
+	list(d.GapsClosed)
+
+	b.WriteString("\n## Audit log\n\n")
+	if !d.Audited {
+		b.WriteString("Auditing was off. Set `PHENOSTORE_AUDIT=true` to include the app's reads and writes.\n")
+	} else {
+		chart(d.dayLabels(), d.AuditByDay[:])
+		b.WriteString("\n| Action | Events |\n|---|---|\n")
+		for _, action := range []string{fhir.AuditCreate, fhir.AuditRead, fhir.AuditUpdate, fhir.AuditDelete, fhir.AuditExecute} {
+			fmt.Fprintf(&b, "| %s | %d |\n", auditActionNames[action], d.AuditByAction[action])
+		}
+		fmt.Fprintf(&b, "| Failed | %d |\n", d.AuditFailures)
+	}
+
+	b.WriteString("\n## Store growth\n\n")
+	if len(d.Growth) == 0 {
+		fmt.Fprintf(&b, "No samples in %s this week. Run **Store Growth** or daemon mode to record them.\n", metricsPath())
+	} else {
+		labels := make([]string, len(d.Growth))
+		totals := make([]int, len(d.Growth))
+		for i, s := range d.Growth {
+			labels[i] = s.Date
+			totals[i] = s.total()
+		}
+		b.WriteString("Resources in the store, per sample.\n\n")
+		chart(labels, totals)
+	}
+	return b.Bytes()
+}
+
+// WriteDigest compiles a week's activity into a Markdown digest in
+// cfg.OutDir and returns its path.
+func (a *App) WriteDigest(ctx context.Context, cfg DigestConfig) (string, error) {
+	start := weekStart(cfg.Week)
+	d, err := a.buildDigest(ctx, start, cfg.Deidentify)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(cfg.OutDir, 0o755); err != nil {
+		return "", fmt.Errorf("creating output directory: %w", err)
+	}
+	path := filepath.Join(cfg.OutDir, "digest-"+start.Format("2006-01-02")+".md")
+	if err := writeFileAtomic(path, d.markdown(time.Now())); err != nil {
+		return "", fmt.Errorf("writing %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// WeeklyDigest writes the digest for this week or last week to the current
+// directory.
+func (a *App) WeeklyDigest() {
+	now := time.Now()
+	week := now.AddDate(0, 0, -7)
+	err := huh.NewSelect[time.Time]().
+		Title("Weekly Digest").
+		Options(
+			huh.NewOption("Last week", week),
+			huh.NewOption("This week so far", now),
+		).
+		Value(&week).
+		Run()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var path string
+	var apiErr error
+	var elapsed time.Duration
+	err = runSpinner("Compiling digest...", func(ctx context.Context) {
+		start := time.Now()
+		path, apiErr = a.WriteDigest(ctx, DigestConfig{OutDir: ".", Week: week})
+		elapsed = time.Since(start)
+	})
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Printf("\n  Wrote %s\n", path)
+	showTiming("Compiled weekly digest", elapsed)
+	PressEnter()
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWeekStart(t *testing.T) {
+	for _, day := range []int{12, 14, 18} { // Monday, Wednesday, Sunday
+		got := weekStart(time.Date(2026, 10, day, 15, 30, 0, 0, time.UTC))
+		if want := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+			t.Errorf("weekStart(Oct %d) = %s, want %s", day, got, want)
+		}
+	}
+
+	d := &weeklyDigest{Start: time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)}
+	if i, ok := d.day("2026-10-14T09:00:00Z"); !ok || i != 2 {
+		t.Errorf("day(Wednesday) = %d, %v", i, ok)
+	}
+	if _, ok := d.day("2026-10-19T00:00:00Z"); ok {
+		t.Error("the next Monday is in the week")
+	}
+}
+
+func TestBarChart(t *testing.T) {
+	got := barChart([]string{"Mon", "Tuesday"}, []int{10, 5})
+	want := "Mon      " + strings.Repeat("█", 30) + " 10\nTuesday  " + strings.Repeat("█", 15) + " 5\n"
+	if got != want {
+		t.Errorf("barChart =\n%s\nwant\n%s", got, want)
+	}
+	if got := barChart([]string{"Mon"}, []int{0}); got != "Mon   0\n" {
+		t.Errorf("barChart of zero = %q", got)
+	}
+}
+
+func TestDigestMarkdown(t *testing.T) {
+	d := &weeklyDigest{
+		Start:            time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC),
+		NewPatients:      []string{"Maria Garcia"},
+		Observations:     [7]int{3, 0, 1},
+		ObservationKinds: map[string]int{"Blood pressure": 3, "Body weight": 1},
+		GapsClosed:       []string{"Overdue: HbA1c test (Diabetes management) — Maria Garcia"},
+	}
+	md := string(d.markdown(time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)))
+	for _, want := range []string{
+		"# Clinic digest: week of 12 October 2026",
+		"| Observations recorded | 4 |",
+		"| Care gaps closed | 1 |",
+		"Mon 12 Oct  " + strings.Repeat("█", 30) + " 3",
+		"| Blood pressure | 3 |\n| Body weight | 1 |",
+		"## Care plans completed\n\nNone.",
+		"Auditing was off.",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("digest is missing %q:\n%s", want, md)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// inEncounter keeps the resources recorded in an encounter. An empty
+// encounterID keeps everything.
+func inEncounter(resources []json.RawMessage, encounterID string) []json.RawMessage {
+	if encounterID == "" {
+		return resources
+	}
+	var out []json.RawMessage
+	for _, raw := range resources {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		if ref, _ := fhir.Path(m, "encounter.reference").(string); ref == "Encounter/"+encounterID {
+			out = append(out, raw)
+		}
+	}
+	return out
+}
+
+// GenerateVisitSummary lets the user pick a patient (and encounter, if any
+// were recorded), creates a Composition over their problems, results, and
+// plan, and retrieves the assembled document with Composition/$document.
+func (a *App) GenerateVisitSummary() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	ctx := context.Background()
+	var summary *Summary
+	var encounters []json.RawMessage
+	var fetchErr error
+
+	err = runSpinner("Loading chart...", func(ctx context.Context) {
+		summary, fetchErr = a.LoadSummary(ctx, patientID)
+		if fetchErr == nil {
+			encounters, fetchErr = a.searchByPatient(ctx, "Encounter", patientID, "")
+		}
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	encounterID := ""
+	if len(encounters) > 0 {
+		encounterID, err = pickEncounter("Summarize encounter", "Whole chart", encounters)
+		if err != nil {
+			if !isAbort(err) {
+				ShowError(err)
+				PressEnter()
+			}
+			return
+		}
+	}
+
+	title := "Visit Summary"
+	path := ""
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().Title("Title").Value(&title),
+			huh.NewInput().Title("Save document JSON to (leave blank to skip)").Value(&path),
+		),
+	)
+	if err := form.Run(); err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	observations := inEncounter(summary.Observations, encounterID)
+	conditions := inEncounter(summary.Conditions, encounterID)
+	plans := inEncounter(summary.Plans, encounterID)
+	encounterRef := ""
+	if encounterID != "" {
+		encounterRef = "Encounter/" + encounterID
+	}
+	sections := fhir.VisitSummarySections(observations, conditions, plans)
+
+	var compositionID string
+	var document json.RawMessage
+	var method string
+	var apiErr error
+	var elapsed time.Duration
+
+	err = runSpinner("Generating visit summary...", func(ctx context.Context) {
+		start := time.Now()
+		created, err := a.createResource(ctx, "Composition",
+			fhir.NewComposition(patientID, encounterRef, title, sections, time.Now()))
+		if err != nil {
+			apiErr = fmt.Errorf("creating composition: %w", err)
+			return
+		}
+		compositionID = fhir.ResourceID(created)
+
+		document, err = a.invokeOperation(ctx, "Composition", compositionID, "$document", nil)
+		if m, perr := fhir.Parse(document); err == nil && perr == nil && mapStr(m, "type") == "document" {
+			method = "$document"
+		} else {
+			method = "assembled locally"
+			included := append([]json.RawMessage{summary.Patient}, observations...)
+			included = append(included, conditions...)
+			included = append(included, plans...)
+			document = fhir.DocumentBundle(created, included, time.Now())
+		}
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+		PressEnter()
+		return
+	}
+	a.emit(ctx, EventCompositionCreated, "Composition", compositionID, patientID)
+
+	fmt.Println()
+	if err := fhir.PrintDocument(document); err != nil {
+		ShowError(err)
+	}
+	if path != "" {
+		var pretty any
+		_ = json.Unmarshal(document, &pretty)
+		out, _ := json.MarshalIndent(pretty, "", "  ")
+		if err := os.WriteFile(path, append(out, '\n'), 0o644); err != nil {
+			ShowError(fmt.Errorf("writing %s: %w", path, err))
+		} else {
+			fmt.Printf("  Wrote %s\n", path)
+		}
+	}
+	showTiming(fmt.Sprintf("Created Composition %s and retrieved document (%s)", compositionID, method), elapsed)
+	PressEnter()
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	neturl "net/url"
+	"strings"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// patientListElements are the Patient elements the patient picker and
+// patient list show. meta carries the restricted label.
+var patientListElements = []string{"meta", "name", "gender", "birthDate"}
+
+// patientSizeSample is how many full Patient resources are fetched, once a
+// session, to estimate what a projected list saved.
+const patientSizeSample = 10
+
+// withElements adds an _elements projection to query, so the server returns
+// only those elements (and id) of each resource.
+func withElements(query neturl.Values, elements []string) neturl.Values {
+	if query == nil {
+		query = neturl.Values{}
+	}
+	query.Set(fhir.SearchElements, strings.Join(elements, ","))
+	return query
+}
+
+// payloadSize is the size of resources as JSON, not counting the bundle
+// around them.
+func payloadSize(resources []json.RawMessage) int {
+	n := 0
+	for _, raw := range resources {
+		n += len(raw)
+	}
+	return n
+}
+
+// fetchPatientList returns every patient with only the elements lists show.
+func (a *App) fetchPatientList(ctx context.Context) (patients []json.RawMessage, err error) {
+	defer func() {
+		a.audit(ctx, fhir.AuditRead, "search-type", "Patient", "Patient", "", err)
+		a.logResultAccess("Patient", patients, err)
+	}()
+	return a.searchPages(ctx, "Patient", 100, 0, withElements(nil, patientListElements), nil)
+}
+
+// fullPatientSize returns the average size of a full Patient resource,
+// measured from a small sample the first time it is needed in a session.
+// It returns 0 if the sample could not be fetched.
+func (a *App) fullPatientSize(ctx context.Context) int {
+	if n := a.session.patientSize(); n > 0 {
+		return n
+	}
+	sample, err := a.searchPages(ctx, "Patient", patientSizeSample, patientSizeSample, nil, nil)
+	a.audit(ctx, fhir.AuditRead, "search-type", "Patient", "Patient", "", err)
+	a.logResultAccess("Patient", sample, err)
+	if err != nil || len(sample) == 0 {
+		return 0
+	}
+	n := payloadSize(sample) / len(sample)
+	a.session.setPatientSize(n)
+	return n
+}
+
+// elementsSaving describes the payload of n patients fetched with
+// _elements, and how much smaller it was than n full resources of
+// fullSize bytes each, e.g. "9.1 KB with _elements, about 22.4 KB (71%)
+// less than full resources".
+func elementsSaving(n, projected, fullSize int) string {
+	s := fhir.FormatBytes(int64(projected)) + " with _elements"
+	full := n * fullSize
+	if fullSize == 0 || full <= projected {
+		return s
+	}
+	saved := full - projected
+	return fmt.Sprintf("%s, about %s (%d%%) less than full resources", s, fhir.FormatBytes(int64(saved)), saved*100/full)
+}
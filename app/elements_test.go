@@ -0,0 +1,12 @@
+package app
+
+import "testing"
+
+func TestElementsSaving(t *testing.T) {
+	if got := elementsSaving(10, 2048, 800); got != "2.0 KB with _elements, about 5.8 KB (74%) less than full resources" {
+		t.Errorf("elementsSaving = %q", got)
+	}
+	if got := elementsSaving(10, 2048, 0); got != "2.0 KB with _elements" {
+		t.Errorf("elementsSaving with no sample = %q", got)
+	}
+}
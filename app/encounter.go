@@ -0,0 +1,138 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// RecordEncounter records a finished visit as an Encounter, optionally
+// linked to one of the patient's active episodes of care. Encounters are
+// what visit summaries, claims, and episode timelines are built around.
+func (a *App) RecordEncounter() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var episodes []json.RawMessage
+	var fetchErr error
+	err = runSpinner("Loading episodes of care...", func(ctx context.Context) {
+		episodes, fetchErr = a.searchByPatient(ctx, "EpisodeOfCare", patientID, "")
+	})
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	var classIdx int
+	var classOptions []huh.Option[int]
+	for i, c := range fhir.EncounterClasses {
+		classOptions = append(classOptions, huh.NewOption(fmt.Sprintf("%s (%s)", c.Display, c.Code), i))
+	}
+	text := "Office visit"
+	startStr := time.Now().Format(measuredAtLayout)
+	lengthStr := "20"
+	var reason string
+
+	fields := []huh.Field{
+		huh.NewSelect[int]().Title("Class").Options(classOptions...).Value(&classIdx),
+		huh.NewInput().Title("Visit type").Value(&text).Validate(func(s string) error {
+			if strings.TrimSpace(s) == "" {
+				return fmt.Errorf("required")
+			}
+			return nil
+		}),
+		huh.NewInput().Title("Started (YYYY-MM-DD HH:MM)").Value(&startStr).Validate(func(s string) error {
+			t, err := time.ParseInLocation(measuredAtLayout, strings.TrimSpace(s), time.Local)
+			if err != nil {
+				return fmt.Errorf("use YYYY-MM-DD HH:MM")
+			}
+			if t.After(time.Now()) {
+				return fmt.Errorf("cannot be in the future")
+			}
+			return nil
+		}),
+		huh.NewInput().Title("Length (minutes)").Value(&lengthStr).Validate(func(s string) error {
+			if n, err := strconv.Atoi(strings.TrimSpace(s)); err != nil || n <= 0 {
+				return fmt.Errorf("must be a positive number")
+			}
+			return nil
+		}),
+		huh.NewInput().Title("Reason (optional)").Value(&reason),
+	}
+
+	episodeID := ""
+	episodeOptions := []huh.Option[string]{huh.NewOption("None", "")}
+	for _, raw := range episodes {
+		m, err := fhir.Parse(raw)
+		if err != nil || mapStr(m, "status") != "active" {
+			continue
+		}
+		episodeOptions = append(episodeOptions, huh.NewOption(fhir.EpisodeDisplay(m), mapStr(m, "id")))
+	}
+	if len(episodeOptions) > 1 {
+		fields = append(fields, huh.NewSelect[string]().
+			Title("Episode of care").
+			Options(episodeOptions...).
+			Value(&episodeID))
+	}
+
+	if err := huh.NewForm(huh.NewGroup(fields...)).Run(); err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	start, _ := time.ParseInLocation(measuredAtLayout, strings.TrimSpace(startStr), time.Local)
+	length, _ := strconv.Atoi(strings.TrimSpace(lengthStr))
+	text = strings.TrimSpace(text)
+	body := fhir.NewEncounter(patientID, fhir.EncounterClasses[classIdx], text, strings.TrimSpace(reason),
+		start, start.Add(time.Duration(length)*time.Minute))
+	if episodeID != "" {
+		m, _ := fhir.Parse(body)
+		fhir.SetEpisodeLink(m, episodeID, true)
+		body, _ = json.Marshal(m)
+	}
+
+	var created json.RawMessage
+	var apiErr error
+
+	err = runSpinner("Recording encounter...", func(ctx context.Context) {
+		created, apiErr = a.createResource(ctx, "Encounter", body)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(fmt.Errorf("creating encounter: %w", apiErr))
+		PressEnter()
+		return
+	}
+
+	id := fhir.ResourceID(created)
+	a.emit(context.Background(), EventEncounterCreated, "Encounter", id, patientID)
+	fmt.Printf("\n  Recorded %s on %s (ID: %s)\n", text, start.Format(measuredAtLayout), id)
+	PressEnter()
+}
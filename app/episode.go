@@ -0,0 +1,366 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// episodeChart is what the episode tools need from a patient's record.
+type episodeChart struct {
+	episodes   []map[string]any
+	conditions []map[string]any
+	encounters []map[string]any
+	plans      []map[string]any
+}
+
+func (a *App) loadEpisodeChart(ctx context.Context, patientID string) (*episodeChart, error) {
+	c := &episodeChart{}
+	for _, s := range []struct {
+		resourceType string
+		into         *[]map[string]any
+	}{
+		{"EpisodeOfCare", &c.episodes},
+		{"Condition", &c.conditions},
+		{"Encounter", &c.encounters},
+		{"CarePlan", &c.plans},
+	} {
+		raws, err := a.searchByPatient(ctx, s.resourceType, patientID, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, raw := range raws {
+			if m, err := fhir.Parse(raw); err == nil {
+				*s.into = append(*s.into, m)
+			}
+		}
+	}
+	return c, nil
+}
+
+// linkable returns the encounters and care plans that can join an episode.
+func (c *episodeChart) linkable() []map[string]any {
+	return append(append([]map[string]any{}, c.encounters...), c.plans...)
+}
+
+// pickEpisode presents a select over a patient's episodes. Returns nil if
+// there are none.
+func pickEpisode(episodes []map[string]any) (map[string]any, error) {
+	if len(episodes) == 0 {
+		fmt.Println("\n  No episodes of care found for this patient.")
+		return nil, nil
+	}
+	var options []huh.Option[int]
+	for i, m := range episodes {
+		options = append(options, huh.NewOption(fhir.EpisodeDisplay(m), i))
+	}
+	var i int
+	err := huh.NewSelect[int]().
+		Title("Select an episode of care").
+		Options(options...).
+		Value(&i).
+		Run()
+	if err != nil {
+		return nil, err
+	}
+	return episodes[i], nil
+}
+
+// pickEpisodeLinks shows the patient's encounters and care plans with those
+// already in the episode preselected, and returns the chosen "Type/id"s.
+func pickEpisodeLinks(c *episodeChart, episodeID string) (map[string]bool, error) {
+	var options []huh.Option[string]
+	for _, m := range c.linkable() {
+		rt, id := mapStr(m, "resourceType"), mapStr(m, "id")
+		label := "Encounter  " + fhir.EncounterDisplay(m)
+		if rt == "CarePlan" {
+			label = fmt.Sprintf("Care plan  %s (%s)", mapStr(m, "title"), mapStr(m, "status"))
+		}
+		options = append(options, huh.NewOption(label, rt+"/"+id).Selected(episodeID != "" && fhir.InEpisode(m, episodeID)))
+	}
+	if len(options) == 0 {
+		return nil, nil
+	}
+
+	var chosen []string
+	err := huh.NewMultiSelect[string]().
+		Title("Encounters and care plans in this episode").
+		Options(options...).
+		Value(&chosen).
+		Run()
+	if err != nil {
+		return nil, err
+	}
+	linked := make(map[string]bool)
+	for _, ref := range chosen {
+		linked[ref] = true
+	}
+	return linked, nil
+}
+
+// saveEpisodeLinks updates every encounter and care plan whose membership in
+// the episode changed, in one transaction, and returns how many were updated.
+func (a *App) saveEpisodeLinks(ctx context.Context, c *episodeChart, episodeID string, linked map[string]bool) (int, error) {
+	var entries []map[string]any
+	var targets []string
+	var changed []map[string]any
+	for _, m := range c.linkable() {
+		rt, id := mapStr(m, "resourceType"), mapStr(m, "id")
+		if !fhir.SetEpisodeLink(m, episodeID, linked[rt+"/"+id]) {
+			continue
+		}
+		body, _ := json.Marshal(m)
+		entries = append(entries, fhir.UpdateEntry(rt, id, body))
+		targets = append(targets, rt+"/"+id)
+		changed = append(changed, m)
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	n, err := a.processChanges(ctx, entries, targets, "UPDATE")
+	if err != nil {
+		return 0, err
+	}
+	for _, m := range changed {
+		event := EventCarePlanUpdated
+		if mapStr(m, "resourceType") == "Encounter" {
+			event = EventEncounterUpdated
+		}
+		a.emit(ctx, event, mapStr(m, "resourceType"), mapStr(m, "id"), resourcePatient(m))
+	}
+	return n, nil
+}
+
+// StartEpisode opens an EpisodeOfCare for a chronic-disease program and
+// links the patient's existing encounters and care plans to it.
+func (a *App) StartEpisode() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	ctx := context.Background()
+	var chart *episodeChart
+	var fetchErr error
+	err = runSpinner("Loading chart...", func(ctx context.Context) {
+		chart, fetchErr = a.loadEpisodeChart(ctx, patientID)
+	})
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	var typeIdx int
+	var typeOptions []huh.Option[int]
+	for i, t := range fhir.EpisodeTypes {
+		typeOptions = append(typeOptions, huh.NewOption(t.Display, i))
+	}
+	conditionID := ""
+	conditionOptions := []huh.Option[string]{huh.NewOption("None", "")}
+	for _, m := range chart.conditions {
+		conditionOptions = append(conditionOptions, huh.NewOption(fhir.ConditionDisplay(m), mapStr(m, "id")))
+	}
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[int]().Title("Program").Options(typeOptions...).Value(&typeIdx),
+			huh.NewSelect[string]().Title("Diagnosis").Options(conditionOptions...).Value(&conditionID),
+		),
+	)
+	if err := form.Run(); err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	linked, err := pickEpisodeLinks(chart, "")
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var episodeID string
+	var updated int
+	var apiErr error
+	var elapsed time.Duration
+	err = runSpinner("Starting episode...", func(ctx context.Context) {
+		start := time.Now()
+		created, err := a.createResource(ctx, "EpisodeOfCare",
+			fhir.NewEpisodeOfCare(patientID, fhir.EpisodeTypes[typeIdx], conditionID, time.Now()))
+		if err != nil {
+			apiErr = fmt.Errorf("creating episode: %w", err)
+			return
+		}
+		episodeID = fhir.ResourceID(created)
+		updated, err = a.saveEpisodeLinks(ctx, chart, episodeID, linked)
+		if err != nil {
+			apiErr = fmt.Errorf("linking to episode: %w", err)
+		}
+		elapsed = time.Since(start)
+	})
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if episodeID != "" {
+		a.emit(ctx, EventEpisodeOfCareCreated, "EpisodeOfCare", episodeID, patientID)
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Printf("\n  Started %s episode (ID: %s) with %d linked encounters and care plans\n",
+		fhir.EpisodeTypes[typeIdx].Display, episodeID, updated)
+	showTiming("Created EpisodeOfCare and linked resources", elapsed)
+	PressEnter()
+}
+
+// EditEpisodeLinks adds encounters and care plans to an episode or removes
+// them from it.
+func (a *App) EditEpisodeLinks() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var chart *episodeChart
+	var fetchErr error
+	err = runSpinner("Loading chart...", func(ctx context.Context) {
+		chart, fetchErr = a.loadEpisodeChart(ctx, patientID)
+	})
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	episode, err := pickEpisode(chart.episodes)
+	if err != nil || episode == nil {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	episodeID := mapStr(episode, "id")
+
+	linked, err := pickEpisodeLinks(chart, episodeID)
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var updated int
+	var apiErr error
+	err = runSpinner("Updating links...", func(ctx context.Context) {
+		updated, apiErr = a.saveEpisodeLinks(ctx, chart, episodeID, linked)
+	})
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Printf("\n  Updated %d encounters and care plans\n", updated)
+	PressEnter()
+}
+
+// EpisodeTimeline shows an episode's encounters, care plans, and dated plan
+// activities in date order.
+func (a *App) EpisodeTimeline() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var chart *episodeChart
+	var fetchErr error
+	var elapsed time.Duration
+	err = runSpinner("Loading chart...", func(ctx context.Context) {
+		start := time.Now()
+		chart, fetchErr = a.loadEpisodeChart(ctx, patientID)
+		elapsed = time.Since(start)
+	})
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	episode, err := pickEpisode(chart.episodes)
+	if err != nil || episode == nil {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	episodeID := mapStr(episode, "id")
+
+	var encounters, plans []map[string]any
+	for _, m := range chart.encounters {
+		if fhir.InEpisode(m, episodeID) {
+			encounters = append(encounters, m)
+		}
+	}
+	for _, m := range chart.plans {
+		if fhir.InEpisode(m, episodeID) {
+			plans = append(plans, m)
+		}
+	}
+
+	fmt.Println()
+	fhir.PrintEpisodeTimeline(episode, fhir.EpisodeTimeline(episode, encounters, plans))
+	showTiming(fmt.Sprintf("%d encounters and %d care plans in episode", len(encounters), len(plans)), elapsed)
+	if raw, err := json.Marshal(episode); err == nil {
+		showingResource(raw)
+	}
+	PressEnter()
+}
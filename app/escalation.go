@@ -0,0 +1,231 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	neturl "net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// defaultEscalationDays is App.EscalationDays when
+// PHENOSTORE_ESCALATION_DAYS is not set.
+const defaultEscalationDays = 7
+
+// parseEscalationDays reads PHENOSTORE_ESCALATION_DAYS. Empty means the
+// default, and 0 turns escalation off.
+func parseEscalationDays(v string) (int, error) {
+	if v == "" {
+		return defaultEscalationDays, nil
+	}
+	days, err := strconv.Atoi(v)
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("invalid PHENOSTORE_ESCALATION_DAYS %q: must be a whole number of days (0 turns escalation off)", v)
+	}
+	return days, nil
+}
+
+// escalationQuery finds escalation Tasks.
+func escalationQuery() neturl.Values {
+	return neturl.Values{fhir.SearchTaskCode: {fhir.EscalationSystem + "|" + fhir.EscalationCode}}
+}
+
+// escalationResult is what one escalation pass did.
+type escalationResult struct {
+	Created int
+	Closed  int
+	// Open are the escalation Tasks still open after the pass, including
+	// the ones it created.
+	Open []map[string]any
+}
+
+// escalate runs the escalation rules over the active care plans of the
+// patients in scope (all of them when scope is nil). Each activity overdue
+// by more than App.EscalationDays gets a Task, once; escalation Tasks whose
+// activity has been done, or whose plan is no longer active, are completed.
+// With escalation off it only returns the open Tasks.
+func (a *App) escalate(ctx context.Context, now time.Time, scope cohortScope) (escalationResult, error) {
+	var result escalationResult
+	rawTasks, err := a.searchAllPages(ctx, "Task", 100, escalationQuery(), nil)
+	if err != nil {
+		return result, err
+	}
+	tasks := make(map[string]map[string]any) // by escalation key
+	for _, raw := range scope.filter(rawTasks) {
+		if m, err := fhir.Parse(raw); err == nil && fhir.TaskEscalationKey(m) != "" {
+			tasks[fhir.TaskEscalationKey(m)] = m
+		}
+	}
+	if a.EscalationDays == 0 {
+		for _, t := range tasks {
+			if fhir.TaskOpen(t) {
+				result.Open = append(result.Open, t)
+			}
+		}
+		return result, nil
+	}
+
+	rawPlans, err := a.searchAllPages(ctx, "CarePlan", 100, neturl.Values{fhir.SearchCarePlanStatus: {"active"}}, nil)
+	if err != nil {
+		return result, err
+	}
+	outstanding := make(map[string]bool)
+	var entries []map[string]any
+	for _, raw := range scope.filter(rawPlans) {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		escalations, open := fhir.PlanEscalations(m, now, a.EscalationDays)
+		for _, key := range open {
+			outstanding[key] = true
+		}
+		for _, e := range escalations {
+			if tasks[e.Key()] != nil {
+				continue
+			}
+			body := fhir.NewEscalationTask(e)
+			entries = append(entries, fhir.BundleEntry("Task", body))
+			if t, err := fhir.Parse(body); err == nil {
+				result.Open = append(result.Open, t)
+			}
+		}
+	}
+
+	var closing []map[string]any
+	var targets []string
+	for key, t := range tasks {
+		switch {
+		case !fhir.TaskOpen(t):
+		case outstanding[key]:
+			result.Open = append(result.Open, t)
+		default:
+			fhir.WithTaskCompleted(t, now)
+			body, _ := json.Marshal(t)
+			closing = append(closing, fhir.UpdateEntry("Task", mapStr(t, "id"), body))
+			targets = append(targets, "Task/"+mapStr(t, "id"))
+		}
+	}
+
+	if len(entries) > 0 {
+		if result.Created, err = a.processTransaction(ctx, entries); err != nil {
+			return result, fmt.Errorf("creating escalation tasks: %w", err)
+		}
+	}
+	if len(closing) > 0 {
+		if result.Closed, err = a.processChanges(ctx, closing, targets, "UPDATE"); err != nil {
+			return result, fmt.Errorf("closing escalation tasks: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// ReassignEscalations assigns open escalation Tasks, picked from a list, to
+// a practitioner in one go.
+func (a *App) ReassignEscalations() {
+	ctx := context.Background()
+	var raw []json.RawMessage
+	var fetchErr error
+
+	err := runSpinner("Loading escalations...", func(ctx context.Context) {
+		raw, fetchErr = a.searchAllPages(ctx, "Task", 100, escalationQuery(), nil)
+	})
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	now := time.Now()
+	names := make(map[string]string)
+	var tasks []map[string]any
+	var owners []string
+	for _, r := range raw {
+		m, err := fhir.Parse(r)
+		if err != nil || !fhir.TaskOpen(m) {
+			continue
+		}
+		tasks = append(tasks, m)
+		if owner := fhir.TaskOwner(m); owner != "" && !slices.Contains(owners, owner) {
+			owners = append(owners, owner)
+		}
+	}
+	if len(tasks) == 0 {
+		fmt.Println("\n  No open escalations.")
+		PressEnter()
+		return
+	}
+
+	options := make([]huh.Option[int], len(tasks))
+	for i, t := range tasks {
+		patientID := fhir.TaskPatient(t)
+		if _, ok := names[patientID]; !ok {
+			names[patientID] = a.resolvePatientName(ctx, patientID)
+		}
+		options[i] = huh.NewOption(fmt.Sprintf("%s: %s", names[patientID], fhir.EscalationDisplay(t, now)), i)
+	}
+
+	var picked []int
+	var practitioner string
+	err = huh.NewForm(huh.NewGroup(
+		huh.NewMultiSelect[int]().
+			Title("Escalations to reassign").
+			Options(options...).
+			Value(&picked).
+			Validate(func(p []int) error {
+				if len(p) == 0 {
+					return fmt.Errorf("pick at least one")
+				}
+				return nil
+			}),
+		huh.NewInput().
+			Title("Assign to (e.g., Dr. Rivera)").
+			Suggestions(owners).
+			Value(&practitioner).
+			Validate(func(s string) error {
+				if strings.TrimSpace(s) == "" {
+					return fmt.Errorf("enter a practitioner")
+				}
+				return nil
+			}),
+	)).Run()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	practitioner = strings.TrimSpace(practitioner)
+
+	entries := make([]map[string]any, 0, len(picked))
+	targets := make([]string, 0, len(picked))
+	for _, i := range picked {
+		t := tasks[i]
+		fhir.WithTaskOwner(t, practitioner, now)
+		body, _ := json.Marshal(t)
+		entries = append(entries, fhir.UpdateEntry("Task", mapStr(t, "id"), body))
+		targets = append(targets, "Task/"+mapStr(t, "id"))
+	}
+
+	fmt.Println()
+	start := time.Now()
+	n, _, err := a.processInBatches(ctx, entries, targets, "UPDATE", "reassigned")
+	if err != nil {
+		ShowError(err)
+	}
+	fmt.Printf("\n  Reassigned %d escalations to %s\n", n, practitioner)
+	showTiming(fmt.Sprintf("Updated %d tasks via transaction bundle", n), time.Since(start))
+	PressEnter()
+}
@@ -0,0 +1,221 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	neturl "net/url"
+	"sort"
+	"time"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+	"github.com/phenoml/phenostore-sdk-go/phenostore/gen"
+)
+
+// How PatientSummary loads a patient's resources (PHENOSTORE_SUMMARY).
+const (
+	SummarySearches   = "searches"   // a read and a search per resource type, in parallel
+	SummaryEverything = "everything" // one Patient/$everything call, falling back to searches
+	SummaryRevinclude = "revinclude" // one Patient search with _revinclude, falling back to searches
+	SummaryCompare    = "compare"    // all three, showing how long each took
+)
+
+// fetchEverything calls Patient/$everything for a patient and follows the
+// bundle's next links. It returns every resource in the compartment,
+// including the patient, and how many calls that took.
+func (a *App) fetchEverything(ctx context.Context, patientID string) (resources []json.RawMessage, calls int, err error) {
+	defer func() {
+		a.audit(ctx, fhir.AuditExecute, "operation", "Patient", "Patient/"+patientID+"/$everything", patientID, err)
+	}()
+	var query neturl.Values
+	for {
+		calls++
+		body, err := a.invokeOperation(ctx, "Patient", patientID, "$everything", query)
+		if err != nil {
+			return nil, calls, err
+		}
+		var bundle gen.Bundle
+		if err := json.Unmarshal(body, &bundle); err != nil || bundle.Type != "searchset" && bundle.Type != "collection" {
+			return nil, calls, fmt.Errorf("$everything did not return a bundle")
+		}
+		resources = append(resources, extractResources(bundle)...)
+		next := nextLink(bundle)
+		if next == "" {
+			return resources, calls, nil
+		}
+		u, err := neturl.Parse(next)
+		if err != nil {
+			return nil, calls, fmt.Errorf("invalid next link: %w", err)
+		}
+		query = u.Query()
+	}
+}
+
+// summaryFromResources sorts the resources of a $everything response or a
+// _revinclude search into a Summary, keeping the observations taken within
+// dates, newest first, and the care plans most recently saved first, as
+// LoadSummaryBetween's searches return them. Resource types the summary
+// does not show are dropped.
+func summaryFromResources(resources []json.RawMessage, patientID string, dates DateRange) (*Summary, error) {
+	var s Summary
+	var observations []json.RawMessage
+	for _, raw := range resources {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		switch mapStr(m, "resourceType") {
+		case "Patient":
+			if mapStr(m, "id") == patientID {
+				s.Patient = raw
+			}
+		case "Flag":
+			s.Flags = append(s.Flags, raw)
+		case "Observation":
+			observations = append(observations, raw)
+		case "Condition":
+			s.Conditions = append(s.Conditions, raw)
+		case "CarePlan":
+			if mapStr(m, "status") == "active" {
+				s.Plans = append(s.Plans, raw)
+			}
+		case "ImagingStudy":
+			s.ImagingStudies = append(s.ImagingStudies, raw)
+		case "Goal":
+			s.Goals = append(s.Goals, raw)
+		}
+	}
+	if s.Patient == nil {
+		return nil, fmt.Errorf("%w: %s", ErrPatientNotFound, patientID)
+	}
+	s.Observations = fhir.ObservationsBetween(observations, dates.From, dates.To)
+	sort.SliceStable(s.Plans, func(i, j int) bool { return lastUpdated(s.Plans[i]) > lastUpdated(s.Plans[j]) })
+	return &s, nil
+}
+
+// lastUpdated returns a resource's meta.lastUpdated, which sorts as text.
+func lastUpdated(raw json.RawMessage) string {
+	m, err := fhir.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	s, _ := fhir.Path(m, "meta.lastUpdated").(string)
+	return s
+}
+
+// summaryLoad is how a summary was loaded and how long it took.
+type summaryLoad struct {
+	Name      string // the strategy: "Searches", "$everything", or "_revinclude"
+	Method    string // e.g. "7 parallel API calls" or "1 $everything call"
+	Elapsed   time.Duration
+	Resources int    // how many resources the summary holds, when compared
+	Err       error  // why the strategy failed
+	Fallback  string // the strategy asked for, when searches were used instead
+}
+
+// summaryByEverything loads a summary with Patient/$everything alone.
+func (a *App) summaryByEverything(ctx context.Context, patientID string, dates DateRange) (*Summary, summaryLoad, error) {
+	start := time.Now()
+	resources, calls, err := a.fetchEverything(ctx, patientID)
+	if err != nil {
+		return nil, summaryLoad{Name: "$everything"}, err
+	}
+	s, err := summaryFromResources(resources, patientID, dates)
+	method := "1 $everything call"
+	if calls > 1 {
+		method = fmt.Sprintf("%d $everything calls, one per page", calls)
+	}
+	return s, summaryLoad{Name: "$everything", Method: method, Elapsed: time.Since(start)}, err
+}
+
+// summaryRevincludes are the _revinclude values that bring in each of a
+// patient's resources the summary shows.
+var summaryRevincludes = []string{
+	"Flag:patient",
+	"Observation:patient",
+	"Condition:patient",
+	"CarePlan:patient",
+	"ImagingStudy:patient",
+	"Goal:patient",
+}
+
+// summaryByRevinclude loads a summary with a single Patient search by _id
+// that brings in the rest with _revinclude, following next links if the
+// server pages the included resources.
+func (a *App) summaryByRevinclude(ctx context.Context, patientID string, dates DateRange) (*Summary, summaryLoad, error) {
+	start := time.Now()
+	calls := 0
+	resources, err := a.searchAllPages(ctx, "Patient", 100, neturl.Values{
+		fhir.SearchID:         {patientID},
+		fhir.SearchRevinclude: summaryRevincludes,
+	}, func(int) { calls++ })
+	load := summaryLoad{Name: "_revinclude"}
+	if err != nil {
+		return nil, load, err
+	}
+	s, err := summaryFromResources(resources, patientID, dates)
+	load.Method = "1 _revinclude search"
+	if calls > 1 {
+		load.Method = fmt.Sprintf("%d _revinclude calls, one per page", calls)
+	}
+	load.Elapsed = time.Since(start)
+	return s, load, err
+}
+
+// loadSummaryOrSearches loads a summary with by, such as
+// summaryByEverything. If that fails, for instance because the server does
+// not support the operation, it loads it with searches instead and reports
+// why in the returned summaryLoad.
+func (a *App) loadSummaryOrSearches(ctx context.Context, patientID string, dates DateRange, by func(context.Context, string, DateRange) (*Summary, summaryLoad, error)) (*Summary, summaryLoad, error) {
+	s, load, err := by(ctx, patientID, dates)
+	if err == nil {
+		return s, load, nil
+	}
+	name := load.Name
+	s, load, loadErr := a.loadSummarySearches(ctx, patientID, dates)
+	load.Err, load.Fallback = err, name
+	return s, load, loadErr
+}
+
+// loadSummarySearches loads a summary with LoadSummaryBetween's parallel
+// read and searches.
+func (a *App) loadSummarySearches(ctx context.Context, patientID string, dates DateRange) (*Summary, summaryLoad, error) {
+	start := time.Now()
+	s, err := a.LoadSummaryBetween(ctx, patientID, dates)
+	return s, summaryLoad{Name: "Searches", Method: "7 parallel API calls", Elapsed: time.Since(start)}, err
+}
+
+// summaryResources counts the resources in a summary, including the patient.
+func summaryResources(s *Summary) int {
+	return len(s.Flags) + len(s.Observations) + len(s.Conditions) + len(s.Plans) + len(s.ImagingStudies) + len(s.Goals) + 1
+}
+
+// printSummaryComparison shows how long a summary took to load with
+// searches and with each other strategy, and how many resources each
+// found. A difference in counts usually means the other strategy returned
+// resources the searches filter out, such as inactive care plans.
+func printSummaryComparison(searches summaryLoad, others ...summaryLoad) {
+	fmt.Println()
+	fmt.Println(barStyle.Bold(true).Render("Summary strategies compared"))
+	for _, load := range append([]summaryLoad{searches}, others...) {
+		if load.Err != nil {
+			fmt.Printf("  %-12s %s\n", load.Name, timingStyle.Render("not available: "+load.Err.Error()))
+			continue
+		}
+		fmt.Printf("  %-12s %-34s %4d resources  %s\n", load.Name, load.Method, load.Resources, load.Elapsed.Round(time.Millisecond))
+	}
+	for _, load := range others {
+		if load.Err == nil && searches.Elapsed > 0 && load.Elapsed > 0 {
+			fmt.Println(timingStyle.Render("  " + fasterBy(load.Name, searches.Elapsed, load.Elapsed)))
+		}
+	}
+}
+
+// fasterBy says whether searches or the strategy called name was faster,
+// and by how much.
+func fasterBy(name string, searches, other time.Duration) string {
+	if other < searches {
+		return fmt.Sprintf("%s was %.1fx faster than searches", name, float64(searches)/float64(other))
+	}
+	return fmt.Sprintf("Searches were %.1fx faster than %s", float64(other)/float64(searches), name)
+}
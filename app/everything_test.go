@@ -0,0 +1,74 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+func TestSummaryFromResources(t *testing.T) {
+	resources := []json.RawMessage{
+		json.RawMessage(`{"resourceType":"Patient","id":"p1"}`),
+		json.RawMessage(`{"resourceType":"Observation","id":"old","effectiveDateTime":"2026-01-05"}`),
+		json.RawMessage(`{"resourceType":"Observation","id":"new","effectiveDateTime":"2026-03-05T10:00:00Z"}`),
+		json.RawMessage(`{"resourceType":"Observation","id":"undated"}`),
+		json.RawMessage(`{"resourceType":"CarePlan","id":"done","status":"completed"}`),
+		json.RawMessage(`{"resourceType":"CarePlan","id":"cp1","status":"active","meta":{"lastUpdated":"2026-02-01T00:00:00Z"}}`),
+		json.RawMessage(`{"resourceType":"CarePlan","id":"cp2","status":"active","meta":{"lastUpdated":"2026-03-01T00:00:00Z"}}`),
+		json.RawMessage(`{"resourceType":"Encounter","id":"e1"}`),
+	}
+
+	s, err := summaryFromResources(resources, "p1", DateRange{From: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, raw := range append(s.Observations, s.Plans...) {
+		ids = append(ids, fhir.ResourceID(raw))
+	}
+	if got := fmt.Sprint(ids); got != "[new old cp2 cp1]" {
+		t.Errorf("observations and plans = %s, want [new old cp2 cp1]", got)
+	}
+	if n := summaryResources(s); n != 5 {
+		t.Errorf("summaryResources = %d, want 5", n)
+	}
+
+	if _, err := summaryFromResources(resources[1:], "p1", DateRange{}); !errors.Is(err, ErrPatientNotFound) {
+		t.Errorf("without the patient: err = %v, want ErrPatientNotFound", err)
+	}
+}
+
+func TestSummaryByRevinclude(t *testing.T) {
+	a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if !strings.HasSuffix(r.URL.Path, "/Patient") || q.Get("_id") != "p1" || !slices.Equal(q["_revinclude"], summaryRevincludes) {
+			t.Errorf("search sent = %s", r.URL)
+		}
+		fmt.Fprint(w, `{"resourceType":"Bundle","type":"searchset","entry":[
+			{"resource":{"resourceType":"Patient","id":"p1"},"search":{"mode":"match"}},
+			{"resource":{"resourceType":"Flag","id":"f1"},"search":{"mode":"include"}},
+			{"resource":{"resourceType":"Condition","id":"c1"},"search":{"mode":"include"}},
+			{"resource":{"resourceType":"Observation","id":"o1","effectiveDateTime":"2026-03-05"},"search":{"mode":"include"}},
+			{"resource":{"resourceType":"Goal","id":"g1"},"search":{"mode":"include"}}
+		]}`)
+	})
+
+	s, load, err := a.summaryByRevinclude(context.Background(), "p1", DateRange{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fhir.ResourceID(s.Patient) != "p1" || len(s.Flags) != 1 || len(s.Conditions) != 1 || len(s.Observations) != 1 || len(s.Goals) != 1 {
+		t.Errorf("summary = %+v", s)
+	}
+	if load.Name != "_revinclude" || load.Method != "1 _revinclude search" {
+		t.Errorf("load = %+v", load)
+	}
+}
@@ -0,0 +1,405 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+var (
+	explorerTitleStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12"))
+	explorerKeyStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("12"))
+	explorerMatchStyle  = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("0")).Background(lipgloss.Color("3"))
+	explorerStringStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	explorerOtherStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("5"))
+	explorerDimStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	explorerCursorStyle = lipgloss.NewStyle().Reverse(true)
+)
+
+const explorerHelp = "↑↓ move  →/← expand/collapse  space toggle  e/c expand/collapse all  / search  n/N next/prev  y copy JSONPath  f copy FHIRPath  " + copyKeysHelp + "  q quit"
+
+// explorer is a Bubble Tea model showing a JSON document as a collapsible
+// tree.
+type explorer struct {
+	title    string
+	root     *fhir.TreeNode
+	expanded map[*fhir.TreeNode]bool
+	visible  []*fhir.TreeNode // nodes on screen when scrolled through
+	cursor   int              // index into visible
+	offset   int              // index of the first visible node shown
+	height   int              // lines available for the tree
+
+	search    textinput.Model
+	searching bool
+	query     string
+	status    string
+
+	clip clipTargets // the resource's ID, URL, and JSON for the copy keys
+}
+
+func newExplorer(title string, root *fhir.TreeNode) *explorer {
+	search := textinput.New()
+	search.Prompt = "Search keys: "
+	e := &explorer{
+		title:    title,
+		root:     root,
+		expanded: map[*fhir.TreeNode]bool{root: true},
+		height:   20,
+		search:   search,
+	}
+	// Open the first level, so the resource's top-level elements show.
+	for _, c := range root.Children {
+		if len(c.Children) > 0 && len(c.Children) <= 3 {
+			e.expanded[c] = true
+		}
+	}
+	e.refresh()
+	return e
+}
+
+// refresh rebuilds the visible list after nodes are expanded or collapsed,
+// keeping the cursor on the same node where it is still visible.
+func (e *explorer) refresh() {
+	var current *fhir.TreeNode
+	if e.cursor < len(e.visible) {
+		current = e.visible[e.cursor]
+	}
+	e.visible = e.visible[:0]
+	var add func(n *fhir.TreeNode)
+	add = func(n *fhir.TreeNode) {
+		for _, c := range n.Children {
+			e.visible = append(e.visible, c)
+			if e.expanded[c] {
+				add(c)
+			}
+		}
+	}
+	add(e.root)
+	e.cursor = 0
+	for i, n := range e.visible {
+		if n == current {
+			e.cursor = i
+		}
+	}
+	e.scroll()
+}
+
+// scroll keeps the cursor on screen.
+func (e *explorer) scroll() {
+	if e.cursor < e.offset {
+		e.offset = e.cursor
+	}
+	if e.cursor >= e.offset+e.height {
+		e.offset = e.cursor - e.height + 1
+	}
+}
+
+func (e *explorer) current() *fhir.TreeNode {
+	if e.cursor < len(e.visible) {
+		return e.visible[e.cursor]
+	}
+	return nil
+}
+
+// moveTo puts the cursor on n, expanding its ancestors so it is visible.
+func (e *explorer) moveTo(n *fhir.TreeNode) {
+	for p := n.Parent; p != nil; p = p.Parent {
+		e.expanded[p] = true
+	}
+	e.refresh()
+	for i, v := range e.visible {
+		if v == n {
+			e.cursor = i
+		}
+	}
+	e.scroll()
+}
+
+// matches returns the nodes whose key contains the search query, in order.
+func (e *explorer) matches() []*fhir.TreeNode {
+	if e.query == "" {
+		return nil
+	}
+	q := strings.ToLower(e.query)
+	var found []*fhir.TreeNode
+	e.root.Walk(func(n *fhir.TreeNode) {
+		if n != e.root && strings.Contains(strings.ToLower(n.Key), q) {
+			found = append(found, n)
+		}
+	})
+	return found
+}
+
+// findNext moves to the next match after the cursor, or the previous one
+// before it, wrapping around.
+func (e *explorer) findNext(forward bool) {
+	found := e.matches()
+	if len(found) == 0 {
+		e.status = fmt.Sprintf("No keys match %q", e.query)
+		return
+	}
+	// Matches are in document order; so is the full walk.
+	order := make(map[*fhir.TreeNode]int)
+	i := 0
+	e.root.Walk(func(n *fhir.TreeNode) { order[n] = i; i++ })
+	at := -1
+	if cur := e.current(); cur != nil {
+		at = order[cur]
+	}
+	pick := -1
+	for j, n := range found {
+		if forward && order[n] > at {
+			pick = j
+			break
+		}
+		if !forward && order[n] < at {
+			pick = j
+		}
+	}
+	if pick < 0 {
+		pick = 0
+		if !forward {
+			pick = len(found) - 1
+		}
+	}
+	e.moveTo(found[pick])
+	e.status = fmt.Sprintf("Match %d of %d for %q", pick+1, len(found), e.query)
+}
+
+func (e *explorer) setAll(open bool) {
+	e.root.Walk(func(n *fhir.TreeNode) {
+		if n != e.root && len(n.Children) > 0 {
+			e.expanded[n] = open
+		}
+	})
+	e.refresh()
+}
+
+func (e *explorer) Init() tea.Cmd { return nil }
+
+func (e *explorer) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		e.height = max(msg.Height-4, 1)
+		e.scroll()
+		return e, nil
+
+	case tea.KeyMsg:
+		if e.searching {
+			switch msg.String() {
+			case "enter":
+				e.searching = false
+				e.search.Blur()
+				e.query = strings.TrimSpace(e.search.Value())
+				e.findNext(true)
+				return e, nil
+			case "esc", "ctrl+c":
+				e.searching = false
+				e.search.Blur()
+				return e, nil
+			}
+			var cmd tea.Cmd
+			e.search, cmd = e.search.Update(msg)
+			return e, cmd
+		}
+
+		e.status = ""
+		n := e.current()
+		switch msg.String() {
+		case "q", "esc", "ctrl+c":
+			return e, tea.Quit
+		case "up", "k":
+			e.cursor = max(e.cursor-1, 0)
+		case "down", "j":
+			e.cursor = min(e.cursor+1, len(e.visible)-1)
+		case "pgup":
+			e.cursor = max(e.cursor-e.height, 0)
+		case "pgdown":
+			e.cursor = min(e.cursor+e.height, len(e.visible)-1)
+		case "home", "g":
+			e.cursor = 0
+		case "end", "G":
+			e.cursor = len(e.visible) - 1
+		case "right", "l", "enter":
+			if n != nil && len(n.Children) > 0 {
+				if e.expanded[n] {
+					e.cursor++
+				} else {
+					e.expanded[n] = true
+					e.refresh()
+				}
+			}
+		case "left", "h":
+			if n != nil && e.expanded[n] {
+				e.expanded[n] = false
+				e.refresh()
+			} else if n != nil && n.Parent != e.root {
+				e.moveTo(n.Parent)
+			}
+		case " ":
+			if n != nil && len(n.Children) > 0 {
+				e.expanded[n] = !e.expanded[n]
+				e.refresh()
+			}
+		case "e":
+			e.setAll(true)
+		case "c":
+			e.setAll(false)
+		case "/":
+			e.searching = true
+			e.search.SetValue(e.query)
+			return e, e.search.Focus()
+		case "n":
+			e.findNext(true)
+		case "N":
+			e.findNext(false)
+		case "y":
+			if n != nil {
+				e.status = fmt.Sprintf("Copied %s to the %s", n.JSONPath(), copyToClipboard(n.JSONPath()))
+			}
+		case "f":
+			if n != nil {
+				e.status = fmt.Sprintf("Copied %s to the %s", n.FHIRPath(), copyToClipboard(n.FHIRPath()))
+			}
+		case "i", "u", "r":
+			e.status, _ = e.clip.copy(msg.String())
+		}
+		e.scroll()
+	}
+	return e, nil
+}
+
+// line renders one node of the tree.
+func (e *explorer) line(n *fhir.TreeNode) string {
+	marker := "  "
+	if len(n.Children) > 0 {
+		marker = "▸ "
+		if e.expanded[n] {
+			marker = "▾ "
+		}
+	}
+	label := explorerKeyStyle.Render(n.Label())
+	if e.query != "" && strings.Contains(strings.ToLower(n.Key), strings.ToLower(e.query)) {
+		label = explorerMatchStyle.Render(n.Label())
+	}
+
+	var value string
+	switch {
+	case n.Kind == '{' && !e.expanded[n]:
+		value = explorerDimStyle.Render(fmt.Sprintf("{%d}", len(n.Children)))
+	case n.Kind == '[' && !e.expanded[n]:
+		value = explorerDimStyle.Render(fmt.Sprintf("[%d]", len(n.Children)))
+	case n.Kind == 0 && strings.HasPrefix(n.Value, `"`):
+		value = explorerStringStyle.Render(n.Value)
+	case n.Kind == 0:
+		value = explorerOtherStyle.Render(n.Value)
+	}
+	return strings.Repeat("  ", n.Depth()-1) + marker + label + ": " + value
+}
+
+func (e *explorer) View() string {
+	var b strings.Builder
+	b.WriteString(explorerTitleStyle.Render(e.title) + "\n")
+	end := min(e.offset+e.height, len(e.visible))
+	for i := e.offset; i < end; i++ {
+		line := e.line(e.visible[i])
+		if i == e.cursor {
+			line = explorerCursorStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	for i := end - e.offset; i < e.height; i++ {
+		b.WriteString("\n")
+	}
+	if n := e.current(); n != nil {
+		b.WriteString(explorerDimStyle.Render(n.FHIRPath()) + "\n")
+	}
+	switch {
+	case e.searching:
+		b.WriteString(e.search.View())
+	case e.status != "":
+		b.WriteString(e.status)
+	default:
+		b.WriteString(explorerDimStyle.Render(explorerHelp))
+	}
+	return b.String()
+}
+
+// exploreJSON shows a resource in the tree explorer until the user quits.
+func exploreJSON(title string, raw json.RawMessage) error {
+	root, err := fhir.ParseTree(raw)
+	if err != nil {
+		return fmt.Errorf("parsing resource: %w", err)
+	}
+	if len(root.Children) == 0 {
+		return fmt.Errorf("resource is empty")
+	}
+	e := newExplorer(title, root)
+	e.clip = newClipTargets(raw)
+	_, err = tea.NewProgram(e, tea.WithAltScreen()).Run()
+	return err
+}
+
+// ExploreResource reads any resource by type and ID and opens it in the tree
+// explorer. A resource from a restricted chart asks for a break-the-glass
+// reason first.
+func (a *App) ExploreResource() {
+	var ref string
+	err := huh.NewInput().
+		Title("Resource (e.g., Patient/123)").
+		Value(&ref).
+		Validate(func(s string) error {
+			rt, id, ok := strings.Cut(strings.TrimSpace(s), "/")
+			if !ok || rt == "" || id == "" || strings.Contains(id, "/") {
+				return fmt.Errorf("use ResourceType/id")
+			}
+			return nil
+		}).
+		Run()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	resourceType, id, _ := strings.Cut(strings.TrimSpace(ref), "/")
+
+	var raw json.RawMessage
+	var fetchErr error
+	err = runSpinner("Loading resource...", func(ctx context.Context) {
+		raw, fetchErr = a.readResource(ctx, resourceType, id)
+	})
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	if m, err := fhir.Parse(raw); err == nil {
+		if err := a.openChartOf(m); err != nil {
+			if !isAbort(err) {
+				ShowError(err)
+				PressEnter()
+			}
+			return
+		}
+	}
+
+	if err := exploreJSON(resourceType+"/"+id, raw); err != nil {
+		ShowError(err)
+		PressEnter()
+	}
+}
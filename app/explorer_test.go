@@ -0,0 +1,54 @@
+package app
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+func TestExplorerSearchAndCollapse(t *testing.T) {
+	root, err := fhir.ParseTree([]byte(`{"resourceType":"Patient","id":"p1",
+		"name":[{"family":"Garcia","given":["Maria"]}],
+		"telecom":[{"system":"phone","value":"555-0101"},{"system":"email","value":"maria@example.com"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := newExplorer("Patient/p1", root)
+	keys := func(s ...string) {
+		for _, k := range s {
+			msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+			switch k {
+			case "enter":
+				msg = tea.KeyMsg{Type: tea.KeyEnter}
+			case "left":
+				msg = tea.KeyMsg{Type: tea.KeyLeft}
+			}
+			e.Update(msg)
+		}
+	}
+
+	// Searching expands the collapsed telecom array to reach the match.
+	keys("/", "s", "y", "s", "enter")
+	if got := e.current().JSONPath(); got != "$.telecom[0].system" {
+		t.Fatalf("first match at %s", got)
+	}
+	keys("n")
+	if got := e.current().JSONPath(); got != "$.telecom[1].system" {
+		t.Errorf("next match at %s", got)
+	}
+	keys("n")
+	if got := e.current().JSONPath(); got != "$.telecom[0].system" {
+		t.Errorf("search did not wrap around, at %s", got)
+	}
+
+	// Left on a leaf goes to its parent, and again collapses it.
+	keys("left", "left")
+	if got := e.current().JSONPath(); got != "$.telecom[0]" || e.expanded[e.current()] {
+		t.Errorf("after collapsing, at %s (expanded %v)", got, e.expanded[e.current()])
+	}
+	keys("c")
+	if len(e.visible) != len(root.Children) {
+		t.Errorf("collapse all shows %d lines, want %d", len(e.visible), len(root.Children))
+	}
+}
@@ -0,0 +1,187 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+var commonFlags = []string{
+	"Fall risk",
+	"Interpreter needed",
+	"Latex allergy",
+	"Difficult venous access",
+	"Hearing impaired",
+}
+
+// CreateFlag lets the user pick a patient and raise an alert flag.
+func (a *App) CreateFlag() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	options := huh.NewOptions(commonFlags...)
+	options = append(options, huh.NewOption("Other (free text)", ""))
+
+	var text string
+	err = huh.NewSelect[string]().
+		Title("Alert flag").
+		Options(options...).
+		Value(&text).
+		Run()
+	if err == nil && text == "" {
+		err = huh.NewInput().Title("Flag text").Value(&text).Run()
+	}
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	body := fhir.NewFlag(patientID, text)
+
+	var created json.RawMessage
+	var apiErr error
+
+	err = runSpinner("Creating flag...", func(ctx context.Context) {
+		created, apiErr = a.createResource(ctx, "Flag", body)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(fmt.Errorf("creating flag: %w", apiErr))
+		PressEnter()
+		return
+	}
+
+	id := fhir.ResourceID(created)
+	a.emit(context.Background(), EventFlagCreated, "Flag", id, patientID)
+	fmt.Printf("\n  Flagged patient: %s (ID: %s)\n", text, id)
+	PressEnter()
+}
+
+// ExpireFlag lets the user pick an active flag and mark it inactive.
+func (a *App) ExpireFlag() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	ctx := context.Background()
+	var flags []json.RawMessage
+	var fetchErr error
+
+	err = runSpinner("Loading flags...", func(ctx context.Context) {
+		flags, fetchErr = a.searchByPatient(ctx, "Flag", patientID, "")
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	var options []huh.Option[string]
+	for _, raw := range flags {
+		m, err := fhir.Parse(raw)
+		if err != nil || mapStr(m, "status") != "active" {
+			continue
+		}
+		options = append(options, huh.NewOption(fhir.FlagText(m), mapStr(m, "id")))
+	}
+
+	if len(options) == 0 {
+		fmt.Println("\n  No active flags for this patient.")
+		PressEnter()
+		return
+	}
+
+	var flagID string
+	err = huh.NewSelect[string]().
+		Title("Select flag to expire").
+		Options(options...).
+		Value(&flagID).
+		Run()
+
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var apiErr error
+
+	err = runSpinner("Expiring flag...", func(ctx context.Context) {
+		raw, err := a.readResource(ctx, "Flag", flagID)
+		if err != nil {
+			apiErr = fmt.Errorf("reading flag: %w", err)
+			return
+		}
+
+		var flag map[string]any
+		if err := json.Unmarshal(raw, &flag); err != nil {
+			apiErr = fmt.Errorf("parsing flag: %w", err)
+			return
+		}
+		flag["status"] = "inactive"
+		period, _ := flag["period"].(map[string]any)
+		if period == nil {
+			period = map[string]any{}
+		}
+		period["end"] = time.Now().UTC().Format(time.RFC3339)
+		flag["period"] = period
+
+		updated, err := json.Marshal(flag)
+		if err != nil {
+			apiErr = fmt.Errorf("marshaling flag: %w", err)
+			return
+		}
+
+		_, err = a.updateResource(ctx, "Flag", flagID, updated)
+		if err != nil {
+			apiErr = fmt.Errorf("updating flag: %w", err)
+			return
+		}
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+		PressEnter()
+		return
+	}
+
+	a.emit(ctx, EventFlagExpired, "Flag", flagID, patientID)
+	fmt.Printf("\n  Expired flag %s\n", flagID)
+	PressEnter()
+}
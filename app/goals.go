@@ -0,0 +1,130 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// parseGoalTargets reads PHENOSTORE_GOAL_TARGETS: comma-separated
+// measure=target entries, such as "hba1c=7,bp=130/80".
+func parseGoalTargets(s string) (map[string]fhir.GoalTarget, error) {
+	targets := make(map[string]fhir.GoalTarget)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		measure, known := fhir.LookupGoalMeasure(strings.TrimSpace(key))
+		if ok && !known {
+			return nil, fmt.Errorf("unknown measure %q in PHENOSTORE_GOAL_TARGETS (use one of: %s)", strings.TrimSpace(key), strings.Join(goalMeasureKeys(), ", "))
+		}
+		t, err := fhir.ParseGoalTarget(measure, value)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("invalid PHENOSTORE_GOAL_TARGETS entry %q (use measure=target, e.g. hba1c=7 or bp=130/80)", entry)
+		}
+		targets[measure.Key] = t
+	}
+	return targets, nil
+}
+
+func goalMeasureKeys() []string {
+	keys := make([]string, len(fhir.GoalMeasures))
+	for i, m := range fhir.GoalMeasures {
+		keys[i] = m.Key
+	}
+	return keys
+}
+
+// goalTargets returns the targets a patient is held to: those of their
+// active Goals, and the configured default for any measure they have no
+// Goal for.
+func (a *App) goalTargets(goals []json.RawMessage) []fhir.GoalTarget {
+	own := make(map[string]fhir.GoalTarget)
+	for _, t := range fhir.GoalTargets(goals) {
+		own[t.Measure.Key] = t
+	}
+	var targets []fhir.GoalTarget
+	for _, m := range fhir.GoalMeasures {
+		if t, ok := own[m.Key]; ok {
+			targets = append(targets, t)
+		} else if t, ok := a.GoalTargets[m.Key]; ok {
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+// SetGoalTarget records a numeric target for a patient as a Goal, which
+// Patient Summary then checks their latest value against.
+func (a *App) SetGoalTarget() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	options := make([]huh.Option[int], len(fhir.GoalMeasures))
+	for i, m := range fhir.GoalMeasures {
+		options[i] = huh.NewOption(m.Name, i)
+	}
+	var idx int
+	var value string
+	err = huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[int]().
+				Title("Measure").
+				Options(options...).
+				Value(&idx),
+		),
+		huh.NewGroup(
+			huh.NewInput().
+				TitleFunc(func() string {
+					if fhir.GoalMeasures[idx].Key == "bp" {
+						return "Target: below systolic/diastolic mmHg (e.g. 130/80)"
+					}
+					return "Target: below " + fhir.GoalMeasures[idx].Unit + " (e.g. 7)"
+				}, &idx).
+				Value(&value).
+				Validate(func(s string) error {
+					_, err := fhir.ParseGoalTarget(fhir.GoalMeasures[idx], s)
+					return err
+				}),
+		),
+	).Run()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	target, _ := fhir.ParseGoalTarget(fhir.GoalMeasures[idx], value)
+
+	var created json.RawMessage
+	var apiErr error
+	err = runSpinner("Saving goal...", func(ctx context.Context) {
+		created, apiErr = a.createResource(ctx, "Goal", fhir.NewGoal(patientID, target))
+	})
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(fmt.Errorf("creating goal: %w", apiErr))
+		PressEnter()
+		return
+	}
+
+	fmt.Printf("\n  Set goal %s %s (ID: %s)\n", target.Measure.Name, target, fhir.ResourceID(created))
+	PressEnter()
+}
@@ -0,0 +1,41 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+func TestParseGoalTargets(t *testing.T) {
+	targets, err := parseGoalTargets(" hba1c=6.5%, bp = 130/80 ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := targets["hba1c"].String(); got != "< 6.5%" {
+		t.Errorf("hba1c = %q", got)
+	}
+	if got := targets["bp"].String(); got != "< 130/80 mmHg" {
+		t.Errorf("bp = %q", got)
+	}
+
+	for _, bad := range []string{"hba1c", "hba1c=", "hba1c=seven", "bp=130", "bp=130/80/70", "ldl=100"} {
+		if _, err := parseGoalTargets(bad); err == nil {
+			t.Errorf("parseGoalTargets(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestGoalTargetsPreferOwnGoal(t *testing.T) {
+	hba1c, _ := fhir.LookupGoalMeasure("hba1c")
+	bp, _ := fhir.LookupGoalMeasure("bp")
+	a := &App{GoalTargets: map[string]fhir.GoalTarget{
+		"hba1c": {Measure: hba1c, Value: 7},
+		"bp":    {Measure: bp, Value: 140, Diastolic: 90},
+	}}
+	own := fhir.NewGoal("p1", fhir.GoalTarget{Measure: bp, Value: 130, Diastolic: 80})
+	got := a.goalTargets([]json.RawMessage{own})
+	if len(got) != 2 || got[0].String() != "< 7%" || got[1].String() != "< 130/80 mmHg" {
+		t.Errorf("goalTargets = %v", got)
+	}
+}
@@ -0,0 +1,350 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	neturl "net/url"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// pickGroup fetches patient cohorts and presents a select. Returns
+// (nil, nil) if no cohorts exist.
+func (a *App) pickGroup() (map[string]any, error) {
+	var groups []json.RawMessage
+	var fetchErr error
+
+	err := runSpinner("Loading cohorts...", func(ctx context.Context) {
+		groups, fetchErr = a.searchAllPages(ctx, "Group", 100, neturl.Values{fhir.SearchGroupType: {"person"}}, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if fetchErr != nil {
+		return nil, fetchErr
+	}
+
+	byID := make(map[string]map[string]any)
+	var options []huh.Option[string]
+	for _, raw := range groups {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		id := mapStr(m, "id")
+		byID[id] = m
+		label := fmt.Sprintf("%s (%d members)", mapStr(m, "name"), len(fhir.GroupMemberIDs(m)))
+		if manager := fhir.GroupManager(m); manager != "" {
+			label += ", managed by " + manager
+		}
+		options = append(options, huh.NewOption(label, id))
+	}
+	if len(options) == 0 {
+		fmt.Println("\n  No cohorts found. Create one first.")
+		return nil, nil
+	}
+
+	var groupID string
+	err = huh.NewSelect[string]().
+		Title("Select a cohort").
+		Options(options...).
+		Value(&groupID).
+		Run()
+	if err != nil {
+		return nil, err
+	}
+	return byID[groupID], nil
+}
+
+// pickMembers shows every patient in a multi-select with the current
+// members preselected, and returns the chosen patient IDs and their names.
+func (a *App) pickMembers(title string, current []string) ([]string, map[string]string, error) {
+	var patients []json.RawMessage
+	var fetchErr error
+
+	err := runSpinner("Loading patients...", func(ctx context.Context) {
+		patients, fetchErr = a.fetchAllPatients(ctx)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if fetchErr != nil {
+		return nil, nil, fetchErr
+	}
+
+	isMember := make(map[string]bool)
+	for _, id := range current {
+		isMember[id] = true
+	}
+	names := make(map[string]string)
+	var options []huh.Option[string]
+	for _, raw := range patients {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		id := fhir.ResourceID(raw)
+		names[id] = fhir.PatientName(m)
+		label := fmt.Sprintf("%s (%s)", names[id], mapStr(m, "birthDate"))
+		options = append(options, huh.NewOption(label, id).Selected(isMember[id]))
+	}
+
+	var chosen []string
+	err = huh.NewMultiSelect[string]().
+		Title(title).
+		Description("Space toggles a patient, enter saves.").
+		Options(options...).
+		Value(&chosen).
+		Filterable(true).
+		Run()
+	return chosen, names, err
+}
+
+// CreateCohort creates a Group of patients, e.g. "Diabetes registry", or a
+// clinician's panel such as "Dr. Lee's panel".
+func (a *App) CreateCohort() {
+	var name, managedBy string
+	err := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("Cohort name (e.g., Diabetes registry, Dr. Lee's panel)").
+				Value(&name).
+				Validate(func(s string) error {
+					if strings.TrimSpace(s) == "" {
+						return fmt.Errorf("required")
+					}
+					return nil
+				}),
+			huh.NewInput().
+				Title("Managed by (e.g., Dr. Lee; blank for none)").
+				Value(&managedBy),
+		),
+	).Run()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	members, names, err := a.pickMembers("Members", nil)
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	group, _ := fhir.Parse(fhir.NewGroup(strings.TrimSpace(name), strings.TrimSpace(managedBy)))
+	fhir.SetGroupMembers(group, members, names)
+	body, _ := json.Marshal(group)
+
+	var created json.RawMessage
+	var apiErr error
+
+	err = runSpinner("Creating cohort...", func(ctx context.Context) {
+		created, apiErr = a.createResource(ctx, "Group", body)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(fmt.Errorf("creating cohort: %w", apiErr))
+		PressEnter()
+		return
+	}
+
+	id := fhir.ResourceID(created)
+	a.emit(context.Background(), EventGroupCreated, "Group", id, "")
+	fmt.Printf("\n  Created cohort %q with %d members (ID: %s)\n", name, len(members), id)
+	PressEnter()
+}
+
+// ManageCohortMembers lets the user pick a cohort and add or remove patients.
+func (a *App) ManageCohortMembers() {
+	group, err := a.pickGroup()
+	if err != nil || group == nil {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	before := fhir.GroupMemberIDs(group)
+	members, names, err := a.pickMembers(fmt.Sprintf("Members of %s", mapStr(group, "name")), before)
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	added, removed := diffMembers(before, members)
+	if added == 0 && removed == 0 {
+		fmt.Println("\n  No changes.")
+		PressEnter()
+		return
+	}
+
+	groupID := mapStr(group, "id")
+	var apiErr error
+	err = runSpinner("Updating cohort...", func(ctx context.Context) {
+		// Re-read so a concurrent edit to other fields isn't lost.
+		raw, err := a.readResource(ctx, "Group", groupID)
+		if err != nil {
+			apiErr = fmt.Errorf("reading cohort: %w", err)
+			return
+		}
+		g, err := fhir.Parse(raw)
+		if err != nil {
+			apiErr = fmt.Errorf("parsing cohort: %w", err)
+			return
+		}
+		fhir.SetGroupMembers(g, members, names)
+		body, _ := json.Marshal(g)
+		if _, err := a.updateResource(ctx, "Group", groupID, body); err != nil {
+			apiErr = fmt.Errorf("updating cohort: %w", err)
+		}
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+		PressEnter()
+		return
+	}
+
+	a.emit(context.Background(), EventGroupUpdated, "Group", groupID, "")
+	fmt.Printf("\n  Added %d and removed %d members (%d total)\n", added, removed, len(members))
+	PressEnter()
+}
+
+// diffMembers counts the IDs added to and removed from a member list.
+func diffMembers(before, after []string) (added, removed int) {
+	was := make(map[string]bool)
+	for _, id := range before {
+		was[id] = true
+	}
+	is := make(map[string]bool)
+	for _, id := range after {
+		is[id] = true
+		if !was[id] {
+			added++
+		}
+	}
+	for _, id := range before {
+		if !is[id] {
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// ViewCohorts lists every cohort with its members.
+func (a *App) ViewCohorts() {
+	var groups []json.RawMessage
+	var fetchErr error
+
+	err := runSpinner("Loading cohorts...", func(ctx context.Context) {
+		groups, fetchErr = a.searchAllPages(ctx, "Group", 100, neturl.Values{fhir.SearchGroupType: {"person"}}, nil)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	if len(groups) == 0 {
+		fmt.Println("  No cohorts found.")
+	}
+	for _, raw := range groups {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		fhir.PrintGroup(m)
+		fmt.Println()
+	}
+	PressEnter()
+}
+
+// cohortScope is the set of patient IDs views are limited to. A nil scope
+// means the whole clinic.
+type cohortScope map[string]bool
+
+func newCohortScope(group map[string]any) cohortScope {
+	s := make(cohortScope)
+	for _, id := range fhir.GroupMemberIDs(group) {
+		s[id] = true
+	}
+	return s
+}
+
+// filter keeps the resources that belong to a patient in the scope.
+func (s cohortScope) filter(resources []json.RawMessage) []json.RawMessage {
+	if s == nil {
+		return resources
+	}
+	var out []json.RawMessage
+	for _, raw := range resources {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		patientID := resourcePatient(m)
+		if mapStr(m, "resourceType") == "Patient" {
+			patientID = mapStr(m, "id")
+		}
+		if s[patientID] {
+			out = append(out, raw)
+		}
+	}
+	return out
+}
+
+// CohortDashboard shows the clinic dashboard for one cohort's members.
+func (a *App) CohortDashboard() {
+	group, err := a.pickGroup()
+	if err != nil || group == nil {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	a.clinicDashboard(mapStr(group, "name"), newCohortScope(group))
+}
+
+// CohortReport runs a custom report over one cohort's members.
+func (a *App) CohortReport() {
+	group, err := a.pickGroup()
+	if err != nil || group == nil {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	a.customReports(mapStr(group, "name"), newCohortScope(group))
+}
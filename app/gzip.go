@@ -0,0 +1,57 @@
+package app
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// gzipTransport asks the server for gzip-compressed responses and
+// decompresses them before the SDK sees them. Go's own transport does the
+// same unless a request sets Accept-Encoding itself; doing it here keeps it
+// explicit, lets PHENOSTORE_GZIP=false turn it off, and works whatever the
+// base transport. Search bundles are repetitive JSON and usually shrink to a
+// fifth of their size or less, which matters on slow links.
+type gzipTransport struct {
+	base http.RoundTripper
+}
+
+func (t *gzipTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.Header.Get("Content-Encoding") != "gzip" {
+		return resp, err
+	}
+	resp.Body = &gzipBody{body: resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// gzipBody decompresses a response body as it is read. The gzip reader is
+// created on the first Read, since responses without a body, such as a 204
+// or a HEAD, may still be marked as gzip.
+type gzipBody struct {
+	body io.ReadCloser
+	once sync.Once
+	zr   *gzip.Reader
+	err  error
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	b.once.Do(func() { b.zr, b.err = gzip.NewReader(b.body) })
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.zr.Read(p)
+}
+
+func (b *gzipBody) Close() error {
+	return b.body.Close()
+}
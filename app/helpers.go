@@ -7,8 +7,10 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"time"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/huh/spinner"
 	"github.com/charmbracelet/lipgloss"
@@ -27,68 +29,80 @@ func isAbort(err error) bool {
 	return errors.Is(err, huh.ErrUserAborted)
 }
 
-// PickPatient fetches all patients and presents a filterable select.
-// Returns ("", nil) if no patients exist.
+// PickPatient presents the patients to pick from. Depending on
+// PatientPicker it lists every patient in a filterable select or searches
+// the server by name, birth date, or MRN first; by default it lists them
+// when they fit on one page of 100 and searches otherwise. Returns ("", nil)
+// if no patients exist. Picking a restricted patient asks for a
+// break-the-glass reason first.
 func (a *App) PickPatient() (string, error) {
 	ctx := context.Background()
 	var patients []json.RawMessage
+	var more, prefetched bool
 	var fetchErr error
 
-	err := spinner.New().
-		Title("Loading patients...").
-		Action(func() {
-			patients, fetchErr = a.fetchAllPatients(ctx)
-		}).
-		Run()
-	if err != nil {
-		return "", err
-	}
-	if fetchErr != nil {
-		return "", fetchErr
+	if a.PatientPicker != PatientPickerSearch {
+		if patients, more, prefetched, fetchErr = a.prefetchedPatients(); fetchErr != nil {
+			return "", fetchErr
+		}
 	}
-
-	if len(patients) == 0 {
-		fmt.Println("\n  No patients found. Try seeding sample data first.")
-		return "", nil
+	if a.PatientPicker != PatientPickerSearch && !prefetched {
+		err := runSpinner("Loading patients...", func(ctx context.Context) {
+			patients, more, fetchErr = a.loadPatientList(ctx)
+			if fetchErr != nil {
+				a.auditPatientList(ctx, nil, fetchErr)
+			}
+		})
+		if err != nil {
+			return "", err
+		}
+		if fetchErr != nil {
+			return "", fetchErr
+		}
 	}
 
-	var options []huh.Option[string]
-	for _, raw := range patients {
-		m, err := fhir.Parse(raw)
+	var patientID, restrictedName string
+	if a.PatientPicker == PatientPickerSearch || more {
+		var err error
+		if patientID, restrictedName, err = a.searchForPatient(ctx); err != nil {
+			return "", err
+		}
+	} else {
+		a.auditPatientList(ctx, patients, nil)
+		if len(patients) == 0 {
+			fmt.Println("\n  No patients found. Try seeding sample data first.")
+			return "", nil
+		}
+		options, restricted := patientOptions(patients)
+		err := huh.NewSelect[string]().
+			Title("Select a patient").
+			Options(options...).
+			Value(&patientID).
+			Filtering(true).
+			Run()
 		if err != nil {
-			continue
+			return "", err
 		}
-		id := fhir.ResourceID(raw)
-		name := fhir.PatientName(m)
-		dob := mapStr(m, "birthDate")
-		label := fmt.Sprintf("%s (%s)", name, dob)
-		options = append(options, huh.NewOption(label, id))
+		restrictedName = restricted[patientID]
 	}
 
-	var patientID string
-	err = huh.NewSelect[string]().
-		Title("Select a patient").
-		Options(options...).
-		Value(&patientID).
-		Filtering(true).
-		Run()
-
-	return patientID, err
+	if restrictedName != "" {
+		if err := a.breakGlass(patientID, restrictedName); err != nil {
+			return "", err
+		}
+	}
+	return patientID, nil
 }
 
 // PickCarePlan fetches active care plans for a patient and presents a select.
 // Returns ("", nil) if no plans exist.
 func (a *App) PickCarePlan(patientID string) (string, error) {
-	ctx := context.Background()
 	var plans []json.RawMessage
 	var fetchErr error
 
-	err := spinner.New().
-		Title("Loading care plans...").
-		Action(func() {
-			plans, fetchErr = a.searchCarePlans(ctx, patientID)
-		}).
-		Run()
+	err := runSpinner("Loading care plans...", func(ctx context.Context) {
+		plans, fetchErr = a.searchCarePlans(ctx, patientID, sortRecentlySaved)
+	})
 	if err != nil {
 		return "", err
 	}
@@ -128,17 +142,97 @@ func (a *App) PickCarePlan(patientID string) (string, error) {
 	return cpID, err
 }
 
-// PressEnter waits for the user to press enter.
+// pickEncounter presents a select over a patient's encounters. The first
+// option, allLabel, returns "".
+func pickEncounter(title, allLabel string, encounters []json.RawMessage) (string, error) {
+	options := []huh.Option[string]{huh.NewOption(allLabel, "")}
+	for _, raw := range encounters {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		options = append(options, huh.NewOption(fhir.EncounterDisplay(m), mapStr(m, "id")))
+	}
+
+	var encounterID string
+	err := huh.NewSelect[string]().
+		Title(title).
+		Options(options...).
+		Value(&encounterID).
+		Run()
+	return encounterID, err
+}
+
+// PressEnter waits for the user to press enter. After a detail view it also
+// offers keys to copy the resource shown.
 func PressEnter() {
+	if raw := detail.raw; raw != nil {
+		detail.raw = nil
+		if waitWithCopyKeys(raw) {
+			return
+		}
+	}
+	if screenLock.after > 0 {
+		waitForEnter()
+		return
+	}
 	fmt.Print("\nPress enter to continue...")
 	bufio.NewReader(os.Stdin).ReadBytes('\n')
 }
 
-// ShowError displays an error message.
+// ShowError displays an error message. An operation cancelled with Ctrl+C
+// is reported quietly rather than as an error.
 func ShowError(err error) {
+	if errors.Is(err, errCancelled) {
+		msg := "Cancelled."
+		if err != errCancelled {
+			msg = err.Error()
+		}
+		fmt.Println(timingStyle.Render("\n  " + msg))
+		return
+	}
 	fmt.Println(errorStyle.Render("\n  Error: " + err.Error()))
 }
 
+// errCancelled is returned by runSpinner when Ctrl+C cancels its action.
+var errCancelled = errors.New("cancelled")
+
+// runSpinner shows a spinner titled title while action runs. Ctrl+C, as a
+// key press in the spinner or as SIGINT, cancels the context action is
+// given, which abandons its pending HTTP calls, and runSpinner returns
+// errCancelled. It waits for action to return first, so that action does
+// not set its caller's results after the caller has moved on.
+func runSpinner(title string, action func(ctx context.Context)) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		action(ctx)
+	}()
+	err := spinner.New().
+		Title(title).
+		Context(ctx).
+		ActionWithErr(func(context.Context) error {
+			<-done
+			return nil
+		}).
+		Run()
+	if err == nil {
+		return nil
+	}
+	cancelled := errors.Is(err, tea.ErrInterrupted) || ctx.Err() != nil
+	cancel()
+	<-done
+	if cancelled {
+		return errCancelled
+	}
+	return err
+}
+
 // showTiming prints a dimmed timing line after API results.
 func showTiming(msg string, d time.Duration) {
 	var dur string
@@ -147,5 +241,5 @@ func showTiming(msg string, d time.Duration) {
 	} else {
 		dur = fmt.Sprintf("%.1fs", d.Seconds())
 	}
-	fmt.Println(timingStyle.Render(fmt.Sprintf("  %s in %s", msg, dur)))
+	fmt.Println(timingStyle.Render(fmt.Sprintf("  %s in %s%s", msg, dur, retryNote())))
 }
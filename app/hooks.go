@@ -0,0 +1,122 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Event names fired after successful mutations.
+const (
+	EventPatientCreated            = "patient.created"
+	EventPatientUpdated            = "patient.updated"
+	EventPatientDeleted            = "patient.deleted"
+	EventPatientBreakGlass         = "patient.breakglass"
+	EventObservationCreated        = "observation.created"
+	EventObservationUpdated        = "observation.updated"
+	EventObservationDeleted        = "observation.deleted"
+	EventConditionCreated          = "condition.created"
+	EventConditionUpdated          = "condition.updated"
+	EventConditionResolved         = "condition.resolved"
+	EventCarePlanCreated           = "careplan.created"
+	EventCarePlanUpdated           = "careplan.updated"
+	EventCarePlanCompleted         = "careplan.completed"
+	EventNutritionOrderCreated     = "nutritionorder.created"
+	EventNutritionOrderRevoked     = "nutritionorder.revoked"
+	EventFlagCreated               = "flag.created"
+	EventFlagExpired               = "flag.expired"
+	EventDeviceCreated             = "device.created"
+	EventClaimCreated              = "claim.created"
+	EventDocumentReferenceCreated  = "documentreference.created"
+	EventCompositionCreated        = "composition.created"
+	EventGroupCreated              = "group.created"
+	EventGroupUpdated              = "group.updated"
+	EventEpisodeOfCareCreated      = "episodeofcare.created"
+	EventEncounterCreated          = "encounter.created"
+	EventEncounterUpdated          = "encounter.updated"
+	EventImagingStudyCreated       = "imagingstudy.created"
+	EventDetectedIssueCreated      = "detectedissue.created"
+	EventDetectedIssueAcknowledged = "detectedissue.acknowledged"
+)
+
+// Event describes something the app just did to a resource.
+type Event struct {
+	Name         string    `json:"event"`
+	ResourceType string    `json:"resourceType"`
+	ResourceID   string    `json:"resourceId"`
+	PatientID    string    `json:"patientId,omitempty"`
+	Time         time.Time `json:"time"`
+}
+
+// Hook is notified of app events. Returning an error does not undo the
+// action; the error is shown to the user.
+type Hook interface {
+	HandleEvent(ctx context.Context, e Event) error
+}
+
+// HookFunc adapts a function to the Hook interface.
+type HookFunc func(ctx context.Context, e Event) error
+
+// HandleEvent calls f(ctx, e).
+func (f HookFunc) HandleEvent(ctx context.Context, e Event) error {
+	return f(ctx, e)
+}
+
+// CommandHook runs a shell command for every event. The event is passed as
+// JSON on stdin and as PHENOSTORE_EVENT* environment variables.
+type CommandHook struct {
+	Command string
+	Timeout time.Duration
+}
+
+// HandleEvent runs the command and waits for it to finish.
+func (h CommandHook) HandleEvent(ctx context.Context, e Event) error {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.Command)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(),
+		"PHENOSTORE_EVENT="+e.Name,
+		"PHENOSTORE_EVENT_RESOURCE_TYPE="+e.ResourceType,
+		"PHENOSTORE_EVENT_RESOURCE_ID="+e.ResourceID,
+		"PHENOSTORE_EVENT_PATIENT_ID="+e.PatientID,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("hook %q: %w: %s", h.Command, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// emit notifies every registered hook of an event. Hook failures are
+// reported but never fail the action that triggered them.
+func (a *App) emit(ctx context.Context, name, resourceType, resourceID, patientID string) {
+	if len(a.Hooks) == 0 {
+		return
+	}
+	e := Event{
+		Name:         name,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		PatientID:    patientID,
+		Time:         time.Now().UTC(),
+	}
+	for _, h := range a.Hooks {
+		if err := h.HandleEvent(ctx, e); err != nil {
+			ShowError(err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// RecordImagingStudy records an ordered or completed imaging study as a
+// placeholder ImagingStudy: modality, description, and date, with no images.
+func (a *App) RecordImagingStudy() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var modalityIdx int
+	var modalityOptions []huh.Option[int]
+	for i, m := range fhir.ImagingModalities {
+		modalityOptions = append(modalityOptions, huh.NewOption(fmt.Sprintf("%s (%s)", m.Display, m.Code), i))
+	}
+	var description string
+	date := time.Now().Format("2006-01-02")
+	status := "registered"
+
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[int]().Title("Modality").Options(modalityOptions...).Value(&modalityIdx),
+			huh.NewInput().Title("Description (e.g., Chest X-ray, 2 views)").Value(&description).Validate(func(s string) error {
+				if strings.TrimSpace(s) == "" {
+					return fmt.Errorf("required")
+				}
+				return nil
+			}),
+			huh.NewInput().Title("Date (YYYY-MM-DD)").Value(&date).Validate(func(s string) error {
+				if _, err := time.Parse("2006-01-02", s); err != nil {
+					return fmt.Errorf("use YYYY-MM-DD")
+				}
+				return nil
+			}),
+			huh.NewSelect[string]().
+				Title("Status").
+				Options(
+					huh.NewOption("Ordered", "registered"),
+					huh.NewOption("Images available", "available"),
+				).
+				Value(&status),
+		),
+	)
+	if err := form.Run(); err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	started, _ := time.Parse("2006-01-02", date)
+	modality := fhir.ImagingModalities[modalityIdx]
+	description = strings.TrimSpace(description)
+	body := fhir.NewImagingStudy(patientID, modality, description, status, started)
+
+	var created json.RawMessage
+	var apiErr error
+
+	err = runSpinner("Recording imaging study...", func(ctx context.Context) {
+		created, apiErr = a.createResource(ctx, "ImagingStudy", body)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(fmt.Errorf("creating imaging study: %w", apiErr))
+		PressEnter()
+		return
+	}
+
+	id := fhir.ResourceID(created)
+	a.emit(context.Background(), EventImagingStudyCreated, "ImagingStudy", id, patientID)
+	fmt.Printf("\n  Recorded %s study %s — %s (ID: %s)\n", modality.Code, description, status, id)
+	PressEnter()
+}
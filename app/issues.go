@@ -0,0 +1,205 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// observationAlerts runs the threshold and trend alert rules over an
+// observation. Trend rules load the patient's earlier results with the same
+// code.
+func (a *App) observationAlerts(ctx context.Context, m map[string]any) ([]fhir.Alert, error) {
+	alerts := fhir.ObservationAlerts(m)
+	code := fhir.TrendCode(m)
+	if code == "" {
+		return alerts, nil
+	}
+	raws, err := a.searchResources(ctx, "Observation", 50, map[string]string{
+		fhir.SearchObservationPatient: fhir.PatientRef(m),
+		fhir.SearchObservationCode:    code,
+		fhir.SearchSort:               "-date",
+	})
+	if err != nil {
+		return alerts, fmt.Errorf("loading earlier results for trend alerts: %w", err)
+	}
+	var history []map[string]any
+	for _, raw := range raws {
+		if h, err := fhir.Parse(raw); err == nil {
+			history = append(history, h)
+		}
+	}
+	return append(alerts, fhir.TrendAlerts(m, history)...), nil
+}
+
+// raiseIssues runs the alert rules over newly created observations and
+// records a DetectedIssue for each alert that fires. It returns the issues
+// created before any error.
+func (a *App) raiseIssues(ctx context.Context, observations []json.RawMessage) ([]json.RawMessage, error) {
+	var issues []json.RawMessage
+	for _, raw := range observations {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		alerts, err := a.observationAlerts(ctx, m)
+		if err != nil {
+			return issues, err
+		}
+		for _, alert := range alerts {
+			body := fhir.NewDetectedIssue(fhir.PatientRef(m), alert, "Observation/"+mapStr(m, "id"), time.Now())
+			created, err := a.createResource(ctx, "DetectedIssue", body)
+			if err != nil {
+				return issues, fmt.Errorf("recording %s alert: %w", alert.Display, err)
+			}
+			issues = append(issues, created)
+		}
+	}
+	return issues, nil
+}
+
+// issueEntries returns transaction entries recording a DetectedIssue for
+// each alert that fires for an observation being created in the same
+// bundle under urn.
+func (a *App) issueEntries(ctx context.Context, urn string, observation json.RawMessage) ([]map[string]any, error) {
+	m, err := fhir.Parse(observation)
+	if err != nil {
+		return nil, nil
+	}
+	alerts, err := a.observationAlerts(ctx, m)
+	if err != nil {
+		return nil, err
+	}
+	var entries []map[string]any
+	for _, alert := range alerts {
+		entries = append(entries, fhir.BundleEntry("DetectedIssue",
+			fhir.NewDetectedIssue(fhir.PatientRef(m), alert, urn, time.Now())))
+	}
+	return entries, nil
+}
+
+// reportIssues emits an event for each created issue and prints it.
+func (a *App) reportIssues(ctx context.Context, issues []json.RawMessage) {
+	for _, raw := range issues {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		a.emit(ctx, EventDetectedIssueCreated, "DetectedIssue", mapStr(m, "id"), resourcePatient(m))
+		fmt.Printf("  ! Alert: %s\n", fhir.DetectedIssueDisplay(m))
+	}
+}
+
+// openIssues returns the DetectedIssues that have not been acknowledged.
+// DetectedIssue has no status search parameter, so issues are filtered here.
+func (a *App) openIssues(ctx context.Context) ([]json.RawMessage, error) {
+	raws, err := a.searchAllPages(ctx, "DetectedIssue", 200, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var open []json.RawMessage
+	for _, raw := range raws {
+		if m, err := fhir.Parse(raw); err == nil && fhir.IssueOpen(m) {
+			open = append(open, raw)
+		}
+	}
+	return open, nil
+}
+
+// AcknowledgeAlerts lists open DetectedIssues across the clinic and marks the
+// chosen ones acknowledged, which removes them from the Clinic Dashboard.
+func (a *App) AcknowledgeAlerts() {
+	ctx := context.Background()
+	var issues []map[string]any
+	names := make(map[string]string)
+	var fetchErr error
+
+	err := runSpinner("Loading alerts...", func(ctx context.Context) {
+		var raws []json.RawMessage
+		raws, fetchErr = a.openIssues(ctx)
+		for _, raw := range raws {
+			m, err := fhir.Parse(raw)
+			if err != nil {
+				continue
+			}
+			issues = append(issues, m)
+			if patientID := resourcePatient(m); names[patientID] == "" {
+				names[patientID] = a.resolvePatientName(ctx, patientID)
+			}
+		}
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+	if len(issues) == 0 {
+		fmt.Println("\n  No open alerts.")
+		PressEnter()
+		return
+	}
+
+	var options []huh.Option[int]
+	for i, m := range issues {
+		label := fmt.Sprintf("%s  %s", names[resourcePatient(m)], fhir.DetectedIssueDisplay(m))
+		options = append(options, huh.NewOption(label, i))
+	}
+	var chosen []int
+	err = huh.NewMultiSelect[int]().
+		Title("Acknowledge alerts").
+		Options(options...).
+		Value(&chosen).
+		Run()
+	if err != nil || len(chosen) == 0 {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var entries []map[string]any
+	var targets []string
+	now := time.Now()
+	for _, i := range chosen {
+		m := issues[i]
+		fhir.AcknowledgeIssue(m, a.ProvenanceAgent, now)
+		body, _ := json.Marshal(m)
+		entries = append(entries, fhir.UpdateEntry("DetectedIssue", mapStr(m, "id"), body))
+		targets = append(targets, "DetectedIssue/"+mapStr(m, "id"))
+	}
+
+	var updated int
+	var apiErr error
+	err = runSpinner("Acknowledging alerts...", func(ctx context.Context) {
+		updated, apiErr = a.processChanges(ctx, entries, targets, "UPDATE")
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+		PressEnter()
+		return
+	}
+
+	for _, i := range chosen {
+		m := issues[i]
+		a.emit(ctx, EventDetectedIssueAcknowledged, "DetectedIssue", mapStr(m, "id"), resourcePatient(m))
+	}
+	fmt.Printf("\n  Acknowledged %d alerts\n", updated)
+	PressEnter()
+}
@@ -0,0 +1,156 @@
+package app
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// rateLimiter paces work to at most a number of units a second, spreading
+// them evenly rather than letting them through in bursts. A nil
+// *rateLimiter does not limit.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration // time per unit
+	next     time.Time     // when the next unit may start
+}
+
+// newRateLimiter returns a limiter for perSecond units a second, or nil for
+// no limit when perSecond is 0.
+func newRateLimiter(perSecond int) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Second / time.Duration(perSecond)}
+}
+
+// wait blocks until n more units may go, or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context, n int) error {
+	if l == nil {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(time.Duration(n) * l.interval)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(start))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+var (
+	loadTestGiven  = []string{"Ana", "Bruno", "Carla", "Daniel", "Elena", "Felipe", "Grace", "Hiro", "Isabel", "Jamal", "Keiko", "Luis", "Mina", "Noah", "Olga", "Priya"}
+	loadTestFamily = []string{"Almeida", "Baker", "Costa", "Diallo", "Evans", "Ferreira", "Gupta", "Hansen", "Ito", "Jones", "Kim", "Lopes", "Moreau", "Nguyen", "Okafor", "Silva"}
+)
+
+// loadTestObservation returns a random vital sign or lab result for the
+// patient, in a plausible range.
+func loadTestObservation(rng *rand.Rand, patientID string) json.RawMessage {
+	switch rng.IntN(6) {
+	case 0:
+		return fhir.NewBloodPressureObservation(patientID, 100+rng.IntN(60), 60+rng.IntN(35))
+	case 1:
+		return fhir.NewHeartRateObservation(patientID, 55+rng.IntN(50))
+	case 2:
+		return fhir.NewWeightObservation(patientID, float64(450+rng.IntN(700))/10)
+	case 3:
+		return fhir.NewTemperatureObservation(patientID, float64(360+rng.IntN(25))/10)
+	case 4:
+		return fhir.NewBloodGlucoseObservation(patientID, float64(70+rng.IntN(130)))
+	default:
+		return fhir.NewOxygenSaturationObservation(patientID, 90+rng.IntN(10))
+	}
+}
+
+// buildLoadTestBundle returns the transaction entries for patients
+// synthetic patients with observations each, all carrying the seed tag.
+// The same sizes always build the same entries, so an interrupted load
+// test can be resumed.
+func buildLoadTestBundle(patients, observations int) []map[string]any {
+	rng := rand.New(rand.NewPCG(uint64(patients), uint64(observations)))
+	entries := make([]map[string]any, 0, patients*(1+observations))
+	for i := range patients {
+		urn := fmt.Sprintf("urn:uuid:loadtest-patient-%d", i+1)
+		gender := []string{"female", "male"}[rng.IntN(2)]
+		dob := time.Date(1940+rng.IntN(80), time.Month(1+rng.IntN(12)), 1+rng.IntN(28), 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+		given := loadTestGiven[rng.IntN(len(loadTestGiven))]
+		family := loadTestFamily[rng.IntN(len(loadTestFamily))]
+		entries = append(entries, bundleEntryWithUrn(urn, "Patient", addSeedTag(fhir.NewPatient(given, family, dob, gender))))
+		for range observations {
+			entries = append(entries, obs(fhir.BundleEntry("Observation", loadTestObservation(rng, urn))))
+		}
+	}
+	pointAtFullURLs(entries)
+	return entries
+}
+
+// LoadTestSeed creates thousands of synthetic patients and observations
+// with the chunked, concurrent import Seed Sample Data uses, optionally
+// rate limited, printing each chunk with the running throughput. Everything
+// it creates carries the seed tag, so Delete Seed Data removes it.
+func (a *App) LoadTestSeed() {
+	patients, observations, rate := 1000, 10, 0
+	var confirm bool
+	err := huh.NewForm(huh.NewGroup(
+		huh.NewSelect[int]().
+			Title("Synthetic patients").
+			Options(huh.NewOption("500", 500), huh.NewOption("1,000", 1000), huh.NewOption("2,000", 2000), huh.NewOption("5,000", 5000), huh.NewOption("10,000", 10000)).
+			Value(&patients),
+		huh.NewSelect[int]().
+			Title("Observations per patient").
+			Options(huh.NewOption("5", 5), huh.NewOption("10", 10), huh.NewOption("20", 20)).
+			Value(&observations),
+		huh.NewSelect[int]().
+			Title("Rate limit").
+			Options(huh.NewOption("None", 0), huh.NewOption("500 resources/s", 500), huh.NewOption("1,000 resources/s", 1000), huh.NewOption("2,000 resources/s", 2000)).
+			Value(&rate),
+		huh.NewConfirm().
+			TitleFunc(func() string {
+				return fmt.Sprintf("Create %d resources?", patients*(1+observations))
+			}, []any{&patients, &observations}).
+			Description(fmt.Sprintf("Transactions of %d, up to %d at a time.",
+				cmp.Or(a.ChunkSize, transactionChunkSize), cmp.Or(a.ChunkConcurrency, defaultChunkConcurrency))).
+			Value(&confirm),
+	)).Run()
+	if err != nil || !confirm {
+		return
+	}
+
+	entries := buildLoadTestBundle(patients, observations)
+	fmt.Println(timingStyle.Render("  Press Ctrl+C to stop; the load test can be resumed later."))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	start := time.Now()
+	created, err := a.importInChunks(ctx, "loadtest", entries, newRateLimiter(rate))
+	elapsed := time.Since(start)
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(fmt.Errorf("load test: %w", err))
+			PressEnter()
+		}
+		return
+	}
+
+	fmt.Printf("\n  Created %d resources (%d patients, %d observations each)\n", created, patients, observations)
+	showTiming(fmt.Sprintf("Created %d resources at %.0f resources/s", created, float64(created)/elapsed.Seconds()), elapsed)
+	PressEnter()
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := newRateLimiter(1000)
+	ctx := context.Background()
+	start := time.Now()
+	for range 3 {
+		if err := l.wait(ctx, 50); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The first 50 go at once; the next 100 take 100ms at 1000 a second.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond || elapsed > time.Second {
+		t.Errorf("150 units at 1000/s took %s, want about 100ms", elapsed)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := l.wait(cancelled, 1000); err == nil {
+		t.Error("wait returned nil for a cancelled context")
+	}
+	if err := (*rateLimiter)(nil).wait(ctx, 1000); err != nil || newRateLimiter(0) != nil {
+		t.Error("a rate of 0 should not limit")
+	}
+}
+
+func TestBuildLoadTestBundle(t *testing.T) {
+	entries := buildLoadTestBundle(3, 4)
+	if len(entries) != 15 {
+		t.Fatalf("got %d entries, want 15", len(entries))
+	}
+	if importFingerprint(entries) != importFingerprint(buildLoadTestBundle(3, 4)) {
+		t.Error("the same sizes built different bundles, so a load test could not be resumed")
+	}
+	for _, e := range entries[1:5] {
+		var m map[string]any
+		if err := json.Unmarshal(e["resource"].(json.RawMessage), &m); err != nil {
+			t.Fatal(err)
+		}
+		if ref := m["subject"].(map[string]any)["reference"]; ref != "urn:uuid:loadtest-patient-1" {
+			t.Errorf("observation subject = %v, want the first patient's fullUrl", ref)
+		}
+		if !strings.Contains(string(e["resource"].(json.RawMessage)), `"code":"seed"`) {
+			t.Error("observation is missing the seed tag")
+		}
+	}
+}
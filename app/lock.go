@@ -0,0 +1,112 @@
+package app
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/huh"
+)
+
+// screenLock holds the inactivity lock settings. It is package state rather
+// than part of App because PressEnter, one of the places the app waits for
+// input, is not a method.
+var screenLock struct {
+	after  time.Duration // zero disables the lock
+	secret string        // PIN, or the client secret when no PIN is set
+	pin    bool
+}
+
+// configureLock reads PHENOSTORE_LOCK_MINUTES and PHENOSTORE_LOCK_PIN. Without
+// a PIN, unlocking requires re-entering the client secret.
+func configureLock(clientSecret string) error {
+	v := os.Getenv("PHENOSTORE_LOCK_MINUTES")
+	if v == "" {
+		return nil
+	}
+	minutes, err := strconv.Atoi(v)
+	if err != nil || minutes < 0 {
+		return fmt.Errorf("invalid PHENOSTORE_LOCK_MINUTES %q: must be a whole number of minutes", v)
+	}
+	screenLock.after = time.Duration(minutes) * time.Minute
+	screenLock.secret = clientSecret
+	if pin := os.Getenv("PHENOSTORE_LOCK_PIN"); pin != "" {
+		screenLock.secret = pin
+		screenLock.pin = true
+	}
+	return nil
+}
+
+// runMenu runs a menu select. When the inactivity lock is enabled and no
+// choice is made in time, the screen is locked and the menu shown again once
+// it is unlocked.
+func runMenu(sel *huh.Select[string]) error {
+	// Retries from a screen without a timing line are not carried into the
+	// next screen's.
+	retries.Store(0)
+	if screenLock.after == 0 {
+		return sel.Run()
+	}
+	for {
+		err := huh.NewForm(huh.NewGroup(sel)).
+			WithShowHelp(false).
+			WithTimeout(screenLock.after).
+			Run()
+		if !errors.Is(err, huh.ErrTimeout) {
+			return err
+		}
+		lockScreen()
+	}
+}
+
+// waitForEnter is PressEnter under the inactivity lock.
+func waitForEnter() {
+	err := huh.NewForm(huh.NewGroup(
+		huh.NewNote().
+			Title("Press enter to continue").
+			Next(true).
+			NextLabel("Continue"),
+	)).
+		WithShowHelp(false).
+		WithTimeout(screenLock.after).
+		Run()
+	if errors.Is(err, huh.ErrTimeout) {
+		lockScreen()
+	}
+}
+
+// lockScreen clears the terminal, including its scrollback, so patient data
+// is no longer on screen, and waits for the PIN or client secret. Aborting
+// exits the app rather than returning to where it was locked.
+func lockScreen() {
+	fmt.Print("\033[H\033[2J\033[3J")
+	fmt.Println(barStyle.Bold(true).Render(fmt.Sprintf("Locked after %s of inactivity", screenLock.after)))
+
+	title, description := "PIN", "Enter PHENOSTORE_LOCK_PIN to unlock."
+	if !screenLock.pin {
+		title, description = "Client secret", "Enter PHENOSTORE_CLIENT_SECRET to unlock."
+	}
+	for {
+		var entered string
+		err := huh.NewInput().
+			Title(title).
+			Description(description).
+			EchoMode(huh.EchoModePassword).
+			Value(&entered).
+			Run()
+		if err != nil {
+			if !isAbort(err) {
+				ShowError(err)
+			}
+			fmt.Println("\nGoodbye!")
+			os.Exit(0)
+		}
+		if subtle.ConstantTimeCompare([]byte(entered), []byte(screenLock.secret)) == 1 {
+			return
+		}
+		fmt.Println(errorStyle.Render("  Incorrect, try again."))
+	}
+}
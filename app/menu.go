@@ -10,19 +10,49 @@ import (
 func (a *App) MainMenu() {
 	for {
 		fmt.Println()
+		a.replayQueueOnMenu()
+		a.prefetchPatients()
+		title := "Community Health Clinic"
+		if a.Role != "" {
+			title += " · " + a.Role
+		}
+		if status := queueStatus(); status != "" {
+			title += " · " + status
+		}
+		options := []huh.Option[string]{
+			huh.NewOption("Seed Sample Data", "seed"),
+			huh.NewOption("Patient Summary", "summary"),
+			huh.NewOption("Export Chart Context", "context"),
+			huh.NewOption("Generate Visit Summary", "visit-summary"),
+			huh.NewOption("View as Patient", "portal"),
+			huh.NewOption("Clinic Dashboard", "dashboard"),
+			huh.NewOption("Acknowledge Alerts", "alerts"),
+			huh.NewOption("Abnormal Results", "abnormal"),
+			huh.NewOption("Custom Reports", "reports"),
+			huh.NewOption("Weekly Digest", "digest"),
+			huh.NewOption("Ask a Question", "ask"),
+			huh.NewOption("Explore Resource", "explore"),
+			huh.NewOption("Search Console", "console"),
+			huh.NewOption("Manage Data", "manage"),
+			huh.NewOption("Snapshot & Restore", "snapshot"),
+			huh.NewOption("Recent Activity", "recent"),
+			huh.NewOption("Audit Trail", "audit"),
+			huh.NewOption("Patient Access Log", "access"),
+			huh.NewOption("Admin Tools", "admin"),
+		}
+		if len(a.Actions) > 0 {
+			options = append(options, huh.NewOption("Plugins", "plugins"))
+		}
+		options = append(options,
+			huh.NewOption("Delete Seed Data", "unseed"),
+			huh.NewOption("Exit", "exit"),
+		)
+
 		var choice string
-		err := huh.NewSelect[string]().
-			Title("Community Health Clinic").
-			Options(
-				huh.NewOption("Seed Sample Data", "seed"),
-				huh.NewOption("Patient Summary", "summary"),
-				huh.NewOption("Clinic Dashboard", "dashboard"),
-				huh.NewOption("Manage Data", "manage"),
-				huh.NewOption("Delete Seed Data", "unseed"),
-				huh.NewOption("Exit", "exit"),
-			).
-			Value(&choice).
-			Run()
+		err := runMenu(huh.NewSelect[string]().
+			Title(title).
+			Options(a.menuOptions("main", options...)...).
+			Value(&choice))
 
 		if err != nil {
 			if isAbort(err) {
@@ -38,10 +68,42 @@ func (a *App) MainMenu() {
 			a.SeedData()
 		case "summary":
 			a.PatientSummary()
+		case "context":
+			a.ExportChartContext()
+		case "visit-summary":
+			a.GenerateVisitSummary()
+		case "portal":
+			a.ViewAsPatient()
 		case "dashboard":
 			a.ClinicDashboard()
+		case "alerts":
+			a.AcknowledgeAlerts()
+		case "abnormal":
+			a.AbnormalResultsReport()
+		case "reports":
+			a.CustomReports()
+		case "digest":
+			a.WeeklyDigest()
+		case "ask":
+			a.AskQuestion()
+		case "explore":
+			a.ExploreResource()
+		case "console":
+			a.SearchConsole()
 		case "manage":
 			a.manageMenu()
+		case "snapshot":
+			a.snapshotMenu()
+		case "recent":
+			a.RecentActivity()
+		case "audit":
+			a.AuditTrail()
+		case "access":
+			a.PatientAccessLog()
+		case "admin":
+			a.adminMenu()
+		case "plugins":
+			a.pluginMenu()
 		case "unseed":
 			a.DeleteSeedData()
 		case "exit":
@@ -54,16 +116,20 @@ func (a *App) MainMenu() {
 func (a *App) manageMenu() {
 	for {
 		var choice string
-		err := huh.NewSelect[string]().
+		err := runMenu(huh.NewSelect[string]().
 			Title("Manage Data").
-			Options(
+			Options(a.menuOptions("manage",
 				huh.NewOption("Patient Management", "patient"),
 				huh.NewOption("Clinical Records", "clinical"),
 				huh.NewOption("Health Plans", "health"),
+				huh.NewOption("Diet Orders", "diet"),
+				huh.NewOption("Home Devices", "devices"),
+				huh.NewOption("Scheduling", "scheduling"),
+				huh.NewOption("Billing", "billing"),
+				huh.NewOption("Cohorts", "cohorts"),
 				huh.NewOption("\u2190 Back", "back"),
-			).
-			Value(&choice).
-			Run()
+			)...).
+			Value(&choice))
 
 		if err != nil {
 			if isAbort(err) {
@@ -80,6 +146,16 @@ func (a *App) manageMenu() {
 			a.clinicalMenu()
 		case "health":
 			a.healthPlanMenu()
+		case "diet":
+			a.dietMenu()
+		case "devices":
+			a.deviceMenu()
+		case "scheduling":
+			a.schedulingMenu()
+		case "billing":
+			a.billingMenu()
+		case "cohorts":
+			a.cohortMenu()
 		case "back":
 			return
 		}
@@ -89,18 +165,22 @@ func (a *App) manageMenu() {
 func (a *App) patientMenu() {
 	for {
 		var choice string
-		err := huh.NewSelect[string]().
+		err := runMenu(huh.NewSelect[string]().
 			Title("Patient Management").
-			Options(
+			Options(a.menuOptions("patient",
 				huh.NewOption("Register New Patient", "register"),
 				huh.NewOption("List All Patients", "list"),
 				huh.NewOption("View Patient Details", "view"),
 				huh.NewOption("Update Contact Info", "update"),
+				huh.NewOption("Add Alert Flag", "flag-add"),
+				huh.NewOption("Expire Alert Flag", "flag-expire"),
+				huh.NewOption("Attach File", "attach"),
+				huh.NewOption("Download Attachment", "download"),
+				huh.NewOption("Restrict Chart", "restrict"),
 				huh.NewOption("Delete Patient", "delete"),
 				huh.NewOption("\u2190 Back", "back"),
-			).
-			Value(&choice).
-			Run()
+			)...).
+			Value(&choice))
 
 		if err != nil {
 			if isAbort(err) {
@@ -119,6 +199,16 @@ func (a *App) patientMenu() {
 			a.ViewPatient()
 		case "update":
 			a.UpdateContact()
+		case "flag-add":
+			a.CreateFlag()
+		case "flag-expire":
+			a.ExpireFlag()
+		case "attach":
+			a.AttachFile()
+		case "download":
+			a.DownloadAttachment()
+		case "restrict":
+			a.RestrictChart()
 		case "delete":
 			a.DeletePatient()
 		case "back":
@@ -130,17 +220,30 @@ func (a *App) patientMenu() {
 func (a *App) clinicalMenu() {
 	for {
 		var choice string
-		err := huh.NewSelect[string]().
+		err := runMenu(huh.NewSelect[string]().
 			Title("Clinical Records").
-			Options(
+			Options(a.menuOptions("clinical",
 				huh.NewOption("Record Vital Signs", "vitals-add"),
+				huh.NewOption("Dictate Vital Signs", "vitals-dictate"),
+				huh.NewOption("Record Lab Result", "lab-add"),
+				huh.NewOption("Record Lab Panel", "panel-add"),
+				huh.NewOption("Record Imaging Study", "imaging-add"),
+				huh.NewOption("Record Encounter", "encounter-add"),
 				huh.NewOption("View Patient Vitals", "vitals-view"),
+				huh.NewOption("Vitals Trends", "vitals-trends"),
+				huh.NewOption("Lab History", "lab-history"),
+				huh.NewOption("Edit Observation", "vitals-edit"),
+				huh.NewOption("Delete Observations", "vitals-delete"),
 				huh.NewOption("Record Diagnosis", "diagnosis-add"),
+				huh.NewOption("Suggest Diagnosis from Complaint", "diagnosis-suggest"),
 				huh.NewOption("View Patient Diagnoses", "diagnosis-view"),
+				huh.NewOption("Resolve Condition", "diagnosis-resolve"),
+				huh.NewOption("Edit Condition", "diagnosis-edit"),
+				huh.NewOption("View Codings", "codings"),
+				huh.NewOption("Manage Problem List", "problems"),
 				huh.NewOption("\u2190 Back", "back"),
-			).
-			Value(&choice).
-			Run()
+			)...).
+			Value(&choice))
 
 		if err != nil {
 			if isAbort(err) {
@@ -153,12 +256,40 @@ func (a *App) clinicalMenu() {
 		switch choice {
 		case "vitals-add":
 			a.RecordVitals()
+		case "vitals-dictate":
+			a.DictateVitals()
+		case "lab-add":
+			a.RecordLabResult()
+		case "panel-add":
+			a.RecordLabPanel()
+		case "imaging-add":
+			a.RecordImagingStudy()
+		case "encounter-add":
+			a.RecordEncounter()
 		case "vitals-view":
 			a.ViewVitals()
+		case "vitals-trends":
+			a.ViewVitalTrends()
+		case "lab-history":
+			a.ViewLabHistory()
+		case "vitals-edit":
+			a.EditObservation()
+		case "vitals-delete":
+			a.DeleteObservations()
 		case "diagnosis-add":
 			a.RecordDiagnosis()
+		case "diagnosis-suggest":
+			a.SuggestDiagnosis()
 		case "diagnosis-view":
 			a.ViewDiagnoses()
+		case "diagnosis-resolve":
+			a.ResolveCondition()
+		case "diagnosis-edit":
+			a.EditCondition()
+		case "codings":
+			a.ViewCodings()
+		case "problems":
+			a.ManageProblemList()
 		case "back":
 			return
 		}
@@ -168,17 +299,23 @@ func (a *App) clinicalMenu() {
 func (a *App) healthPlanMenu() {
 	for {
 		var choice string
-		err := huh.NewSelect[string]().
+		err := runMenu(huh.NewSelect[string]().
 			Title("Health Plans").
-			Options(
+			Options(a.menuOptions("health",
 				huh.NewOption("Create New Plan", "create"),
+				huh.NewOption("Create Plan from Template", "template"),
 				huh.NewOption("Add Activity to Plan", "add"),
 				huh.NewOption("Complete Activity", "complete"),
 				huh.NewOption("View Plan Status", "status"),
+				huh.NewOption("Set Goal Target", "goal-target"),
+				huh.NewOption("Plan Outcomes Report", "outcomes"),
+				huh.NewOption("Reassign Escalations", "escalations"),
+				huh.NewOption("Start Episode of Care", "episode"),
+				huh.NewOption("Edit Episode Links", "episode-links"),
+				huh.NewOption("Episode Timeline", "timeline"),
 				huh.NewOption("\u2190 Back", "back"),
-			).
-			Value(&choice).
-			Run()
+			)...).
+			Value(&choice))
 
 		if err != nil {
 			if isAbort(err) {
@@ -191,12 +328,279 @@ func (a *App) healthPlanMenu() {
 		switch choice {
 		case "create":
 			a.CreatePlan()
+		case "template":
+			a.CreatePlanFromTemplate()
 		case "add":
 			a.AddActivity()
 		case "complete":
 			a.CompleteActivity()
 		case "status":
 			a.ViewPlanStatus()
+		case "goal-target":
+			a.SetGoalTarget()
+		case "outcomes":
+			a.OutcomesReport()
+		case "escalations":
+			a.ReassignEscalations()
+		case "episode":
+			a.StartEpisode()
+		case "episode-links":
+			a.EditEpisodeLinks()
+		case "timeline":
+			a.EpisodeTimeline()
+		case "back":
+			return
+		}
+	}
+}
+
+func (a *App) dietMenu() {
+	for {
+		var choice string
+		err := runMenu(huh.NewSelect[string]().
+			Title("Diet Orders").
+			Options(a.menuOptions("diet",
+				huh.NewOption("Order Diet", "create"),
+				huh.NewOption("View Diet Orders", "view"),
+				huh.NewOption("Discontinue Diet Order", "discontinue"),
+				huh.NewOption("\u2190 Back", "back"),
+			)...).
+			Value(&choice))
+
+		if err != nil {
+			if isAbort(err) {
+				return
+			}
+			ShowError(err)
+			continue
+		}
+
+		switch choice {
+		case "create":
+			a.CreateDietOrder()
+		case "view":
+			a.ViewDietOrders()
+		case "discontinue":
+			a.DiscontinueDietOrder()
+		case "back":
+			return
+		}
+	}
+}
+
+func (a *App) deviceMenu() {
+	for {
+		var choice string
+		err := runMenu(huh.NewSelect[string]().
+			Title("Home Devices").
+			Options(a.menuOptions("devices",
+				huh.NewOption("Register Device", "register"),
+				huh.NewOption("View Patient Devices", "view"),
+				huh.NewOption("View Device Readings", "readings"),
+				huh.NewOption("\u2190 Back", "back"),
+			)...).
+			Value(&choice))
+
+		if err != nil {
+			if isAbort(err) {
+				return
+			}
+			ShowError(err)
+			continue
+		}
+
+		switch choice {
+		case "register":
+			a.RegisterDevice()
+		case "view":
+			a.ViewDevices()
+		case "readings":
+			a.ViewDeviceReadings()
+		case "back":
+			return
+		}
+	}
+}
+
+func (a *App) schedulingMenu() {
+	for {
+		var choice string
+		err := runMenu(huh.NewSelect[string]().
+			Title("Scheduling").
+			Options(a.menuOptions("scheduling",
+				huh.NewOption("Generate Slots", "generate"),
+				huh.NewOption("Find Open Slots", "find"),
+				huh.NewOption("\u2190 Back", "back"),
+			)...).
+			Value(&choice))
+
+		if err != nil {
+			if isAbort(err) {
+				return
+			}
+			ShowError(err)
+			continue
+		}
+
+		switch choice {
+		case "generate":
+			a.GenerateSlots()
+		case "find":
+			a.FindOpenSlots()
+		case "back":
+			return
+		}
+	}
+}
+
+func (a *App) billingMenu() {
+	for {
+		var choice string
+		err := runMenu(huh.NewSelect[string]().
+			Title("Billing").
+			Options(a.menuOptions("billing",
+				huh.NewOption("Generate Claim", "generate"),
+				huh.NewOption("View Claims", "view"),
+				huh.NewOption("\u2190 Back", "back"),
+			)...).
+			Value(&choice))
+
+		if err != nil {
+			if isAbort(err) {
+				return
+			}
+			ShowError(err)
+			continue
+		}
+
+		switch choice {
+		case "generate":
+			a.GenerateClaim()
+		case "view":
+			a.ViewClaims()
+		case "back":
+			return
+		}
+	}
+}
+
+func (a *App) adminMenu() {
+	for {
+		var choice string
+		err := runMenu(huh.NewSelect[string]().
+			Title("Admin Tools").
+			Options(a.menuOptions("admin",
+				huh.NewOption("Data Quality Audit", "quality"),
+				huh.NewOption("Clean Up Orphaned Resources", "orphans"),
+				huh.NewOption("Recode Conditions", "recode"),
+				huh.NewOption("Store Overview", "overview"),
+				huh.NewOption("Store Growth", "growth"),
+				huh.NewOption("Benchmark", "benchmark"),
+				huh.NewOption("Load Test Seeding", "loadtest"),
+				huh.NewOption("Offline Queue", "queue"),
+				huh.NewOption("\u2190 Back", "back"),
+			)...).
+			Value(&choice))
+
+		if err != nil {
+			if isAbort(err) {
+				return
+			}
+			ShowError(err)
+			continue
+		}
+
+		switch choice {
+		case "quality":
+			a.DataQualityAudit()
+		case "orphans":
+			a.CleanUpOrphans()
+		case "recode":
+			a.RecodeConditions()
+		case "overview":
+			a.StoreOverview()
+		case "growth":
+			a.StoreGrowth()
+		case "benchmark":
+			a.Benchmark()
+		case "loadtest":
+			a.LoadTestSeed()
+		case "queue":
+			a.OfflineQueue()
+		case "back":
+			return
+		}
+	}
+}
+
+func (a *App) cohortMenu() {
+	for {
+		var choice string
+		err := runMenu(huh.NewSelect[string]().
+			Title("Cohorts").
+			Options(a.menuOptions("cohorts",
+				huh.NewOption("Create Cohort", "create"),
+				huh.NewOption("Build Cohort from Criteria", "build"),
+				huh.NewOption("Add/Remove Members", "members"),
+				huh.NewOption("View Cohorts", "view"),
+				huh.NewOption("Cohort Dashboard", "dashboard"),
+				huh.NewOption("Cohort Report", "report"),
+				huh.NewOption("\u2190 Back", "back"),
+			)...).
+			Value(&choice))
+
+		if err != nil {
+			if isAbort(err) {
+				return
+			}
+			ShowError(err)
+			continue
+		}
+
+		switch choice {
+		case "create":
+			a.CreateCohort()
+		case "build":
+			a.BuildCohort()
+		case "members":
+			a.ManageCohortMembers()
+		case "view":
+			a.ViewCohorts()
+		case "dashboard":
+			a.CohortDashboard()
+		case "report":
+			a.CohortReport()
+		case "back":
+			return
+		}
+	}
+}
+
+func (a *App) snapshotMenu() {
+	for {
+		var choice string
+		err := runMenu(huh.NewSelect[string]().
+			Title("Snapshot & Restore").
+			Options(a.menuOptions("snapshot",
+				huh.NewOption("Take Snapshot", "take"),
+				huh.NewOption("Restore Snapshot", "restore"),
+				huh.NewOption("\u2190 Back", "back"),
+			)...).
+			Value(&choice))
+
+		if err != nil {
+			if isAbort(err) {
+				return
+			}
+			ShowError(err)
+			continue
+		}
+
+		switch choice {
+		case "take":
+			a.TakeSnapshot()
+		case "restore":
+			a.RestoreSnapshot()
 		case "back":
 			return
 		}
@@ -0,0 +1,190 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/phenoml/phenostore-sdk-go/phenostore/gen"
+)
+
+// metricsResourceTypes are the resource types counted in each store sample.
+var metricsResourceTypes = storedResourceTypes
+
+// metricsSample is one day's resource counts.
+type metricsSample struct {
+	Date   string         `json:"date"`
+	Counts map[string]int `json:"counts"`
+}
+
+func (s metricsSample) total() int {
+	n := 0
+	for _, c := range s.Counts {
+		n += c
+	}
+	return n
+}
+
+// metricsPath is where store samples are kept, from PHENOSTORE_METRICS_FILE.
+func metricsPath() string {
+	if path := os.Getenv("PHENOSTORE_METRICS_FILE"); path != "" {
+		return path
+	}
+	return "store-metrics.json"
+}
+
+// loadMetrics reads the samples recorded so far. A missing file is no samples.
+func loadMetrics(path string) ([]metricsSample, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var samples []metricsSample
+	if err := json.Unmarshal(data, &samples); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return samples, nil
+}
+
+// addSample records s, replacing any earlier sample from the same day, and
+// keeps the samples in date order.
+func addSample(samples []metricsSample, s metricsSample) []metricsSample {
+	out := []metricsSample{s}
+	for _, old := range samples {
+		if old.Date != s.Date {
+			out = append(out, old)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Date < out[j].Date })
+	return out
+}
+
+// countResources returns how many resources of a type the store holds,
+// using _summary=count so no resources are transferred.
+func (a *App) countResources(ctx context.Context, resourceType string) (int, error) {
+	summary := gen.Count
+	params := &gen.SearchResourcesParams{
+		UnderscoreSummary: &summary,
+	}
+	resp, err := a.Client.Inner().SearchResourcesWithResponse(
+		ctx, a.Client.Tenant(), a.Client.Store(),
+		gen.ResourceType(resourceType), params,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("counting %s: %w", resourceType, err)
+	}
+	if resp.HTTPResponse.StatusCode >= 400 {
+		return 0, fmt.Errorf("count %s failed: HTTP %d", resourceType, resp.HTTPResponse.StatusCode)
+	}
+	var bundle gen.Bundle
+	if err := json.Unmarshal(resp.Body, &bundle); err != nil {
+		return 0, fmt.Errorf("parsing %s count: %w", resourceType, err)
+	}
+	if bundle.Total == nil {
+		return 0, fmt.Errorf("count %s: response has no total", resourceType)
+	}
+	return *bundle.Total, nil
+}
+
+// recordStoreMetrics counts every metrics resource type and saves the
+// result as today's sample. It returns all samples, oldest first.
+func (a *App) recordStoreMetrics(ctx context.Context, now time.Time) ([]metricsSample, error) {
+	sample := metricsSample{Date: now.Format("2006-01-02"), Counts: make(map[string]int)}
+	for _, rt := range metricsResourceTypes {
+		n, err := a.countResources(ctx, rt)
+		if err != nil {
+			return nil, err
+		}
+		sample.Counts[rt] = n
+	}
+
+	path := metricsPath()
+	samples, err := loadMetrics(path)
+	if err != nil {
+		return nil, err
+	}
+	samples = addSample(samples, sample)
+	data, err := json.MarshalIndent(samples, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return nil, fmt.Errorf("writing %s: %w", path, err)
+	}
+	return samples, nil
+}
+
+// StoreGrowth samples today's resource counts, saves them with the earlier
+// samples in PHENOSTORE_METRICS_FILE, and charts the store's growth.
+func (a *App) StoreGrowth() {
+	var samples []metricsSample
+	var apiErr error
+	var elapsed time.Duration
+
+	err := runSpinner("Counting resources...", func(ctx context.Context) {
+		start := time.Now()
+		samples, apiErr = a.recordStoreMetrics(ctx, start)
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	printGrowthChart(samples)
+	fmt.Println()
+	showTiming(fmt.Sprintf("Counted %d resource types with _summary=count (%d days in %s)", len(metricsResourceTypes), len(samples), metricsPath()), elapsed)
+	PressEnter()
+}
+
+// printGrowthChart renders the total per sample as bars, then each resource
+// type's latest count and change since the first sample.
+func printGrowthChart(samples []metricsSample) {
+	fmt.Println(barStyle.Bold(true).Render("Store Growth"))
+	peak := 0
+	for _, s := range samples {
+		peak = max(peak, s.total())
+	}
+	const width = 40
+	prev := -1
+	for _, s := range samples {
+		n := 0
+		if peak > 0 {
+			n = int(math.Round(float64(s.total()) / float64(peak) * width))
+		}
+		delta := ""
+		if prev >= 0 {
+			delta = timingStyle.Render(fmt.Sprintf(" %+d", s.total()-prev))
+		}
+		fmt.Printf("  %s  %s %d%s\n", s.Date, barStyle.Render(strings.Repeat("█", n)), s.total(), delta)
+		prev = s.total()
+	}
+
+	first, last := samples[0], samples[len(samples)-1]
+	fmt.Println()
+	fmt.Println(timingStyle.Render(fmt.Sprintf("  %-18s %8s %8s", "RESOURCE", "COUNT", "CHANGE")))
+	for _, rt := range metricsResourceTypes {
+		if last.Counts[rt] == 0 && first.Counts[rt] == 0 {
+			continue
+		}
+		fmt.Printf("  %-18s %8d %+8d\n", rt, last.Counts[rt], last.Counts[rt]-first.Counts[rt])
+	}
+	fmt.Println(timingStyle.Render("  Change is since " + first.Date))
+}
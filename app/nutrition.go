@@ -0,0 +1,245 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// dietOption is a commonly ordered diet with its SNOMED CT code.
+type dietOption struct {
+	code    string
+	display string
+}
+
+var dietOptions = []dietOption{
+	{code: "386619000", display: "Low sodium diet"},
+	{code: "33489005", display: "Renal diet"},
+	{code: "160670007", display: "Diabetic diet"},
+}
+
+// CreateDietOrder lets the user pick a patient and order a diet.
+func (a *App) CreateDietOrder() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var options []huh.Option[int]
+	for i, d := range dietOptions {
+		options = append(options, huh.NewOption(d.display, i))
+	}
+	options = append(options, huh.NewOption("Other (free text)", -1))
+
+	var dietIdx int
+	var instruction string
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[int]().
+				Title("Diet").
+				Options(options...).
+				Value(&dietIdx),
+			huh.NewInput().Title("Instructions (optional, e.g., < 2g sodium/day)").Value(&instruction),
+		),
+	)
+	if err := form.Run(); err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var diet dietOption
+	if dietIdx >= 0 {
+		diet = dietOptions[dietIdx]
+	} else {
+		if err := huh.NewInput().Title("Diet name").Value(&diet.display).Run(); err != nil {
+			if !isAbort(err) {
+				ShowError(err)
+				PressEnter()
+			}
+			return
+		}
+	}
+
+	body := fhir.NewNutritionOrder(patientID, diet.code, diet.display, instruction)
+
+	var created json.RawMessage
+	var apiErr error
+
+	err = runSpinner("Ordering diet...", func(ctx context.Context) {
+		created, apiErr = a.createResource(ctx, "NutritionOrder", body)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(fmt.Errorf("creating nutrition order: %w", apiErr))
+		PressEnter()
+		return
+	}
+
+	id := fhir.ResourceID(created)
+	a.emit(context.Background(), EventNutritionOrderCreated, "NutritionOrder", id, patientID)
+	fmt.Printf("\n  Ordered %s (ID: %s)\n", diet.display, id)
+	PressEnter()
+}
+
+// ViewDietOrders lets the user pick a patient and view their diet orders.
+func (a *App) ViewDietOrders() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var orders []json.RawMessage
+	var fetchErr error
+	var elapsed time.Duration
+
+	err = runSpinner("Loading diet orders...", func(ctx context.Context) {
+		start := time.Now()
+		orders, fetchErr = a.searchByPatient(ctx, "NutritionOrder", patientID, "")
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	if len(orders) == 0 {
+		fmt.Println("  No diet orders found.")
+	} else {
+		fhir.PrintNutritionOrderList(orders)
+		showTiming(fmt.Sprintf("Fetched %d diet orders", len(orders)), elapsed)
+	}
+	PressEnter()
+}
+
+// DiscontinueDietOrder lets the user pick an active diet order and revoke it.
+func (a *App) DiscontinueDietOrder() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	ctx := context.Background()
+	var orders []json.RawMessage
+	var fetchErr error
+
+	err = runSpinner("Loading diet orders...", func(ctx context.Context) {
+		orders, fetchErr = a.searchByPatient(ctx, "NutritionOrder", patientID, "")
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	var options []huh.Option[string]
+	for _, raw := range orders {
+		m, err := fhir.Parse(raw)
+		if err != nil || mapStr(m, "status") != "active" {
+			continue
+		}
+		options = append(options, huh.NewOption(fhir.NutritionOrderDiet(m), mapStr(m, "id")))
+	}
+
+	if len(options) == 0 {
+		fmt.Println("\n  No active diet orders for this patient.")
+		PressEnter()
+		return
+	}
+
+	var orderID string
+	err = huh.NewSelect[string]().
+		Title("Select diet order to discontinue").
+		Options(options...).
+		Value(&orderID).
+		Run()
+
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var apiErr error
+
+	err = runSpinner("Discontinuing diet order...", func(ctx context.Context) {
+		raw, err := a.readResource(ctx, "NutritionOrder", orderID)
+		if err != nil {
+			apiErr = fmt.Errorf("reading nutrition order: %w", err)
+			return
+		}
+
+		var order map[string]any
+		if err := json.Unmarshal(raw, &order); err != nil {
+			apiErr = fmt.Errorf("parsing nutrition order: %w", err)
+			return
+		}
+		order["status"] = "revoked"
+
+		updated, err := json.Marshal(order)
+		if err != nil {
+			apiErr = fmt.Errorf("marshaling nutrition order: %w", err)
+			return
+		}
+
+		_, err = a.updateResource(ctx, "NutritionOrder", orderID, updated)
+		if err != nil {
+			apiErr = fmt.Errorf("updating nutrition order: %w", err)
+			return
+		}
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+		PressEnter()
+		return
+	}
+
+	a.emit(ctx, EventNutritionOrderRevoked, "NutritionOrder", orderID, patientID)
+	fmt.Printf("\n  Discontinued diet order %s\n", orderID)
+	PressEnter()
+}
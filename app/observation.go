@@ -4,15 +4,94 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
+	neturl "net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/charmbracelet/huh"
-	"github.com/charmbracelet/huh/spinner"
 	"github.com/phenoml/phenostore-example-go/fhir"
 )
 
-// RecordVitals guides the user through recording an observation.
+// singleVital is a vital sign recorded from one number. Blood pressure and
+// weight have their own steps in RecordVitals.
+type singleVital struct {
+	key, label, prompt string
+	integer            bool
+	min, max           float64
+	build              func(patientID string, value float64) json.RawMessage
+}
+
+// singleVitals are the one-number vital signs offered by Record Vital Signs.
+var singleVitals = []singleVital{
+	{"height", "Height", "Height (cm)", false, 1, 300, fhir.NewHeightObservation},
+	{"heart-rate", "Heart Rate", "Heart rate (bpm)", true, 1, 300,
+		func(p string, v float64) json.RawMessage { return fhir.NewHeartRateObservation(p, int(v)) }},
+	{"temperature", "Temperature", "Temperature (°C)", false, 25, 45, fhir.NewTemperatureObservation},
+	{"spo2", "O2 Saturation", "O2 saturation (%)", true, 1, 100,
+		func(p string, v float64) json.RawMessage { return fhir.NewOxygenSaturationObservation(p, int(v)) }},
+	{"respiratory-rate", "Respiratory Rate", "Respiratory rate (/min)", true, 1, 100,
+		func(p string, v float64) json.RawMessage { return fhir.NewRespiratoryRateObservation(p, int(v)) }},
+	{"bmi", "BMI", "BMI (kg/m2)", false, 5, 100, fhir.NewBMIObservation},
+	{"pain", "Pain Score", "Pain score (0-10)", true, 0, 10,
+		func(p string, v float64) json.RawMessage { return fhir.NewPainScoreObservation(p, int(v)) }},
+	{"head-circumference", "Head Circumference", "Head circumference (cm)", false, 20, 80, fhir.NewHeadCircumferenceObservation},
+}
+
+// parse reads an entered value in the vital's stored unit. Temperatures
+// may be entered in °F or °C; see fhir.ParseTemperature.
+func (v singleVital) parse(s string) (float64, error) {
+	if v.key == "temperature" {
+		return fhir.ParseTemperature(s)
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// title is the prompt for the vital, in the preferred temperature unit.
+func (v singleVital) title() string {
+	if v.key == "temperature" {
+		return fmt.Sprintf("Temperature (%s, or add C or F)", fhir.TemperatureUnit())
+	}
+	return v.prompt
+}
+
+// validate checks an entered value is a number in the vital's range.
+func (v singleVital) validate(s string) error {
+	value, err := v.parse(s)
+	if err != nil || (v.integer && value != math.Trunc(value)) {
+		if v.integer {
+			return fmt.Errorf("must be a whole number")
+		}
+		return fmt.Errorf("must be a number")
+	}
+	if value < v.min || value > v.max {
+		if v.key == "temperature" && fhir.DisplayUnits == fhir.USUnits {
+			return fmt.Errorf("must be between 77 and 113 °F")
+		}
+		return fmt.Errorf("must be between %g and %g", v.min, v.max)
+	}
+	return nil
+}
+
+// validateMmHg checks an entered blood pressure is a whole number of mmHg
+// that a cuff could read. Readings that are possible but unlikely are
+// caught afterwards by confirmPlausible.
+func validateMmHg(s string) error {
+	v, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return fmt.Errorf("must be a whole number")
+	}
+	if v < 1 || v > 400 {
+		return fmt.Errorf("must be between 1 and 400")
+	}
+	return nil
+}
+
+// RecordVitals guides the user through recording an observation. Readings
+// outside their plausible range (see VitalRange) must be confirmed or
+// entered again.
 func (a *App) RecordVitals() {
 	patientID, err := a.PickPatient()
 	if err != nil || patientID == "" {
@@ -23,14 +102,17 @@ func (a *App) RecordVitals() {
 		return
 	}
 
+	options := []huh.Option[string]{
+		huh.NewOption("Blood Pressure", "bp"),
+		huh.NewOption("Weight", "weight"),
+	}
+	for _, v := range singleVitals {
+		options = append(options, huh.NewOption(v.label, v.key))
+	}
 	var obsType string
 	err = huh.NewSelect[string]().
 		Title("Vital sign type").
-		Options(
-			huh.NewOption("Blood Pressure", "bp"),
-			huh.NewOption("Weight", "weight"),
-			huh.NewOption("Heart Rate", "heart-rate"),
-		).
+		Options(options...).
 		Value(&obsType).
 		Run()
 
@@ -42,77 +124,184 @@ func (a *App) RecordVitals() {
 		return
 	}
 
+	ctx := context.Background()
 	var body json.RawMessage
+	// bmi is set when a weight is recorded for a patient with a height on
+	// file and the user chooses to store the BMI calculated from them.
+	var bmi float64
+	var heightID string
 
 	switch obsType {
 	case "bp":
 		var systolicStr, diastolicStr string
-		form := huh.NewForm(
-			huh.NewGroup(
-				huh.NewInput().Title("Systolic (mmHg)").Value(&systolicStr),
-				huh.NewInput().Title("Diastolic (mmHg)").Value(&diastolicStr),
-			),
-		)
-		if err := form.Run(); err != nil {
-			if !isAbort(err) {
-				ShowError(err)
-				PressEnter()
+		var systolic, diastolic int
+		for {
+			form := huh.NewForm(
+				huh.NewGroup(
+					huh.NewInput().Title("Systolic (mmHg)").Value(&systolicStr).Validate(validateMmHg),
+					huh.NewInput().Title("Diastolic (mmHg)").Value(&diastolicStr).Validate(validateMmHg),
+				),
+			)
+			if err := form.Run(); err != nil {
+				if !isAbort(err) {
+					ShowError(err)
+					PressEnter()
+				}
+				return
+			}
+			systolic, _ = strconv.Atoi(strings.TrimSpace(systolicStr))
+			diastolic, _ = strconv.Atoi(strings.TrimSpace(diastolicStr))
+			ok, err := confirmPlausible(a.implausible("systolic", float64(systolic)), a.implausible("diastolic", float64(diastolic)))
+			if err != nil {
+				if !isAbort(err) {
+					ShowError(err)
+					PressEnter()
+				}
+				return
+			}
+			if ok {
+				break
 			}
-			return
-		}
-		systolic, err1 := strconv.Atoi(systolicStr)
-		diastolic, err2 := strconv.Atoi(diastolicStr)
-		if err1 != nil || err2 != nil {
-			ShowError(fmt.Errorf("systolic and diastolic must be numbers"))
-			PressEnter()
-			return
 		}
 		body = fhir.NewBloodPressureObservation(patientID, systolic, diastolic)
 
 	case "weight":
 		var valueStr string
-		if err := huh.NewInput().Title("Weight (kg)").Value(&valueStr).Run(); err != nil {
-			if !isAbort(err) {
-				ShowError(err)
-				PressEnter()
+		var value float64
+		for {
+			err := huh.NewInput().
+				Title(fmt.Sprintf("Weight (%s, or add kg or lb)", fhir.WeightUnit())).
+				Value(&valueStr).
+				Validate(func(s string) error {
+					_, err := fhir.ParseWeight(s)
+					return err
+				}).
+				Run()
+			if err == nil {
+				value, _ = fhir.ParseWeight(valueStr)
+				var ok bool
+				if ok, err = confirmPlausible(a.implausible("weight", value)); err == nil && ok {
+					break
+				}
+			}
+			if err != nil {
+				if !isAbort(err) {
+					ShowError(err)
+					PressEnter()
+				}
+				return
 			}
-			return
-		}
-		value, err := strconv.ParseFloat(valueStr, 64)
-		if err != nil {
-			ShowError(fmt.Errorf("weight must be a number"))
-			PressEnter()
-			return
 		}
 		body = fhir.NewWeightObservation(patientID, value)
 
-	case "heart-rate":
-		var valueStr string
-		if err := huh.NewInput().Title("Heart rate (bpm)").Value(&valueStr).Run(); err != nil {
+		bmi, heightID, err = a.offerBMI(ctx, patientID, value)
+		if err != nil {
 			if !isAbort(err) {
 				ShowError(err)
 				PressEnter()
 			}
 			return
 		}
-		value, err := strconv.Atoi(valueStr)
-		if err != nil {
-			ShowError(fmt.Errorf("heart rate must be a number"))
+
+	default:
+		i := slices.IndexFunc(singleVitals, func(v singleVital) bool { return v.key == obsType })
+		v := singleVitals[i]
+		var valueStr string
+		var value float64
+		for {
+			err := huh.NewInput().Title(v.title()).Value(&valueStr).Validate(v.validate).Run()
+			if err == nil {
+				value, _ = v.parse(valueStr)
+				var ok bool
+				if ok, err = confirmPlausible(a.implausible(v.key, value)); err == nil && ok {
+					break
+				}
+			}
+			if err != nil {
+				if !isAbort(err) {
+					ShowError(err)
+					PressEnter()
+				}
+				return
+			}
+		}
+		body = v.build(patientID, value)
+	}
+
+	measured, err := askMeasuredAt()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
 			PressEnter()
-			return
 		}
-		body = fhir.NewHeartRateObservation(patientID, value)
+		return
+	}
+	if !measured.IsZero() {
+		body = fhir.WithEffective(body, measured)
 	}
 
-	var created json.RawMessage
-	var apiErr error
+	note, err := askNote()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	if note != "" {
+		body = fhir.WithNote(body, note)
+	}
 
-	err = spinner.New().
-		Title("Recording observation...").
-		Action(func() {
-			created, apiErr = a.Client.CreateResource(context.Background(), "Observation", body, nil)
-		}).
-		Run()
+	body, err = askMeasurement(body)
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	deviceID, err := a.pickDevice(patientID, true)
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	if deviceID != "" {
+		body = fhir.WithDevice(body, "Device/"+deviceID)
+	} else if body, err = askDeviceName(body); err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var created, createdBMI json.RawMessage
+	var issues []json.RawMessage
+	var apiErr, bmiErr, issueErr error
+
+	err = runSpinner("Recording observation...", func(ctx context.Context) {
+		created, apiErr = a.createResource(ctx, "Observation", body)
+		if apiErr != nil {
+			return
+		}
+		observations := []json.RawMessage{created}
+		if bmi > 0 {
+			bmiBody := fhir.WithDerivedFrom(fhir.NewBMIObservation(patientID, bmi),
+				"Observation/"+fhir.ResourceID(created), "Observation/"+heightID)
+			if !measured.IsZero() {
+				bmiBody = fhir.WithEffective(bmiBody, measured)
+			}
+			createdBMI, bmiErr = a.createResource(ctx, "Observation", bmiBody)
+			if bmiErr == nil {
+				observations = append(observations, createdBMI)
+			}
+		}
+		issues, issueErr = a.raiseIssues(ctx, observations)
+	})
 
 	if err != nil {
 		ShowError(err)
@@ -126,10 +315,132 @@ func (a *App) RecordVitals() {
 	}
 
 	id := fhir.ResourceID(created)
+	a.emit(ctx, EventObservationCreated, "Observation", id, patientID)
 	fmt.Printf("\n  Recorded %s observation (ID: %s)\n", obsType, id)
+	if createdBMI != nil {
+		bmiID := fhir.ResourceID(createdBMI)
+		a.emit(ctx, EventObservationCreated, "Observation", bmiID, patientID)
+		fmt.Printf("  Recorded BMI %.1f (ID: %s)\n", bmi, bmiID)
+	}
+	if bmiErr != nil {
+		ShowError(fmt.Errorf("creating BMI observation: %w", bmiErr))
+	}
+	a.reportIssues(ctx, issues)
+	if issueErr != nil {
+		ShowError(issueErr)
+	}
 	PressEnter()
 }
 
+// measuredAtLayout is how a backdated measurement time is entered.
+const measuredAtLayout = "2006-01-02 15:04"
+
+// askMeasuredAt asks when a reading was taken, for results recorded after
+// the fact. It returns the zero time when left blank, meaning now.
+func askMeasuredAt() (time.Time, error) {
+	var s string
+	err := huh.NewInput().
+		Title("Measured at (YYYY-MM-DD HH:MM, blank for now)").
+		Value(&s).
+		Validate(func(s string) error {
+			if strings.TrimSpace(s) == "" {
+				return nil
+			}
+			t, err := time.ParseInLocation(measuredAtLayout, strings.TrimSpace(s), time.Local)
+			if err != nil {
+				return fmt.Errorf("use YYYY-MM-DD HH:MM")
+			}
+			if t.After(time.Now()) {
+				return fmt.Errorf("cannot be in the future")
+			}
+			return nil
+		}).
+		Run()
+	if err != nil || strings.TrimSpace(s) == "" {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation(measuredAtLayout, strings.TrimSpace(s), time.Local)
+}
+
+// askNote asks for an optional free-text note on an observation, such as
+// the circumstances of a reading. It returns "" when left blank.
+func askNote() (string, error) {
+	var s string
+	err := huh.NewText().
+		Title("Note (optional)").
+		Placeholder("e.g. taken after exercise; repeat reading").
+		Value(&s).
+		Run()
+	return strings.TrimSpace(s), err
+}
+
+// askMeasurement asks how a vital sign was taken, for the vitals that have
+// methods, body sites, or (for blood pressure) cuff sizes to choose from,
+// and records the answers on the observation. Every question may be left
+// as not recorded.
+func askMeasurement(body json.RawMessage) (json.RawMessage, error) {
+	m, err := fhir.Parse(body)
+	if err != nil {
+		return body, nil
+	}
+	code, _ := fhir.Path(m, "code.coding.code").(string)
+	methods, sites := fhir.MeasurementMethods(code), fhir.MeasurementSites(code)
+	options := func(choices []fhir.MeasurementOption) []huh.Option[int] {
+		opts := []huh.Option[int]{huh.NewOption("Not recorded", -1)}
+		for i, c := range choices {
+			opts = append(opts, huh.NewOption(c.Display, i))
+		}
+		return opts
+	}
+
+	method, site := -1, -1
+	var cuff string
+	var fields []huh.Field
+	if len(methods) > 0 {
+		fields = append(fields, huh.NewSelect[int]().Title("Method").Options(options(methods)...).Value(&method))
+	}
+	if len(sites) > 0 {
+		fields = append(fields, huh.NewSelect[int]().Title("Site").Options(options(sites)...).Value(&site))
+	}
+	if code == "85354-9" {
+		fields = append(fields, huh.NewSelect[string]().
+			Title("Cuff size").
+			Options(append([]huh.Option[string]{huh.NewOption("Not recorded", "")}, huh.NewOptions(fhir.CuffSizes...)...)...).
+			Value(&cuff))
+	}
+	if len(fields) == 0 {
+		return body, nil
+	}
+	if err := huh.NewForm(huh.NewGroup(fields...).Title("How was it measured?")).Run(); err != nil {
+		return body, err
+	}
+
+	var chosen fhir.MeasurementOption
+	if method >= 0 {
+		chosen = methods[method]
+	}
+	body = fhir.WithMethod(body, chosen, cuff)
+	if site >= 0 {
+		body = fhir.WithBodySite(body, sites[site])
+	}
+	return body, nil
+}
+
+// askDeviceName asks for the device a reading was taken with when it is
+// not one of the patient's registered devices, e.g. a clinic monitor.
+func askDeviceName(body json.RawMessage) (json.RawMessage, error) {
+	var name string
+	err := huh.NewInput().
+		Title("Device (optional)").
+		Placeholder("e.g. clinic monitor, Welch Allyn Connex").
+		Value(&name).
+		Run()
+	if name = strings.TrimSpace(name); err == nil && name != "" {
+		body = fhir.WithDeviceName(body, name)
+	}
+	return body, err
+}
+
 // ViewVitals lets the user pick a patient and view their observations.
 func (a *App) ViewVitals() {
 	patientID, err := a.PickPatient()
@@ -141,18 +452,24 @@ func (a *App) ViewVitals() {
 		return
 	}
 
+	dates, err := askDateRange()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
 	var observations []json.RawMessage
 	var fetchErr error
 	var elapsed time.Duration
 
-	err = spinner.New().
-		Title("Loading observations...").
-		Action(func() {
-			start := time.Now()
-			observations, fetchErr = a.searchByPatient(context.Background(), "Observation", patientID)
-			elapsed = time.Since(start)
-		}).
-		Run()
+	err = runSpinner("Loading observations...", func(ctx context.Context) {
+		start := time.Now()
+		observations, fetchErr = a.searchObservations(ctx, patientID, dates)
+		elapsed = time.Since(start)
+	})
 
 	if err != nil {
 		ShowError(err)
@@ -167,10 +484,393 @@ func (a *App) ViewVitals() {
 
 	fmt.Println()
 	if len(observations) == 0 {
-		fmt.Println("  No observations found.")
+		fmt.Printf("  No observations found (%s).\n", dates)
 	} else {
 		fhir.PrintObservationList(observations)
-		showTiming(fmt.Sprintf("Fetched %d observations", len(observations)), elapsed)
+		showTiming(fmt.Sprintf("Fetched %d observations (%s)", len(observations), dates), elapsed)
+	}
+	PressEnter()
+}
+
+// ViewVitalTrends shows sparklines of a patient's blood pressure, weight,
+// and glucose over time.
+func (a *App) ViewVitalTrends() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var observations []json.RawMessage
+	var fetchErr error
+	var elapsed time.Duration
+
+	err = runSpinner("Loading observations...", func(ctx context.Context) {
+		start := time.Now()
+		observations, fetchErr = a.searchAllPages(ctx, "Observation", 100, neturl.Values{
+			fhir.SearchObservationPatient: {patientID},
+			fhir.SearchObservationCode:    {strings.Join(fhir.VitalTrendCodes, ",")},
+			fhir.SearchSort:               {"date"},
+		}, nil)
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	fhir.PrintVitalTrends(fhir.BuildVitalTrends(observations))
+	showTiming(fmt.Sprintf("Fetched %d observations", len(observations)), elapsed)
+	PressEnter()
+}
+
+// ViewLabHistory shows a patient's lab results as a table of tests by date,
+// with the change from each test's previous result.
+func (a *App) ViewLabHistory() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var observations []json.RawMessage
+	var fetchErr error
+	var elapsed time.Duration
+
+	err = runSpinner("Loading lab results...", func(ctx context.Context) {
+		start := time.Now()
+		observations, fetchErr = a.searchAllPages(ctx, "Observation", 100, neturl.Values{
+			fhir.SearchObservationPatient: {patientID},
+			fhir.SearchSort:               {"date"},
+		}, nil)
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	fhir.PrintLabHistory(fhir.BuildLabHistory(observations))
+	showTiming(fmt.Sprintf("Fetched %d observations", len(observations)), elapsed)
+	PressEnter()
+}
+
+// offerBMI looks up the patient's latest height and, if there is one, offers
+// to record the BMI calculated from it and a new weight. It returns the BMI
+// and the height Observation's ID, or zero if there is no height or the user
+// declines.
+func (a *App) offerBMI(ctx context.Context, patientID string, kg float64) (float64, string, error) {
+	var heights []json.RawMessage
+	var fetchErr error
+	err := runSpinner("Looking up height...", func(ctx context.Context) {
+		heights, fetchErr = a.searchResources(ctx, "Observation", 1, map[string]string{
+			fhir.SearchObservationPatient: patientID,
+			fhir.SearchObservationCode:    "8302-2",
+			fhir.SearchSort:               "-date",
+		})
+	})
+	if err != nil {
+		return 0, "", err
+	}
+	if fetchErr != nil {
+		return 0, "", fmt.Errorf("looking up height: %w", fetchErr)
+	}
+	if len(heights) == 0 {
+		return 0, "", nil
+	}
+
+	m, err := fhir.Parse(heights[0])
+	if err != nil {
+		return 0, "", nil
+	}
+	cm, _ := fhir.Path(m, "valueQuantity.value").(float64)
+	if cm <= 0 {
+		return 0, "", nil
+	}
+	bmi := fhir.ComputeBMI(kg, cm)
+	measured := fhir.ObservationDate(m)
+	if measured == "" {
+		measured = "date not recorded"
+	}
+
+	record := true
+	err = huh.NewConfirm().
+		Title(fmt.Sprintf("Also record BMI %.1f?", bmi)).
+		Description(fmt.Sprintf("From this weight and the latest height, %g cm (%s)", cm, measured)).
+		Value(&record).
+		Run()
+	if err != nil || !record {
+		return 0, "", err
+	}
+	return bmi, mapStr(m, "id"), nil
+}
+
+// RecordLabPanel records a lab panel (e.g. a lipid panel) as one panel
+// Observation whose hasMember references the individual results. The panel
+// and its results are created in a single transaction so they are stored as
+// one unit.
+func (a *App) RecordLabPanel() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var panelIdx int
+	var options []huh.Option[int]
+	for i, p := range fhir.LabPanels {
+		options = append(options, huh.NewOption(p.Text, i))
+	}
+	if err := huh.NewSelect[int]().Title("Panel").Options(options...).Value(&panelIdx).Run(); err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	panel := fhir.LabPanels[panelIdx]
+
+	values := make([]string, len(panel.Members))
+	var fields []huh.Field
+	for i, m := range panel.Members {
+		fields = append(fields, huh.NewInput().
+			Title(fmt.Sprintf("%s (%s, blank to skip)", m.Text, m.Unit)).
+			Value(&values[i]).
+			Validate(func(s string) error {
+				if s == "" {
+					return nil
+				}
+				if _, err := strconv.ParseFloat(s, 64); err != nil {
+					return fmt.Errorf("must be a number")
+				}
+				return nil
+			}))
+	}
+	if err := huh.NewForm(huh.NewGroup(fields...)).Run(); err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	note, err := askNote()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var entries, issues []map[string]any
+	var refs []string
+	results := make(map[string]json.RawMessage) // by urn
+	for i, m := range panel.Members {
+		if values[i] == "" {
+			continue
+		}
+		value, _ := strconv.ParseFloat(values[i], 64)
+		urn := "urn:uuid:" + newUUID()
+		body := fhir.NewLabObservation(patientID, m, value)
+		entries = append(entries, bundleEntryWithUrn(urn, "Observation", body))
+		results[urn] = body
+		refs = append(refs, urn)
+	}
+	if len(refs) == 0 {
+		fmt.Println("\n  No results entered.")
+		PressEnter()
+		return
+	}
+	panelBody := fhir.NewPanelObservation(patientID, panel, refs)
+	if note != "" {
+		panelBody = fhir.WithNote(panelBody, note)
+	}
+	entries = append(entries, fhir.BundleEntry("Observation", panelBody))
+
+	var created int
+	var apiErr error
+	var elapsed time.Duration
+
+	err = runSpinner("Recording panel...", func(ctx context.Context) {
+		start := time.Now()
+		for _, urn := range refs {
+			var more []map[string]any
+			more, apiErr = a.issueEntries(ctx, urn, results[urn])
+			if apiErr != nil {
+				return
+			}
+			issues = append(issues, more...)
+		}
+		created, apiErr = a.processTransaction(ctx, append(entries, issues...))
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(fmt.Errorf("processing bundle: %w", apiErr))
+		PressEnter()
+		return
+	}
+
+	fmt.Printf("\n  Recorded %s with %d results\n", panel.Text, len(refs))
+	for _, e := range issues {
+		raw, _ := e["resource"].(json.RawMessage)
+		if m, err := fhir.Parse(raw); err == nil {
+			fmt.Printf("  ! Alert: %s\n", fhir.DetectedIssueDisplay(m))
+		}
+	}
+	showTiming(fmt.Sprintf("Created %d resources via transaction bundle", created), elapsed)
+	PressEnter()
+}
+
+// labMatches is how many tests the lab test picker lists at once.
+const labMatches = 30
+
+// pickLabTest searches the bundled LOINC catalog by name, unit, or code as
+// the user types. With nothing typed it lists the common tests.
+func pickLabTest() (fhir.LabTest, error) {
+	var query string
+	var test fhir.LabTest
+	err := huh.NewForm(huh.NewGroup(
+		huh.NewInput().
+			Title("Search lab tests (name, unit, or LOINC code, e.g., ferritin or 2276-4)").
+			Value(&query),
+		huh.NewSelect[fhir.LabTest]().
+			Title("Lab test").
+			OptionsFunc(func() []huh.Option[fhir.LabTest] {
+				tests := fhir.LabTests
+				if strings.TrimSpace(query) != "" {
+					tests = fhir.SearchLabTests(query, labMatches)
+				}
+				options := make([]huh.Option[fhir.LabTest], len(tests))
+				for i, t := range tests {
+					options[i] = huh.NewOption(fmt.Sprintf("%s (%s) — LOINC %s", t.Text, t.Unit, t.Code), t)
+				}
+				return options
+			}, &query).
+			Value(&test).
+			Validate(func(t fhir.LabTest) error {
+				if t.Code == "" {
+					return fmt.Errorf("search for a test and pick one")
+				}
+				return nil
+			}),
+	)).Run()
+	return test, err
+}
+
+// RecordLabResult records a single lab result, picked from the bundled
+// LOINC catalog.
+func (a *App) RecordLabResult() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	test, err := pickLabTest()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var valueStr string
+	err = huh.NewInput().
+		Title(fmt.Sprintf("%s (%s)", test.Text, test.Unit)).
+		Value(&valueStr).
+		Validate(func(s string) error {
+			if _, err := strconv.ParseFloat(s, 64); err != nil {
+				return fmt.Errorf("must be a number")
+			}
+			return nil
+		}).
+		Run()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	value, _ := strconv.ParseFloat(valueStr, 64)
+	body := fhir.NewLabObservation(patientID, test, value)
+
+	note, err := askNote()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	if note != "" {
+		body = fhir.WithNote(body, note)
+	}
+
+	ctx := context.Background()
+	var created json.RawMessage
+	var issues []json.RawMessage
+	var apiErr, issueErr error
+
+	err = runSpinner("Recording lab result...", func(ctx context.Context) {
+		created, apiErr = a.createResource(ctx, "Observation", body)
+		if apiErr == nil {
+			issues, issueErr = a.raiseIssues(ctx, []json.RawMessage{created})
+		}
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(fmt.Errorf("creating observation: %w", apiErr))
+		PressEnter()
+		return
+	}
+
+	id := fhir.ResourceID(created)
+	a.emit(ctx, EventObservationCreated, "Observation", id, patientID)
+	fmt.Printf("\n  Recorded %s %g %s (ID: %s)\n", test.Text, value, test.Unit, id)
+	a.reportIssues(ctx, issues)
+	if issueErr != nil {
+		ShowError(issueErr)
 	}
 	PressEnter()
 }
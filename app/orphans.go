@@ -0,0 +1,188 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// orphanResourceTypes are the types checked for subjects that no longer exist.
+var orphanResourceTypes = []string{"Observation", "Condition", "CarePlan"}
+
+// orphanGroup is the set of resources that point at one missing patient.
+type orphanGroup struct {
+	patientID string
+	resources []map[string]any
+}
+
+// findOrphans groups resources by subject patient, keeping only patients
+// that are not in patients. Subjects that are not patients, such as Groups,
+// are not orphans.
+func findOrphans(patients, resources []json.RawMessage) []orphanGroup {
+	known := make(map[string]bool)
+	for _, raw := range patients {
+		known[fhir.ResourceID(raw)] = true
+	}
+	byPatient := make(map[string][]map[string]any)
+	for _, raw := range resources {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		if id, ok := fhir.SubjectPatient(m); ok && id != "" && !known[id] {
+			byPatient[id] = append(byPatient[id], m)
+		}
+	}
+	groups := make([]orphanGroup, 0, len(byPatient))
+	for id, rs := range byPatient {
+		groups = append(groups, orphanGroup{id, rs})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].patientID < groups[j].patientID })
+	return groups
+}
+
+// counts summarizes a group as e.g. "3 Observation, 1 Condition".
+func (g orphanGroup) counts() string {
+	n := make(map[string]int)
+	for _, m := range g.resources {
+		n[mapStr(m, "resourceType")]++
+	}
+	s := ""
+	for _, rt := range orphanResourceTypes {
+		if n[rt] > 0 {
+			if s != "" {
+				s += ", "
+			}
+			s += fmt.Sprintf("%d %s", n[rt], rt)
+		}
+	}
+	return s
+}
+
+// CleanUpOrphans finds Observations, Conditions, and CarePlans whose subject
+// patient no longer exists, e.g. after a hard delete of the patient, and for
+// each missing patient offers to delete the resources or re-link them to an
+// existing patient.
+func (a *App) CleanUpOrphans() {
+	ctx := context.Background()
+	var groups []orphanGroup
+	var fetchErr error
+	var elapsed time.Duration
+
+	err := runSpinner("Scanning for orphaned resources...", func(ctx context.Context) {
+		start := time.Now()
+		patients, err := a.searchAllPages(ctx, "Patient", 100, nil, nil)
+		if err != nil {
+			fetchErr = err
+			return
+		}
+		var resources []json.RawMessage
+		for _, rt := range orphanResourceTypes {
+			page, err := a.searchAllPages(ctx, rt, 100, nil, nil)
+			if err != nil {
+				fetchErr = err
+				return
+			}
+			resources = append(resources, page...)
+		}
+		groups = findOrphans(patients, resources)
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	showTiming(fmt.Sprintf("Found orphaned resources for %d missing patients", len(groups)), elapsed)
+	if len(groups) == 0 {
+		PressEnter()
+		return
+	}
+
+	deleted, relinked := 0, 0
+review:
+	for _, g := range groups {
+		fmt.Printf("\n  Patient/%s (missing): %s\n", g.patientID, g.counts())
+
+		var action string
+		err := huh.NewSelect[string]().
+			Title(fmt.Sprintf("Orphans of Patient/%s", g.patientID)).
+			Options(
+				huh.NewOption(fmt.Sprintf("Delete %d resources", len(g.resources)), "delete"),
+				huh.NewOption("Re-link to an existing patient", "relink"),
+				huh.NewOption("Skip", "skip"),
+				huh.NewOption("Stop", "stop"),
+			).
+			Value(&action).
+			Run()
+		if err != nil || action == "stop" {
+			if err != nil && !isAbort(err) {
+				ShowError(err)
+			}
+			break
+		}
+
+		entries := make([]map[string]any, 0, len(g.resources))
+		targets := make([]string, 0, len(g.resources))
+		switch action {
+		case "skip":
+			continue
+
+		case "delete":
+			for _, m := range g.resources {
+				rt, id := mapStr(m, "resourceType"), mapStr(m, "id")
+				entries = append(entries, fhir.DeleteEntry(rt, id))
+				targets = append(targets, rt+"/"+id)
+			}
+			n, _, err := a.processInBatches(ctx, entries, targets, "DELETE", "deleted")
+			deleted += n
+			if err != nil {
+				ShowError(err)
+				if errors.Is(err, errCancelled) {
+					break review
+				}
+			}
+
+		case "relink":
+			patientID, err := a.PickPatient()
+			if err != nil || patientID == "" {
+				if err != nil && !isAbort(err) {
+					ShowError(err)
+				}
+				continue
+			}
+			for _, m := range g.resources {
+				rt, id := mapStr(m, "resourceType"), mapStr(m, "id")
+				m["subject"] = map[string]any{"reference": "Patient/" + patientID}
+				body, _ := json.Marshal(m)
+				entries = append(entries, fhir.UpdateEntry(rt, id, body))
+				targets = append(targets, rt+"/"+id)
+			}
+			n, _, err := a.processInBatches(ctx, entries, targets, "UPDATE", "re-linked")
+			relinked += n
+			if err != nil {
+				ShowError(err)
+				if errors.Is(err, errCancelled) {
+					break review
+				}
+			}
+		}
+	}
+
+	fmt.Printf("\n  Deleted %d and re-linked %d orphaned resources\n", deleted, relinked)
+	PressEnter()
+}
@@ -0,0 +1,24 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFindOrphans(t *testing.T) {
+	patients := []json.RawMessage{json.RawMessage(`{"resourceType":"Patient","id":"p1"}`)}
+	resources := []json.RawMessage{
+		json.RawMessage(`{"resourceType":"Observation","id":"o1","subject":{"reference":"Patient/p1"}}`),
+		json.RawMessage(`{"resourceType":"Observation","id":"o2","subject":{"reference":"Patient/gone"}}`),
+		json.RawMessage(`{"resourceType":"Condition","id":"c1","subject":{"reference":"Patient/gone"}}`),
+		json.RawMessage(`{"resourceType":"Observation","id":"o3","subject":{"reference":"Group/g1"}}`),
+		json.RawMessage(`{"resourceType":"Observation","id":"o4","subject":{"reference":"Device/d1"}}`),
+		json.RawMessage(`{"resourceType":"Observation","id":"o5","subject":{"reference":"https://other.example/fhir/Patient/p9"}}`),
+		json.RawMessage(`{"resourceType":"Observation","id":"o6"}`),
+	}
+
+	groups := findOrphans(patients, resources)
+	if len(groups) != 1 || groups[0].patientID != "gone" || groups[0].counts() != "1 Observation, 1 Condition" {
+		t.Errorf("findOrphans = %+v, want the two resources of Patient/gone", groups)
+	}
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// outcomeQuarters is how many calendar quarters, the current one included,
+// the outcomes report covers.
+const outcomeQuarters = 4
+
+// askPlanOutcome asks how a care plan that was just completed turned out.
+func askPlanOutcome() (fhir.PlanOutcome, error) {
+	options := make([]huh.Option[int], len(fhir.PlanOutcomes))
+	for i, o := range fhir.PlanOutcomes {
+		options[i] = huh.NewOption(o.Display, i)
+	}
+	var idx int
+	err := huh.NewSelect[int]().
+		Title("All activities are done. How did the plan turn out?").
+		Options(options...).
+		Value(&idx).
+		Run()
+	return fhir.PlanOutcomes[idx], err
+}
+
+// updatePlanGoals records a plan's outcome as the achievement status of
+// each Goal it references.
+func (a *App) updatePlanGoals(ctx context.Context, cp map[string]any, o fhir.PlanOutcome) error {
+	for _, id := range fhir.CarePlanGoalIDs(cp) {
+		raw, err := a.readResource(ctx, "Goal", id)
+		if err != nil {
+			return fmt.Errorf("reading goal %s: %w", id, err)
+		}
+		goal, err := fhir.Parse(raw)
+		if err != nil {
+			return fmt.Errorf("parsing goal %s: %w", id, err)
+		}
+		fhir.WithGoalAchievement(goal, o)
+		body, _ := json.Marshal(goal)
+		if _, err := a.updateResource(ctx, "Goal", id, body); err != nil {
+			return fmt.Errorf("updating goal %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// outcomeTables summarizes care plans started in the last outcomeQuarters
+// quarters by quarter and template: how many were completed, and with what
+// outcome.
+func (a *App) outcomeTables(ctx context.Context, now time.Time, scope cohortScope) ([]reportTable, error) {
+	plans, err := a.searchAllPages(ctx, "CarePlan", 100, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var parsed []map[string]any
+	for _, raw := range scope.filter(plans) {
+		if m, err := fhir.Parse(raw); err == nil {
+			parsed = append(parsed, m)
+		}
+	}
+	templates := make(map[string]string, len(planTemplates))
+	for _, t := range planTemplates {
+		templates[planTemplateBaseURL+t.name] = t.title
+	}
+	since := fhir.QuarterStart(now).AddDate(0, -3*(outcomeQuarters-1), 0)
+
+	t := reportTable{Title: "Care plan outcomes", Headers: []string{"Quarter", "Template", "Plans", "Completed", "Completion"}}
+	for _, o := range fhir.PlanOutcomes {
+		t.Headers = append(t.Headers, o.Display)
+	}
+	t.Headers = append(t.Headers, "No outcome")
+	for _, r := range fhir.SummarizePlanOutcomes(parsed, templates, since) {
+		row := []string{r.Quarter, r.Template, strconv.Itoa(r.Plans), strconv.Itoa(r.Completed),
+			fmt.Sprintf("%.0f%%", 100*r.CompletionRate())}
+		for _, o := range fhir.PlanOutcomes {
+			row = append(row, strconv.Itoa(r.Outcomes[o.Code]))
+		}
+		t.Rows = append(t.Rows, append(row, strconv.Itoa(r.Outcomes[""])))
+	}
+	return []reportTable{t}, nil
+}
+
+// OutcomesReport shows how care plans started in the last four quarters
+// turned out, by quarter and template.
+func (a *App) OutcomesReport() {
+	var tables []reportTable
+	var fetchErr error
+	var elapsed time.Duration
+
+	err := runSpinner("Summarizing care plan outcomes...", func(ctx context.Context) {
+		start := time.Now()
+		tables, fetchErr = a.outcomeTables(ctx, time.Now(), nil)
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	for _, t := range tables {
+		printReportTable(t)
+	}
+	showTiming("Summarized care plan outcomes", elapsed)
+	PressEnter()
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// typeCount is how many resources of one type the store holds and how long
+// the count took.
+type typeCount struct {
+	ResourceType string
+	Count        int
+	Elapsed      time.Duration
+}
+
+// countAll counts every resource type in parallel with _summary=count, in
+// the order of types.
+func (a *App) countAll(ctx context.Context, types []string) ([]typeCount, error) {
+	counts := make([]typeCount, len(types))
+	errs := make([]error, len(types))
+	var wg sync.WaitGroup
+	for i, rt := range types {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			n, err := a.countResources(ctx, rt)
+			counts[i], errs[i] = typeCount{rt, n, time.Since(start)}, err
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return counts, nil
+}
+
+// StoreOverview counts each resource type the demo writes and shows the
+// totals with how long each count took. Unlike Store Growth, nothing is
+// saved.
+func (a *App) StoreOverview() {
+	var counts []typeCount
+	var apiErr error
+	var elapsed time.Duration
+
+	err := runSpinner("Counting resources...", func(ctx context.Context) {
+		start := time.Now()
+		counts, apiErr = a.countAll(ctx, metricsResourceTypes)
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	printStoreOverview(counts)
+	fmt.Println()
+	showTiming(fmt.Sprintf("Counted %d resource types with _summary=count (%d parallel API calls)", len(counts), len(counts)), elapsed)
+	PressEnter()
+}
+
+// printStoreOverview lists each resource type's count and count time, then
+// the total. Types with no resources are dimmed.
+func printStoreOverview(counts []typeCount) {
+	fmt.Println(barStyle.Bold(true).Render("Store Overview"))
+	fmt.Println(timingStyle.Render(fmt.Sprintf("  %-18s %8s %8s", "RESOURCE", "COUNT", "TIME")))
+	total := 0
+	for _, c := range counts {
+		line := fmt.Sprintf("  %-18s %8d %8s", c.ResourceType, c.Count, c.Elapsed.Round(time.Millisecond))
+		if c.Count == 0 {
+			line = timingStyle.Render(line)
+		}
+		fmt.Println(line)
+		total += c.Count
+	}
+	fmt.Printf("  %-18s %8d\n", "Total", total)
+}
@@ -0,0 +1,27 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"testing"
+)
+
+func TestCountAll(t *testing.T) {
+	a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+		total := map[string]int{"Patient": 5, "Observation": 42}[path.Base(r.URL.Path)]
+		fmt.Fprintf(w, `{"resourceType":"Bundle","type":"searchset","total":%d}`, total)
+	})
+
+	counts, err := a.countAll(context.Background(), []string{"Patient", "Observation", "Flag"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []int{5, 42, 0}
+	for i, c := range counts {
+		if c.Count != want[i] {
+			t.Errorf("%s = %d, want %d", c.ResourceType, c.Count, want[i])
+		}
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"time"
 
 	"github.com/charmbracelet/huh"
-	"github.com/charmbracelet/huh/spinner"
 	"github.com/phenoml/phenostore-example-go/fhir"
 )
 
@@ -39,12 +38,9 @@ func (a *App) RegisterPatient() {
 	var created json.RawMessage
 	var apiErr error
 
-	err := spinner.New().
-		Title("Registering patient...").
-		Action(func() {
-			created, apiErr = a.Client.CreateResource(context.Background(), "Patient", body, nil)
-		}).
-		Run()
+	err := runSpinner("Registering patient...", func(ctx context.Context) {
+		created, apiErr = a.createResource(ctx, "Patient", body)
+	})
 
 	if err != nil {
 		ShowError(err)
@@ -58,24 +54,27 @@ func (a *App) RegisterPatient() {
 	}
 
 	id := fhir.ResourceID(created)
+	a.emit(context.Background(), EventPatientCreated, "Patient", id, id)
 	fmt.Printf("\n  Created patient %s %s (ID: %s)\n", given, family, id)
 	PressEnter()
 }
 
-// ListPatients fetches and displays all patients.
+// ListPatients fetches and displays all patients, asking the server for only
+// the elements the list shows.
 func (a *App) ListPatients() {
 	var patients []json.RawMessage
+	var fullSize int
 	var fetchErr error
 	var elapsed time.Duration
 
-	err := spinner.New().
-		Title("Loading patients...").
-		Action(func() {
-			start := time.Now()
-			patients, fetchErr = a.fetchAllPatients(context.Background())
-			elapsed = time.Since(start)
-		}).
-		Run()
+	err := runSpinner("Loading patients...", func(ctx context.Context) {
+		start := time.Now()
+		patients, fetchErr = a.fetchPatientList(ctx)
+		elapsed = time.Since(start)
+		if fetchErr == nil && len(patients) > 0 {
+			fullSize = a.fullPatientSize(ctx)
+		}
+	})
 
 	if err != nil {
 		ShowError(err)
@@ -93,7 +92,7 @@ func (a *App) ListPatients() {
 		fmt.Println("  No patients found.")
 	} else {
 		fhir.PrintPatientList(patients)
-		showTiming(fmt.Sprintf("Fetched %d patients", len(patients)), elapsed)
+		showTiming(fmt.Sprintf("Fetched %d patients, %s,", len(patients), elementsSaving(len(patients), payloadSize(patients), fullSize)), elapsed)
 	}
 	PressEnter()
 }
@@ -114,17 +113,20 @@ func (a *App) ViewPatient() {
 	}
 
 	var raw json.RawMessage
+	var flags []json.RawMessage
 	var apiErr error
 	var elapsed time.Duration
 
-	err = spinner.New().
-		Title("Loading patient...").
-		Action(func() {
-			start := time.Now()
-			raw, apiErr = a.Client.ReadResource(context.Background(), "Patient", patientID)
-			elapsed = time.Since(start)
-		}).
-		Run()
+	err = runSpinner("Loading patient...", func(ctx context.Context) {
+		start := time.Now()
+		raw, apiErr = a.readResource(ctx, "Patient", patientID)
+		if apiErr != nil {
+			apiErr = fmt.Errorf("reading patient: %w", apiErr)
+			return
+		}
+		flags, apiErr = a.searchByPatient(ctx, "Flag", patientID, "")
+		elapsed = time.Since(start)
+	})
 
 	if err != nil {
 		ShowError(err)
@@ -132,14 +134,16 @@ func (a *App) ViewPatient() {
 		return
 	}
 	if apiErr != nil {
-		ShowError(fmt.Errorf("reading patient: %w", apiErr))
+		ShowError(apiErr)
 		PressEnter()
 		return
 	}
 
 	fmt.Println()
+	fhir.PrintFlagBanner(flags)
 	fhir.PrintPatient(raw)
 	showTiming("Loaded patient", elapsed)
+	showingResource(raw)
 	PressEnter()
 }
 
@@ -180,45 +184,41 @@ func (a *App) UpdateContact() {
 	}
 
 	var apiErr error
-	err = spinner.New().
-		Title("Updating patient...").
-		Action(func() {
-			ctx := context.Background()
-
-			raw, err := a.Client.ReadResource(ctx, "Patient", patientID)
-			if err != nil {
-				apiErr = fmt.Errorf("reading patient: %w", err)
-				return
-			}
-
-			var patient map[string]any
-			if err := json.Unmarshal(raw, &patient); err != nil {
-				apiErr = fmt.Errorf("parsing patient: %w", err)
-				return
-			}
-
-			telecoms, _ := patient["telecom"].([]any)
-			if phone != "" {
-				telecoms = append(telecoms, map[string]any{"system": "phone", "value": phone})
-			}
-			if email != "" {
-				telecoms = append(telecoms, map[string]any{"system": "email", "value": email})
-			}
-			patient["telecom"] = telecoms
-
-			updated, err := json.Marshal(patient)
-			if err != nil {
-				apiErr = fmt.Errorf("marshaling patient: %w", err)
-				return
-			}
-
-			_, err = a.Client.UpdateResource(ctx, "Patient", patientID, updated, nil)
-			if err != nil {
-				apiErr = fmt.Errorf("updating patient: %w", err)
-				return
-			}
-		}).
-		Run()
+	err = runSpinner("Updating patient...", func(ctx context.Context) {
+
+		raw, err := a.readResource(ctx, "Patient", patientID)
+		if err != nil {
+			apiErr = fmt.Errorf("reading patient: %w", err)
+			return
+		}
+
+		var patient map[string]any
+		if err := json.Unmarshal(raw, &patient); err != nil {
+			apiErr = fmt.Errorf("parsing patient: %w", err)
+			return
+		}
+
+		telecoms, _ := patient["telecom"].([]any)
+		if phone != "" {
+			telecoms = append(telecoms, map[string]any{"system": "phone", "value": phone})
+		}
+		if email != "" {
+			telecoms = append(telecoms, map[string]any{"system": "email", "value": email})
+		}
+		patient["telecom"] = telecoms
+
+		updated, err := json.Marshal(patient)
+		if err != nil {
+			apiErr = fmt.Errorf("marshaling patient: %w", err)
+			return
+		}
+
+		_, err = a.updateResource(ctx, "Patient", patientID, updated)
+		if err != nil {
+			apiErr = fmt.Errorf("updating patient: %w", err)
+			return
+		}
+	})
 
 	if err != nil {
 		ShowError(err)
@@ -231,6 +231,7 @@ func (a *App) UpdateContact() {
 		return
 	}
 
+	a.emit(context.Background(), EventPatientUpdated, "Patient", patientID, patientID)
 	fmt.Printf("\n  Updated patient %s\n", patientID)
 	PressEnter()
 }
@@ -261,12 +262,9 @@ func (a *App) DeletePatient() {
 	}
 
 	var apiErr error
-	err = spinner.New().
-		Title("Deleting patient...").
-		Action(func() {
-			apiErr = a.Client.DeleteResource(context.Background(), "Patient", patientID)
-		}).
-		Run()
+	err = runSpinner("Deleting patient...", func(ctx context.Context) {
+		apiErr = a.deleteResource(ctx, "Patient", patientID)
+	})
 
 	if err != nil {
 		ShowError(err)
@@ -279,6 +277,7 @@ func (a *App) DeletePatient() {
 		return
 	}
 
+	a.emit(context.Background(), EventPatientDeleted, "Patient", patientID, patientID)
 	fmt.Printf("\n  Deleted patient %s\n", patientID)
 	PressEnter()
 }
@@ -0,0 +1,156 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	neturl "net/url"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// How PickPatient finds a patient (PHENOSTORE_PATIENT_PICKER).
+const (
+	PatientPickerAuto   = "auto"   // list every patient if they fit on one page, else search
+	PatientPickerList   = "list"   // always list every patient
+	PatientPickerSearch = "search" // always search the server first
+)
+
+const (
+	// patientListPage is the most patients the auto picker lists; a store
+	// with more is searched instead.
+	patientListPage = 100
+	// patientSearchPage is how many matches a patient search shows at once.
+	patientSearchPage = 20
+)
+
+// Picker options that are not patients.
+const (
+	pickMore      = "\x00more"
+	pickNewSearch = "\x00search"
+)
+
+// birthDateTerm matches a birth date typed in a patient search: a year, a
+// year and month, or a full date.
+var birthDateTerm = regexp.MustCompile(`^\d{4}(-\d{2}(-\d{2})?)?$`)
+
+// patientSearchQuery turns what was typed in a patient search into Patient
+// search parameters. Dates such as 1980-04-12 search birthdate, other terms
+// containing a digit search identifier (e.g. an MRN), and every remaining
+// word must match part of a name.
+func patientSearchQuery(s string) (neturl.Values, error) {
+	query := neturl.Values{}
+	for _, term := range strings.Fields(s) {
+		switch {
+		case birthDateTerm.MatchString(term):
+			query.Add(fhir.SearchPatientBirthdate, term)
+		case strings.ContainsAny(term, "0123456789"):
+			query.Add(fhir.SearchPatientIdentifier, term)
+		default:
+			query.Add(fhir.SearchPatientName, term)
+		}
+	}
+	if len(query) == 0 {
+		return nil, fmt.Errorf("enter a name, birth date, or MRN")
+	}
+	return query, nil
+}
+
+// searchPatients fetches one page of patients matching query, or the page
+// next points to, and its next link.
+func (a *App) searchPatients(ctx context.Context, query neturl.Values, next string) (patients []json.RawMessage, link string, err error) {
+	defer func() {
+		a.audit(ctx, fhir.AuditRead, "search-type", "Patient", "Patient", "", err)
+		a.logResultAccess("Patient", patients, err)
+	}()
+	return a.searchPage(ctx, "Patient", patientSearchPage, withElements(query, patientListElements), next)
+}
+
+// patientOptions labels patients for a select with their name and birth
+// date, and returns the names of restricted patients by ID.
+func patientOptions(patients []json.RawMessage) ([]huh.Option[string], map[string]string) {
+	var options []huh.Option[string]
+	restricted := make(map[string]string)
+	for _, raw := range patients {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		id := fhir.ResourceID(raw)
+		name := fhir.PatientName(m)
+		label := fmt.Sprintf("%s (%s)", name, mapStr(m, "birthDate"))
+		if fhir.IsRestricted(m) {
+			label += " [restricted]"
+			restricted[id] = name
+		}
+		options = append(options, huh.NewOption(label, id))
+	}
+	return options, restricted
+}
+
+// searchForPatient asks for a name, birth date, or MRN, searches the server
+// for matching patients, and presents a page of them at a time. It returns
+// the picked patient's ID and, if they are restricted, their name.
+func (a *App) searchForPatient(ctx context.Context) (patientID, restrictedName string, err error) {
+	var text string
+	for {
+		err = huh.NewInput().
+			Title("Search patients").
+			Description("Name, birth date (YYYY-MM-DD), or MRN, e.g. ruiz 1980-04-12").
+			Value(&text).
+			Validate(func(s string) error {
+				_, err := patientSearchQuery(s)
+				return err
+			}).
+			Run()
+		if err != nil {
+			return "", "", err
+		}
+		query, _ := patientSearchQuery(text)
+
+		var patients []json.RawMessage
+		next := ""
+		for {
+			var page []json.RawMessage
+			var fetchErr error
+			err = runSpinner("Searching patients...", func(ctx context.Context) {
+				page, next, fetchErr = a.searchPatients(ctx, query, next)
+			})
+			if err != nil {
+				return "", "", err
+			}
+			if fetchErr != nil {
+				return "", "", fetchErr
+			}
+			patients = append(patients, page...)
+			if len(patients) == 0 {
+				fmt.Printf("\n  No patients match %q.\n\n", strings.TrimSpace(text))
+				break
+			}
+
+			options, restricted := patientOptions(patients)
+			if next != "" {
+				options = append(options, huh.NewOption(fmt.Sprintf("More results (showing %d)...", len(patients)), pickMore))
+			}
+			options = append(options, huh.NewOption("New search", pickNewSearch))
+			err = huh.NewSelect[string]().
+				Title(fmt.Sprintf("Select a patient (%d found)", len(patients))).
+				Options(options...).
+				Value(&patientID).
+				Filtering(true).
+				Run()
+			if err != nil {
+				return "", "", err
+			}
+			if patientID == pickNewSearch {
+				break
+			}
+			if patientID != pickMore {
+				return patientID, restricted[patientID], nil
+			}
+		}
+	}
+}
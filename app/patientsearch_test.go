@@ -0,0 +1,19 @@
+package app
+
+import "testing"
+
+func TestPatientSearchQuery(t *testing.T) {
+	q, err := patientSearchQuery(" ana  ruiz 1980-04-12 MRN-0042 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := q.Encode(); got != "birthdate=1980-04-12&identifier=MRN-0042&name=ana&name=ruiz" {
+		t.Errorf("query = %s", got)
+	}
+	if q, _ := patientSearchQuery("1980"); q.Get("birthdate") != "1980" {
+		t.Errorf("a year searches birthdate: %v", q)
+	}
+	if _, err := patientSearchQuery("   "); err == nil {
+		t.Error("empty search: want an error")
+	}
+}
@@ -4,13 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
+	neturl "net/url"
 	"time"
 
 	"github.com/charmbracelet/huh"
-	"github.com/charmbracelet/huh/spinner"
 	"github.com/phenoml/phenostore-example-go/fhir"
-	"github.com/phenoml/phenostore-sdk-go/phenostore/gen"
 )
 
 // CreatePlan lets the user pick a patient and create a new care plan.
@@ -38,12 +36,9 @@ func (a *App) CreatePlan() {
 	var created json.RawMessage
 	var apiErr error
 
-	err = spinner.New().
-		Title("Creating care plan...").
-		Action(func() {
-			created, apiErr = a.Client.CreateResource(context.Background(), "CarePlan", body, nil)
-		}).
-		Run()
+	err = runSpinner("Creating care plan...", func(ctx context.Context) {
+		created, apiErr = a.createResource(ctx, "CarePlan", body)
+	})
 
 	if err != nil {
 		ShowError(err)
@@ -57,6 +52,7 @@ func (a *App) CreatePlan() {
 	}
 
 	id := fhir.ResourceID(created)
+	a.emit(context.Background(), EventCarePlanCreated, "CarePlan", id, patientID)
 	fmt.Printf("\n  Created health plan %q (ID: %s)\n", title, id)
 	PressEnter()
 }
@@ -99,40 +95,36 @@ func (a *App) AddActivity() {
 
 	var apiErr error
 
-	err = spinner.New().
-		Title("Adding activity...").
-		Action(func() {
-			ctx := context.Background()
+	err = runSpinner("Adding activity...", func(ctx context.Context) {
 
-			raw, err := a.Client.ReadResource(ctx, "CarePlan", cpID)
-			if err != nil {
-				apiErr = fmt.Errorf("reading care plan: %w", err)
-				return
-			}
+		raw, err := a.readResource(ctx, "CarePlan", cpID)
+		if err != nil {
+			apiErr = fmt.Errorf("reading care plan: %w", err)
+			return
+		}
 
-			var carePlan map[string]any
-			if err := json.Unmarshal(raw, &carePlan); err != nil {
-				apiErr = fmt.Errorf("parsing care plan: %w", err)
-				return
-			}
+		var carePlan map[string]any
+		if err := json.Unmarshal(raw, &carePlan); err != nil {
+			apiErr = fmt.Errorf("parsing care plan: %w", err)
+			return
+		}
 
-			activities, _ := carePlan["activity"].([]any)
-			activities = append(activities, fhir.NewCarePlanActivity(description, due))
-			carePlan["activity"] = activities
+		activities, _ := carePlan["activity"].([]any)
+		activities = append(activities, fhir.NewCarePlanActivity(description, due))
+		carePlan["activity"] = activities
 
-			updated, err := json.Marshal(carePlan)
-			if err != nil {
-				apiErr = fmt.Errorf("marshaling care plan: %w", err)
-				return
-			}
+		updated, err := json.Marshal(carePlan)
+		if err != nil {
+			apiErr = fmt.Errorf("marshaling care plan: %w", err)
+			return
+		}
 
-			_, err = a.Client.UpdateResource(ctx, "CarePlan", cpID, updated, nil)
-			if err != nil {
-				apiErr = fmt.Errorf("updating care plan: %w", err)
-				return
-			}
-		}).
-		Run()
+		_, err = a.updateResource(ctx, "CarePlan", cpID, updated)
+		if err != nil {
+			apiErr = fmt.Errorf("updating care plan: %w", err)
+			return
+		}
+	})
 
 	if err != nil {
 		ShowError(err)
@@ -145,11 +137,13 @@ func (a *App) AddActivity() {
 		return
 	}
 
+	a.emit(context.Background(), EventCarePlanUpdated, "CarePlan", cpID, patientID)
 	fmt.Printf("\n  Added activity: %s\n", description)
 	PressEnter()
 }
 
 // CompleteActivity lets the user pick a patient, plan, and activity to mark as completed.
+// Completing the last activity completes the plan, and asks for its outcome.
 func (a *App) CompleteActivity() {
 	patientID, err := a.PickPatient()
 	if err != nil || patientID == "" {
@@ -174,12 +168,9 @@ func (a *App) CompleteActivity() {
 	var carePlanRaw json.RawMessage
 	var apiErr error
 
-	err = spinner.New().
-		Title("Loading care plan...").
-		Action(func() {
-			carePlanRaw, apiErr = a.Client.ReadResource(ctx, "CarePlan", cpID)
-		}).
-		Run()
+	err = runSpinner("Loading care plan...", func(ctx context.Context) {
+		carePlanRaw, apiErr = a.readResource(ctx, "CarePlan", cpID)
+	})
 
 	if err != nil {
 		ShowError(err)
@@ -262,18 +253,29 @@ func (a *App) CompleteActivity() {
 			break
 		}
 	}
+	var outcome fhir.PlanOutcome
 	if allDone {
 		carePlan["status"] = "completed"
+		outcome, err = askPlanOutcome()
+		if err != nil {
+			if !isAbort(err) {
+				ShowError(err)
+				PressEnter()
+			}
+			return
+		}
+		fhir.WithPlanOutcome(carePlan, outcome, time.Now())
 	}
 
 	updated, _ := json.Marshal(carePlan)
+	var goalErr error
 
-	err = spinner.New().
-		Title("Updating care plan...").
-		Action(func() {
-			_, apiErr = a.Client.UpdateResource(ctx, "CarePlan", cpID, updated, nil)
-		}).
-		Run()
+	err = runSpinner("Updating care plan...", func(ctx context.Context) {
+		_, apiErr = a.updateResource(ctx, "CarePlan", cpID, updated)
+		if apiErr == nil && allDone {
+			goalErr = a.updatePlanGoals(ctx, carePlan, outcome)
+		}
+	})
 
 	if err != nil {
 		ShowError(err)
@@ -286,10 +288,19 @@ func (a *App) CompleteActivity() {
 		return
 	}
 
+	event := EventCarePlanUpdated
+	if allDone {
+		event = EventCarePlanCompleted
+	}
+	a.emit(ctx, event, "CarePlan", cpID, patientID)
+
 	desc, _ := detail["description"].(string)
 	fmt.Printf("\n  Completed activity: %s\n", desc)
 	if allDone {
-		fmt.Println("  All activities completed \u2014 plan marked as completed.")
+		fmt.Printf("  All activities completed \u2014 plan marked as completed (%s).\n", outcome.Display)
+	}
+	if goalErr != nil {
+		ShowError(goalErr)
 	}
 	PressEnter()
 }
@@ -309,14 +320,11 @@ func (a *App) ViewPlanStatus() {
 	var fetchErr error
 	var elapsed time.Duration
 
-	err = spinner.New().
-		Title("Loading care plans...").
-		Action(func() {
-			start := time.Now()
-			plans, fetchErr = a.searchCarePlans(context.Background(), patientID)
-			elapsed = time.Since(start)
-		}).
-		Run()
+	err = runSpinner("Loading care plans...", func(ctx context.Context) {
+		start := time.Now()
+		plans, fetchErr = a.searchCarePlans(ctx, patientID, sortRecentlySaved)
+		elapsed = time.Since(start)
+	})
 
 	if err != nil {
 		ShowError(err)
@@ -341,46 +349,41 @@ func (a *App) ViewPlanStatus() {
 
 // ClinicDashboard shows all active plans with progress across all patients.
 func (a *App) ClinicDashboard() {
+	a.clinicDashboard("", nil)
+}
+
+// clinicDashboard shows the dashboard for the patients in scope, or the whole
+// clinic when scope is nil. cohort names the scope in the output.
+func (a *App) clinicDashboard(cohort string, scope cohortScope) {
 	ctx := context.Background()
 	var entries []json.RawMessage
+	var included map[string]string
+	var bloodPressures []json.RawMessage
+	var issues []json.RawMessage
+	var escalations escalationResult
 	var fetchErr error
 	var elapsed time.Duration
-
-	err := spinner.New().
-		Title("Loading clinic dashboard...").
-		Action(func() {
-			start := time.Now()
-			count := gen.SearchCount(100)
-			params := &gen.SearchResourcesParams{
-				UnderscoreCount: &count,
-			}
-			resp, err := a.Client.Inner().SearchResourcesWithResponse(
-				ctx, a.Client.Tenant(), a.Client.Store(),
-				gen.ResourceType("CarePlan"), params,
-				func(ctx context.Context, req *http.Request) error {
-					q := req.URL.Query()
-					q.Set("status", "active")
-					req.URL.RawQuery = q.Encode()
-					return nil
-				},
-			)
-			if err != nil {
-				fetchErr = fmt.Errorf("searching care plans: %w", err)
-				return
-			}
-			if resp.HTTPResponse.StatusCode >= 400 {
-				fetchErr = fmt.Errorf("search failed: HTTP %d", resp.HTTPResponse.StatusCode)
-				return
-			}
-			var bundle gen.Bundle
-			if err := json.Unmarshal(resp.Body, &bundle); err != nil {
-				fetchErr = fmt.Errorf("parsing response: %w", err)
-				return
-			}
-			entries = extractResources(bundle)
-			elapsed = time.Since(start)
-		}).
-		Run()
+	now := time.Now()
+
+	err := runSpinner("Loading clinic dashboard...", func(ctx context.Context) {
+		start := time.Now()
+		entries, fetchErr = a.searchAllPages(ctx, "CarePlan", 100, neturl.Values{
+			fhir.SearchCarePlanStatus: {"active"},
+			fhir.SearchInclude:        {"CarePlan:patient"},
+		}, nil)
+		entries, included = includedPatients(entries)
+		if fetchErr == nil && a.DashboardNarrative {
+			bloodPressures, fetchErr = a.searchAllPages(ctx, "Observation", 200, neturl.Values{fhir.SearchObservationCode: {"85354-9"}}, nil)
+		}
+		if fetchErr == nil {
+			issues, fetchErr = a.openIssues(ctx)
+		}
+		if fetchErr == nil {
+			escalations, fetchErr = a.escalate(ctx, now, scope)
+		}
+		entries, bloodPressures, issues = scope.filter(entries), scope.filter(bloodPressures), scope.filter(issues)
+		elapsed = time.Since(start)
+	})
 
 	if err != nil {
 		ShowError(err)
@@ -393,15 +396,18 @@ func (a *App) ClinicDashboard() {
 		return
 	}
 
-	if len(entries) == 0 {
+	if len(entries) == 0 && len(issues) == 0 && len(escalations.Open) == 0 {
 		fmt.Println("\n  No active health plans found.")
 		PressEnter()
 		return
 	}
 
-	// Resolve patient names and collect dashboard plans
+	// Resolve patient names and collect dashboard plans. Plans' patients
+	// come back with them through _include; anyone else, or a patient the
+	// server did not include, is read one at a time.
 	patientNames := make(map[string]string)
 	var allPlans []fhir.DashboardPlan
+	var openIssues []map[string]any
 
 	for _, raw := range entries {
 		m, err := fhir.Parse(raw)
@@ -411,15 +417,48 @@ func (a *App) ClinicDashboard() {
 		patientID := fhir.PatientRef(m)
 		name, ok := patientNames[patientID]
 		if !ok {
-			name = a.resolvePatientName(ctx, patientID)
+			if name, ok = included[patientID]; !ok {
+				name = a.resolvePatientName(ctx, patientID)
+			}
 			patientNames[patientID] = name
 		}
 		dp := fhir.GetDashboardPlan(m, name)
 		allPlans = append(allPlans, dp)
 	}
+	planPatients := len(patientNames)
+	for _, raw := range issues {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		patientID := resourcePatient(m)
+		if _, ok := patientNames[patientID]; !ok {
+			patientNames[patientID] = a.resolvePatientName(ctx, patientID)
+		}
+		openIssues = append(openIssues, m)
+	}
+	for _, t := range escalations.Open {
+		patientID := fhir.TaskPatient(t)
+		if _, ok := patientNames[patientID]; !ok {
+			patientNames[patientID] = a.resolvePatientName(ctx, patientID)
+		}
+	}
 
 	fmt.Println()
+	if cohort != "" {
+		fmt.Printf("  Cohort: %s\n\n", cohort)
+	}
+	fhir.PrintEscalations(escalations.Open, patientNames, now)
+	fhir.PrintOpenIssues(openIssues, patientNames)
+	if len(entries) == 0 {
+		fmt.Println("  No active health plans found.")
+		PressEnter()
+		return
+	}
+	if a.DashboardNarrative {
+		fhir.PrintNarrative(fhir.ComputeDashboardStats(allPlans, bloodPressures, now).Narrative())
+	}
 	fhir.PrintClinicDashboard(allPlans)
-	showTiming(fmt.Sprintf("Fetched %d active care plans across %d patients", len(entries), len(patientNames)), elapsed)
+	showTiming(fmt.Sprintf("Fetched %d active care plans across %d patients", len(entries), planPatients), elapsed)
 	PressEnter()
 }
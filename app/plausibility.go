@@ -0,0 +1,160 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// VitalRange is the range a vital sign reading is expected to fall in, in the
+// unit it is stored in (mmHg, kg, cm, °C, ...). A reading outside it is more
+// often a typo than a real value, so recording one needs an explicit override.
+type VitalRange struct {
+	Min, Max float64
+}
+
+// defaultVitalRanges are the ranges used for vitals not in App.VitalRanges,
+// by vital key. Blood pressure is checked as "systolic" and "diastolic".
+var defaultVitalRanges = map[string]VitalRange{
+	"systolic":           {60, 260},
+	"diastolic":          {30, 160},
+	"weight":             {0.5, 350},
+	"height":             {30, 250},
+	"heart-rate":         {25, 250},
+	"temperature":        {30, 43},
+	"spo2":               {50, 100},
+	"respiratory-rate":   {4, 70},
+	"bmi":                {10, 80},
+	"head-circumference": {25, 65},
+}
+
+// vitalUnits are the label, stored unit, and UCUM code of each vital with a
+// plausible range, for messages.
+var vitalUnits = map[string]struct{ label, unit, code string }{
+	"systolic":           {"Systolic", "mmHg", "mm[Hg]"},
+	"diastolic":          {"Diastolic", "mmHg", "mm[Hg]"},
+	"weight":             {"Weight", "kg", "kg"},
+	"height":             {"Height", "cm", "cm"},
+	"heart-rate":         {"Heart rate", "bpm", "/min"},
+	"temperature":        {"Temperature", "°C", "Cel"},
+	"spo2":               {"O2 saturation", "%", "%"},
+	"respiratory-rate":   {"Respiratory rate", "/min", "/min"},
+	"bmi":                {"BMI", "kg/m2", "kg/m2"},
+	"head-circumference": {"Head circumference", "cm", "cm"},
+}
+
+// vitalCodes are the LOINC codes of the vitals with a plausible range, for
+// checking stored or parsed observations. Systolic comes first so problems
+// read in the usual order.
+var vitalCodes = []struct{ code, key string }{
+	{"8480-6", "systolic"},
+	{"8462-4", "diastolic"},
+	{"29463-7", "weight"},
+	{"8302-2", "height"},
+	{"8867-4", "heart-rate"},
+	{"8310-5", "temperature"},
+	{"2708-6", "spo2"},
+	{"9279-1", "respiratory-rate"},
+	{"39156-5", "bmi"},
+	{"9843-4", "head-circumference"},
+}
+
+// parseVitalRanges reads PHENOSTORE_VITAL_RANGES: comma-separated
+// name=min-max entries, such as "systolic=70-250,temperature=32-42".
+func parseVitalRanges(s string) (map[string]VitalRange, error) {
+	ranges := make(map[string]VitalRange)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, bounds, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if _, known := defaultVitalRanges[name]; ok && !known {
+			return nil, fmt.Errorf("unknown vital %q in PHENOSTORE_VITAL_RANGES (use one of: %s)", name, strings.Join(vitalRangeNames(), ", "))
+		}
+		lo, hi, dash := strings.Cut(bounds, "-")
+		minValue, err1 := strconv.ParseFloat(strings.TrimSpace(lo), 64)
+		maxValue, err2 := strconv.ParseFloat(strings.TrimSpace(hi), 64)
+		if !ok || !dash || err1 != nil || err2 != nil || minValue >= maxValue {
+			return nil, fmt.Errorf("invalid PHENOSTORE_VITAL_RANGES entry %q (use name=min-max, e.g. systolic=60-260)", entry)
+		}
+		ranges[name] = VitalRange{minValue, maxValue}
+	}
+	return ranges, nil
+}
+
+func vitalRangeNames() []string {
+	names := make([]string, 0, len(defaultVitalRanges))
+	for name := range defaultVitalRanges {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// vitalRange returns the plausible range for a vital key.
+func (a *App) vitalRange(key string) (VitalRange, bool) {
+	if r, ok := a.VitalRanges[key]; ok {
+		return r, true
+	}
+	r, ok := defaultVitalRanges[key]
+	return r, ok
+}
+
+// implausible describes a reading outside its vital's plausible range, or
+// returns "" if it is in range or the vital has none.
+func (a *App) implausible(key string, value float64) string {
+	r, ok := a.vitalRange(key)
+	if !ok || (value >= r.Min && value <= r.Max) {
+		return ""
+	}
+	u := vitalUnits[key]
+	show := func(v float64) string {
+		v, _ = fhir.DisplayQuantity(v, u.unit, u.code)
+		return strconv.FormatFloat(math.Round(v*10)/10, 'f', -1, 64)
+	}
+	_, unit := fhir.DisplayQuantity(value, u.unit, u.code)
+	return fmt.Sprintf("%s %s %s is outside the expected range %s–%s %s",
+		u.label, show(value), unit, show(r.Min), show(r.Max), unit)
+}
+
+// implausibleObservation describes each reading of an observation outside
+// its vital's plausible range. Observations that are not vitals have none.
+func (a *App) implausibleObservation(m map[string]any) []string {
+	readings := fhir.ObservationReadings(m)
+	var problems []string
+	for _, v := range vitalCodes {
+		if value, ok := readings[v.code]; ok {
+			if p := a.implausible(v.key, value); p != "" {
+				problems = append(problems, p)
+			}
+		}
+	}
+	return problems
+}
+
+// confirmPlausible returns true if every reading is in range, that is if
+// all problems from implausible are "". Otherwise it shows the problems and
+// asks whether to record the readings anyway, returning false if they should
+// be entered again.
+func confirmPlausible(problems ...string) (bool, error) {
+	problems = slices.DeleteFunc(problems, func(p string) bool { return p == "" })
+	if len(problems) == 0 {
+		return true, nil
+	}
+	override := false
+	err := huh.NewConfirm().
+		Title("Record this reading anyway?").
+		Description(strings.Join(problems, "\n")).
+		Affirmative("Record anyway").
+		Negative("Re-enter").
+		Value(&override).
+		Run()
+	return override, err
+}
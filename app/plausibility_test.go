@@ -0,0 +1,82 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+func TestParseVitalRanges(t *testing.T) {
+	ranges, err := parseVitalRanges(" systolic=70-250, temperature = 32.5-42 ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]VitalRange{"systolic": {70, 250}, "temperature": {32.5, 42}}
+	if len(ranges) != len(want) {
+		t.Fatalf("ranges = %v, want %v", ranges, want)
+	}
+	for k, r := range want {
+		if ranges[k] != r {
+			t.Errorf("ranges[%s] = %v, want %v", k, ranges[k], r)
+		}
+	}
+
+	for _, bad := range []string{"systolic", "systolic=70", "systolic=250-70", "systolic=a-b", "sistolic=70-250"} {
+		if _, err := parseVitalRanges(bad); err == nil {
+			t.Errorf("parseVitalRanges(%q) succeeded, want an error", bad)
+		}
+	}
+}
+
+func TestImplausible(t *testing.T) {
+	a := &App{VitalRanges: map[string]VitalRange{"heart-rate": {40, 180}}}
+	tests := []struct {
+		key   string
+		value float64
+		want  string
+	}{
+		{"systolic", 120, ""},
+		{"systolic", 260, ""},
+		{"systolic", 300, "Systolic 300 mmHg is outside the expected range 60–260 mmHg"},
+		{"temperature", 29.5, "Temperature 29.5 °C is outside the expected range 30–43 °C"},
+		{"heart-rate", 200, "Heart rate 200 bpm is outside the expected range 40–180 bpm"},
+		{"pain", 10, ""},
+	}
+	for _, tt := range tests {
+		if got := a.implausible(tt.key, tt.value); got != tt.want {
+			t.Errorf("implausible(%s, %g) = %q, want %q", tt.key, tt.value, got, tt.want)
+		}
+	}
+
+	fhir.DisplayUnits = fhir.USUnits
+	defer func() { fhir.DisplayUnits = fhir.MetricUnits }()
+	want := "Temperature 111.2 °F is outside the expected range 86–109.4 °F"
+	if got := a.implausible("temperature", 44); got != want {
+		t.Errorf("implausible(temperature, 44) in US units = %q, want %q", got, want)
+	}
+}
+
+func TestImplausibleObservation(t *testing.T) {
+	a := &App{}
+	drafts := parseDictation("p1", "BP three twenty over ninety one, pulse seventy eight, weight four hundred kilos, glucose nine hundred")
+	var got []string
+	for _, d := range drafts {
+		m, err := fhir.Parse(d.body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, a.implausibleObservation(m)...)
+	}
+	want := []string{
+		"Systolic 320 mmHg is outside the expected range 60–260 mmHg",
+		"Weight 400 kg is outside the expected range 0.5–350 kg",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("problems = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("problem %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
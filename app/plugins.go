@@ -0,0 +1,197 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/huh"
+)
+
+// MenuAction is a custom entry in the Plugins menu. Go code embedding the app
+// can append to App.Actions directly; subprocess plugins are discovered from
+// PHENOSTORE_PLUGIN_DIR.
+type MenuAction struct {
+	Title string
+	Run   func(a *App)
+}
+
+// pluginDescription is what a plugin prints in response to "describe".
+type pluginDescription struct {
+	Name    string `json:"name"`
+	Actions []struct {
+		ID    string `json:"id"`
+		Title string `json:"title"`
+	} `json:"actions"`
+}
+
+// pluginRequest is one line a running plugin writes to stdout.
+type pluginRequest struct {
+	Op           string            `json:"op"`
+	ResourceType string            `json:"resourceType,omitempty"`
+	ID           string            `json:"id,omitempty"`
+	Params       map[string]string `json:"params,omitempty"`
+	Resource     json.RawMessage   `json:"resource,omitempty"`
+	Text         string            `json:"text,omitempty"`
+}
+
+// pluginResponse is the line written back to the plugin's stdin for every
+// client operation.
+type pluginResponse struct {
+	Result any    `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// loadPlugins runs "<plugin> describe" for every executable in dir and
+// registers the actions it reports.
+func (a *App) loadPlugins(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: reading plugin directory: %s\n", err)
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || info.IsDir() || info.Mode()&0o111 == 0 {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		out, err := exec.CommandContext(ctx, path, "describe").Output()
+		cancel()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: plugin %s: describe failed: %s\n", entry.Name(), err)
+			continue
+		}
+		var desc pluginDescription
+		if err := json.Unmarshal(out, &desc); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: plugin %s: invalid describe output: %s\n", entry.Name(), err)
+			continue
+		}
+		for _, act := range desc.Actions {
+			title := act.Title
+			if desc.Name != "" {
+				title = desc.Name + ": " + title
+			}
+			id := act.ID
+			a.Actions = append(a.Actions, MenuAction{
+				Title: title,
+				Run:   func(a *App) { a.runPlugin(path, id) },
+			})
+		}
+	}
+}
+
+// runPlugin starts "<plugin> run <action>" and serves its client requests
+// until it sends {"op":"done"} or exits.
+func (a *App) runPlugin(path, action string) {
+	ctx := context.Background()
+	cmd := exec.CommandContext(ctx, path, "run", action)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if err := cmd.Start(); err != nil {
+		ShowError(fmt.Errorf("starting plugin: %w", err))
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	enc := json.NewEncoder(stdin)
+	for scanner.Scan() {
+		var req pluginRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			// Plain output lines are shown as-is.
+			fmt.Println(scanner.Text())
+			continue
+		}
+		if req.Op == "done" {
+			break
+		}
+		if req.Op == "print" {
+			fmt.Println(req.Text)
+			continue
+		}
+		result, err := a.handlePluginRequest(ctx, req)
+		resp := pluginResponse{Result: result}
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		if err := enc.Encode(resp); err != nil {
+			break
+		}
+	}
+	stdin.Close()
+	io.Copy(io.Discard, stdout)
+
+	if err := cmd.Wait(); err != nil {
+		ShowError(fmt.Errorf("plugin exited: %w", err))
+	}
+	PressEnter()
+}
+
+// handlePluginRequest performs a client operation on behalf of a plugin.
+func (a *App) handlePluginRequest(ctx context.Context, req pluginRequest) (any, error) {
+	switch req.Op {
+	case "search":
+		return a.searchResources(ctx, req.ResourceType, 100, req.Params)
+	case "read":
+		return a.readResource(ctx, req.ResourceType, req.ID)
+	case "create":
+		return a.createResource(ctx, req.ResourceType, req.Resource)
+	case "update":
+		return a.updateResource(ctx, req.ResourceType, req.ID, req.Resource)
+	case "delete":
+		return nil, a.deleteResource(ctx, req.ResourceType, req.ID)
+	}
+	return nil, fmt.Errorf("unknown op %q", req.Op)
+}
+
+func (a *App) pluginMenu() {
+	for {
+		options := make([]huh.Option[int], 0, len(a.Actions)+1)
+		for i, act := range a.Actions {
+			options = append(options, huh.NewOption(act.Title, i))
+		}
+		options = append(options, huh.NewOption("\u2190 Back", -1))
+
+		var choice int
+		err := huh.NewSelect[int]().
+			Title("Plugins").
+			Options(options...).
+			Value(&choice).
+			Run()
+
+		if err != nil {
+			if isAbort(err) {
+				return
+			}
+			ShowError(err)
+			continue
+		}
+
+		if choice < 0 {
+			return
+		}
+		a.Actions[choice].Run(a)
+	}
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// LoadPatientView loads a patient's summary and builds the patient-facing
+// view of it.
+func (a *App) LoadPatientView(ctx context.Context, patientID string) (fhir.PatientView, error) {
+	s, err := a.LoadSummary(ctx, patientID)
+	if err != nil {
+		return fhir.PatientView{}, err
+	}
+	return fhir.BuildPatientView(time.Now(), s.Patient, s.Observations, s.Conditions, s.Plans), nil
+}
+
+// ViewAsPatient shows a patient's chart the way a patient portal would:
+// read-only, in plain language, with what is coming up in their care plans.
+func (a *App) ViewAsPatient() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var view fhir.PatientView
+	var apiErr error
+	var elapsed time.Duration
+
+	err = runSpinner("Loading patient view...", func(ctx context.Context) {
+		start := time.Now()
+		view, apiErr = a.LoadPatientView(ctx, patientID)
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	fhir.PrintPatientView(view)
+	showTiming("Loaded patient view from the same summary as Patient Summary", elapsed)
+	PressEnter()
+}
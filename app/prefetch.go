@@ -0,0 +1,112 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// patientListTTL is how long PickPatient uses a prefetched patient list.
+// Patients created, updated, or deleted through the app drop it at once; the
+// TTL bounds how long patients added by another client can be missing.
+const patientListTTL = time.Minute
+
+// patientPrefetchTimeout bounds a background fetch of the patient list, so
+// one that hangs does not hold up PickPatient for longer.
+const patientPrefetchTimeout = 30 * time.Second
+
+// patientPrefetch is a patient list fetched in the background for
+// PickPatient. Its fields other than done and at are set before done is
+// closed and not changed after.
+type patientPrefetch struct {
+	done     chan struct{} // closed once the fetch has finished
+	at       time.Time     // when the fetch started
+	patients []json.RawMessage
+	more     bool // there are more patients than the list holds
+	err      error
+}
+
+// loadPatientList fetches the patients PickPatient lists: all of them with
+// PatientPickerList, otherwise the first page and whether there are more.
+// It does not audit the search, since a prefetched list may never be shown;
+// callers record the read with auditPatientList once it is.
+func (a *App) loadPatientList(ctx context.Context) ([]json.RawMessage, bool, error) {
+	query := withElements(nil, patientListElements)
+	if a.PatientPicker == PatientPickerList {
+		patients, err := a.searchPages(ctx, "Patient", 100, 0, query, nil)
+		return patients, false, err
+	}
+	patients, next, err := a.searchPage(ctx, "Patient", patientListPage, query, "")
+	return patients, next != "", err
+}
+
+// auditPatientList records a read of the patient list in the audit trail
+// and the access log.
+func (a *App) auditPatientList(ctx context.Context, patients []json.RawMessage, err error) {
+	a.audit(ctx, fhir.AuditRead, "search-type", "Patient", "Patient", "", err)
+	a.logResultAccess("Patient", patients, err)
+}
+
+// prefetchPatients starts fetching the patient list in the background,
+// unless a fetch is already under way or its list is still fresh. The main
+// menu calls it each time it is shown, so the list is ready by the time a
+// screen picks a patient, and is fetched again after changes to patients.
+// The fetch is not audited: nobody has seen the list yet, and PickPatient
+// records the read when it shows it.
+func (a *App) prefetchPatients() {
+	if a.PatientPicker == PatientPickerSearch {
+		return
+	}
+	p, ok := a.session.startPatientPrefetch(time.Now())
+	if !ok {
+		return
+	}
+	go func() {
+		defer close(p.done)
+		ctx, cancel := context.WithTimeout(context.Background(), patientPrefetchTimeout)
+		defer cancel()
+		p.patients, p.more, p.err = a.loadPatientList(ctx)
+	}()
+}
+
+// prefetchedPatients returns the prefetched patient list, waiting for the
+// fetch under a spinner if it has not finished. ok is false if there is no
+// fresh prefetch or it failed, and the list should be fetched directly.
+func (a *App) prefetchedPatients() (patients []json.RawMessage, more, ok bool, err error) {
+	p := a.session.patientPrefetch(time.Now())
+	if p == nil {
+		return nil, false, false, nil
+	}
+	select {
+	case <-p.done:
+	default:
+		err := runSpinner("Loading patients...", func(ctx context.Context) {
+			select {
+			case <-p.done:
+			case <-ctx.Done():
+			}
+		})
+		if err != nil {
+			return nil, false, false, err
+		}
+	}
+	if p.err != nil {
+		return nil, false, false, nil
+	}
+	return p.patients, p.more, true, nil
+}
+
+// touchesPatients reports whether any transaction entry creates, updates,
+// or deletes a Patient.
+func touchesPatients(entries []map[string]any) bool {
+	for _, e := range entries {
+		request, _ := e["request"].(map[string]any)
+		if url := mapStr(request, "url"); url == "Patient" || strings.HasPrefix(url, "Patient/") || strings.HasPrefix(url, "Patient?") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPrefetchPatients(t *testing.T) {
+	var searches atomic.Int64
+	a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			searches.Add(1)
+			fmt.Fprint(w, `{"resourceType":"Bundle","type":"searchset","entry":[{"resource":{"resourceType":"Patient","id":"p1"}}]}`)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write(body)
+	})
+	prefetch := func() {
+		a.prefetchPatients()
+		<-a.session.patientPrefetch(time.Now()).done
+	}
+
+	prefetch()
+	prefetch()
+	patients, more, ok, err := a.prefetchedPatients()
+	if err != nil || !ok || more || len(patients) != 1 {
+		t.Fatalf("prefetchedPatients = %d patients, more %v, ok %v, %v", len(patients), more, ok, err)
+	}
+	if n := searches.Load(); n != 1 {
+		t.Errorf("two prefetches searched %d times, want 1", n)
+	}
+	if entries, _ := a.session.access.forPatient("p1"); len(entries) != 0 {
+		t.Errorf("prefetching logged %d accesses before the list was shown, want 0", len(entries))
+	}
+
+	if _, err := a.createResource(context.Background(), "Patient", json.RawMessage(`{"resourceType":"Patient"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok, _ := a.prefetchedPatients(); ok {
+		t.Error("the prefetched list was kept after a patient was created")
+	}
+	prefetch()
+	if n := searches.Load(); n != 2 {
+		t.Errorf("searched %d times after a patient was created, want 2", n)
+	}
+}
@@ -0,0 +1,245 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// ManageProblemList shows a patient's curated problem list and lets the user
+// add, remove, and reorder the Conditions it references. The List is created
+// on first save.
+func (a *App) ManageProblemList() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	ctx := context.Background()
+	var list map[string]any
+	var conditions []json.RawMessage
+	var apiErr error
+
+	err = runSpinner("Loading problem list...", func(ctx context.Context) {
+		list, apiErr = a.findProblemList(ctx, patientID)
+		if apiErr != nil {
+			return
+		}
+		conditions, apiErr = a.searchByPatient(ctx, "Condition", patientID, "")
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+		PressEnter()
+		return
+	}
+
+	if list == nil {
+		list, _ = fhir.Parse(fhir.NewProblemList(patientID))
+	}
+
+	for {
+		fmt.Println()
+		fhir.PrintProblemList(list)
+		fmt.Println()
+
+		entries, _ := list["entry"].([]any)
+		var choice string
+		err := huh.NewSelect[string]().
+			Title("Problem List").
+			Options(
+				huh.NewOption("Add Condition", "add"),
+				huh.NewOption("Remove Entry", "remove"),
+				huh.NewOption("Move Entry Up", "up"),
+				huh.NewOption("Move Entry Down", "down"),
+				huh.NewOption("\u2190 Back", "back"),
+			).
+			Value(&choice).
+			Run()
+
+		if err != nil {
+			if !isAbort(err) {
+				ShowError(err)
+				PressEnter()
+			}
+			return
+		}
+
+		switch choice {
+		case "add":
+			entry, ok := pickConditionEntry(conditions, entries)
+			if !ok {
+				continue
+			}
+			entries = append(entries, entry)
+		case "remove", "up", "down":
+			idx, ok := pickListEntry(list, "Select entry")
+			if !ok {
+				continue
+			}
+			switch choice {
+			case "remove":
+				entries = append(entries[:idx], entries[idx+1:]...)
+			case "up":
+				if idx == 0 {
+					continue
+				}
+				entries[idx-1], entries[idx] = entries[idx], entries[idx-1]
+			case "down":
+				if idx == len(entries)-1 {
+					continue
+				}
+				entries[idx+1], entries[idx] = entries[idx], entries[idx+1]
+			}
+		case "back":
+			return
+		}
+
+		list["entry"] = entries
+		saved, err := a.saveProblemList(ctx, list)
+		if err != nil {
+			ShowError(err)
+			PressEnter()
+			return
+		}
+		list = saved
+	}
+}
+
+// findProblemList returns the patient's problem List, or nil if none exists.
+func (a *App) findProblemList(ctx context.Context, patientID string) (map[string]any, error) {
+	lists, err := a.searchResources(ctx, "List", 1, map[string]string{
+		fhir.SearchListPatient: patientID,
+		fhir.SearchListCode:    "http://loinc.org|" + fhir.ProblemListCode,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(lists) == 0 {
+		return nil, nil
+	}
+	m, err := fhir.Parse(lists[0])
+	if err != nil {
+		return nil, fmt.Errorf("parsing problem list: %w", err)
+	}
+	return m, nil
+}
+
+// saveProblemList creates the List if it has no ID yet, otherwise updates it.
+func (a *App) saveProblemList(ctx context.Context, list map[string]any) (map[string]any, error) {
+	body, err := json.Marshal(list)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling problem list: %w", err)
+	}
+
+	var saved json.RawMessage
+	var apiErr error
+	err = runSpinner("Saving problem list...", func(ctx context.Context) {
+		if id := mapStr(list, "id"); id != "" {
+			saved, apiErr = a.updateResource(ctx, "List", id, body)
+		} else {
+			saved, apiErr = a.createResource(ctx, "List", body)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if apiErr != nil {
+		return nil, fmt.Errorf("saving problem list: %w", apiErr)
+	}
+
+	m, err := fhir.Parse(saved)
+	if err != nil {
+		return nil, fmt.Errorf("parsing problem list: %w", err)
+	}
+	return m, nil
+}
+
+// pickConditionEntry offers the patient's Conditions that are not already on
+// the list and returns a List entry for the chosen one.
+func pickConditionEntry(conditions []json.RawMessage, entries []any) (map[string]any, bool) {
+	listed := make(map[string]bool)
+	for _, e := range entries {
+		entry, _ := e.(map[string]any)
+		item, _ := entry["item"].(map[string]any)
+		listed[mapStr(item, "reference")] = true
+	}
+
+	var options []huh.Option[string]
+	displays := make(map[string]string)
+	for _, raw := range conditions {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		ref := "Condition/" + mapStr(m, "id")
+		if listed[ref] {
+			continue
+		}
+		displays[ref] = fhir.ConditionDisplay(m)
+		options = append(options, huh.NewOption(displays[ref], ref))
+	}
+
+	if len(options) == 0 {
+		fmt.Println("\n  All of this patient's conditions are already on the list.")
+		PressEnter()
+		return nil, false
+	}
+
+	var ref string
+	err := huh.NewSelect[string]().
+		Title("Select condition to add").
+		Options(options...).
+		Value(&ref).
+		Run()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return nil, false
+	}
+	return fhir.NewListEntry(ref, displays[ref]), true
+}
+
+// pickListEntry lets the user choose an entry of a List by position.
+func pickListEntry(list map[string]any, title string) (int, bool) {
+	displays := fhir.ListEntryDisplays(list)
+	if len(displays) == 0 {
+		fmt.Println("\n  The problem list is empty.")
+		PressEnter()
+		return 0, false
+	}
+
+	var options []huh.Option[int]
+	for i, d := range displays {
+		options = append(options, huh.NewOption(fmt.Sprintf("%d. %s", i+1, d), i))
+	}
+
+	var idx int
+	err := huh.NewSelect[int]().
+		Title(title).
+		Options(options...).
+		Value(&idx).
+		Run()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return 0, false
+	}
+	return idx, true
+}
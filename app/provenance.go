@@ -0,0 +1,279 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+	"github.com/phenoml/phenostore-sdk-go/phenostore/gen"
+)
+
+// All app mutations go through createResource, updateResource,
+// deleteResource, processTransaction, processChanges, and, for chunked
+// imports, createChunk (see chunks.go). When
+// App.ProvenanceAgent is set, each one is submitted as a transaction bundle
+// together with a Provenance resource recording the agent, time, and target.
+// When App.Audit is set, each one is also followed by an AuditEvent. If the
+// store cannot be reached, each one but createChunk is saved to the offline
+// queue instead (see queue.go). Unit codes are checked before anything is sent or queued
+// (see ucum.go).
+
+func (a *App) createResource(ctx context.Context, resourceType string, body json.RawMessage) (json.RawMessage, error) {
+	if err := a.checkResourceUnits(body); err != nil {
+		return nil, err
+	}
+	op := queuedOp{Method: "POST", ResourceType: resourceType, Body: body}
+	if err := a.queueBehindPending(ctx, op); err != nil {
+		return nil, err
+	}
+	created, err := a.createResourceWithProvenance(ctx, resourceType, body)
+	if resourceType == "Patient" {
+		a.session.forgetPatientList()
+	}
+	entity := resourceType
+	if id := fhir.ResourceID(created); id != "" {
+		entity += "/" + id
+	}
+	a.audit(ctx, fhir.AuditCreate, "create", resourceType, entity, bodyPatient(resourceType, fhir.ResourceID(created), body), err)
+	return created, queueIfUnreachable(op, err)
+}
+
+func (a *App) createResourceWithProvenance(ctx context.Context, resourceType string, body json.RawMessage) (json.RawMessage, error) {
+	if a.ProvenanceAgent == "" {
+		return a.Client.CreateResource(ctx, resourceType, body, nil)
+	}
+	urn := "urn:uuid:" + newUUID()
+	entries := []map[string]any{bundleEntryWithUrn(urn, resourceType, body)}
+	result, err := a.transactionWithProvenance(ctx, entries, []string{urn}, "CREATE")
+	if err != nil {
+		return nil, err
+	}
+	return entryResource(result, 0)
+}
+
+func (a *App) updateResource(ctx context.Context, resourceType, id string, body json.RawMessage) (json.RawMessage, error) {
+	if err := a.checkResourceUnits(body); err != nil {
+		return nil, err
+	}
+	op := queuedOp{Method: "PUT", ResourceType: resourceType, ResourceID: id, Body: body}
+	if err := a.queueBehindPending(ctx, op); err != nil {
+		return nil, err
+	}
+	updated, err := a.updateResourceWithProvenance(ctx, resourceType, id, body)
+	if resourceType == "Patient" {
+		a.session.forgetPatientName(id)
+		a.session.forgetPatientList()
+	}
+	a.audit(ctx, fhir.AuditUpdate, "update", resourceType, resourceType+"/"+id, bodyPatient(resourceType, id, body), err)
+	return updated, queueIfUnreachable(op, err)
+}
+
+func (a *App) updateResourceWithProvenance(ctx context.Context, resourceType, id string, body json.RawMessage) (json.RawMessage, error) {
+	if a.ProvenanceAgent == "" {
+		return a.Client.UpdateResource(ctx, resourceType, id, body, nil)
+	}
+	ref := resourceType + "/" + id
+	entries := []map[string]any{{
+		"resource": body,
+		"request":  map[string]any{"method": "PUT", "url": ref},
+	}}
+	result, err := a.transactionWithProvenance(ctx, entries, []string{ref}, "UPDATE")
+	if err != nil {
+		return nil, err
+	}
+	return entryResource(result, 0)
+}
+
+func (a *App) deleteResource(ctx context.Context, resourceType, id string) error {
+	op := queuedOp{Method: "DELETE", ResourceType: resourceType, ResourceID: id}
+	if err := a.queueBehindPending(ctx, op); err != nil {
+		return err
+	}
+	err := a.deleteResourceWithProvenance(ctx, resourceType, id)
+	if resourceType == "Patient" {
+		a.session.forgetPatientName(id)
+		a.session.forgetPatientList()
+	}
+	a.audit(ctx, fhir.AuditDelete, "delete", resourceType, resourceType+"/"+id, bodyPatient(resourceType, id, nil), err)
+	return queueIfUnreachable(op, err)
+}
+
+func (a *App) deleteResourceWithProvenance(ctx context.Context, resourceType, id string) error {
+	if a.ProvenanceAgent == "" {
+		return a.Client.DeleteResource(ctx, resourceType, id)
+	}
+	ref := resourceType + "/" + id
+	entries := []map[string]any{{
+		"request": map[string]any{"method": "DELETE", "url": ref},
+	}}
+	_, err := a.transactionWithProvenance(ctx, entries, []string{ref}, "DELETE")
+	return err
+}
+
+// processTransaction submits entries as a transaction bundle and returns how
+// many of them were created (HTTP 201). References are checked first, so a
+// bundle with broken links is reported without being sent. Entries without a
+// fullUrl are given one so the Provenance can reference them.
+func (a *App) processTransaction(ctx context.Context, entries []map[string]any) (created int, err error) {
+	if err := a.checkUnits(entries); err != nil {
+		return 0, err
+	}
+	var targets []string
+	if a.ProvenanceAgent != "" {
+		targets = make([]string, 0, len(entries))
+		for _, e := range entries {
+			urn, _ := e["fullUrl"].(string)
+			if urn == "" {
+				urn = "urn:uuid:" + newUUID()
+				e["fullUrl"] = urn
+			}
+			targets = append(targets, urn)
+		}
+	}
+	op := queuedOp{Method: "BUNDLE", Entries: entries, Targets: targets, Activity: "CREATE"}
+	if err := a.queueBehindPending(ctx, op); err != nil {
+		return 0, err
+	}
+	if err := a.checkReferences(ctx, entries); err != nil {
+		return 0, queueIfUnreachable(op, err)
+	}
+	defer func() { a.audit(ctx, fhir.AuditExecute, "transaction", "Bundle", "Bundle", "", err) }()
+	result, err := a.transaction(ctx, entries, targets, "CREATE")
+	if err != nil {
+		return 0, queueIfUnreachable(op, err)
+	}
+
+	if result.Entry != nil {
+		// Response entries are in request order; the Provenance, if any, is last.
+		for i, entry := range *result.Entry {
+			if i >= len(entries) {
+				break
+			}
+			if entry.Response != nil && entry.Response.Status != nil && strings.HasPrefix(*entry.Response.Status, "201") {
+				created++
+			}
+		}
+	}
+	return created, nil
+}
+
+// changeBatchSize is how many entries bulk tools put in each processChanges
+// transaction.
+const changeBatchSize = 50
+
+// processChanges submits PUT or DELETE entries as a transaction bundle and
+// returns how many succeeded. targets are the "Type/id" references of the
+// entries and activity the v3 DataOperation code (UPDATE or DELETE), used for
+// the Provenance when one is recorded.
+func (a *App) processChanges(ctx context.Context, entries []map[string]any, targets []string, activity string) (changed int, err error) {
+	if err := a.checkUnits(entries); err != nil {
+		return 0, err
+	}
+	op := queuedOp{Method: "BUNDLE", Entries: entries, Targets: targets, Activity: activity}
+	if err := a.queueBehindPending(ctx, op); err != nil {
+		return 0, err
+	}
+	defer func() { a.audit(ctx, fhir.AuditExecute, "transaction", "Bundle", "Bundle", "", err) }()
+	result, err := a.transaction(ctx, entries, targets, activity)
+	for _, ref := range targets {
+		if id, ok := strings.CutPrefix(ref, "Patient/"); ok {
+			a.session.forgetPatientName(id)
+		}
+	}
+	if err != nil {
+		return 0, queueIfUnreachable(op, err)
+	}
+
+	if result.Entry != nil {
+		for i, entry := range *result.Entry {
+			if i >= len(entries) {
+				break
+			}
+			if entry.Response != nil && entry.Response.Status != nil && strings.HasPrefix(*entry.Response.Status, "20") {
+				changed++
+			}
+		}
+	}
+	return changed, nil
+}
+
+// processInBatches runs processChanges over entries in transactions of
+// changeBatchSize, printing a progress line per batch. It stops at the first
+// failed batch, or at the next batch boundary after Ctrl+C: the batch in
+// flight is left to finish, so it is applied in full or not at all and the
+// count stays right. It returns how many entries succeeded and how many were
+// in the batches that went through.
+func (a *App) processInBatches(ctx context.Context, entries []map[string]any, targets []string, activity, verb string) (changed, done int, err error) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	batches := (len(entries) + changeBatchSize - 1) / changeBatchSize
+	if batches > 1 {
+		fmt.Println(timingStyle.Render("  Press Ctrl+C to stop after the current batch."))
+	}
+	for b := 0; b < batches; b++ {
+		if ctx.Err() != nil {
+			return changed, done, fmt.Errorf("%w after batch %d/%d, with %d of %d %s", errCancelled, b, batches, changed, len(entries), verb)
+		}
+		lo, hi := b*changeBatchSize, min((b+1)*changeBatchSize, len(entries))
+		n, err := a.processChanges(context.WithoutCancel(ctx), entries[lo:hi], targets[lo:hi], activity)
+		if err != nil {
+			return changed, done, fmt.Errorf("batch %d/%d: %w", b+1, batches, err)
+		}
+		changed += n
+		done = hi
+		fmt.Printf("  Batch %d/%d: %d %s (%d/%d)\n", b+1, batches, n, verb, changed, len(entries))
+	}
+	return changed, done, nil
+}
+
+// transaction submits entries as a transaction bundle, with a Provenance for
+// targets when App.ProvenanceAgent is set.
+func (a *App) transaction(ctx context.Context, entries []map[string]any, targets []string, activity string) (*gen.Bundle, error) {
+	if touchesPatients(entries) {
+		defer a.session.forgetPatientList()
+	}
+	if a.ProvenanceAgent == "" {
+		return a.Client.ProcessBundle(ctx, fhir.TransactionBundle(entries))
+	}
+	return a.transactionWithProvenance(ctx, entries, targets, activity)
+}
+
+// transactionWithProvenance appends a Provenance for targets to entries and
+// submits them as one transaction.
+func (a *App) transactionWithProvenance(ctx context.Context, entries []map[string]any, targets []string, activity string) (*gen.Bundle, error) {
+	prov := fhir.NewProvenance(targets, activity, a.ProvenanceAgent)
+	all := append(entries[:len(entries):len(entries)], fhir.BundleEntry("Provenance", prov))
+	result, err := a.Client.ProcessBundle(ctx, fhir.TransactionBundle(all))
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// entryResource returns the resource from the i-th transaction response entry.
+func entryResource(result *gen.Bundle, i int) (json.RawMessage, error) {
+	if result.Entry == nil || len(*result.Entry) <= i {
+		return nil, fmt.Errorf("transaction response has no entry %d", i)
+	}
+	entry := (*result.Entry)[i]
+	if entry.Resource != nil {
+		return *entry.Resource, nil
+	}
+	// Servers may omit the resource (Prefer: return=minimal); fall back to
+	// the ID from the location, e.g. ".../Patient/123/_history/1".
+	if entry.Response != nil && entry.Response.Location != nil {
+		loc, _, _ := strings.Cut(*entry.Response.Location, "/_history")
+		parts := strings.Split(loc, "/")
+		if len(parts) >= 2 {
+			return json.Marshal(map[string]string{
+				"resourceType": parts[len(parts)-2],
+				"id":           parts[len(parts)-1],
+			})
+		}
+	}
+	return nil, fmt.Errorf("transaction response entry %d has no resource", i)
+}
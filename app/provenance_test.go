@@ -0,0 +1,44 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+func TestProcessInBatchesCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var transactions int
+	a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+		transactions++
+		var bundle struct{ Entry []map[string]any }
+		_ = json.NewDecoder(r.Body).Decode(&bundle)
+		// Ctrl+C while the first batch is in flight.
+		cancel()
+		var response []map[string]any
+		for range bundle.Entry {
+			response = append(response, map[string]any{"response": map[string]any{"status": "200 OK"}})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"resourceType": "Bundle", "type": "transaction-response", "entry": response})
+	})
+
+	n := changeBatchSize + 10
+	entries := make([]map[string]any, n)
+	targets := make([]string, n)
+	for i := range entries {
+		entries[i] = fhir.DeleteEntry("Observation", "o1")
+		targets[i] = "Observation/o1"
+	}
+	changed, done, err := a.processInBatches(ctx, entries, targets, "DELETE", "deleted")
+	if !errors.Is(err, errCancelled) {
+		t.Fatalf("err = %v, want a cancellation", err)
+	}
+	if transactions != 1 || changed != changeBatchSize || done != changeBatchSize {
+		t.Errorf("sent %d transactions, %d changed, %d done; want the first batch to finish and no more", transactions, changed, done)
+	}
+}
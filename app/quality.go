@@ -0,0 +1,237 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// qualityResourceTypes are the clinical types the data quality audit scans
+// alongside Patient.
+var qualityResourceTypes = []string{"Observation", "Condition", "CarePlan", "Flag"}
+
+// DataQualityAudit scans the store for data quality issues, lists the
+// offenders, and offers a one-keystroke fix for each issue that has one.
+func (a *App) DataQualityAudit() {
+	var issues []fhir.QualityIssue
+	var scanned int
+	var fetchErr error
+	var elapsed time.Duration
+
+	err := runSpinner("Scanning resources...", func(ctx context.Context) {
+		start := time.Now()
+		patients, err := a.searchAllPages(ctx, "Patient", 100, nil, nil)
+		if err != nil {
+			fetchErr = err
+			return
+		}
+		var resources []json.RawMessage
+		for _, rt := range qualityResourceTypes {
+			page, err := a.searchAllPages(ctx, rt, 100, nil, nil)
+			if err != nil {
+				fetchErr = err
+				return
+			}
+			resources = append(resources, page...)
+		}
+		scanned = len(patients) + len(resources)
+		issues = fhir.FindQualityIssues(patients, resources)
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	if len(issues) == 0 {
+		fmt.Printf("  No issues found in %d resources.\n", scanned)
+		showTiming(fmt.Sprintf("Scanned %d resources", scanned), elapsed)
+		PressEnter()
+		return
+	}
+	fhir.PrintQualityIssues(issues)
+	showTiming(fmt.Sprintf("Scanned %d resources", scanned), elapsed)
+
+	fixed := 0
+	for {
+		var options []huh.Option[int]
+		for i, q := range issues {
+			if q.Fix != fhir.FixNone {
+				options = append(options, huh.NewOption(fmt.Sprintf("%s: %s", q.Ref(), q.FixLabel()), i))
+			}
+		}
+		if len(options) == 0 {
+			break
+		}
+		automatic := 0
+		for _, q := range issues {
+			if q.Automatic() {
+				automatic++
+			}
+		}
+		if automatic > 0 {
+			options = append(options, huh.NewOption(fmt.Sprintf("Fix all %d that need no input", automatic), -1))
+		}
+		options = append(options, huh.NewOption("Done", -2))
+
+		var choice int
+		err := huh.NewSelect[int]().
+			Title("Fix an issue").
+			Options(options...).
+			Value(&choice).
+			Run()
+		if err != nil || choice == -2 {
+			if err != nil && !isAbort(err) {
+				ShowError(err)
+			}
+			break
+		}
+
+		var picked []int
+		if choice == -1 {
+			var confirm bool
+			err := huh.NewConfirm().
+				Title(fmt.Sprintf("Apply %d fixes? Resources with a missing patient are deleted.", automatic)).
+				Value(&confirm).
+				Run()
+			if err != nil || !confirm {
+				continue
+			}
+			for i, q := range issues {
+				if q.Automatic() {
+					picked = append(picked, i)
+				}
+			}
+		} else {
+			picked = []int{choice}
+		}
+
+		// An undated observation can only be dated by someone who knows
+		// when it was measured.
+		var measured time.Time
+		if choice >= 0 && issues[choice].Fix == fhir.FixSetDate {
+			measured, err = askObservationDate(issues[choice].Ref())
+			if err != nil || measured.IsZero() {
+				if err != nil && !isAbort(err) {
+					ShowError(err)
+				}
+				continue
+			}
+		}
+
+		var fixErr error
+		done := make(map[int]bool)
+		err = runSpinner("Fixing...", func(ctx context.Context) {
+			for _, i := range picked {
+				if fixErr = a.fixQualityIssue(ctx, issues[i], measured); fixErr != nil {
+					fixErr = fmt.Errorf("%s: %w", issues[i].Ref(), fixErr)
+					return
+				}
+				done[i] = true
+			}
+		})
+		if err != nil {
+			ShowError(err)
+			break
+		}
+
+		var remaining []fhir.QualityIssue
+		for i, q := range issues {
+			if !done[i] {
+				remaining = append(remaining, q)
+			}
+		}
+		issues = remaining
+		fixed += len(done)
+		if fixErr != nil {
+			ShowError(fixErr)
+		}
+	}
+
+	if fixed > 0 {
+		fmt.Printf("\n  Fixed %d issues\n", fixed)
+	}
+	PressEnter()
+}
+
+// askObservationDate asks when an undated observation was measured. It
+// returns the zero time when left blank.
+func askObservationDate(ref string) (time.Time, error) {
+	var s string
+	err := huh.NewInput().
+		Title(fmt.Sprintf("When was %s measured? (YYYY-MM-DD HH:MM, blank to skip)", ref)).
+		Value(&s).
+		Validate(func(s string) error {
+			if strings.TrimSpace(s) == "" {
+				return nil
+			}
+			t, err := time.ParseInLocation(measuredAtLayout, strings.TrimSpace(s), time.Local)
+			if err != nil {
+				return fmt.Errorf("use YYYY-MM-DD HH:MM")
+			}
+			if t.After(time.Now()) {
+				return fmt.Errorf("cannot be in the future")
+			}
+			return nil
+		}).
+		Run()
+	if err != nil || strings.TrimSpace(s) == "" {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation(measuredAtLayout, strings.TrimSpace(s), time.Local)
+}
+
+// fixQualityIssue applies an issue's fix. measured is the date entered for
+// an undated observation and is ignored by other fixes.
+func (a *App) fixQualityIssue(ctx context.Context, q fhir.QualityIssue, measured time.Time) error {
+	if q.Fix == fhir.FixDelete {
+		return a.deleteResource(ctx, q.ResourceType, q.ID)
+	}
+
+	raw, err := a.readResource(ctx, q.ResourceType, q.ID)
+	if err != nil {
+		return fmt.Errorf("reading: %w", err)
+	}
+	m, err := fhir.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("parsing: %w", err)
+	}
+
+	switch q.Fix {
+	case fhir.FixSetDate:
+		if measured.IsZero() {
+			return fmt.Errorf("no measurement date entered")
+		}
+		m["effectiveDateTime"] = measured.Format(time.RFC3339)
+	case fhir.FixAddIdentifier:
+		ids, _ := m["identifier"].([]any)
+		m["identifier"] = append(ids, fhir.NewMRNIdentifier(q.ID))
+	default:
+		return fmt.Errorf("no fix available")
+	}
+
+	body, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("marshaling: %w", err)
+	}
+	if _, err := a.updateResource(ctx, q.ResourceType, q.ID, body); err != nil {
+		return fmt.Errorf("updating: %w", err)
+	}
+	if q.ResourceType == "Patient" {
+		a.emit(ctx, EventPatientUpdated, "Patient", q.ID, q.ID)
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// searchParam is one FHIR search parameter. Queries keep them in order so the
+// generated query reads the same way it is sent.
+type searchParam struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// structuredQuery is a natural-language question translated into a FHIR search.
+type structuredQuery struct {
+	ResourceType string        `json:"resourceType"`
+	Params       []searchParam `json:"params"`
+}
+
+// String renders the query as it would appear in a URL, unescaped for reading.
+func (q structuredQuery) String() string {
+	parts := make([]string, len(q.Params))
+	for i, p := range q.Params {
+		parts[i] = p.Name + "=" + p.Value
+	}
+	if len(parts) == 0 {
+		return q.ResourceType
+	}
+	return q.ResourceType + "?" + strings.Join(parts, "&")
+}
+
+func (q structuredQuery) values() neturl.Values {
+	v := make(neturl.Values)
+	for _, p := range q.Params {
+		v.Add(p.Name, p.Value)
+	}
+	return v
+}
+
+// conditionTerms maps words in a question to ICD-10 categories. A term
+// matches every code in the bundled ICD-10 list that starts with one of its
+// prefixes, so conditions recorded with a more specific code (E66.01 rather
+// than E66.9, J45.990 rather than J45.909) are found too.
+var conditionTerms = []struct {
+	pattern  *regexp.Regexp
+	prefixes []string
+}{
+	{regexp.MustCompile(`\bdiabet(ic|ics|es)\b`), []string{"E11"}},
+	{regexp.MustCompile(`\bhypertensi(ve|on)\b`), []string{"I10"}},
+	{regexp.MustCompile(`\bobes(e|ity)\b`), []string{"E66.0", "E66.9"}},
+	{regexp.MustCompile(`\banxi(ety|ous)\b`), []string{"F41"}},
+	{regexp.MustCompile(`\b(hyperlipidemia|high cholesterol)\b`), []string{"E78"}},
+	{regexp.MustCompile(`\b(ckd|chronic kidney disease|kidney disease)\b`), []string{"N18"}},
+	{regexp.MustCompile(`\b(asthma|asthmatic|bronchospasm)\b`), []string{"J45"}},
+	{regexp.MustCompile(`\b(allergies|allergic rhinitis)\b`), []string{"J30"}},
+}
+
+// conditionCodes lists the bundled ICD-10 codes starting with any of
+// prefixes, comma-separated for a token search.
+func conditionCodes(prefixes []string) string {
+	var codes []string
+	for _, c := range fhir.ICD10Codes() {
+		for _, p := range prefixes {
+			if strings.HasPrefix(c.Code, p) {
+				codes = append(codes, c.Code)
+				break
+			}
+		}
+	}
+	return strings.Join(codes, ",")
+}
+
+// measurementCodes maps measurement names to LOINC codes.
+var measurementCodes = map[string]string{
+	"hba1c":            "4548-4",
+	"a1c":              "4548-4",
+	"glucose":          "2345-7",
+	"blood glucose":    "2345-7",
+	"cholesterol":      "2093-3",
+	"creatinine":       "2160-0",
+	"egfr":             "33914-3",
+	"bmi":              "39156-5",
+	"weight":           "29463-7",
+	"heart rate":       "8867-4",
+	"pulse":            "8867-4",
+	"temperature":      "8310-5",
+	"oxygen":           "2708-6",
+	"o2 saturation":    "2708-6",
+	"spo2":             "2708-6",
+	"respiratory rate": "9279-1",
+}
+
+var comparators = map[string]string{
+	"over": "gt", "above": "gt", "greater than": "gt", "more than": "gt", ">": "gt",
+	"under": "lt", "below": "lt", "less than": "lt", "<": "lt",
+	"at least": "ge", ">=": "ge",
+	"at most": "le", "<=": "le",
+	"of": "eq", "equal to": "eq", "=": "eq",
+}
+
+var (
+	measurementPattern = regexp.MustCompile(`\b(hba1c|a1c|blood glucose|glucose|cholesterol|creatinine|egfr|bmi|weight|heart rate|pulse|temperature|o2 saturation|spo2|oxygen|respiratory rate)(?:\s+(?:readings?|results?|levels?|values?))?\s+(?:is\s+|was\s+)?(greater than|more than|less than|at least|at most|equal to|over|above|under|below|of|>=|<=|>|<|=)\s*(\d+(?:\.\d+)?)`)
+	olderPattern       = regexp.MustCompile(`\b(?:older than|over the age of|aged? over)\s+(\d+)`)
+	youngerPattern     = regexp.MustCompile(`\b(?:younger than|under the age of|aged? under)\s+(\d+)`)
+	namedPattern       = regexp.MustCompile(`\bnamed\s+([a-z][a-z'-]*)`)
+	femalePattern      = regexp.MustCompile(`\b(female|females|women|woman)\b`)
+	malePattern        = regexp.MustCompile(`\b(male|males|men|man)\b`)
+	observationPattern = regexp.MustCompile(`\b(observations?|results?|readings?|labs?|values?)\b`)
+)
+
+// parseQuery translates a question like "show diabetic patients with HbA1c
+// over 8" into a FHIR search with a small rule-based grammar. Patient
+// questions use _has to filter on conditions and observations; questions about
+// results search Observation and chain patient criteria through "patient.".
+func parseQuery(text string, now time.Time) (structuredQuery, error) {
+	s := strings.ToLower(strings.TrimSpace(text))
+
+	var patientParams []searchParam
+	for _, t := range conditionTerms {
+		if t.pattern.MatchString(s) {
+			patientParams = append(patientParams, searchParam{"_has:Condition:patient:code", conditionCodes(t.prefixes)})
+		}
+	}
+	if femalePattern.MatchString(s) {
+		patientParams = append(patientParams, searchParam{"gender", "female"})
+	} else if malePattern.MatchString(s) {
+		patientParams = append(patientParams, searchParam{"gender", "male"})
+	}
+	if m := olderPattern.FindStringSubmatch(s); m != nil {
+		years, _ := strconv.Atoi(m[1])
+		patientParams = append(patientParams, searchParam{"birthdate", "lt" + now.AddDate(-years, 0, 0).Format("2006-01-02")})
+	}
+	if m := youngerPattern.FindStringSubmatch(s); m != nil {
+		years, _ := strconv.Atoi(m[1])
+		patientParams = append(patientParams, searchParam{"birthdate", "gt" + now.AddDate(-years, 0, 0).Format("2006-01-02")})
+	}
+	if m := namedPattern.FindStringSubmatch(s); m != nil {
+		patientParams = append(patientParams, searchParam{"name", m[1]})
+	}
+
+	var measurements []string
+	for _, m := range measurementPattern.FindAllStringSubmatch(s, -1) {
+		measurements = append(measurements, measurementCodes[m[1]]+"$"+comparators[m[2]]+m[3])
+	}
+
+	if observationPattern.MatchString(s) && len(measurements) > 0 {
+		q := structuredQuery{ResourceType: "Observation"}
+		for _, m := range measurements {
+			q.Params = append(q.Params, searchParam{"code-value-quantity", m})
+		}
+		for _, p := range patientParams {
+			q.Params = append(q.Params, searchParam{"patient." + p.Name, p.Value})
+		}
+		return q, nil
+	}
+
+	q := structuredQuery{ResourceType: "Patient", Params: patientParams}
+	for _, m := range measurements {
+		q.Params = append(q.Params, searchParam{"_has:Observation:patient:code-value-quantity", m})
+	}
+	if len(q.Params) == 0 {
+		return q, fmt.Errorf("could not find any criteria in %q; try a condition (diabetic, hypertensive), a measurement (HbA1c over 8), gender, or age (older than 60)", text)
+	}
+	return q, nil
+}
+
+// translateWithLLM asks the endpoint in PHENOSTORE_NLQ_URL to translate a
+// question. The endpoint receives {"query": "..."} and must answer with a
+// structuredQuery: {"resourceType": "Patient", "params": [{"name": ..., "value": ...}]}.
+func translateWithLLM(ctx context.Context, url, text string) (structuredQuery, error) {
+	var q structuredQuery
+	body, _ := json.Marshal(map[string]string{"query": text})
+
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return q, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return q, fmt.Errorf("query translator: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return q, fmt.Errorf("query translator: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&q); err != nil {
+		return q, fmt.Errorf("query translator: invalid response: %w", err)
+	}
+	if q.ResourceType == "" {
+		return q, fmt.Errorf("query translator: response has no resourceType")
+	}
+	return q, nil
+}
+
+// AskQuestion translates a natural-language question into a FHIR search,
+// shows the generated query, and runs it once the user confirms.
+func (a *App) AskQuestion() {
+	var text string
+	err := huh.NewInput().
+		Title("Ask a question").
+		Placeholder("show diabetic patients with HbA1c over 8").
+		Value(&text).
+		Run()
+	if err != nil || strings.TrimSpace(text) == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	translator := "rules"
+	q, err := parseQuery(text, time.Now())
+	if url := os.Getenv("PHENOSTORE_NLQ_URL"); url != "" {
+		var llmErr error
+		spinErr := runSpinner("Translating question...", func(ctx context.Context) {
+			var llmQuery structuredQuery
+			llmQuery, llmErr = translateWithLLM(ctx, url, text)
+			if llmErr == nil {
+				q, err, translator = llmQuery, nil, "LLM"
+			}
+		})
+		if spinErr != nil {
+			ShowError(spinErr)
+			PressEnter()
+			return
+		}
+		if llmErr != nil {
+			ShowError(fmt.Errorf("%w (falling back to rules)", llmErr))
+		}
+	}
+	if err == nil {
+		err = fhir.CheckSearchParams(q.ResourceType, q.values())
+	}
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+
+	fmt.Printf("\n  Generated query (%s):\n", translator)
+	fmt.Printf("    GET %s\n\n", q)
+
+	run := true
+	err = huh.NewConfirm().
+		Title("Run this query?").
+		Value(&run).
+		Run()
+	if err != nil || !run {
+		return
+	}
+
+	var results []json.RawMessage
+	var fetchErr error
+	var elapsed time.Duration
+
+	err = runSpinner("Searching...", func(ctx context.Context) {
+		start := time.Now()
+		query := q.values()
+		if q.ResourceType == "Observation" && query.Get(fhir.SearchSort) == "" {
+			query.Set(fhir.SearchSort, sortNewest)
+		}
+		results, fetchErr = a.searchValues(ctx, q.ResourceType, 100, query)
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	switch {
+	case len(results) == 0:
+		fmt.Println("  No matches.")
+	case q.ResourceType == "Patient":
+		fhir.PrintPatientList(results)
+	case q.ResourceType == "Observation":
+		fhir.PrintObservationList(results)
+	default:
+		for _, raw := range results {
+			fmt.Printf("  %s/%s\n", q.ResourceType, fhir.ResourceID(raw))
+		}
+	}
+	if len(results) > 0 {
+		showTiming(fmt.Sprintf("Found %d %s resources", len(results), q.ResourceType), elapsed)
+	}
+	PressEnter()
+}
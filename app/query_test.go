@@ -0,0 +1,49 @@
+package app
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseQueryConditions(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		question string
+		want     []string // codes the condition search must include
+	}{
+		{"diabetic patients", []string{"E11.9", "E11.65"}},
+		{"obese women", []string{"E66.01", "E66.9"}},
+		{"asthmatic patients", []string{"J45.909", "J45.990"}},
+		{"patients with kidney disease", []string{"N18.3", "N18.30"}},
+		{"hypertensive patients", []string{"I10"}},
+	}
+	for _, tt := range tests {
+		q, err := parseQuery(tt.question, now)
+		if err != nil {
+			t.Fatalf("parseQuery(%q): %v", tt.question, err)
+		}
+		codes := strings.Split(q.values().Get("_has:Condition:patient:code"), ",")
+		for _, code := range tt.want {
+			if !slices.Contains(codes, code) {
+				t.Errorf("parseQuery(%q) searches %v, missing %s", tt.question, codes, code)
+			}
+		}
+	}
+}
+
+// Every code Suggest Diagnosis offers in a category a question can name is
+// found by that question.
+func TestConditionTermsCoverSuggestions(t *testing.T) {
+	for _, term := range conditionTerms {
+		codes := strings.Split(conditionCodes(term.prefixes), ",")
+		for _, entry := range icd10Index {
+			for _, p := range term.prefixes {
+				if strings.HasPrefix(entry.code, p) && !slices.Contains(codes, entry.code) {
+					t.Errorf("%s (%s) is not in the search for %s", entry.code, entry.display, term.pattern)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,501 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	neturl "net/url"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+	"github.com/phenoml/phenostore-sdk-go/phenostore"
+)
+
+// Mutations that fail because the store is unreachable are saved to a local
+// queue file instead of being lost, and replayed in order once it answers
+// again. While anything is waiting, new mutations join the back of the queue
+// so they cannot overtake it. An update is replayed only if the resource is
+// still at the version it was read at; otherwise, or if the server rejects
+// the replay, the operation is kept as a conflict for the user to review.
+
+// queuedOp is one mutation waiting in the offline queue.
+type queuedOp struct {
+	// Method is POST, PUT, DELETE, or BUNDLE (a transaction).
+	Method       string          `json:"method"`
+	ResourceType string          `json:"resourceType,omitempty"`
+	ResourceID   string          `json:"resourceId,omitempty"`
+	Body         json.RawMessage `json:"body,omitempty"`
+	// Version is the meta.versionId a PUT was based on.
+	Version  string           `json:"version,omitempty"`
+	Entries  []map[string]any `json:"entries,omitempty"`
+	Targets  []string         `json:"targets,omitempty"`
+	Activity string           `json:"activity,omitempty"`
+	QueuedAt time.Time        `json:"queuedAt"`
+	// Conflict, when set, says why replaying the operation failed. Conflicts
+	// stay in the queue but no longer hold up the operations behind them.
+	Conflict string `json:"conflict,omitempty"`
+}
+
+// Target describes what the operation changes, e.g. "PUT CarePlan/123".
+func (op queuedOp) Target() string {
+	switch op.Method {
+	case "POST":
+		return "POST " + op.ResourceType
+	case "BUNDLE":
+		return fmt.Sprintf("transaction of %d entries", len(op.Entries))
+	}
+	return op.Method + " " + op.ResourceType + "/" + op.ResourceID
+}
+
+// QueuedError is returned in place of a mutation's result when the store was
+// unreachable and the mutation was queued for replay.
+type QueuedError struct {
+	Pending int
+	Cause   error
+}
+
+func (e *QueuedError) Error() string {
+	return fmt.Sprintf("store unreachable, change queued in %s (%d pending): %s", queuePath(), e.Pending, e.Cause)
+}
+
+func (e *QueuedError) Unwrap() error { return e.Cause }
+
+// queuePath is the offline queue file, from PHENOSTORE_QUEUE_FILE.
+func queuePath() string {
+	if path := os.Getenv("PHENOSTORE_QUEUE_FILE"); path != "" {
+		return path
+	}
+	return "pending-operations.json"
+}
+
+func loadQueue() ([]queuedOp, error) {
+	data, err := os.ReadFile(queuePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ops []queuedOp
+	if err := json.Unmarshal(data, &ops); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", queuePath(), err)
+	}
+	return ops, nil
+}
+
+func saveQueue(ops []queuedOp) error {
+	if len(ops) == 0 {
+		err := os.Remove(queuePath())
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	data, err := json.MarshalIndent(ops, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(queuePath(), data)
+}
+
+// queueCounts returns how many queued operations are waiting to be replayed
+// and how many are conflicts.
+func queueCounts(ops []queuedOp) (pending, conflicts int) {
+	for _, op := range ops {
+		if op.Conflict != "" {
+			conflicts++
+		} else {
+			pending++
+		}
+	}
+	return pending, conflicts
+}
+
+// isUnreachable reports whether err means the request never got an answer
+// from the store: a network failure or a gateway error in front of it. A
+// request cancelled with Ctrl+C is not; the user gave up on it.
+func isUnreachable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var ooe *phenostore.OperationOutcomeError
+	if errors.As(err, &ooe) {
+		return ooe.StatusCode == 502 || ooe.StatusCode == 503 || ooe.StatusCode == 504
+	}
+	var netErr net.Error
+	var urlErr *neturl.Error
+	return errors.As(err, &netErr) || errors.As(err, &urlErr)
+}
+
+// neverArrived reports whether err means a request cannot have reached the
+// store: the connection was refused, the host name did not resolve, or the
+// store said it is unavailable. After a timeout, a dropped connection, or a
+// gateway error, the store may have applied the change without answering.
+func neverArrived(err error) bool {
+	var ooe *phenostore.OperationOutcomeError
+	if errors.As(err, &ooe) {
+		return ooe.StatusCode == 503
+	}
+	var dnsErr *net.DNSError
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.As(err, &dnsErr)
+}
+
+// replaySafe reports whether op can be queued, or left waiting, after err
+// without risk of applying it twice. Updates and deletes can: a replayed
+// update checks the version it was read at, and deleting twice does no
+// harm. Creates and transactions only can if the request never arrived, as
+// retryable decides for retries; replaying one the store did apply would
+// create duplicates.
+func replaySafe(op queuedOp, err error) bool {
+	if !isUnreachable(err) {
+		return false
+	}
+	if op.creates() {
+		return neverArrived(err)
+	}
+	return true
+}
+
+// creates reports whether op creates resources, so that applying it twice
+// would create duplicates.
+func (op queuedOp) creates() bool {
+	return op.Method == "POST" || op.Method == "BUNDLE"
+}
+
+// queueMu serializes changes to the queue file, so concurrent mutations do
+// not drop each other's entries and two replays do not send the same one.
+var queueMu sync.Mutex
+
+// enqueue appends op to the queue and returns the QueuedError to hand back
+// to the caller.
+func enqueue(op queuedOp, cause error) error {
+	queueMu.Lock()
+	defer queueMu.Unlock()
+	ops, err := loadQueue()
+	if err != nil {
+		return fmt.Errorf("%w (and the offline queue could not be read: %s)", cause, err)
+	}
+	op.QueuedAt = time.Now().UTC()
+	if op.Method == "PUT" {
+		if m, err := fhir.Parse(op.Body); err == nil {
+			op.Version, _ = fhir.Path(m, "meta.versionId").(string)
+		}
+	}
+	if op.Method == "BUNDLE" {
+		pinVersions(op.Entries)
+	}
+	ops = append(ops, op)
+	if err := saveQueue(ops); err != nil {
+		return fmt.Errorf("%w (and the change could not be queued: %s)", cause, err)
+	}
+	pending, _ := queueCounts(ops)
+	return &QueuedError{Pending: pending, Cause: cause}
+}
+
+// pinVersions sets ifMatch on each PUT entry from its resource's
+// meta.versionId, so a replayed transaction fails rather than overwriting a
+// newer version.
+func pinVersions(entries []map[string]any) {
+	for _, e := range entries {
+		request, _ := e["request"].(map[string]any)
+		if request == nil || request["method"] != "PUT" {
+			continue
+		}
+		raw, err := json.Marshal(e["resource"])
+		if err != nil {
+			continue
+		}
+		if m, err := fhir.Parse(raw); err == nil {
+			if v, _ := fhir.Path(m, "meta.versionId").(string); v != "" {
+				request["ifMatch"] = `W/"` + v + `"`
+			}
+		}
+	}
+}
+
+// queueIfUnreachable queues op when err says the store could not be reached
+// and replaySafe allows it, and otherwise returns err unchanged.
+func queueIfUnreachable(op queuedOp, err error) error {
+	if !replaySafe(op, err) {
+		return err
+	}
+	return enqueue(op, err)
+}
+
+// queueBehindPending replays any waiting operations before a new mutation.
+// If some are still waiting afterwards, op is queued behind them and the
+// QueuedError is returned; otherwise nil, and the caller goes ahead.
+func (a *App) queueBehindPending(ctx context.Context, op queuedOp) error {
+	ops, err := loadQueue()
+	if err != nil {
+		return err
+	}
+	if pending, _ := queueCounts(ops); pending == 0 {
+		return nil
+	}
+	result, err := a.replayQueue(ctx)
+	if err != nil {
+		return err
+	}
+	if result.Remaining == 0 {
+		return nil
+	}
+	return enqueue(op, result.Cause)
+}
+
+// replayResult summarizes one pass over the queue.
+type replayResult struct {
+	Replayed  int
+	Conflicts int
+	// Remaining is how many operations are still waiting because the store
+	// stopped answering, and Cause is the error that stopped the pass.
+	Remaining int
+	Cause     error
+}
+
+// replayQueue replays waiting operations in order, stopping at the first one
+// the store does not answer. Operations that fail for any other reason are
+// marked as conflicts.
+func (a *App) replayQueue(ctx context.Context) (replayResult, error) {
+	queueMu.Lock()
+	defer queueMu.Unlock()
+	var result replayResult
+	ops, err := loadQueue()
+	if err != nil {
+		return result, err
+	}
+	done := make([]bool, len(ops))
+	for i := range ops {
+		if ops[i].Conflict != "" {
+			continue
+		}
+		if result.Cause == nil && ctx.Err() != nil {
+			result.Cause = ctx.Err()
+		}
+		if result.Cause != nil {
+			result.Remaining++
+			continue
+		}
+		err := a.replayOp(ctx, ops[i])
+		switch {
+		case err == nil:
+			done[i] = true
+			result.Replayed++
+		case replaySafe(ops[i], err) || ctx.Err() != nil && !ops[i].creates():
+			result.Cause = err
+			result.Remaining++
+		case isUnreachable(err) || ctx.Err() != nil:
+			// The store may have applied it, and replaying it again could
+			// create duplicates, so the user checks first.
+			ops[i].Conflict = "no answer from the store, which may have applied it (check before retrying): " + err.Error()
+			result.Conflicts++
+			result.Cause = err
+		default:
+			ops[i].Conflict = err.Error()
+			result.Conflicts++
+		}
+	}
+
+	if result.Replayed > 0 {
+		a.session.forgetPatientList()
+	}
+	var kept []queuedOp
+	for i, op := range ops {
+		if !done[i] {
+			kept = append(kept, op)
+		}
+	}
+	return result, saveQueue(kept)
+}
+
+// replayOp submits one queued operation, checking first that updates and
+// deletes still apply to what is on the server.
+func (a *App) replayOp(ctx context.Context, op queuedOp) error {
+	switch op.Method {
+	case "POST":
+		_, err := a.createResourceWithProvenance(ctx, op.ResourceType, op.Body)
+		return err
+
+	case "PUT":
+		if op.Version != "" {
+			raw, err := a.Client.ReadResource(ctx, op.ResourceType, op.ResourceID)
+			if phenostore.IsNotFound(err) || phenostore.IsGone(err) {
+				return fmt.Errorf("%s was deleted on the server", op.ResourceType+"/"+op.ResourceID)
+			}
+			if err != nil {
+				return err
+			}
+			m, _ := fhir.Parse(raw)
+			if current, _ := fhir.Path(m, "meta.versionId").(string); current != op.Version {
+				return fmt.Errorf("%s changed on the server (version %s, change was based on %s)",
+					op.ResourceType+"/"+op.ResourceID, current, op.Version)
+			}
+		}
+		_, err := a.updateResourceWithProvenance(ctx, op.ResourceType, op.ResourceID, op.Body)
+		return err
+
+	case "DELETE":
+		_, err := a.Client.ReadResource(ctx, op.ResourceType, op.ResourceID)
+		if phenostore.IsNotFound(err) || phenostore.IsGone(err) {
+			return nil // already gone
+		}
+		if err != nil {
+			return err
+		}
+		return a.deleteResourceWithProvenance(ctx, op.ResourceType, op.ResourceID)
+
+	case "BUNDLE":
+		_, err := a.transaction(ctx, op.Entries, op.Targets, op.Activity)
+		if phenostore.IsConflict(err) {
+			return fmt.Errorf("a resource in the transaction changed on the server: %w", err)
+		}
+		return err
+	}
+	return fmt.Errorf("unknown queued method %q", op.Method)
+}
+
+// queueStatus is the main menu's queue summary, or "" when the queue is
+// empty.
+func queueStatus() string {
+	ops, err := loadQueue()
+	if err != nil {
+		return "offline queue unreadable"
+	}
+	pending, conflicts := queueCounts(ops)
+	switch {
+	case pending > 0 && conflicts > 0:
+		return fmt.Sprintf("%d changes queued offline, %d conflicts", pending, conflicts)
+	case pending > 0:
+		return fmt.Sprintf("%d changes queued offline", pending)
+	case conflicts > 0:
+		return fmt.Sprintf("%d queued changes in conflict", conflicts)
+	}
+	return ""
+}
+
+// replayQueueOnMenu tries to replay waiting operations each time the main
+// menu is shown, so queued changes go out as soon as the store is back.
+func (a *App) replayQueueOnMenu() {
+	ops, err := loadQueue()
+	if err != nil {
+		return
+	}
+	if pending, _ := queueCounts(ops); pending == 0 {
+		return
+	}
+
+	var result replayResult
+	var replayErr error
+	err = runSpinner("Replaying queued changes...", func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+		result, replayErr = a.replayQueue(ctx)
+	})
+	if err != nil || replayErr != nil {
+		return
+	}
+	if result.Replayed > 0 || result.Conflicts > 0 {
+		fmt.Printf("  Replayed %d queued changes (%d conflicts, %d still waiting)\n",
+			result.Replayed, result.Conflicts, result.Remaining)
+	}
+}
+
+// OfflineQueue lists queued changes and lets the user retry them or discard
+// conflicts.
+func (a *App) OfflineQueue() {
+	for {
+		ops, err := loadQueue()
+		if err != nil {
+			ShowError(err)
+			PressEnter()
+			return
+		}
+
+		fmt.Println()
+		if len(ops) == 0 {
+			fmt.Println("  No queued changes.")
+			PressEnter()
+			return
+		}
+		fmt.Printf("  %-20s %-40s %s\n", "QUEUED", "CHANGE", "STATUS")
+		for _, op := range ops {
+			status := "waiting"
+			if op.Conflict != "" {
+				status = "conflict: " + op.Conflict
+			}
+			fmt.Printf("  %-20s %-40s %s\n", op.QueuedAt.Local().Format("2006-01-02 15:04:05"), op.Target(), status)
+		}
+
+		var choice string
+		err = huh.NewSelect[string]().
+			Title("Offline Queue").
+			Options(
+				huh.NewOption("Retry now", "retry"),
+				huh.NewOption("Retry conflicts", "retry-conflicts"),
+				huh.NewOption("Discard conflicts", "discard-conflicts"),
+				huh.NewOption("Discard everything", "discard-all"),
+				huh.NewOption("\u2190 Back", "back"),
+			).
+			Value(&choice).
+			Run()
+		if err != nil || choice == "back" {
+			if err != nil && !isAbort(err) {
+				ShowError(err)
+				PressEnter()
+			}
+			return
+		}
+
+		switch choice {
+		case "retry", "retry-conflicts":
+			if choice == "retry-conflicts" {
+				for i := range ops {
+					ops[i].Conflict = ""
+				}
+				if err := saveQueue(ops); err != nil {
+					ShowError(err)
+					continue
+				}
+			}
+			var result replayResult
+			var replayErr error
+			err := runSpinner("Replaying queued changes...", func(ctx context.Context) {
+				result, replayErr = a.replayQueue(ctx)
+			})
+			if err != nil || replayErr != nil {
+				ShowError(errors.Join(err, replayErr))
+				continue
+			}
+			fmt.Printf("\n  Replayed %d, %d conflicts, %d still waiting\n", result.Replayed, result.Conflicts, result.Remaining)
+			if result.Cause != nil {
+				ShowError(result.Cause)
+			}
+
+		case "discard-conflicts", "discard-all":
+			var keep []queuedOp
+			for _, op := range ops {
+				if choice == "discard-conflicts" && op.Conflict == "" {
+					keep = append(keep, op)
+				}
+			}
+			var confirm bool
+			err := huh.NewConfirm().
+				Title(fmt.Sprintf("Discard %d queued changes?", len(ops)-len(keep))).
+				Description("Discarded changes are never sent to the store.").
+				Value(&confirm).
+				Run()
+			if err != nil || !confirm {
+				continue
+			}
+			if err := saveQueue(keep); err != nil {
+				ShowError(err)
+			}
+		}
+	}
+}
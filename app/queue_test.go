@@ -0,0 +1,45 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net"
+	neturl "net/url"
+	"os"
+	"syscall"
+	"testing"
+
+	"github.com/phenoml/phenostore-sdk-go/phenostore"
+)
+
+func TestReplaySafe(t *testing.T) {
+	urlErr := func(err error) error { return &neturl.Error{Op: "Post", URL: "https://store.example", Err: err} }
+	refused := urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)})
+	noHost := urlErr(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "store.example"}})
+	timeout := urlErr(context.DeadlineExceeded)
+	cancelled := urlErr(context.Canceled)
+
+	tests := []struct {
+		name   string
+		method string
+		err    error
+		want   bool
+	}{
+		{"create, connection refused", "POST", refused, true},
+		{"create, unknown host", "POST", noHost, true},
+		{"create, 503", "POST", &phenostore.OperationOutcomeError{StatusCode: 503}, true},
+		{"create, timeout", "POST", timeout, false},
+		{"create, 502", "POST", &phenostore.OperationOutcomeError{StatusCode: 502}, false},
+		{"transaction, 504", "BUNDLE", &phenostore.OperationOutcomeError{StatusCode: 504}, false},
+		{"update, timeout", "PUT", timeout, true},
+		{"delete, 502", "DELETE", &phenostore.OperationOutcomeError{StatusCode: 502}, true},
+		{"update, cancelled", "PUT", cancelled, false},
+		{"create, rejected", "POST", &phenostore.OperationOutcomeError{StatusCode: 422}, false},
+		{"create, other error", "POST", errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		if got := replaySafe(queuedOp{Method: tt.method}, tt.err); got != tt.want {
+			t.Errorf("%s: replaySafe = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
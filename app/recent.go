@@ -0,0 +1,232 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	neturl "net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// recentTypes are the resource types Recent Activity searches: those the
+// app writes, less AuditEvent and Provenance, which it writes as a side
+// effect of other changes, and of Recent Activity itself.
+var recentTypes = slices.DeleteFunc(slices.Clone(storedResourceTypes), func(rt string) bool {
+	return rt == "AuditEvent" || rt == "Provenance"
+})
+
+// recentPerType is how many changed resources of each type are fetched.
+const recentPerType = 100
+
+// recentShown is how many changes the timeline lists.
+const recentShown = 50
+
+// recentChange is one resource changed since the chosen time.
+type recentChange struct {
+	ResourceType string
+	ID           string
+	Version      string
+	Updated      time.Time
+	Label        string
+}
+
+// recentTypeChanges is what one resource type's search found. More is set
+// when more than recentPerType resources changed.
+type recentTypeChanges struct {
+	ResourceType string
+	Changes      []recentChange
+	More         bool
+}
+
+// recentChanges searches each type for resources saved after since, with
+// _lastUpdated=gt, most recent first, in parallel and in the order of types.
+func (a *App) recentChanges(ctx context.Context, types []string, since time.Time) ([]recentTypeChanges, error) {
+	found := make([]recentTypeChanges, len(types))
+	errs := make([]error, len(types))
+	var wg sync.WaitGroup
+	for i, rt := range types {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			query := withSort(neturl.Values{fhir.SearchLastUpdated: {"gt" + since.UTC().Format(time.RFC3339)}}, sortRecentlySaved)
+			resources, next, err := a.searchPage(ctx, rt, recentPerType, query, "")
+			a.audit(ctx, fhir.AuditRead, "search-type", rt, rt, "", err)
+			a.logResultAccess(rt, resources, err)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			found[i] = recentTypeChanges{ResourceType: rt, More: next != ""}
+			for _, raw := range resources {
+				if c, ok := parseRecentChange(raw); ok {
+					found[i].Changes = append(found[i].Changes, c)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return found, nil
+}
+
+// parseRecentChange reads a resource's type, id, version, last update, and
+// label.
+func parseRecentChange(raw json.RawMessage) (recentChange, bool) {
+	m, err := fhir.Parse(raw)
+	if err != nil {
+		return recentChange{}, false
+	}
+	updated, _ := time.Parse(time.RFC3339, lastUpdated(raw))
+	version, _ := fhir.Path(m, "meta.versionId").(string)
+	return recentChange{
+		ResourceType: mapStr(m, "resourceType"),
+		ID:           mapStr(m, "id"),
+		Version:      version,
+		Updated:      updated,
+		Label:        consoleEntryLabel(m),
+	}, true
+}
+
+// recentTimeline merges every type's changes, most recent first.
+func recentTimeline(found []recentTypeChanges) []recentChange {
+	var all []recentChange
+	for _, f := range found {
+		all = append(all, f.Changes...)
+	}
+	slices.SortStableFunc(all, func(x, y recentChange) int { return y.Updated.Compare(x.Updated) })
+	return all
+}
+
+// askSince asks from when to show changes, defaulting to when the app
+// started.
+func (a *App) askSince() (time.Time, error) {
+	now := time.Now()
+	started := a.session.started
+	if started.IsZero() {
+		started = now
+	}
+	since := started
+	custom := time.Time{}
+	err := huh.NewSelect[time.Time]().
+		Title("Show changes since").
+		Options(
+			huh.NewOption(fmt.Sprintf("The app started (%s)", started.Format("15:04")), started),
+			huh.NewOption("The last hour", now.Add(-time.Hour)),
+			huh.NewOption("The last 24 hours", now.Add(-24*time.Hour)),
+			huh.NewOption("The last 7 days", now.AddDate(0, 0, -7)),
+			huh.NewOption("A chosen time...", custom),
+		).
+		Value(&since).
+		Run()
+	if err != nil || !since.IsZero() {
+		return since, err
+	}
+	var s string
+	err = huh.NewInput().
+		Title("Since (YYYY-MM-DD HH:MM)").
+		Value(&s).
+		Validate(func(s string) error {
+			t, err := time.ParseInLocation(measuredAtLayout, strings.TrimSpace(s), time.Local)
+			if err != nil {
+				return fmt.Errorf("use YYYY-MM-DD HH:MM")
+			}
+			if t.After(time.Now()) {
+				return fmt.Errorf("cannot be in the future")
+			}
+			return nil
+		}).
+		Run()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation(measuredAtLayout, strings.TrimSpace(s), time.Local)
+}
+
+// RecentActivity shows what changed in the store since the app started or
+// a chosen time: how many resources of each type were created or updated,
+// then the most recent changes, newest first. Changes by other clients are
+// included, since the search is on the server's _lastUpdated.
+func (a *App) RecentActivity() {
+	since, err := a.askSince()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var found []recentTypeChanges
+	var fetchErr error
+	var elapsed time.Duration
+	err = runSpinner("Searching for changes...", func(ctx context.Context) {
+		start := time.Now()
+		found, fetchErr = a.recentChanges(ctx, recentTypes, since)
+		elapsed = time.Since(start)
+	})
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	printRecentActivity(found, since)
+	fmt.Println()
+	showTiming(fmt.Sprintf("Searched %d resource types with _lastUpdated (%d parallel API calls)", len(found), len(found)), elapsed)
+	PressEnter()
+}
+
+// printRecentActivity lists the types with changes and their counts, then
+// the newest recentShown changes with their time and version.
+func printRecentActivity(found []recentTypeChanges, since time.Time) {
+	fmt.Println(barStyle.Bold(true).Render("Recent Activity since " + since.Format("2006-01-02 15:04")))
+	timeline := recentTimeline(found)
+	if len(timeline) == 0 {
+		fmt.Println("  Nothing has changed.")
+		return
+	}
+	var counts []string
+	for _, f := range found {
+		if len(f.Changes) == 0 {
+			continue
+		}
+		count := fmt.Sprintf("%d", len(f.Changes))
+		if f.More {
+			count += "+"
+		}
+		counts = append(counts, fmt.Sprintf("%s %s", count, f.ResourceType))
+	}
+	fmt.Println("  " + strings.Join(counts, ", "))
+	fmt.Println()
+
+	for _, c := range timeline[:min(len(timeline), recentShown)] {
+		change := "updated"
+		if c.Version == "1" {
+			change = "created"
+		}
+		line := fmt.Sprintf("  %s  %-7s %s/%s", c.Updated.Local().Format("01-02 15:04:05"), change, c.ResourceType, c.ID)
+		if c.Label != "" {
+			line += "  " + timingStyle.Render(c.Label)
+		}
+		fmt.Println(line)
+	}
+	if len(timeline) > recentShown {
+		fmt.Println(timingStyle.Render(fmt.Sprintf("  ...and %d earlier changes", len(timeline)-recentShown)))
+	}
+}
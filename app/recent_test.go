@@ -0,0 +1,48 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestRecentChanges(t *testing.T) {
+	since := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("_lastUpdated"); got != "gt2026-10-16T09:00:00Z" {
+			t.Errorf("_lastUpdated = %q", got)
+		}
+		switch path.Base(r.URL.Path) {
+		case "Patient":
+			fmt.Fprint(w, `{"resourceType":"Bundle","type":"searchset","entry":[
+				{"resource":{"resourceType":"Patient","id":"p1","meta":{"versionId":"2","lastUpdated":"2026-10-16T09:30:00Z"},"name":[{"given":["Maria"],"family":"Garcia"}]}}]}`)
+		case "Condition":
+			fmt.Fprint(w, `{"resourceType":"Bundle","type":"searchset","link":[{"relation":"next","url":"Condition?page=2"}],"entry":[
+				{"resource":{"resourceType":"Condition","id":"c1","meta":{"versionId":"1","lastUpdated":"2026-10-16T10:00:00Z"}}},
+				{"resource":{"resourceType":"Condition","id":"c2","meta":{"versionId":"1","lastUpdated":"2026-10-16T09:10:00Z"}}}]}`)
+		default:
+			fmt.Fprint(w, `{"resourceType":"Bundle","type":"searchset"}`)
+		}
+	})
+
+	found, err := a.recentChanges(context.Background(), []string{"Patient", "Condition", "Flag"}, since)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 3 || len(found[0].Changes) != 1 || len(found[1].Changes) != 2 || !found[1].More || len(found[2].Changes) != 0 {
+		t.Fatalf("unexpected changes: %+v", found)
+	}
+	if c := found[0].Changes[0]; c.Label != "Maria Garcia" || c.Version != "2" {
+		t.Errorf("patient change = %+v", c)
+	}
+	var order []string
+	for _, c := range recentTimeline(found) {
+		order = append(order, c.ID)
+	}
+	if fmt.Sprint(order) != "[c1 p1 c2]" {
+		t.Errorf("timeline order = %v, want [c1 p1 c2]", order)
+	}
+}
@@ -0,0 +1,174 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// recodeCondition replaces every coding in a Condition's code that matches
+// fromSystem (any system if empty) and fromCode. It reports whether anything
+// changed.
+func recodeCondition(m map[string]any, fromSystem, fromCode, toSystem, toCode, toDisplay string) bool {
+	code, _ := m["code"].(map[string]any)
+	codings, _ := code["coding"].([]any)
+	changed := false
+	for i, c := range codings {
+		coding, ok := c.(map[string]any)
+		if !ok || mapStr(coding, "code") != fromCode || (fromSystem != "" && mapStr(coding, "system") != fromSystem) {
+			continue
+		}
+		replacement := map[string]any{"system": toSystem, "code": toCode}
+		if toDisplay != "" {
+			replacement["display"] = toDisplay
+		}
+		codings[i] = replacement
+		changed = true
+	}
+	if changed && toDisplay != "" {
+		code["text"] = toDisplay
+	}
+	return changed
+}
+
+// RecodeConditions finds every Condition coded with one code and rewrites it
+// to another, e.g. to migrate local codes to ICD-10-CM. Matches are gathered
+// with a paged search first, so rewriting them cannot shift later pages, then
+// updated in batched transactions. A dry run only lists what would change.
+func (a *App) RecodeConditions() {
+	var fromSystem, fromCode, toCode, toDisplay string
+	toSystem := fhir.ICD10System
+	dryRun := true
+
+	required := func(s string) error {
+		if strings.TrimSpace(s) == "" {
+			return fmt.Errorf("required")
+		}
+		return nil
+	}
+
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().Title("Current code system (blank for any)").Value(&fromSystem),
+			huh.NewInput().Title("Current code").Value(&fromCode).Validate(required),
+			huh.NewInput().Title("New code system").Value(&toSystem).Validate(required),
+			huh.NewInput().Title("New code (e.g., E11.9)").Value(&toCode).Validate(func(s string) error {
+				if err := required(s); err != nil {
+					return err
+				}
+				if strings.TrimSpace(toSystem) == fhir.ICD10System {
+					if _, ok := fhir.LookupICD10(s); !ok {
+						return fmt.Errorf("%s is not in the bundled ICD-10-CM codes", fhir.NormalizeICD10(s))
+					}
+				}
+				return nil
+			}),
+			huh.NewInput().Title("New display name (optional)").Value(&toDisplay),
+			huh.NewConfirm().Title("Dry run (report only)?").Value(&dryRun),
+		),
+	)
+	if err := form.Run(); err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	fromSystem, fromCode = strings.TrimSpace(fromSystem), strings.TrimSpace(fromCode)
+	toSystem, toCode = strings.TrimSpace(toSystem), strings.TrimSpace(toCode)
+	if toSystem == fhir.ICD10System {
+		toCode = fhir.NormalizeICD10(toCode)
+	}
+
+	token := fromCode
+	if fromSystem != "" {
+		token = fromSystem + "|" + fromCode
+	}
+
+	ctx := context.Background()
+	var matches []json.RawMessage
+	var fetchErr error
+	var elapsed time.Duration
+
+	err := runSpinner("Finding conditions...", func(ctx context.Context) {
+		start := time.Now()
+		matches, fetchErr = a.searchAllPages(ctx, "Condition", 100, neturl.Values{fhir.SearchConditionCode: {token}}, nil)
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	type change struct {
+		id, patientID string
+		body          json.RawMessage
+	}
+	var changes []change
+	for _, raw := range matches {
+		m, err := fhir.Parse(raw)
+		if err != nil || !recodeCondition(m, fromSystem, fromCode, toSystem, toCode, toDisplay) {
+			continue
+		}
+		body, _ := json.Marshal(m)
+		changes = append(changes, change{id: mapStr(m, "id"), patientID: fhir.PatientRef(m), body: body})
+	}
+
+	fmt.Println()
+	showTiming(fmt.Sprintf("Found %d conditions coded %s", len(changes), token), elapsed)
+	if len(changes) == 0 {
+		PressEnter()
+		return
+	}
+
+	if dryRun {
+		fmt.Printf("  %-38s %-38s %s\n", "CONDITION", "PATIENT", "CHANGE")
+		for _, c := range changes {
+			fmt.Printf("  %-38s %-38s %s → %s\n", c.id, c.patientID, fromCode, toCode)
+		}
+		fmt.Println("\n  Dry run: nothing was changed.")
+		PressEnter()
+		return
+	}
+
+	var confirm bool
+	err = huh.NewConfirm().
+		Title(fmt.Sprintf("Rewrite %d conditions from %s to %s?", len(changes), fromCode, toCode)).
+		Value(&confirm).
+		Run()
+	if err != nil || !confirm {
+		return
+	}
+
+	entries := make([]map[string]any, len(changes))
+	targets := make([]string, len(changes))
+	for i, c := range changes {
+		entries[i] = fhir.UpdateEntry("Condition", c.id, c.body)
+		targets[i] = "Condition/" + c.id
+	}
+	start := time.Now()
+	updated, done, err := a.processInBatches(ctx, entries, targets, "UPDATE", "updated")
+	for _, c := range changes[:done] {
+		a.emit(ctx, EventConditionUpdated, "Condition", c.id, c.patientID)
+	}
+	if err != nil {
+		ShowError(err)
+	}
+
+	fmt.Println()
+	showTiming(fmt.Sprintf("Recoded %d of %d conditions", updated, len(changes)), time.Since(start))
+	PressEnter()
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	neturl "net/url"
+	"strings"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// referenceCheckBatch is how many IDs checkReferences looks up per search.
+const referenceCheckBatch = 100
+
+// BrokenReferencesError lists references in a transaction bundle that point
+// at neither another entry nor an existing server resource.
+type BrokenReferencesError struct {
+	Broken []fhir.EntryReference
+}
+
+func (e *BrokenReferencesError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d broken references, bundle not submitted:", len(e.Broken))
+	for _, r := range e.Broken {
+		b.WriteString("\n    " + r.String())
+	}
+	return b.String()
+}
+
+// checkReferences verifies every reference in entries before they are
+// submitted. urn: references must match another entry's fullUrl; relative
+// references must match a PUT entry or a resource on the server, which is
+// looked up with one _id search per resource type. It returns a
+// *BrokenReferencesError if anything does not resolve.
+func (a *App) checkReferences(ctx context.Context, entries []map[string]any) error {
+	refs, local := fhir.BundleReferences(entries)
+
+	remote := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, r := range refs {
+		rt, id, ok := r.Target()
+		if local[r.Reference] || r.IsURN() || !ok || local[rt+"/"+id] || seen[rt+"/"+id] {
+			continue
+		}
+		seen[rt+"/"+id] = true
+		remote[rt] = append(remote[rt], id)
+	}
+
+	existing := make(map[string]bool)
+	for rt, ids := range remote {
+		for lo := 0; lo < len(ids); lo += referenceCheckBatch {
+			batch := ids[lo:min(lo+referenceCheckBatch, len(ids))]
+			found, err := a.searchValues(ctx, rt, len(batch), neturl.Values{
+				fhir.SearchID:       {strings.Join(batch, ",")},
+				fhir.SearchElements: {"id"},
+			})
+			if err != nil {
+				return fmt.Errorf("checking references: %w", err)
+			}
+			for _, raw := range found {
+				existing[rt+"/"+fhir.ResourceID(raw)] = true
+			}
+		}
+	}
+
+	var broken []fhir.EntryReference
+	for _, r := range refs {
+		if local[r.Reference] {
+			continue
+		}
+		rt, id, ok := r.Target()
+		if r.IsURN() || !ok || (!local[rt+"/"+id] && !existing[rt+"/"+id]) {
+			broken = append(broken, r)
+		}
+	}
+	if len(broken) > 0 {
+		return &BrokenReferencesError{Broken: broken}
+	}
+	return nil
+}
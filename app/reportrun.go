@@ -0,0 +1,360 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// followUpWindow is how far ahead the follow-ups-due report looks.
+const followUpWindow = 7 * 24 * time.Hour
+
+// ReportRunConfig controls a non-interactive report run.
+type ReportRunConfig struct {
+	Name       string   // a built-in report or a report definition's file name
+	OutDir     string   // directory the report files are written to
+	Formats    []string // "md" and/or "csv"
+	Cohort     string   // Group name or ID to limit the report to; empty for the whole clinic
+	Deidentify bool     // pseudonymize patients, drop patient IDs, and shift dates
+}
+
+// reportTable is one table of a rendered report.
+type reportTable struct {
+	Title   string
+	Headers []string
+	Rows    [][]string
+}
+
+// builtinReports are the reports that are not YAML definitions, by name.
+var builtinReports = map[string]func(a *App, ctx context.Context, now time.Time, scope cohortScope) ([]reportTable, error){
+	"abnormal-results": (*App).abnormalTables,
+	"care-gaps":        (*App).careGapTables,
+	"follow-ups-due":   (*App).followUpTables,
+	"plan-outcomes":    (*App).outcomeTables,
+	"registry":         (*App).registryTables,
+}
+
+// ReportNames lists the reports RunReport accepts: the built-in reports and
+// the definitions in the report directory, by file name.
+func ReportNames() ([]string, error) {
+	names := make([]string, 0, len(builtinReports))
+	for name := range builtinReports {
+		names = append(names, name)
+	}
+	defs, err := loadReports(reportDir())
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range defs {
+		names = append(names, d.Name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// RunReport renders a report to files in cfg.OutDir without prompting, for
+// cron jobs that mail clinics a daily worklist. It returns the paths written.
+func (a *App) RunReport(ctx context.Context, cfg ReportRunConfig) ([]string, error) {
+	for _, f := range cfg.Formats {
+		if f != "md" && f != "csv" {
+			return nil, fmt.Errorf("unknown format %q (use md or csv)", f)
+		}
+	}
+
+	var scope cohortScope
+	var cohort string
+	if cfg.Cohort != "" {
+		group, err := a.findGroup(ctx, cfg.Cohort)
+		if err != nil {
+			return nil, err
+		}
+		scope = newCohortScope(group)
+		cohort = mapStr(group, "name")
+	}
+
+	now := time.Now()
+	title := cfg.Name
+	var tables []reportTable
+	if build, ok := builtinReports[cfg.Name]; ok {
+		var err error
+		if tables, err = build(a, ctx, now, scope); err != nil {
+			return nil, err
+		}
+	} else {
+		defs, err := loadReports(reportDir())
+		if err != nil {
+			return nil, err
+		}
+		i := -1
+		for j, d := range defs {
+			if d.Name == cfg.Name {
+				i = j
+			}
+		}
+		if i < 0 {
+			names, _ := ReportNames()
+			return nil, fmt.Errorf("unknown report %q (use one of: %s)", cfg.Name, strings.Join(names, ", "))
+		}
+		def := defs[i]
+		resources, err := a.searchAllPages(ctx, def.Resource, 100, def.query(), nil)
+		if err != nil {
+			return nil, err
+		}
+		title = def.Title
+		tables = []reportTable{definitionTable(def, def.evaluate(scope.filter(resources)))}
+	}
+
+	if cohort != "" {
+		title += " — " + cohort
+	}
+	if cfg.Deidentify {
+		tables = deidentifyTables(tables, newDeidentifier())
+	}
+
+	if err := os.MkdirAll(cfg.OutDir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating output directory: %w", err)
+	}
+	var paths []string
+	for _, f := range cfg.Formats {
+		files := make(map[string][]byte)
+		switch f {
+		case "md":
+			files[cfg.Name+".md"] = markdownReport(title, now, tables)
+		case "csv":
+			for _, t := range tables {
+				name := cfg.Name + ".csv"
+				if len(tables) > 1 {
+					name = cfg.Name + "-" + slug(t.Title) + ".csv"
+				}
+				files[name] = csvTable(t)
+			}
+		}
+		for name, data := range files {
+			path := filepath.Join(cfg.OutDir, name)
+			if err := writeFileAtomic(path, data); err != nil {
+				return nil, fmt.Errorf("writing %s: %w", path, err)
+			}
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// findGroup returns the cohort whose ID or name (ignoring case) is nameOrID.
+func (a *App) findGroup(ctx context.Context, nameOrID string) (map[string]any, error) {
+	groups, err := a.searchAllPages(ctx, "Group", 100, neturl.Values{fhir.SearchGroupType: {"person"}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, raw := range groups {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		if mapStr(m, "id") == nameOrID || strings.EqualFold(mapStr(m, "name"), nameOrID) {
+			return m, nil
+		}
+	}
+	return nil, fmt.Errorf("no cohort named %q", nameOrID)
+}
+
+func (a *App) careGapTables(ctx context.Context, now time.Time, scope cohortScope) ([]reportTable, error) {
+	report, err := a.buildCareGapReport(ctx, now, scope)
+	if err != nil {
+		return nil, err
+	}
+	overdue := reportTable{Title: "Overdue activities", Headers: []string{"Patient", "Patient ID", "Plan", "Activity", "Status", "Due"}}
+	for _, o := range report.Overdue {
+		overdue.Rows = append(overdue.Rows, []string{o.PatientName, o.PatientID, o.Plan, o.Activity, o.Status, o.Due})
+	}
+	without := reportTable{Title: "Patients without an active plan", Headers: []string{"Patient", "Patient ID"}}
+	for _, p := range report.PatientsWithout {
+		without.Rows = append(without.Rows, []string{p.PatientName, p.PatientID})
+	}
+	return []reportTable{overdue, without}, nil
+}
+
+// followUpTables lists outstanding activities on active plans that are
+// overdue or due within followUpWindow, soonest first.
+func (a *App) followUpTables(ctx context.Context, now time.Time, scope cohortScope) ([]reportTable, error) {
+	plans, err := a.searchAllPages(ctx, "CarePlan", 100, neturl.Values{fhir.SearchCarePlanStatus: {"active"}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	type followUp struct {
+		due  time.Time
+		cols []string
+	}
+	var due []followUp
+	names := make(map[string]string)
+	for _, raw := range scope.filter(plans) {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		patientID := fhir.PatientRef(m)
+		if _, ok := names[patientID]; !ok {
+			names[patientID] = a.resolvePatientName(ctx, patientID)
+		}
+		dp := fhir.GetDashboardPlan(m, names[patientID])
+		for _, item := range dp.Outstanding {
+			t, ok := fhir.ScheduledDate(item.ScheduleNote)
+			if !ok || t.After(now.Add(followUpWindow)) {
+				continue
+			}
+			when := t.Format("2006-01-02")
+			if t.Before(now) {
+				when += " (overdue)"
+			}
+			due = append(due, followUp{t, []string{when, dp.PatientName, patientID, dp.Title, item.Description, item.Status}})
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool { return due[i].due.Before(due[j].due) })
+
+	t := reportTable{Title: "Follow-ups due", Headers: []string{"Due", "Patient", "Patient ID", "Plan", "Activity", "Status"}}
+	for _, f := range due {
+		t.Rows = append(t.Rows, f.cols)
+	}
+	return []reportTable{t}, nil
+}
+
+// registryTables lists the patients in scope with their active conditions.
+func (a *App) registryTables(ctx context.Context, now time.Time, scope cohortScope) ([]reportTable, error) {
+	patients, err := a.fetchAllPatients(ctx)
+	if err != nil {
+		return nil, err
+	}
+	conditions, err := a.searchAllPages(ctx, "Condition", 100, neturl.Values{fhir.SearchConditionClinicalStatus: {"active"}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	problems := make(map[string][]string)
+	for _, raw := range scope.filter(conditions) {
+		if m, err := fhir.Parse(raw); err == nil {
+			problems[fhir.PatientRef(m)] = append(problems[fhir.PatientRef(m)], fhir.ConditionDisplay(m))
+		}
+	}
+
+	t := reportTable{Title: "Registry", Headers: []string{"Patient", "Patient ID", "Birth date", "Gender", "Active conditions"}}
+	for _, raw := range scope.filter(patients) {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		id := mapStr(m, "id")
+		t.Rows = append(t.Rows, []string{fhir.PatientName(m), id, mapStr(m, "birthDate"), mapStr(m, "gender"), strings.Join(problems[id], "; ")})
+	}
+	sort.SliceStable(t.Rows, func(i, j int) bool { return t.Rows[i][0] < t.Rows[j][0] })
+	return []reportTable{t}, nil
+}
+
+// definitionTable lays out an evaluated report definition as a table.
+func definitionTable(d ReportDefinition, rows []reportRow) reportTable {
+	groupLabel := d.GroupBy
+	if groupLabel == "" {
+		groupLabel = d.Resource
+	}
+	t := reportTable{Title: d.Title, Headers: []string{groupLabel}}
+	for _, c := range d.Columns {
+		t.Headers = append(t.Headers, c.Label)
+	}
+	for _, r := range rows {
+		row := []string{r.Key}
+		for i := range d.Columns {
+			row = append(row, formatReportValue(r.Values[i], r.Valid[i]))
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t
+}
+
+// markdownReport renders tables as a Markdown document.
+func markdownReport(title string, generated time.Time, tables []reportTable) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s\n\nGenerated %s.\n", title, generated.Format("2006-01-02 15:04 MST"))
+	cell := strings.NewReplacer("|", `\|`, "\n", " ")
+	for _, t := range tables {
+		fmt.Fprintf(&b, "\n## %s\n\n", t.Title)
+		if len(t.Rows) == 0 {
+			b.WriteString("None.\n")
+			continue
+		}
+		b.WriteString("| " + strings.Join(t.Headers, " | ") + " |\n")
+		b.WriteString(strings.Repeat("|---", len(t.Headers)) + "|\n")
+		for _, row := range t.Rows {
+			cells := make([]string, len(row))
+			for i, c := range row {
+				cells[i] = cell.Replace(c)
+			}
+			b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		}
+	}
+	return b.Bytes()
+}
+
+// printReportTable prints a table to the terminal, each column as wide as
+// its widest cell.
+func printReportTable(t reportTable) {
+	fmt.Println(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12")).Render(t.Title))
+	if len(t.Rows) == 0 {
+		fmt.Println("  None.")
+		return
+	}
+	widths := make([]int, len(t.Headers))
+	for i, h := range t.Headers {
+		widths[i] = len(h)
+	}
+	for _, row := range t.Rows {
+		for i, c := range row {
+			widths[i] = max(widths[i], len(c))
+		}
+	}
+	line := func(cells []string) string {
+		s := ""
+		for i, c := range cells {
+			s += fmt.Sprintf("  %-*s", widths[i], c)
+		}
+		return s
+	}
+	fmt.Println(timingStyle.Render(line(t.Headers)))
+	for _, row := range t.Rows {
+		fmt.Println(line(row))
+	}
+}
+
+// csvTable renders one table as CSV with a header row.
+func csvTable(t reportTable) []byte {
+	var b bytes.Buffer
+	w := csv.NewWriter(&b)
+	w.Write(t.Headers)
+	w.WriteAll(t.Rows)
+	return b.Bytes()
+}
+
+// slug turns a title into a file name part, e.g. "Overdue activities" into
+// "overdue-activities".
+func slug(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
@@ -0,0 +1,386 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/phenoml/phenostore-example-go/fhir"
+	"gopkg.in/yaml.v3"
+)
+
+// ReportDefinition is a custom report loaded from a YAML file. Resources
+// matching the search are grouped by a field path and each column is an
+// aggregation expression: count(), count(path), distinct(path), sum(path),
+// avg(path), min(path), or max(path).
+type ReportDefinition struct {
+	Name     string            `yaml:"-"` // file name without extension
+	Title    string            `yaml:"title"`
+	Resource string            `yaml:"resource"`
+	Search   map[string]string `yaml:"search"`
+	GroupBy  string            `yaml:"group_by"`
+	Columns  []ReportColumn    `yaml:"columns"`
+	// Chart names a column to draw as a bar chart under the table.
+	Chart string `yaml:"chart"`
+}
+
+// ReportColumn is one aggregated column of a custom report.
+type ReportColumn struct {
+	Label string `yaml:"label"`
+	Expr  string `yaml:"expr"`
+}
+
+var reportExpr = regexp.MustCompile(`^(count|distinct|sum|avg|min|max)\(\s*([A-Za-z0-9_.]*)\s*\)$`)
+
+var barStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("12"))
+
+// reportDir is where custom report definitions are loaded from.
+func reportDir() string {
+	if dir := os.Getenv("PHENOSTORE_REPORT_DIR"); dir != "" {
+		return dir
+	}
+	return "reports"
+}
+
+// loadReports reads every *.yaml / *.yml report definition in dir.
+func loadReports(dir string) ([]ReportDefinition, error) {
+	var paths []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+
+	var reports []ReportDefinition
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		var def ReportDefinition
+		if err := yaml.Unmarshal(data, &def); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		if err := def.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		def.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if def.Title == "" {
+			def.Title = def.Name
+		}
+		reports = append(reports, def)
+	}
+	return reports, nil
+}
+
+// query returns the report's search parameters as a query.
+func (d ReportDefinition) query() neturl.Values {
+	search := make(neturl.Values, len(d.Search))
+	for k, v := range d.Search {
+		search.Set(k, v)
+	}
+	return search
+}
+
+func (d ReportDefinition) validate() error {
+	if d.Resource == "" {
+		return fmt.Errorf("report has no resource")
+	}
+	if len(d.Columns) == 0 {
+		return fmt.Errorf("report has no columns")
+	}
+	if err := fhir.CheckSearchParams(d.Resource, d.query()); err != nil {
+		return err
+	}
+	for _, c := range d.Columns {
+		m := reportExpr.FindStringSubmatch(c.Expr)
+		if m == nil {
+			return fmt.Errorf("column %q: invalid expression %q", c.Label, c.Expr)
+		}
+		if m[1] != "count" && m[2] == "" {
+			return fmt.Errorf("column %q: %s() needs a field path", c.Label, m[1])
+		}
+	}
+	return nil
+}
+
+// reportRow is one group of an evaluated report.
+type reportRow struct {
+	Key    string
+	Values []float64
+	Valid  []bool
+}
+
+// evaluate groups resources and computes every column expression per group.
+func (d ReportDefinition) evaluate(resources []json.RawMessage) []reportRow {
+	groups := make(map[string][]map[string]any)
+	for _, raw := range resources {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		key := "(all)"
+		if d.GroupBy != "" {
+			key = "(none)"
+			if v := fhir.Path(m, d.GroupBy); v != nil {
+				key = fmt.Sprint(v)
+			}
+		}
+		groups[key] = append(groups[key], m)
+	}
+
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	rows := make([]reportRow, 0, len(keys))
+	for _, key := range keys {
+		row := reportRow{Key: key}
+		for _, c := range d.Columns {
+			v, ok := aggregate(c.Expr, groups[key])
+			row.Values = append(row.Values, v)
+			row.Valid = append(row.Valid, ok)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// aggregate evaluates a validated column expression over a group.
+func aggregate(expr string, group []map[string]any) (float64, bool) {
+	m := reportExpr.FindStringSubmatch(expr)
+	fn, path := m[1], m[2]
+
+	if fn == "count" && path == "" {
+		return float64(len(group)), true
+	}
+
+	var values []float64
+	seen := make(map[string]bool)
+	for _, r := range group {
+		v := fhir.Path(r, path)
+		if v == nil {
+			continue
+		}
+		if fn == "distinct" {
+			if key := fmt.Sprint(v); !seen[key] {
+				seen[key] = true
+				values = append(values, 1)
+			}
+			continue
+		}
+		if fn == "count" {
+			values = append(values, 1)
+			continue
+		}
+		if f, ok := toFloat(v); ok {
+			values = append(values, f)
+		}
+	}
+
+	switch fn {
+	case "count", "distinct":
+		return float64(len(values)), true
+	case "sum":
+		total := 0.0
+		for _, v := range values {
+			total += v
+		}
+		return total, true
+	}
+	if len(values) == 0 {
+		return 0, false
+	}
+	switch fn {
+	case "avg":
+		total := 0.0
+		for _, v := range values {
+			total += v
+		}
+		return total / float64(len(values)), true
+	case "min":
+		lo := values[0]
+		for _, v := range values[1:] {
+			lo = math.Min(lo, v)
+		}
+		return lo, true
+	default: // max
+		hi := values[0]
+		for _, v := range values[1:] {
+			hi = math.Max(hi, v)
+		}
+		return hi, true
+	}
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func formatReportValue(v float64, ok bool) string {
+	if !ok {
+		return "—"
+	}
+	if v == math.Trunc(v) {
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(v, 'f', 1, 64)
+}
+
+// printReport renders evaluated rows as a table plus an optional bar chart.
+func printReport(d ReportDefinition, rows []reportRow) {
+	fmt.Println(lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("12")).Render(d.Title))
+
+	groupLabel := d.GroupBy
+	if groupLabel == "" {
+		groupLabel = d.Resource
+	}
+	keyWidth := len(groupLabel)
+	for _, r := range rows {
+		keyWidth = max(keyWidth, len(r.Key))
+	}
+
+	header := fmt.Sprintf("  %-*s", keyWidth, groupLabel)
+	for _, c := range d.Columns {
+		header += fmt.Sprintf("  %10s", c.Label)
+	}
+	fmt.Println(timingStyle.Render(header))
+	for _, r := range rows {
+		line := fmt.Sprintf("  %-*s", keyWidth, r.Key)
+		for i := range d.Columns {
+			line += fmt.Sprintf("  %10s", formatReportValue(r.Values[i], r.Valid[i]))
+		}
+		fmt.Println(line)
+	}
+
+	chartCol := -1
+	for i, c := range d.Columns {
+		if c.Label == d.Chart {
+			chartCol = i
+		}
+	}
+	if chartCol < 0 || len(rows) == 0 {
+		return
+	}
+
+	peak := 0.0
+	for _, r := range rows {
+		if r.Valid[chartCol] {
+			peak = math.Max(peak, r.Values[chartCol])
+		}
+	}
+	if peak <= 0 {
+		return
+	}
+	fmt.Println()
+	const width = 40
+	for _, r := range rows {
+		if !r.Valid[chartCol] {
+			continue
+		}
+		n := barLength(r.Values[chartCol], peak, width)
+		fmt.Printf("  %-*s  %s %s\n", keyWidth, r.Key,
+			barStyle.Render(strings.Repeat("█", n)),
+			formatReportValue(r.Values[chartCol], r.Valid[chartCol]))
+	}
+}
+
+// barLength scales v to a bar of at most width cells, the longest for peak.
+// Values at or below zero, such as a negative average, get no bar.
+func barLength(v, peak float64, width int) int {
+	return max(0, int(math.Round(v/peak*float64(width))))
+}
+
+// CustomReports lets the user pick a YAML report definition and renders it.
+func (a *App) CustomReports() {
+	a.customReports("", nil)
+}
+
+// customReports runs a report over the patients in scope, or the whole clinic
+// when scope is nil. cohort names the scope in the output.
+func (a *App) customReports(cohort string, scope cohortScope) {
+	dir := reportDir()
+	reports, err := loadReports(dir)
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if len(reports) == 0 {
+		fmt.Printf("\n  No report definitions found in %s.\n", dir)
+		PressEnter()
+		return
+	}
+
+	options := make([]huh.Option[int], len(reports))
+	for i, r := range reports {
+		options[i] = huh.NewOption(r.Title, i)
+	}
+	var idx int
+	err = huh.NewSelect[int]().
+		Title("Custom Reports").
+		Options(options...).
+		Value(&idx).
+		Run()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	def := reports[idx]
+
+	var resources []json.RawMessage
+	var fetchErr error
+	var elapsed time.Duration
+
+	err = runSpinner("Running report...", func(ctx context.Context) {
+		start := time.Now()
+		resources, fetchErr = a.searchAllPages(ctx, def.Resource, 100, def.query(), nil)
+		resources = scope.filter(resources)
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	if cohort != "" {
+		def.Title += " — " + cohort
+	}
+	printReport(def, def.evaluate(resources))
+	showTiming(fmt.Sprintf("Aggregated %d %s resources", len(resources), def.Resource), elapsed)
+	PressEnter()
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEvaluateDistinct(t *testing.T) {
+	def := ReportDefinition{
+		Resource: "Condition",
+		GroupBy:  "code.text",
+		Columns: []ReportColumn{
+			{Label: "Conditions", Expr: "count(subject.reference)"},
+			{Label: "Patients", Expr: "distinct(subject.reference)"},
+		},
+	}
+	if err := def.validate(); err != nil {
+		t.Fatal(err)
+	}
+	resources := []json.RawMessage{
+		json.RawMessage(`{"resourceType":"Condition","code":{"text":"Hypertension"},"subject":{"reference":"Patient/p1"}}`),
+		json.RawMessage(`{"resourceType":"Condition","code":{"text":"Hypertension"},"subject":{"reference":"Patient/p1"}}`),
+		json.RawMessage(`{"resourceType":"Condition","code":{"text":"Hypertension"},"subject":{"reference":"Patient/p2"}}`),
+		json.RawMessage(`{"resourceType":"Condition","code":{"text":"Hypertension"}}`),
+	}
+	rows := def.evaluate(resources)
+	if len(rows) != 1 || rows[0].Values[0] != 3 || rows[0].Values[1] != 2 {
+		t.Errorf("rows = %+v, want 3 conditions for 2 patients", rows)
+	}
+
+	if err := (ReportDefinition{Resource: "Condition", Columns: []ReportColumn{{Label: "Patients", Expr: "distinct()"}}}).validate(); err == nil {
+		t.Error("distinct() with no field path: want an error")
+	}
+}
+
+func TestBarLength(t *testing.T) {
+	tests := []struct {
+		v, peak float64
+		want    int
+	}{
+		{10, 10, 40},
+		{5, 10, 20},
+		{0, 10, 0},
+		{-3.5, 10, 0},
+	}
+	for _, tt := range tests {
+		if got := barLength(tt.v, tt.peak, 40); got != tt.want {
+			t.Errorf("barLength(%v, %v) = %d, want %d", tt.v, tt.peak, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package app
+
+// storedResourceTypes are the resource types the app writes, and those it
+// reads that other clients write, such as Procedure. Snapshots capture
+// them, Store Growth counts them, and Recent Activity searches them. Add a
+// type here when a screen starts writing it. They are in the order a
+// snapshot is restored: each after the types it usually refers to.
+// Subscriptions are left out: they are configuration, not data, and a
+// restored one would start notifying the original's endpoint.
+var storedResourceTypes = []string{
+	"Patient", "Group", "Device", "Binary", "PlanDefinition",
+	"Schedule", "Slot", "Appointment", "Condition", "EpisodeOfCare", "Encounter",
+	"Flag", "List", "Observation", "ImagingStudy", "Procedure",
+	"NutritionOrder", "Goal", "CarePlan", "Task", "DetectedIssue",
+	"Claim", "DocumentReference", "Composition", "Provenance", "AuditEvent",
+}
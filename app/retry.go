@@ -0,0 +1,163 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Every request the SDK sends, including token requests, goes through a
+// retryTransport. Rate limiting (429) and an overloaded or restarting
+// server (503) are retried for any request, since the server did not act on
+// it. A bad gateway or gateway timeout (502, 504) and a dropped connection
+// may come after the server did act, so they are only retried for GET, PUT,
+// DELETE, and HEAD, which are safe to send twice; a refused connection is
+// retried for anything. Waits double from retryBaseDelay up to
+// retryMaxDelay, with jitter so that parallel calls do not retry in step,
+// and a Retry-After header replaces the computed wait.
+
+const (
+	// defaultRetries is how many times a request is retried when
+	// PHENOSTORE_RETRIES is not set.
+	defaultRetries = 3
+	retryBaseDelay = 250 * time.Millisecond
+	retryMaxDelay  = 8 * time.Second
+	// maxRetryAfter is the longest Retry-After that is waited out. A server
+	// asking for longer gets its response passed back instead.
+	maxRetryAfter = 30 * time.Second
+)
+
+// retries counts the retries since the last timing line, which reports
+// them.
+var retries atomic.Int64
+
+// parseRetries parses PHENOSTORE_RETRIES, returning the default when it is
+// empty.
+func parseRetries(v string) (int, error) {
+	if v == "" {
+		return defaultRetries, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 || n > 10 {
+		return 0, fmt.Errorf("invalid PHENOSTORE_RETRIES %q: must be a whole number from 0 to 10 (0 turns retries off)", v)
+	}
+	return n, nil
+}
+
+// retryTransport retries failed requests with backoff; see the comment at
+// the top of the file.
+type retryTransport struct {
+	base      http.RoundTripper
+	retries   int
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= t.retries || !retryable(req, resp, err) {
+			return resp, err
+		}
+		wait, ok := t.wait(attempt, resp)
+		if !ok {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		retries.Add(1)
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// retryable reports whether a request that got resp or err may be sent
+// again. Requests whose body cannot be read a second time never are.
+func retryable(req *http.Request, resp *http.Response, err error) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	idempotent := req.Method == http.MethodGet || req.Method == http.MethodHead ||
+		req.Method == http.MethodPut || req.Method == http.MethodDelete
+	if err != nil {
+		if req.Context().Err() != nil {
+			return false
+		}
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return true
+		}
+		var netErr net.Error
+		return idempotent && (errors.As(err, &netErr) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF))
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent
+	}
+	return false
+}
+
+// wait returns how long to wait before retry attempt+1: the response's
+// Retry-After if it has one, or an exponential backoff with jitter. It
+// returns false if the server asks for longer than maxRetryAfter.
+func (t *retryTransport) wait(attempt int, resp *http.Response) (time.Duration, bool) {
+	if resp != nil {
+		if d, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return d, d <= maxRetryAfter
+		}
+	}
+	d := min(t.baseDelay<<attempt, t.maxDelay)
+	// Equal jitter: at least half the backoff, so waits still grow.
+	return d/2 + rand.N(d/2+1), true
+}
+
+// retryAfter parses a Retry-After header, which is either a number of
+// seconds or an HTTP date.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// retryNote describes the retries since the last call, e.g. ", after 2
+// retries", for the end of a timing line, or "" if there were none.
+func retryNote() string {
+	switch n := retries.Swap(0); n {
+	case 0:
+		return ""
+	case 1:
+		return ", after 1 retry"
+	default:
+		return fmt.Sprintf(", after %d retries", n)
+	}
+}
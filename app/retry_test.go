@@ -0,0 +1,138 @@
+package app
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flakyServer answers each request with the next of statuses, then 200,
+// recording the bodies it was sent.
+func flakyServer(t *testing.T, header http.Header, statuses ...int) (*httptest.Server, *[]string) {
+	t.Helper()
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		status := http.StatusOK
+		if len(bodies) <= len(statuses) {
+			status = statuses[len(bodies)-1]
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &bodies
+}
+
+func testRetryClient() *http.Client {
+	return &http.Client{Transport: &retryTransport{base: http.DefaultTransport, retries: 3, baseDelay: time.Millisecond, maxDelay: 4 * time.Millisecond}}
+}
+
+func TestRetryTransport(t *testing.T) {
+	retries.Store(0)
+	srv, bodies := flakyServer(t, nil, 503, 504)
+	resp, err := testRetryClient().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(*bodies) != 3 {
+		t.Errorf("GET: status %d after %d requests, want 200 after 3", resp.StatusCode, len(*bodies))
+	}
+	if note := retryNote(); note != ", after 2 retries" {
+		t.Errorf("retryNote = %q", note)
+	}
+	if note := retryNote(); note != "" {
+		t.Errorf("retryNote after reporting = %q, want empty", note)
+	}
+
+	srv, bodies = flakyServer(t, nil, 429)
+	resp, err = testRetryClient().Post(srv.URL, "application/fhir+json", strings.NewReader(`{"resourceType":"Bundle"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := *bodies; len(got) != 2 || got[1] != `{"resourceType":"Bundle"}` {
+		t.Errorf("POST after 429 sent %q, want the body twice", got)
+	}
+
+	srv, bodies = flakyServer(t, nil, 502)
+	resp, err = testRetryClient().Post(srv.URL, "application/fhir+json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway || len(*bodies) != 1 {
+		t.Errorf("POST after 502: status %d after %d requests, want 502 after 1", resp.StatusCode, len(*bodies))
+	}
+
+	srv, bodies = flakyServer(t, http.Header{"Retry-After": {"120"}}, 503)
+	resp, err = testRetryClient().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || len(*bodies) != 1 {
+		t.Errorf("Retry-After 120: status %d after %d requests, want 503 after 1", resp.StatusCode, len(*bodies))
+	}
+	retries.Store(0)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"5", 5 * time.Second, true},
+		{"Fri, 16 Oct 2026 12:00:10 GMT", 10 * time.Second, true},
+		{"Fri, 16 Oct 2026 11:00:00 GMT", 0, true},
+		{"", 0, false},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		if got, ok := retryAfter(tt.header, now); got != tt.want || ok != tt.ok {
+			t.Errorf("retryAfter(%q) = %v, %v; want %v, %v", tt.header, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestGzipTransport(t *testing.T) {
+	const bundle = `{"resourceType":"Bundle","type":"searchset"}`
+	var accepted string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accepted = r.Header.Get("Accept-Encoding")
+		if accepted != "gzip" {
+			io.WriteString(w, bundle)
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		zw := gzip.NewWriter(w)
+		io.WriteString(zw, bundle)
+		zw.Close()
+	}))
+	t.Cleanup(srv.Close)
+
+	for _, compress := range []bool{true, false} {
+		resp, err := newHTTPClient(0, compress, TransportSettings{}).Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil || string(body) != bundle {
+			t.Errorf("compress %v: body %q, %v", compress, body, err)
+		}
+		if want := map[bool]string{true: "gzip", false: ""}[compress]; accepted != want {
+			t.Errorf("compress %v: Accept-Encoding %q, want %q", compress, accepted, want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+)
+
+// roleMenus lists, per role, the entries each menu shows, by option value.
+// "*" shows the whole menu, and a menu missing from a role shows nothing
+// but Back. Roles only shape the menus; what the client may read and write
+// is still decided by the server.
+var roleMenus = map[string]map[string][]string{
+	"front-desk": {
+		"main":       {"summary", "portal", "manage"},
+		"manage":     {"patient", "scheduling", "billing"},
+		"patient":    {"register", "list", "view", "update", "attach", "download"},
+		"scheduling": {"find"},
+		"billing":    {"*"},
+	},
+	"nurse": {
+		"main":       {"summary", "dashboard", "alerts", "abnormal", "manage"},
+		"manage":     {"patient", "clinical", "health", "diet", "devices", "scheduling"},
+		"patient":    {"list", "view", "flag-add", "flag-expire", "attach", "download"},
+		"clinical":   {"vitals-add", "vitals-dictate", "lab-add", "panel-add", "vitals-view", "vitals-trends", "lab-history", "vitals-edit", "diagnosis-view"},
+		"health":     {"complete", "status", "timeline"},
+		"diet":       {"view"},
+		"devices":    {"*"},
+		"scheduling": {"find"},
+	},
+	"provider": {
+		"main":     {"summary", "context", "visit-summary", "portal", "dashboard", "alerts", "abnormal", "reports", "digest", "ask", "explore", "console", "recent", "manage", "plugins"},
+		"manage":   {"patient", "clinical", "health", "diet", "devices", "cohorts"},
+		"patient":  {"list", "view", "flag-add", "flag-expire", "attach", "download"},
+		"clinical": {"*"},
+		"health":   {"*"},
+		"diet":     {"*"},
+		"devices":  {"view", "readings"},
+		"cohorts":  {"*"},
+	},
+	"admin": nil, // every menu
+}
+
+// validateRole returns an error naming the known roles if role is not one.
+// An empty role is valid and shows every menu.
+func validateRole(role string) error {
+	if _, ok := roleMenus[role]; ok || role == "" {
+		return nil
+	}
+	roles := make([]string, 0, len(roleMenus))
+	for r := range roleMenus {
+		roles = append(roles, r)
+	}
+	sort.Strings(roles)
+	return fmt.Errorf("unknown PHENOSTORE_ROLE %q (use one of: %s)", role, strings.Join(roles, ", "))
+}
+
+// menuOptions returns the options of a menu that the app's role may see.
+// Back and Exit are always kept; an unknown role sees nothing else.
+func (a *App) menuOptions(menu string, options ...huh.Option[string]) []huh.Option[string] {
+	menus, ok := roleMenus[a.Role]
+	if a.Role == "" || (ok && menus == nil) {
+		return options
+	}
+	allowed := menus[menu]
+	var out []huh.Option[string]
+	for _, o := range options {
+		if o.Value == "back" || o.Value == "exit" || slices.Contains(allowed, "*") || slices.Contains(allowed, o.Value) {
+			out = append(out, o)
+		}
+	}
+	return out
+}
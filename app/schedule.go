@@ -0,0 +1,297 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	neturl "net/url"
+	"strconv"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// GenerateSlots creates a practitioner's Schedule and fills it with free
+// Slots on weekdays, in one transaction bundle.
+func (a *App) GenerateSlots() {
+	practitioner := ""
+	startDate := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	daysStr := "5"
+	hours := "09:00-17:00"
+	lengthStr := "30"
+
+	validateDate := func(s string) error {
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return fmt.Errorf("use YYYY-MM-DD")
+		}
+		return nil
+	}
+	validatePositive := func(s string) error {
+		if n, err := strconv.Atoi(s); err != nil || n <= 0 {
+			return fmt.Errorf("must be a positive number")
+		}
+		return nil
+	}
+
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewInput().Title("Practitioner (e.g., Dr. Rivera)").Value(&practitioner),
+			huh.NewInput().Title("First day (YYYY-MM-DD)").Value(&startDate).Validate(validateDate),
+			huh.NewInput().Title("Number of days").Value(&daysStr).Validate(validatePositive),
+			huh.NewInput().Title("Hours (HH:MM-HH:MM)").Value(&hours).Validate(func(s string) error {
+				_, _, err := parseHours(s)
+				return err
+			}),
+			huh.NewInput().Title("Slot length (minutes)").Value(&lengthStr).Validate(validatePositive),
+		),
+	)
+	if err := form.Run(); err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	first, _ := time.ParseInLocation("2006-01-02", startDate, time.Local)
+	days, _ := strconv.Atoi(daysStr)
+	length, _ := strconv.Atoi(lengthStr)
+	open, closeAt, _ := parseHours(hours)
+
+	scheduleURN := "urn:uuid:" + newUUID()
+	entries := []map[string]any{
+		bundleEntryWithUrn(scheduleURN, "Schedule", fhir.NewSchedule(practitioner, "")),
+	}
+	for d := 0; d < days; d++ {
+		day := first.AddDate(0, 0, d)
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			continue
+		}
+		for t := day.Add(open); !t.Add(time.Duration(length) * time.Minute).After(day.Add(closeAt)); t = t.Add(time.Duration(length) * time.Minute) {
+			entries = append(entries, fhir.BundleEntry("Slot", fhir.NewSlot(scheduleURN, t, t.Add(time.Duration(length)*time.Minute))))
+		}
+	}
+
+	var created int
+	var apiErr error
+	var elapsed time.Duration
+
+	err := runSpinner("Generating slots...", func(ctx context.Context) {
+		start := time.Now()
+		created, apiErr = a.processTransaction(ctx, entries)
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(fmt.Errorf("processing bundle: %w", apiErr))
+		PressEnter()
+		return
+	}
+
+	fmt.Printf("\n  Created a schedule for %s with %d slots\n", practitioner, len(entries)-1)
+	showTiming(fmt.Sprintf("Created %d resources via transaction bundle", created), elapsed)
+	PressEnter()
+}
+
+// FindOpenSlots lets the user pick a practitioner's schedule and a day, then
+// lists the free slots on that day and offers to book one.
+func (a *App) FindOpenSlots() {
+	var schedules []json.RawMessage
+	var fetchErr error
+
+	err := runSpinner("Loading schedules...", func(ctx context.Context) {
+		schedules, fetchErr = a.searchAllPages(ctx, "Schedule", 100, neturl.Values{fhir.SearchScheduleActive: {"true"}}, nil)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+	if len(schedules) == 0 {
+		fmt.Println("\n  No schedules found. Generate slots first.")
+		PressEnter()
+		return
+	}
+
+	var options []huh.Option[string]
+	for _, raw := range schedules {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		options = append(options, huh.NewOption(fhir.ScheduleDisplay(m), mapStr(m, "id")))
+	}
+
+	var scheduleID string
+	date := time.Now().Format("2006-01-02")
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Practitioner").
+				Options(options...).
+				Value(&scheduleID),
+			huh.NewInput().Title("Day (YYYY-MM-DD)").Value(&date).Validate(func(s string) error {
+				if _, err := time.Parse("2006-01-02", s); err != nil {
+					return fmt.Errorf("use YYYY-MM-DD")
+				}
+				return nil
+			}),
+		),
+	)
+	if err := form.Run(); err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	day, _ := time.ParseInLocation("2006-01-02", date, time.Local)
+	query := neturl.Values{
+		fhir.SearchSlotSchedule: {"Schedule/" + scheduleID},
+		fhir.SearchSlotStatus:   {"free"},
+		fhir.SearchSlotStart:    {"ge" + day.UTC().Format(time.RFC3339), "lt" + day.AddDate(0, 0, 1).UTC().Format(time.RFC3339)},
+		fhir.SearchSort:         {fhir.SearchSlotStart},
+	}
+
+	var slots []json.RawMessage
+	var elapsed time.Duration
+
+	err = runSpinner("Searching open slots...", func(ctx context.Context) {
+		start := time.Now()
+		slots, fetchErr = a.searchValues(ctx, "Slot", 100, query)
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	fmt.Println()
+	if len(slots) == 0 {
+		fmt.Println("  No open slots on that day.")
+		PressEnter()
+		return
+	}
+	fhir.PrintSlotList(slots)
+	showTiming(fmt.Sprintf("Found %d open slots", len(slots)), elapsed)
+
+	practitioner := ""
+	for _, raw := range schedules {
+		if m, err := fhir.Parse(raw); err == nil && mapStr(m, "id") == scheduleID {
+			practitioner, _ = fhir.Path(m, "actor.display").(string)
+		}
+	}
+	a.bookSlot(slots, practitioner)
+}
+
+// bookSlot offers to book one of slots for a patient. The Appointment is
+// created and the Slot marked busy in one transaction, and the Slot update
+// is pinned to the version that was listed, so two people booking the same
+// slot cannot both succeed.
+func (a *App) bookSlot(slots []json.RawMessage, practitioner string) {
+	options := []huh.Option[int]{huh.NewOption("Don't book", -1)}
+	for i, raw := range slots {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		start, err := time.Parse(time.RFC3339, mapStr(m, "start"))
+		if err != nil {
+			continue
+		}
+		options = append(options, huh.NewOption(start.Local().Format("Mon Jan 02 15:04"), i))
+	}
+	choice := -1
+	err := huh.NewSelect[int]().
+		Title("Book a slot").
+		Options(options...).
+		Value(&choice).
+		Run()
+	if err != nil || choice < 0 {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	slot, _ := fhir.Parse(slots[choice])
+	slotID := mapStr(slot, "id")
+	start, _ := time.Parse(time.RFC3339, mapStr(slot, "start"))
+	end, _ := time.Parse(time.RFC3339, mapStr(slot, "end"))
+	slot["status"] = "busy"
+	slotBody, _ := json.Marshal(slot)
+	entries := []map[string]any{
+		fhir.BundleEntry("Appointment", fhir.NewAppointment(patientID, slotID, practitioner, start, end)),
+		fhir.UpdateEntry("Slot", slotID, slotBody),
+	}
+	pinVersions(entries)
+
+	var apiErr error
+	var elapsed time.Duration
+
+	err = runSpinner("Booking...", func(ctx context.Context) {
+		t := time.Now()
+		_, apiErr = a.processTransaction(ctx, entries)
+		elapsed = time.Since(t)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(fmt.Errorf("booking slot: %w", apiErr))
+		PressEnter()
+		return
+	}
+
+	fmt.Printf("\n  Booked %s on %s\n", a.resolvePatientName(context.Background(), patientID), start.Local().Format("Mon Jan 02 15:04"))
+	showTiming("Created Appointment and marked Slot busy via transaction bundle", elapsed)
+	PressEnter()
+}
+
+// parseHours parses a working-hours range like "09:00-17:00" into offsets
+// from midnight.
+func parseHours(s string) (open, closeAt time.Duration, err error) {
+	var oh, om, ch, cm int
+	if _, err := fmt.Sscanf(s, "%d:%d-%d:%d", &oh, &om, &ch, &cm); err != nil {
+		return 0, 0, fmt.Errorf("use HH:MM-HH:MM")
+	}
+	open = time.Duration(oh)*time.Hour + time.Duration(om)*time.Minute
+	closeAt = time.Duration(ch)*time.Hour + time.Duration(cm)*time.Minute
+	if open >= closeAt || closeAt > 24*time.Hour {
+		return 0, 0, fmt.Errorf("closing time must be after opening time")
+	}
+	return open, closeAt, nil
+}
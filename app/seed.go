@@ -1,14 +1,18 @@
 package app
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
-	"strings"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/charmbracelet/huh"
-	"github.com/charmbracelet/huh/spinner"
 	"github.com/phenoml/phenostore-example-go/fhir"
 )
 
@@ -37,31 +41,143 @@ func obs(entry map[string]any) map[string]any {
 	return entry
 }
 
-// SeedData loads sample patients with observations, conditions, and care plans.
+// seedProfile selects which of the sample patients' resources are seeded.
+type seedProfile string
+
+const (
+	seedFullCharts   seedProfile = "full"     // every resource
+	seedProblemLists seedProfile = "problems" // patients, conditions, and flags only
+)
+
+// keeps reports whether the profile includes resources of type rt.
+func (p seedProfile) keeps(rt string) bool {
+	switch p {
+	case seedProblemLists:
+		return rt == "Patient" || rt == "Condition" || rt == "Flag"
+	}
+	return true
+}
+
+// seedPatients build the sample patients' entries, in seeding order.
+var seedPatients = []func() []map[string]any{
+	seedMariaGarcia,
+	seedWeiChen,
+	seedAlexThompson,
+	seedSarahJohnson,
+	seedJamesWilliams,
+}
+
+// buildSeedBundle returns the transaction entries for the first n sample
+// patients, limited to the resources profile keeps. It does no I/O, so the
+// seed data can be checked without a server.
+func buildSeedBundle(n int, profile seedProfile) []map[string]any {
+	var entries []map[string]any
+	for _, build := range seedPatients[:min(max(n, 0), len(seedPatients))] {
+		for _, e := range build() {
+			request, _ := e["request"].(map[string]any)
+			if rt, _ := request["url"].(string); profile.keeps(rt) {
+				entries = append(entries, e)
+			}
+		}
+	}
+
+	pointAtFullURLs(entries)
+	return entries
+}
+
+// pointAtFullURLs fixes up references between new entries. The fhir
+// builders write "Patient/" + patientID, which for a patient that is itself
+// an entry gives "Patient/urn:uuid:...". Point those at the entry's fullUrl
+// instead so the server resolves them within the bundle.
+func pointAtFullURLs(entries []map[string]any) {
+	urns := make(map[string]string)
+	for _, e := range entries {
+		request, _ := e["request"].(map[string]any)
+		rt, _ := request["url"].(string)
+		if fullURL, _ := e["fullUrl"].(string); fullURL != "" {
+			urns[rt+"/"+fullURL] = fullURL
+		}
+	}
+	for _, e := range entries {
+		var m map[string]any
+		if err := json.Unmarshal(e["resource"].(json.RawMessage), &m); err != nil {
+			continue
+		}
+		rewriteReferences(m, urns)
+		b, _ := json.Marshal(m)
+		e["resource"] = json.RawMessage(b)
+	}
+}
+
+// SeedData loads sample patients with observations, conditions, diet orders,
+// and care plans.
 func (a *App) SeedData() {
+	count := len(seedPatients)
+	profile := seedFullCharts
+	countOptions := make([]huh.Option[int], 0, len(seedPatients))
+	for n := len(seedPatients); n >= 1; n-- {
+		label := fmt.Sprintf("%d patients", n)
+		if n == 1 {
+			label = "1 patient"
+		}
+		countOptions = append(countOptions, huh.NewOption(label, n))
+	}
+
 	var confirm bool
-	err := huh.NewConfirm().
-		Title("Seed sample data?").
-		Description("Creates 5 patients with vitals, lab results, conditions, and care plans.").
-		Value(&confirm).
-		Run()
+	err := huh.NewForm(huh.NewGroup(
+		huh.NewSelect[int]().
+			Title("Sample patients").
+			Options(countOptions...).
+			Value(&count),
+		huh.NewSelect[seedProfile]().
+			Title("Charts").
+			Options(
+				huh.NewOption("Full charts: vitals, lab results, conditions, devices, diet orders, and care plans", seedFullCharts),
+				huh.NewOption("Problem lists: patients, conditions, and flags only", seedProblemLists),
+			).
+			Value(&profile),
+		huh.NewConfirm().
+			Title("Seed sample data?").
+			Value(&confirm),
+	)).Run()
 	if err != nil || !confirm {
 		return
 	}
 
-	var entries []map[string]any
-	p := func(urn string) string { return urn } // alias for readability
+	entries := buildSeedBundle(count, profile)
+
+	fmt.Println(timingStyle.Render("  Press Ctrl+C to stop; the import can be resumed later."))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	start := time.Now()
+	created, err := a.importInChunks(ctx, "seed", entries, nil)
+	elapsed := time.Since(start)
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(fmt.Errorf("seeding: %w", err))
+			PressEnter()
+		}
+		return
+	}
 
-	// --- Patient 1: Maria Garcia ---
-	// 39-year-old woman managing hypertension and anxiety. Elevated BP, on a
-	// low-sodium diet plan. Recently started therapy for anxiety.
-	p1 := p("urn:uuid:patient-1")
+	fmt.Printf("\n  Seeded %d resources (%d patients)\n", created, count)
+	showTiming(fmt.Sprintf("Created %d resources via chunked transaction bundles, up to %d at a time", created, cmp.Or(a.ChunkConcurrency, defaultChunkConcurrency)), elapsed)
+	PressEnter()
+}
+
+// seedMariaGarcia builds Maria Garcia's chart.
+// 39-year-old woman managing hypertension and anxiety. Elevated BP, on a
+// low-sodium diet plan. Recently started therapy for anxiety.
+func seedMariaGarcia() []map[string]any {
+	var entries []map[string]any
+	p1 := "urn:uuid:patient-1"
 	entries = append(entries, bundleEntryWithUrn(p1, "Patient",
 		addSeedTag(seedPatient("Maria", "Garcia", "1985-03-22", "female", "555-0101", "maria.garcia@email.com",
 			&seedAddress{line: "Rua das Flores 142", city: "Rio de Janeiro", state: "RJ", postalCode: "20040-020"}))))
 	// Vitals
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewBloodPressureObservation(p1, 142, 91))))
-	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewBloodPressureObservation(p1, 138, 88))))
+	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.WithDevice(fhir.NewBloodPressureObservation(p1, 138, 88), "urn:uuid:device-1"))))
+	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewHeightObservation(p1, 166))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewWeightObservation(p1, 68.2))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewHeartRateObservation(p1, 78))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewTemperatureObservation(p1, 36.6))))
@@ -72,8 +188,13 @@ func (a *App) SeedData() {
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewTotalCholesterolObservation(p1, 218))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewBloodGlucoseObservation(p1, 92))))
 	// Conditions
-	entries = append(entries, fhir.BundleEntry("Condition", addSeedTag(fhir.NewCondition(p1, "I10", "Essential Hypertension"))))
-	entries = append(entries, fhir.BundleEntry("Condition", addSeedTag(fhir.NewCondition(p1, "F41.1", "Generalized Anxiety Disorder"))))
+	entries = append(entries, fhir.BundleEntry("Condition", addSeedTag(seedCondition(p1, "I10", "Essential Hypertension"))))
+	entries = append(entries, fhir.BundleEntry("Condition", addSeedTag(seedCondition(p1, "F41.1", "Generalized Anxiety Disorder"))))
+	// Home devices
+	entries = append(entries, bundleEntryWithUrn("urn:uuid:device-1", "Device",
+		addSeedTag(fhir.NewDevice(p1, "70665002", "Blood pressure cuff", "BPC-20417"))))
+	// Diet orders
+	entries = append(entries, fhir.BundleEntry("NutritionOrder", addSeedTag(fhir.NewNutritionOrder(p1, "386619000", "Low sodium diet", "Limit sodium to 2 g/day"))))
 	// Care plans
 	entries = append(entries, bundleEntryWithUrn("urn:uuid:cp-1a", "CarePlan",
 		addSeedTag(carePlanWithActivities(p1, "Hypertension Management", []seedActivity{
@@ -88,16 +209,21 @@ func (a *App) SeedData() {
 			{description: "Cognitive behavioral therapy referral", status: "completed"},
 			{description: "4-week therapy check-in", status: "not-started", schedule: "By 2025-05-15"},
 		}))))
+	return entries
+}
 
-	// --- Patient 2: Wei Chen ---
-	// 32-year-old man, generally healthy. Came in for a wellness visit. Mild
-	// seasonal allergies, otherwise unremarkable. Good baseline vitals.
-	p2 := p("urn:uuid:patient-2")
+// seedWeiChen builds Wei Chen's chart.
+// 32-year-old man, generally healthy. Came in for a wellness visit. Mild
+// seasonal allergies, otherwise unremarkable. Good baseline vitals.
+func seedWeiChen() []map[string]any {
+	var entries []map[string]any
+	p2 := "urn:uuid:patient-2"
 	entries = append(entries, bundleEntryWithUrn(p2, "Patient",
 		addSeedTag(seedPatient("Wei", "Chen", "1992-07-14", "male", "555-0202", "",
 			&seedAddress{line: "Av. Atlântica 1702", city: "Rio de Janeiro", state: "RJ", postalCode: "22021-001"}))))
 	// Vitals
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewBloodPressureObservation(p2, 118, 76))))
+	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewHeightObservation(p2, 182))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewWeightObservation(p2, 79.5))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewHeartRateObservation(p2, 68))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewTemperatureObservation(p2, 36.5))))
@@ -108,7 +234,9 @@ func (a *App) SeedData() {
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewTotalCholesterolObservation(p2, 185))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewBloodGlucoseObservation(p2, 88))))
 	// Conditions
-	entries = append(entries, fhir.BundleEntry("Condition", addSeedTag(fhir.NewCondition(p2, "J30.2", "Seasonal Allergic Rhinitis"))))
+	entries = append(entries, fhir.BundleEntry("Condition", addSeedTag(seedCondition(p2, "J30.2", "Seasonal Allergic Rhinitis"))))
+	// Flags
+	entries = append(entries, fhir.BundleEntry("Flag", addSeedTag(fhir.NewFlag(p2, "Interpreter needed: Mandarin"))))
 	// Care plans
 	entries = append(entries, bundleEntryWithUrn("urn:uuid:cp-2", "CarePlan",
 		addSeedTag(carePlanWithActivities(p2, "Annual Wellness", []seedActivity{
@@ -117,17 +245,22 @@ func (a *App) SeedData() {
 			{description: "Flu vaccination", status: "not-started", schedule: "By 2025-10-01"},
 			{description: "Schedule next annual physical", status: "not-started", schedule: "By 2026-03-01"},
 		}))))
+	return entries
+}
 
-	// --- Patient 3: Alex Thompson ---
-	// 47-year-old non-binary patient with multiple comorbidities — diabetes,
-	// hypertension, and obesity. Complex care needs with two active plans.
-	p3 := p("urn:uuid:patient-3")
+// seedAlexThompson builds Alex Thompson's chart.
+// 47-year-old non-binary patient with multiple comorbidities — diabetes,
+// hypertension, and obesity. Complex care needs with two active plans.
+func seedAlexThompson() []map[string]any {
+	var entries []map[string]any
+	p3 := "urn:uuid:patient-3"
 	entries = append(entries, bundleEntryWithUrn(p3, "Patient",
 		addSeedTag(seedPatient("Alex", "Thompson", "1978-11-03", "other", "555-0303", "alex.t@email.com",
 			&seedAddress{line: "Rua Visconde de Pirajá 330", city: "Rio de Janeiro", state: "RJ", postalCode: "22410-002"}))))
 	// Vitals
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewBloodPressureObservation(p3, 148, 94))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewBloodPressureObservation(p3, 145, 92))))
+	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewHeightObservation(p3, 168))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewWeightObservation(p3, 104.3))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewWeightObservation(p3, 101.8))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewHeartRateObservation(p3, 88))))
@@ -137,13 +270,18 @@ func (a *App) SeedData() {
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewBMIObservation(p3, 36.2))))
 	// Labs
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewHbA1cObservation(p3, 7.8))))
-	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewBloodGlucoseObservation(p3, 156))))
+	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.WithDevice(fhir.NewBloodGlucoseObservation(p3, 156), "urn:uuid:device-3"))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewTotalCholesterolObservation(p3, 242))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewCreatinineObservation(p3, 1.1))))
 	// Conditions
-	entries = append(entries, fhir.BundleEntry("Condition", addSeedTag(fhir.NewCondition(p3, "E11.9", "Type 2 Diabetes Mellitus"))))
-	entries = append(entries, fhir.BundleEntry("Condition", addSeedTag(fhir.NewCondition(p3, "I10", "Essential Hypertension"))))
-	entries = append(entries, fhir.BundleEntry("Condition", addSeedTag(fhir.NewCondition(p3, "E66.01", "Morbid Obesity due to Excess Calories"))))
+	entries = append(entries, fhir.BundleEntry("Condition", addSeedTag(seedCondition(p3, "E11.9", "Type 2 Diabetes Mellitus"))))
+	entries = append(entries, fhir.BundleEntry("Condition", addSeedTag(seedCondition(p3, "I10", "Essential Hypertension"))))
+	entries = append(entries, fhir.BundleEntry("Condition", addSeedTag(seedCondition(p3, "E66.01", "Morbid Obesity due to Excess Calories"))))
+	// Home devices
+	entries = append(entries, bundleEntryWithUrn("urn:uuid:device-3", "Device",
+		addSeedTag(fhir.NewDevice(p3, "337414009", "Blood glucose meter", "GLU-88213"))))
+	// Diet orders
+	entries = append(entries, fhir.BundleEntry("NutritionOrder", addSeedTag(fhir.NewNutritionOrder(p3, "160670007", "Diabetic diet", "Consistent carbohydrate intake, 45-60 g per meal"))))
 	// Care plans
 	entries = append(entries, bundleEntryWithUrn("urn:uuid:cp-3a", "CarePlan",
 		addSeedTag(carePlanWithActivities(p3, "Diabetes Care Plan", []seedActivity{
@@ -160,16 +298,23 @@ func (a *App) SeedData() {
 			{description: "Monthly weigh-in and progress review", status: "not-started", schedule: "By 2025-05-01"},
 			{description: "Evaluate for bariatric surgery referral if <5% loss in 6 months", status: "not-started", schedule: "By 2025-10-01"},
 		}))))
+	return entries
+}
 
-	// --- Patient 4: Sarah Johnson ---
-	// 23-year-old college athlete getting sports clearance. Excellent vitals.
-	// Mild exercise-induced asthma, well-controlled. Mostly done with her plan.
-	p4 := p("urn:uuid:patient-4")
+// seedSarahJohnson builds Sarah Johnson's chart.
+// 23-year-old college athlete getting sports clearance. Excellent vitals.
+// Mild exercise-induced asthma, well-controlled. Mostly done with her plan.
+// A well-known athlete, so her chart is restricted and opening it asks for
+// a break-the-glass reason.
+func seedSarahJohnson() []map[string]any {
+	var entries []map[string]any
+	p4 := "urn:uuid:patient-4"
 	entries = append(entries, bundleEntryWithUrn(p4, "Patient",
-		addSeedTag(seedPatient("Sarah", "Johnson", "2001-05-28", "female", "", "sarah.j@university.edu",
-			&seedAddress{line: "Rua Jardim Botânico 920", city: "Rio de Janeiro", state: "RJ", postalCode: "22460-030"}))))
+		fhir.WithRestricted(addSeedTag(seedPatient("Sarah", "Johnson", "2001-05-28", "female", "", "sarah.j@university.edu",
+			&seedAddress{line: "Rua Jardim Botânico 920", city: "Rio de Janeiro", state: "RJ", postalCode: "22460-030"})), true)))
 	// Vitals
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewBloodPressureObservation(p4, 108, 68))))
+	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewHeightObservation(p4, 170))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewWeightObservation(p4, 61.2))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewHeartRateObservation(p4, 52))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewTemperatureObservation(p4, 36.4))))
@@ -177,7 +322,7 @@ func (a *App) SeedData() {
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewRespiratoryRateObservation(p4, 12))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewBMIObservation(p4, 21.3))))
 	// Conditions
-	entries = append(entries, fhir.BundleEntry("Condition", addSeedTag(fhir.NewCondition(p4, "J45.990", "Exercise-Induced Bronchospasm"))))
+	entries = append(entries, fhir.BundleEntry("Condition", addSeedTag(seedCondition(p4, "J45.990", "Exercise-Induced Bronchospasm"))))
 	// Care plans
 	entries = append(entries, bundleEntryWithUrn("urn:uuid:cp-4", "CarePlan",
 		addSeedTag(carePlanWithActivities(p4, "Sports Clearance", []seedActivity{
@@ -186,17 +331,22 @@ func (a *App) SeedData() {
 			{description: "Pulmonary function test", status: "completed"},
 			{description: "Rescue inhaler prescription renewal", status: "not-started", schedule: "By 2025-08-01"},
 		}))))
+	return entries
+}
 
-	// --- Patient 5: James Williams ---
-	// 60-year-old man with chronic kidney disease, hypertension, and high
-	// cholesterol. Multiple specialists involved. Highest-acuity patient.
-	p5 := p("urn:uuid:patient-5")
+// seedJamesWilliams builds James Williams's chart.
+// 60-year-old man with chronic kidney disease, hypertension, and high
+// cholesterol. Multiple specialists involved. Highest-acuity patient.
+func seedJamesWilliams() []map[string]any {
+	var entries []map[string]any
+	p5 := "urn:uuid:patient-5"
 	entries = append(entries, bundleEntryWithUrn(p5, "Patient",
 		addSeedTag(seedPatient("James", "Williams", "1965-09-10", "male", "555-0505", "jwilliams@email.com",
 			&seedAddress{line: "Av. Niemeyer 776", city: "Rio de Janeiro", state: "RJ", postalCode: "22450-221"}))))
 	// Vitals
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewBloodPressureObservation(p5, 162, 99))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewBloodPressureObservation(p5, 155, 96))))
+	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewHeightObservation(p5, 176))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewWeightObservation(p5, 88.4))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewHeartRateObservation(p5, 82))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewTemperatureObservation(p5, 36.7))))
@@ -209,9 +359,13 @@ func (a *App) SeedData() {
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewTotalCholesterolObservation(p5, 261))))
 	entries = append(entries, obs(fhir.BundleEntry("Observation", fhir.NewBloodGlucoseObservation(p5, 108))))
 	// Conditions
-	entries = append(entries, fhir.BundleEntry("Condition", addSeedTag(fhir.NewCondition(p5, "I10", "Essential Hypertension"))))
-	entries = append(entries, fhir.BundleEntry("Condition", addSeedTag(fhir.NewCondition(p5, "N18.3", "Chronic Kidney Disease, Stage 3"))))
-	entries = append(entries, fhir.BundleEntry("Condition", addSeedTag(fhir.NewCondition(p5, "E78.5", "Hyperlipidemia, Unspecified"))))
+	entries = append(entries, fhir.BundleEntry("Condition", addSeedTag(seedCondition(p5, "I10", "Essential Hypertension"))))
+	entries = append(entries, fhir.BundleEntry("Condition", addSeedTag(seedCondition(p5, "N18.3", "Chronic Kidney Disease, Stage 3"))))
+	entries = append(entries, fhir.BundleEntry("Condition", addSeedTag(seedCondition(p5, "E78.5", "Hyperlipidemia, Unspecified"))))
+	// Flags
+	entries = append(entries, fhir.BundleEntry("Flag", addSeedTag(fhir.NewFlag(p5, "Fall risk"))))
+	// Diet orders
+	entries = append(entries, fhir.BundleEntry("NutritionOrder", addSeedTag(fhir.NewNutritionOrder(p5, "33489005", "Renal diet", "Low protein, low sodium; limit potassium and phosphorus"))))
 	// Care plans
 	entries = append(entries, bundleEntryWithUrn("urn:uuid:cp-5a", "CarePlan",
 		addSeedTag(carePlanWithActivities(p5, "CKD Monitoring", []seedActivity{
@@ -227,47 +381,7 @@ func (a *App) SeedData() {
 			{description: "Recheck lipids in 6 weeks", status: "not-started", schedule: "By 2025-05-15"},
 			{description: "Cardiology consult for stress test", status: "not-started", schedule: "By 2025-06-01"},
 		}))))
-
-	bundle := fhir.TransactionBundle(entries)
-
-	var created int
-	var apiErr error
-	var elapsed time.Duration
-
-	err = spinner.New().
-		Title("Seeding sample data...").
-		Action(func() {
-			start := time.Now()
-			result, err := a.Client.ProcessBundle(context.Background(), bundle)
-			elapsed = time.Since(start)
-			if err != nil {
-				apiErr = err
-				return
-			}
-			if result.Entry != nil {
-				for _, entry := range *result.Entry {
-					if entry.Response != nil && entry.Response.Status != nil && strings.HasPrefix(*entry.Response.Status, "201") {
-						created++
-					}
-				}
-			}
-		}).
-		Run()
-
-	if err != nil {
-		ShowError(err)
-		PressEnter()
-		return
-	}
-	if apiErr != nil {
-		ShowError(fmt.Errorf("processing bundle: %w", apiErr))
-		PressEnter()
-		return
-	}
-
-	fmt.Printf("\n  Seeded %d resources (5 patients with vitals, labs, conditions, and care plans)\n", created)
-	showTiming(fmt.Sprintf("Created %d resources via transaction bundle", created), elapsed)
-	PressEnter()
+	return entries
 }
 
 type seedAddress struct {
@@ -277,6 +391,16 @@ type seedAddress struct {
 	postalCode string
 }
 
+// seedCondition builds a Condition coded in ICD-10 and, where the concept is
+// in snomedForICD10, SNOMED CT too.
+func seedCondition(patientID, icd10Code, display string) json.RawMessage {
+	c := fhir.NewCondition(patientID, icd10Code, display)
+	if concept, ok := snomedForICD10[icd10Code]; ok {
+		c = fhir.WithSNOMED(c, concept.code, concept.term)
+	}
+	return c
+}
+
 // seedPatient builds a Patient resource with optional contact info and address.
 func seedPatient(given, family, dob, gender, phone, email string, addr *seedAddress) json.RawMessage {
 	p := map[string]any{
@@ -373,53 +497,119 @@ func (a *App) DeleteSeedData() {
 	if err != nil || !confirm {
 		return
 	}
-
-	ctx := context.Background()
-	var deleted int
-	var apiErr error
-	var elapsed time.Duration
-
-	// Delete dependents before patients to avoid referential issues.
-	resourceTypes := []string{"CarePlan", "Observation", "Condition", "Patient"}
-
-	err = spinner.New().
-		Title("Deleting seed data...").
-		Action(func() {
-			start := time.Now()
-			for _, rt := range resourceTypes {
-				ids, err := a.searchByTag(ctx, rt, seedTagQuery)
-				if err != nil {
-					apiErr = err
-					return
-				}
-				for _, id := range ids {
-					if err := a.Client.DeleteResource(ctx, rt, id); err != nil {
-						apiErr = fmt.Errorf("deleting %s/%s: %w", rt, id, err)
-						return
-					}
-					deleted++
-				}
-			}
-			elapsed = time.Since(start)
-		}).
-		Run()
-
+	ok, err := confirmWithCode("Delete all seed data")
 	if err != nil {
 		ShowError(err)
 		PressEnter()
 		return
 	}
-	if apiErr != nil {
-		ShowError(apiErr)
-		PressEnter()
+	if !ok {
 		return
 	}
 
+	workers := cmp.Or(a.DeleteConcurrency, defaultDeleteConcurrency)
+	var deleted int
+	start := time.Now()
+
+	// Delete dependents before patients to avoid referential issues. Types go
+	// one at a time, and each type's resources in parallel.
+	resourceTypes := []string{"CarePlan", "NutritionOrder", "Flag", "Observation", "Device", "Condition", "Patient"}
+
+	fmt.Println()
+	for _, rt := range resourceTypes {
+		var n int
+		var apiErr error
+		var elapsed time.Duration
+		err = runSpinner(fmt.Sprintf("Deleting seed %s resources (%d at a time)...", rt, workers), func(ctx context.Context) {
+			typeStart := time.Now()
+			ids, err := a.searchByTag(ctx, rt, seedTagQuery)
+			if err != nil {
+				apiErr = err
+				return
+			}
+			n, apiErr = a.deleteAll(ctx, rt, ids, workers)
+			elapsed = time.Since(typeStart)
+		})
+		deleted += n
+
+		if err != nil {
+			ShowError(err)
+			PressEnter()
+			return
+		}
+		if apiErr != nil {
+			ShowError(fmt.Errorf("%w (%d resources deleted before the error)", apiErr, deleted))
+			PressEnter()
+			return
+		}
+		if n > 0 {
+			fmt.Printf("  %-15s %4d deleted %s\n", rt, n, timingStyle.Render(elapsed.Round(time.Millisecond).String()))
+		}
+	}
+
 	if deleted == 0 {
-		fmt.Println("\n  No seed data found.")
+		fmt.Println("  No seed data found.")
 	} else {
 		fmt.Printf("\n  Deleted %d seed resources.\n", deleted)
-		showTiming(fmt.Sprintf("Deleted %d resources", deleted), elapsed)
+		showTiming(fmt.Sprintf("Deleted %d resources, %d at a time", deleted, workers), time.Since(start))
 	}
 	PressEnter()
 }
+
+// defaultDeleteConcurrency is how many deletes DeleteSeedData runs at once
+// when PHENOSTORE_DELETE_CONCURRENCY is not set.
+const defaultDeleteConcurrency = 8
+
+// parseDeleteConcurrency parses PHENOSTORE_DELETE_CONCURRENCY, returning
+// the default when it is empty.
+func parseDeleteConcurrency(v string) (int, error) {
+	if v == "" {
+		return defaultDeleteConcurrency, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > 64 {
+		return 0, fmt.Errorf("invalid PHENOSTORE_DELETE_CONCURRENCY %q: must be a whole number from 1 to 64", v)
+	}
+	return n, nil
+}
+
+// deleteAll deletes the resources of one type with the given IDs, at most
+// workers at a time. It stops handing out deletes at the first failure and
+// returns how many succeeded, along with that error. Deletes already sent
+// are left to finish rather than cancelled: the store may apply a delete
+// whose request was cancelled, and the count would then miss it.
+func (a *App) deleteAll(ctx context.Context, resourceType string, ids []string, workers int) (int, error) {
+	queue := make(chan string)
+	stop := make(chan struct{})
+	var deleted atomic.Int64
+	var once sync.Once
+	var firstErr error
+	var wg sync.WaitGroup
+	for range min(workers, len(ids)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range queue {
+				if err := a.deleteResource(ctx, resourceType, id); err != nil {
+					once.Do(func() {
+						firstErr = fmt.Errorf("deleting %s/%s: %w", resourceType, id, err)
+						close(stop)
+					})
+					continue
+				}
+				deleted.Add(1)
+			}
+		}()
+	}
+feed:
+	for _, id := range ids {
+		select {
+		case queue <- id:
+		case <-stop:
+			break feed
+		}
+	}
+	close(queue)
+	wg.Wait()
+	return int(deleted.Load()), firstErr
+}
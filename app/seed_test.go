@@ -0,0 +1,184 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+func seedResourceCounts(entries []map[string]any) map[string]int {
+	counts := make(map[string]int)
+	for _, e := range entries {
+		request, _ := e["request"].(map[string]any)
+		rt, _ := request["url"].(string)
+		counts[rt]++
+	}
+	return counts
+}
+
+func TestBuildSeedBundleCounts(t *testing.T) {
+	tests := []struct {
+		name    string
+		n       int
+		profile seedProfile
+		want    map[string]int
+	}{
+		{"all patients, full charts", 5, seedFullCharts, map[string]int{
+			"Patient": 5, "Observation": 56, "Condition": 10, "Flag": 2,
+			"Device": 2, "NutritionOrder": 3, "CarePlan": 8,
+		}},
+		{"one patient, full charts", 1, seedFullCharts, map[string]int{
+			"Patient": 1, "Observation": 11, "Condition": 2,
+			"Device": 1, "NutritionOrder": 1, "CarePlan": 2,
+		}},
+		{"all patients, problem lists", 5, seedProblemLists, map[string]int{
+			"Patient": 5, "Condition": 10, "Flag": 2,
+		}},
+		{"more patients than exist", 99, seedProblemLists, map[string]int{
+			"Patient": 5, "Condition": 10, "Flag": 2,
+		}},
+		{"no patients", 0, seedFullCharts, map[string]int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := seedResourceCounts(buildSeedBundle(tt.n, tt.profile))
+			for rt, want := range tt.want {
+				if got[rt] != want {
+					t.Errorf("%s entries = %d, want %d", rt, got[rt], want)
+				}
+			}
+			for rt, n := range got {
+				if _, ok := tt.want[rt]; !ok {
+					t.Errorf("unexpected %d %s entries", n, rt)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildSeedBundleTags(t *testing.T) {
+	system, code, _ := strings.Cut(seedTagQuery, "|")
+	for i, e := range buildSeedBundle(len(seedPatients), seedFullCharts) {
+		m, err := fhir.Parse(e["resource"].(json.RawMessage))
+		if err != nil {
+			t.Fatalf("entry %d: %v", i, err)
+		}
+		tagged := false
+		meta, _ := m["meta"].(map[string]any)
+		tags, _ := meta["tag"].([]any)
+		for _, tag := range tags {
+			tm, _ := tag.(map[string]any)
+			if mapStr(tm, "system") == system && mapStr(tm, "code") == code {
+				tagged = true
+			}
+		}
+		if !tagged {
+			t.Errorf("entry %d (%s) has no %s tag", i, mapStr(m, "resourceType"), seedTagQuery)
+		}
+	}
+}
+
+func TestBuildSeedBundleReferences(t *testing.T) {
+	for _, profile := range []seedProfile{seedFullCharts, seedProblemLists} {
+		for n := 1; n <= len(seedPatients); n++ {
+			refs, local := fhir.BundleReferences(buildSeedBundle(n, profile))
+			if len(refs) == 0 {
+				t.Fatalf("%s/%d: no references found", profile, n)
+			}
+			for _, r := range refs {
+				if !r.IsURN() || !local[r.Reference] {
+					t.Errorf("%s/%d: %s does not resolve within the bundle", profile, n, r)
+				}
+			}
+		}
+	}
+}
+
+func TestCheckReferencesAcceptsSeedBundles(t *testing.T) {
+	a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("checkReferences looked up %s; every seed reference should resolve within the bundle", r.URL)
+		fmt.Fprint(w, `{"resourceType":"Bundle","type":"searchset"}`)
+	})
+	bundles := map[string][]map[string]any{
+		"full charts":   buildSeedBundle(len(seedPatients), seedFullCharts),
+		"problem lists": buildSeedBundle(len(seedPatients), seedProblemLists),
+		"load test":     buildLoadTestBundle(3, 4),
+	}
+	for name, entries := range bundles {
+		if err := a.checkReferences(context.Background(), entries); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestBuildSeedBundleJSON(t *testing.T) {
+	for i, e := range buildSeedBundle(len(seedPatients), seedFullCharts) {
+		raw, ok := e["resource"].(json.RawMessage)
+		if !ok || !json.Valid(raw) {
+			t.Errorf("entry %d: resource is not valid JSON", i)
+			continue
+		}
+		m, _ := fhir.Parse(raw)
+		request, _ := e["request"].(map[string]any)
+		if rt := mapStr(m, "resourceType"); rt == "" || rt != request["url"] {
+			t.Errorf("entry %d: resourceType %q does not match request url %v", i, rt, request["url"])
+		}
+		if request["method"] != "POST" {
+			t.Errorf("entry %d: method %v, want POST", i, request["method"])
+		}
+	}
+	if _, err := json.Marshal(map[string]any{"resourceType": "Bundle", "type": "transaction", "entry": buildSeedBundle(len(seedPatients), seedFullCharts)}); err != nil {
+		t.Errorf("marshalling bundle: %v", err)
+	}
+	if err := (&App{UCUM: UCUMStrict}).checkUnits(buildSeedBundle(len(seedPatients), seedFullCharts)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDeleteAll(t *testing.T) {
+	var inFlight, maxInFlight, deletes atomic.Int64
+	a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for m := maxInFlight.Load(); n > m && !maxInFlight.CompareAndSwap(m, n); m = maxInFlight.Load() {
+		}
+		time.Sleep(5 * time.Millisecond)
+		if path.Base(r.URL.Path) == "bad" {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"resourceType":"OperationOutcome","issue":[{"severity":"error","code":"conflict"}]}`)
+			return
+		}
+		deletes.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	ids := make([]string, 20)
+	for i := range ids {
+		ids[i] = strconv.Itoa(i)
+	}
+	n, err := a.deleteAll(context.Background(), "Observation", ids, 4)
+	if err != nil || n != 20 {
+		t.Fatalf("deleteAll = %d, %v; want 20, nil", n, err)
+	}
+	if m := maxInFlight.Load(); m > 4 || m < 2 {
+		t.Errorf("max deletes in flight = %d, want 2 to 4", m)
+	}
+
+	deletes.Store(0)
+	n, err = a.deleteAll(context.Background(), "Observation", append([]string{"bad"}, ids...), 2)
+	if err == nil {
+		t.Fatal("deleteAll with a failing delete: no error")
+	}
+	if n >= 20 || int64(n) != deletes.Load() {
+		t.Errorf("after a failure: deleted %d (server saw %d), want fewer than 20", n, deletes.Load())
+	}
+}
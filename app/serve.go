@@ -0,0 +1,255 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+	"github.com/phenoml/phenostore-sdk-go/phenostore"
+)
+
+// patientListItem is the compact patient shape returned by GET /patients.
+type patientListItem struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Gender     string `json:"gender"`
+	BirthDate  string `json:"birthDate"`
+	Restricted bool   `json:"restricted,omitempty"`
+}
+
+// breakGlassHeader carries the reason for opening a restricted patient's
+// chart through the API.
+const breakGlassHeader = "X-Break-Glass-Reason"
+
+// clinicStats is the response body of GET /stats.
+type clinicStats struct {
+	Patients                  int `json:"patients"`
+	ActiveCarePlans           int `json:"activeCarePlans"`
+	OverdueActivities         int `json:"overdueActivities"`
+	PatientsWithoutActivePlan int `json:"patientsWithoutActivePlan"`
+}
+
+// ServeConfig controls the JSON API.
+type ServeConfig struct {
+	// Addr is the address the API listens on.
+	Addr string
+	// Deidentify serves pseudonymized patients with shifted dates, keeping
+	// resource IDs so clients can still follow links. The portal view,
+	// which is written for the patient themselves, is not served.
+	Deidentify bool
+}
+
+// apiServer serves the JSON API for an App.
+type apiServer struct {
+	*App
+	deid *fhir.Deidentifier // nil unless the API is de-identified
+}
+
+// Serve runs a small read-only JSON API on cfg.Addr until ctx is cancelled:
+//
+//	GET /patients               compact patient list
+//	GET /patients/{id}/summary  patient with observations, conditions, and care plans
+//	GET /patients/{id}/context  flattened chart context (?format=text for plain text)
+//	GET /patients/{id}/portal   plain-language view for the patient
+//	GET /stats                  clinic-wide counts and care gaps
+//
+// A restricted patient's chart is only served with a reason in the
+// X-Break-Glass-Reason header; see openChart.
+func (a *App) Serve(ctx context.Context, cfg ServeConfig) error {
+	api := &apiServer{App: a}
+	if cfg.Deidentify {
+		// One Deidentifier for the server's lifetime keeps pseudonyms and
+		// dates consistent across requests.
+		api.deid = newDeidentifier()
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /patients", api.handlePatients)
+	mux.HandleFunc("GET /patients/{id}/summary", api.handleSummary)
+	mux.HandleFunc("GET /patients/{id}/context", api.handleContext)
+	if api.deid == nil {
+		mux.HandleFunc("GET /patients/{id}/portal", api.handlePortal)
+	}
+	mux.HandleFunc("GET /stats", api.handleStats)
+
+	srv := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           logRequests(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		log.Printf("serving on %s", cfg.Addr)
+		errc <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
+
+func (a *apiServer) handlePatients(w http.ResponseWriter, r *http.Request) {
+	patients, err := a.fetchAllPatients(r.Context())
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	items := make([]patientListItem, 0, len(patients))
+	for _, raw := range patients {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		item := patientListItem{
+			ID:         mapStr(m, "id"),
+			Name:       fhir.PatientName(m),
+			Gender:     mapStr(m, "gender"),
+			BirthDate:  mapStr(m, "birthDate"),
+			Restricted: fhir.IsRestricted(m),
+		}
+		if a.deid != nil {
+			item.Name = a.deid.Pseudonym(item.ID)
+			item.BirthDate = a.deid.Date(item.BirthDate)
+		}
+		items = append(items, item)
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (a *apiServer) handleSummary(w http.ResponseWriter, r *http.Request) {
+	if !a.openChart(w, r) {
+		return
+	}
+	summary, err := a.LoadSummary(r.Context(), r.PathValue("id"))
+	if errors.Is(err, ErrPatientNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	if a.deid != nil {
+		summary.deidentify(a.deid)
+	}
+	writeJSON(w, http.StatusOK, summary)
+}
+
+func (a *apiServer) handleContext(w http.ResponseWriter, r *http.Request) {
+	if !a.openChart(w, r) {
+		return
+	}
+	chart, err := a.LoadChartContext(r.Context(), r.PathValue("id"), a.deid)
+	if errors.Is(err, ErrPatientNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	if r.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, chart.Text())
+		return
+	}
+	writeJSON(w, http.StatusOK, chart)
+}
+
+func (a *apiServer) handlePortal(w http.ResponseWriter, r *http.Request) {
+	if !a.openChart(w, r) {
+		return
+	}
+	view, err := a.LoadPatientView(r.Context(), r.PathValue("id"))
+	if errors.Is(err, ErrPatientNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, view)
+}
+
+// openChart checks that the chart of the patient in the request path may be
+// served. A restricted chart needs a reason in breakGlassHeader, which is
+// recorded as a break-the-glass AuditEvent first; every request gives its
+// own, since API clients are not the session that gave an earlier one. If
+// the chart may not be served, openChart writes the error response and
+// returns false.
+func (a *apiServer) openChart(w http.ResponseWriter, r *http.Request) bool {
+	patientID := r.PathValue("id")
+	raw, err := a.Client.ReadResource(r.Context(), "Patient", patientID)
+	if phenostore.IsNotFound(err) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%w: %s", ErrPatientNotFound, patientID))
+		return false
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Errorf("reading patient: %w", err))
+		return false
+	}
+	m, err := fhir.Parse(raw)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Errorf("parsing patient: %w", err))
+		return false
+	}
+	if !fhir.IsRestricted(m) {
+		return true
+	}
+	reason := strings.TrimSpace(r.Header.Get(breakGlassHeader))
+	if reason == "" {
+		writeError(w, http.StatusForbidden, fmt.Errorf("chart is restricted; give a reason in the %s header", breakGlassHeader))
+		return false
+	}
+	if err := a.recordBreakGlass(r.Context(), patientID, reason); err != nil {
+		writeError(w, http.StatusBadGateway, fmt.Errorf("%w; the chart was not served", err))
+		return false
+	}
+	return true
+}
+
+func (a *apiServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	report, err := a.buildCareGapReport(r.Context(), time.Now(), nil)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, clinicStats{
+		Patients:                  report.Patients,
+		ActiveCarePlans:           report.ActivePlans,
+		OverdueActivities:         len(report.Overdue),
+		PatientsWithoutActivePlan: len(report.PatientsWithout),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next.ServeHTTP(w, r)
+		log.Printf("%s %s (%s)", r.Method, r.URL.Path, time.Since(start).Round(time.Millisecond))
+	})
+}
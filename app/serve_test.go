@@ -0,0 +1,69 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+func TestOpenChart(t *testing.T) {
+	var reasons []string
+	a := newTestApp(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			var m map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&m)
+			reason, _ := fhir.BreakGlassReason(m)
+			reasons = append(reasons, reason)
+			m["id"] = "ae1"
+			_ = json.NewEncoder(w).Encode(m)
+			return
+		}
+		switch id := path.Base(r.URL.Path); id {
+		case "p1":
+			w.Write(fhir.WithRestricted(json.RawMessage(`{"resourceType":"Patient","id":"p1"}`), true))
+		case "p2":
+			fmt.Fprint(w, `{"resourceType":"Patient","id":"p2"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"resourceType":"OperationOutcome","issue":[{"severity":"error","code":"not-found"}]}`)
+		}
+	})
+	api := &apiServer{App: a}
+
+	open := func(id, reason string) int {
+		r := httptest.NewRequest(http.MethodGet, "/patients/"+id+"/summary", nil)
+		r.SetPathValue("id", id)
+		if reason != "" {
+			r.Header.Set(breakGlassHeader, reason)
+		}
+		w := httptest.NewRecorder()
+		if api.openChart(w, r) {
+			return http.StatusOK
+		}
+		return w.Code
+	}
+
+	if got := open("p2", ""); got != http.StatusOK {
+		t.Errorf("unrestricted chart: status %d, want 200", got)
+	}
+	if got := open("p1", ""); got != http.StatusForbidden {
+		t.Errorf("restricted chart without a reason: status %d, want 403", got)
+	}
+	if got := open("p1", "Emergency treatment"); got != http.StatusOK {
+		t.Errorf("restricted chart with a reason: status %d, want 200", got)
+	}
+	if got := open("p1", ""); got != http.StatusForbidden {
+		t.Errorf("restricted chart after an earlier reason: status %d, want 403", got)
+	}
+	if got := open("p9", ""); got != http.StatusNotFound {
+		t.Errorf("unknown patient: status %d, want 404", got)
+	}
+	if len(reasons) != 1 || reasons[0] != "Emergency treatment" {
+		t.Errorf("recorded reasons = %q, want one break-the-glass AuditEvent", reasons)
+	}
+}
@@ -0,0 +1,137 @@
+package app
+
+import (
+	"sync"
+	"time"
+)
+
+// session is the state an App builds up while it runs, as opposed to the
+// configuration in App's exported fields. API mode's handlers and
+// LoadSummary's goroutines share one App, so everything here is safe for
+// concurrent use.
+type session struct {
+	access  accessLog // has its own lock
+	started time.Time // set by Initialize, and not changed after
+
+	mu          sync.Mutex
+	brokenGlass map[string]time.Time  // restricted charts opened, by patient ID
+	fullPatient int                   // average size of a full Patient, once measured
+	names       map[string]cachedName // patient names by ID, for resolvePatientName
+	patients    *patientPrefetch      // the patient list PickPatient shows next
+}
+
+// cachedName is a patient's name and when it was read.
+type cachedName struct {
+	name string
+	at   time.Time
+}
+
+// patientName returns a patient's cached name if it was read within
+// patientNameTTL of now.
+func (s *session) patientName(patientID string, now time.Time) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.names[patientID]
+	if !ok || now.Sub(c.at) >= patientNameTTL {
+		return "", false
+	}
+	return c.name, true
+}
+
+// setPatientName caches a patient's name, read at now.
+func (s *session) setPatientName(patientID, name string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.names == nil {
+		s.names = make(map[string]cachedName)
+	}
+	s.names[patientID] = cachedName{name, now}
+}
+
+// forgetPatientName drops a patient's cached name, e.g. once they have been
+// updated or deleted.
+func (s *session) forgetPatientName(patientID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.names, patientID)
+}
+
+// startPatientPrefetch returns a new patientPrefetch for the caller to fill
+// in, or false if the current one is still fresh at now and has not failed.
+func (s *session) startPatientPrefetch(now time.Time) (*patientPrefetch, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p := s.patients; p != nil && now.Sub(p.at) < patientListTTL {
+		select {
+		case <-p.done:
+			if p.err == nil {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+	}
+	s.patients = &patientPrefetch{done: make(chan struct{}), at: now}
+	return s.patients, true
+}
+
+// patientPrefetch returns the prefetched patient list if it was started
+// within patientListTTL of now, finished or not.
+func (s *session) patientPrefetch(now time.Time) *patientPrefetch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.patients == nil || now.Sub(s.patients.at) >= patientListTTL {
+		return nil
+	}
+	return s.patients
+}
+
+// forgetPatientList drops the prefetched patient list, e.g. once a patient
+// has been created, updated, or deleted.
+func (s *session) forgetPatientList() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.patients = nil
+}
+
+// patientSize returns the average size of a full Patient resource, or 0 if
+// it has not been measured.
+func (s *session) patientSize() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fullPatient
+}
+
+// setPatientSize records the average size of a full Patient resource.
+func (s *session) setPatientSize(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.fullPatient = n
+}
+
+// glassOpen reports whether a break-the-glass reason for the patient was
+// given within breakGlassTTL of now.
+func (s *session) glassOpen(patientID string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.brokenGlass[patientID]
+	return ok && now.Sub(t) < breakGlassTTL
+}
+
+// openGlass records that a break-the-glass reason was given at t.
+func (s *session) openGlass(patientID string, t time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.brokenGlass == nil {
+		s.brokenGlass = make(map[string]time.Time)
+	}
+	s.brokenGlass[patientID] = t
+}
+
+// closeGlass forgets a patient's break-the-glass reason, e.g. once their
+// chart is no longer restricted.
+func (s *session) closeGlass(patientID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.brokenGlass, patientID)
+}
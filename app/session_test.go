@@ -0,0 +1,113 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// These tests share one App or one queue file between goroutines the way
+// API mode and LoadSummary do. Run them with -race.
+
+func TestSessionConcurrentUse(t *testing.T) {
+	a := &App{}
+	ctx := context.Background()
+	const workers, rounds = 8, 50
+
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			patientID := fmt.Sprintf("p%d", w)
+			results := []json.RawMessage{json.RawMessage(`{"resourceType":"Observation","subject":{"reference":"Patient/` + patientID + `"}}`)}
+			for range rounds {
+				a.audit(ctx, fhir.AuditRead, "read", "Patient", "Patient/"+patientID, patientID, nil)
+				a.logResultAccess("Observation", results, nil)
+				a.session.openGlass(patientID, time.Now())
+				if !a.session.glassOpen(patientID, time.Now()) {
+					t.Errorf("%s: glass not open right after opening it", patientID)
+				}
+				a.session.closeGlass(patientID)
+				a.session.access.forPatient(patientID)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for w := range workers {
+		entries, _ := a.session.access.forPatient(fmt.Sprintf("p%d", w))
+		if len(entries) != 2*rounds {
+			t.Errorf("p%d: %d access entries, want %d", w, len(entries), 2*rounds)
+		}
+	}
+}
+
+func TestGlassOpenExpires(t *testing.T) {
+	var s session
+	opened := time.Now()
+	s.openGlass("p1", opened)
+	if !s.glassOpen("p1", opened.Add(breakGlassTTL-time.Second)) {
+		t.Error("glass closed before breakGlassTTL")
+	}
+	if s.glassOpen("p1", opened.Add(breakGlassTTL)) {
+		t.Error("glass still open after breakGlassTTL")
+	}
+	if s.glassOpen("p2", opened) {
+		t.Error("glass open for a patient who was never opened")
+	}
+}
+
+func TestPatientNameExpires(t *testing.T) {
+	var s session
+	read := time.Now()
+	s.setPatientName("p1", "Maria Garcia", read)
+	if name, ok := s.patientName("p1", read.Add(patientNameTTL-time.Second)); !ok || name != "Maria Garcia" {
+		t.Errorf("patientName before patientNameTTL = %q, %v", name, ok)
+	}
+	if _, ok := s.patientName("p1", read.Add(patientNameTTL)); ok {
+		t.Error("name still cached after patientNameTTL")
+	}
+	s.forgetPatientName("p1")
+	if _, ok := s.patientName("p1", read); ok {
+		t.Error("name still cached after forgetPatientName")
+	}
+}
+
+func TestEnqueueConcurrent(t *testing.T) {
+	t.Setenv("PHENOSTORE_QUEUE_FILE", filepath.Join(t.TempDir(), "queue.json"))
+	const n = 20
+
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			op := queuedOp{Method: "DELETE", ResourceType: "Observation", ResourceID: fmt.Sprint(i)}
+			var queued *QueuedError
+			if err := enqueue(op, errors.New("store unreachable")); !errors.As(err, &queued) {
+				t.Errorf("enqueue: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	ops, err := loadQueue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, op := range ops {
+		seen[op.ResourceID] = true
+	}
+	if len(ops) != n || len(seen) != n {
+		t.Errorf("queue holds %d operations (%d distinct), want %d", len(ops), len(seen), n)
+	}
+}
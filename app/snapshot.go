@@ -0,0 +1,329 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	neturl "net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// snapshotResourceTypes lists the resource types captured in a snapshot, in
+// the order they are restored (referenced resources first).
+var snapshotResourceTypes = storedResourceTypes
+
+// TakeSnapshot writes seed-tagged resources, everything, or one cohort or
+// panel's patients and their resources to a directory with one NDJSON file
+// per resource type.
+func (a *App) TakeSnapshot() {
+	scope := "seed"
+	dir := "snapshot-" + time.Now().Format("20060102-150405")
+	var deidentify bool
+
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("What to capture").
+				Options(
+					huh.NewOption("Seed data only", "seed"),
+					huh.NewOption("Everything in the store", "all"),
+					huh.NewOption("A cohort or panel", "group"),
+				).
+				Value(&scope),
+			huh.NewInput().Title("Output directory").Value(&dir),
+			huh.NewConfirm().
+				Title("De-identify?").
+				Description("Replace names, strip contact details and attached files, and shift dates.").
+				Value(&deidentify),
+		),
+	)
+	if err := form.Run(); err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var query neturl.Values
+	var group map[string]any
+	var cohort cohortScope
+	switch scope {
+	case "seed":
+		query = neturl.Values{fhir.SearchTag: {seedTagQuery}}
+	case "group":
+		var err error
+		group, err = a.pickGroup()
+		if err != nil || group == nil {
+			if err != nil && !isAbort(err) {
+				ShowError(err)
+				PressEnter()
+			}
+			return
+		}
+		cohort = newCohortScope(group)
+	}
+	var deid *fhir.Deidentifier
+	if deidentify {
+		deid = newDeidentifier()
+	}
+
+	counts := make(map[string]int)
+	var apiErr error
+	var elapsed time.Duration
+
+	err := runSpinner("Taking snapshot...", func(ctx context.Context) {
+		start := time.Now()
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			apiErr = fmt.Errorf("creating snapshot directory: %w", err)
+			return
+		}
+		for _, rt := range snapshotResourceTypes {
+			var resources []json.RawMessage
+			if rt == "Group" && group != nil {
+				b, _ := json.Marshal(group)
+				resources = []json.RawMessage{b}
+			} else {
+				var err error
+				resources, err = a.searchAllPages(ctx, rt, 1000, query, nil)
+				if err != nil {
+					apiErr = err
+					return
+				}
+				if rt != "Group" {
+					resources = cohort.filter(resources)
+				}
+			}
+			if deid != nil {
+				for i, raw := range resources {
+					resources[i] = deid.Resource(raw)
+				}
+			}
+			if err := writeNDJSON(filepath.Join(dir, rt+".ndjson"), resources); err != nil {
+				apiErr = err
+				return
+			}
+			counts[rt] = len(resources)
+		}
+		elapsed = time.Since(start)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(apiErr)
+		PressEnter()
+		return
+	}
+
+	total := 0
+	fmt.Printf("\n  Snapshot written to %s\n", dir)
+	for _, rt := range snapshotResourceTypes {
+		fmt.Printf("    %-16s %d\n", rt, counts[rt])
+		total += counts[rt]
+	}
+	showTiming(fmt.Sprintf("Captured %d resources", total), elapsed)
+	PressEnter()
+}
+
+// RestoreSnapshot recreates the resources from a snapshot directory in
+// chunked transaction bundles, rewriting references to the new server IDs.
+// An interrupted restore can be resumed by restoring the same directory.
+func (a *App) RestoreSnapshot() {
+	var dir string
+	if err := huh.NewInput().Title("Snapshot directory").Value(&dir).Run(); err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	entries, err := snapshotBundleEntries(dir)
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if len(entries) == 0 {
+		fmt.Println("\n  Snapshot is empty.")
+		PressEnter()
+		return
+	}
+
+	var confirm bool
+	err = huh.NewConfirm().
+		Title(fmt.Sprintf("Restore %d resources?", len(entries))).
+		Description("Resources are created as new; existing data is not modified.").
+		Value(&confirm).
+		Run()
+	if err != nil || !confirm {
+		return
+	}
+
+	fmt.Println(timingStyle.Render("  Press Ctrl+C to stop; the import can be resumed later."))
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	start := time.Now()
+	created, err := a.importInChunks(ctx, "snapshot", entries, nil)
+	elapsed := time.Since(start)
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(fmt.Errorf("restoring snapshot: %w", err))
+			PressEnter()
+		}
+		return
+	}
+
+	fmt.Printf("\n  Restored %d resources from %s\n", created, dir)
+	showTiming(fmt.Sprintf("Created %d resources via chunked transaction bundles", created), elapsed)
+	PressEnter()
+}
+
+// writeNDJSON writes one compact JSON resource per line.
+func writeNDJSON(path string, resources []json.RawMessage) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", path, err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for _, raw := range resources {
+		var line bytes.Buffer
+		if err := json.Compact(&line, raw); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+		line.WriteByte('\n')
+		if _, err := w.Write(line.Bytes()); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// snapshotBundleEntries reads a snapshot directory and builds transaction
+// entries. Each resource gets a urn:uuid fullUrl and every reference to a
+// snapshotted resource is rewritten to point at that urn. The urns are
+// derived from the snapshotted IDs, so restoring the same snapshot again
+// builds the same entries and an interrupted restore can be resumed.
+func snapshotBundleEntries(dir string) ([]map[string]any, error) {
+	type snapshotResource struct {
+		resourceType string
+		resource     map[string]any
+	}
+	var resources []snapshotResource
+	urns := make(map[string]string)
+
+	for _, rt := range snapshotResourceTypes {
+		path := filepath.Join(dir, rt+".ndjson")
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("opening %s: %w", path, err)
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			m, err := fhir.Parse(line)
+			if err != nil {
+				f.Close()
+				return nil, fmt.Errorf("parsing %s: %w", path, err)
+			}
+			if id := mapStr(m, "id"); id != "" {
+				urns[rt+"/"+id] = "urn:uuid:" + nameUUID(rt+"/"+id)
+			}
+			resources = append(resources, snapshotResource{resourceType: rt, resource: m})
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+	}
+
+	var entries []map[string]any
+	for _, r := range resources {
+		urn := urns[r.resourceType+"/"+mapStr(r.resource, "id")]
+		if urn == "" {
+			urn = "urn:uuid:" + nameUUID(fmt.Sprintf("%s#%d", r.resourceType, len(entries)))
+		}
+		delete(r.resource, "id")
+		// Keep tags so restored seed data can still be cleaned up.
+		if meta, ok := r.resource["meta"].(map[string]any); ok {
+			if tags, ok := meta["tag"]; ok {
+				r.resource["meta"] = map[string]any{"tag": tags}
+			} else {
+				delete(r.resource, "meta")
+			}
+		}
+		rewriteReferences(r.resource, urns)
+		b, err := json.Marshal(r.resource)
+		if err != nil {
+			return nil, fmt.Errorf("marshaling %s: %w", r.resourceType, err)
+		}
+		entries = append(entries, bundleEntryWithUrn(urn, r.resourceType, b))
+	}
+	return entries, nil
+}
+
+// rewriteReferences replaces every "reference" value found in urns.
+func rewriteReferences(v any, urns map[string]string) {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, vv := range t {
+			if ref, ok := vv.(string); ok && k == "reference" {
+				if urn, ok := urns[ref]; ok {
+					t[k] = urn
+				}
+				continue
+			}
+			rewriteReferences(vv, urns)
+		}
+	case []any:
+		for _, vv := range t {
+			rewriteReferences(vv, urns)
+		}
+	}
+}
+
+// nameUUID returns a name-based (version 5) UUID string for name, the same
+// for the same name every time.
+func nameUUID(name string) string {
+	sum := sha1.Sum([]byte(name))
+	b := sum[:16]
+	b[6] = b[6]&0x0f | 0x50
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// newUUID returns a random (version 4) UUID string.
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
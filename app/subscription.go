@@ -0,0 +1,123 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// SubscriptionConfig controls the subscription listener.
+type SubscriptionConfig struct {
+	// Addr is the local address the listener binds to.
+	Addr string
+	// Endpoint is the URL PhenoStore POSTs notifications to. It defaults to
+	// http://<Addr>/notify; set it to a public URL (e.g. a tunnel) when the
+	// server cannot reach Addr directly.
+	Endpoint string
+	// Criteria is the search the Subscription matches, e.g. "CarePlan".
+	Criteria string
+}
+
+// Subscribe creates a rest-hook Subscription for cfg.Criteria, listens for
+// its notifications on cfg.Addr, and logs each changed resource until ctx is
+// cancelled. The Subscription is deleted on the way out.
+func (a *App) Subscribe(ctx context.Context, cfg SubscriptionConfig) error {
+	if cfg.Criteria == "" {
+		return fmt.Errorf("subscription needs criteria")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "http://" + cfg.Addr + "/notify"
+	}
+	// A per-run secret lets the listener ignore POSTs that did not come from
+	// this Subscription.
+	token := newUUID()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /notify", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, 10<<20))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		logNotification(body)
+	})
+
+	// Bind before subscribing so the server's first notification has
+	// somewhere to go.
+	ln, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errc := make(chan error, 1)
+	go func() {
+		log.Printf("listening for notifications on %s", ln.Addr())
+		errc <- srv.Serve(ln)
+	}()
+
+	created, err := a.createResource(ctx, "Subscription",
+		fhir.NewSubscription(cfg.Criteria, cfg.Endpoint, "Authorization: Bearer "+token, "Community Health Clinic live updates"))
+	if err != nil {
+		srv.Close()
+		return fmt.Errorf("creating subscription: %w", err)
+	}
+	subID := fhir.ResourceID(created)
+	status := ""
+	if m, err := fhir.Parse(created); err == nil {
+		status = mapStr(m, "status")
+	}
+	log.Printf("Subscription/%s (%s) on %q → %s", subID, status, cfg.Criteria, cfg.Endpoint)
+
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+	}
+
+	// ctx is already cancelled here, so clean up on a fresh one.
+	cleanupCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if derr := a.deleteResource(cleanupCtx, "Subscription", subID); derr != nil {
+		log.Printf("deleting Subscription/%s: %s", subID, derr)
+	} else {
+		log.Printf("deleted Subscription/%s", subID)
+	}
+	if err != nil {
+		return err
+	}
+	if serr := srv.Shutdown(cleanupCtx); serr != nil && !errors.Is(serr, http.ErrServerClosed) {
+		return serr
+	}
+	return nil
+}
+
+// logNotification prints one line per resource in a notification, or a ping
+// line for a notification without a payload.
+func logNotification(body []byte) {
+	resources, err := fhir.NotificationResources(body)
+	if err != nil {
+		log.Printf("unreadable notification: %s", err)
+		return
+	}
+	if len(resources) == 0 {
+		log.Printf("notification (no payload)")
+		return
+	}
+	for _, m := range resources {
+		log.Printf("changed %s", fhir.NotificationLine(m))
+	}
+}
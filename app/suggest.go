@@ -0,0 +1,204 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+)
+
+// codeSuggestion is a candidate ICD-10 code for a presenting complaint.
+type codeSuggestion struct {
+	Code    string  `json:"code"`
+	Display string  `json:"display"`
+	Score   float64 `json:"score"`
+}
+
+// icd10Index is a small embedded keyword index of common primary-care
+// diagnoses. Multi-word keywords are matched as phrases and weigh more.
+var icd10Index = []struct {
+	code     string
+	display  string
+	keywords []string
+}{
+	{"I10", "Essential (primary) hypertension", []string{"hypertension", "high blood pressure", "blood pressure", "bp"}},
+	{"E11.9", "Type 2 diabetes mellitus without complications", []string{"diabetes", "high sugar", "blood sugar", "thirst", "thirsty", "frequent urination", "polyuria"}},
+	{"E78.5", "Hyperlipidemia, unspecified", []string{"cholesterol", "lipids", "hyperlipidemia"}},
+	{"E66.9", "Obesity, unspecified", []string{"obesity", "overweight", "weight gain"}},
+	{"J06.9", "Acute upper respiratory infection, unspecified", []string{"cold", "runny nose", "congestion", "stuffy nose", "sore throat", "cough"}},
+	{"J02.9", "Acute pharyngitis, unspecified", []string{"sore throat", "throat pain", "painful swallowing", "scratchy throat"}},
+	{"J20.9", "Acute bronchitis, unspecified", []string{"cough", "productive cough", "chest congestion", "phlegm", "wheezing"}},
+	{"J45.909", "Unspecified asthma, uncomplicated", []string{"asthma", "wheezing", "wheeze", "inhaler", "shortness of breath"}},
+	{"J30.2", "Other seasonal allergic rhinitis", []string{"allergies", "hay fever", "sneezing", "itchy eyes", "pollen"}},
+	{"R06.02", "Shortness of breath", []string{"shortness of breath", "short of breath", "breathless", "dyspnea"}},
+	{"R51.9", "Headache, unspecified", []string{"headache", "head pain", "head hurts"}},
+	{"G43.909", "Migraine, unspecified, not intractable, without status migrainosus", []string{"migraine", "aura", "throbbing headache", "light sensitivity"}},
+	{"R42", "Dizziness and giddiness", []string{"dizzy", "dizziness", "lightheaded", "vertigo", "spinning"}},
+	{"R07.9", "Chest pain, unspecified", []string{"chest pain", "chest tightness", "chest pressure"}},
+	{"R10.9", "Unspecified abdominal pain", []string{"abdominal pain", "stomach pain", "stomach ache", "belly pain", "cramps"}},
+	{"K21.9", "Gastro-esophageal reflux disease without esophagitis", []string{"heartburn", "reflux", "acid reflux", "indigestion"}},
+	{"R11.2", "Nausea with vomiting, unspecified", []string{"nausea", "vomiting", "throwing up", "nauseous"}},
+	{"A09", "Infectious gastroenteritis and colitis, unspecified", []string{"diarrhea", "stomach bug", "food poisoning", "loose stools"}},
+	{"N39.0", "Urinary tract infection, site not specified", []string{"burning urination", "painful urination", "dysuria", "urinary", "uti"}},
+	{"N18.30", "Chronic kidney disease, stage 3 unspecified", []string{"kidney disease", "ckd", "kidney function"}},
+	{"M54.50", "Low back pain, unspecified", []string{"back pain", "lower back", "low back pain", "sciatica"}},
+	{"M25.50", "Pain in unspecified joint", []string{"joint pain", "knee pain", "shoulder pain", "hip pain", "arthralgia"}},
+	{"F41.1", "Generalized anxiety disorder", []string{"anxiety", "anxious", "worry", "worried", "nervous", "panic"}},
+	{"F32.A", "Depression, unspecified", []string{"depression", "depressed", "low mood", "sad", "hopeless"}},
+	{"G47.00", "Insomnia, unspecified", []string{"insomnia", "cant sleep", "trouble sleeping", "sleep"}},
+	{"R53.83", "Other fatigue", []string{"fatigue", "tired", "exhausted", "no energy"}},
+	{"R50.9", "Fever, unspecified", []string{"fever", "chills", "feverish", "temperature"}},
+	{"L30.9", "Dermatitis, unspecified", []string{"rash", "itchy skin", "eczema", "itchy"}},
+	{"L03.90", "Cellulitis, unspecified", []string{"skin infection", "red swollen", "warm skin", "cellulitis"}},
+	{"H10.9", "Unspecified conjunctivitis", []string{"pink eye", "red eye", "eye discharge", "conjunctivitis"}},
+	{"H66.90", "Otitis media, unspecified, unspecified ear", []string{"ear pain", "earache", "ear infection"}},
+}
+
+var nonWord = regexp.MustCompile(`[^a-z0-9]+`)
+
+// suggestCodes ranks the embedded index against a free-text complaint and
+// returns up to limit matches.
+func suggestCodes(complaint string, limit int) []codeSuggestion {
+	text := " " + strings.TrimSpace(nonWord.ReplaceAllString(strings.ToLower(strings.ReplaceAll(complaint, "'", "")), " ")) + " "
+
+	var out []codeSuggestion
+	for _, entry := range icd10Index {
+		score := 0.0
+		for _, kw := range entry.keywords {
+			if strings.Contains(text, " "+kw+" ") {
+				score += float64(len(strings.Fields(kw)))
+			}
+		}
+		if score > 0 {
+			out = append(out, codeSuggestion{Code: entry.code, Display: entry.display, Score: score})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// suggestCodesRemote asks the coding endpoint in PHENOSTORE_CODING_URL for
+// suggestions. It receives {"text": "...", "system": "ICD-10"} and must answer
+// with {"suggestions": [{"code": ..., "display": ..., "score": ...}]}.
+func suggestCodesRemote(ctx context.Context, url, complaint string) ([]codeSuggestion, error) {
+	body, _ := json.Marshal(map[string]string{"text": complaint, "system": "ICD-10"})
+
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("coding service: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("coding service: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	var result struct {
+		Suggestions []codeSuggestion `json:"suggestions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("coding service: invalid response: %w", err)
+	}
+	return result.Suggestions, nil
+}
+
+// SuggestDiagnosis lets the user pick a patient, describe the presenting
+// complaint, and record a Condition from one of the suggested ICD-10 codes.
+func (a *App) SuggestDiagnosis() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var complaint string
+	err = huh.NewText().
+		Title("Presenting complaint").
+		Placeholder("3 days of sore throat and runny nose, mild fever").
+		Value(&complaint).
+		Run()
+	if err != nil || strings.TrimSpace(complaint) == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	suggestions := suggestCodes(complaint, 5)
+	source := "keyword index"
+	if url := os.Getenv("PHENOSTORE_CODING_URL"); url != "" {
+		var remote []codeSuggestion
+		var remoteErr error
+		err := runSpinner("Suggesting codes...", func(ctx context.Context) {
+			remote, remoteErr = suggestCodesRemote(ctx, url, complaint)
+		})
+		if err != nil {
+			ShowError(err)
+			PressEnter()
+			return
+		}
+		if remoteErr != nil {
+			ShowError(fmt.Errorf("%w (using keyword index)", remoteErr))
+		} else {
+			suggestions, source = remote, "coding service"
+		}
+	}
+
+	var options []huh.Option[int]
+	for i, s := range suggestions {
+		options = append(options, huh.NewOption(fmt.Sprintf("%-8s %s", s.Code, s.Display), i))
+	}
+	options = append(options, huh.NewOption("None of these (enter manually)", -1))
+
+	choice := -1
+	if len(suggestions) > 0 {
+		choice = 0
+	}
+	err = huh.NewSelect[int]().
+		Title(fmt.Sprintf("Suggested diagnoses (%s)", source)).
+		Options(options...).
+		Value(&choice).
+		Run()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	var code, display string
+	if choice >= 0 {
+		code, display = suggestions[choice].Code, suggestions[choice].Display
+	} else if code, display, err = pickICD10(); err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	a.createCondition(patientID, code, display)
+}
@@ -3,11 +3,10 @@ package app
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
-	"time"
 
-	"github.com/charmbracelet/huh/spinner"
 	"github.com/phenoml/phenostore-example-go/fhir"
 	"github.com/phenoml/phenostore-sdk-go/phenostore"
 )
@@ -23,70 +22,44 @@ func (a *App) PatientSummary() {
 		return
 	}
 
-	ctx := context.Background()
-	var patient json.RawMessage
-	var observations, conditions, plans []json.RawMessage
-	var apiErr error
-	var elapsed time.Duration
-
-	err = spinner.New().
-		Title("Loading patient summary...").
-		Action(func() {
-			start := time.Now()
-
-			var wg sync.WaitGroup
-			var patientErr error
-			var observationsErr error
-			var conditionsErr error
-			var plansErr error
-
-			// Fire all 4 API calls in parallel.
-			wg.Add(4)
-			go func() {
-				defer wg.Done()
-				var err error
-				patient, err = a.Client.ReadResource(ctx, "Patient", patientID)
-				if err != nil {
-					patientErr = err
-				}
-			}()
-			go func() {
-				defer wg.Done()
-				observations, observationsErr = a.searchByPatient(ctx, "Observation", patientID)
-			}()
-			go func() {
-				defer wg.Done()
-				conditions, conditionsErr = a.searchByPatient(ctx, "Condition", patientID)
-			}()
-			go func() {
-				defer wg.Done()
-				plans, plansErr = a.searchByPatient(ctx, "CarePlan", patientID)
-			}()
-			wg.Wait()
+	dates, err := askDateRange()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
 
-			elapsed = time.Since(start)
+	var summary *Summary
+	var load summaryLoad
+	var compared []summaryLoad
+	var apiErr error
 
-			if phenostore.IsNotFound(patientErr) {
-				apiErr = fmt.Errorf("patient %s not found", patientID)
-				return
-			}
-			if patientErr != nil {
-				apiErr = fmt.Errorf("reading patient: %w", patientErr)
-				return
-			}
-			if observationsErr != nil {
-				apiErr = fmt.Errorf("loading observations: %w", observationsErr)
-				return
-			}
-			if conditionsErr != nil {
-				apiErr = fmt.Errorf("loading conditions: %w", conditionsErr)
+	err = runSpinner("Loading patient summary...", func(ctx context.Context) {
+		switch a.SummarySource {
+		case SummaryEverything:
+			summary, load, apiErr = a.loadSummaryOrSearches(ctx, patientID, dates, a.summaryByEverything)
+		case SummaryRevinclude:
+			summary, load, apiErr = a.loadSummaryOrSearches(ctx, patientID, dates, a.summaryByRevinclude)
+		case SummaryCompare:
+			// One after the other, so none slows the others down.
+			if summary, load, apiErr = a.loadSummarySearches(ctx, patientID, dates); apiErr != nil {
 				return
 			}
-			if plansErr != nil {
-				apiErr = fmt.Errorf("loading care plans: %w", plansErr)
+			for _, by := range []func(context.Context, string, DateRange) (*Summary, summaryLoad, error){a.summaryByEverything, a.summaryByRevinclude} {
+				other, load, err := by(ctx, patientID, dates)
+				if err != nil {
+					load.Err = err
+				} else {
+					load.Resources = summaryResources(other)
+				}
+				compared = append(compared, load)
 			}
-		}).
-		Run()
+		default:
+			summary, load, apiErr = a.loadSummarySearches(ctx, patientID, dates)
+		}
+	})
 
 	if err != nil {
 		ShowError(err)
@@ -100,8 +73,114 @@ func (a *App) PatientSummary() {
 	}
 
 	fmt.Println()
-	fhir.PrintSummary(patient, observations, conditions, plans)
-	total := len(observations) + len(conditions) + len(plans) + 1
-	showTiming(fmt.Sprintf("Loaded patient summary (%d resources, 4 parallel API calls)", total), elapsed)
+	fhir.PrintSummary(summary.Patient, summary.Flags, summary.Observations, summary.Conditions, summary.Plans, summary.ImagingStudies, a.goalTargets(summary.Goals))
+	label := fmt.Sprintf("Loaded patient summary (%d resources, %s)", summaryResources(summary), load.Method)
+	if !dates.All() {
+		label += ", observations " + dates.String()
+	}
+	if load.Fallback != "" {
+		label += ", " + load.Fallback + " unavailable"
+	}
+	showTiming(label, load.Elapsed)
+	if a.SummarySource == SummaryCompare {
+		load.Resources = summaryResources(summary)
+		printSummaryComparison(load, compared...)
+	}
+	showingResource(summary.Patient)
 	PressEnter()
 }
+
+// ErrPatientNotFound is returned by LoadSummary when the patient does not exist.
+var ErrPatientNotFound = errors.New("patient not found")
+
+// Summary is a patient with their flags, observations, conditions, care
+// plans, imaging studies, and goals, as raw FHIR JSON.
+type Summary struct {
+	Patient        json.RawMessage   `json:"patient"`
+	Flags          []json.RawMessage `json:"flags"`
+	Observations   []json.RawMessage `json:"observations"`
+	Conditions     []json.RawMessage `json:"conditions"`
+	Plans          []json.RawMessage `json:"carePlans"`
+	ImagingStudies []json.RawMessage `json:"imagingStudies"`
+	Goals          []json.RawMessage `json:"goals"`
+}
+
+// LoadSummary fetches a patient and their related resources with 7 parallel
+// API calls. It needs only a.Client, so other Go programs can construct
+// &App{Client: client} and reuse the same orchestration as the TUI and the
+// serve command.
+func (a *App) LoadSummary(ctx context.Context, patientID string) (*Summary, error) {
+	return a.LoadSummaryBetween(ctx, patientID, DateRange{})
+}
+
+// LoadSummaryBetween is LoadSummary with only the observations taken within
+// dates. Everything else is loaded in full.
+func (a *App) LoadSummaryBetween(ctx context.Context, patientID string, dates DateRange) (*Summary, error) {
+	var s Summary
+	var wg sync.WaitGroup
+	var patientErr error
+	var flagsErr error
+	var observationsErr error
+	var conditionsErr error
+	var plansErr error
+	var imagingErr error
+	var goalsErr error
+
+	// Fire all 7 API calls in parallel.
+	wg.Add(7)
+	go func() {
+		defer wg.Done()
+		s.Patient, patientErr = a.readResource(ctx, "Patient", patientID)
+	}()
+	go func() {
+		defer wg.Done()
+		s.Flags, flagsErr = a.searchByPatient(ctx, "Flag", patientID, "")
+	}()
+	go func() {
+		defer wg.Done()
+		s.Observations, observationsErr = a.searchObservations(ctx, patientID, dates)
+	}()
+	go func() {
+		defer wg.Done()
+		s.Conditions, conditionsErr = a.searchByPatient(ctx, "Condition", patientID, "")
+	}()
+	go func() {
+		defer wg.Done()
+		s.Plans, plansErr = a.searchByPatient(ctx, "CarePlan", patientID, sortRecentlySaved)
+	}()
+	go func() {
+		defer wg.Done()
+		s.ImagingStudies, imagingErr = a.searchByPatient(ctx, "ImagingStudy", patientID, "")
+	}()
+	go func() {
+		defer wg.Done()
+		s.Goals, goalsErr = a.searchByPatient(ctx, "Goal", patientID, "")
+	}()
+	wg.Wait()
+
+	if phenostore.IsNotFound(patientErr) {
+		return nil, fmt.Errorf("%w: %s", ErrPatientNotFound, patientID)
+	}
+	if patientErr != nil {
+		return nil, fmt.Errorf("reading patient: %w", patientErr)
+	}
+	if flagsErr != nil {
+		return nil, fmt.Errorf("loading flags: %w", flagsErr)
+	}
+	if observationsErr != nil {
+		return nil, fmt.Errorf("loading observations: %w", observationsErr)
+	}
+	if conditionsErr != nil {
+		return nil, fmt.Errorf("loading conditions: %w", conditionsErr)
+	}
+	if plansErr != nil {
+		return nil, fmt.Errorf("loading care plans: %w", plansErr)
+	}
+	if imagingErr != nil {
+		return nil, fmt.Errorf("loading imaging studies: %w", imagingErr)
+	}
+	if goalsErr != nil {
+		return nil, fmt.Errorf("loading goals: %w", goalsErr)
+	}
+	return &s, nil
+}
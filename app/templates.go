@@ -0,0 +1,233 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	neturl "net/url"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// planTemplateBaseURL prefixes the canonical URL of every built-in template.
+const planTemplateBaseURL = "https://example.org/fhir/PlanDefinition/"
+
+// planTemplate is a built-in care plan template, installed on the server as a
+// PlanDefinition the first time templates are used.
+type planTemplate struct {
+	name        string
+	title       string
+	description string
+	actions     []fhir.PlanAction
+}
+
+var planTemplates = []planTemplate{
+	{
+		name:        "diabetes-management",
+		title:       "Diabetes Management",
+		description: "Glycemic control, complication screening, and self-management education for type 2 diabetes.",
+		actions: []fhir.PlanAction{
+			{Title: "Baseline HbA1c lab test", DueDays: 7},
+			{Title: "Diabetic retinal exam", DueDays: 60},
+			{Title: "Diabetic foot exam", DueDays: 60},
+			{Title: "Complete diabetes self-management education", DueDays: 45},
+			{Title: "Repeat HbA1c in 3 months", DueDays: 90},
+		},
+	},
+	{
+		name:        "hypertension-management",
+		title:       "Hypertension Management",
+		description: "Blood pressure monitoring, lifestyle changes, and medication review.",
+		actions: []fhir.PlanAction{
+			{Title: "Start home blood pressure monitoring", DueDays: 7},
+			{Title: "Start low-sodium diet program", DueDays: 14},
+			{Title: "Follow-up BP check in 30 days", DueDays: 30},
+			{Title: "Evaluate need for medication adjustment", DueDays: 60},
+		},
+	},
+	{
+		name:        "ckd-management",
+		title:       "Chronic Kidney Disease Management",
+		description: "Monitoring of kidney function and cardiovascular risk for CKD stage 3.",
+		actions: []fhir.PlanAction{
+			{Title: "Baseline creatinine and eGFR", DueDays: 7},
+			{Title: "Urine albumin-to-creatinine ratio", DueDays: 14},
+			{Title: "Renal diet counseling", DueDays: 30},
+			{Title: "Nephrology referral", DueDays: 45},
+			{Title: "Repeat eGFR in 3 months", DueDays: 90},
+		},
+	},
+}
+
+// ensurePlanTemplates returns the built-in PlanDefinitions from the server,
+// creating any that are missing.
+func (a *App) ensurePlanTemplates(ctx context.Context) ([]json.RawMessage, error) {
+	urls := make([]string, len(planTemplates))
+	for i, t := range planTemplates {
+		urls[i] = planTemplateBaseURL + t.name
+	}
+	existing, err := a.searchResources(ctx, "PlanDefinition", 100, map[string]string{fhir.SearchPlanDefinitionURL: strings.Join(urls, ",")})
+	if err != nil {
+		return nil, err
+	}
+	have := make(map[string]bool)
+	for _, raw := range existing {
+		if m, err := fhir.Parse(raw); err == nil {
+			have[mapStr(m, "url")] = true
+		}
+	}
+
+	for i, t := range planTemplates {
+		if have[urls[i]] {
+			continue
+		}
+		created, err := a.createResource(ctx, "PlanDefinition",
+			fhir.NewPlanDefinition(urls[i], t.name, t.title, t.description, t.actions))
+		if err != nil {
+			return nil, fmt.Errorf("installing template %s: %w", t.name, err)
+		}
+		existing = append(existing, created)
+	}
+	return existing, nil
+}
+
+// applyPlanDefinition calls PlanDefinition/{id}/$apply for a patient.
+func (a *App) applyPlanDefinition(ctx context.Context, planDefinitionID, patientID string) (json.RawMessage, error) {
+	body, err := a.invokeOperation(ctx, "PlanDefinition", planDefinitionID, "$apply",
+		neturl.Values{"subject": {"Patient/" + patientID}})
+	if err != nil {
+		return nil, err
+	}
+	m, err := fhir.Parse(body)
+	if err != nil || mapStr(m, "resourceType") != "CarePlan" {
+		return nil, fmt.Errorf("$apply did not return a CarePlan")
+	}
+	return body, nil
+}
+
+// CreatePlanFromTemplate lets the user pick a patient and a PlanDefinition
+// template, then creates a CarePlan from it. The server's $apply is used when
+// supported; otherwise the template is expanded on the client.
+func (a *App) CreatePlanFromTemplate() {
+	patientID, err := a.PickPatient()
+	if err != nil || patientID == "" {
+		if err != nil && !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+
+	ctx := context.Background()
+	var templates []json.RawMessage
+	var fetchErr error
+
+	err = runSpinner("Loading templates...", func(ctx context.Context) {
+		templates, fetchErr = a.ensurePlanTemplates(ctx)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if fetchErr != nil {
+		ShowError(fetchErr)
+		PressEnter()
+		return
+	}
+
+	byID := make(map[string]map[string]any)
+	var options []huh.Option[string]
+	for _, raw := range templates {
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		id := mapStr(m, "id")
+		byID[id] = m
+		options = append(options, huh.NewOption(fmt.Sprintf("%s (%d steps)", mapStr(m, "title"), len(fhir.PlanDefinitionActivities(m, time.Now()))), id))
+	}
+
+	var templateID string
+	err = huh.NewSelect[string]().
+		Title("Select template").
+		Options(options...).
+		Value(&templateID).
+		Run()
+	if err != nil {
+		if !isAbort(err) {
+			ShowError(err)
+			PressEnter()
+		}
+		return
+	}
+	pd := byID[templateID]
+
+	var created json.RawMessage
+	var method string
+	var apiErr error
+
+	err = runSpinner("Creating care plan...", func(ctx context.Context) {
+		body, err := a.applyPlanDefinition(ctx, templateID, patientID)
+		if err == nil {
+			method = "$apply"
+			body = completeAppliedCarePlan(body, pd, patientID)
+		} else {
+			method = "client-side expansion"
+			body = fhir.CarePlanFromPlanDefinition(pd, patientID, time.Now())
+		}
+		created, apiErr = a.createResource(ctx, "CarePlan", body)
+	})
+
+	if err != nil {
+		ShowError(err)
+		PressEnter()
+		return
+	}
+	if apiErr != nil {
+		ShowError(fmt.Errorf("creating care plan: %w", apiErr))
+		PressEnter()
+		return
+	}
+
+	id := fhir.ResourceID(created)
+	a.emit(ctx, EventCarePlanCreated, "CarePlan", id, patientID)
+	fmt.Printf("\n  Created %q from template via %s (ID: %s)\n", mapStr(pd, "title"), method, id)
+	PressEnter()
+}
+
+// completeAppliedCarePlan fills in what a server's $apply result may leave
+// out: $apply returns an unsaved draft, and some servers put the actions in a
+// contained RequestGroup instead of activity details the app can track.
+func completeAppliedCarePlan(raw json.RawMessage, pd map[string]any, patientID string) json.RawMessage {
+	cp, err := fhir.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	delete(cp, "id")
+	cp["status"] = "active"
+	if _, ok := cp["intent"]; !ok {
+		cp["intent"] = "plan"
+	}
+	cp["subject"] = map[string]any{"reference": "Patient/" + patientID}
+	if mapStr(cp, "title") == "" {
+		cp["title"] = mapStr(pd, "title")
+	}
+	hasDetail := false
+	if acts, ok := cp["activity"].([]any); ok {
+		for _, act := range acts {
+			if m, ok := act.(map[string]any); ok && m["detail"] != nil {
+				hasDetail = true
+			}
+		}
+	}
+	if !hasDetail {
+		cp["activity"] = fhir.PlanDefinitionActivities(pd, time.Now())
+	}
+	b, _ := json.Marshal(cp)
+	return b
+}
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Transport defaults, which are those of Go's http.DefaultTransport except
+// that idle connections are kept for the store's host as freely as for all
+// hosts together, rather than two at a time.
+const (
+	defaultConnectTimeout = 30 * time.Second
+	defaultKeepAlive      = 30 * time.Second
+	defaultMaxIdleConns   = 100
+)
+
+// TransportSettings tune the HTTP client every SDK request goes through.
+type TransportSettings struct {
+	// Timeout limits a whole request, retries and reading the response
+	// included. 0 means no limit.
+	Timeout time.Duration
+	// ConnectTimeout limits how long opening a connection may take.
+	ConnectTimeout time.Duration
+	// MaxIdleConns is how many idle connections are kept open for reuse,
+	// across all hosts and to any one host.
+	MaxIdleConns int
+	// KeepAlive is the interval between TCP keep-alive probes on open
+	// connections. Negative turns them off.
+	KeepAlive time.Duration
+}
+
+// loadTransportSettings reads PHENOSTORE_TIMEOUT,
+// PHENOSTORE_CONNECT_TIMEOUT, PHENOSTORE_MAX_IDLE_CONNS, and
+// PHENOSTORE_KEEPALIVE, using the defaults for any that are not set.
+func loadTransportSettings() (TransportSettings, error) {
+	s := TransportSettings{ConnectTimeout: defaultConnectTimeout, KeepAlive: defaultKeepAlive, MaxIdleConns: defaultMaxIdleConns}
+	var err error
+	if s.Timeout, err = parseTimeout("PHENOSTORE_TIMEOUT", os.Getenv("PHENOSTORE_TIMEOUT"), 0); err != nil {
+		return s, err
+	}
+	if s.ConnectTimeout, err = parseTimeout("PHENOSTORE_CONNECT_TIMEOUT", os.Getenv("PHENOSTORE_CONNECT_TIMEOUT"), s.ConnectTimeout); err != nil {
+		return s, err
+	}
+	if s.KeepAlive, err = parseTimeout("PHENOSTORE_KEEPALIVE", os.Getenv("PHENOSTORE_KEEPALIVE"), s.KeepAlive); err != nil {
+		return s, err
+	}
+	if s.KeepAlive == 0 {
+		s.KeepAlive = -1
+	}
+	if s.MaxIdleConns, err = parseMaxIdleConns(os.Getenv("PHENOSTORE_MAX_IDLE_CONNS")); err != nil {
+		return s, err
+	}
+	return s, nil
+}
+
+// parseTimeout parses a duration setting such as "45s" or "2m", returning
+// def when v is empty. "0" is allowed and means off.
+func parseTimeout(name, v string, def time.Duration) (time.Duration, error) {
+	if v == "" {
+		return def, nil
+	}
+	if v == "0" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a duration such as 30s or 2m, or 0 for none", name, v)
+	}
+	return d, nil
+}
+
+// parseMaxIdleConns parses PHENOSTORE_MAX_IDLE_CONNS, returning the default
+// when it is empty.
+func parseMaxIdleConns(v string) (int, error) {
+	if v == "" {
+		return defaultMaxIdleConns, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > 1000 {
+		return 0, fmt.Errorf("invalid PHENOSTORE_MAX_IDLE_CONNS %q: must be a whole number from 1 to 1000", v)
+	}
+	return n, nil
+}
+
+// newHTTPClient returns the HTTP client every SDK request goes through,
+// token requests included. Retries wrap compression, so each attempt asks
+// for gzip afresh. With compress off, responses are requested uncompressed.
+func newHTTPClient(retries int, compress bool, settings TransportSettings) *http.Client {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.DialContext = (&net.Dialer{
+		Timeout:   settings.ConnectTimeout,
+		KeepAlive: settings.KeepAlive,
+	}).DialContext
+	if settings.MaxIdleConns > 0 {
+		base.MaxIdleConns = settings.MaxIdleConns
+		base.MaxIdleConnsPerHost = settings.MaxIdleConns
+	}
+	var transport http.RoundTripper = base
+	if compress {
+		transport = &gzipTransport{base: base}
+	} else {
+		base.DisableCompression = true
+	}
+	return &http.Client{
+		Timeout: settings.Timeout,
+		Transport: &retryTransport{
+			base:      transport,
+			retries:   retries,
+			baseDelay: retryBaseDelay,
+			maxDelay:  retryMaxDelay,
+		},
+	}
+}
@@ -0,0 +1,54 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLoadTransportSettings(t *testing.T) {
+	t.Setenv("PHENOSTORE_TIMEOUT", "45s")
+	t.Setenv("PHENOSTORE_CONNECT_TIMEOUT", "")
+	t.Setenv("PHENOSTORE_KEEPALIVE", "0")
+	t.Setenv("PHENOSTORE_MAX_IDLE_CONNS", "8")
+	s, err := loadTransportSettings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := TransportSettings{Timeout: 45 * time.Second, ConnectTimeout: defaultConnectTimeout, KeepAlive: -1, MaxIdleConns: 8}
+	if s != want {
+		t.Errorf("got %+v, want %+v", s, want)
+	}
+
+	for _, bad := range []string{"45", "-1s", "soon"} {
+		t.Setenv("PHENOSTORE_TIMEOUT", bad)
+		if _, err := loadTransportSettings(); err == nil {
+			t.Errorf("PHENOSTORE_TIMEOUT=%q: expected an error", bad)
+		}
+	}
+	t.Setenv("PHENOSTORE_TIMEOUT", "")
+	t.Setenv("PHENOSTORE_MAX_IDLE_CONNS", "0")
+	if _, err := loadTransportSettings(); err == nil {
+		t.Error("PHENOSTORE_MAX_IDLE_CONNS=0: expected an error")
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client := newHTTPClient(0, true, TransportSettings{Timeout: 50 * time.Millisecond})
+	start := time.Now()
+	if _, err := client.Get(srv.URL); err == nil {
+		t.Fatal("expected a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("request took %s despite a 50ms timeout", elapsed)
+	}
+}
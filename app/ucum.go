@@ -0,0 +1,76 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/phenoml/phenostore-example-go/fhir"
+)
+
+// UCUM check modes, from PHENOSTORE_UCUM.
+const (
+	UCUMWarn   = "warn"   // reject invalid unit codes, log unknown ones
+	UCUMStrict = "strict" // reject invalid and unknown unit codes
+	UCUMOff    = "off"    // send units unchecked
+)
+
+// InvalidUnitsError lists unit codes that stopped a change from being sent.
+type InvalidUnitsError struct {
+	Problems []string
+}
+
+func (e *InvalidUnitsError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d invalid unit codes, change not submitted:", len(e.Problems))
+	for _, p := range e.Problems {
+		b.WriteString("\n    " + p)
+	}
+	return b.String()
+}
+
+// checkUnits checks the UCUM codes of the resources in entries against the
+// bundled subset (see fhir.CheckUnits) before they are sent. Missing codes
+// and known misspellings such as "mmHg" are rejected with an
+// *InvalidUnitsError. Codes outside the subset, which may still be valid
+// UCUM, are logged, or rejected too when a.UCUM is UCUMStrict.
+func (a *App) checkUnits(entries []map[string]any) error {
+	if a.UCUM == UCUMOff {
+		return nil
+	}
+	var rejected []string
+	for i, e := range entries {
+		if e["resource"] == nil {
+			continue
+		}
+		raw, err := json.Marshal(e["resource"])
+		if err != nil {
+			continue
+		}
+		m, err := fhir.Parse(raw)
+		if err != nil {
+			continue
+		}
+		for _, p := range fhir.CheckUnits(m) {
+			desc := p.String()
+			if len(entries) > 1 {
+				desc = fmt.Sprintf("entry %d (%s) %s", i, mapStr(m, "resourceType"), desc)
+			}
+			if p.Invalid() || a.UCUM == UCUMStrict {
+				rejected = append(rejected, desc)
+			} else {
+				log.Printf("unit check: %s", desc)
+			}
+		}
+	}
+	if len(rejected) > 0 {
+		return &InvalidUnitsError{Problems: rejected}
+	}
+	return nil
+}
+
+// checkResourceUnits is checkUnits for a single resource.
+func (a *App) checkResourceUnits(body json.RawMessage) error {
+	return a.checkUnits([]map[string]any{{"resource": body}})
+}
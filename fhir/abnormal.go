@@ -0,0 +1,117 @@
+package fhir
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// AbnormalResult is an observation outside its reference range.
+type AbnormalResult struct {
+	PatientID string
+	ID        string // Observation ID
+	Name      string
+	Value     string // as ObservationValue shows it
+	Flag      string // "H" above the range, "L" below it
+	Range     string // e.g. "70–140 mg/dL" or "≥ 60 mL/min/1.73m2"
+	Time      time.Time
+}
+
+// ReferenceRange returns the range an observation's value is judged
+// against: its own referenceRange if it has one, otherwise the clinic's
+// usual range for its LOINC code. A zero bound is not checked, and ok is
+// false if there is no range. Bounds are in the stored unit.
+func ReferenceRange(m map[string]any) (low, high float64, ok bool) {
+	for _, r := range getSlice(m, "referenceRange") {
+		rm, _ := r.(map[string]any)
+		lo, lok := numberValue(getMap(rm, "low"), "value")
+		hi, hok := numberValue(getMap(rm, "high"), "value")
+		if lok || hok {
+			return lo, hi, true
+		}
+	}
+	code := observationLoincCode(m)
+	for _, r := range plainResults {
+		if r.code == code && (r.low != 0 || r.high != 0) {
+			return r.low, r.high, true
+		}
+	}
+	return 0, 0, false
+}
+
+// CheckAbnormal judges one observation against its reference range. Blood
+// pressure has no single value, so it is abnormal at or above 140/90, the
+// cutoff the dashboard uses for uncontrolled blood pressure. Observations
+// entered in error are never abnormal.
+func CheckAbnormal(m map[string]any) (AbnormalResult, bool) {
+	if getString(m, "status") == "entered-in-error" {
+		return AbnormalResult{}, false
+	}
+	r := AbnormalResult{
+		PatientID: PatientRef(m),
+		ID:        getString(m, "id"),
+		Name:      getString(getMap(m, "code"), "text"),
+		Value:     ObservationValue(m),
+	}
+	r.Time, _ = effectiveTime(m)
+
+	if isBloodPressure(m) {
+		systolic, diastolic, ok := bloodPressure(m)
+		if !ok || (systolic < uncontrolledSystolic && diastolic < uncontrolledDiastolic) {
+			return AbnormalResult{}, false
+		}
+		r.Flag = "H"
+		r.Range = fmt.Sprintf("< %d/%d mmHg", uncontrolledSystolic, uncontrolledDiastolic)
+		return r, true
+	}
+
+	vq := getMap(m, "valueQuantity")
+	value, ok := numberValue(vq, "value")
+	low, high, ranged := ReferenceRange(m)
+	if !ok || !ranged {
+		return AbnormalResult{}, false
+	}
+	switch {
+	case low != 0 && value < low:
+		r.Flag = "L"
+	case high != 0 && value > high:
+		r.Flag = "H"
+	default:
+		return AbnormalResult{}, false
+	}
+	unit, code := getString(vq, "unit"), getString(vq, "code")
+	low, shown := displayQuantity(low, unit, code)
+	high, _ = displayQuantity(high, unit, code)
+	switch {
+	case low != 0 && high != 0:
+		r.Range = fmt.Sprintf("%s–%s %s", formatNumber(low), formatNumber(high), shown)
+	case low != 0:
+		r.Range = fmt.Sprintf("≥ %s %s", formatNumber(low), shown)
+	default:
+		r.Range = fmt.Sprintf("≤ %s %s", formatNumber(high), shown)
+	}
+	return r, true
+}
+
+// AbnormalResults returns every abnormal observation, by patient ID and
+// then newest first.
+func AbnormalResults(observations []json.RawMessage) []AbnormalResult {
+	var results []AbnormalResult
+	for _, raw := range observations {
+		m, err := Parse(raw)
+		if err != nil {
+			continue
+		}
+		if r, ok := CheckAbnormal(m); ok {
+			results = append(results, r)
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].PatientID != results[j].PatientID {
+			return results[i].PatientID < results[j].PatientID
+		}
+		return results[i].Time.After(results[j].Time)
+	})
+	return results
+}
@@ -0,0 +1,45 @@
+package fhir
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCheckAbnormal(t *testing.T) {
+	withRange := func(raw json.RawMessage, low, high float64) json.RawMessage {
+		var m map[string]any
+		_ = json.Unmarshal(raw, &m)
+		m["referenceRange"] = []any{map[string]any{
+			"low":  map[string]any{"value": low},
+			"high": map[string]any{"value": high},
+		}}
+		b, _ := json.Marshal(m)
+		return b
+	}
+	tests := []struct {
+		name      string
+		raw       json.RawMessage
+		flag      string
+		wantRange string
+	}{
+		{"high glucose", NewBloodGlucoseObservation("p1", 210), "H", "70–140 mg/dL"},
+		{"normal glucose", NewBloodGlucoseObservation("p1", 95), "", ""},
+		{"low potassium", NewPotassiumObservation("p1", 3.1), "L", "3.5–5 mmol/L"},
+		{"low eGFR", NewEGFRObservation("p1", 42), "L", "≥ 60 mL/min/1.73m2"},
+		{"high LDL", NewLDLObservation("p1", 160), "H", "≤ 99 mg/dL"},
+		{"own range wins", withRange(NewBloodGlucoseObservation("p1", 120), 70, 99), "H", "70–99 mg/dL"},
+		{"high blood pressure", NewBloodPressureObservation("p1", 150, 85), "H", "< 140/90 mmHg"},
+		{"normal blood pressure", NewBloodPressureObservation("p1", 128, 82), "", ""},
+		{"no range", NewHeightObservation("p1", 180), "", ""},
+	}
+	for _, tt := range tests {
+		m, err := Parse(tt.raw)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		r, ok := CheckAbnormal(m)
+		if ok != (tt.flag != "") || r.Flag != tt.flag || r.Range != tt.wantRange {
+			t.Errorf("%s: got %q %q (ok=%v), want %q %q", tt.name, r.Flag, r.Range, ok, tt.flag, tt.wantRange)
+		}
+	}
+}
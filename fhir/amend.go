@@ -0,0 +1,229 @@
+package fhir
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ObservationStatuses are the statuses an existing observation can be set
+// to: amended or corrected after its value changes, or entered-in-error if it
+// should never have been recorded.
+var ObservationStatuses = []string{"final", "amended", "corrected", "entered-in-error"}
+
+// ObservationLabel describes an observation on one line for pickers, e.g.
+// "Weight 70 kg (2025-03-01 09:30)".
+func ObservationLabel(m map[string]any) string {
+	label := getString(getMap(m, "code"), "text")
+	if v := ObservationValue(m); v != "" {
+		label += " " + v
+	}
+	if date := effectiveDisplay(m); date != "" {
+		label += " (" + date + ")"
+	}
+	if status := getString(m, "status"); status != "" && status != "final" {
+		label += " [" + status + "]"
+	}
+	return label
+}
+
+// ObservationValueInput returns an observation's value the way
+// SetObservationValue reads it, e.g. "142/91" or "70 kg", or "" if it has no
+// editable value.
+func ObservationValueInput(m map[string]any) string {
+	if systolic, diastolic, ok := bloodPressure(m); ok {
+		return fmt.Sprintf("%g/%g", systolic, diastolic)
+	}
+	vq := getMap(m, "valueQuantity")
+	if vq == nil {
+		return ""
+	}
+	switch getString(vq, "code") {
+	case "kg", "Cel":
+		return ObservationValue(m)
+	}
+	return strconv.FormatFloat(getNumber(vq, "value"), 'f', -1, 64)
+}
+
+// ObservationReadings returns an observation's numeric readings by LOINC
+// code, in their stored units: the systolic and diastolic components of a
+// blood pressure, or the observation's own code and value otherwise.
+func ObservationReadings(m map[string]any) map[string]float64 {
+	readings := make(map[string]float64)
+	if isBloodPressure(m) {
+		if v, ok := bloodPressureComponent(m, systolicCode, 0); ok {
+			readings[systolicCode] = v
+		}
+		if v, ok := bloodPressureComponent(m, diastolicCode, 1); ok {
+			readings[diastolicCode] = v
+		}
+		return readings
+	}
+	if v, ok := numberValue(getMap(m, "valueQuantity"), "value"); ok {
+		if code := observationLoincCode(m); code != "" {
+			readings[code] = v
+		}
+	}
+	return readings
+}
+
+// SetObservationValue replaces an observation's value with one entered as
+// text: "142/91" for blood pressure, a weight or temperature in either unit
+// (see ParseWeight and ParseTemperature), or a number in the stored unit.
+func SetObservationValue(m map[string]any, s string) error {
+	s = strings.TrimSpace(s)
+	if len(getSlice(m, "component")) >= 2 {
+		sys, dia, ok := strings.Cut(s, "/")
+		systolic, err1 := strconv.Atoi(strings.TrimSpace(sys))
+		diastolic, err2 := strconv.Atoi(strings.TrimSpace(dia))
+		if !ok || err1 != nil || err2 != nil {
+			return fmt.Errorf("enter blood pressure as systolic/diastolic, e.g. 128/82")
+		}
+		sq, dq := bloodPressureQuantity(m, systolicCode, 0), bloodPressureQuantity(m, diastolicCode, 1)
+		if sq == nil || dq == nil {
+			return fmt.Errorf("this blood pressure has no systolic and diastolic readings to change")
+		}
+		sq["value"], dq["value"] = float64(systolic), float64(diastolic)
+		return nil
+	}
+
+	vq := getMap(m, "valueQuantity")
+	if vq == nil {
+		return fmt.Errorf("this observation has no value to change")
+	}
+	var value float64
+	var err error
+	switch getString(vq, "code") {
+	case "kg":
+		value, err = ParseWeight(s)
+	case "Cel":
+		value, err = ParseTemperature(s)
+	default:
+		value, err = strconv.ParseFloat(s, 64)
+		if err != nil {
+			err = fmt.Errorf("must be a number")
+		}
+	}
+	if err != nil {
+		return err
+	}
+	vq["value"] = value
+	return nil
+}
+
+// FieldChange is one value that differs between two versions of a resource.
+// Before or After is "" when the field was added or removed.
+type FieldChange struct {
+	Path   string // e.g. "component[0].valueQuantity.value"
+	Before string
+	After  string
+}
+
+// DiffResources lists the fields that differ between two versions of a
+// resource, sorted by path. meta is ignored, since the server rewrites it on
+// every update.
+func DiffResources(before, after map[string]any) []FieldChange {
+	var changes []FieldChange
+	diffValues("", before, after, &changes)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+func diffValues(path string, before, after any, changes *[]FieldChange) {
+	// Round-trip through JSON so builder types ([]map[string]any, int)
+	// compare equal to their decoded forms.
+	before, after = normalizeJSON(before), normalizeJSON(after)
+
+	bm, bok := before.(map[string]any)
+	am, aok := after.(map[string]any)
+	if bok && aok {
+		keys := make(map[string]bool)
+		for k := range bm {
+			keys[k] = true
+		}
+		for k := range am {
+			keys[k] = true
+		}
+		for k := range keys {
+			if path == "" && k == "meta" {
+				continue
+			}
+			diffValues(joinPath(path, k), bm[k], am[k], changes)
+		}
+		return
+	}
+	bs, bok := before.([]any)
+	as, aok := after.([]any)
+	if bok && aok {
+		for i := range max(len(bs), len(as)) {
+			var b, a any
+			if i < len(bs) {
+				b = bs[i]
+			}
+			if i < len(as) {
+				a = as[i]
+			}
+			diffValues(fmt.Sprintf("%s[%d]", path, i), b, a, changes)
+		}
+		return
+	}
+	if b, a := leafString(before), leafString(after); b != a {
+		*changes = append(*changes, FieldChange{Path: path, Before: b, After: a})
+	}
+}
+
+func normalizeJSON(v any) any {
+	switch v.(type) {
+	case nil, string, float64, bool, map[string]any, []any:
+		return v
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	if json.Unmarshal(b, &out) != nil {
+		return v
+	}
+	return out
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func leafString(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// PrintDiff displays field changes as "path: before → after".
+func PrintDiff(changes []FieldChange) {
+	width := 0
+	for _, c := range changes {
+		width = max(width, len(c.Path))
+	}
+	for _, c := range changes {
+		before, after := c.Before, c.After
+		if before == "" {
+			before = "(none)"
+		}
+		if after == "" {
+			after = "(none)"
+		}
+		fmt.Printf("  %-*s  %s → %s\n", width, c.Path, labelStyle.UnsetWidth().Render(before), after)
+	}
+}
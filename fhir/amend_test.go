@@ -0,0 +1,89 @@
+package fhir
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestSetObservationValue(t *testing.T) {
+	parse := func(raw json.RawMessage) map[string]any {
+		m, err := Parse(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+
+	// Components stored diastolic first are still matched by LOINC code.
+	reversed := parse(json.RawMessage(`{"resourceType":"Observation","code":{"coding":[{"code":"85354-9"}]},"component":[` +
+		`{"code":{"coding":[{"code":"8462-4"}]},"valueQuantity":{"value":80}},` +
+		`{"code":{"coding":[{"code":"8480-6"}]},"valueQuantity":{"value":120}}]}`))
+	uncoded := parse(json.RawMessage(`{"resourceType":"Observation","component":[` +
+		`{"valueQuantity":{"value":120}},{"valueQuantity":{"value":80}}]}`))
+	onlySystolic := parse(json.RawMessage(`{"resourceType":"Observation","component":[` +
+		`{"code":{"coding":[{"code":"8480-6"}]},"valueQuantity":{"value":120}},` +
+		`{"code":{"coding":[{"code":"8867-4"}]},"valueQuantity":{"value":70}}]}`))
+
+	tests := []struct {
+		name    string
+		m       map[string]any
+		input   string
+		want    string // ObservationValue after the change
+		wantErr bool
+	}{
+		{"coded blood pressure", parse(NewBloodPressureObservation("p1", 118, 76)), "142/91", "142/91 mmHg", false},
+		{"reversed components", reversed, "142 / 91", "142/91 mmHg", false},
+		{"uncoded components", uncoded, "142/91", "142/91 mmHg", false},
+		{"no diastolic", onlySystolic, "142/91", "", true},
+		{"blood pressure as one number", parse(NewBloodPressureObservation("p1", 118, 76)), "142", "", true},
+		{"weight in pounds", parse(NewWeightObservation("p1", 70)), "154 lb", "69.9 kg", false},
+		{"weight in kilograms", parse(NewWeightObservation("p1", 70)), "72.5", "72.5 kg", false},
+		{"temperature in Fahrenheit", parse(NewTemperatureObservation("p1", 37)), "100.4 F", "38 °C", false},
+		{"plain number", parse(NewBloodGlucoseObservation("p1", 95)), "110", "110 mg/dL", false},
+		{"not a number", parse(NewBloodGlucoseObservation("p1", 95)), "high", "", true},
+		{"no value", parse(json.RawMessage(`{"resourceType":"Observation"}`)), "5", "", true},
+	}
+	for _, tt := range tests {
+		err := SetObservationValue(tt.m, tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: SetObservationValue(%q) error = %v, want error %v", tt.name, tt.input, err, tt.wantErr)
+			continue
+		}
+		if err == nil {
+			if got := ObservationValue(tt.m); got != tt.want {
+				t.Errorf("%s: after SetObservationValue(%q) value = %q, want %q", tt.name, tt.input, got, tt.want)
+			}
+		}
+	}
+
+	// The reversed panel keeps its order, with each value in its own slot.
+	first, _ := getSlice(reversed, "component")[0].(map[string]any)
+	if got := getNumber(getMap(first, "valueQuantity"), "value"); got != 91 {
+		t.Errorf("reversed diastolic component = %v, want 91", got)
+	}
+}
+
+func TestDiffResources(t *testing.T) {
+	before, _ := Parse(NewBloodPressureObservation("p1", 118, 76))
+	after, _ := Parse(NewBloodPressureObservation("p1", 118, 76))
+	after["status"] = "amended"
+	after["meta"] = map[string]any{"versionId": "2"}
+	SetObservationValue(after, "120/76")
+	delete(after, "category")
+	after["note"] = []map[string]any{{"text": "Repeat reading"}}
+
+	got := DiffResources(before, after)
+	want := []FieldChange{
+		{Path: "category", Before: `[{"coding":[{"code":"vital-signs","display":"Vital Signs","system":"http://terminology.hl7.org/CodeSystem/observation-category"}]}]`},
+		{Path: "component[0].valueQuantity.value", Before: "118", After: "120"},
+		{Path: "note", After: `[{"text":"Repeat reading"}]`},
+		{Path: "status", Before: "final", After: "amended"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffResources =\n%+v\nwant\n%+v", got, want)
+	}
+	if changes := DiffResources(before, before); len(changes) != 0 {
+		t.Errorf("DiffResources of identical resources = %+v, want none", changes)
+	}
+}
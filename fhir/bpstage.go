@@ -0,0 +1,122 @@
+package fhir
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// BPStage is a blood pressure category from the 2017 ACC/AHA guideline.
+type BPStage string
+
+const (
+	BPNormal   BPStage = "normal"   // below 120/80
+	BPElevated BPStage = "elevated" // systolic 120-129, diastolic below 80
+	BPStage1   BPStage = "stage-1"  // systolic 130-139 or diastolic 80-89
+	BPStage2   BPStage = "stage-2"  // 140/90 or higher
+	BPCrisis   BPStage = "crisis"   // 180/120 or higher
+)
+
+// Stage 1 starts here; stage 2 and crisis use the uncontrolled and crisis
+// thresholds the alert rules and dashboard use.
+const (
+	elevatedSystolic = 120
+	stage1Systolic   = 130
+	stage1Diastolic  = 80
+)
+
+var bpStageStyles = map[BPStage]lipgloss.Style{
+	BPNormal:   lipgloss.NewStyle().Foreground(lipgloss.Color("2")),
+	BPElevated: lipgloss.NewStyle().Foreground(lipgloss.Color("3")),
+	BPStage1:   lipgloss.NewStyle().Foreground(lipgloss.Color("208")),
+	BPStage2:   lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("1")),
+	BPCrisis:   flagStyle,
+}
+
+// ClassifyBloodPressure returns the stage of a reading. The higher of the
+// stages the two numbers fall in wins.
+func ClassifyBloodPressure(systolic, diastolic float64) BPStage {
+	switch {
+	case systolic >= crisisSystolic || diastolic >= crisisDiastolic:
+		return BPCrisis
+	case systolic >= uncontrolledSystolic || diastolic >= uncontrolledDiastolic:
+		return BPStage2
+	case systolic >= stage1Systolic || diastolic >= stage1Diastolic:
+		return BPStage1
+	case systolic >= elevatedSystolic:
+		return BPElevated
+	}
+	return BPNormal
+}
+
+// BloodPressureStage classifies a blood pressure Observation. ok is false
+// if m is not one or is missing a reading.
+func BloodPressureStage(m map[string]any) (stage BPStage, ok bool) {
+	if !isBloodPressure(m) {
+		return "", false
+	}
+	systolic, diastolic, ok := bloodPressure(m)
+	if !ok {
+		return "", false
+	}
+	return ClassifyBloodPressure(systolic, diastolic), true
+}
+
+// Label is the stage's name for display, e.g. "Stage 1 hypertension".
+func (s BPStage) Label() string {
+	switch s {
+	case BPNormal:
+		return "Normal"
+	case BPElevated:
+		return "Elevated"
+	case BPStage1:
+		return "Stage 1 hypertension"
+	case BPStage2:
+		return "Stage 2 hypertension"
+	case BPCrisis:
+		return "Hypertensive crisis"
+	}
+	return string(s)
+}
+
+// Render returns the stage's label colored by severity, from green for
+// normal to a red badge for a crisis.
+func (s BPStage) Render() string {
+	if style, ok := bpStageStyles[s]; ok {
+		return style.Render(s.Label())
+	}
+	return s.Label()
+}
+
+// latestBloodPressure returns the most recent blood pressure Observation
+// with both readings, or nil if there is none. Readings entered in error are
+// skipped.
+func latestBloodPressure(observations []map[string]any) map[string]any {
+	var latest map[string]any
+	var latestAt time.Time
+	for _, m := range observations {
+		if _, ok := BloodPressureStage(m); !ok || getString(m, "status") == "entered-in-error" {
+			continue
+		}
+		if at, _ := effectiveTime(m); latest == nil || at.After(latestAt) {
+			latest, latestAt = m, at
+		}
+	}
+	return latest
+}
+
+// printBloodPressureStage prints the latest blood pressure and its stage as a
+// line of the patient header.
+func printBloodPressureStage(observations []map[string]any) {
+	m := latestBloodPressure(observations)
+	if m == nil {
+		return
+	}
+	stage, _ := BloodPressureStage(m)
+	line := bloodPressureDisplay(m) + "  " + stage.Render()
+	if date := effectiveDisplay(m); date != "" {
+		line += "  " + labelStyle.UnsetWidth().Render(fmt.Sprintf("(%s)", date))
+	}
+	fmt.Printf("  %s%s\n", labelStyle.Render("BP:"), line)
+}
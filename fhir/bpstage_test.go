@@ -0,0 +1,65 @@
+package fhir
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestClassifyBloodPressure(t *testing.T) {
+	tests := []struct {
+		systolic, diastolic float64
+		want                BPStage
+	}{
+		{119, 79, BPNormal},
+		{120, 79, BPElevated},
+		{129, 70, BPElevated},
+		{130, 70, BPStage1},
+		{118, 80, BPStage1},
+		{139, 89, BPStage1},
+		{140, 70, BPStage2},
+		{125, 90, BPStage2},
+		{179, 119, BPStage2},
+		{180, 100, BPCrisis},
+		{150, 120, BPCrisis},
+	}
+	for _, tt := range tests {
+		if got := ClassifyBloodPressure(tt.systolic, tt.diastolic); got != tt.want {
+			t.Errorf("ClassifyBloodPressure(%g, %g) = %s, want %s", tt.systolic, tt.diastolic, got, tt.want)
+		}
+	}
+}
+
+func TestLatestBloodPressure(t *testing.T) {
+	at := func(raw json.RawMessage, effective time.Time) map[string]any {
+		m, err := Parse(WithEffective(raw, effective))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	day := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	missingSystolic, _ := Parse(json.RawMessage(`{"resourceType":"Observation","code":{"coding":[{"code":"85354-9"}]},` +
+		`"effectiveDateTime":"2026-03-09T09:00:00Z","component":[{"code":{"coding":[{"code":"8462-4"}]},"valueQuantity":{"value":95}}]}`))
+	observations := []map[string]any{
+		at(NewBloodPressureObservation("p1", 118, 76), day),
+		at(NewBloodPressureObservation("p1", 142, 91), day.AddDate(0, 0, 7)),
+		at(NewHeartRateObservation("p1", 70), day.AddDate(0, 0, 10)),
+		missingSystolic,
+		at(NewBloodPressureObservation("p1", 131, 84), day.AddDate(0, 0, 2)),
+	}
+	latest := latestBloodPressure(observations)
+	if stage, ok := BloodPressureStage(latest); !ok || stage != BPStage2 {
+		t.Errorf("latest reading is %s (%v), want the stage 2 reading", stage, ok)
+	}
+	if latestBloodPressure(observations[2:4]) != nil {
+		t.Error("found a blood pressure among readings without a complete one")
+	}
+
+	mistaken := at(NewBloodPressureObservation("p1", 185, 125), day.AddDate(0, 0, 14))
+	mistaken["status"] = "entered-in-error"
+	latest = latestBloodPressure(append(observations, mistaken))
+	if stage, ok := BloodPressureStage(latest); !ok || stage != BPStage2 {
+		t.Errorf("latest reading is %s (%v), want the stage 2 reading rather than the one entered in error", stage, ok)
+	}
+}
@@ -0,0 +1,125 @@
+package fhir
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// LocalCodeSystem is the clinic's own code system, for local codes such as
+// a billing or problem-list code carried alongside the standard ones.
+const LocalCodeSystem = "https://example.org/fhir/CodeSystem/local"
+
+// CodeSystem names a code system a coding can be in.
+type CodeSystem struct {
+	Name   string
+	System string
+}
+
+// CodeSystems are the code systems the app knows by name, in the order they
+// are offered when adding a coding.
+var CodeSystems = []CodeSystem{
+	{"ICD-10-CM", ICD10System},
+	{"SNOMED CT", SNOMEDSystem},
+	{"LOINC", "http://loinc.org"},
+	{"Local", LocalCodeSystem},
+}
+
+// CodeSystemName returns the short name of a code system, such as
+// "SNOMED CT", or the system URI itself if it is not one of CodeSystems.
+func CodeSystemName(system string) string {
+	if name, ok := knownCodeSystem(system); ok {
+		return name
+	}
+	if system == "" {
+		return "(no system)"
+	}
+	return system
+}
+
+func knownCodeSystem(system string) (string, bool) {
+	for _, cs := range CodeSystems {
+		if cs.System == system {
+			return cs.Name, true
+		}
+	}
+	return "", false
+}
+
+// Coding is one code for a concept in one code system.
+type Coding struct {
+	System  string
+	Code    string
+	Display string
+}
+
+func (c Coding) toMap() map[string]any {
+	m := map[string]any{"system": c.System, "code": c.Code}
+	if c.Display != "" {
+		m["display"] = c.Display
+	}
+	return m
+}
+
+// WithCoding adds a coding to a resource's code, after those it already
+// has, so a Condition or Observation can carry the same concept in several
+// code systems. A coding with the same system and code is replaced.
+func WithCoding(resource json.RawMessage, c Coding) json.RawMessage {
+	var m map[string]any
+	if err := json.Unmarshal(resource, &m); err != nil {
+		return resource
+	}
+	cc, _ := m["code"].(map[string]any)
+	if cc == nil {
+		cc = map[string]any{}
+		m["code"] = cc
+	}
+	var codings []any
+	for _, existing := range getSlice(cc, "coding") {
+		em, _ := existing.(map[string]any)
+		if getString(em, "system") == c.System && getString(em, "code") == c.Code {
+			continue
+		}
+		codings = append(codings, existing)
+	}
+	cc["coding"] = append(codings, c.toMap())
+	b, _ := json.Marshal(m)
+	return b
+}
+
+// Codings returns every coding of a resource's code, in order.
+func Codings(m map[string]any) []Coding {
+	var codings []Coding
+	for _, c := range getSlice(getMap(m, "code"), "coding") {
+		cm, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		codings = append(codings, Coding{
+			System:  getString(cm, "system"),
+			Code:    getString(cm, "code"),
+			Display: getString(cm, "display"),
+		})
+	}
+	return codings
+}
+
+// PrintCodings shows a resource's name and every coding of its code, one
+// per line with the code system's name, the code, its display, and the
+// system URI.
+func PrintCodings(m map[string]any) {
+	name := getString(getMap(m, "code"), "text")
+	if name == "" {
+		name = getString(m, "resourceType")
+	}
+	codings := Codings(m)
+	fmt.Println(headerStyle.Render(fmt.Sprintf("%s — %s", name, plural(len(codings), "coding", "codings"))))
+	if len(codings) == 0 {
+		fmt.Println("  No codings.")
+		return
+	}
+	for _, c := range codings {
+		fmt.Println(strings.TrimRight(fmt.Sprintf("  %s %-10s  %s", labelStyle.Render(CodeSystemName(c.System)), c.Code, c.Display), " "))
+		fmt.Printf("  %14s %s\n", "", labelStyle.UnsetWidth().Render(c.System))
+	}
+}
@@ -0,0 +1,39 @@
+package fhir
+
+import "testing"
+
+func TestCodings(t *testing.T) {
+	raw := NewCondition("p1", "I10", "Essential Hypertension",
+		Coding{System: SNOMEDSystem, Code: "38341003", Display: "Hypertensive disorder, systemic arterial"},
+		Coding{System: LocalCodeSystem, Code: "HTN-01"},
+	)
+	raw = WithCoding(raw, Coding{System: "urn:clinic", Code: "H1"})
+	// The same system and code again replaces rather than duplicates.
+	raw = WithCoding(raw, Coding{System: LocalCodeSystem, Code: "HTN-01", Display: "Hypertension"})
+	m, err := Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codings := Codings(m)
+	want := []Coding{
+		{ICD10System, "I10", "Essential Hypertension"},
+		{SNOMEDSystem, "38341003", "Hypertensive disorder, systemic arterial"},
+		{"urn:clinic", "H1", ""},
+		{LocalCodeSystem, "HTN-01", "Hypertension"},
+	}
+	if len(codings) != len(want) {
+		t.Fatalf("Codings = %v, want %v", codings, want)
+	}
+	for i := range want {
+		if codings[i] != want[i] {
+			t.Errorf("coding %d = %v, want %v", i, codings[i], want[i])
+		}
+	}
+	if got := conditionCodes(m); got != "I10 · SNOMED 38341003 · H1 · Local HTN-01" {
+		t.Errorf("conditionCodes = %q", got)
+	}
+	if got := CodeSystemName("urn:clinic"); got != "urn:clinic" {
+		t.Errorf("CodeSystemName of an unknown system = %q", got)
+	}
+}
@@ -0,0 +1,288 @@
+package fhir
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChartContext is a flattened, token-efficient view of a patient's chart for
+// feeding to language-model pipelines. It keeps only what a clinician would
+// scan first: alerts, active problems, the latest value of each lab and vital,
+// and outstanding care plan items. Resource IDs are left out.
+type ChartContext struct {
+	Patient   ContextPatient    `json:"patient"`
+	Alerts    []string          `json:"alerts,omitempty"`
+	Problems  []ContextProblem  `json:"problems,omitempty"`
+	Labs      []ContextResult   `json:"latestLabs,omitempty"`
+	Vitals    []ContextResult   `json:"latestVitals,omitempty"`
+	OpenItems []ContextPlanItem `json:"openPlanItems,omitempty"`
+}
+
+// ContextPatient is the demographic header of a ChartContext.
+type ContextPatient struct {
+	Name      string `json:"name"`
+	Gender    string `json:"gender,omitempty"`
+	BirthDate string `json:"birthDate,omitempty"`
+	Age       int    `json:"age,omitempty"`
+}
+
+// ContextProblem is an active condition.
+type ContextProblem struct {
+	Display string `json:"display"`
+	Code    string `json:"code,omitempty"`
+}
+
+// ContextResult is the most recent observation for one code.
+type ContextResult struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Date  string `json:"date,omitempty"`
+}
+
+// ContextPlanItem is an outstanding care plan activity.
+type ContextPlanItem struct {
+	Plan        string `json:"plan"`
+	Description string `json:"description"`
+	Status      string `json:"status,omitempty"`
+	Due         string `json:"due,omitempty"`
+}
+
+// BuildChartContext flattens a patient and their related resources into a
+// ChartContext. now is used to compute the patient's age.
+func BuildChartContext(now time.Time, patient json.RawMessage, flags, observations, conditions, plans []json.RawMessage) ChartContext {
+	var c ChartContext
+
+	if m, err := Parse(patient); err == nil {
+		c.Patient = ContextPatient{
+			Name:      PatientName(m),
+			Gender:    getString(m, "gender"),
+			BirthDate: getString(m, "birthDate"),
+		}
+		if dob, err := time.Parse("2006-01-02", c.Patient.BirthDate); err == nil {
+			c.Patient.Age = ageAt(dob, now)
+		}
+	}
+
+	for _, raw := range flags {
+		m, err := Parse(raw)
+		if err != nil || getString(m, "status") != "active" {
+			continue
+		}
+		c.Alerts = append(c.Alerts, FlagText(m))
+	}
+
+	for _, raw := range conditions {
+		m, err := Parse(raw)
+		if err != nil || !ConditionActive(m) {
+			continue
+		}
+		c.Problems = append(c.Problems, ContextProblem{
+			Display: ConditionDisplay(m),
+			Code:    firstCoding(getMap(m, "code")),
+		})
+	}
+
+	// Keep the latest observation per code; ties go to the later entry.
+	latest := make(map[string]map[string]any)
+	var order []string
+	for _, raw := range observations {
+		m, err := Parse(raw)
+		if err != nil {
+			continue
+		}
+		key := observationLoincCode(m)
+		if key == "" {
+			key = getString(getMap(m, "code"), "text")
+		}
+		prev, seen := latest[key]
+		if !seen {
+			order = append(order, key)
+		}
+		if !seen || getString(m, "effectiveDateTime") >= getString(prev, "effectiveDateTime") {
+			latest[key] = m
+		}
+	}
+	for _, key := range order {
+		m := latest[key]
+		value := ObservationValue(m)
+		if value == "" {
+			continue
+		}
+		r := ContextResult{
+			Name:  getString(getMap(m, "code"), "text"),
+			Value: value,
+			Date:  dateOnly(getString(m, "effectiveDateTime")),
+		}
+		if ObservationCategory(m) == CategoryLaboratory {
+			c.Labs = append(c.Labs, r)
+		} else {
+			c.Vitals = append(c.Vitals, r)
+		}
+	}
+
+	for _, raw := range plans {
+		m, err := Parse(raw)
+		if err != nil || getString(m, "status") != "active" {
+			continue
+		}
+		dp := GetDashboardPlan(m, "")
+		for _, item := range dp.Outstanding {
+			due := ""
+			if t, ok := ScheduledDate(item.ScheduleNote); ok {
+				due = t.Format("2006-01-02")
+			}
+			c.OpenItems = append(c.OpenItems, ContextPlanItem{
+				Plan:        dp.Title,
+				Description: item.Description,
+				Status:      item.Status,
+				Due:         due,
+			})
+		}
+	}
+	sort.SliceStable(c.OpenItems, func(i, j int) bool {
+		// Dated items first, soonest first.
+		di, dj := c.OpenItems[i].Due, c.OpenItems[j].Due
+		if (di == "") != (dj == "") {
+			return di != ""
+		}
+		return di < dj
+	})
+
+	return c
+}
+
+// Text renders the context as compact plain text, one section per line group.
+func (c ChartContext) Text() string {
+	var b strings.Builder
+
+	header := []string{c.Patient.Name}
+	if c.Patient.Gender != "" {
+		header = append(header, c.Patient.Gender)
+	}
+	if c.Patient.BirthDate != "" {
+		header = append(header, "DOB "+c.Patient.BirthDate)
+	}
+	if c.Patient.Age > 0 {
+		header = append(header, "age "+strconv.Itoa(c.Patient.Age))
+	}
+	fmt.Fprintf(&b, "PATIENT: %s\n", strings.Join(header, " | "))
+
+	if len(c.Alerts) > 0 {
+		fmt.Fprintf(&b, "ALERTS: %s\n", strings.Join(c.Alerts, "; "))
+	}
+
+	if len(c.Problems) > 0 {
+		problems := make([]string, len(c.Problems))
+		for i, p := range c.Problems {
+			problems[i] = p.Display
+			if p.Code != "" {
+				problems[i] += " (" + p.Code + ")"
+			}
+		}
+		fmt.Fprintf(&b, "PROBLEMS: %s\n", strings.Join(problems, "; "))
+	}
+
+	writeResults := func(label string, results []ContextResult) {
+		if len(results) == 0 {
+			return
+		}
+		parts := make([]string, len(results))
+		for i, r := range results {
+			parts[i] = r.Name + " " + r.Value
+			if r.Date != "" {
+				parts[i] += " (" + r.Date + ")"
+			}
+		}
+		fmt.Fprintf(&b, "%s: %s\n", label, strings.Join(parts, "; "))
+	}
+	writeResults("LABS", c.Labs)
+	writeResults("VITALS", c.Vitals)
+
+	if len(c.OpenItems) > 0 {
+		b.WriteString("OPEN PLAN ITEMS:\n")
+		for _, item := range c.OpenItems {
+			fmt.Fprintf(&b, "- [%s] %s", item.Plan, item.Description)
+			if item.Status != "" {
+				fmt.Fprintf(&b, " (%s", item.Status)
+				if item.Due != "" {
+					fmt.Fprintf(&b, ", due %s", item.Due)
+				}
+				b.WriteString(")")
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// ObservationValue formats an Observation's value, e.g. "142/91 mmHg" for
+// blood pressure or "7.2 %" for a simple quantity, rounded the same way
+// printObservation shows it. It returns "" if the Observation has no value.
+func ObservationValue(m map[string]any) string {
+	if isBloodPressure(m) {
+		return bloodPressureDisplay(m)
+	}
+	vq := getMap(m, "valueQuantity")
+	if vq == nil {
+		return ""
+	}
+	value, ok := numberValue(vq, "value")
+	value, unit := displayQuantity(value, getString(vq, "unit"), getString(vq, "code"))
+	val := "?"
+	if ok {
+		val = formatNumber(value)
+	}
+	if unit != "" {
+		return val + " " + unit
+	}
+	return val
+}
+
+// ConditionActive reports whether a Condition's clinical status is active,
+// recurrence, or relapse, treating a missing status as active. A condition
+// entered in error is never active.
+func ConditionActive(m map[string]any) bool {
+	code := ConditionStatus(m)
+	return code == "" || code == "active" || code == "recurrence" || code == "relapse"
+}
+
+// firstCoding returns the code of the first coding in a CodeableConcept.
+func firstCoding(cc map[string]any) string {
+	codings := getSlice(cc, "coding")
+	if len(codings) == 0 {
+		return ""
+	}
+	if c, ok := codings[0].(map[string]any); ok {
+		return getString(c, "code")
+	}
+	return ""
+}
+
+// codingIn returns the code of the first coding in system, or "".
+func codingIn(cc map[string]any, system string) string {
+	for _, c := range getSlice(cc, "coding") {
+		if coding, ok := c.(map[string]any); ok && getString(coding, "system") == system {
+			return getString(coding, "code")
+		}
+	}
+	return ""
+}
+
+func dateOnly(s string) string {
+	if len(s) >= 10 {
+		return s[:10]
+	}
+	return s
+}
+
+func ageAt(dob, now time.Time) int {
+	age := now.Year() - dob.Year()
+	if now.Month() < dob.Month() || (now.Month() == dob.Month() && now.Day() < dob.Day()) {
+		age--
+	}
+	return age
+}
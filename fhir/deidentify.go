@@ -0,0 +1,162 @@
+package fhir
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// identifyingFields are removed from every resource during de-identification.
+// note is free text, which may name the patient or their family.
+var identifyingFields = []string{"identifier", "telecom", "address", "photo", "contact", "note"}
+
+var datePattern = regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}(T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?)?\b`)
+
+// Deidentifier strips direct identifiers from FHIR resources and shifts every
+// date by the same offset, so timelines stay intact across an export.
+// Patients get a stable pseudonym ("Patient 001") keyed by their resource ID.
+// A Deidentifier is safe for concurrent use.
+type Deidentifier struct {
+	shiftDays  int
+	mu         sync.Mutex
+	pseudonyms map[string]int
+}
+
+// NewDeidentifier creates a Deidentifier that moves dates by shiftDays.
+func NewDeidentifier(shiftDays int) *Deidentifier {
+	return &Deidentifier{
+		shiftDays:  shiftDays,
+		pseudonyms: make(map[string]int),
+	}
+}
+
+// Pseudonym returns the stable replacement name for a patient ID.
+func (d *Deidentifier) Pseudonym(patientID string) string {
+	return "Patient " + d.number(patientID)
+}
+
+func (d *Deidentifier) number(patientID string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n, ok := d.pseudonyms[patientID]
+	if !ok {
+		n = len(d.pseudonyms) + 1
+		d.pseudonyms[patientID] = n
+	}
+	return fmt.Sprintf("%03d", n)
+}
+
+// Resource returns a de-identified copy of a FHIR resource. Resources that
+// fail to parse are returned unchanged.
+func (d *Deidentifier) Resource(raw json.RawMessage) json.RawMessage {
+	m, err := Parse(raw)
+	if err != nil {
+		return raw
+	}
+	for _, field := range identifyingFields {
+		delete(m, field)
+	}
+	// Narrative text may repeat names and contact details.
+	if _, ok := m["text"].(map[string]any); ok {
+		delete(m, "text")
+	}
+	if getString(m, "resourceType") == "Patient" {
+		m["name"] = []map[string]any{
+			{"given": []string{"Patient"}, "family": d.number(getString(m, "id"))},
+		}
+	}
+	d.scrubDisplays(m)
+	scrubAttachments(m)
+	b, err := json.Marshal(d.shiftDates(m))
+	if err != nil {
+		return raw
+	}
+	return b
+}
+
+// scrubDisplays walks a decoded JSON value and replaces the display of every
+// reference, which is usually a person's name: a patient's with their
+// pseudonym, anyone else's, such as a practitioner's, with nothing.
+func (d *Deidentifier) scrubDisplays(v any) {
+	switch t := v.(type) {
+	case map[string]any:
+		if ref, ok := t["reference"].(string); ok {
+			if _, named := t["display"]; named {
+				if id, ok := strings.CutPrefix(ref, "Patient/"); ok {
+					t["display"] = d.Pseudonym(id)
+				} else {
+					delete(t, "display")
+				}
+			}
+		}
+		for _, vv := range t {
+			d.scrubDisplays(vv)
+		}
+	case []any:
+		for _, vv := range t {
+			d.scrubDisplays(vv)
+		}
+	}
+}
+
+// scrubAttachments walks a decoded JSON value and removes the content and
+// title of every attachment, and the content of a Binary. Uploaded photos
+// and documents cannot be scrubbed, and a title is usually the uploaded
+// file's name. Both are recognized by their contentType.
+func scrubAttachments(v any) {
+	switch t := v.(type) {
+	case map[string]any:
+		if _, ok := t["contentType"].(string); ok {
+			delete(t, "data")
+			delete(t, "title")
+		}
+		for _, vv := range t {
+			scrubAttachments(vv)
+		}
+	case []any:
+		for _, vv := range t {
+			scrubAttachments(vv)
+		}
+	}
+}
+
+// Date shifts a FHIR date or dateTime, or any dates within text, by the
+// Deidentifier's offset, for exports built from resources rather than the
+// resources themselves.
+func (d *Deidentifier) Date(s string) string {
+	return datePattern.ReplaceAllStringFunc(s, d.shiftDate)
+}
+
+// shiftDates walks a decoded JSON value and moves every embedded date,
+// including meta.lastUpdated, which is when the record was written and would
+// otherwise give the offset away.
+func (d *Deidentifier) shiftDates(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, vv := range t {
+			t[k] = d.shiftDates(vv)
+		}
+		return t
+	case []any:
+		for i, vv := range t {
+			t[i] = d.shiftDates(vv)
+		}
+		return t
+	case string:
+		return datePattern.ReplaceAllStringFunc(t, d.shiftDate)
+	}
+	return v
+}
+
+func (d *Deidentifier) shiftDate(s string) string {
+	if ts, err := time.Parse(time.RFC3339, s); err == nil {
+		return ts.AddDate(0, 0, d.shiftDays).Format(time.RFC3339)
+	}
+	if ts, err := time.Parse("2006-01-02", s[:min(10, len(s))]); err == nil {
+		return ts.AddDate(0, 0, d.shiftDays).Format("2006-01-02") + s[10:]
+	}
+	return s
+}
@@ -0,0 +1,101 @@
+package fhir
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeidentifierResource(t *testing.T) {
+	d := NewDeidentifier(-10)
+
+	patient, _ := Parse(d.Resource(json.RawMessage(`{
+		"resourceType": "Patient", "id": "p1",
+		"name": [{"given": ["Sarah"], "family": "Johnson"}],
+		"birthDate": "1980-03-15",
+		"identifier": [{"value": "MRN-1"}],
+		"telecom": [{"value": "555-0100"}],
+		"text": {"div": "<div>Sarah Johnson</div>"},
+		"meta": {"lastUpdated": "2026-03-05T10:00:00Z"}
+	}`)))
+	if PatientName(patient) != "Patient 001" || getString(patient, "birthDate") != "1980-03-05" {
+		t.Errorf("patient name and birth date = %q, %q", PatientName(patient), getString(patient, "birthDate"))
+	}
+	for _, field := range []string{"identifier", "telecom", "text"} {
+		if _, ok := patient[field]; ok {
+			t.Errorf("patient kept %s", field)
+		}
+	}
+	if got, _ := Path(patient, "meta.lastUpdated").(string); got != "2026-02-23T10:00:00Z" {
+		t.Errorf("meta.lastUpdated = %q, want it shifted with the other dates", got)
+	}
+
+	obs := d.Resource(json.RawMessage(`{
+		"resourceType": "Observation", "id": "o1",
+		"subject": {"reference": "Patient/p1", "display": "Sarah Johnson"},
+		"performer": [{"reference": "Practitioner/x", "display": "Dr. Lee"}],
+		"code": {"coding": [{"system": "http://loinc.org", "code": "8867-4", "display": "Heart rate"}]},
+		"effectiveDateTime": "2026-03-05T10:00:00Z",
+		"note": [{"text": "Sarah felt dizzy"}]
+	}`))
+	for _, leaked := range []string{"Sarah", "Johnson", "Dr. Lee", "2026-03-05T"} {
+		if strings.Contains(string(obs), leaked) {
+			t.Errorf("observation still contains %q: %s", leaked, obs)
+		}
+	}
+	m, _ := Parse(obs)
+	if got, _ := Path(m, "subject.display").(string); got != "Patient 001" {
+		t.Errorf("subject.display = %q, want the patient's pseudonym", got)
+	}
+	if got, _ := Path(m, "code.coding.display").(string); got != "Heart rate" {
+		t.Errorf("coding display = %q, want it kept", got)
+	}
+	if got := getString(m, "effectiveDateTime"); got != "2026-02-23T10:00:00Z" {
+		t.Errorf("effectiveDateTime = %q", got)
+	}
+
+	group, _ := Parse(d.Resource(json.RawMessage(`{"resourceType":"Group","member":[{"entity":{"reference":"Patient/p2","display":"Tom Baker"}},{"entity":{"reference":"Patient/p1"}}]}`)))
+	members := getSlice(group, "member")
+	if got, _ := Path(members[0].(map[string]any), "entity.display").(string); got != "Patient 002" {
+		t.Errorf("first member display = %q, want Patient 002", got)
+	}
+	if _, ok := getMap(members[1].(map[string]any), "entity")["display"]; ok {
+		t.Error("a display was added to a member without one")
+	}
+}
+
+func TestDeidentifierAttachments(t *testing.T) {
+	d := NewDeidentifier(-10)
+
+	binary, _ := Parse(d.Resource(NewBinary("image/jpeg", []byte("photo of Sarah"))))
+	if _, ok := binary["data"]; ok || getString(binary, "contentType") != "image/jpeg" {
+		t.Errorf("binary = %v, want its contentType without data", binary)
+	}
+
+	attachment := NewAttachment("Binary/b1", "application/pdf", "sarah-johnson-referral.pdf", 2048)
+	doc, _ := Parse(d.Resource(NewDocumentReference("p1", attachment, time.Date(2026, 3, 5, 10, 0, 0, 0, time.UTC))))
+	content, _ := getSlice(doc, "content")[0].(map[string]any)
+	att := getMap(content, "attachment")
+	if _, ok := att["title"]; ok {
+		t.Errorf("attachment kept its title: %v", att)
+	}
+	if getString(att, "url") != "Binary/b1" {
+		t.Errorf("attachment url = %q, want it kept", getString(att, "url"))
+	}
+}
+
+func TestDeidentifierDate(t *testing.T) {
+	d := NewDeidentifier(-10)
+	tests := []struct{ in, want string }{
+		{"2026-03-05", "2026-02-23"},
+		{"2026-03-05T10:00:00Z", "2026-02-23T10:00:00Z"},
+		{"Every 3 months, next 2026-03-05", "Every 3 months, next 2026-02-23"},
+		{"weekly", "weekly"},
+	}
+	for _, tt := range tests {
+		if got := d.Date(tt.in); got != tt.want {
+			t.Errorf("Date(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
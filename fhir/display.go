@@ -3,7 +3,11 @@ package fhir
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 )
@@ -14,6 +18,7 @@ var (
 	checkDone    = lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Render("[x]")
 	checkActive  = lipgloss.NewStyle().Foreground(lipgloss.Color("3")).Render("[~]")
 	checkOpen    = lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Render("[ ]")
+	flagStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("15")).Background(lipgloss.Color("1")).Padding(0, 1)
 )
 
 // --- JSON access helpers ---
@@ -45,6 +50,27 @@ func getSlice(m map[string]any, key string) []any {
 	return nil
 }
 
+// numberValue is getNumber that also reports whether m holds a finite
+// number at key, so a missing reading can be told apart from 0.
+func numberValue(m map[string]any, key string) (float64, bool) {
+	var f float64
+	switch n := m[key].(type) {
+	case float64:
+		f = n
+	case json.Number:
+		var err error
+		if f, err = n.Float64(); err != nil {
+			return 0, false
+		}
+	default:
+		return 0, false
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, false
+	}
+	return f, true
+}
+
 func getNumber(m map[string]any, key string) float64 {
 	if v, ok := m[key]; ok {
 		switch n := v.(type) {
@@ -58,6 +84,33 @@ func getNumber(m map[string]any, key string) float64 {
 	return 0
 }
 
+// Path resolves a dot-separated field path such as "code.coding.code" in a
+// decoded resource. Arrays along the way resolve to their first element.
+// Returns nil if any step is missing.
+func Path(m map[string]any, path string) any {
+	var cur any = m
+	for _, key := range strings.Split(path, ".") {
+		if arr, ok := cur.([]any); ok {
+			if len(arr) == 0 {
+				return nil
+			}
+			cur = arr[0]
+		}
+		obj, ok := cur.(map[string]any)
+		if !ok {
+			return nil
+		}
+		cur = obj[key]
+	}
+	if arr, ok := cur.([]any); ok {
+		if len(arr) == 0 {
+			return nil
+		}
+		return arr[0]
+	}
+	return cur
+}
+
 // Parse unmarshals raw JSON into a map.
 func Parse(raw json.RawMessage) (map[string]any, error) {
 	var m map[string]any
@@ -109,6 +162,13 @@ func PatientRef(m map[string]any) string {
 	return ref
 }
 
+// SubjectPatient returns the ID of the Patient a resource's subject
+// refers to as "Patient/<id>". ok is false when the subject is missing or
+// is something else, such as a Group, a Device, or an absolute URL.
+func SubjectPatient(m map[string]any) (id string, ok bool) {
+	return strings.CutPrefix(getString(getMap(m, "subject"), "reference"), "Patient/")
+}
+
 // PrintPatient displays a Patient resource.
 func PrintPatient(raw json.RawMessage) {
 	m, err := Parse(raw)
@@ -165,6 +225,29 @@ func PrintPatient(raw json.RawMessage) {
 	}
 }
 
+// FlagText returns the display text of a Flag.
+func FlagText(m map[string]any) string {
+	return getString(getMap(m, "code"), "text")
+}
+
+// PrintFlagBanner displays active flags as a highlighted banner.
+// Inactive flags are skipped; nothing is printed if none are active.
+func PrintFlagBanner(flags []json.RawMessage) {
+	var active []string
+	for _, raw := range flags {
+		m, err := Parse(raw)
+		if err != nil || getString(m, "status") != "active" {
+			continue
+		}
+		active = append(active, flagStyle.Render("! "+FlagText(m)))
+	}
+	if len(active) == 0 {
+		return
+	}
+	fmt.Println(strings.Join(active, " "))
+	fmt.Println()
+}
+
 // PrintPatientList displays a list of patients in a compact format.
 func PrintPatientList(entries []json.RawMessage) {
 	fmt.Println(headerStyle.Render(fmt.Sprintf("Patients (%d)", len(entries))))
@@ -183,44 +266,243 @@ func PrintPatientList(entries []json.RawMessage) {
 
 // PrintObservation displays a single Observation.
 func PrintObservation(m map[string]any) {
+	printObservation(m, "  ")
+}
+
+func printObservation(m map[string]any, indent string) {
 	code := getMap(m, "code")
 	display := ""
 	if code != nil {
 		display = getString(code, "text")
 	}
+	const width = 16
 
-	// Check for components (blood pressure)
-	if components := getSlice(m, "component"); len(components) >= 2 {
-		c1, _ := components[0].(map[string]any)
-		c2, _ := components[1].(map[string]any)
-		v1 := getNumber(getMap(c1, "valueQuantity"), "value")
-		v2 := getNumber(getMap(c2, "valueQuantity"), "value")
-		fmt.Printf("  %-16s  %d/%d mmHg\n", display, int(v1), int(v2))
+	value, ok := observationValueDisplay(m)
+	if !ok {
 		return
 	}
 
-	// Simple value
-	vq := getMap(m, "valueQuantity")
-	if vq != nil {
-		val := getNumber(vq, "value")
-		unit := getString(vq, "unit")
-		if val == float64(int(val)) {
-			fmt.Printf("  %-16s  %d %s\n", display, int(val), unit)
-		} else {
-			fmt.Printf("  %-16s  %.1f %s\n", display, val, unit)
+	date := effectiveDisplay(m)
+	if status := getString(m, "status"); status != "" && status != "final" {
+		date = strings.TrimSpace(date + " " + status)
+	}
+	line := fmt.Sprintf("%s%-*s  %-16s", indent, width, display, value)
+	if date != "" {
+		line += "  " + labelStyle.UnsetWidth().Render(date)
+	}
+	if stage, ok := BloodPressureStage(m); ok {
+		line += "  " + stage.Render()
+	}
+	fmt.Println(strings.TrimRight(line, " "))
+	// How it was measured, then notes, sit under the value column.
+	if details := MeasurementDetails(m); details != "" {
+		fmt.Printf("%s%*s  %s\n", indent, width, "", labelStyle.UnsetWidth().Render(details))
+	}
+	for _, note := range ObservationNotes(m) {
+		for _, line := range strings.Split(note, "\n") {
+			fmt.Printf("%s%*s  %s\n", indent, width, "", labelStyle.UnsetWidth().Render(line))
+		}
+	}
+}
+
+// observationValueDisplay formats an Observation's value for
+// printObservation, or returns ok=false if it has none to show. Missing or
+// non-numeric readings show as "?" rather than 0.
+func observationValueDisplay(m map[string]any) (value string, ok bool) {
+	if members := getSlice(m, "hasMember"); len(members) > 0 && getMap(m, "valueQuantity") == nil {
+		// Panels have no value of their own; their results are in hasMember.
+		return fmt.Sprintf("(%d results)", len(members)), true
+	}
+	if value := ObservationValue(m); value != "" {
+		return value, true
+	}
+	return "", false
+}
+
+// Blood pressure component codes (LOINC).
+const (
+	systolicCode  = "8480-6"
+	diastolicCode = "8462-4"
+)
+
+// isBloodPressure reports whether an Observation is a blood pressure panel,
+// by code or, for uncoded data, by having two or more components.
+func isBloodPressure(m map[string]any) bool {
+	return firstCoding(getMap(m, "code")) == "85354-9" || len(getSlice(m, "component")) >= 2
+}
+
+// bloodPressureComponent returns the value of the blood pressure component
+// with the given LOINC code. A component with no code is taken by position
+// instead, systolic first.
+func bloodPressureComponent(m map[string]any, code string, index int) (float64, bool) {
+	return numberValue(bloodPressureQuantity(m, code, index), "value")
+}
+
+// bloodPressureQuantity returns the valueQuantity of the blood pressure
+// component bloodPressureComponent reads, or nil if there is none.
+func bloodPressureQuantity(m map[string]any, code string, index int) map[string]any {
+	components := getSlice(m, "component")
+	for _, c := range components {
+		cm, _ := c.(map[string]any)
+		if firstCoding(getMap(cm, "code")) == code {
+			return getMap(cm, "valueQuantity")
+		}
+	}
+	if index < len(components) {
+		cm, _ := components[index].(map[string]any)
+		if firstCoding(getMap(cm, "code")) == "" {
+			return getMap(cm, "valueQuantity")
+		}
+	}
+	return nil
+}
+
+// bloodPressureDisplay formats a blood pressure as "142/91 mmHg", with "?"
+// for a missing reading.
+func bloodPressureDisplay(m map[string]any) string {
+	part := func(code string, index int) string {
+		if v, ok := bloodPressureComponent(m, code, index); ok {
+			return formatNumber(v)
+		}
+		return "?"
+	}
+	return part(systolicCode, 0) + "/" + part(diastolicCode, 1) + " mmHg"
+}
+
+// formatNumber formats a measured value: whole numbers without a decimal
+// point, others to one decimal place. Values one decimal place would
+// misrepresent, such as 0.04 or 1e20, get three significant digits instead.
+func formatNumber(v float64) string {
+	abs := math.Abs(v)
+	switch {
+	case math.IsNaN(v) || math.IsInf(v, 0):
+		return "?"
+	case v == 0:
+		return "0"
+	case abs >= 1e15 || abs < 0.05:
+		return strconv.FormatFloat(v, 'g', 3, 64)
+	case v == math.Trunc(v):
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(v, 'f', 1, 64)
+}
+
+// effectiveDisplay returns when an observation was measured in local time,
+// or "" if it has no effectiveDateTime.
+func effectiveDisplay(m map[string]any) string {
+	s := getString(m, "effectiveDateTime")
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.Local().Format("2006-01-02 15:04")
+	}
+	return s
+}
+
+// ObservationDate returns the day an observation was measured, in local
+// time, as YYYY-MM-DD, or "" if it has no effectiveDateTime.
+func ObservationDate(m map[string]any) string {
+	s := getString(m, "effectiveDateTime")
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.Local().Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", s); err == nil {
+		return s
+	}
+	return ""
+}
+
+// effectiveTime returns when an observation was measured, from its
+// effectiveDateTime, which may be a full timestamp or just a date.
+func effectiveTime(m map[string]any) (time.Time, bool) {
+	s := getString(m, "effectiveDateTime")
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, true
+	}
+	return time.Time{}, false
+}
+
+// ObservationsBetween returns the observations measured from from up to,
+// but not including, to, newest first, as a date search sorted with
+// _sort=-date would. A zero bound is open. With either bound set,
+// observations without a date are left out.
+func ObservationsBetween(entries []json.RawMessage, from, to time.Time) []json.RawMessage {
+	type dated struct {
+		raw json.RawMessage
+		at  time.Time
+	}
+	var kept []dated
+	for _, raw := range entries {
+		m, err := Parse(raw)
+		if err != nil {
+			continue
+		}
+		at, ok := effectiveTime(m)
+		if !ok && (!from.IsZero() || !to.IsZero()) {
+			continue
+		}
+		if (!from.IsZero() && at.Before(from)) || (!to.IsZero() && !at.Before(to)) {
+			continue
 		}
+		kept = append(kept, dated{raw, at})
 	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].at.After(kept[j].at) })
+	out := make([]json.RawMessage, len(kept))
+	for i, d := range kept {
+		out[i] = d.raw
+	}
+	return out
 }
 
-// PrintObservationList displays multiple observations.
+// PanelMemberRefs returns the "Observation/id" references of a panel's
+// hasMember results.
+func PanelMemberRefs(m map[string]any) []string {
+	var refs []string
+	for _, v := range getSlice(m, "hasMember") {
+		ref, _ := v.(map[string]any)
+		if r := getString(ref, "reference"); r != "" {
+			refs = append(refs, r)
+		}
+	}
+	return refs
+}
+
+// PrintObservationList displays multiple observations in the order given,
+// which for the app's searches is newest first, sorted by the server.
+// Results that belong to a panel in the list are shown indented under it
+// rather than on their own.
 func PrintObservationList(entries []json.RawMessage) {
 	fmt.Println(headerStyle.Render(fmt.Sprintf("Observations (%d)", len(entries))))
+	var all []map[string]any
+	byRef := make(map[string]map[string]any)
 	for _, raw := range entries {
 		m, err := Parse(raw)
 		if err != nil {
 			continue
 		}
+		all = append(all, m)
+		byRef["Observation/"+getString(m, "id")] = m
+	}
+	inPanel := make(map[string]bool)
+	for _, m := range all {
+		for _, ref := range PanelMemberRefs(m) {
+			if byRef[ref] != nil {
+				inPanel[ref] = true
+			}
+		}
+	}
+
+	for _, m := range all {
+		if inPanel["Observation/"+getString(m, "id")] {
+			continue
+		}
 		PrintObservation(m)
+		for _, ref := range PanelMemberRefs(m) {
+			if member := byRef[ref]; member != nil {
+				printObservation(member, "    ")
+			}
+		}
 	}
 }
 
@@ -230,18 +512,57 @@ func PrintCondition(m map[string]any) {
 	if code == nil {
 		return
 	}
-	display := getString(code, "text")
-	icd := ""
-	if codings := getSlice(code, "coding"); len(codings) > 0 {
-		if c, ok := codings[0].(map[string]any); ok {
-			icd = getString(c, "code")
+	line := "  " + getString(code, "text")
+	if codes := conditionCodes(m); codes != "" {
+		line += fmt.Sprintf(" (%s)", codes)
+	}
+	if details := conditionDetails(m); details != "" {
+		line += "  " + labelStyle.UnsetWidth().Render(details)
+	}
+	fmt.Println(line)
+}
+
+// conditionCodes lists a condition's codes in every system it is coded in,
+// ICD-10 first, e.g. "I10 · SNOMED 38341003 · Local HTN-01". A code in a
+// system the app does not know by name shows alone.
+func conditionCodes(m map[string]any) string {
+	cc := getMap(m, "code")
+	var parts []string
+	if icd := codingIn(cc, ICD10System); icd != "" {
+		parts = append(parts, icd)
+	}
+	if snomed := codingIn(cc, SNOMEDSystem); snomed != "" {
+		parts = append(parts, "SNOMED "+snomed)
+	}
+	for _, c := range Codings(m) {
+		if c.Code == "" || c.System == ICD10System || c.System == SNOMEDSystem {
+			continue
+		}
+		if name, ok := knownCodeSystem(c.System); ok {
+			parts = append(parts, name+" "+c.Code)
+		} else {
+			parts = append(parts, c.Code)
 		}
 	}
-	if icd != "" {
-		fmt.Printf("  %s (%s)\n", display, icd)
-	} else {
-		fmt.Printf("  %s\n", display)
+	return strings.Join(parts, " · ")
+}
+
+// conditionDetails describes when a condition began and, unless it is still
+// active, its status and when it abated, e.g. "onset 2024-03-01 · resolved
+// 2026-05-01".
+func conditionDetails(m map[string]any) string {
+	var parts []string
+	if onset := dateOnly(getString(m, "onsetDateTime")); onset != "" {
+		parts = append(parts, "onset "+onset)
 	}
+	if !ConditionActive(m) {
+		status := ConditionStatus(m)
+		if abated := dateOnly(getString(m, "abatementDateTime")); abated != "" {
+			status += " " + abated
+		}
+		parts = append(parts, status)
+	}
+	return strings.Join(parts, " · ")
 }
 
 // PrintConditionList displays multiple conditions.
@@ -256,6 +577,41 @@ func PrintConditionList(entries []json.RawMessage) {
 	}
 }
 
+// ConditionDisplay returns the display text of a Condition.
+func ConditionDisplay(m map[string]any) string {
+	return getString(getMap(m, "code"), "text")
+}
+
+// ListEntryDisplays returns the display text of each entry in a List, in order.
+func ListEntryDisplays(m map[string]any) []string {
+	var displays []string
+	for _, e := range getSlice(m, "entry") {
+		entry, ok := e.(map[string]any)
+		if !ok {
+			continue
+		}
+		item := getMap(entry, "item")
+		display := getString(item, "display")
+		if display == "" {
+			display = getString(item, "reference")
+		}
+		displays = append(displays, display)
+	}
+	return displays
+}
+
+// PrintProblemList displays a curated problem List in ranked order.
+func PrintProblemList(m map[string]any) {
+	entries := ListEntryDisplays(m)
+	fmt.Println(headerStyle.Render(fmt.Sprintf("Problem List (%d)", len(entries))))
+	if len(entries) == 0 {
+		fmt.Println("  (empty)")
+	}
+	for i, display := range entries {
+		fmt.Printf("  %d. %s\n", i+1, display)
+	}
+}
+
 // carePlanProgress counts completed and total activities in a CarePlan.
 func carePlanProgress(m map[string]any) (completed, total int) {
 	for _, a := range getSlice(m, "activity") {
@@ -334,6 +690,7 @@ func PrintCarePlanList(entries []json.RawMessage) {
 
 // DashboardPlan holds a parsed care plan with its patient name for the clinic dashboard.
 type DashboardPlan struct {
+	PatientID   string
 	PatientName string
 	Title       string
 	Completed   int
@@ -351,6 +708,7 @@ type DashboardItem struct {
 // GetDashboardPlan extracts dashboard info from a CarePlan.
 func GetDashboardPlan(carePlan map[string]any, patientName string) DashboardPlan {
 	dp := DashboardPlan{
+		PatientID:   PatientRef(carePlan),
 		PatientName: patientName,
 		Title:       getString(carePlan, "title"),
 	}
@@ -377,6 +735,26 @@ func GetDashboardPlan(carePlan map[string]any, patientName string) DashboardPlan
 	return dp
 }
 
+// ScheduledDate parses the due date out of a scheduledString like "By 2025-05-01".
+func ScheduledDate(note string) (time.Time, bool) {
+	t, err := time.Parse("2006-01-02", strings.TrimSpace(strings.TrimPrefix(note, "By ")))
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// OverdueItems returns the outstanding activities whose due date is before now.
+func (dp DashboardPlan) OverdueItems(now time.Time) []DashboardItem {
+	var overdue []DashboardItem
+	for _, item := range dp.Outstanding {
+		if due, ok := ScheduledDate(item.ScheduleNote); ok && due.Before(now) {
+			overdue = append(overdue, item)
+		}
+	}
+	return overdue
+}
+
 // PrintClinicDashboard displays active plans grouped by patient with progress.
 func PrintClinicDashboard(plans []DashboardPlan) {
 	if len(plans) == 0 {
@@ -417,15 +795,6 @@ func PrintClinicDashboard(plans []DashboardPlan) {
 	}
 }
 
-// labLoincCodes are LOINC codes that represent lab results rather than vital signs.
-var labLoincCodes = map[string]bool{
-	"2345-7":  true, // Blood Glucose
-	"2093-3":  true, // Total Cholesterol
-	"4548-4":  true, // HbA1c
-	"2160-0":  true, // Creatinine
-	"33914-3": true, // eGFR
-}
-
 // observationLoincCode extracts the primary LOINC code from an Observation.
 func observationLoincCode(m map[string]any) string {
 	code := getMap(m, "code")
@@ -442,25 +811,33 @@ func observationLoincCode(m map[string]any) string {
 	return ""
 }
 
-// PrintSummary displays a full patient summary with observations, conditions, and plans.
-func PrintSummary(patient json.RawMessage, observations, conditions, plans []json.RawMessage) {
+// PrintSummary displays a full patient summary with active flags, observations,
+// conditions, and plans, and progress toward goals' targets.
+func PrintSummary(patient json.RawMessage, flags, observations, conditions, plans, imaging []json.RawMessage, goals []GoalTarget) {
+	PrintFlagBanner(flags)
 	PrintPatient(patient)
-	fmt.Println()
 
-	// Split observations into vital signs and lab results.
-	var vitals, labs []json.RawMessage
+	// Split observations by category.
+	var parsed []map[string]any
+	var vitals, labs, other []json.RawMessage
 	for _, raw := range observations {
 		m, err := Parse(raw)
 		if err != nil {
 			continue
 		}
-		loinc := observationLoincCode(m)
-		if labLoincCodes[loinc] {
-			labs = append(labs, raw)
-		} else {
+		parsed = append(parsed, m)
+		switch ObservationCategory(m) {
+		case CategoryVitalSigns:
 			vitals = append(vitals, raw)
+		case CategoryLaboratory:
+			labs = append(labs, raw)
+		default:
+			other = append(other, raw)
 		}
 	}
+	printBloodPressureStage(parsed)
+	fmt.Println()
+	PrintGoalProgress(EvaluateGoals(goals, parsed))
 
 	if len(vitals) > 0 {
 		fmt.Println(headerStyle.Render(fmt.Sprintf("Vital Signs (%d)", len(vitals))))
@@ -478,6 +855,25 @@ func PrintSummary(patient json.RawMessage, observations, conditions, plans []jso
 		}
 		fmt.Println()
 	}
+	if len(other) > 0 {
+		fmt.Println(headerStyle.Render(fmt.Sprintf("Other Observations (%d)", len(other))))
+		for _, raw := range other {
+			m, _ := Parse(raw)
+			PrintObservation(m)
+		}
+		fmt.Println()
+	}
+	if len(imaging) > 0 {
+		fmt.Println(headerStyle.Render(fmt.Sprintf("Imaging (%d)", len(imaging))))
+		for _, raw := range imaging {
+			m, err := Parse(raw)
+			if err != nil {
+				continue
+			}
+			fmt.Println("  " + ImagingStudyDisplay(m))
+		}
+		fmt.Println()
+	}
 
 	if len(conditions) > 0 {
 		PrintConditionList(conditions)
@@ -494,3 +890,306 @@ func PrintSummary(patient json.RawMessage, observations, conditions, plans []jso
 		}
 	}
 }
+
+// ImagingStudyDisplay returns an imaging study's date, modality,
+// description, and status, e.g. "2026-03-02  DX  Chest X-ray, 2 views (available)".
+func ImagingStudyDisplay(m map[string]any) string {
+	modality, _ := Path(m, "modality.code").(string)
+	return fmt.Sprintf("%-10s  %-2s  %s (%s)", dateOnly(getString(m, "started")), modality,
+		getString(m, "description"), getString(m, "status"))
+}
+
+// NutritionOrderDiet returns the diet name of a NutritionOrder.
+func NutritionOrderDiet(m map[string]any) string {
+	oralDiet := getMap(m, "oralDiet")
+	if oralDiet == nil {
+		return ""
+	}
+	types := getSlice(oralDiet, "type")
+	if len(types) == 0 {
+		return ""
+	}
+	if t, ok := types[0].(map[string]any); ok {
+		return getString(t, "text")
+	}
+	return ""
+}
+
+// PrintNutritionOrder displays a single NutritionOrder.
+func PrintNutritionOrder(m map[string]any) {
+	line := fmt.Sprintf("  %s [%s]", NutritionOrderDiet(m), getString(m, "status"))
+	if instruction := getString(getMap(m, "oralDiet"), "instruction"); instruction != "" {
+		line += " — " + instruction
+	}
+	if dt := getString(m, "dateTime"); len(dt) >= 10 {
+		line += fmt.Sprintf("  (ordered %s)", dt[:10])
+	}
+	fmt.Println(line)
+}
+
+// PrintNutritionOrderList displays multiple diet orders.
+func PrintNutritionOrderList(entries []json.RawMessage) {
+	fmt.Println(headerStyle.Render(fmt.Sprintf("Diet Orders (%d)", len(entries))))
+	for _, raw := range entries {
+		m, err := Parse(raw)
+		if err != nil {
+			continue
+		}
+		PrintNutritionOrder(m)
+	}
+}
+
+var auditActions = map[string]string{
+	"C": "create",
+	"R": "read",
+	"U": "update",
+	"D": "delete",
+	"E": "execute",
+}
+
+// PrintAuditEventList displays AuditEvents as one line each: time, action,
+// outcome, agent, and the entities involved.
+func PrintAuditEventList(entries []json.RawMessage) {
+	fmt.Println(headerStyle.Render(fmt.Sprintf("Audit Events (%d)", len(entries))))
+	for _, raw := range entries {
+		m, err := Parse(raw)
+		if err != nil {
+			continue
+		}
+		recorded := getString(m, "recorded")
+		if t, err := time.Parse(time.RFC3339, recorded); err == nil {
+			recorded = t.Local().Format("2006-01-02 15:04:05")
+		}
+		action := auditActions[getString(m, "action")]
+		if sub, ok := Path(m, "subtype.code").(string); ok && sub != "" {
+			action = sub
+		}
+		outcome := "ok"
+		if getString(m, "outcome") != "0" {
+			outcome = "FAILED"
+		}
+		agent, _ := Path(m, "agent.who.display").(string)
+
+		var entities []string
+		for _, e := range getSlice(m, "entity") {
+			em, ok := e.(map[string]any)
+			if !ok {
+				continue
+			}
+			if ref := getString(getMap(em, "what"), "reference"); ref != "" {
+				entities = append(entities, ref)
+			} else if name := getString(em, "name"); name != "" {
+				entities = append(entities, name)
+			}
+		}
+		if reason, ok := BreakGlassReason(m); ok {
+			action = "break-glass"
+			entities = append(entities, fmt.Sprintf("%q", reason))
+		}
+		fmt.Printf("  %-19s  %-12s  %-6s  %-20s  %s\n", recorded, action, outcome, agent, strings.Join(entities, ", "))
+	}
+}
+
+// DeviceDisplay returns the user-friendly name of a Device, falling back to
+// its type.
+func DeviceDisplay(m map[string]any) string {
+	if name, ok := Path(m, "deviceName.name").(string); ok && name != "" {
+		return name
+	}
+	return getString(getMap(m, "type"), "text")
+}
+
+// EncounterDisplay returns an encounter's start date and type, e.g.
+// "2026-03-02  Office visit".
+func EncounterDisplay(m map[string]any) string {
+	label, _ := Path(m, "type.text").(string)
+	if label == "" {
+		label, _ = Path(m, "class.code").(string)
+	}
+	if start := getString(getMap(m, "period"), "start"); len(start) >= 10 {
+		label = start[:10] + "  " + label
+	}
+	return label
+}
+
+// PrintDeviceList displays a patient's devices.
+func PrintDeviceList(entries []json.RawMessage) {
+	fmt.Println(headerStyle.Render(fmt.Sprintf("Devices (%d)", len(entries))))
+	for _, raw := range entries {
+		m, err := Parse(raw)
+		if err != nil {
+			continue
+		}
+		serial := getString(m, "serialNumber")
+		if serial == "" {
+			serial = "-"
+		}
+		fmt.Printf("  %-36s  %-24s  %-16s  %s\n", getString(m, "id"), DeviceDisplay(m), serial, getString(m, "status"))
+	}
+}
+
+// ScheduleDisplay returns the practitioner a Schedule belongs to.
+func ScheduleDisplay(m map[string]any) string {
+	name, _ := Path(m, "actor.display").(string)
+	if comment := getString(m, "comment"); comment != "" {
+		return name + " — " + comment
+	}
+	return name
+}
+
+// PrintSlotList displays slots as local start and end times.
+func PrintSlotList(entries []json.RawMessage) {
+	fmt.Println(headerStyle.Render(fmt.Sprintf("Open Slots (%d)", len(entries))))
+	for _, raw := range entries {
+		m, err := Parse(raw)
+		if err != nil {
+			continue
+		}
+		start, err1 := time.Parse(time.RFC3339, getString(m, "start"))
+		end, err2 := time.Parse(time.RFC3339, getString(m, "end"))
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		fmt.Printf("  %s  %s–%s  %s\n", start.Local().Format("Mon Jan 02"),
+			start.Local().Format("15:04"), end.Local().Format("15:04"), getString(m, "status"))
+	}
+}
+
+// PrintClaim displays a Claim's diagnoses, line items, and total.
+func PrintClaim(m map[string]any) {
+	created := getString(m, "created")
+	if t, err := time.Parse(time.RFC3339, created); err == nil {
+		created = t.Local().Format("2006-01-02")
+	}
+	fmt.Println(headerStyle.Render(fmt.Sprintf("Claim %s  %s  (%s)", getString(m, "id"), created, getString(m, "status"))))
+	for _, d := range getSlice(m, "diagnosis") {
+		dm, ok := d.(map[string]any)
+		if !ok {
+			continue
+		}
+		cc := getMap(dm, "diagnosisCodeableConcept")
+		code, _ := Path(cc, "coding.code").(string)
+		fmt.Printf("  Dx %d  %-8s %s\n", int(getNumber(dm, "sequence")), code, getString(cc, "text"))
+	}
+	for _, it := range getSlice(m, "item") {
+		im, ok := it.(map[string]any)
+		if !ok {
+			continue
+		}
+		ps := getMap(im, "productOrService")
+		code, _ := Path(ps, "coding.code").(string)
+		fmt.Printf("  %-6s %-44.44s %9.2f\n", code, getString(ps, "text"), getNumber(getMap(im, "net"), "value"))
+	}
+	total := getMap(m, "total")
+	fmt.Printf("  %-51s %9.2f %s\n", "Total", getNumber(total, "value"), getString(total, "currency"))
+}
+
+// PrintClaimList displays multiple claims.
+func PrintClaimList(entries []json.RawMessage) {
+	for _, raw := range entries {
+		m, err := Parse(raw)
+		if err != nil {
+			continue
+		}
+		PrintClaim(m)
+		fmt.Println()
+	}
+}
+
+// DocumentReferenceDisplay returns the title of a DocumentReference's
+// attachment, falling back to its description.
+func DocumentReferenceDisplay(m map[string]any) string {
+	if title, ok := Path(m, "content.attachment.title").(string); ok && title != "" {
+		return title
+	}
+	return getString(m, "description")
+}
+
+// PrintDocumentReferenceList displays a patient's attachments.
+func PrintDocumentReferenceList(entries []json.RawMessage) {
+	fmt.Println(headerStyle.Render(fmt.Sprintf("Attachments (%d)", len(entries))))
+	for _, raw := range entries {
+		m, err := Parse(raw)
+		if err != nil {
+			continue
+		}
+		contentType, _ := Path(m, "content.attachment.contentType").(string)
+		size, _ := Path(m, "content.attachment.size").(float64)
+		date := getString(m, "date")
+		if len(date) >= 10 {
+			date = date[:10]
+		}
+		fmt.Printf("  %-10s  %-32s  %-24s  %8s\n", date, DocumentReferenceDisplay(m), contentType, FormatBytes(int64(size)))
+	}
+}
+
+// FormatBytes renders a byte count as B, KB, or MB.
+func FormatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+// GroupManager returns who manages a Group, e.g. "Dr. Lee" for a panel, or
+// "" for a cohort with no manager.
+func GroupManager(m map[string]any) string {
+	return getString(getMap(m, "managingEntity"), "display")
+}
+
+// GroupMemberIDs returns the patient IDs of a Group's current members.
+// Members marked inactive are skipped.
+func GroupMemberIDs(m map[string]any) []string {
+	var ids []string
+	for _, mem := range getSlice(m, "member") {
+		mm, ok := mem.(map[string]any)
+		if !ok {
+			continue
+		}
+		if inactive, _ := mm["inactive"].(bool); inactive {
+			continue
+		}
+		if id, ok := strings.CutPrefix(getString(getMap(mm, "entity"), "reference"), "Patient/"); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// PrintGroup displays a Group and the names of its members.
+func PrintGroup(m map[string]any) {
+	fmt.Println(headerStyle.Render(fmt.Sprintf("%s (%d members)", getString(m, "name"), len(GroupMemberIDs(m)))))
+	if manager := GroupManager(m); manager != "" {
+		fmt.Printf("  Managed by %s\n", manager)
+	}
+	for _, mem := range getSlice(m, "member") {
+		mm, ok := mem.(map[string]any)
+		if !ok {
+			continue
+		}
+		entity := getMap(mm, "entity")
+		name := getString(entity, "display")
+		if name == "" {
+			name = getString(entity, "reference")
+		}
+		fmt.Printf("  %s\n", name)
+	}
+}
+
+// NotificationLine describes a resource received in a subscription
+// notification, e.g. "CarePlan/123 (Patient/456): Diabetes Management (2/5 activities complete)".
+func NotificationLine(m map[string]any) string {
+	ref := getString(m, "resourceType") + "/" + getString(m, "id")
+	if patient := PatientRef(m); patient != "" {
+		ref += " (Patient/" + patient + ")"
+	}
+	line := documentLine(m)
+	if strings.HasPrefix(line, getString(m, "resourceType")+"/") {
+		return ref
+	}
+	return ref + ": " + line
+}
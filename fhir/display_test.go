@@ -0,0 +1,256 @@
+package fhir
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/quick"
+	"time"
+)
+
+// magnitudes generates values from 1e-20 to 1e20 of either sign, since
+// testing/quick's own float64s are nearly all enormous.
+var magnitudes = &quick.Config{
+	MaxCount: 5000,
+	Values: func(args []reflect.Value, r *rand.Rand) {
+		for i := range args {
+			v := (r.Float64()*2 - 1) * math.Pow(10, float64(r.Intn(41)-20))
+			if r.Intn(4) == 0 {
+				v = math.Round(v)
+			}
+			args[i] = reflect.ValueOf(v)
+		}
+	},
+}
+
+func TestFormatNumberProperties(t *testing.T) {
+	near := func(v float64) bool {
+		p, err := strconv.ParseFloat(formatNumber(v), 64)
+		if err != nil {
+			return false
+		}
+		return math.Abs(p-v) <= 0.05+0.005*math.Abs(v)
+	}
+	keepsSign := func(v float64) bool {
+		p, _ := strconv.ParseFloat(formatNumber(v), 64)
+		if v == 0 {
+			return p == 0
+		}
+		return p != 0 && (p < 0) == (v < 0)
+	}
+	for name, prop := range map[string]func(float64) bool{"close to the value": near, "keeps the sign": keepsSign} {
+		if err := quick.Check(prop, magnitudes); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	wholeIsExact := func(n int32) bool {
+		return formatNumber(float64(n)) == strconv.Itoa(int(n))
+	}
+	if err := quick.Check(wholeIsExact, nil); err != nil {
+		t.Errorf("whole numbers: %v", err)
+	}
+
+	for _, v := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if got := formatNumber(v); got != "?" {
+			t.Errorf("formatNumber(%v) = %q, want ?", v, got)
+		}
+	}
+}
+
+func TestBloodPressureDisplayProperties(t *testing.T) {
+	matches := func(systolic, diastolic int16) bool {
+		m, _ := Parse(NewBloodPressureObservation("p1", int(systolic), int(diastolic)))
+		want := fmt.Sprintf("%d/%d mmHg", systolic, diastolic)
+		value, ok := observationValueDisplay(m)
+		return ok && value == want && ObservationValue(m) == want
+	}
+	if err := quick.Check(matches, nil); err != nil {
+		t.Error(err)
+	}
+
+	// Coded components are read by code, so their order does not matter.
+	orderFree := func(systolic, diastolic int16) bool {
+		m, _ := Parse(NewBloodPressureObservation("p1", int(systolic), int(diastolic)))
+		before := bloodPressureDisplay(m)
+		c := getSlice(m, "component")
+		c[0], c[1] = c[1], c[0]
+		return bloodPressureDisplay(m) == before
+	}
+	if err := quick.Check(orderFree, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestObservationValueDisplayMalformed(t *testing.T) {
+	bp := func(components ...any) map[string]any {
+		return map[string]any{
+			"code":      map[string]any{"coding": []any{map[string]any{"code": "85354-9"}}},
+			"component": components,
+		}
+	}
+	component := func(code string, value any) map[string]any {
+		c := map[string]any{"valueQuantity": map[string]any{"value": value}}
+		if code != "" {
+			c["code"] = map[string]any{"coding": []any{map[string]any{"code": code}}}
+		}
+		return c
+	}
+	quantity := func(vq map[string]any) map[string]any {
+		return map[string]any{"valueQuantity": vq}
+	}
+
+	tests := []struct {
+		name string
+		m    map[string]any
+		want string
+	}{
+		{"bp missing systolic value", bp(map[string]any{"code": map[string]any{"coding": []any{map[string]any{"code": systolicCode}}}}, component(diastolicCode, 91.0)), "?/91 mmHg"},
+		{"bp with one component", bp(component(systolicCode, 142.0)), "142/? mmHg"},
+		{"bp with no components", bp(), "?/? mmHg"},
+		{"bp with string values", bp(component(systolicCode, "142"), component(diastolicCode, "91")), "?/? mmHg"},
+		{"bp uncoded components by position", bp(component("", 142.0), component("", 91.0)), "142/91 mmHg"},
+		{"bp fractional", bp(component(systolicCode, 120.5), component(diastolicCode, 80.0)), "120.5/80 mmHg"},
+		{"bp huge", bp(component(systolicCode, 1e20), component(diastolicCode, 80.0)), "1e+20/80 mmHg"},
+		{"quantity without value", quantity(map[string]any{"unit": "kg", "code": "kg"}), "? kg"},
+		{"quantity with string value", quantity(map[string]any{"value": "70", "unit": "kg"}), "? kg"},
+		{"negative quantity", quantity(map[string]any{"value": -2.0, "unit": "mmol/L"}), "-2 mmol/L"},
+		{"tiny quantity", quantity(map[string]any{"value": 0.004, "unit": "mg/dL"}), "0.004 mg/dL"},
+		{"huge quantity", quantity(map[string]any{"value": 9.3e18, "unit": "/uL"}), "9.3e+18 /uL"},
+		{"quantity without unit", quantity(map[string]any{"value": 7.25}), "7.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := observationValueDisplay(tt.m)
+			if !ok || got != tt.want {
+				t.Errorf("observationValueDisplay = %q, %v; want %q", got, ok, tt.want)
+			}
+			if got := ObservationValue(tt.m); got != tt.want {
+				t.Errorf("ObservationValue = %q, want %q", got, tt.want)
+			}
+			if strings.Contains(got, "-9223372036854775808") {
+				t.Errorf("value overflowed: %q", got)
+			}
+		})
+	}
+
+	if _, _, ok := bloodPressure(bp(component(systolicCode, 142.0))); ok {
+		t.Error("bloodPressure reported ok with no diastolic reading")
+	}
+	if _, ok := observationValueDisplay(map[string]any{"valueString": "positive"}); ok {
+		t.Error("observationValueDisplay reported ok with no quantity")
+	}
+}
+
+func TestConditionDetails(t *testing.T) {
+	c, err := Parse(WithOnset(NewCondition("p1", "I10", "Essential Hypertension"), time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := conditionDetails(c); got != "onset 2024-03-01" {
+		t.Errorf("active condition details = %q", got)
+	}
+
+	WithResolved(c, time.Date(2026, 5, 1, 15, 0, 0, 0, time.Local))
+	if ConditionActive(c) || ConditionClinicalStatus(c) != "resolved" {
+		t.Errorf("after WithResolved, status = %q", ConditionClinicalStatus(c))
+	}
+	if got := conditionDetails(c); got != "onset 2024-03-01 · resolved 2026-05-01" {
+		t.Errorf("resolved condition details = %q", got)
+	}
+}
+
+func TestConditionCodes(t *testing.T) {
+	c, err := Parse(WithSNOMED(NewCondition("p1", "I10", "Essential Hypertension"), "38341003", "Hypertensive disorder, systemic arterial"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := conditionCodes(c); got != "I10 · SNOMED 38341003" {
+		t.Errorf("conditionCodes = %q", got)
+	}
+	// The ICD-10 coding stays first, for code that reads only one.
+	if got := firstCoding(getMap(c, "code")); got != "I10" {
+		t.Errorf("first coding = %q, want I10", got)
+	}
+
+	local := map[string]any{"code": map[string]any{"coding": []any{map[string]any{"system": "urn:local", "code": "HTN"}}}}
+	if got := conditionCodes(local); got != "HTN" {
+		t.Errorf("conditionCodes of a local code = %q", got)
+	}
+}
+
+func TestEditCondition(t *testing.T) {
+	c, _ := Parse(WithSNOMED(NewCondition("p1", "I10", "Essential Hypertension"), "38341003", "Hypertensive disorder, systemic arterial"))
+
+	// Renaming keeps both codes; recoding drops the SNOMED CT code.
+	WithConditionText(c, "Hypertension")
+	if got := conditionCodes(c); got != "I10 · SNOMED 38341003" || ConditionDisplay(c) != "Hypertension" {
+		t.Errorf("after rename: %q %q", ConditionDisplay(c), got)
+	}
+	WithConditionCode(c, "I10", "Hypertension")
+	if got := conditionCodes(c); got != "I10 · SNOMED 38341003" {
+		t.Errorf("same code: %q", got)
+	}
+	WithConditionCode(c, "I11.9", "Hypertensive heart disease")
+	if got := conditionCodes(c); got != "I11.9" || ConditionICD10(c) != "I11.9" {
+		t.Errorf("after recode: %q", got)
+	}
+
+	WithConditionStatus(c, "inactive")
+	if ConditionActive(c) || ConditionStatus(c) != "inactive" {
+		t.Errorf("inactive: status %q", ConditionStatus(c))
+	}
+	WithConditionStatus(c, "entered-in-error")
+	if ConditionActive(c) || ConditionStatus(c) != "entered-in-error" || c["clinicalStatus"] != nil {
+		t.Errorf("entered-in-error: status %q, clinicalStatus %v", ConditionStatus(c), c["clinicalStatus"])
+	}
+	if got := conditionDetails(c); got != "entered-in-error" {
+		t.Errorf("conditionDetails = %q", got)
+	}
+
+	WithResolved(c, time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC))
+	WithConditionStatus(c, "active")
+	if !ConditionActive(c) || c["verificationStatus"] != nil || c["abatementDateTime"] != nil {
+		t.Errorf("reactivated: %v", c)
+	}
+}
+
+func TestEncounterDisplay(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)
+	m, err := Parse(NewEncounter("p1", EncounterClasses[0], "Office visit", "Follow-up", start, start.Add(20*time.Minute)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := EncounterDisplay(m), "2026-03-02  Office visit"; got != want {
+		t.Errorf("EncounterDisplay = %q, want %q", got, want)
+	}
+	SetEpisodeLink(m, "e1", true)
+	if ids := EpisodeIDs(m); len(ids) != 1 || ids[0] != "e1" {
+		t.Errorf("EpisodeIDs after linking = %v, want [e1]", ids)
+	}
+	delete(m, "type")
+	if got, want := EncounterDisplay(m), "2026-03-02  AMB"; got != want {
+		t.Errorf("EncounterDisplay without a type = %q, want %q", got, want)
+	}
+}
+
+func TestObservationDate(t *testing.T) {
+	measured := time.Date(2025, 11, 3, 15, 30, 0, 0, time.Local)
+	tests := []struct {
+		m    map[string]any
+		want string
+	}{
+		{map[string]any{"effectiveDateTime": measured.Format(time.RFC3339), "meta": map[string]any{"lastUpdated": "2026-10-16T09:00:00Z"}}, "2025-11-03"},
+		{map[string]any{"effectiveDateTime": "2025-11-03"}, "2025-11-03"},
+		{map[string]any{"meta": map[string]any{"lastUpdated": "2026-10-16T09:00:00Z"}}, ""},
+	}
+	for _, tt := range tests {
+		if got := ObservationDate(tt.m); got != tt.want {
+			t.Errorf("ObservationDate(%v) = %q, want %q", tt.m, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package fhir
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+// CompositionSection is one section of a Composition: a LOINC-coded title,
+// the resources it covers, and one narrative line per resource.
+type CompositionSection struct {
+	Title   string
+	Code    string
+	Display string
+	Entries []string
+	Lines   []string
+}
+
+// VisitSummarySections builds the problem, results, and plan sections of a
+// visit summary. Only active conditions and care plans are included.
+func VisitSummarySections(observations, conditions, plans []json.RawMessage) []CompositionSection {
+	problems := CompositionSection{Title: "Problems", Code: "11450-4", Display: "Problem list - Reported"}
+	for _, raw := range conditions {
+		m, err := Parse(raw)
+		if err != nil || !ConditionActive(m) {
+			continue
+		}
+		problems.Entries = append(problems.Entries, "Condition/"+getString(m, "id"))
+		problems.Lines = append(problems.Lines, documentLine(m))
+	}
+
+	results := CompositionSection{Title: "Vital Signs and Results", Code: "30954-2", Display: "Relevant diagnostic tests/laboratory data Narrative"}
+	for _, raw := range observations {
+		m, err := Parse(raw)
+		if err != nil {
+			continue
+		}
+		results.Entries = append(results.Entries, "Observation/"+getString(m, "id"))
+		results.Lines = append(results.Lines, documentLine(m))
+	}
+
+	plan := CompositionSection{Title: "Plan of Care", Code: "18776-5", Display: "Plan of care note"}
+	for _, raw := range plans {
+		m, err := Parse(raw)
+		if err != nil || getString(m, "status") != "active" {
+			continue
+		}
+		plan.Entries = append(plan.Entries, "CarePlan/"+getString(m, "id"))
+		plan.Lines = append(plan.Lines, documentLine(m))
+	}
+	return []CompositionSection{problems, results, plan}
+}
+
+// documentLine is the one-line narrative for a resource in a document.
+func documentLine(m map[string]any) string {
+	switch getString(m, "resourceType") {
+	case "Condition":
+		if code := conditionCodes(m); code != "" {
+			return fmt.Sprintf("%s (%s)", ConditionDisplay(m), code)
+		}
+		return ConditionDisplay(m)
+	case "Observation":
+		line := getString(getMap(m, "code"), "text") + ": " + ObservationValue(m)
+		if members := getSlice(m, "hasMember"); len(members) > 0 && ObservationValue(m) == "" {
+			line = fmt.Sprintf("%s (%d results)", getString(getMap(m, "code"), "text"), len(members))
+		}
+		if date := dateOnly(getString(m, "effectiveDateTime")); date != "" {
+			line += " on " + date
+		}
+		return line
+	case "CarePlan":
+		completed, total := carePlanProgress(m)
+		return fmt.Sprintf("%s (%d/%d activities complete)", getString(m, "title"), completed, total)
+	case "Patient":
+		return PatientName(m)
+	}
+	return getString(m, "resourceType") + "/" + getString(m, "id")
+}
+
+// NewComposition builds a final Composition (LOINC 34133-9, summary of
+// episode note) for a patient, optionally tied to an encounter.
+func NewComposition(patientID, encounterRef, title string, sections []CompositionSection, now time.Time) json.RawMessage {
+	var secs []map[string]any
+	for _, s := range sections {
+		sec := map[string]any{
+			"title": s.Title,
+			"code": map[string]any{
+				"coding": []map[string]any{{"system": "http://loinc.org", "code": s.Code, "display": s.Display}},
+			},
+			"text": map[string]any{"status": "generated", "div": narrativeDiv(s.Lines)},
+		}
+		if len(s.Entries) > 0 {
+			var entries []map[string]any
+			for _, ref := range s.Entries {
+				entries = append(entries, map[string]any{"reference": ref})
+			}
+			sec["entry"] = entries
+		} else {
+			sec["emptyReason"] = map[string]any{
+				"coding": []map[string]any{{"system": "http://terminology.hl7.org/CodeSystem/list-empty-reason", "code": "nilknown", "display": "Nil Known"}},
+			}
+		}
+		secs = append(secs, sec)
+	}
+
+	c := map[string]any{
+		"resourceType": "Composition",
+		"status":       "final",
+		"type": map[string]any{
+			"coding": []map[string]any{{"system": "http://loinc.org", "code": "34133-9", "display": "Summary of episode note"}},
+		},
+		"subject": map[string]any{"reference": "Patient/" + patientID},
+		"date":    now.UTC().Format(time.RFC3339),
+		"author":  []map[string]any{{"display": "Community Health Clinic"}},
+		"title":   title,
+		"section": secs,
+	}
+	if encounterRef != "" {
+		c["encounter"] = map[string]any{"reference": encounterRef}
+	}
+	b, _ := json.Marshal(c)
+	return b
+}
+
+func narrativeDiv(lines []string) string {
+	if len(lines) == 0 {
+		return `<div xmlns="http://www.w3.org/1999/xhtml"><p>None recorded.</p></div>`
+	}
+	var b strings.Builder
+	b.WriteString(`<div xmlns="http://www.w3.org/1999/xhtml"><ul>`)
+	for _, l := range lines {
+		b.WriteString("<li>" + html.EscapeString(l) + "</li>")
+	}
+	b.WriteString("</ul></div>")
+	return b.String()
+}
+
+// DocumentBundle assembles a document Bundle on the client, the way
+// Composition/$document does on a server: the Composition first, then every
+// resource it references. fullUrls are the resources' relative references.
+func DocumentBundle(composition json.RawMessage, resources []json.RawMessage, now time.Time) json.RawMessage {
+	entries := []map[string]any{{"fullUrl": "Composition/" + ResourceID(composition), "resource": composition}}
+	for _, raw := range resources {
+		m, err := Parse(raw)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, map[string]any{
+			"fullUrl":  getString(m, "resourceType") + "/" + getString(m, "id"),
+			"resource": raw,
+		})
+	}
+	b, _ := json.Marshal(map[string]any{
+		"resourceType": "Bundle",
+		"type":         "document",
+		"timestamp":    now.UTC().Format(time.RFC3339),
+		"entry":        entries,
+	})
+	return b
+}
+
+// PrintDocument displays a document Bundle: the Composition's title and
+// subject, then each section with a line per referenced resource.
+func PrintDocument(bundle json.RawMessage) error {
+	b, err := Parse(bundle)
+	if err != nil {
+		return err
+	}
+	byRef := make(map[string]map[string]any)
+	var composition map[string]any
+	for _, e := range getSlice(b, "entry") {
+		entry, _ := e.(map[string]any)
+		res := getMap(entry, "resource")
+		if res == nil {
+			continue
+		}
+		rt := getString(res, "resourceType")
+		if rt == "Composition" && composition == nil {
+			composition = res
+		}
+		byRef[rt+"/"+getString(res, "id")] = res
+	}
+	if composition == nil {
+		return fmt.Errorf("document has no Composition")
+	}
+
+	subject := getString(getMap(composition, "subject"), "reference")
+	if p, ok := byRef[subject]; ok {
+		subject = PatientName(p)
+	}
+	fmt.Println(headerStyle.Render(getString(composition, "title")))
+	fmt.Printf("  %s %s\n", labelStyle.Render("Patient:"), subject)
+	fmt.Printf("  %s %s\n", labelStyle.Render("Date:"), dateOnly(getString(composition, "date")))
+	for _, s := range getSlice(composition, "section") {
+		sec, _ := s.(map[string]any)
+		fmt.Println()
+		fmt.Println(headerStyle.Render("  " + getString(sec, "title")))
+		entries := getSlice(sec, "entry")
+		if len(entries) == 0 {
+			fmt.Println("    None recorded.")
+		}
+		for _, e := range entries {
+			em, _ := e.(map[string]any)
+			ref := getString(em, "reference")
+			if res, ok := byRef[ref]; ok {
+				fmt.Println("    " + documentLine(res))
+			} else {
+				fmt.Println("    " + ref)
+			}
+		}
+	}
+	fmt.Printf("\n  %d resources in document\n", len(getSlice(b, "entry")))
+	return nil
+}
@@ -0,0 +1,193 @@
+package fhir
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EpisodeOfCareExtension links a CarePlan to an EpisodeOfCare. R4 CarePlan
+// has no episode element, so this is the cross-version workflow extension.
+const EpisodeOfCareExtension = "http://hl7.org/fhir/StructureDefinition/workflow-episodeOfCare"
+
+// EpisodeType is a chronic-disease program an EpisodeOfCare can track.
+type EpisodeType struct {
+	Code    string // SNOMED CT
+	Display string
+}
+
+// EpisodeTypes are the programs offered when starting an episode.
+var EpisodeTypes = []EpisodeType{
+	{"709044004", "Chronic kidney disease"},
+	{"44054006", "Diabetes mellitus type 2"},
+	{"38341003", "Hypertension"},
+	{"84114007", "Heart failure"},
+	{"13645005", "Chronic obstructive pulmonary disease"},
+}
+
+// NewEpisodeOfCare builds an active EpisodeOfCare for a patient, starting
+// now. conditionID, if set, is recorded as the episode's diagnosis.
+func NewEpisodeOfCare(patientID string, t EpisodeType, conditionID string, now time.Time) json.RawMessage {
+	e := map[string]any{
+		"resourceType": "EpisodeOfCare",
+		"status":       "active",
+		"type": []map[string]any{{
+			"coding": []map[string]any{{"system": "http://snomed.info/sct", "code": t.Code, "display": t.Display}},
+			"text":   t.Display + " care",
+		}},
+		"patient":              map[string]any{"reference": "Patient/" + patientID},
+		"managingOrganization": map[string]any{"display": "Community Health Clinic"},
+		"period":               map[string]any{"start": now.Format("2006-01-02")},
+	}
+	if conditionID != "" {
+		e["diagnosis"] = []map[string]any{{
+			"condition": map[string]any{"reference": "Condition/" + conditionID},
+			"rank":      1,
+		}}
+	}
+	b, _ := json.Marshal(e)
+	return b
+}
+
+// EpisodeIDs returns the EpisodeOfCare IDs an Encounter (episodeOfCare) or
+// CarePlan (EpisodeOfCareExtension) is linked to.
+func EpisodeIDs(m map[string]any) []string {
+	var ids []string
+	for _, e := range getSlice(m, "episodeOfCare") {
+		ref, _ := e.(map[string]any)
+		if id, ok := strings.CutPrefix(getString(ref, "reference"), "EpisodeOfCare/"); ok {
+			ids = append(ids, id)
+		}
+	}
+	for _, e := range getSlice(m, "extension") {
+		ext, _ := e.(map[string]any)
+		if getString(ext, "url") != EpisodeOfCareExtension {
+			continue
+		}
+		if id, ok := strings.CutPrefix(getString(getMap(ext, "valueReference"), "reference"), "EpisodeOfCare/"); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// InEpisode reports whether a resource is linked to the episode.
+func InEpisode(m map[string]any, episodeID string) bool {
+	for _, id := range EpisodeIDs(m) {
+		if id == episodeID {
+			return true
+		}
+	}
+	return false
+}
+
+// SetEpisodeLink links a resource to an episode or removes the link, and
+// reports whether anything changed. Encounters use episodeOfCare; other
+// resources use EpisodeOfCareExtension.
+func SetEpisodeLink(m map[string]any, episodeID string, linked bool) bool {
+	if InEpisode(m, episodeID) == linked {
+		return false
+	}
+	ref := "EpisodeOfCare/" + episodeID
+	field, match := "extension", func(v map[string]any) bool {
+		return getString(v, "url") == EpisodeOfCareExtension && getString(getMap(v, "valueReference"), "reference") == ref
+	}
+	add := map[string]any{"url": EpisodeOfCareExtension, "valueReference": map[string]any{"reference": ref}}
+	if getString(m, "resourceType") == "Encounter" {
+		field, match = "episodeOfCare", func(v map[string]any) bool { return getString(v, "reference") == ref }
+		add = map[string]any{"reference": ref}
+	}
+
+	var kept []any
+	for _, v := range getSlice(m, field) {
+		if vm, ok := v.(map[string]any); ok && match(vm) {
+			continue
+		}
+		kept = append(kept, v)
+	}
+	if linked {
+		kept = append(kept, add)
+	}
+	if len(kept) == 0 {
+		delete(m, field)
+	} else {
+		m[field] = kept
+	}
+	return true
+}
+
+// EpisodeDisplay returns an episode's program and period, e.g.
+// "Chronic kidney disease care (active, since 2026-01-15)".
+func EpisodeDisplay(m map[string]any) string {
+	label, _ := Path(m, "type.text").(string)
+	if label == "" {
+		label = "Episode of care"
+	}
+	status := getString(m, "status")
+	period := getMap(m, "period")
+	if end := getString(period, "end"); end != "" {
+		return fmt.Sprintf("%s (%s, %s to %s)", label, status, dateOnly(getString(period, "start")), dateOnly(end))
+	}
+	return fmt.Sprintf("%s (%s, since %s)", label, status, dateOnly(getString(period, "start")))
+}
+
+// TimelineEvent is one dated line on an episode timeline.
+type TimelineEvent struct {
+	Date string
+	Kind string
+	Text string
+}
+
+// EpisodeTimeline orders an episode's start and end, its encounters, and its
+// care plans with their dated activities.
+func EpisodeTimeline(episode map[string]any, encounters, plans []map[string]any) []TimelineEvent {
+	label, _ := Path(episode, "type.text").(string)
+	period := getMap(episode, "period")
+	events := []TimelineEvent{{dateOnly(getString(period, "start")), "Episode", "Started " + label}}
+	if end := getString(period, "end"); end != "" {
+		events = append(events, TimelineEvent{dateOnly(end), "Episode", "Ended (" + getString(episode, "status") + ")"})
+	}
+
+	for _, m := range encounters {
+		text, _ := Path(m, "type.text").(string)
+		if text == "" {
+			text, _ = Path(m, "class.code").(string)
+		}
+		events = append(events, TimelineEvent{dateOnly(getString(getMap(m, "period"), "start")), "Encounter", text})
+	}
+
+	for _, m := range plans {
+		start := getString(getMap(m, "period"), "start")
+		if start == "" {
+			start = getString(m, "created")
+		}
+		events = append(events, TimelineEvent{dateOnly(start), "CarePlan", fmt.Sprintf("%s (%s)", getString(m, "title"), getString(m, "status"))})
+		for _, a := range getSlice(m, "activity") {
+			act, _ := a.(map[string]any)
+			detail := getMap(act, "detail")
+			due, ok := ScheduledDate(getString(detail, "scheduledString"))
+			if !ok {
+				continue
+			}
+			events = append(events, TimelineEvent{due.Format("2006-01-02"), "Activity",
+				fmt.Sprintf("%s (%s)", getString(detail, "description"), getString(detail, "status"))})
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Date < events[j].Date })
+	return events
+}
+
+// PrintEpisodeTimeline displays an episode and its timeline.
+func PrintEpisodeTimeline(episode map[string]any, events []TimelineEvent) {
+	fmt.Println(headerStyle.Render(EpisodeDisplay(episode)))
+	for _, e := range events {
+		date := e.Date
+		if date == "" {
+			date = "(undated)"
+		}
+		fmt.Printf("  %-10s  %s %s\n", date, labelStyle.Render(fmt.Sprintf("%-9s", e.Kind)), e.Text)
+	}
+}
@@ -0,0 +1,203 @@
+package fhir
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// EscalationSystem is the code system of the Task code marking an
+// escalated overdue activity, and EscalationCode that code.
+const (
+	EscalationSystem = "https://example.org/fhir/CodeSystem/escalation"
+	EscalationCode   = "overdue-activity"
+)
+
+// EscalationKeySystem is the identifier system of escalation Tasks. The
+// value names the escalated activity, e.g. "CarePlan/123/activity/2", so
+// each activity is escalated at most once.
+const EscalationKeySystem = "https://example.org/fhir/NamingSystem/escalation-key"
+
+// Escalation is a care plan activity overdue by more than the escalation
+// threshold.
+type Escalation struct {
+	CarePlanID string
+	PatientID  string
+	Plan       string // plan title
+	Activity   string // activity description
+	Index      int    // index of the activity in the plan
+	Due        time.Time
+	// DaysOverdue is how many whole days have passed since the due date.
+	DaysOverdue int
+}
+
+// Key is the escalation's identifier value.
+func (e Escalation) Key() string {
+	return EscalationKey(e.CarePlanID, e.Index)
+}
+
+// EscalationKey is the key of the activity at index in a care plan.
+func EscalationKey(carePlanID string, index int) string {
+	return fmt.Sprintf("CarePlan/%s/activity/%d", carePlanID, index)
+}
+
+// daysOverdue is how many whole days after due now is.
+func daysOverdue(due, now time.Time) int {
+	return int(now.Sub(due) / (24 * time.Hour))
+}
+
+// PlanEscalations returns the activities of a care plan that are neither
+// completed nor cancelled and were due more than days days before now. open
+// holds the keys of all the plan's outstanding activities, overdue or not,
+// so escalations whose activity has since been done can be closed.
+func PlanEscalations(carePlan map[string]any, now time.Time, days int) (escalations []Escalation, open []string) {
+	id := getString(carePlan, "id")
+	for i, a := range getSlice(carePlan, "activity") {
+		act, _ := a.(map[string]any)
+		detail := getMap(act, "detail")
+		if detail == nil {
+			continue
+		}
+		switch getString(detail, "status") {
+		case "completed", "cancelled", "stopped", "entered-in-error":
+			continue
+		}
+		open = append(open, EscalationKey(id, i))
+		due, ok := ScheduledDate(getString(detail, "scheduledString"))
+		if !ok || daysOverdue(due, now) <= days {
+			continue
+		}
+		escalations = append(escalations, Escalation{
+			CarePlanID:  id,
+			PatientID:   PatientRef(carePlan),
+			Plan:        getString(carePlan, "title"),
+			Activity:    getString(detail, "description"),
+			Index:       i,
+			Due:         due,
+			DaysOverdue: daysOverdue(due, now),
+		})
+	}
+	return escalations, open
+}
+
+// NewEscalationTask builds an urgent Task to follow up on an overdue
+// activity, based on its care plan and owned by no one yet.
+func NewEscalationTask(e Escalation) json.RawMessage {
+	t := map[string]any{
+		"resourceType": "Task",
+		"identifier":   []map[string]any{{"system": EscalationKeySystem, "value": e.Key()}},
+		"status":       "requested",
+		"intent":       "order",
+		"priority":     "urgent",
+		"code": map[string]any{
+			"coding": []map[string]any{{"system": EscalationSystem, "code": EscalationCode, "display": "Overdue care plan activity"}},
+		},
+		"description": fmt.Sprintf("Overdue: %s (%s)", e.Activity, e.Plan),
+		"basedOn":     []map[string]any{{"reference": "CarePlan/" + e.CarePlanID}},
+		"for":         map[string]any{"reference": "Patient/" + e.PatientID},
+		"authoredOn":  time.Now().UTC().Format(time.RFC3339),
+		"restriction": map[string]any{
+			"period": map[string]any{"end": e.Due.Format("2006-01-02")},
+		},
+	}
+	b, _ := json.Marshal(t)
+	return b
+}
+
+// TaskEscalationKey returns an escalation Task's key, or "" if the Task is
+// not an escalation.
+func TaskEscalationKey(task map[string]any) string {
+	for _, id := range getSlice(task, "identifier") {
+		im, _ := id.(map[string]any)
+		if getString(im, "system") == EscalationKeySystem {
+			return getString(im, "value")
+		}
+	}
+	return ""
+}
+
+// TaskOpen reports whether a Task still needs doing.
+func TaskOpen(task map[string]any) bool {
+	switch getString(task, "status") {
+	case "completed", "cancelled", "rejected", "failed", "entered-in-error":
+		return false
+	}
+	return true
+}
+
+// TaskPatient returns the ID of the patient a Task is for.
+func TaskPatient(task map[string]any) string {
+	ref := getString(getMap(task, "for"), "reference")
+	return strings.TrimPrefix(ref, "Patient/")
+}
+
+// TaskOwner returns who a Task is assigned to, or "".
+func TaskOwner(task map[string]any) string {
+	owner := getMap(task, "owner")
+	if d := getString(owner, "display"); d != "" {
+		return d
+	}
+	return getString(owner, "reference")
+}
+
+// WithTaskOwner assigns a Task to a practitioner, by name, and marks it
+// modified now.
+func WithTaskOwner(task map[string]any, practitioner string, now time.Time) {
+	task["owner"] = map[string]any{"display": practitioner}
+	task["lastModified"] = now.UTC().Format(time.RFC3339)
+}
+
+// WithTaskCompleted marks a Task completed now.
+func WithTaskCompleted(task map[string]any, now time.Time) {
+	task["status"] = "completed"
+	task["lastModified"] = now.UTC().Format(time.RFC3339)
+}
+
+// taskDue returns the end of a Task's restriction period.
+func taskDue(task map[string]any) (time.Time, bool) {
+	return ScheduledDate(getString(getMap(getMap(task, "restriction"), "period"), "end"))
+}
+
+// EscalationDisplay describes an escalation Task on one line: what is
+// overdue, by how long, and who it is assigned to.
+func EscalationDisplay(task map[string]any, now time.Time) string {
+	line := strings.TrimPrefix(getString(task, "description"), "Overdue: ")
+	if due, ok := taskDue(task); ok {
+		line += fmt.Sprintf(", %s overdue", plural(daysOverdue(due, now), "day", "days"))
+	}
+	owner := TaskOwner(task)
+	if owner == "" {
+		owner = "unassigned"
+	}
+	return line + " → " + owner
+}
+
+var escalationStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+
+// PrintEscalations shows open escalation Tasks in red, longest overdue
+// first. names maps patient IDs to display names.
+func PrintEscalations(tasks []map[string]any, names map[string]string, now time.Time) {
+	if len(tasks) == 0 {
+		return
+	}
+	sorted := append([]map[string]any{}, tasks...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		di, _ := taskDue(sorted[i])
+		dj, _ := taskDue(sorted[j])
+		return di.Before(dj)
+	})
+
+	fmt.Println(flagStyle.Render(fmt.Sprintf("Escalations (%d)", len(sorted))))
+	for _, t := range sorted {
+		name := names[TaskPatient(t)]
+		if name == "" {
+			name = getString(getMap(t, "for"), "reference")
+		}
+		fmt.Println(escalationStyle.Render(fmt.Sprintf("  %-20s %s", name, EscalationDisplay(t, now))))
+	}
+	fmt.Println()
+}
@@ -0,0 +1,73 @@
+package fhir
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPlanEscalations(t *testing.T) {
+	activity := func(desc, status, due string) any {
+		return map[string]any{"detail": map[string]any{"description": desc, "status": status, "scheduledString": "By " + due}}
+	}
+	cp := map[string]any{
+		"resourceType": "CarePlan",
+		"id":           "cp1",
+		"title":        "Diabetes management",
+		"subject":      map[string]any{"reference": "Patient/p1"},
+		"activity": []any{
+			activity("HbA1c test", "scheduled", "2026-04-01"),  // 31 days overdue
+			activity("Eye exam", "not-started", "2026-04-25"),  // 7 days overdue
+			activity("Foot exam", "completed", "2026-03-01"),   // done
+			activity("Diet review", "cancelled", "2026-03-01"), // cancelled
+			activity("Follow-up call", "in-progress", "2026-06-01"),
+		},
+	}
+	now := time.Date(2026, 5, 2, 9, 0, 0, 0, time.UTC)
+
+	escalations, open := PlanEscalations(cp, now, 7)
+	if len(escalations) != 1 {
+		t.Fatalf("got %d escalations, want 1: %+v", len(escalations), escalations)
+	}
+	e := escalations[0]
+	if e.Key() != "CarePlan/cp1/activity/0" || e.PatientID != "p1" || e.Activity != "HbA1c test" || e.DaysOverdue != 31 {
+		t.Errorf("escalation = %+v", e)
+	}
+	wantOpen := []string{"CarePlan/cp1/activity/0", "CarePlan/cp1/activity/1", "CarePlan/cp1/activity/4"}
+	if !reflect.DeepEqual(open, wantOpen) {
+		t.Errorf("open = %v, want %v", open, wantOpen)
+	}
+
+	if escalations, _ := PlanEscalations(cp, now, 6); len(escalations) != 2 {
+		t.Errorf("with 6 days got %d escalations, want 2", len(escalations))
+	}
+}
+
+func TestEscalationTask(t *testing.T) {
+	e := Escalation{CarePlanID: "cp1", PatientID: "p1", Plan: "Diabetes management", Activity: "HbA1c test",
+		Due: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)}
+	task, err := Parse(NewEscalationTask(e))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key := TaskEscalationKey(task); key != e.Key() {
+		t.Errorf("TaskEscalationKey = %q, want %q", key, e.Key())
+	}
+	if TaskPatient(task) != "p1" || !TaskOpen(task) {
+		t.Errorf("task for %q, open %v", TaskPatient(task), TaskOpen(task))
+	}
+
+	now := time.Date(2026, 5, 2, 9, 0, 0, 0, time.UTC)
+	if got, want := EscalationDisplay(task, now), "HbA1c test (Diabetes management), 31 days overdue → unassigned"; got != want {
+		t.Errorf("EscalationDisplay = %q, want %q", got, want)
+	}
+	WithTaskOwner(task, "Dr. Rivera", now)
+	if got := EscalationDisplay(task, now); !strings.HasSuffix(got, "→ Dr. Rivera") {
+		t.Errorf("after reassigning, EscalationDisplay = %q", got)
+	}
+	WithTaskCompleted(task, now)
+	if TaskOpen(task) {
+		t.Error("completed task is still open")
+	}
+}
@@ -0,0 +1,255 @@
+package fhir
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// GoalMeasure is a measurement a patient can have a numeric target for.
+type GoalMeasure struct {
+	Key  string // short name used in PHENOSTORE_GOAL_TARGETS
+	Name string
+	Code string // LOINC of the observation the target is checked against
+	Unit string
+}
+
+// GoalMeasures are the measurements goals can target. A target is met when
+// the latest value is below it: HbA1c in %, and blood pressure as
+// systolic/diastolic in mmHg.
+var GoalMeasures = []GoalMeasure{
+	{Key: "hba1c", Name: "HbA1c", Code: "4548-4", Unit: "%"},
+	{Key: "bp", Name: "Blood pressure", Code: "85354-9", Unit: "mmHg"},
+}
+
+// LookupGoalMeasure finds a goal measure by key.
+func LookupGoalMeasure(key string) (GoalMeasure, bool) {
+	for _, m := range GoalMeasures {
+		if m.Key == key {
+			return m, true
+		}
+	}
+	return GoalMeasure{}, false
+}
+
+// GoalTarget is a patient's target for one measure: the latest value should
+// be below Value, and for blood pressure the diastolic below Diastolic.
+type GoalTarget struct {
+	Measure   GoalMeasure
+	Value     float64
+	Diastolic float64
+	GoalID    string // the Goal it came from, or "" for a configured default
+}
+
+// String formats the target, e.g. "< 7%" or "< 130/80 mmHg".
+func (t GoalTarget) String() string {
+	if t.Measure.Key == "bp" {
+		return fmt.Sprintf("< %s/%s mmHg", formatNumber(t.Value), formatNumber(t.Diastolic))
+	}
+	return "< " + formatNumber(t.Value) + t.Measure.Unit
+}
+
+// ParseGoalTarget reads a target as typed for a measure: a number such as
+// "7" or "6.5%", or systolic/diastolic such as "130/80" for blood pressure.
+func ParseGoalTarget(m GoalMeasure, s string) (GoalTarget, error) {
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), m.Unit))
+	t := GoalTarget{Measure: m}
+	if m.Key == "bp" {
+		var extra string
+		if n, _ := fmt.Sscanf(s, "%g/%g%s", &t.Value, &t.Diastolic, &extra); n != 2 || t.Value <= 0 || t.Diastolic <= 0 {
+			return GoalTarget{}, fmt.Errorf("use systolic/diastolic, e.g. 130/80")
+		}
+		return t, nil
+	}
+	var extra string
+	if n, _ := fmt.Sscanf(s, "%g%s", &t.Value, &extra); n != 1 || t.Value <= 0 {
+		return GoalTarget{}, fmt.Errorf("use a positive number, e.g. 7")
+	}
+	return t, nil
+}
+
+// goalDetail is one Goal.target entry: a LOINC measure and an upper bound.
+func goalDetail(code, display string, value float64, unit, ucum string) map[string]any {
+	return map[string]any{
+		"measure": map[string]any{"coding": []map[string]any{{"system": "http://loinc.org", "code": code, "display": display}}},
+		"detailQuantity": map[string]any{
+			"value": value, "comparator": "<", "unit": unit,
+			"system": "http://unitsofmeasure.org", "code": ucum,
+		},
+	}
+}
+
+// NewGoal builds an active FHIR Goal holding a numeric target. A blood
+// pressure target has one target entry for systolic and one for diastolic.
+func NewGoal(patientID string, t GoalTarget) json.RawMessage {
+	var targets []map[string]any
+	if t.Measure.Key == "bp" {
+		targets = []map[string]any{
+			goalDetail(systolicCode, "Systolic blood pressure", t.Value, "mmHg", "mm[Hg]"),
+			goalDetail(diastolicCode, "Diastolic blood pressure", t.Diastolic, "mmHg", "mm[Hg]"),
+		}
+	} else {
+		targets = []map[string]any{goalDetail(t.Measure.Code, t.Measure.Name, t.Value, t.Measure.Unit, t.Measure.Unit)}
+	}
+	g := map[string]any{
+		"resourceType":    "Goal",
+		"lifecycleStatus": "active",
+		"description":     map[string]any{"text": t.Measure.Name + " " + t.String()},
+		"subject":         map[string]any{"reference": "Patient/" + patientID},
+		"target":          targets,
+	}
+	b, _ := json.Marshal(g)
+	return b
+}
+
+// GoalTargets reads the numeric targets of a patient's active Goals. When
+// several Goals target one measure, the most recently updated wins. Goals
+// without a target for a known measure are skipped.
+func GoalTargets(goals []json.RawMessage) []GoalTarget {
+	byMeasure := make(map[string]GoalTarget)
+	updated := make(map[string]string)
+	for _, raw := range goals {
+		m, err := Parse(raw)
+		if err != nil || getString(m, "lifecycleStatus") != "active" {
+			continue
+		}
+		bounds := make(map[string]float64)
+		for _, t := range getSlice(m, "target") {
+			tm, _ := t.(map[string]any)
+			if v, ok := numberValue(getMap(tm, "detailQuantity"), "value"); ok {
+				bounds[firstCoding(getMap(tm, "measure"))] = v
+			}
+		}
+		var t GoalTarget
+		for _, gm := range GoalMeasures {
+			switch {
+			case gm.Key == "bp" && bounds[systolicCode] > 0 && bounds[diastolicCode] > 0:
+				t = GoalTarget{Measure: gm, Value: bounds[systolicCode], Diastolic: bounds[diastolicCode]}
+			case gm.Key != "bp" && bounds[gm.Code] > 0:
+				t = GoalTarget{Measure: gm, Value: bounds[gm.Code]}
+			default:
+				continue
+			}
+			t.GoalID = getString(m, "id")
+			at, _ := Path(m, "meta.lastUpdated").(string)
+			if prev, seen := updated[gm.Key]; !seen || at >= prev {
+				byMeasure[gm.Key], updated[gm.Key] = t, at
+			}
+		}
+	}
+	var targets []GoalTarget
+	for _, gm := range GoalMeasures {
+		if t, ok := byMeasure[gm.Key]; ok {
+			targets = append(targets, t)
+		}
+	}
+	return targets
+}
+
+// GoalProgress is how a patient stands against one target.
+type GoalProgress struct {
+	Target GoalTarget
+	Latest string    // latest value as ObservationValue shows it, or ""
+	Date   time.Time // when the latest value was measured
+	Met    bool
+	// Change is the latest value minus the one before it (systolic for
+	// blood pressure), and HasPrevious whether there was one.
+	Change      float64
+	HasPrevious bool
+}
+
+// Trend describes the direction of the last change. Lower is better for
+// every goal measure.
+func (p GoalProgress) Trend() string {
+	switch {
+	case !p.HasPrevious:
+		return ""
+	case p.Change < 0:
+		return "↓ improving"
+	case p.Change > 0:
+		return "↑ worsening"
+	}
+	return "→ unchanged"
+}
+
+// EvaluateGoals compares each target with the patient's latest observation
+// of its measure. Observations without a date or value, and those entered
+// in error, are ignored.
+func EvaluateGoals(targets []GoalTarget, observations []map[string]any) []GoalProgress {
+	progress := make([]GoalProgress, 0, len(targets))
+	for _, t := range targets {
+		type reading struct {
+			m         map[string]any
+			at        time.Time
+			v, second float64
+		}
+		var readings []reading
+		for _, m := range observations {
+			if observationLoincCode(m) != t.Measure.Code || getString(m, "status") == "entered-in-error" {
+				continue
+			}
+			at, ok := effectiveTime(m)
+			if !ok {
+				continue
+			}
+			if t.Measure.Key == "bp" {
+				if s, d, ok := bloodPressure(m); ok {
+					readings = append(readings, reading{m, at, s, d})
+				}
+			} else if v, ok := numberValue(getMap(m, "valueQuantity"), "value"); ok {
+				readings = append(readings, reading{m: m, at: at, v: v})
+			}
+		}
+		sort.SliceStable(readings, func(i, j int) bool { return readings[i].at.Before(readings[j].at) })
+
+		p := GoalProgress{Target: t}
+		if n := len(readings); n > 0 {
+			last := readings[n-1]
+			p.Latest, p.Date = ObservationValue(last.m), last.at
+			p.Met = last.v < t.Value && (t.Measure.Key != "bp" || last.second < t.Diastolic)
+			if n > 1 {
+				p.Change, p.HasPrevious = last.v-readings[n-2].v, true
+			}
+		}
+		progress = append(progress, p)
+	}
+	return progress
+}
+
+var (
+	goalMetStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	goalMissedStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("1"))
+)
+
+// PrintGoalProgress prints each goal with the latest value, whether it is
+// met, and which way it is heading.
+func PrintGoalProgress(progress []GoalProgress) {
+	if len(progress) == 0 {
+		return
+	}
+	fmt.Println(headerStyle.Render("Goals"))
+	for _, p := range progress {
+		line := fmt.Sprintf("  %s target %-16s", labelStyle.Render(p.Target.Measure.Name), p.Target.String())
+		if p.Latest == "" {
+			fmt.Println(line + "  no readings yet")
+			continue
+		}
+		status := goalMissedStyle.Render("not met")
+		if p.Met {
+			status = goalMetStyle.Render("met")
+		}
+		line += fmt.Sprintf("  latest %s (%s)  %s", p.Latest, p.Date.Local().Format("2006-01-02"), status)
+		if trend := p.Trend(); trend != "" {
+			line += "  " + trend
+		}
+		if p.Target.GoalID == "" {
+			line += "  (clinic default)"
+		}
+		fmt.Println(line)
+	}
+	fmt.Println()
+}
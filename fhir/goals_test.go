@@ -0,0 +1,43 @@
+package fhir
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEvaluateGoals(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 9, 0, 0, 0, time.UTC) }
+	hba1c, _ := LookupGoalMeasure("hba1c")
+	bp, _ := LookupGoalMeasure("bp")
+
+	goals := GoalTargets([]json.RawMessage{
+		NewGoal("p1", GoalTarget{Measure: hba1c, Value: 7}),
+		NewGoal("p1", GoalTarget{Measure: bp, Value: 130, Diastolic: 80}),
+	})
+	if len(goals) != 2 || goals[0].Value != 7 || goals[1].Value != 130 || goals[1].Diastolic != 80 {
+		t.Fatalf("GoalTargets = %+v", goals)
+	}
+
+	var observations []map[string]any
+	for _, raw := range []json.RawMessage{
+		WithEffective(NewHbA1cObservation("p1", 8.1), day(1)),
+		WithEffective(NewHbA1cObservation("p1", 7.4), day(20)),
+		WithEffective(NewBloodPressureObservation("p1", 124, 84), day(10)), // diastolic over
+		WithEffective(NewBloodPressureObservation("p1", 118, 76), day(2)),
+	} {
+		m, _ := Parse(raw)
+		observations = append(observations, m)
+	}
+	progress := EvaluateGoals(goals, observations)
+	if p := progress[0]; p.Met || p.Latest != "7.4 %" || p.Trend() != "↓ improving" {
+		t.Errorf("HbA1c = %+v, trend %q", p, p.Trend())
+	}
+	if p := progress[1]; p.Met || p.Trend() != "↑ worsening" || !p.Date.Equal(day(10)) {
+		t.Errorf("blood pressure = %+v, trend %q", p, p.Trend())
+	}
+
+	if p := EvaluateGoals(goals[:1], nil)[0]; p.Latest != "" || p.Trend() != "" {
+		t.Errorf("no readings = %+v", p)
+	}
+}
@@ -0,0 +1,90 @@
+package fhir
+
+import (
+	_ "embed"
+	"sort"
+	"strings"
+)
+
+// ICD10Code is one ICD-10-CM code and its description.
+type ICD10Code struct {
+	Code        string
+	Description string
+}
+
+//go:embed icd10cm.txt
+var icd10Data string
+
+// icd10Codes is the bundled ICD-10-CM subset, in file order, and icd10ByCode
+// indexes it.
+var icd10Codes, icd10ByCode = parseICD10(icd10Data)
+
+func parseICD10(data string) ([]ICD10Code, map[string]ICD10Code) {
+	var codes []ICD10Code
+	byCode := make(map[string]ICD10Code)
+	for _, line := range strings.Split(data, "\n") {
+		code, desc, ok := strings.Cut(line, "\t")
+		if !ok || strings.HasPrefix(line, "#") {
+			continue
+		}
+		c := ICD10Code{Code: code, Description: strings.TrimSpace(desc)}
+		codes = append(codes, c)
+		byCode[code] = c
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i].Code < codes[j].Code })
+	return codes, byCode
+}
+
+// ICD10Codes returns the bundled ICD-10-CM subset, sorted by code.
+func ICD10Codes() []ICD10Code {
+	return icd10Codes
+}
+
+// NormalizeICD10 puts a code as typed into its canonical form: upper case,
+// with the dot after the category, so "e119" becomes "E11.9".
+func NormalizeICD10(code string) string {
+	code = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), ".", ""))
+	if len(code) > 3 {
+		code = code[:3] + "." + code[3:]
+	}
+	return code
+}
+
+// LookupICD10 finds a code in the bundled subset, however it is typed.
+func LookupICD10(code string) (ICD10Code, bool) {
+	c, ok := icd10ByCode[NormalizeICD10(code)]
+	return c, ok
+}
+
+// SearchICD10 returns up to limit codes matching query: codes starting with
+// it first, then codes whose description has every word of it.
+func SearchICD10(query string, limit int) []ICD10Code {
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return nil
+	}
+	prefix := NormalizeICD10(query)
+	var byCode, byDescription []ICD10Code
+	for _, c := range icd10Codes {
+		if len(words) == 1 && strings.HasPrefix(c.Code, prefix) {
+			byCode = append(byCode, c)
+			continue
+		}
+		desc := strings.ToLower(c.Description)
+		all := true
+		for _, w := range words {
+			if !strings.Contains(desc, w) {
+				all = false
+				break
+			}
+		}
+		if all {
+			byDescription = append(byDescription, c)
+		}
+	}
+	matches := append(byCode, byDescription...)
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
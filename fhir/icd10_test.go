@@ -0,0 +1,39 @@
+package fhir
+
+import "testing"
+
+func TestLookupICD10(t *testing.T) {
+	for _, typed := range []string{"E11.9", "e119", " e11.9 "} {
+		c, ok := LookupICD10(typed)
+		if !ok || c.Code != "E11.9" || c.Description != "Type 2 diabetes mellitus without complications" {
+			t.Errorf("LookupICD10(%q) = %+v, %v", typed, c, ok)
+		}
+	}
+	if _, ok := LookupICD10("E11.99999"); ok {
+		t.Error("found a code that is not in the subset")
+	}
+}
+
+func TestSearchICD10(t *testing.T) {
+	codes := func(cs []ICD10Code) []string {
+		var out []string
+		for _, c := range cs {
+			out = append(out, c.Code)
+		}
+		return out
+	}
+
+	// A code prefix lists codes in order.
+	got := codes(SearchICD10("n18.3", 10))
+	if len(got) != 4 || got[0] != "N18.3" || got[3] != "N18.32" {
+		t.Errorf("SearchICD10(n18.3) = %v", got)
+	}
+	// Words must all appear, in any order.
+	got = codes(SearchICD10("diabetes type 2 kidney", 10))
+	if len(got) != 1 || got[0] != "E11.22" {
+		t.Errorf("SearchICD10(diabetes type 2 kidney) = %v", got)
+	}
+	if got := SearchICD10("asthma", 2); len(got) != 2 {
+		t.Errorf("limit not applied: %v", codes(got))
+	}
+}
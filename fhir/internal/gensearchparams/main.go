@@ -0,0 +1,159 @@
+// Command gensearchparams writes fhir/searchparams_gen.go from the search
+// parameter definitions in fhir/searchparams.txt. Run it with
+// go generate ./fhir.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	input  = "searchparams.txt"
+	output = "searchparams_gen.go"
+)
+
+// paramTypes are the parameter types searchparams.txt may use.
+var paramTypes = map[string]bool{
+	"number": true, "date": true, "string": true, "token": true, "reference": true,
+	"composite": true, "quantity": true, "uri": true, "special": true, "result": true,
+}
+
+// initialisms are name parts written in upper case in constant names.
+var initialisms = map[string]bool{"id": true, "url": true, "uri": true, "udi": true, "di": true}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("gensearchparams: ")
+
+	params, err := readParams(input)
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, err := generate(params)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(output, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// readParams returns the parameter types in path by resource type, then by
+// name. Common parameters are under "*".
+func readParams(path string) (map[string]map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	params := make(map[string]map[string]string)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: want \"ResourceType name type\", got %q", path, n, line)
+		}
+		rt, name, typ := fields[0], fields[1], fields[2]
+		if !paramTypes[typ] {
+			return nil, fmt.Errorf("%s:%d: unknown parameter type %q", path, n, typ)
+		}
+		if params[rt] == nil {
+			params[rt] = make(map[string]string)
+		}
+		if _, dup := params[rt][name]; dup {
+			return nil, fmt.Errorf("%s:%d: %s %s defined twice", path, n, rt, name)
+		}
+		params[rt][name] = typ
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(params["*"]) == 0 {
+		return nil, fmt.Errorf("%s: no common (\"*\") parameters", path)
+	}
+	return params, nil
+}
+
+func generate(params map[string]map[string]string) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by gensearchparams from %s; DO NOT EDIT.\n\n", input)
+	b.WriteString("package fhir\n\n")
+
+	b.WriteString("// Search parameters every resource type supports.\nconst (\n")
+	for _, name := range sortedKeys(params["*"]) {
+		fmt.Fprintf(&b, "\tSearch%s = %q\n", constName(name), name)
+	}
+	b.WriteString(")\n")
+
+	var resourceTypes []string
+	for rt := range params {
+		if rt != "*" {
+			resourceTypes = append(resourceTypes, rt)
+		}
+	}
+	sort.Strings(resourceTypes)
+	for _, rt := range resourceTypes {
+		fmt.Fprintf(&b, "\n// %s search parameters.\nconst (\n", rt)
+		for _, name := range sortedKeys(params[rt]) {
+			fmt.Fprintf(&b, "\tSearch%s%s = %q\n", rt, constName(name), name)
+		}
+		b.WriteString(")\n")
+	}
+
+	b.WriteString("\n// commonSearchParams maps the parameters every resource type supports to\n// their types.\n")
+	b.WriteString("var commonSearchParams = map[string]string{\n")
+	writeTypes(&b, params["*"])
+	b.WriteString("}\n")
+
+	b.WriteString("\n// searchParams maps resource types to their own search parameters and\n// those parameters' types.\n")
+	b.WriteString("var searchParams = map[string]map[string]string{\n")
+	for _, rt := range resourceTypes {
+		fmt.Fprintf(&b, "\t%q: {\n", rt)
+		writeTypes(&b, params[rt])
+		b.WriteString("\t},\n")
+	}
+	b.WriteString("}\n")
+
+	return format.Source(b.Bytes())
+}
+
+func writeTypes(b *bytes.Buffer, types map[string]string) {
+	for _, name := range sortedKeys(types) {
+		fmt.Fprintf(b, "\t%q: %q,\n", name, types[name])
+	}
+}
+
+// constName turns a parameter name like "code-value-quantity" or
+// "_lastUpdated" into the CodeValueQuantity or LastUpdated of a constant.
+func constName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(strings.TrimPrefix(name, "_"), "-") {
+		if initialisms[part] {
+			b.WriteString(strings.ToUpper(part))
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,275 @@
+package fhir
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AlertSystem is the code system for the app's clinical alert rules.
+const AlertSystem = "https://example.org/fhir/CodeSystem/clinical-alert"
+
+// Alert is a clinical alert rule that fired for an observation.
+type Alert struct {
+	Code     string // AlertSystem code
+	Display  string
+	Severity string // DetectedIssue severity: high, moderate, or low
+	Detail   string
+}
+
+// Thresholds for the alert rules. Uncontrolled blood pressure uses the same
+// 140/90 cutoff as the dashboard narrative.
+const (
+	crisisSystolic  = 180
+	crisisDiastolic = 120
+	lowEGFR         = 60
+	severeEGFR      = 30
+)
+
+// ObservationAlerts runs the alert rules over an Observation: blood pressure
+// at or above 140/90 (high severity from 180/120) and eGFR below 60 (high
+// severity below 30).
+func ObservationAlerts(m map[string]any) []Alert {
+	var alerts []Alert
+	switch firstCoding(getMap(m, "code")) {
+	case "85354-9":
+		systolic, diastolic, ok := bloodPressure(m)
+		if !ok || (systolic < uncontrolledSystolic && diastolic < uncontrolledDiastolic) {
+			break
+		}
+		a := Alert{Code: "high-bp", Display: "High blood pressure", Severity: "moderate",
+			Detail: fmt.Sprintf("Blood pressure %.0f/%.0f mmHg is at or above %d/%d", systolic, diastolic, uncontrolledSystolic, uncontrolledDiastolic)}
+		if systolic >= crisisSystolic || diastolic >= crisisDiastolic {
+			a.Severity = "high"
+			a.Detail = fmt.Sprintf("Blood pressure %.0f/%.0f mmHg is at or above %d/%d", systolic, diastolic, crisisSystolic, crisisDiastolic)
+		}
+		alerts = append(alerts, a)
+	case "33914-3":
+		q := getMap(m, "valueQuantity")
+		if q == nil {
+			break
+		}
+		value := getNumber(q, "value")
+		if value >= lowEGFR {
+			break
+		}
+		a := Alert{Code: "low-egfr", Display: "Low eGFR", Severity: "moderate",
+			Detail: fmt.Sprintf("eGFR %g mL/min/1.73m2 is below %d", value, lowEGFR)}
+		if value < severeEGFR {
+			a.Severity = "high"
+			a.Detail = fmt.Sprintf("eGFR %g mL/min/1.73m2 is below %d", value, severeEGFR)
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts
+}
+
+// TrendRule is an alert on how much an observation changed from an earlier
+// one of the same code, rather than on its value alone.
+type TrendRule struct {
+	Code     string // LOINC code the rule watches
+	Alert    string // AlertSystem code
+	Display  string
+	Severity string
+	// Change that fires the alert: a rise above it when positive, a drop
+	// below it when negative. It is in the observation's stored unit, or a
+	// percentage of the earlier value if Percent is set.
+	Change  float64
+	Percent bool
+	// Window compares with every earlier value measured within it and fires
+	// on the largest change. When zero, only the previous value is compared.
+	Window time.Duration
+}
+
+// TrendRules are the trend alert rules: weight up more than 2 kg in 7 days,
+// and eGFR down more than 20% since the previous result.
+var TrendRules = []TrendRule{
+	{Code: "29463-7", Alert: "weight-gain", Display: "Rapid weight gain", Severity: "moderate", Change: 2, Window: 7 * 24 * time.Hour},
+	{Code: "33914-3", Alert: "egfr-decline", Display: "eGFR decline", Severity: "moderate", Change: -20, Percent: true},
+}
+
+// TrendCode returns the code whose history TrendAlerts needs for an
+// observation, or "" if no trend rule watches it.
+func TrendCode(m map[string]any) string {
+	code := observationLoincCode(m)
+	for _, r := range TrendRules {
+		if r.Code == code {
+			return code
+		}
+	}
+	return ""
+}
+
+// TrendAlerts runs the trend rules over an observation, comparing it with
+// the patient's earlier observations in history. Observations without a
+// date, measured after m, or entered in error are not compared, and history
+// may include m itself.
+func TrendAlerts(m map[string]any, history []map[string]any) []Alert {
+	q := getMap(m, "valueQuantity")
+	t, ok := effectiveTime(m)
+	if q == nil || !ok {
+		return nil
+	}
+	code, value, unit := observationLoincCode(m), getNumber(q, "value"), getString(q, "unit")
+
+	var alerts []Alert
+	for _, r := range TrendRules {
+		if r.Code != code {
+			continue
+		}
+		var base map[string]any
+		var baseTime time.Time
+		var best float64
+		for _, h := range history {
+			ht, ok := effectiveTime(h)
+			hq := getMap(h, "valueQuantity")
+			if !ok || hq == nil || !ht.Before(t) || observationLoincCode(h) != code ||
+				getString(h, "status") == "entered-in-error" ||
+				(getString(m, "id") != "" && getString(h, "id") == getString(m, "id")) {
+				continue
+			}
+			if r.Window > 0 {
+				if t.Sub(ht) > r.Window {
+					continue
+				}
+				change := r.change(getNumber(hq, "value"), value)
+				if base == nil || (r.Change > 0 && change > best) || (r.Change < 0 && change < best) {
+					base, baseTime, best = h, ht, change
+				}
+			} else if base == nil || ht.After(baseTime) {
+				base, baseTime = h, ht
+			}
+		}
+		if base == nil {
+			continue
+		}
+		before := getNumber(getMap(base, "valueQuantity"), "value")
+		change := r.change(before, value)
+		if (r.Change > 0 && change <= r.Change) || (r.Change < 0 && change >= r.Change) {
+			continue
+		}
+
+		text := getString(getMap(m, "code"), "text")
+		direction := "up"
+		if change < 0 {
+			direction = "down"
+		}
+		amount := fmt.Sprintf("%g %s", math.Abs(round1(change)), unit)
+		if r.Percent {
+			amount = fmt.Sprintf("%.0f%%", math.Abs(change))
+		}
+		since := "since " + baseTime.Local().Format("2006-01-02")
+		if r.Window > 0 {
+			since = fmt.Sprintf("in %d days", int(math.Ceil(t.Sub(baseTime).Hours()/24)))
+		}
+		alerts = append(alerts, Alert{Code: r.Alert, Display: r.Display, Severity: r.Severity,
+			Detail: fmt.Sprintf("%s %s %s %s (%g → %g %s)", text, direction, amount, since, before, value, unit)})
+	}
+	return alerts
+}
+
+// change is how much a value moved from before, as a percentage of before
+// for percentage rules.
+func (r TrendRule) change(before, after float64) float64 {
+	if r.Percent {
+		if before == 0 {
+			return 0
+		}
+		return (after - before) / before * 100
+	}
+	return after - before
+}
+
+// NewDetectedIssue records a fired alert for a patient. implicated is the
+// reference of the resource that triggered it, e.g. "Observation/123" or a
+// bundle entry's urn:uuid.
+func NewDetectedIssue(patientID string, alert Alert, implicated string, now time.Time) json.RawMessage {
+	d := map[string]any{
+		"resourceType": "DetectedIssue",
+		"status":       "final",
+		"code": map[string]any{
+			"coding": []map[string]any{{"system": AlertSystem, "code": alert.Code, "display": alert.Display}},
+			"text":   alert.Display,
+		},
+		"severity":           alert.Severity,
+		"patient":            map[string]any{"reference": "Patient/" + patientID},
+		"identifiedDateTime": now.UTC().Format(time.RFC3339),
+		"implicated":         []map[string]any{{"reference": implicated}},
+		"detail":             alert.Detail,
+	}
+	b, _ := json.Marshal(d)
+	return b
+}
+
+// IssueOpen reports whether a DetectedIssue still needs attention: it has
+// not been acknowledged (no mitigation) and was not cancelled or entered in
+// error.
+func IssueOpen(m map[string]any) bool {
+	switch getString(m, "status") {
+	case "cancelled", "entered-in-error":
+		return false
+	}
+	return len(getSlice(m, "mitigation")) == 0
+}
+
+// AcknowledgeIssue records a mitigation saying the issue was acknowledged.
+// by, if set, is recorded as the mitigation's author.
+func AcknowledgeIssue(m map[string]any, by string, now time.Time) {
+	mitigation := map[string]any{
+		"action": map[string]any{"text": "Acknowledged"},
+		"date":   now.UTC().Format(time.RFC3339),
+	}
+	if by != "" {
+		mitigation["author"] = map[string]any{"display": by}
+	}
+	m["mitigation"] = append(getSlice(m, "mitigation"), mitigation)
+}
+
+// DetectedIssueDisplay returns an issue's severity and detail, e.g.
+// "[high] eGFR 24 mL/min/1.73m2 is below 30 (2026-03-02)".
+func DetectedIssueDisplay(m map[string]any) string {
+	detail := getString(m, "detail")
+	if detail == "" {
+		detail = getString(getMap(m, "code"), "text")
+	}
+	return fmt.Sprintf("[%s] %s (%s)", getString(m, "severity"), detail, dateOnly(getString(m, "identifiedDateTime")))
+}
+
+// severityRank orders issues high, moderate, low, then anything else.
+func severityRank(m map[string]any) int {
+	switch getString(m, "severity") {
+	case "high":
+		return 0
+	case "moderate":
+		return 1
+	case "low":
+		return 2
+	}
+	return 3
+}
+
+// PrintOpenIssues displays open detected issues, high severity first, with
+// the patient names from names (keyed by patient ID). Nothing is printed if
+// there are none.
+func PrintOpenIssues(issues []map[string]any, names map[string]string) {
+	if len(issues) == 0 {
+		return
+	}
+	sorted := append([]map[string]any{}, issues...)
+	sort.SliceStable(sorted, func(i, j int) bool { return severityRank(sorted[i]) < severityRank(sorted[j]) })
+
+	fmt.Println(flagStyle.Render(fmt.Sprintf("! %d open alerts", len(sorted))))
+	for _, m := range sorted {
+		ref, _ := Path(m, "patient.reference").(string)
+		patientID := strings.TrimPrefix(ref, "Patient/")
+		name := names[patientID]
+		if name == "" {
+			name = ref
+		}
+		fmt.Printf("  %-20s %s\n", name, DetectedIssueDisplay(m))
+	}
+	fmt.Println()
+}
@@ -0,0 +1,77 @@
+package fhir
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTrendAlerts(t *testing.T) {
+	day := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	obs := func(raw json.RawMessage, at time.Time, id, status string) map[string]any {
+		m, err := Parse(WithEffective(raw, at))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != "" {
+			m["id"] = id
+		}
+		if status != "" {
+			m["status"] = status
+		}
+		return m
+	}
+	weight := func(kg float64, at time.Time) map[string]any {
+		return obs(NewWeightObservation("p1", kg), at, "", "")
+	}
+	egfr := func(v float64, at time.Time) map[string]any {
+		return obs(NewEGFRObservation("p1", v), at, "", "")
+	}
+	undated, _ := Parse(NewWeightObservation("p1", 60))
+
+	tests := []struct {
+		name    string
+		m       map[string]any
+		history []map[string]any
+		want    string // alert code, or "" for none
+	}{
+		{"weight up 2.5 kg in 5 days", weight(72.5, day.AddDate(0, 0, 5)),
+			[]map[string]any{weight(70, day)}, "weight-gain"},
+		{"weight up exactly 2 kg", weight(72, day.AddDate(0, 0, 5)),
+			[]map[string]any{weight(70, day)}, ""},
+		{"largest rise in the window", weight(72.5, day.AddDate(0, 0, 6)),
+			[]map[string]any{weight(70, day), weight(72, day.AddDate(0, 0, 5))}, "weight-gain"},
+		{"rise outside the window", weight(75, day.AddDate(0, 0, 8)),
+			[]map[string]any{weight(70, day)}, ""},
+		{"later reading is not a baseline", weight(72.5, day),
+			[]map[string]any{weight(70, day.AddDate(0, 0, 1))}, ""},
+		{"entered in error is not a baseline", weight(72.5, day.AddDate(0, 0, 5)),
+			[]map[string]any{obs(NewWeightObservation("p1", 70), day, "", "entered-in-error")}, ""},
+		{"undated reading is not a baseline", weight(72.5, day.AddDate(0, 0, 5)),
+			[]map[string]any{undated}, ""},
+		{"history may include the observation itself", obs(NewWeightObservation("p1", 72.5), day.AddDate(0, 0, 5), "o2", ""),
+			[]map[string]any{weight(70, day), obs(NewWeightObservation("p1", 72.5), day.AddDate(0, 0, 5), "o2", "")}, "weight-gain"},
+		{"eGFR down 25% since the previous result", egfr(45, day.AddDate(0, 3, 0)),
+			[]map[string]any{egfr(80, day.AddDate(0, -6, 0)), egfr(60, day)}, "egfr-decline"},
+		{"eGFR down 10%", egfr(54, day.AddDate(0, 3, 0)),
+			[]map[string]any{egfr(90, day.AddDate(0, -6, 0)), egfr(60, day)}, ""},
+		{"no trend rule", obs(NewHeartRateObservation("p1", 120), day.AddDate(0, 0, 1), "", ""),
+			[]map[string]any{obs(NewHeartRateObservation("p1", 60), day, "", "")}, ""},
+		{"undated observation", undated, []map[string]any{weight(50, day)}, ""},
+	}
+	for _, tt := range tests {
+		alerts := TrendAlerts(tt.m, tt.history)
+		got := ""
+		if len(alerts) > 0 {
+			got = alerts[0].Code
+		}
+		if got != tt.want || len(alerts) > 1 {
+			t.Errorf("%s: TrendAlerts = %+v, want %q", tt.name, alerts, tt.want)
+		}
+	}
+
+	alerts := TrendAlerts(weight(72.5, day.AddDate(0, 0, 5)), []map[string]any{weight(70, day)})
+	if want := "Weight up 2.5 kg in 5 days (70 → 72.5 kg)"; len(alerts) != 1 || alerts[0].Detail != want {
+		t.Errorf("weight gain detail = %+v, want %q", alerts, want)
+	}
+}
@@ -0,0 +1,191 @@
+package fhir
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// LabHistory is a patient's lab results pivoted into a table: one row per
+// test and one column per day with a result.
+type LabHistory struct {
+	Dates []string // YYYY-MM-DD in local time, oldest first
+	Rows  []LabHistoryRow
+}
+
+// LabHistoryRow is one test's results, keyed by date.
+type LabHistoryRow struct {
+	Code  string // LOINC
+	Name  string
+	Unit  string
+	Cells map[string]LabHistoryCell
+}
+
+// LabHistoryCell is a result and how it changed from the test's previous
+// result. Delta is zero and First is set for the first result.
+type LabHistoryCell struct {
+	Value float64
+	Delta float64
+	First bool
+}
+
+// Arrow shows the direction of the change from the previous result.
+func (c LabHistoryCell) Arrow() string {
+	switch {
+	case c.First:
+		return ""
+	case c.Delta > 0:
+		return "↑"
+	case c.Delta < 0:
+		return "↓"
+	}
+	return "→"
+}
+
+// String formats the cell as its value followed by the arrow and the
+// change, e.g. "6.8 ↑0.4".
+func (c LabHistoryCell) String() string {
+	switch {
+	case c.First:
+		return formatNumber(c.Value)
+	case c.Delta == 0:
+		return formatNumber(c.Value) + " →"
+	}
+	return formatNumber(c.Value) + " " + c.Arrow() + formatNumber(math.Abs(c.Delta))
+}
+
+// label is the row's test name with its unit.
+func (r LabHistoryRow) label() string {
+	if r.Unit == "" {
+		return r.Name
+	}
+	return r.Name + " (" + r.Unit + ")"
+}
+
+// BuildLabHistory pivots laboratory observations into a LabHistory. An
+// observation counts as a lab result if it is categorized as laboratory or
+// its code is in the LOINC catalog, and it has a numeric value and a date.
+// When a test has several results on one day the latest is kept. Results
+// entered in error are left out. Rows are sorted by test name.
+func BuildLabHistory(observations []json.RawMessage) LabHistory {
+	type result struct {
+		t     time.Time
+		value float64
+	}
+	rows := make(map[string]*LabHistoryRow)
+	latest := make(map[string]map[string]result) // code → date → result
+	dates := make(map[string]bool)
+	for _, raw := range observations {
+		m, err := Parse(raw)
+		if err != nil || getString(m, "status") == "entered-in-error" {
+			continue
+		}
+		code := observationLoincCode(m)
+		test, known := LookupLOINC(code)
+		if code == "" || (!known && ObservationCategory(m) != CategoryLaboratory) {
+			continue
+		}
+		vq := getMap(m, "valueQuantity")
+		value, ok := numberValue(vq, "value")
+		t, dated := effectiveTime(m)
+		if !ok || !dated {
+			continue
+		}
+		if rows[code] == nil {
+			row := &LabHistoryRow{Code: code, Name: test.Text, Unit: getString(vq, "unit")}
+			if !known {
+				row.Name = cmp.Or(getString(getMap(m, "code"), "text"), code)
+			}
+			rows[code] = row
+			latest[code] = make(map[string]result)
+		}
+		day := t.Local().Format("2006-01-02")
+		if prev, seen := latest[code][day]; !seen || !t.Before(prev.t) {
+			latest[code][day] = result{t, value}
+		}
+		dates[day] = true
+	}
+
+	var h LabHistory
+	for day := range dates {
+		h.Dates = append(h.Dates, day)
+	}
+	sort.Strings(h.Dates)
+	for code, row := range rows {
+		row.Cells = make(map[string]LabHistoryCell)
+		var prev *result
+		for _, day := range h.Dates {
+			r, ok := latest[code][day]
+			if !ok {
+				continue
+			}
+			cell := LabHistoryCell{Value: r.value, First: prev == nil}
+			if prev != nil {
+				cell.Delta = r.value - prev.value
+			}
+			row.Cells[day] = cell
+			prev = &r
+		}
+		h.Rows = append(h.Rows, *row)
+	}
+	sort.Slice(h.Rows, func(i, j int) bool { return h.Rows[i].Name < h.Rows[j].Name })
+	return h
+}
+
+// labHistoryColumns is how many of the latest dates PrintLabHistory shows,
+// so the table fits a terminal.
+const labHistoryColumns = 6
+
+// PrintLabHistory prints a LabHistory as a table of tests by date. Only
+// the latest dates are shown; deltas are still against the test's previous
+// result, even when that is in a column not shown.
+func PrintLabHistory(h LabHistory) {
+	if len(h.Rows) == 0 {
+		fmt.Println("  No lab results found.")
+		return
+	}
+	dates := h.Dates[max(len(h.Dates)-labHistoryColumns, 0):]
+
+	nameWidth := len("Test")
+	for _, r := range h.Rows {
+		nameWidth = max(nameWidth, utf8.RuneCountInString(r.label()))
+	}
+	widths := make([]int, len(dates))
+	for i, day := range dates {
+		widths[i] = len(day)
+		for _, r := range h.Rows {
+			if c, ok := r.Cells[day]; ok {
+				widths[i] = max(widths[i], utf8.RuneCountInString(c.String()))
+			}
+		}
+	}
+	pad := func(s string, width int) string {
+		return s + strings.Repeat(" ", max(width-utf8.RuneCountInString(s), 0))
+	}
+
+	fmt.Println(headerStyle.Render("Lab History"))
+	if len(dates) < len(h.Dates) {
+		fmt.Printf("  Showing the latest %d of %d dates\n", len(dates), len(h.Dates))
+	}
+	header := "  " + pad("Test", nameWidth)
+	for i, day := range dates {
+		header += "  " + pad(day, widths[i])
+	}
+	fmt.Println(labelStyle.UnsetWidth().Render(header))
+	for _, r := range h.Rows {
+		line := "  " + pad(r.label(), nameWidth)
+		for i, day := range dates {
+			cell := ""
+			if c, ok := r.Cells[day]; ok {
+				cell = c.String()
+			}
+			line += "  " + pad(cell, widths[i])
+		}
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+}
@@ -0,0 +1,53 @@
+package fhir
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestBuildLabHistory(t *testing.T) {
+	at := func(d, h int) time.Time { return time.Date(2026, 3, d, h, 0, 0, 0, time.Local) }
+	observations := []json.RawMessage{
+		WithEffective(NewHbA1cObservation("p1", 7.2), at(20, 9)),
+		WithEffective(NewHbA1cObservation("p1", 6.8), at(1, 9)),
+		WithEffective(NewHbA1cObservation("p1", 6.8), at(10, 9)),
+		WithEffective(NewCreatinineObservation("p1", 1.4), at(10, 8)),
+		WithEffective(NewCreatinineObservation("p1", 1.1), at(10, 15)), // later the same day
+		WithEffective(NewCreatinineObservation("p1", 1.3), at(20, 9)),
+		WithEffective(NewHeartRateObservation("p1", 70), at(5, 9)), // a vital, not a lab
+		json.RawMessage(`{"resourceType":"Observation","status":"entered-in-error","code":{"coding":[{"code":"4548-4"}]},"effectiveDateTime":"2026-03-25","valueQuantity":{"value":12,"unit":"%"}}`),
+	}
+	h := BuildLabHistory(observations)
+
+	if want := []string{"2026-03-01", "2026-03-10", "2026-03-20"}; !slices.Equal(h.Dates, want) {
+		t.Errorf("Dates = %v, want %v", h.Dates, want)
+	}
+	cells := make(map[string][]string)
+	for _, r := range h.Rows {
+		for _, day := range h.Dates {
+			if c, ok := r.Cells[day]; ok {
+				cells[r.Code] = append(cells[r.Code], c.String())
+			}
+		}
+	}
+	want := map[string][]string{
+		"4548-4": {"6.8", "6.8 →", "7.2 ↑0.4"},
+		"2160-0": {"1.1", "1.3 ↑0.2"},
+	}
+	if len(cells) != len(want) {
+		t.Errorf("rows = %v, want %v", cells, want)
+	}
+	for code, w := range want {
+		if !slices.Equal(cells[code], w) {
+			t.Errorf("%s = %v, want %v", code, cells[code], w)
+		}
+	}
+	if h.Rows[0].Name != "Creatinine" || h.Rows[0].Unit != "mg/dL" {
+		t.Errorf("first row = %s (%s), want rows sorted by name", h.Rows[0].Name, h.Rows[0].Unit)
+	}
+	if c := (LabHistoryCell{Value: 4, Delta: -0.5}); c.String() != "4 ↓0.5" || c.Arrow() != "↓" {
+		t.Errorf("falling cell = %q", c.String())
+	}
+}
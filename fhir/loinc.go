@@ -0,0 +1,73 @@
+package fhir
+
+import (
+	_ "embed"
+	"strings"
+)
+
+//go:embed loinc.txt
+var loincData string
+
+// labCatalog is the bundled LOINC subset, in file order, and labByCode
+// indexes it.
+var labCatalog, labByCode = parseLabCatalog(loincData)
+
+func parseLabCatalog(data string) ([]LabTest, map[string]LabTest) {
+	var tests []LabTest
+	byCode := make(map[string]LabTest)
+	for _, line := range strings.Split(data, "\n") {
+		f := strings.Split(line, "\t")
+		if len(f) != 5 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		t := LabTest{Code: f[0], Text: f[1], Unit: f[2], UnitCode: f[3], Display: strings.TrimSpace(f[4])}
+		tests = append(tests, t)
+		byCode[t.Code] = t
+	}
+	return tests, byCode
+}
+
+// LabCatalog returns the bundled LOINC subset of lab tests with a numeric
+// result.
+func LabCatalog() []LabTest {
+	return labCatalog
+}
+
+// LookupLOINC finds a lab test in the bundled subset by LOINC code.
+func LookupLOINC(code string) (LabTest, bool) {
+	t, ok := labByCode[strings.TrimSpace(code)]
+	return t, ok
+}
+
+// SearchLabTests returns up to limit lab tests matching query: a LOINC code
+// starting with it, or every word of it in the test's short name, long name,
+// or unit. Matches on the short name come first.
+func SearchLabTests(query string, limit int) []LabTest {
+	words := strings.Fields(strings.ToLower(query))
+	if len(words) == 0 {
+		return nil
+	}
+	has := func(s string) bool {
+		s = strings.ToLower(s)
+		for _, w := range words {
+			if !strings.Contains(s, w) {
+				return false
+			}
+		}
+		return true
+	}
+	var first, rest []LabTest
+	for _, t := range labCatalog {
+		switch {
+		case len(words) == 1 && strings.HasPrefix(t.Code, words[0]), has(t.Text):
+			first = append(first, t)
+		case has(t.Text + " " + t.Display + " " + t.Unit):
+			rest = append(rest, t)
+		}
+	}
+	matches := append(first, rest...)
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
@@ -0,0 +1,43 @@
+package fhir
+
+import "testing"
+
+func TestLabCatalog(t *testing.T) {
+	for _, test := range LabCatalog() {
+		m, err := Parse(NewLabObservation("p1", test, 1))
+		if err != nil {
+			t.Fatalf("%s: %v", test.Code, err)
+		}
+		for _, p := range CheckUnits(m) {
+			t.Errorf("%s %s: %s", test.Code, test.Text, p)
+		}
+	}
+	// The common tests are in the catalog, with the same units.
+	for _, test := range LabTests {
+		got, ok := LookupLOINC(test.Code)
+		if !ok || got.UnitCode != test.UnitCode {
+			t.Errorf("LabTests %s (%s) not in the catalog as %+v", test.Code, test.Text, got)
+		}
+	}
+}
+
+func TestSearchLabTests(t *testing.T) {
+	texts := func(tests []LabTest) []string {
+		var out []string
+		for _, t := range tests {
+			out = append(out, t.Text)
+		}
+		return out
+	}
+	if got := texts(SearchLabTests("2276", 5)); len(got) != 1 || got[0] != "Ferritin" {
+		t.Errorf("search by code = %v", got)
+	}
+	// Short-name matches come before matches on the long name.
+	got := texts(SearchLabTests("cholesterol", 10))
+	if len(got) < 5 || got[0] != "Total Cholesterol" {
+		t.Errorf("search by name = %v", got)
+	}
+	if got := texts(SearchLabTests("urine creatinine", 10)); len(got) != 3 {
+		t.Errorf("search by words = %v", got)
+	}
+}
@@ -0,0 +1,157 @@
+package fhir
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// MeasurementOption is one way a vital sign can be taken: a method, such as
+// auscultation, or a body site, such as the left upper arm. Code is a SNOMED
+// CT code, or "" for options recorded as text only.
+type MeasurementOption struct {
+	Code    string
+	Display string
+}
+
+// measurementMethods are the methods offered for each vital sign, by LOINC
+// code.
+var measurementMethods = map[string][]MeasurementOption{
+	"85354-9": {
+		{"37931006", "Auscultation (manual)"},
+		{"", "Automated (oscillometric)"},
+	},
+	"8867-4": {
+		{"113011001", "Palpation"},
+		{"37931006", "Auscultation"},
+		{"", "Automated monitor"},
+	},
+}
+
+// measurementSites are the body sites offered for each vital sign, by LOINC
+// code.
+var measurementSites = map[string][]MeasurementOption{
+	"85354-9": {
+		{"368208006", "Left upper arm"},
+		{"368209003", "Right upper arm"},
+		{"5951000", "Left wrist"},
+		{"9736006", "Right wrist"},
+		{"68367000", "Thigh"},
+	},
+	"8310-5": {
+		{"123851003", "Oral"},
+		{"91470000", "Axillary"},
+		{"117590005", "Tympanic"},
+		{"34402009", "Rectal"},
+	},
+	"2708-6": {
+		{"7569003", "Finger"},
+		{"48800003", "Ear lobe"},
+	},
+}
+
+// CuffSizes are the blood pressure cuff sizes offered when recording a
+// blood pressure. The size is recorded in the method's text.
+var CuffSizes = []string{"Infant", "Child", "Small adult", "Adult", "Large adult", "Thigh"}
+
+// MeasurementMethods returns the methods a vital sign can be taken by, by
+// its LOINC code, or nil if none are offered.
+func MeasurementMethods(code string) []MeasurementOption {
+	return measurementMethods[code]
+}
+
+// MeasurementSites returns the body sites a vital sign can be taken at, by
+// its LOINC code, or nil if none are offered.
+func MeasurementSites(code string) []MeasurementOption {
+	return measurementSites[code]
+}
+
+// measurementConcept is a CodeableConcept for an option, with text so
+// options without a code still say what they are.
+func measurementConcept(o MeasurementOption, text string) map[string]any {
+	cc := map[string]any{"text": text}
+	if o.Code != "" {
+		cc["coding"] = []map[string]any{{"system": SNOMEDSystem, "code": o.Code, "display": o.Display}}
+	}
+	return cc
+}
+
+// WithMethod returns a copy of an Observation recording how it was taken.
+// A blood pressure's cuff size, if given, is added to the method's text,
+// e.g. "Automated (oscillometric), large adult cuff". An empty method with a
+// cuff size records the cuff size alone.
+func WithMethod(observation json.RawMessage, method MeasurementOption, cuffSize string) json.RawMessage {
+	var m map[string]any
+	if err := json.Unmarshal(observation, &m); err != nil {
+		return observation
+	}
+	var parts []string
+	if method.Display != "" {
+		parts = append(parts, method.Display)
+	}
+	if cuffSize != "" {
+		parts = append(parts, strings.ToLower(cuffSize)+" cuff")
+	}
+	if len(parts) == 0 {
+		return observation
+	}
+	m["method"] = measurementConcept(method, strings.Join(parts, ", "))
+	b, _ := json.Marshal(m)
+	return b
+}
+
+// WithBodySite returns a copy of an Observation recording where on the
+// body it was taken.
+func WithBodySite(observation json.RawMessage, site MeasurementOption) json.RawMessage {
+	var m map[string]any
+	if err := json.Unmarshal(observation, &m); err != nil {
+		return observation
+	}
+	m["bodySite"] = measurementConcept(site, site.Display)
+	b, _ := json.Marshal(m)
+	return b
+}
+
+// WithDeviceName returns a copy of an Observation naming the device that
+// took the measurement, for devices not registered as Device resources.
+func WithDeviceName(observation json.RawMessage, name string) json.RawMessage {
+	var m map[string]any
+	if err := json.Unmarshal(observation, &m); err != nil {
+		return observation
+	}
+	m["device"] = map[string]any{"display": name}
+	b, _ := json.Marshal(m)
+	return b
+}
+
+// MeasurementDetails describes how an observation was taken, from its
+// method, body site, and device, e.g. "Auscultation (manual), adult cuff ·
+// Left upper arm · device Device/123". It is "" when none are recorded.
+func MeasurementDetails(m map[string]any) string {
+	var parts []string
+	concept := func(cc map[string]any) string {
+		if text := getString(cc, "text"); text != "" {
+			return text
+		}
+		for _, c := range getSlice(cc, "coding") {
+			cm, _ := c.(map[string]any)
+			if d := getString(cm, "display"); d != "" {
+				return d
+			}
+		}
+		return firstCoding(cc)
+	}
+	if method := concept(getMap(m, "method")); method != "" {
+		parts = append(parts, method)
+	}
+	if site := concept(getMap(m, "bodySite")); site != "" {
+		parts = append(parts, site)
+	}
+	if device := getMap(m, "device"); device != nil {
+		if name := getString(device, "display"); name != "" {
+			parts = append(parts, "device "+name)
+		} else if ref := getString(device, "reference"); ref != "" {
+			parts = append(parts, "device "+ref)
+		}
+	}
+	return strings.Join(parts, " · ")
+}
@@ -0,0 +1,30 @@
+package fhir
+
+import "testing"
+
+func TestMeasurementDetails(t *testing.T) {
+	bp := NewBloodPressureObservation("p1", 128, 82)
+	bp = WithMethod(bp, MeasurementMethods("85354-9")[1], "Large adult")
+	bp = WithBodySite(bp, MeasurementSites("85354-9")[0])
+	bp = WithDeviceName(bp, "Clinic monitor")
+	m, err := Parse(bp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Automated (oscillometric), large adult cuff · Left upper arm · device Clinic monitor"
+	if got := MeasurementDetails(m); got != want {
+		t.Errorf("MeasurementDetails = %q, want %q", got, want)
+	}
+	if Path(m, "method.coding") != nil {
+		t.Errorf("text-only method has a coding: %v", m["method"])
+	}
+	if code, _ := Path(m, "bodySite.coding.code").(string); code != "368208006" {
+		t.Errorf("bodySite code = %q, want 368208006", code)
+	}
+
+	temp := WithMethod(NewTemperatureObservation("p1", 37), MeasurementOption{}, "")
+	m, _ = Parse(temp)
+	if got := MeasurementDetails(m); got != "" {
+		t.Errorf("nothing recorded: MeasurementDetails = %q, want empty", got)
+	}
+}
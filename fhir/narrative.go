@@ -0,0 +1,163 @@
+package fhir
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DashboardStats are the clinic-wide numbers behind the dashboard narrative.
+type DashboardStats struct {
+	Patients       int
+	ActivePlans    int
+	Activities     int
+	Completed      int
+	Outstanding    int
+	Overdue        int
+	DueThisWeek    int
+	BPPatients     int
+	UncontrolledBP int
+	// MostOverdue is the patient with the most overdue activities, if any.
+	MostOverdue      string
+	MostOverdueCount int
+}
+
+// Blood pressure at or above either threshold counts as uncontrolled.
+const (
+	uncontrolledSystolic  = 140
+	uncontrolledDiastolic = 90
+)
+
+// ComputeDashboardStats summarizes active plans and blood pressure
+// observations. Only each patient's latest blood pressure reading counts.
+func ComputeDashboardStats(plans []DashboardPlan, bloodPressures []json.RawMessage, now time.Time) DashboardStats {
+	var s DashboardStats
+	s.ActivePlans = len(plans)
+
+	weekEnd := now.AddDate(0, 0, 7)
+	patients := make(map[string]bool)
+	overdueByPatient := make(map[string]int)
+	for _, p := range plans {
+		patients[p.PatientID] = true
+		s.Activities += p.Total
+		s.Completed += p.Completed
+		s.Outstanding += len(p.Outstanding)
+		for _, item := range p.Outstanding {
+			due, ok := ScheduledDate(item.ScheduleNote)
+			if !ok {
+				continue
+			}
+			if due.Before(now) {
+				s.Overdue++
+				overdueByPatient[p.PatientName]++
+			} else if due.Before(weekEnd) {
+				s.DueThisWeek++
+			}
+		}
+	}
+	s.Patients = len(patients)
+	for name, n := range overdueByPatient {
+		if n > s.MostOverdueCount || (n == s.MostOverdueCount && name < s.MostOverdue) {
+			s.MostOverdue, s.MostOverdueCount = name, n
+		}
+	}
+
+	latest := make(map[string]map[string]any)
+	for _, raw := range bloodPressures {
+		m, err := Parse(raw)
+		if err != nil {
+			continue
+		}
+		patientID := PatientRef(m)
+		prev, ok := latest[patientID]
+		if !ok || getString(m, "effectiveDateTime") >= getString(prev, "effectiveDateTime") {
+			latest[patientID] = m
+		}
+	}
+	for _, m := range latest {
+		systolic, diastolic, ok := bloodPressure(m)
+		if !ok {
+			continue
+		}
+		s.BPPatients++
+		if systolic >= uncontrolledSystolic || diastolic >= uncontrolledDiastolic {
+			s.UncontrolledBP++
+		}
+	}
+	return s
+}
+
+// bloodPressure returns the systolic and diastolic components of a blood
+// pressure Observation, or ok=false unless both are present.
+func bloodPressure(m map[string]any) (systolic, diastolic float64, ok bool) {
+	systolic, sok := bloodPressureComponent(m, systolicCode, 0)
+	diastolic, dok := bloodPressureComponent(m, diastolicCode, 1)
+	return systolic, diastolic, sok && dok
+}
+
+// Narrative turns the stats into a few sentences of prose.
+func (s DashboardStats) Narrative() []string {
+	var lines []string
+
+	pct := 0
+	if s.Activities > 0 {
+		pct = s.Completed * 100 / s.Activities
+	}
+	lines = append(lines, fmt.Sprintf("%s %s %s with %s outstanding (%d%% of all activities complete).",
+		plural(s.Patients, "patient", "patients"), verb(s.Patients, "has", "have"),
+		plural(s.ActivePlans, "active care plan", "active care plans"),
+		plural(s.Outstanding, "activity", "activities"), pct))
+
+	switch {
+	case s.Overdue > 0 && s.DueThisWeek > 0:
+		lines = append(lines, fmt.Sprintf("%s %s overdue and %d more %s due this week.",
+			plural(s.Overdue, "activity", "activities"), verb(s.Overdue, "is", "are"), s.DueThisWeek, verb(s.DueThisWeek, "is", "are")))
+	case s.Overdue > 0:
+		lines = append(lines, fmt.Sprintf("%s %s overdue; nothing else is due this week.",
+			plural(s.Overdue, "activity", "activities"), verb(s.Overdue, "is", "are")))
+	case s.DueThisWeek > 0:
+		lines = append(lines, fmt.Sprintf("Nothing is overdue; %s %s due this week.",
+			plural(s.DueThisWeek, "activity", "activities"), verb(s.DueThisWeek, "is", "are")))
+	default:
+		lines = append(lines, "Nothing is overdue or due this week.")
+	}
+	if s.MostOverdueCount > 1 {
+		lines = append(lines, fmt.Sprintf("%s has the most overdue items (%d).", s.MostOverdue, s.MostOverdueCount))
+	}
+
+	switch {
+	case s.BPPatients == 0:
+		lines = append(lines, "No blood pressure readings on record.")
+	case s.UncontrolledBP > 0:
+		lines = append(lines, fmt.Sprintf("%s of %d with a blood pressure reading %s uncontrolled BP (latest at or above %d/%d).",
+			plural(s.UncontrolledBP, "patient", "patients"), s.BPPatients, verb(s.UncontrolledBP, "has", "have"),
+			uncontrolledSystolic, uncontrolledDiastolic))
+	default:
+		lines = append(lines, fmt.Sprintf("All %d patients with a blood pressure reading are below %d/%d.",
+			s.BPPatients, uncontrolledSystolic, uncontrolledDiastolic))
+	}
+	return lines
+}
+
+// PrintNarrative displays the narrative panel above the dashboard.
+func PrintNarrative(lines []string) {
+	fmt.Println(headerStyle.Render("Summary"))
+	for _, line := range lines {
+		fmt.Println("  " + line)
+	}
+	fmt.Println()
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return "1 " + one
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
+
+func verb(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
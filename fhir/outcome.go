@@ -0,0 +1,193 @@
+package fhir
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CarePlanOutcomeExtension records how a completed CarePlan turned out. R4
+// CarePlan has outcomes only per activity, not for the plan as a whole.
+const CarePlanOutcomeExtension = "https://example.org/fhir/StructureDefinition/careplan-outcome"
+
+// CarePlanOutcomeSystem is the code system of PlanOutcome codes.
+const CarePlanOutcomeSystem = "https://example.org/fhir/CodeSystem/careplan-outcome"
+
+// PlanOutcome is how a completed care plan turned out.
+type PlanOutcome struct {
+	Code    string // CarePlanOutcomeSystem code
+	Display string
+	// GoalStatus is the goal-achievement code set on the plan's goals.
+	// That value set has no "partially achieved", so a partly met plan
+	// marks its goals as improving.
+	GoalStatus string
+}
+
+// PlanOutcomes are the outcomes offered when a plan is completed.
+var PlanOutcomes = []PlanOutcome{
+	{"met", "Goal met", "achieved"},
+	{"partially-met", "Goal partially met", "improving"},
+	{"not-met", "Goal not met", "not-achieved"},
+}
+
+// WithPlanOutcome records a care plan's outcome, replacing any earlier one,
+// and ends the plan's period at now.
+func WithPlanOutcome(cp map[string]any, o PlanOutcome, now time.Time) {
+	var exts []any
+	for _, e := range getSlice(cp, "extension") {
+		if em, ok := e.(map[string]any); !ok || getString(em, "url") != CarePlanOutcomeExtension {
+			exts = append(exts, e)
+		}
+	}
+	cp["extension"] = append(exts, map[string]any{
+		"url": CarePlanOutcomeExtension,
+		"valueCodeableConcept": map[string]any{
+			"coding": []any{map[string]any{"system": CarePlanOutcomeSystem, "code": o.Code, "display": o.Display}},
+			"text":   o.Display,
+		},
+	})
+	period := getMap(cp, "period")
+	if period == nil {
+		period = map[string]any{}
+		cp["period"] = period
+	}
+	period["end"] = now.UTC().Format(time.RFC3339)
+}
+
+// CarePlanOutcome returns the outcome code recorded on a care plan, or "".
+func CarePlanOutcome(cp map[string]any) string {
+	for _, e := range getSlice(cp, "extension") {
+		em, ok := e.(map[string]any)
+		if ok && getString(em, "url") == CarePlanOutcomeExtension {
+			return firstCoding(getMap(em, "valueCodeableConcept"))
+		}
+	}
+	return ""
+}
+
+// WithGoalAchievement sets a Goal's achievementStatus from its plan's outcome.
+func WithGoalAchievement(goal map[string]any, o PlanOutcome) {
+	goal["achievementStatus"] = map[string]any{
+		"coding": []any{map[string]any{
+			"system": "http://terminology.hl7.org/CodeSystem/goal-achievement",
+			"code":   o.GoalStatus,
+		}},
+		"text": o.Display,
+	}
+}
+
+// CarePlanGoalIDs returns the IDs of the Goals a care plan references.
+func CarePlanGoalIDs(cp map[string]any) []string {
+	var ids []string
+	for _, g := range getSlice(cp, "goal") {
+		gm, _ := g.(map[string]any)
+		if id, ok := strings.CutPrefix(getString(gm, "reference"), "Goal/"); ok && id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// carePlanStarted returns when a care plan was created: its created date,
+// else the start of its period, else when it was last updated.
+func carePlanStarted(cp map[string]any) (time.Time, bool) {
+	for _, s := range []string{getString(cp, "created"), getString(getMap(cp, "period"), "start"), getString(getMap(cp, "meta"), "lastUpdated")} {
+		if s == "" {
+			continue
+		}
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t, true
+		}
+		if t, err := time.Parse("2006-01-02", s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// quarter names the calendar quarter t falls in, e.g. "2026 Q3".
+func quarter(t time.Time) string {
+	return fmt.Sprintf("%d Q%d", t.Year(), (int(t.Month())-1)/3+1)
+}
+
+// QuarterStart returns the start of the calendar quarter t falls in.
+func QuarterStart(t time.Time) time.Time {
+	return time.Date(t.Year(), time.Month((int(t.Month())-1)/3*3+1), 1, 0, 0, 0, 0, t.Location())
+}
+
+// OutcomeRow summarizes the care plans from one template started in one
+// quarter.
+type OutcomeRow struct {
+	Quarter   string // e.g. "2026 Q3"
+	Template  string
+	Plans     int
+	Completed int
+	// Outcomes counts completed plans by PlanOutcome code; "" counts those
+	// completed without one.
+	Outcomes map[string]int
+}
+
+// CompletionRate is the share of the row's plans that are completed.
+func (r OutcomeRow) CompletionRate() float64 {
+	if r.Plans == 0 {
+		return 0
+	}
+	return float64(r.Completed) / float64(r.Plans)
+}
+
+// SummarizePlanOutcomes groups care plans started on or after since by
+// quarter and by the template they were created from. templates maps
+// PlanDefinition canonical URLs to titles; plans not created from a known
+// template are grouped under their own title. Rows are sorted by quarter,
+// newest first, then by template.
+func SummarizePlanOutcomes(plans []map[string]any, templates map[string]string, since time.Time) []OutcomeRow {
+	rows := make(map[[2]string]*OutcomeRow)
+	for _, cp := range plans {
+		started, ok := carePlanStarted(cp)
+		if !ok || started.Before(since) {
+			continue
+		}
+		status := getString(cp, "status")
+		if status == "entered-in-error" || status == "draft" {
+			continue
+		}
+		template := ""
+		for _, c := range getSlice(cp, "instantiatesCanonical") {
+			if s, _ := c.(string); templates[s] != "" {
+				template = templates[s]
+				break
+			}
+		}
+		if template == "" {
+			template = getString(cp, "title")
+		}
+		if template == "" {
+			template = "(untitled)"
+		}
+
+		key := [2]string{quarter(started.In(since.Location())), template}
+		r := rows[key]
+		if r == nil {
+			r = &OutcomeRow{Quarter: key[0], Template: key[1], Outcomes: map[string]int{}}
+			rows[key] = r
+		}
+		r.Plans++
+		if status == "completed" {
+			r.Completed++
+			r.Outcomes[CarePlanOutcome(cp)]++
+		}
+	}
+
+	out := make([]OutcomeRow, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Quarter != out[j].Quarter {
+			return out[i].Quarter > out[j].Quarter
+		}
+		return out[i].Template < out[j].Template
+	})
+	return out
+}
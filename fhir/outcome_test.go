@@ -0,0 +1,79 @@
+package fhir
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWithPlanOutcome(t *testing.T) {
+	cp := map[string]any{
+		"resourceType": "CarePlan",
+		"extension":    []any{map[string]any{"url": EpisodeOfCareExtension, "valueReference": map[string]any{"reference": "EpisodeOfCare/1"}}},
+	}
+	now := time.Date(2026, 5, 2, 9, 0, 0, 0, time.UTC)
+	WithPlanOutcome(cp, PlanOutcomes[0], now)
+	WithPlanOutcome(cp, PlanOutcomes[2], now)
+
+	if got := CarePlanOutcome(cp); got != "not-met" {
+		t.Errorf("CarePlanOutcome = %q, want not-met", got)
+	}
+	if n := len(getSlice(cp, "extension")); n != 2 {
+		t.Errorf("%d extensions, want the episode link and one outcome", n)
+	}
+	if end := getString(getMap(cp, "period"), "end"); end != "2026-05-02T09:00:00Z" {
+		t.Errorf("period.end = %q", end)
+	}
+}
+
+func TestSummarizePlanOutcomes(t *testing.T) {
+	const diabetes = "https://example.org/fhir/PlanDefinition/diabetes-management"
+	plan := func(created, status, outcome string, canonical ...any) map[string]any {
+		cp := map[string]any{"resourceType": "CarePlan", "title": "Custom plan", "status": status, "created": created}
+		if len(canonical) > 0 {
+			cp["instantiatesCanonical"] = canonical
+		}
+		for _, o := range PlanOutcomes {
+			if o.Code == outcome {
+				WithPlanOutcome(cp, o, time.Now())
+			}
+		}
+		return cp
+	}
+	plans := []map[string]any{
+		plan("2026-04-10T00:00:00Z", "completed", "met", diabetes),
+		plan("2026-05-01T00:00:00Z", "completed", "partially-met", diabetes),
+		plan("2026-06-30T23:00:00Z", "active", "", diabetes),
+		plan("2026-06-01T00:00:00Z", "completed", "", diabetes),
+		plan("2026-07-01T00:00:00Z", "completed", "not-met", diabetes),
+		plan("2026-07-02T00:00:00Z", "active", ""),
+		plan("2026-08-01T00:00:00Z", "entered-in-error", "", diabetes),
+		plan("2025-12-31T00:00:00Z", "completed", "met", diabetes),
+	}
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	got := SummarizePlanOutcomes(plans, map[string]string{diabetes: "Diabetes Management"}, since)
+	want := []OutcomeRow{
+		{Quarter: "2026 Q3", Template: "Custom plan", Plans: 1, Outcomes: map[string]int{}},
+		{Quarter: "2026 Q3", Template: "Diabetes Management", Plans: 1, Completed: 1, Outcomes: map[string]int{"not-met": 1}},
+		{Quarter: "2026 Q2", Template: "Diabetes Management", Plans: 4, Completed: 3,
+			Outcomes: map[string]int{"met": 1, "partially-met": 1, "": 1}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SummarizePlanOutcomes =\n  %+v\nwant\n  %+v", got, want)
+	}
+	if rate := got[2].CompletionRate(); rate != 0.75 {
+		t.Errorf("CompletionRate = %g, want 0.75", rate)
+	}
+}
+
+func TestQuarterStart(t *testing.T) {
+	for _, tt := range []struct{ in, want string }{
+		{"2026-01-01", "2026-01-01"}, {"2026-03-31", "2026-01-01"},
+		{"2026-08-15", "2026-07-01"}, {"2026-12-31", "2026-10-01"},
+	} {
+		in, _ := time.Parse("2006-01-02", tt.in)
+		if got := QuarterStart(in).Format("2006-01-02"); got != tt.want {
+			t.Errorf("QuarterStart(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
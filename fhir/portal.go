@@ -0,0 +1,250 @@
+package fhir
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PatientView is the chart as a patient portal would show it: health
+// conditions, latest results, and upcoming care plan activities in plain
+// language. Codes, resource IDs, and staff-only content such as alert flags
+// and imaging are left out.
+type PatientView struct {
+	FirstName  string          `json:"firstName"`
+	Conditions []string        `json:"conditions,omitempty"`
+	Results    []PatientResult `json:"results,omitempty"`
+	Upcoming   []PatientTask   `json:"upcoming,omitempty"`
+}
+
+// PatientResult is the latest result for one kind of measurement.
+type PatientResult struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	Date  string `json:"date,omitempty"`
+	Note  string `json:"note,omitempty"` // how the value compares with the usual range
+}
+
+// PatientTask is an outstanding care plan activity.
+type PatientTask struct {
+	Description string `json:"description"`
+	Due         string `json:"due,omitempty"`
+	Overdue     bool   `json:"overdue,omitempty"`
+
+	dueAt time.Time
+}
+
+// plainConditions are the patient-facing names of the ICD-10 codes the demo
+// uses. Other conditions keep their recorded display.
+var plainConditions = map[string]string{
+	"E11.9":   "Type 2 diabetes",
+	"E66.01":  "Obesity (weight that puts your health at risk)",
+	"E78.5":   "High cholesterol",
+	"F41.1":   "Anxiety",
+	"I10":     "High blood pressure",
+	"J30.2":   "Seasonal allergies (hay fever)",
+	"J45.990": "Asthma brought on by exercise",
+	"N18.3":   "Chronic kidney disease (stage 3)",
+}
+
+// plainResult is the patient-facing name of a measurement and its usual
+// range; a zero bound is not checked.
+type plainResult struct {
+	code      string // LOINC
+	name      string
+	low, high float64
+}
+
+// plainResults are listed in the order they are shown. Measurements not
+// listed keep their recorded name and get no range note. The ranges are
+// also ReferenceRange's defaults.
+var plainResults = []plainResult{
+	{code: "85354-9", name: "Blood pressure"},
+	{code: "8867-4", name: "Pulse", low: 60, high: 100},
+	{code: "8310-5", name: "Temperature", low: 36.1, high: 37.2},
+	{code: "2708-6", name: "Oxygen level", low: 95, high: 100},
+	{code: "9279-1", name: "Breathing rate", low: 12, high: 20},
+	{code: "29463-7", name: "Weight"},
+	{code: "8302-2", name: "Height"},
+	{code: "39156-5", name: "Body mass index (BMI)", low: 18.5, high: 24.9},
+	{code: "72514-3", name: "Pain level"},
+	{code: "9843-4", name: "Head size"},
+	{code: "2345-7", name: "Blood sugar", low: 70, high: 140},
+	{code: "4548-4", name: "Average blood sugar (A1c)", high: 5.6},
+	{code: "2093-3", name: "Total cholesterol", high: 199},
+	{code: "18262-6", name: `"Bad" cholesterol (LDL)`, high: 99},
+	{code: "2085-9", name: `"Good" cholesterol (HDL)`, low: 40},
+	{code: "2571-8", name: "Blood fats (triglycerides)", high: 149},
+	{code: "2160-0", name: "Kidney waste level (creatinine)", low: 0.6, high: 1.3},
+	{code: "33914-3", name: "Kidney function (eGFR)", low: 60},
+	{code: "2823-3", name: "Potassium", low: 3.5, high: 5.0},
+	{code: "2951-2", name: "Sodium", low: 135, high: 145},
+	{code: "1742-6", name: "Liver test (ALT)", low: 7, high: 56},
+	{code: "1920-8", name: "Liver test (AST)", low: 10, high: 40},
+	{code: "3016-3", name: "Thyroid test (TSH)", low: 0.4, high: 4.0},
+	{code: "718-7", name: "Hemoglobin (red blood cells)", low: 12, high: 17.5},
+}
+
+// note says how a value compares with the usual range, or "" if there is
+// no range.
+func (r plainResult) note(v float64) string {
+	switch {
+	case r.low == 0 && r.high == 0:
+		return ""
+	case r.low != 0 && v < r.low:
+		return "Lower than usual"
+	case r.high != 0 && v > r.high:
+		return "Higher than usual"
+	}
+	return "In the usual range"
+}
+
+// BuildPatientView builds the patient-facing view of a chart. Only the
+// latest observation of each kind is kept. now decides which activities are
+// overdue.
+func BuildPatientView(now time.Time, patient json.RawMessage, observations, conditions, plans []json.RawMessage) PatientView {
+	var v PatientView
+	if m, err := Parse(patient); err == nil {
+		v.FirstName, _ = Path(m, "name.given").(string)
+		if v.FirstName == "" {
+			v.FirstName = PatientName(m)
+		}
+	}
+
+	for _, raw := range conditions {
+		m, err := Parse(raw)
+		if err != nil || !ConditionActive(m) {
+			continue
+		}
+		name := plainConditions[firstCoding(getMap(m, "code"))]
+		if name == "" {
+			name = ConditionDisplay(m)
+		}
+		v.Conditions = append(v.Conditions, name)
+	}
+
+	latest := make(map[string]map[string]any)
+	var unlisted []string
+	for _, raw := range observations {
+		m, err := Parse(raw)
+		if err != nil || ObservationValue(m) == "" || getString(m, "status") == "entered-in-error" {
+			continue
+		}
+		code := observationLoincCode(m)
+		prev, seen := latest[code]
+		if !seen && !plainResultKnown(code) {
+			unlisted = append(unlisted, code)
+		}
+		// Ties, including results without a date, go to the later entry.
+		t, _ := effectiveTime(m)
+		pt, _ := effectiveTime(prev)
+		if !seen || !t.Before(pt) {
+			latest[code] = m
+		}
+	}
+	for _, r := range plainResults {
+		if m, ok := latest[r.code]; ok {
+			v.Results = append(v.Results, patientResult(r, m))
+		}
+	}
+	for _, code := range unlisted {
+		m := latest[code]
+		v.Results = append(v.Results, patientResult(plainResult{name: getString(getMap(m, "code"), "text")}, m))
+	}
+
+	for _, raw := range plans {
+		m, err := Parse(raw)
+		if err != nil || getString(m, "status") != "active" {
+			continue
+		}
+		for _, item := range GetDashboardPlan(m, "").Outstanding {
+			task := PatientTask{Description: item.Description}
+			if due, ok := ScheduledDate(item.ScheduleNote); ok {
+				task.Due = due.Format("January 2, 2006")
+				task.Overdue = due.Before(now)
+				task.dueAt = due
+			}
+			v.Upcoming = append(v.Upcoming, task)
+		}
+	}
+	// Soonest first, which puts overdue activities at the top; undated
+	// activities go last.
+	sort.SliceStable(v.Upcoming, func(i, j int) bool {
+		di, dj := v.Upcoming[i].dueAt, v.Upcoming[j].dueAt
+		if di.IsZero() != dj.IsZero() {
+			return !di.IsZero()
+		}
+		return di.Before(dj)
+	})
+	return v
+}
+
+func plainResultKnown(code string) bool {
+	for _, r := range plainResults {
+		if r.code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func patientResult(r plainResult, m map[string]any) PatientResult {
+	res := PatientResult{Name: r.name, Value: ObservationValue(m)}
+	if t, ok := effectiveTime(m); ok {
+		res.Date = t.Local().Format("Jan 2, 2006")
+	}
+	if systolic, diastolic, ok := bloodPressure(m); ok {
+		res.Note = "At goal (under 140/90)"
+		if systolic >= uncontrolledSystolic || diastolic >= uncontrolledDiastolic {
+			res.Note = "Above goal (under 140/90)"
+		}
+	} else if vq := getMap(m, "valueQuantity"); vq != nil {
+		res.Note = r.note(getNumber(vq, "value"))
+	}
+	return res
+}
+
+// PrintPatientView displays the patient-facing view.
+func PrintPatientView(v PatientView) {
+	fmt.Println(headerStyle.Render(fmt.Sprintf("Hello, %s", v.FirstName)))
+	fmt.Println("  This is what you would see in your patient portal.")
+	fmt.Println()
+
+	fmt.Println(headerStyle.Render("Your health conditions"))
+	for _, c := range v.Conditions {
+		fmt.Printf("  • %s\n", c)
+	}
+	if len(v.Conditions) == 0 {
+		fmt.Println("  None on record.")
+	}
+	fmt.Println()
+
+	fmt.Println(headerStyle.Render("Your latest results"))
+	for _, r := range v.Results {
+		line := fmt.Sprintf("  %-32s %-18s %-13s", r.Name, r.Value, r.Date)
+		fmt.Println(strings.TrimRight(line+" "+r.Note, " "))
+	}
+	if len(v.Results) == 0 {
+		fmt.Println("  No results yet.")
+	}
+	fmt.Println()
+
+	fmt.Println(headerStyle.Render("Coming up"))
+	for _, t := range v.Upcoming {
+		switch {
+		case t.Overdue:
+			fmt.Printf("  %s %s (was due %s)\n", flagStyle.Render("Past due"), t.Description, t.Due)
+		case t.Due != "":
+			fmt.Printf("  • %s, by %s\n", t.Description, t.Due)
+		default:
+			fmt.Printf("  • %s\n", t.Description)
+		}
+	}
+	if len(v.Upcoming) == 0 {
+		fmt.Println("  Nothing scheduled.")
+	}
+	fmt.Println()
+	fmt.Println(labelStyle.UnsetWidth().Render("  Questions about your results? Ask your care team at your next visit."))
+}
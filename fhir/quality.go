@@ -0,0 +1,138 @@
+package fhir
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// QualityFix says how a data quality issue can be repaired.
+type QualityFix int
+
+const (
+	// FixNone means the issue needs a person to look at it.
+	FixNone QualityFix = iota
+	// FixSetDate sets the observation's effectiveDateTime to a date someone
+	// enters. meta.lastUpdated says when the observation was stored, not
+	// when it was measured, so it is never used instead.
+	FixSetDate
+	// FixAddIdentifier gives the patient a local MRN.
+	FixAddIdentifier
+	// FixDelete deletes the resource.
+	FixDelete
+)
+
+// QualityIssue is one problem found by the data quality audit.
+type QualityIssue struct {
+	ResourceType string
+	ID           string
+	PatientID    string
+	Problem      string
+	Fix          QualityFix
+}
+
+// Ref returns the issue's resource as a "Type/id" reference.
+func (q QualityIssue) Ref() string {
+	return q.ResourceType + "/" + q.ID
+}
+
+// Automatic reports whether the fix can be applied without asking anything,
+// as Fix all does.
+func (q QualityIssue) Automatic() bool {
+	return q.Fix != FixNone && q.Fix != FixSetDate
+}
+
+// FixLabel describes the fix, or is empty when there is none.
+func (q QualityIssue) FixLabel() string {
+	switch q.Fix {
+	case FixSetDate:
+		return "enter the date measured"
+	case FixAddIdentifier:
+		return "assign local MRN"
+	case FixDelete:
+		return "delete " + q.ResourceType
+	}
+	return ""
+}
+
+// FindQualityIssues checks patients and clinical resources for missing dates,
+// codes, identifiers, and subject references to patients that don't exist.
+func FindQualityIssues(patients, resources []json.RawMessage) []QualityIssue {
+	var issues []QualityIssue
+	known := make(map[string]bool)
+	for _, raw := range patients {
+		m, err := Parse(raw)
+		if err != nil {
+			continue
+		}
+		id := getString(m, "id")
+		known[id] = true
+		if len(getSlice(m, "identifier")) == 0 {
+			issues = append(issues, QualityIssue{"Patient", id, id, "patient has no identifier", FixAddIdentifier})
+		}
+	}
+
+	for _, raw := range resources {
+		m, err := Parse(raw)
+		if err != nil {
+			continue
+		}
+		rt, id := getString(m, "resourceType"), getString(m, "id")
+		patientID, isPatient := SubjectPatient(m)
+		switch {
+		case getString(getMap(m, "subject"), "reference") == "":
+			issues = append(issues, QualityIssue{rt, id, "", "no subject", FixNone})
+		case isPatient && !known[patientID]:
+			issues = append(issues, QualityIssue{rt, id, patientID, "subject patient does not exist", FixDelete})
+			continue // deleting it resolves anything else
+		}
+
+		switch rt {
+		case "Observation":
+			if !hasEffective(m) {
+				issues = append(issues, QualityIssue{rt, id, patientID, "observation has no date", FixSetDate})
+			}
+		case "Condition":
+			if len(getSlice(getMap(m, "code"), "coding")) == 0 {
+				issues = append(issues, QualityIssue{rt, id, patientID, "condition has no code", FixNone})
+			}
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool { return issues[i].Problem < issues[j].Problem })
+	return issues
+}
+
+func hasEffective(m map[string]any) bool {
+	for _, key := range []string{"effectiveDateTime", "effectiveInstant", "effectivePeriod", "effectiveTiming"} {
+		if _, ok := m[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// PrintQualityIssues displays the audit findings with a count per problem.
+func PrintQualityIssues(issues []QualityIssue) {
+	counts := make(map[string]int)
+	var problems []string
+	for _, q := range issues {
+		if counts[q.Problem] == 0 {
+			problems = append(problems, q.Problem)
+		}
+		counts[q.Problem]++
+	}
+
+	fmt.Println(headerStyle.Render(fmt.Sprintf("Data Quality Issues (%d)", len(issues))))
+	for _, p := range problems {
+		fmt.Printf("  %4d  %s\n", counts[p], p)
+	}
+	fmt.Println()
+	for _, q := range issues {
+		fix := q.FixLabel()
+		if fix == "" {
+			fix = "-"
+		}
+		fmt.Printf("  %-50s  %-40s  %s\n", q.Ref(), q.Problem, fix)
+	}
+}
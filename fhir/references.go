@@ -0,0 +1,124 @@
+package fhir
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// EntryReference is a reference found in one entry of a transaction bundle.
+type EntryReference struct {
+	// Entry is the index of the entry in the bundle.
+	Entry int
+	// Source describes the entry, e.g. "Observation" or its fullUrl.
+	Source string
+	// Path is where the reference sits, e.g. "subject.reference".
+	Path string
+	// Reference is the value itself, e.g. "Patient/123" or "urn:uuid:...".
+	Reference string
+}
+
+func (r EntryReference) String() string {
+	return fmt.Sprintf("entry %d (%s) %s → %s", r.Entry, r.Source, r.Path, r.Reference)
+}
+
+// IsURN reports whether the reference points at another bundle entry's
+// fullUrl rather than a server resource.
+func (r EntryReference) IsURN() bool {
+	return strings.HasPrefix(r.Reference, "urn:")
+}
+
+// Target returns the resource type and ID of a relative reference such as
+// "Patient/123" or "Patient/123/_history/2", or ok=false for anything else.
+func (r EntryReference) Target() (resourceType, id string, ok bool) {
+	parts := strings.Split(r.Reference, "/")
+	if len(parts) != 2 && !(len(parts) == 4 && parts[2] == "_history") {
+		return "", "", false
+	}
+	if parts[0] == "" || parts[1] == "" || strings.Contains(parts[0], ":") {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+// BundleReferences returns every reference in the entries' resources that
+// could be broken: relative references and urn: references. Contained
+// ("#id") and absolute URL references are skipped. local holds the
+// references the bundle itself satisfies: each entry's fullUrl and the
+// "Type/id" of each PUT entry.
+func BundleReferences(entries []map[string]any) (refs []EntryReference, local map[string]bool) {
+	local = make(map[string]bool)
+	for i, e := range entries {
+		if fullURL, _ := e["fullUrl"].(string); fullURL != "" {
+			local[fullURL] = true
+		}
+		request, _ := e["request"].(map[string]any)
+		if getString(request, "method") == "PUT" {
+			local[getString(request, "url")] = true
+		}
+
+		res := entryResourceMap(e["resource"])
+		if res == nil {
+			continue
+		}
+		source := getString(res, "resourceType")
+		if fullURL, _ := e["fullUrl"].(string); fullURL != "" {
+			source += " " + fullURL
+		}
+		walkReferences(res, "", func(path, ref string) {
+			if strings.HasPrefix(ref, "#") || strings.Contains(ref, "://") {
+				return
+			}
+			refs = append(refs, EntryReference{Entry: i, Source: source, Path: path, Reference: ref})
+		})
+	}
+	return refs, local
+}
+
+// entryResourceMap accepts a bundle entry resource as raw JSON or a map.
+func entryResourceMap(v any) map[string]any {
+	switch r := v.(type) {
+	case map[string]any:
+		return r
+	case json.RawMessage:
+		m, _ := Parse(r)
+		return m
+	case nil:
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	m, _ := Parse(b)
+	return m
+}
+
+// walkReferences calls fn for every string "reference" field under v.
+func walkReferences(v any, path string, fn func(path, ref string)) {
+	switch t := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := t[k]
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			if ref, ok := child.(string); ok && k == "reference" {
+				fn(p, ref)
+				continue
+			}
+			walkReferences(child, p, fn)
+		}
+	case []any:
+		for i, child := range t {
+			walkReferences(child, fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+	}
+}
@@ -1,6 +1,12 @@
 package fhir
 
-import "encoding/json"
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+)
 
 // NewPatient builds a FHIR Patient resource as JSON.
 func NewPatient(given, family, dob, gender string) json.RawMessage {
@@ -22,8 +28,10 @@ func NewPatient(given, family, dob, gender string) json.RawMessage {
 // NewBloodPressureObservation builds a FHIR Observation for blood pressure.
 func NewBloodPressureObservation(patientID string, systolic, diastolic int) json.RawMessage {
 	obs := map[string]any{
-		"resourceType": "Observation",
-		"status":       "final",
+		"resourceType":      "Observation",
+		"status":            "final",
+		"category":          observationCategory(CategoryVitalSigns),
+		"effectiveDateTime": time.Now().UTC().Format(time.RFC3339),
 		"code": map[string]any{
 			"coding": []map[string]any{
 				{
@@ -81,8 +89,10 @@ func NewBloodPressureObservation(patientID string, systolic, diastolic int) json
 // NewWeightObservation builds a FHIR Observation for body weight.
 func NewWeightObservation(patientID string, kg float64) json.RawMessage {
 	obs := map[string]any{
-		"resourceType": "Observation",
-		"status":       "final",
+		"resourceType":      "Observation",
+		"status":            "final",
+		"category":          observationCategory(CategoryVitalSigns),
+		"effectiveDateTime": time.Now().UTC().Format(time.RFC3339),
 		"code": map[string]any{
 			"coding": []map[string]any{
 				{
@@ -110,8 +120,10 @@ func NewWeightObservation(patientID string, kg float64) json.RawMessage {
 // NewHeartRateObservation builds a FHIR Observation for heart rate.
 func NewHeartRateObservation(patientID string, bpm int) json.RawMessage {
 	obs := map[string]any{
-		"resourceType": "Observation",
-		"status":       "final",
+		"resourceType":      "Observation",
+		"status":            "final",
+		"category":          observationCategory(CategoryVitalSigns),
+		"effectiveDateTime": time.Now().UTC().Format(time.RFC3339),
 		"code": map[string]any{
 			"coding": []map[string]any{
 				{
@@ -136,11 +148,50 @@ func NewHeartRateObservation(patientID string, bpm int) json.RawMessage {
 	return b
 }
 
+// Observation categories, from the observation-category code system.
+const (
+	ObservationCategorySystem = "http://terminology.hl7.org/CodeSystem/observation-category"
+
+	CategoryVitalSigns = "vital-signs"
+	CategoryLaboratory = "laboratory"
+)
+
+var categoryDisplays = map[string]string{
+	CategoryVitalSigns: "Vital Signs",
+	CategoryLaboratory: "Laboratory",
+}
+
+// observationCategory returns an Observation.category with one coding.
+func observationCategory(code string) []map[string]any {
+	return []map[string]any{{
+		"coding": []map[string]any{{"system": ObservationCategorySystem, "code": code, "display": categoryDisplays[code]}},
+	}}
+}
+
+// ObservationCategory returns the code of an Observation's first
+// observation-category coding, or "" if it has none.
+func ObservationCategory(m map[string]any) string {
+	for _, c := range getSlice(m, "category") {
+		cm, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		for _, coding := range getSlice(cm, "coding") {
+			if cd, ok := coding.(map[string]any); ok && getString(cd, "system") == ObservationCategorySystem {
+				return getString(cd, "code")
+			}
+		}
+	}
+	return ""
+}
+
 // newSimpleObservation builds a FHIR Observation with a single valueQuantity.
-func newSimpleObservation(patientID, loincCode, loincDisplay, text string, value float64, unit, unitCode string) json.RawMessage {
+func newSimpleObservation(patientID, category, loincCode, loincDisplay, text string, value float64, unit, unitCode string) json.RawMessage {
 	obs := map[string]any{
-		"resourceType": "Observation",
-		"status":       "final",
+		"resourceType":      "Observation",
+		"status":            "final",
+		"category":          observationCategory(category),
+		"effectiveDateTime": time.Now().UTC().Format(time.RFC3339),
 		"code": map[string]any{
 			"coding": []map[string]any{
 				{
@@ -166,55 +217,280 @@ func newSimpleObservation(patientID, loincCode, loincDisplay, text string, value
 }
 
 func NewTemperatureObservation(patientID string, celsius float64) json.RawMessage {
-	return newSimpleObservation(patientID, "8310-5", "Body temperature", "Temperature", celsius, "°C", "Cel")
+	return newSimpleObservation(patientID, CategoryVitalSigns, "8310-5", "Body temperature", "Temperature", celsius, "°C", "Cel")
 }
 
 func NewOxygenSaturationObservation(patientID string, percent int) json.RawMessage {
-	return newSimpleObservation(patientID, "2708-6", "Oxygen saturation", "O2 Saturation", float64(percent), "%", "%")
+	return newSimpleObservation(patientID, CategoryVitalSigns, "2708-6", "Oxygen saturation", "O2 Saturation", float64(percent), "%", "%")
 }
 
 func NewRespiratoryRateObservation(patientID string, perMin int) json.RawMessage {
-	return newSimpleObservation(patientID, "9279-1", "Respiratory rate", "Respiratory Rate", float64(perMin), "/min", "/min")
+	return newSimpleObservation(patientID, CategoryVitalSigns, "9279-1", "Respiratory rate", "Respiratory Rate", float64(perMin), "/min", "/min")
 }
 
 func NewBloodGlucoseObservation(patientID string, mgDL float64) json.RawMessage {
-	return newSimpleObservation(patientID, "2345-7", "Glucose [Mass/volume] in Blood", "Blood Glucose", mgDL, "mg/dL", "mg/dL")
+	return newSimpleObservation(patientID, CategoryLaboratory, "2345-7", "Glucose [Mass/volume] in Blood", "Blood Glucose", mgDL, "mg/dL", "mg/dL")
 }
 
 func NewTotalCholesterolObservation(patientID string, mgDL float64) json.RawMessage {
-	return newSimpleObservation(patientID, "2093-3", "Cholesterol [Mass/volume] in Serum or Plasma", "Total Cholesterol", mgDL, "mg/dL", "mg/dL")
+	return newSimpleObservation(patientID, CategoryLaboratory, "2093-3", "Cholesterol [Mass/volume] in Serum or Plasma", "Total Cholesterol", mgDL, "mg/dL", "mg/dL")
 }
 
 func NewBMIObservation(patientID string, value float64) json.RawMessage {
-	return newSimpleObservation(patientID, "39156-5", "Body mass index", "BMI", value, "kg/m2", "kg/m2")
+	return newSimpleObservation(patientID, CategoryVitalSigns, "39156-5", "Body mass index", "BMI", value, "kg/m2", "kg/m2")
+}
+
+func NewHeightObservation(patientID string, cm float64) json.RawMessage {
+	return newSimpleObservation(patientID, CategoryVitalSigns, "8302-2", "Body height", "Height", cm, "cm", "cm")
+}
+
+func NewHeadCircumferenceObservation(patientID string, cm float64) json.RawMessage {
+	return newSimpleObservation(patientID, CategoryVitalSigns, "9843-4", "Head Occipital-frontal circumference", "Head Circumference", cm, "cm", "cm")
+}
+
+// NewPainScoreObservation builds a patient-reported pain score on the 0-10
+// numeric rating scale.
+func NewPainScoreObservation(patientID string, score int) json.RawMessage {
+	return newSimpleObservation(patientID, CategoryVitalSigns, "72514-3", "Pain severity - 0-10 verbal numeric rating [Score] - Reported", "Pain Score", float64(score), "/10", "{score}")
+}
+
+// ComputeBMI returns body mass index from weight and height, rounded to one
+// decimal place.
+func ComputeBMI(kg, cm float64) float64 {
+	m := cm / 100
+	return math.Round(kg/(m*m)*10) / 10
+}
+
+// WithDerivedFrom returns a copy of an Observation that records the
+// observations it was calculated from, e.g. "Observation/123".
+func WithDerivedFrom(observation json.RawMessage, refs ...string) json.RawMessage {
+	var m map[string]any
+	if err := json.Unmarshal(observation, &m); err != nil {
+		return observation
+	}
+	var derived []map[string]any
+	for _, ref := range refs {
+		derived = append(derived, map[string]any{"reference": ref})
+	}
+	m["derivedFrom"] = derived
+	b, _ := json.Marshal(m)
+	return b
+}
+
+// WithEffective sets an observation's effectiveDateTime, for results
+// measured earlier than they are recorded. The builders set it to now.
+func WithEffective(observation json.RawMessage, t time.Time) json.RawMessage {
+	var m map[string]any
+	if err := json.Unmarshal(observation, &m); err != nil {
+		return observation
+	}
+	m["effectiveDateTime"] = t.UTC().Format(time.RFC3339)
+	b, _ := json.Marshal(m)
+	return b
 }
 
 func NewHbA1cObservation(patientID string, percent float64) json.RawMessage {
-	return newSimpleObservation(patientID, "4548-4", "Hemoglobin A1c/Hemoglobin.total in Blood", "HbA1c", percent, "%", "%")
+	return newSimpleObservation(patientID, CategoryLaboratory, "4548-4", "Hemoglobin A1c/Hemoglobin.total in Blood", "HbA1c", percent, "%", "%")
 }
 
 func NewCreatinineObservation(patientID string, mgDL float64) json.RawMessage {
-	return newSimpleObservation(patientID, "2160-0", "Creatinine [Mass/volume] in Serum or Plasma", "Creatinine", mgDL, "mg/dL", "mg/dL")
+	return newSimpleObservation(patientID, CategoryLaboratory, "2160-0", "Creatinine [Mass/volume] in Serum or Plasma", "Creatinine", mgDL, "mg/dL", "mg/dL")
 }
 
 func NewEGFRObservation(patientID string, value float64) json.RawMessage {
-	return newSimpleObservation(patientID, "33914-3", "Glomerular filtration rate/1.73 sq M.predicted", "eGFR", value, "mL/min/1.73m2", "mL/min/{1.73_m2}")
+	return newSimpleObservation(patientID, CategoryLaboratory, "33914-3", "Glomerular filtration rate/1.73 sq M.predicted", "eGFR", value, "mL/min/1.73m2", "mL/min/{1.73_m2}")
 }
 
-// NewCondition builds a FHIR Condition resource with an ICD-10 code.
-func NewCondition(patientID, icd10Code, display string) json.RawMessage {
-	c := map[string]any{
-		"resourceType":   "Condition",
-		"clinicalStatus": map[string]any{"coding": []map[string]any{{"system": "http://terminology.hl7.org/CodeSystem/condition-clinical", "code": "active"}}},
+func NewLDLObservation(patientID string, mgDL float64) json.RawMessage {
+	return newSimpleObservation(patientID, CategoryLaboratory, "18262-6", "Cholesterol in LDL [Mass/volume] in Serum or Plasma by Direct assay", "LDL Cholesterol", mgDL, "mg/dL", "mg/dL")
+}
+
+func NewHDLObservation(patientID string, mgDL float64) json.RawMessage {
+	return newSimpleObservation(patientID, CategoryLaboratory, "2085-9", "Cholesterol in HDL [Mass/volume] in Serum or Plasma", "HDL Cholesterol", mgDL, "mg/dL", "mg/dL")
+}
+
+func NewTriglyceridesObservation(patientID string, mgDL float64) json.RawMessage {
+	return newSimpleObservation(patientID, CategoryLaboratory, "2571-8", "Triglyceride [Mass/volume] in Serum or Plasma", "Triglycerides", mgDL, "mg/dL", "mg/dL")
+}
+
+func NewTSHObservation(patientID string, mIUL float64) json.RawMessage {
+	return newSimpleObservation(patientID, CategoryLaboratory, "3016-3", "Thyrotropin [Units/volume] in Serum or Plasma", "TSH", mIUL, "mIU/L", "m[IU]/L")
+}
+
+func NewPotassiumObservation(patientID string, mmolL float64) json.RawMessage {
+	return newSimpleObservation(patientID, CategoryLaboratory, "2823-3", "Potassium [Moles/volume] in Serum or Plasma", "Potassium", mmolL, "mmol/L", "mmol/L")
+}
+
+func NewSodiumObservation(patientID string, mmolL float64) json.RawMessage {
+	return newSimpleObservation(patientID, CategoryLaboratory, "2951-2", "Sodium [Moles/volume] in Serum or Plasma", "Sodium", mmolL, "mmol/L", "mmol/L")
+}
+
+func NewALTObservation(patientID string, unitsL float64) json.RawMessage {
+	return newSimpleObservation(patientID, CategoryLaboratory, "1742-6", "Alanine aminotransferase [Enzymatic activity/volume] in Serum or Plasma", "ALT", unitsL, "U/L", "U/L")
+}
+
+func NewASTObservation(patientID string, unitsL float64) json.RawMessage {
+	return newSimpleObservation(patientID, CategoryLaboratory, "1920-8", "Aspartate aminotransferase [Enzymatic activity/volume] in Serum or Plasma", "AST", unitsL, "U/L", "U/L")
+}
+
+func NewHemoglobinObservation(patientID string, gDL float64) json.RawMessage {
+	return newSimpleObservation(patientID, CategoryLaboratory, "718-7", "Hemoglobin [Mass/volume] in Blood", "Hemoglobin", gDL, "g/dL", "g/dL")
+}
+
+// LabTest is a single lab result the app can record.
+type LabTest struct {
+	Code     string // LOINC
+	Display  string
+	Text     string
+	Unit     string
+	UnitCode string // UCUM
+}
+
+// LabTests are the common tests Record Lab Result lists before anything is
+// searched, matching the single-result builders above. LabCatalog has the
+// rest.
+var LabTests = []LabTest{
+	{"2345-7", "Glucose [Mass/volume] in Blood", "Blood Glucose", "mg/dL", "mg/dL"},
+	{"4548-4", "Hemoglobin A1c/Hemoglobin.total in Blood", "HbA1c", "%", "%"},
+	{"2093-3", "Cholesterol [Mass/volume] in Serum or Plasma", "Total Cholesterol", "mg/dL", "mg/dL"},
+	{"18262-6", "Cholesterol in LDL [Mass/volume] in Serum or Plasma by Direct assay", "LDL Cholesterol", "mg/dL", "mg/dL"},
+	{"2085-9", "Cholesterol in HDL [Mass/volume] in Serum or Plasma", "HDL Cholesterol", "mg/dL", "mg/dL"},
+	{"2571-8", "Triglyceride [Mass/volume] in Serum or Plasma", "Triglycerides", "mg/dL", "mg/dL"},
+	{"2160-0", "Creatinine [Mass/volume] in Serum or Plasma", "Creatinine", "mg/dL", "mg/dL"},
+	{"33914-3", "Glomerular filtration rate/1.73 sq M.predicted", "eGFR", "mL/min/1.73m2", "mL/min/{1.73_m2}"},
+	{"2823-3", "Potassium [Moles/volume] in Serum or Plasma", "Potassium", "mmol/L", "mmol/L"},
+	{"2951-2", "Sodium [Moles/volume] in Serum or Plasma", "Sodium", "mmol/L", "mmol/L"},
+	{"1742-6", "Alanine aminotransferase [Enzymatic activity/volume] in Serum or Plasma", "ALT", "U/L", "U/L"},
+	{"1920-8", "Aspartate aminotransferase [Enzymatic activity/volume] in Serum or Plasma", "AST", "U/L", "U/L"},
+	{"3016-3", "Thyrotropin [Units/volume] in Serum or Plasma", "TSH", "mIU/L", "m[IU]/L"},
+	{"718-7", "Hemoglobin [Mass/volume] in Blood", "Hemoglobin", "g/dL", "g/dL"},
+}
+
+// LabPanel is a panel Observation and the member results it groups with
+// hasMember.
+type LabPanel struct {
+	Code    string // LOINC
+	Display string
+	Text    string
+	Members []LabTest
+}
+
+// LabPanels are the panels offered by Record Lab Panel.
+var LabPanels = []LabPanel{
+	{
+		Code: "57698-3", Display: "Lipid panel with direct LDL - Serum or Plasma", Text: "Lipid Panel",
+		Members: []LabTest{
+			{"2093-3", "Cholesterol [Mass/volume] in Serum or Plasma", "Total Cholesterol", "mg/dL", "mg/dL"},
+			{"2085-9", "Cholesterol in HDL [Mass/volume] in Serum or Plasma", "HDL Cholesterol", "mg/dL", "mg/dL"},
+			{"18262-6", "Cholesterol in LDL [Mass/volume] in Serum or Plasma by Direct assay", "LDL Cholesterol", "mg/dL", "mg/dL"},
+			{"2571-8", "Triglyceride [Mass/volume] in Serum or Plasma", "Triglycerides", "mg/dL", "mg/dL"},
+		},
+	},
+	{
+		Code: "24362-6", Display: "Renal function 2000 panel - Serum or Plasma", Text: "Renal Panel",
+		Members: []LabTest{
+			{"2160-0", "Creatinine [Mass/volume] in Serum or Plasma", "Creatinine", "mg/dL", "mg/dL"},
+			{"33914-3", "Glomerular filtration rate/1.73 sq M.predicted", "eGFR", "mL/min/1.73m2", "mL/min/{1.73_m2}"},
+			{"3094-0", "Urea nitrogen [Mass/volume] in Serum or Plasma", "BUN", "mg/dL", "mg/dL"},
+			{"2345-7", "Glucose [Mass/volume] in Serum or Plasma", "Glucose", "mg/dL", "mg/dL"},
+		},
+	},
+}
+
+// NewLabObservation builds the Observation for one lab result, on its own
+// or as a panel member.
+func NewLabObservation(patientID string, t LabTest, value float64) json.RawMessage {
+	return newSimpleObservation(patientID, CategoryLaboratory, t.Code, t.Display, t.Text, value, t.Unit, t.UnitCode)
+}
+
+// NewPanelObservation builds a panel Observation with no value of its own
+// that groups its results through hasMember. memberRefs are references such
+// as "Observation/123" or bundle urns.
+func NewPanelObservation(patientID string, p LabPanel, memberRefs []string) json.RawMessage {
+	members := make([]map[string]any, 0, len(memberRefs))
+	for _, ref := range memberRefs {
+		members = append(members, map[string]any{"reference": ref})
+	}
+	obs := map[string]any{
+		"resourceType":      "Observation",
+		"status":            "final",
+		"category":          observationCategory(CategoryLaboratory),
+		"effectiveDateTime": time.Now().UTC().Format(time.RFC3339),
 		"code": map[string]any{
 			"coding": []map[string]any{
 				{
-					"system":  "http://hl7.org/fhir/sid/icd-10-cm",
-					"code":    icd10Code,
-					"display": display,
+					"system":  "http://loinc.org",
+					"code":    p.Code,
+					"display": p.Display,
 				},
 			},
-			"text": display,
+			"text": p.Text,
+		},
+		"subject": map[string]any{
+			"reference": "Patient/" + patientID,
+		},
+		"hasMember": members,
+	}
+	b, _ := json.Marshal(obs)
+	return b
+}
+
+// ImagingModality is a DICOM acquisition modality.
+type ImagingModality struct {
+	Code    string
+	Display string
+}
+
+// ImagingModalities are the modalities offered by Record Imaging Study.
+var ImagingModalities = []ImagingModality{
+	{"DX", "Digital Radiography"},
+	{"CT", "Computed Tomography"},
+	{"MR", "Magnetic Resonance"},
+	{"US", "Ultrasound"},
+	{"MG", "Mammography"},
+	{"NM", "Nuclear Medicine"},
+}
+
+// NewImagingStudy builds a placeholder ImagingStudy with no series or
+// instances: just the modality, a description, and the date. status is
+// "registered" for an ordered study or "available" once images exist.
+func NewImagingStudy(patientID string, modality ImagingModality, description, status string, started time.Time) json.RawMessage {
+	s := map[string]any{
+		"resourceType": "ImagingStudy",
+		"status":       status,
+		"subject":      map[string]any{"reference": "Patient/" + patientID},
+		"started":      started.Format("2006-01-02"),
+		"modality": []map[string]any{{
+			"system":  "http://dicom.nema.org/resources/ontology/DCM",
+			"code":    modality.Code,
+			"display": modality.Display,
+		}},
+		"description": description,
+	}
+	b, _ := json.Marshal(s)
+	return b
+}
+
+// Code systems diagnoses are coded in.
+const (
+	ICD10System  = "http://hl7.org/fhir/sid/icd-10-cm"
+	SNOMEDSystem = "http://snomed.info/sct"
+)
+
+// NewCondition builds a FHIR Condition resource with an ICD-10 code and,
+// after it, any further codings for the same concept, such as SNOMED CT or
+// a local code.
+func NewCondition(patientID, icd10Code, display string, extra ...Coding) json.RawMessage {
+	codings := []map[string]any{{"system": ICD10System, "code": icd10Code, "display": display}}
+	for _, c := range extra {
+		codings = append(codings, c.toMap())
+	}
+	c := map[string]any{
+		"resourceType":   "Condition",
+		"clinicalStatus": map[string]any{"coding": []map[string]any{{"system": conditionClinicalSystem, "code": "active"}}},
+		"code": map[string]any{
+			"coding": codings,
+			"text":   display,
 		},
 		"subject": map[string]any{
 			"reference": "Patient/" + patientID,
@@ -224,6 +500,126 @@ func NewCondition(patientID, icd10Code, display string) json.RawMessage {
 	return b
 }
 
+// conditionClinicalSystem is the code system of Condition.clinicalStatus.
+const conditionClinicalSystem = "http://terminology.hl7.org/CodeSystem/condition-clinical"
+
+// WithOnset sets a condition's onsetDateTime to the day it began.
+func WithOnset(condition json.RawMessage, onset time.Time) json.RawMessage {
+	var m map[string]any
+	if err := json.Unmarshal(condition, &m); err != nil {
+		return condition
+	}
+	m["onsetDateTime"] = onset.Format("2006-01-02")
+	b, _ := json.Marshal(m)
+	return b
+}
+
+// WithSNOMED adds a SNOMED CT coding to a condition's code, after its ICD-10
+// coding, so the condition carries both.
+func WithSNOMED(condition json.RawMessage, code, display string) json.RawMessage {
+	return WithCoding(condition, Coding{System: SNOMEDSystem, Code: code, Display: display})
+}
+
+// WithResolved marks a condition resolved, abated on the given day.
+func WithResolved(condition map[string]any, abated time.Time) {
+	condition["clinicalStatus"] = map[string]any{
+		"coding": []any{map[string]any{"system": conditionClinicalSystem, "code": "resolved", "display": "Resolved"}},
+	}
+	condition["abatementDateTime"] = abated.Format("2006-01-02")
+}
+
+// ConditionClinicalStatus returns a condition's clinical status code, such
+// as "active" or "resolved".
+func ConditionClinicalStatus(m map[string]any) string {
+	return firstCoding(getMap(m, "clinicalStatus"))
+}
+
+// conditionVerificationSystem is the code system of
+// Condition.verificationStatus.
+const conditionVerificationSystem = "http://terminology.hl7.org/CodeSystem/condition-ver-status"
+
+// ConditionStatuses are the statuses a condition can be given when it is
+// edited: its clinical status, or entered-in-error if it was recorded by
+// mistake.
+var ConditionStatuses = []string{"active", "inactive", "resolved", "entered-in-error"}
+
+// ConditionStatus returns a condition's status as ConditionStatuses names
+// it: "entered-in-error" if its verification status says so, otherwise its
+// clinical status code.
+func ConditionStatus(m map[string]any) string {
+	if firstCoding(getMap(m, "verificationStatus")) == "entered-in-error" {
+		return "entered-in-error"
+	}
+	return ConditionClinicalStatus(m)
+}
+
+// WithConditionStatus sets a condition's status. entered-in-error is a
+// verification status, and such a condition must not have a clinical
+// status, so that is removed. A condition made active again loses its
+// abatement date.
+func WithConditionStatus(condition map[string]any, status string) {
+	if status == "entered-in-error" {
+		delete(condition, "clinicalStatus")
+		condition["verificationStatus"] = map[string]any{
+			"coding": []any{map[string]any{"system": conditionVerificationSystem, "code": "entered-in-error", "display": "Entered in Error"}},
+		}
+		return
+	}
+	if ConditionStatus(condition) == "entered-in-error" {
+		delete(condition, "verificationStatus")
+	}
+	condition["clinicalStatus"] = map[string]any{
+		"coding": []any{map[string]any{"system": conditionClinicalSystem, "code": status}},
+	}
+	switch status {
+	case "active", "recurrence", "relapse":
+		delete(condition, "abatementDateTime")
+	}
+}
+
+// ConditionICD10 returns a condition's ICD-10 code, or "" if it has none.
+func ConditionICD10(m map[string]any) string {
+	return codingIn(getMap(m, "code"), ICD10System)
+}
+
+// WithConditionText renames a condition, leaving its codes as they are.
+func WithConditionText(condition map[string]any, text string) {
+	cc, _ := condition["code"].(map[string]any)
+	if cc == nil {
+		cc = map[string]any{}
+		condition["code"] = cc
+	}
+	cc["text"] = text
+}
+
+// WithConditionCode replaces a condition's ICD-10 coding and its text. A
+// SNOMED CT coding described the old code, so it is dropped when the code
+// changes. Codings in other systems are kept.
+func WithConditionCode(condition map[string]any, icd10Code, display string) {
+	cc, _ := condition["code"].(map[string]any)
+	if cc == nil {
+		cc = map[string]any{}
+		condition["code"] = cc
+	}
+	changed := codingIn(cc, ICD10System) != icd10Code
+	codings := []any{map[string]any{"system": ICD10System, "code": icd10Code, "display": display}}
+	for _, c := range getSlice(cc, "coding") {
+		cm, _ := c.(map[string]any)
+		switch getString(cm, "system") {
+		case ICD10System:
+			// Replaced by the new coding.
+		case SNOMEDSystem:
+			if !changed {
+				codings = append(codings, cm)
+			}
+		default:
+			codings = append(codings, c)
+		}
+	}
+	cc["coding"] = codings
+	cc["text"] = display
+}
+
 // NewCarePlan builds a FHIR CarePlan resource.
 func NewCarePlan(patientID, title string) json.RawMessage {
 	cp := map[string]any{
@@ -231,6 +627,7 @@ func NewCarePlan(patientID, title string) json.RawMessage {
 		"status":       "active",
 		"intent":       "plan",
 		"title":        title,
+		"created":      time.Now().UTC().Format(time.RFC3339),
 		"subject": map[string]any{
 			"reference": "Patient/" + patientID,
 		},
@@ -265,6 +662,27 @@ func BundleEntry(resourceType string, resource json.RawMessage) map[string]any {
 	}
 }
 
+// UpdateEntry creates a bundle entry that replaces an existing resource.
+func UpdateEntry(resourceType, id string, resource json.RawMessage) map[string]any {
+	return map[string]any{
+		"resource": json.RawMessage(resource),
+		"request": map[string]any{
+			"method": "PUT",
+			"url":    resourceType + "/" + id,
+		},
+	}
+}
+
+// DeleteEntry creates a bundle entry that deletes a resource.
+func DeleteEntry(resourceType, id string) map[string]any {
+	return map[string]any{
+		"request": map[string]any{
+			"method": "DELETE",
+			"url":    resourceType + "/" + id,
+		},
+	}
+}
+
 // TransactionBundle wraps entries into a FHIR transaction bundle.
 func TransactionBundle(entries []map[string]any) json.RawMessage {
 	b := map[string]any{
@@ -275,3 +693,816 @@ func TransactionBundle(entries []map[string]any) json.RawMessage {
 	raw, _ := json.Marshal(b)
 	return raw
 }
+
+// NewNutritionOrder builds an active FHIR NutritionOrder for an oral diet.
+// The diet is coded with SNOMED CT when a code is given.
+func NewNutritionOrder(patientID, snomedCode, display, instruction string) json.RawMessage {
+	dietType := map[string]any{"text": display}
+	if snomedCode != "" {
+		dietType["coding"] = []map[string]any{
+			{
+				"system":  "http://snomed.info/sct",
+				"code":    snomedCode,
+				"display": display,
+			},
+		}
+	}
+	oralDiet := map[string]any{
+		"type": []map[string]any{dietType},
+	}
+	if instruction != "" {
+		oralDiet["instruction"] = instruction
+	}
+	no := map[string]any{
+		"resourceType": "NutritionOrder",
+		"status":       "active",
+		"intent":       "order",
+		"patient": map[string]any{
+			"reference": "Patient/" + patientID,
+		},
+		"dateTime": time.Now().UTC().Format(time.RFC3339),
+		"oralDiet": oralDiet,
+	}
+	b, _ := json.Marshal(no)
+	return b
+}
+
+// NewFlag builds an active FHIR Flag (patient alert) starting now.
+func NewFlag(patientID, text string) json.RawMessage {
+	f := map[string]any{
+		"resourceType": "Flag",
+		"status":       "active",
+		"category": []map[string]any{
+			{
+				"coding": []map[string]any{
+					{
+						"system":  "http://terminology.hl7.org/CodeSystem/flag-category",
+						"code":    "safety",
+						"display": "Safety",
+					},
+				},
+			},
+		},
+		"code": map[string]any{
+			"text": text,
+		},
+		"subject": map[string]any{
+			"reference": "Patient/" + patientID,
+		},
+		"period": map[string]any{
+			"start": time.Now().UTC().Format(time.RFC3339),
+		},
+	}
+	b, _ := json.Marshal(f)
+	return b
+}
+
+// ProblemListCode is the LOINC code identifying a patient's problem list.
+const ProblemListCode = "11450-4"
+
+// NewProblemList builds an empty working FHIR List for a patient's curated problems.
+func NewProblemList(patientID string) json.RawMessage {
+	l := map[string]any{
+		"resourceType": "List",
+		"status":       "current",
+		"mode":         "working",
+		"title":        "Problem List",
+		"code": map[string]any{
+			"coding": []map[string]any{
+				{
+					"system":  "http://loinc.org",
+					"code":    ProblemListCode,
+					"display": "Problem list - Reported",
+				},
+			},
+		},
+		"subject": map[string]any{
+			"reference": "Patient/" + patientID,
+		},
+		"entry": []any{},
+	}
+	b, _ := json.Marshal(l)
+	return b
+}
+
+// NewListEntry creates a List entry referencing another resource.
+func NewListEntry(reference, display string) map[string]any {
+	return map[string]any{
+		"item": map[string]any{
+			"reference": reference,
+			"display":   display,
+		},
+	}
+}
+
+// NewProvenance builds a FHIR Provenance recording that agent performed
+// activity (a v3 DataOperation code such as CREATE, UPDATE, or DELETE) on
+// the target references.
+func NewProvenance(targets []string, activity, agent string) json.RawMessage {
+	refs := make([]map[string]any, len(targets))
+	for i, t := range targets {
+		refs[i] = map[string]any{"reference": t}
+	}
+	p := map[string]any{
+		"resourceType": "Provenance",
+		"target":       refs,
+		"recorded":     time.Now().UTC().Format(time.RFC3339),
+		"activity": map[string]any{
+			"coding": []map[string]any{
+				{
+					"system": "http://terminology.hl7.org/CodeSystem/v3-DataOperation",
+					"code":   activity,
+				},
+			},
+		},
+		"agent": []map[string]any{
+			{
+				"who": map[string]any{
+					"display": agent,
+				},
+			},
+		},
+	}
+	b, _ := json.Marshal(p)
+	return b
+}
+
+// AuditEvent actions (R4 AuditEvent.action).
+const (
+	AuditCreate  = "C"
+	AuditRead    = "R"
+	AuditUpdate  = "U"
+	AuditDelete  = "D"
+	AuditExecute = "E"
+)
+
+// NewAuditEvent builds a RESTful-operation AuditEvent. interaction is a FHIR
+// restful-interaction code such as "read", "search-type", or "transaction".
+// entity is a reference ("Patient/123") or, for searches, a resource type;
+// patientID, when set, is added as a second entity so the event can be found
+// with the patient search parameter.
+func NewAuditEvent(action, interaction, entity, patientID, agent string, success bool) json.RawMessage {
+	outcome := "0"
+	if !success {
+		outcome = "8"
+	}
+	var entities []map[string]any
+	if strings.Contains(entity, "/") {
+		entities = append(entities, map[string]any{"what": map[string]any{"reference": entity}})
+	} else if entity != "" {
+		entities = append(entities, map[string]any{"name": entity})
+	}
+	if patientID != "" && entity != "Patient/"+patientID {
+		entities = append(entities, map[string]any{"what": map[string]any{"reference": "Patient/" + patientID}})
+	}
+	e := map[string]any{
+		"resourceType": "AuditEvent",
+		"type": map[string]any{
+			"system":  "http://terminology.hl7.org/CodeSystem/audit-event-type",
+			"code":    "rest",
+			"display": "RESTful Operation",
+		},
+		"subtype": []map[string]any{
+			{
+				"system": "http://hl7.org/fhir/restful-interaction",
+				"code":   interaction,
+			},
+		},
+		"action":   action,
+		"recorded": time.Now().UTC().Format(time.RFC3339),
+		"outcome":  outcome,
+		"agent": []map[string]any{
+			{
+				"who":       map[string]any{"display": agent},
+				"requestor": true,
+			},
+		},
+		"source": map[string]any{
+			"observer": map[string]any{"display": "phenostore-example-go"},
+		},
+		"entity": entities,
+	}
+	b, _ := json.Marshal(e)
+	return b
+}
+
+// ConfidentialitySystem is the code system for resource security labels;
+// code "R" marks a restricted record.
+const ConfidentialitySystem = "http://terminology.hl7.org/CodeSystem/v3-Confidentiality"
+
+// ActReasonSystem is the code system for purposes of use; code "BTG" is
+// break-the-glass access.
+const ActReasonSystem = "http://terminology.hl7.org/CodeSystem/v3-ActReason"
+
+// IsRestricted reports whether a resource carries the restricted
+// confidentiality label in meta.security.
+func IsRestricted(m map[string]any) bool {
+	for _, s := range getSlice(getMap(m, "meta"), "security") {
+		if sm, ok := s.(map[string]any); ok && getString(sm, "system") == ConfidentialitySystem && getString(sm, "code") == "R" {
+			return true
+		}
+	}
+	return false
+}
+
+// WithRestricted adds or removes the restricted confidentiality label,
+// keeping any other meta and security labels.
+func WithRestricted(resource json.RawMessage, restricted bool) json.RawMessage {
+	var m map[string]any
+	if err := json.Unmarshal(resource, &m); err != nil {
+		return resource
+	}
+	meta := getMap(m, "meta")
+	if meta == nil {
+		meta = map[string]any{}
+	}
+	var security []any
+	for _, s := range getSlice(meta, "security") {
+		if sm, ok := s.(map[string]any); ok && getString(sm, "system") == ConfidentialitySystem && getString(sm, "code") == "R" {
+			continue
+		}
+		security = append(security, s)
+	}
+	if restricted {
+		security = append(security, map[string]any{"system": ConfidentialitySystem, "code": "R", "display": "restricted"})
+	}
+	if len(security) > 0 {
+		meta["security"] = security
+	} else {
+		delete(meta, "security")
+	}
+	m["meta"] = meta
+	b, _ := json.Marshal(m)
+	return b
+}
+
+// NewBreakGlassAuditEvent builds the AuditEvent for opening a restricted
+// patient's chart, with the reason given as the text of a BTG purpose of
+// event.
+func NewBreakGlassAuditEvent(patientID, agent, reason string) json.RawMessage {
+	var m map[string]any
+	_ = json.Unmarshal(NewAuditEvent(AuditRead, "read", "Patient/"+patientID, patientID, agent, true), &m)
+	m["purposeOfEvent"] = []map[string]any{
+		{
+			"coding": []map[string]any{
+				{"system": ActReasonSystem, "code": "BTG", "display": "break the glass"},
+			},
+			"text": reason,
+		},
+	}
+	b, _ := json.Marshal(m)
+	return b
+}
+
+// BreakGlassReason returns the reason recorded on a break-the-glass
+// AuditEvent, and whether the event is one.
+func BreakGlassReason(m map[string]any) (string, bool) {
+	for _, p := range getSlice(m, "purposeOfEvent") {
+		pm, ok := p.(map[string]any)
+		if !ok {
+			continue
+		}
+		for _, c := range getSlice(pm, "coding") {
+			if cm, ok := c.(map[string]any); ok && getString(cm, "system") == ActReasonSystem && getString(cm, "code") == "BTG" {
+				return getString(pm, "text"), true
+			}
+		}
+	}
+	return "", false
+}
+
+// NewDevice builds a FHIR Device assigned to a patient, such as a home blood
+// pressure cuff. code is a SNOMED CT device type; serial may be empty.
+func NewDevice(patientID, code, display, serial string) json.RawMessage {
+	deviceType := map[string]any{"text": display}
+	if code != "" {
+		deviceType["coding"] = []map[string]any{
+			{
+				"system":  "http://snomed.info/sct",
+				"code":    code,
+				"display": display,
+			},
+		}
+	}
+	d := map[string]any{
+		"resourceType": "Device",
+		"status":       "active",
+		"type":         deviceType,
+		"deviceName": []map[string]any{
+			{
+				"name": display,
+				"type": "user-friendly-name",
+			},
+		},
+		"patient": map[string]any{
+			"reference": "Patient/" + patientID,
+		},
+	}
+	if serial != "" {
+		d["serialNumber"] = serial
+	}
+	b, _ := json.Marshal(d)
+	return b
+}
+
+// WithDevice returns a copy of an Observation that references the device that
+// took the measurement, e.g. "Device/123".
+func WithDevice(observation json.RawMessage, deviceRef string) json.RawMessage {
+	var m map[string]any
+	if err := json.Unmarshal(observation, &m); err != nil {
+		return observation
+	}
+	m["device"] = map[string]any{"reference": deviceRef}
+	b, _ := json.Marshal(m)
+	return b
+}
+
+// WithNote returns a copy of an Observation with a free-text annotation
+// appended to its notes, e.g. "taken after exercise".
+func WithNote(observation json.RawMessage, text string) json.RawMessage {
+	var m map[string]any
+	if err := json.Unmarshal(observation, &m); err != nil {
+		return observation
+	}
+	m["note"] = append(getSlice(m, "note"), map[string]any{
+		"text": text,
+		"time": time.Now().UTC().Format(time.RFC3339),
+	})
+	b, _ := json.Marshal(m)
+	return b
+}
+
+// ObservationNotes returns the text of an Observation's annotations.
+func ObservationNotes(m map[string]any) []string {
+	var notes []string
+	for _, n := range getSlice(m, "note") {
+		nm, _ := n.(map[string]any)
+		if text := getString(nm, "text"); text != "" {
+			notes = append(notes, text)
+		}
+	}
+	return notes
+}
+
+// PlanAction is one step of a PlanDefinition template, due DueDays after the
+// plan is applied (0 for no due date).
+type PlanAction struct {
+	Title   string
+	DueDays int
+}
+
+// NewPlanDefinition builds an active clinical-protocol PlanDefinition whose
+// actions carry their due offset as timingDuration in days.
+func NewPlanDefinition(url, name, title, description string, actions []PlanAction) json.RawMessage {
+	acts := make([]map[string]any, len(actions))
+	for i, a := range actions {
+		act := map[string]any{"title": a.Title}
+		if a.DueDays > 0 {
+			act["timingDuration"] = map[string]any{
+				"value":  a.DueDays,
+				"unit":   "days",
+				"system": "http://unitsofmeasure.org",
+				"code":   "d",
+			}
+		}
+		acts[i] = act
+	}
+	pd := map[string]any{
+		"resourceType": "PlanDefinition",
+		"url":          url,
+		"name":         name,
+		"title":        title,
+		"description":  description,
+		"status":       "active",
+		"type": map[string]any{
+			"coding": []map[string]any{
+				{
+					"system": "http://terminology.hl7.org/CodeSystem/plan-definition-type",
+					"code":   "clinical-protocol",
+				},
+			},
+		},
+		"action": acts,
+	}
+	b, _ := json.Marshal(pd)
+	return b
+}
+
+// PlanDefinitionActivities expands a PlanDefinition's actions into CarePlan
+// activities, with due dates counted from now.
+func PlanDefinitionActivities(pd map[string]any, now time.Time) []any {
+	var acts []any
+	for _, a := range getSlice(pd, "action") {
+		action, ok := a.(map[string]any)
+		if !ok {
+			continue
+		}
+		due := ""
+		if days := getNumber(getMap(action, "timingDuration"), "value"); days > 0 {
+			due = now.AddDate(0, 0, int(days)).Format("2006-01-02")
+		}
+		desc := getString(action, "title")
+		if desc == "" {
+			desc = getString(action, "description")
+		}
+		acts = append(acts, NewCarePlanActivity(desc, due))
+	}
+	return acts
+}
+
+// CarePlanFromPlanDefinition instantiates a PlanDefinition for a patient on
+// the client, for servers that do not support $apply.
+func CarePlanFromPlanDefinition(pd map[string]any, patientID string, now time.Time) json.RawMessage {
+	cp := map[string]any{
+		"resourceType": "CarePlan",
+		"status":       "active",
+		"intent":       "plan",
+		"title":        getString(pd, "title"),
+		"created":      now.UTC().Format(time.RFC3339),
+		"subject": map[string]any{
+			"reference": "Patient/" + patientID,
+		},
+		"activity": PlanDefinitionActivities(pd, now),
+	}
+	if url := getString(pd, "url"); url != "" {
+		cp["instantiatesCanonical"] = []string{url}
+	}
+	b, _ := json.Marshal(cp)
+	return b
+}
+
+// NewSchedule builds an active Schedule for a practitioner, identified by
+// display name only.
+func NewSchedule(practitioner, comment string) json.RawMessage {
+	s := map[string]any{
+		"resourceType": "Schedule",
+		"active":       true,
+		"actor": []map[string]any{
+			{"display": practitioner},
+		},
+	}
+	if comment != "" {
+		s["comment"] = comment
+	}
+	b, _ := json.Marshal(s)
+	return b
+}
+
+// NewSlot builds a free Slot in a schedule. scheduleRef is a reference such
+// as "Schedule/123" or a bundle urn.
+func NewSlot(scheduleRef string, start, end time.Time) json.RawMessage {
+	s := map[string]any{
+		"resourceType": "Slot",
+		"schedule":     map[string]any{"reference": scheduleRef},
+		"status":       "free",
+		"start":        start.UTC().Format(time.RFC3339),
+		"end":          end.UTC().Format(time.RFC3339),
+	}
+	b, _ := json.Marshal(s)
+	return b
+}
+
+// EncounterClass is an Encounter.class from the v3 ActCode system.
+type EncounterClass struct {
+	Code    string
+	Display string
+}
+
+// EncounterClasses are the kinds of visit Record Encounter offers.
+var EncounterClasses = []EncounterClass{
+	{"AMB", "ambulatory"},
+	{"VR", "virtual"},
+	{"HH", "home health"},
+}
+
+// NewEncounter builds a finished Encounter for a visit. reason, if set, is
+// recorded as free text.
+func NewEncounter(patientID string, class EncounterClass, text, reason string, start, end time.Time) json.RawMessage {
+	e := map[string]any{
+		"resourceType": "Encounter",
+		"status":       "finished",
+		"class": map[string]any{
+			"system":  "http://terminology.hl7.org/CodeSystem/v3-ActCode",
+			"code":    class.Code,
+			"display": class.Display,
+		},
+		"type":    []map[string]any{{"text": text}},
+		"subject": map[string]any{"reference": "Patient/" + patientID},
+		"period": map[string]any{
+			"start": start.UTC().Format(time.RFC3339),
+			"end":   end.UTC().Format(time.RFC3339),
+		},
+	}
+	if reason != "" {
+		e["reasonCode"] = []map[string]any{{"text": reason}}
+	}
+	b, _ := json.Marshal(e)
+	return b
+}
+
+// NewAppointment builds a booked Appointment for a patient in a slot, with
+// the practitioner identified by display name as on their Schedule.
+func NewAppointment(patientID, slotID, practitioner string, start, end time.Time) json.RawMessage {
+	participants := []map[string]any{
+		{"actor": map[string]any{"reference": "Patient/" + patientID}, "status": "accepted"},
+	}
+	if practitioner != "" {
+		participants = append(participants, map[string]any{"actor": map[string]any{"display": practitioner}, "status": "accepted"})
+	}
+	a := map[string]any{
+		"resourceType": "Appointment",
+		"status":       "booked",
+		"slot":         []map[string]any{{"reference": "Slot/" + slotID}},
+		"start":        start.UTC().Format(time.RFC3339),
+		"end":          end.UTC().Format(time.RFC3339),
+		"participant":  participants,
+	}
+	b, _ := json.Marshal(a)
+	return b
+}
+
+// ClaimDiagnosis is an ICD-10 diagnosis listed on a Claim.
+type ClaimDiagnosis struct {
+	Code    string
+	Display string
+}
+
+// CPTSystem is the code system claim items are billed in.
+const CPTSystem = "http://www.ama-assn.org/go/cpt"
+
+// ProcedureCPT returns a procedure's CPT code, or "" if it has none.
+func ProcedureCPT(m map[string]any) string {
+	return codingIn(getMap(m, "code"), CPTSystem)
+}
+
+// ClaimItem is a billed service, coded with CPT.
+type ClaimItem struct {
+	Code    string
+	Display string
+	Price   float64
+	// Source is the resource billed, e.g. "Observation/123", if any. It is
+	// recorded as supporting information, so later claims can leave it out.
+	Source string
+}
+
+// claimInfoSystem is the code system of Claim.supportingInfo categories.
+const claimInfoSystem = "http://terminology.hl7.org/CodeSystem/claiminformationcategory"
+
+// NewClaim builds a professional Claim for a self-pay patient. Every item is
+// linked to all diagnoses. encounterRef and serviced, a YYYY-MM-DD date of
+// service, are recorded on each item when set.
+func NewClaim(patientID, encounterRef, serviced string, diagnoses []ClaimDiagnosis, items []ClaimItem) json.RawMessage {
+	diagSeq := make([]int, len(diagnoses))
+	diags := make([]map[string]any, len(diagnoses))
+	for i, d := range diagnoses {
+		diagSeq[i] = i + 1
+		diags[i] = map[string]any{
+			"sequence": i + 1,
+			"diagnosisCodeableConcept": map[string]any{
+				"coding": []map[string]any{
+					{
+						"system":  ICD10System,
+						"code":    d.Code,
+						"display": d.Display,
+					},
+				},
+				"text": d.Display,
+			},
+		}
+	}
+
+	total := 0.0
+	lines := make([]map[string]any, len(items))
+	var info []map[string]any
+	for i, it := range items {
+		total += it.Price
+		line := map[string]any{
+			"sequence": i + 1,
+			"productOrService": map[string]any{
+				"coding": []map[string]any{
+					{
+						"system":  CPTSystem,
+						"code":    it.Code,
+						"display": it.Display,
+					},
+				},
+				"text": it.Display,
+			},
+			"unitPrice": map[string]any{"value": it.Price, "currency": "USD"},
+			"net":       map[string]any{"value": it.Price, "currency": "USD"},
+		}
+		if len(diagSeq) > 0 {
+			line["diagnosisSequence"] = diagSeq
+		}
+		if encounterRef != "" {
+			line["encounter"] = []map[string]any{{"reference": encounterRef}}
+		}
+		if serviced != "" {
+			line["servicedDate"] = serviced
+		}
+		if it.Source != "" {
+			info = append(info, map[string]any{
+				"sequence":       len(info) + 1,
+				"category":       map[string]any{"coding": []map[string]any{{"system": claimInfoSystem, "code": "info"}}},
+				"valueReference": map[string]any{"reference": it.Source},
+			})
+			line["informationSequence"] = []int{len(info)}
+		}
+		lines[i] = line
+	}
+
+	c := map[string]any{
+		"resourceType": "Claim",
+		"status":       "active",
+		"type": map[string]any{
+			"coding": []map[string]any{
+				{
+					"system": "http://terminology.hl7.org/CodeSystem/claim-type",
+					"code":   "professional",
+				},
+			},
+		},
+		"use":     "claim",
+		"patient": map[string]any{"reference": "Patient/" + patientID},
+		"created": time.Now().UTC().Format(time.RFC3339),
+		"provider": map[string]any{
+			"display": "Community Health Clinic",
+		},
+		"priority": map[string]any{
+			"coding": []map[string]any{
+				{
+					"system": "http://terminology.hl7.org/CodeSystem/processpriority",
+					"code":   "normal",
+				},
+			},
+		},
+		"insurance": []map[string]any{
+			{
+				"sequence": 1,
+				"focal":    true,
+				"coverage": map[string]any{"display": "Self-pay"},
+			},
+		},
+		"diagnosis": diags,
+		"item":      lines,
+		"total":     map[string]any{"value": total, "currency": "USD"},
+	}
+	if len(info) > 0 {
+		c["supportingInfo"] = info
+	}
+	b, _ := json.Marshal(c)
+	return b
+}
+
+// NewBinary builds a FHIR Binary resource holding raw file content.
+func NewBinary(contentType string, data []byte) json.RawMessage {
+	b, _ := json.Marshal(map[string]any{
+		"resourceType": "Binary",
+		"contentType":  contentType,
+		"data":         base64.StdEncoding.EncodeToString(data),
+	})
+	return b
+}
+
+// NewAttachment builds an Attachment that points at a Binary resource.
+func NewAttachment(binaryRef, contentType, title string, size int) map[string]any {
+	return map[string]any{
+		"contentType": contentType,
+		"url":         binaryRef,
+		"title":       title,
+		"size":        size,
+	}
+}
+
+// NewDocumentReference builds a current DocumentReference that indexes an
+// attachment for a patient.
+func NewDocumentReference(patientID string, attachment map[string]any, now time.Time) json.RawMessage {
+	d := map[string]any{
+		"resourceType": "DocumentReference",
+		"status":       "current",
+		"subject": map[string]any{
+			"reference": "Patient/" + patientID,
+		},
+		"date":        now.UTC().Format(time.RFC3339),
+		"description": attachment["title"],
+		"content": []map[string]any{
+			{"attachment": attachment},
+		},
+	}
+	b, _ := json.Marshal(d)
+	return b
+}
+
+// BinaryContent decodes a Binary resource's data. A body that is not a
+// Binary resource is returned as is, for servers that answer a Binary read
+// with the raw content.
+func BinaryContent(raw []byte) ([]byte, error) {
+	var m map[string]any
+	if err := json.Unmarshal(raw, &m); err != nil || getString(m, "resourceType") != "Binary" {
+		return raw, nil
+	}
+	return base64.StdEncoding.DecodeString(getString(m, "data"))
+}
+
+// MRNSystem is the identifier system for medical record numbers the app
+// assigns.
+const MRNSystem = "https://example.org/fhir/mrn"
+
+// NewMRNIdentifier builds a local medical record number identifier derived
+// from a patient's ID.
+func NewMRNIdentifier(patientID string) map[string]any {
+	value := strings.ToUpper(strings.ReplaceAll(patientID, "-", ""))
+	if len(value) > 10 {
+		value = value[:10]
+	}
+	return map[string]any{
+		"use":    "usual",
+		"type":   map[string]any{"coding": []map[string]any{{"system": "http://terminology.hl7.org/CodeSystem/v2-0203", "code": "MR"}}},
+		"system": MRNSystem,
+		"value":  "MRN-" + value,
+	}
+}
+
+// NewGroup builds an empty, active FHIR Group of patients: a cohort, or a
+// clinician's panel when managedBy names who manages it (e.g. "Dr. Lee").
+func NewGroup(name, managedBy string) json.RawMessage {
+	g := map[string]any{
+		"resourceType": "Group",
+		"active":       true,
+		"type":         "person",
+		"actual":       true,
+		"name":         name,
+		"quantity":     0,
+	}
+	if managedBy != "" {
+		g["managingEntity"] = map[string]any{"display": managedBy}
+	}
+	b, _ := json.Marshal(g)
+	return b
+}
+
+// SetGroupMembers replaces a Group's members with the given patients and
+// updates its quantity. names holds an optional display for each patient.
+func SetGroupMembers(g map[string]any, patientIDs []string, names map[string]string) {
+	members := make([]map[string]any, 0, len(patientIDs))
+	for _, id := range patientIDs {
+		entity := map[string]any{"reference": "Patient/" + id}
+		if name := names[id]; name != "" {
+			entity["display"] = name
+		}
+		members = append(members, map[string]any{"entity": entity})
+	}
+	g["member"] = members
+	g["quantity"] = len(members)
+}
+
+// NewSubscription builds an R4 rest-hook Subscription that POSTs the full
+// matching resource to endpoint. header, if set, is sent with every
+// notification (e.g. "Authorization: Bearer ...").
+func NewSubscription(criteria, endpoint, header, reason string) json.RawMessage {
+	channel := map[string]any{
+		"type":     "rest-hook",
+		"endpoint": endpoint,
+		"payload":  "application/fhir+json",
+	}
+	if header != "" {
+		channel["header"] = []string{header}
+	}
+	s := map[string]any{
+		"resourceType": "Subscription",
+		"status":       "requested",
+		"reason":       reason,
+		"criteria":     criteria,
+		"channel":      channel,
+	}
+	b, _ := json.Marshal(s)
+	return b
+}
+
+// NotificationResources returns the resources carried by a rest-hook
+// notification: the resource itself for an R4 full-resource payload, or the
+// entries of a notification Bundle, skipping SubscriptionStatus and
+// Parameters status entries. An empty body (a ping) has no resources.
+func NotificationResources(body []byte) ([]map[string]any, error) {
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return nil, nil
+	}
+	m, err := Parse(body)
+	if err != nil {
+		return nil, err
+	}
+	if getString(m, "resourceType") != "Bundle" {
+		return []map[string]any{m}, nil
+	}
+	var out []map[string]any
+	for _, e := range getSlice(m, "entry") {
+		entry, _ := e.(map[string]any)
+		res := getMap(entry, "resource")
+		switch getString(res, "resourceType") {
+		case "", "SubscriptionStatus", "Parameters":
+			continue
+		}
+		out = append(out, res)
+	}
+	return out, nil
+}
@@ -0,0 +1,152 @@
+package fhir
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+//go:generate go run ./internal/gensearchparams
+
+// modifiers lists the search modifiers each parameter type takes besides
+// :missing. Reference parameters also take a resource type, as in
+// subject:Patient.
+var modifiers = map[string][]string{
+	"string":    {"exact", "contains"},
+	"token":     {"text", "not", "above", "below", "in", "not-in", "of-type"},
+	"reference": {"identifier", "above", "below"},
+	"uri":       {"above", "below"},
+}
+
+// SearchParamNames returns the search parameters a resource type supports,
+// sorted, for offering completion. It returns nil for resource types not in
+// searchparams.txt.
+func SearchParamNames(resourceType string) []string {
+	own, ok := searchParams[resourceType]
+	if !ok {
+		return nil
+	}
+	names := make([]string, 0, len(commonSearchParams)+len(own))
+	for name := range commonSearchParams {
+		names = append(names, name)
+	}
+	for name := range own {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckSearchParams checks the parameter names of a search against the
+// parameters resourceType supports, so a typo like "patinet" fails before the
+// search is sent rather than being ignored by the server. Modifiers are
+// checked against the parameter's type, and _has parameters against the
+// resource type they name. The targets of chained parameters are not checked,
+// and neither are the parameters of resource types not in searchparams.txt.
+func CheckSearchParams(resourceType string, query url.Values) error {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := checkSearchParam(resourceType, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkSearchParam(resourceType, key string) error {
+	if rest, ok := strings.CutPrefix(key, "_has:"); ok {
+		parts := strings.SplitN(rest, ":", 3)
+		if len(parts) != 3 {
+			return fmt.Errorf("invalid search parameter %q (use _has:Type:reference:parameter)", key)
+		}
+		if err := checkSearchParam(parts[0], parts[1]); err != nil {
+			return err
+		}
+		return checkSearchParam(parts[0], parts[2])
+	}
+
+	name, chain, chained := strings.Cut(key, ".")
+	name, modifier, _ := strings.Cut(name, ":")
+	own, known := searchParams[resourceType]
+	typ, ok := commonSearchParams[name]
+	if !ok {
+		if !known {
+			return nil
+		}
+		if typ, ok = own[name]; !ok {
+			if s := closestSearchParam(name, own); s != "" {
+				return fmt.Errorf("unknown search parameter %q for %s (did you mean %q?)", name, resourceType, s)
+			}
+			return fmt.Errorf("unknown search parameter %q for %s", name, resourceType)
+		}
+	}
+
+	if modifier != "" && !modifierAllowed(typ, modifier) {
+		return fmt.Errorf("search parameter %q for %s does not take the :%s modifier", name, resourceType, modifier)
+	}
+	if chained && typ != "reference" {
+		return fmt.Errorf("search parameter %q for %s is not a reference and cannot be chained with .%s", name, resourceType, chain)
+	}
+	return nil
+}
+
+func modifierAllowed(typ, modifier string) bool {
+	if typ == "result" {
+		return false
+	}
+	if modifier == "missing" {
+		return true
+	}
+	if typ == "reference" {
+		if _, ok := searchParams[modifier]; ok {
+			return true
+		}
+	}
+	for _, m := range modifiers[typ] {
+		if m == modifier {
+			return true
+		}
+	}
+	return false
+}
+
+// closestSearchParam returns the parameter within two edits of name, common
+// parameters included, or "" if there is none.
+func closestSearchParam(name string, own map[string]string) string {
+	best, bestDist := "", 3
+	for _, params := range []map[string]string{commonSearchParams, own} {
+		for candidate := range params {
+			d := editDistance(name, candidate)
+			if d < bestDist || d == bestDist && candidate < best {
+				best, bestDist = candidate, d
+			}
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
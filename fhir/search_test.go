@@ -0,0 +1,72 @@
+package fhir
+
+import (
+	"net/url"
+	"slices"
+	"testing"
+)
+
+func TestCheckSearchParams(t *testing.T) {
+	tests := []struct {
+		name         string
+		resourceType string
+		key          string
+		wantErr      string
+	}{
+		{"own parameter", "Observation", SearchObservationCodeValueQuantity, ""},
+		{"common parameter", "Condition", SearchTag, ""},
+		{"result parameter", "AuditEvent", SearchSort, ""},
+		{"typo", "Observation", "patinet",
+			`unknown search parameter "patinet" for Observation (did you mean "patient"?)`},
+		{"typo of a common parameter", "Patient", "_lastupdated",
+			`unknown search parameter "_lastupdated" for Patient (did you mean "_lastUpdated"?)`},
+		{"no close match", "Slot", "practitioner", `unknown search parameter "practitioner" for Slot`},
+		{"other type's parameter", "Patient", "clinical-status",
+			`unknown search parameter "clinical-status" for Patient`},
+		{"unlisted resource type", "Basic", "anything", ""},
+		{"modifier", "Patient", "name:exact", ""},
+		{"missing modifier", "Condition", "abatement-date:missing", ""},
+		{"resource type modifier", "Observation", "subject:Patient", ""},
+		{"wrong modifier", "Patient", "birthdate:exact",
+			`search parameter "birthdate" for Patient does not take the :exact modifier`},
+		{"modifier on a result parameter", "Patient", "_count:missing",
+			`search parameter "_count" for Patient does not take the :missing modifier`},
+		{"chain", "Observation", "patient.gender", ""},
+		{"chain with type", "Observation", "subject:Patient.name", ""},
+		{"chain on a token", "Observation", "code.display",
+			`search parameter "code" for Observation is not a reference and cannot be chained with .display`},
+		{"has", "Patient", "_has:Condition:patient:code", ""},
+		{"has with a typo", "Patient", "_has:Observation:patient:cod",
+			`unknown search parameter "cod" for Observation (did you mean "code"?)`},
+		{"has with a bad reference", "Patient", "_has:Condition:patinet:code",
+			`unknown search parameter "patinet" for Condition (did you mean "patient"?)`},
+		{"malformed has", "Patient", "_has:Condition:code", `invalid search parameter "_has:Condition:code" (use _has:Type:reference:parameter)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckSearchParams(tt.resourceType, url.Values{tt.key: {"x"}})
+			got := ""
+			if err != nil {
+				got = err.Error()
+			}
+			if got != tt.wantErr {
+				t.Errorf("CheckSearchParams(%s, %q) = %q, want %q", tt.resourceType, tt.key, got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSearchParamNames(t *testing.T) {
+	names := SearchParamNames("Slot")
+	for _, want := range []string{SearchSlotSchedule, SearchSlotStart, SearchID, SearchSort} {
+		if !slices.Contains(names, want) {
+			t.Errorf("SearchParamNames(Slot) has no %q", want)
+		}
+	}
+	if !slices.IsSorted(names) {
+		t.Error("SearchParamNames(Slot) is not sorted")
+	}
+	if names := SearchParamNames("Basic"); names != nil {
+		t.Errorf("SearchParamNames(Basic) = %v, want nil", names)
+	}
+}
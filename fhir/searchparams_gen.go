@@ -0,0 +1,867 @@
+// Code generated by gensearchparams from searchparams.txt; DO NOT EDIT.
+
+package fhir
+
+// Search parameters every resource type supports.
+const (
+	SearchContained     = "_contained"
+	SearchContainedType = "_containedType"
+	SearchContent       = "_content"
+	SearchCount         = "_count"
+	SearchElements      = "_elements"
+	SearchFilter        = "_filter"
+	SearchHas           = "_has"
+	SearchID            = "_id"
+	SearchInclude       = "_include"
+	SearchLastUpdated   = "_lastUpdated"
+	SearchList          = "_list"
+	SearchProfile       = "_profile"
+	SearchQuery         = "_query"
+	SearchRevinclude    = "_revinclude"
+	SearchSecurity      = "_security"
+	SearchSort          = "_sort"
+	SearchSource        = "_source"
+	SearchSummary       = "_summary"
+	SearchTag           = "_tag"
+	SearchText          = "_text"
+	SearchTotal         = "_total"
+	SearchType          = "_type"
+)
+
+// AuditEvent search parameters.
+const (
+	SearchAuditEventAction     = "action"
+	SearchAuditEventAddress    = "address"
+	SearchAuditEventAgent      = "agent"
+	SearchAuditEventAgentName  = "agent-name"
+	SearchAuditEventAgentRole  = "agent-role"
+	SearchAuditEventAltid      = "altid"
+	SearchAuditEventDate       = "date"
+	SearchAuditEventEntity     = "entity"
+	SearchAuditEventEntityName = "entity-name"
+	SearchAuditEventEntityRole = "entity-role"
+	SearchAuditEventEntityType = "entity-type"
+	SearchAuditEventOutcome    = "outcome"
+	SearchAuditEventPatient    = "patient"
+	SearchAuditEventPolicy     = "policy"
+	SearchAuditEventSite       = "site"
+	SearchAuditEventSource     = "source"
+	SearchAuditEventSubtype    = "subtype"
+	SearchAuditEventType       = "type"
+)
+
+// CarePlan search parameters.
+const (
+	SearchCarePlanActivityCode          = "activity-code"
+	SearchCarePlanActivityDate          = "activity-date"
+	SearchCarePlanActivityReference     = "activity-reference"
+	SearchCarePlanBasedOn               = "based-on"
+	SearchCarePlanCareTeam              = "care-team"
+	SearchCarePlanCategory              = "category"
+	SearchCarePlanCondition             = "condition"
+	SearchCarePlanDate                  = "date"
+	SearchCarePlanEncounter             = "encounter"
+	SearchCarePlanGoal                  = "goal"
+	SearchCarePlanIdentifier            = "identifier"
+	SearchCarePlanInstantiatesCanonical = "instantiates-canonical"
+	SearchCarePlanInstantiatesURI       = "instantiates-uri"
+	SearchCarePlanIntent                = "intent"
+	SearchCarePlanPartOf                = "part-of"
+	SearchCarePlanPatient               = "patient"
+	SearchCarePlanPerformer             = "performer"
+	SearchCarePlanReplaces              = "replaces"
+	SearchCarePlanStatus                = "status"
+	SearchCarePlanSubject               = "subject"
+)
+
+// Claim search parameters.
+const (
+	SearchClaimCareTeam     = "care-team"
+	SearchClaimCreated      = "created"
+	SearchClaimDetailUDI    = "detail-udi"
+	SearchClaimEncounter    = "encounter"
+	SearchClaimEnterer      = "enterer"
+	SearchClaimFacility     = "facility"
+	SearchClaimIdentifier   = "identifier"
+	SearchClaimInsurer      = "insurer"
+	SearchClaimItemUDI      = "item-udi"
+	SearchClaimPatient      = "patient"
+	SearchClaimPayee        = "payee"
+	SearchClaimPriority     = "priority"
+	SearchClaimProcedureUDI = "procedure-udi"
+	SearchClaimProvider     = "provider"
+	SearchClaimStatus       = "status"
+	SearchClaimSubdetailUDI = "subdetail-udi"
+	SearchClaimUse          = "use"
+)
+
+// Condition search parameters.
+const (
+	SearchConditionAbatementAge       = "abatement-age"
+	SearchConditionAbatementDate      = "abatement-date"
+	SearchConditionAbatementString    = "abatement-string"
+	SearchConditionAsserter           = "asserter"
+	SearchConditionBodySite           = "body-site"
+	SearchConditionCategory           = "category"
+	SearchConditionClinicalStatus     = "clinical-status"
+	SearchConditionCode               = "code"
+	SearchConditionEncounter          = "encounter"
+	SearchConditionEvidence           = "evidence"
+	SearchConditionEvidenceDetail     = "evidence-detail"
+	SearchConditionIdentifier         = "identifier"
+	SearchConditionOnsetAge           = "onset-age"
+	SearchConditionOnsetDate          = "onset-date"
+	SearchConditionOnsetInfo          = "onset-info"
+	SearchConditionPatient            = "patient"
+	SearchConditionRecordedDate       = "recorded-date"
+	SearchConditionSeverity           = "severity"
+	SearchConditionStage              = "stage"
+	SearchConditionSubject            = "subject"
+	SearchConditionVerificationStatus = "verification-status"
+)
+
+// DetectedIssue search parameters.
+const (
+	SearchDetectedIssueAuthor     = "author"
+	SearchDetectedIssueCode       = "code"
+	SearchDetectedIssueIdentified = "identified"
+	SearchDetectedIssueIdentifier = "identifier"
+	SearchDetectedIssueImplicated = "implicated"
+	SearchDetectedIssuePatient    = "patient"
+)
+
+// Device search parameters.
+const (
+	SearchDeviceDeviceName   = "device-name"
+	SearchDeviceIdentifier   = "identifier"
+	SearchDeviceLocation     = "location"
+	SearchDeviceManufacturer = "manufacturer"
+	SearchDeviceModel        = "model"
+	SearchDeviceOrganization = "organization"
+	SearchDevicePatient      = "patient"
+	SearchDeviceStatus       = "status"
+	SearchDeviceType         = "type"
+	SearchDeviceUDICarrier   = "udi-carrier"
+	SearchDeviceUDIDI        = "udi-di"
+	SearchDeviceURL          = "url"
+)
+
+// DocumentReference search parameters.
+const (
+	SearchDocumentReferenceAuthenticator = "authenticator"
+	SearchDocumentReferenceAuthor        = "author"
+	SearchDocumentReferenceCategory      = "category"
+	SearchDocumentReferenceContenttype   = "contenttype"
+	SearchDocumentReferenceCustodian     = "custodian"
+	SearchDocumentReferenceDate          = "date"
+	SearchDocumentReferenceDescription   = "description"
+	SearchDocumentReferenceEncounter     = "encounter"
+	SearchDocumentReferenceEvent         = "event"
+	SearchDocumentReferenceFacility      = "facility"
+	SearchDocumentReferenceFormat        = "format"
+	SearchDocumentReferenceIdentifier    = "identifier"
+	SearchDocumentReferenceLanguage      = "language"
+	SearchDocumentReferenceLocation      = "location"
+	SearchDocumentReferencePatient       = "patient"
+	SearchDocumentReferencePeriod        = "period"
+	SearchDocumentReferenceRelated       = "related"
+	SearchDocumentReferenceRelatesto     = "relatesto"
+	SearchDocumentReferenceRelation      = "relation"
+	SearchDocumentReferenceSecurityLabel = "security-label"
+	SearchDocumentReferenceSetting       = "setting"
+	SearchDocumentReferenceStatus        = "status"
+	SearchDocumentReferenceSubject       = "subject"
+	SearchDocumentReferenceType          = "type"
+)
+
+// Encounter search parameters.
+const (
+	SearchEncounterAccount            = "account"
+	SearchEncounterAppointment        = "appointment"
+	SearchEncounterBasedOn            = "based-on"
+	SearchEncounterClass              = "class"
+	SearchEncounterDate               = "date"
+	SearchEncounterDiagnosis          = "diagnosis"
+	SearchEncounterEpisodeOfCare      = "episode-of-care"
+	SearchEncounterIdentifier         = "identifier"
+	SearchEncounterLength             = "length"
+	SearchEncounterLocation           = "location"
+	SearchEncounterLocationPeriod     = "location-period"
+	SearchEncounterPartOf             = "part-of"
+	SearchEncounterParticipant        = "participant"
+	SearchEncounterParticipantType    = "participant-type"
+	SearchEncounterPatient            = "patient"
+	SearchEncounterPractitioner       = "practitioner"
+	SearchEncounterReasonCode         = "reason-code"
+	SearchEncounterReasonReference    = "reason-reference"
+	SearchEncounterServiceProvider    = "service-provider"
+	SearchEncounterSpecialArrangement = "special-arrangement"
+	SearchEncounterStatus             = "status"
+	SearchEncounterSubject            = "subject"
+	SearchEncounterType               = "type"
+)
+
+// EpisodeOfCare search parameters.
+const (
+	SearchEpisodeOfCareCareManager      = "care-manager"
+	SearchEpisodeOfCareCondition        = "condition"
+	SearchEpisodeOfCareDate             = "date"
+	SearchEpisodeOfCareIdentifier       = "identifier"
+	SearchEpisodeOfCareIncomingReferral = "incoming-referral"
+	SearchEpisodeOfCareOrganization     = "organization"
+	SearchEpisodeOfCarePatient          = "patient"
+	SearchEpisodeOfCareStatus           = "status"
+	SearchEpisodeOfCareType             = "type"
+)
+
+// Flag search parameters.
+const (
+	SearchFlagAuthor     = "author"
+	SearchFlagDate       = "date"
+	SearchFlagEncounter  = "encounter"
+	SearchFlagIdentifier = "identifier"
+	SearchFlagPatient    = "patient"
+	SearchFlagSubject    = "subject"
+)
+
+// Group search parameters.
+const (
+	SearchGroupActual              = "actual"
+	SearchGroupCharacteristic      = "characteristic"
+	SearchGroupCharacteristicValue = "characteristic-value"
+	SearchGroupCode                = "code"
+	SearchGroupExclude             = "exclude"
+	SearchGroupIdentifier          = "identifier"
+	SearchGroupManagingEntity      = "managing-entity"
+	SearchGroupMember              = "member"
+	SearchGroupType                = "type"
+	SearchGroupValue               = "value"
+)
+
+// ImagingStudy search parameters.
+const (
+	SearchImagingStudyBasedon     = "basedon"
+	SearchImagingStudyBodysite    = "bodysite"
+	SearchImagingStudyDicomClass  = "dicom-class"
+	SearchImagingStudyEncounter   = "encounter"
+	SearchImagingStudyEndpoint    = "endpoint"
+	SearchImagingStudyIdentifier  = "identifier"
+	SearchImagingStudyInstance    = "instance"
+	SearchImagingStudyInterpreter = "interpreter"
+	SearchImagingStudyModality    = "modality"
+	SearchImagingStudyPatient     = "patient"
+	SearchImagingStudyPerformer   = "performer"
+	SearchImagingStudyReason      = "reason"
+	SearchImagingStudyReferrer    = "referrer"
+	SearchImagingStudySeries      = "series"
+	SearchImagingStudyStarted     = "started"
+	SearchImagingStudyStatus      = "status"
+	SearchImagingStudySubject     = "subject"
+)
+
+// List search parameters.
+const (
+	SearchListCode        = "code"
+	SearchListDate        = "date"
+	SearchListEmptyReason = "empty-reason"
+	SearchListEncounter   = "encounter"
+	SearchListIdentifier  = "identifier"
+	SearchListItem        = "item"
+	SearchListNotes       = "notes"
+	SearchListPatient     = "patient"
+	SearchListSource      = "source"
+	SearchListStatus      = "status"
+	SearchListSubject     = "subject"
+	SearchListTitle       = "title"
+)
+
+// NutritionOrder search parameters.
+const (
+	SearchNutritionOrderAdditive              = "additive"
+	SearchNutritionOrderDatetime              = "datetime"
+	SearchNutritionOrderEncounter             = "encounter"
+	SearchNutritionOrderFormula               = "formula"
+	SearchNutritionOrderIdentifier            = "identifier"
+	SearchNutritionOrderInstantiatesCanonical = "instantiates-canonical"
+	SearchNutritionOrderInstantiatesURI       = "instantiates-uri"
+	SearchNutritionOrderOraldiet              = "oraldiet"
+	SearchNutritionOrderPatient               = "patient"
+	SearchNutritionOrderProvider              = "provider"
+	SearchNutritionOrderStatus                = "status"
+	SearchNutritionOrderSupplement            = "supplement"
+)
+
+// Observation search parameters.
+const (
+	SearchObservationBasedOn                    = "based-on"
+	SearchObservationCategory                   = "category"
+	SearchObservationCode                       = "code"
+	SearchObservationCodeValueConcept           = "code-value-concept"
+	SearchObservationCodeValueDate              = "code-value-date"
+	SearchObservationCodeValueQuantity          = "code-value-quantity"
+	SearchObservationCodeValueString            = "code-value-string"
+	SearchObservationComboCode                  = "combo-code"
+	SearchObservationComboCodeValueConcept      = "combo-code-value-concept"
+	SearchObservationComboCodeValueQuantity     = "combo-code-value-quantity"
+	SearchObservationComboDataAbsentReason      = "combo-data-absent-reason"
+	SearchObservationComboValueConcept          = "combo-value-concept"
+	SearchObservationComboValueQuantity         = "combo-value-quantity"
+	SearchObservationComponentCode              = "component-code"
+	SearchObservationComponentCodeValueConcept  = "component-code-value-concept"
+	SearchObservationComponentCodeValueQuantity = "component-code-value-quantity"
+	SearchObservationComponentDataAbsentReason  = "component-data-absent-reason"
+	SearchObservationComponentValueConcept      = "component-value-concept"
+	SearchObservationComponentValueQuantity     = "component-value-quantity"
+	SearchObservationDataAbsentReason           = "data-absent-reason"
+	SearchObservationDate                       = "date"
+	SearchObservationDerivedFrom                = "derived-from"
+	SearchObservationDevice                     = "device"
+	SearchObservationEncounter                  = "encounter"
+	SearchObservationFocus                      = "focus"
+	SearchObservationHasMember                  = "has-member"
+	SearchObservationIdentifier                 = "identifier"
+	SearchObservationMethod                     = "method"
+	SearchObservationPartOf                     = "part-of"
+	SearchObservationPatient                    = "patient"
+	SearchObservationPerformer                  = "performer"
+	SearchObservationSpecimen                   = "specimen"
+	SearchObservationStatus                     = "status"
+	SearchObservationSubject                    = "subject"
+	SearchObservationValueConcept               = "value-concept"
+	SearchObservationValueDate                  = "value-date"
+	SearchObservationValueQuantity              = "value-quantity"
+	SearchObservationValueString                = "value-string"
+)
+
+// Patient search parameters.
+const (
+	SearchPatientActive              = "active"
+	SearchPatientAddress             = "address"
+	SearchPatientAddressCity         = "address-city"
+	SearchPatientAddressCountry      = "address-country"
+	SearchPatientAddressPostalcode   = "address-postalcode"
+	SearchPatientAddressState        = "address-state"
+	SearchPatientAddressUse          = "address-use"
+	SearchPatientBirthdate           = "birthdate"
+	SearchPatientDeathDate           = "death-date"
+	SearchPatientDeceased            = "deceased"
+	SearchPatientEmail               = "email"
+	SearchPatientFamily              = "family"
+	SearchPatientGender              = "gender"
+	SearchPatientGeneralPractitioner = "general-practitioner"
+	SearchPatientGiven               = "given"
+	SearchPatientIdentifier          = "identifier"
+	SearchPatientLanguage            = "language"
+	SearchPatientLink                = "link"
+	SearchPatientName                = "name"
+	SearchPatientOrganization        = "organization"
+	SearchPatientPhone               = "phone"
+	SearchPatientPhonetic            = "phonetic"
+	SearchPatientTelecom             = "telecom"
+)
+
+// PlanDefinition search parameters.
+const (
+	SearchPlanDefinitionComposedOf      = "composed-of"
+	SearchPlanDefinitionContext         = "context"
+	SearchPlanDefinitionContextQuantity = "context-quantity"
+	SearchPlanDefinitionContextType     = "context-type"
+	SearchPlanDefinitionDate            = "date"
+	SearchPlanDefinitionDefinition      = "definition"
+	SearchPlanDefinitionDependsOn       = "depends-on"
+	SearchPlanDefinitionDerivedFrom     = "derived-from"
+	SearchPlanDefinitionDescription     = "description"
+	SearchPlanDefinitionEffective       = "effective"
+	SearchPlanDefinitionIdentifier      = "identifier"
+	SearchPlanDefinitionJurisdiction    = "jurisdiction"
+	SearchPlanDefinitionName            = "name"
+	SearchPlanDefinitionPredecessor     = "predecessor"
+	SearchPlanDefinitionPublisher       = "publisher"
+	SearchPlanDefinitionStatus          = "status"
+	SearchPlanDefinitionSuccessor       = "successor"
+	SearchPlanDefinitionTitle           = "title"
+	SearchPlanDefinitionTopic           = "topic"
+	SearchPlanDefinitionType            = "type"
+	SearchPlanDefinitionURL             = "url"
+	SearchPlanDefinitionVersion         = "version"
+)
+
+// Procedure search parameters.
+const (
+	SearchProcedureBasedOn               = "based-on"
+	SearchProcedureCategory              = "category"
+	SearchProcedureCode                  = "code"
+	SearchProcedureDate                  = "date"
+	SearchProcedureEncounter             = "encounter"
+	SearchProcedureIdentifier            = "identifier"
+	SearchProcedureInstantiatesCanonical = "instantiates-canonical"
+	SearchProcedureInstantiatesURI       = "instantiates-uri"
+	SearchProcedureLocation              = "location"
+	SearchProcedurePartOf                = "part-of"
+	SearchProcedurePatient               = "patient"
+	SearchProcedurePerformer             = "performer"
+	SearchProcedureReasonCode            = "reason-code"
+	SearchProcedureReasonReference       = "reason-reference"
+	SearchProcedureStatus                = "status"
+	SearchProcedureSubject               = "subject"
+)
+
+// Schedule search parameters.
+const (
+	SearchScheduleActive          = "active"
+	SearchScheduleActor           = "actor"
+	SearchScheduleDate            = "date"
+	SearchScheduleIdentifier      = "identifier"
+	SearchScheduleServiceCategory = "service-category"
+	SearchScheduleServiceType     = "service-type"
+	SearchScheduleSpecialty       = "specialty"
+)
+
+// Slot search parameters.
+const (
+	SearchSlotAppointmentType = "appointment-type"
+	SearchSlotIdentifier      = "identifier"
+	SearchSlotSchedule        = "schedule"
+	SearchSlotServiceCategory = "service-category"
+	SearchSlotServiceType     = "service-type"
+	SearchSlotSpecialty       = "specialty"
+	SearchSlotStart           = "start"
+	SearchSlotStatus          = "status"
+)
+
+// Task search parameters.
+const (
+	SearchTaskAuthoredOn      = "authored-on"
+	SearchTaskBasedOn         = "based-on"
+	SearchTaskBusinessStatus  = "business-status"
+	SearchTaskCode            = "code"
+	SearchTaskEncounter       = "encounter"
+	SearchTaskFocus           = "focus"
+	SearchTaskGroupIdentifier = "group-identifier"
+	SearchTaskIdentifier      = "identifier"
+	SearchTaskIntent          = "intent"
+	SearchTaskModified        = "modified"
+	SearchTaskOwner           = "owner"
+	SearchTaskPartOf          = "part-of"
+	SearchTaskPatient         = "patient"
+	SearchTaskPerformer       = "performer"
+	SearchTaskPeriod          = "period"
+	SearchTaskPriority        = "priority"
+	SearchTaskRequester       = "requester"
+	SearchTaskStatus          = "status"
+	SearchTaskSubject         = "subject"
+)
+
+// commonSearchParams maps the parameters every resource type supports to
+// their types.
+var commonSearchParams = map[string]string{
+	"_contained":     "result",
+	"_containedType": "result",
+	"_content":       "special",
+	"_count":         "result",
+	"_elements":      "result",
+	"_filter":        "special",
+	"_has":           "special",
+	"_id":            "token",
+	"_include":       "result",
+	"_lastUpdated":   "date",
+	"_list":          "special",
+	"_profile":       "uri",
+	"_query":         "special",
+	"_revinclude":    "result",
+	"_security":      "token",
+	"_sort":          "result",
+	"_source":        "uri",
+	"_summary":       "result",
+	"_tag":           "token",
+	"_text":          "special",
+	"_total":         "result",
+	"_type":          "special",
+}
+
+// searchParams maps resource types to their own search parameters and
+// those parameters' types.
+var searchParams = map[string]map[string]string{
+	"AuditEvent": {
+		"action":      "token",
+		"address":     "string",
+		"agent":       "reference",
+		"agent-name":  "string",
+		"agent-role":  "token",
+		"altid":       "token",
+		"date":        "date",
+		"entity":      "reference",
+		"entity-name": "string",
+		"entity-role": "token",
+		"entity-type": "token",
+		"outcome":     "token",
+		"patient":     "reference",
+		"policy":      "uri",
+		"site":        "token",
+		"source":      "reference",
+		"subtype":     "token",
+		"type":        "token",
+	},
+	"CarePlan": {
+		"activity-code":          "token",
+		"activity-date":          "date",
+		"activity-reference":     "reference",
+		"based-on":               "reference",
+		"care-team":              "reference",
+		"category":               "token",
+		"condition":              "reference",
+		"date":                   "date",
+		"encounter":              "reference",
+		"goal":                   "reference",
+		"identifier":             "token",
+		"instantiates-canonical": "reference",
+		"instantiates-uri":       "uri",
+		"intent":                 "token",
+		"part-of":                "reference",
+		"patient":                "reference",
+		"performer":              "reference",
+		"replaces":               "reference",
+		"status":                 "token",
+		"subject":                "reference",
+	},
+	"Claim": {
+		"care-team":     "reference",
+		"created":       "date",
+		"detail-udi":    "reference",
+		"encounter":     "reference",
+		"enterer":       "reference",
+		"facility":      "reference",
+		"identifier":    "token",
+		"insurer":       "reference",
+		"item-udi":      "reference",
+		"patient":       "reference",
+		"payee":         "reference",
+		"priority":      "token",
+		"procedure-udi": "reference",
+		"provider":      "reference",
+		"status":        "token",
+		"subdetail-udi": "reference",
+		"use":           "token",
+	},
+	"Condition": {
+		"abatement-age":       "quantity",
+		"abatement-date":      "date",
+		"abatement-string":    "string",
+		"asserter":            "reference",
+		"body-site":           "token",
+		"category":            "token",
+		"clinical-status":     "token",
+		"code":                "token",
+		"encounter":           "reference",
+		"evidence":            "token",
+		"evidence-detail":     "reference",
+		"identifier":          "token",
+		"onset-age":           "quantity",
+		"onset-date":          "date",
+		"onset-info":          "string",
+		"patient":             "reference",
+		"recorded-date":       "date",
+		"severity":            "token",
+		"stage":               "token",
+		"subject":             "reference",
+		"verification-status": "token",
+	},
+	"DetectedIssue": {
+		"author":     "reference",
+		"code":       "token",
+		"identified": "date",
+		"identifier": "token",
+		"implicated": "reference",
+		"patient":    "reference",
+	},
+	"Device": {
+		"device-name":  "string",
+		"identifier":   "token",
+		"location":     "reference",
+		"manufacturer": "string",
+		"model":        "string",
+		"organization": "reference",
+		"patient":      "reference",
+		"status":       "token",
+		"type":         "token",
+		"udi-carrier":  "string",
+		"udi-di":       "string",
+		"url":          "uri",
+	},
+	"DocumentReference": {
+		"authenticator":  "reference",
+		"author":         "reference",
+		"category":       "token",
+		"contenttype":    "token",
+		"custodian":      "reference",
+		"date":           "date",
+		"description":    "string",
+		"encounter":      "reference",
+		"event":          "token",
+		"facility":       "token",
+		"format":         "token",
+		"identifier":     "token",
+		"language":       "token",
+		"location":       "uri",
+		"patient":        "reference",
+		"period":         "date",
+		"related":        "reference",
+		"relatesto":      "reference",
+		"relation":       "token",
+		"security-label": "token",
+		"setting":        "token",
+		"status":         "token",
+		"subject":        "reference",
+		"type":           "token",
+	},
+	"Encounter": {
+		"account":             "reference",
+		"appointment":         "reference",
+		"based-on":            "reference",
+		"class":               "token",
+		"date":                "date",
+		"diagnosis":           "reference",
+		"episode-of-care":     "reference",
+		"identifier":          "token",
+		"length":              "quantity",
+		"location":            "reference",
+		"location-period":     "date",
+		"part-of":             "reference",
+		"participant":         "reference",
+		"participant-type":    "token",
+		"patient":             "reference",
+		"practitioner":        "reference",
+		"reason-code":         "token",
+		"reason-reference":    "reference",
+		"service-provider":    "reference",
+		"special-arrangement": "token",
+		"status":              "token",
+		"subject":             "reference",
+		"type":                "token",
+	},
+	"EpisodeOfCare": {
+		"care-manager":      "reference",
+		"condition":         "reference",
+		"date":              "date",
+		"identifier":        "token",
+		"incoming-referral": "reference",
+		"organization":      "reference",
+		"patient":           "reference",
+		"status":            "token",
+		"type":              "token",
+	},
+	"Flag": {
+		"author":     "reference",
+		"date":       "date",
+		"encounter":  "reference",
+		"identifier": "token",
+		"patient":    "reference",
+		"subject":    "reference",
+	},
+	"Group": {
+		"actual":               "token",
+		"characteristic":       "token",
+		"characteristic-value": "composite",
+		"code":                 "token",
+		"exclude":              "token",
+		"identifier":           "token",
+		"managing-entity":      "reference",
+		"member":               "reference",
+		"type":                 "token",
+		"value":                "token",
+	},
+	"ImagingStudy": {
+		"basedon":     "reference",
+		"bodysite":    "token",
+		"dicom-class": "token",
+		"encounter":   "reference",
+		"endpoint":    "reference",
+		"identifier":  "token",
+		"instance":    "token",
+		"interpreter": "reference",
+		"modality":    "token",
+		"patient":     "reference",
+		"performer":   "reference",
+		"reason":      "token",
+		"referrer":    "reference",
+		"series":      "token",
+		"started":     "date",
+		"status":      "token",
+		"subject":     "reference",
+	},
+	"List": {
+		"code":         "token",
+		"date":         "date",
+		"empty-reason": "token",
+		"encounter":    "reference",
+		"identifier":   "token",
+		"item":         "reference",
+		"notes":        "string",
+		"patient":      "reference",
+		"source":       "reference",
+		"status":       "token",
+		"subject":      "reference",
+		"title":        "string",
+	},
+	"NutritionOrder": {
+		"additive":               "token",
+		"datetime":               "date",
+		"encounter":              "reference",
+		"formula":                "token",
+		"identifier":             "token",
+		"instantiates-canonical": "reference",
+		"instantiates-uri":       "uri",
+		"oraldiet":               "token",
+		"patient":                "reference",
+		"provider":               "reference",
+		"status":                 "token",
+		"supplement":             "token",
+	},
+	"Observation": {
+		"based-on":                      "reference",
+		"category":                      "token",
+		"code":                          "token",
+		"code-value-concept":            "composite",
+		"code-value-date":               "composite",
+		"code-value-quantity":           "composite",
+		"code-value-string":             "composite",
+		"combo-code":                    "token",
+		"combo-code-value-concept":      "composite",
+		"combo-code-value-quantity":     "composite",
+		"combo-data-absent-reason":      "token",
+		"combo-value-concept":           "token",
+		"combo-value-quantity":          "quantity",
+		"component-code":                "token",
+		"component-code-value-concept":  "composite",
+		"component-code-value-quantity": "composite",
+		"component-data-absent-reason":  "token",
+		"component-value-concept":       "token",
+		"component-value-quantity":      "quantity",
+		"data-absent-reason":            "token",
+		"date":                          "date",
+		"derived-from":                  "reference",
+		"device":                        "reference",
+		"encounter":                     "reference",
+		"focus":                         "reference",
+		"has-member":                    "reference",
+		"identifier":                    "token",
+		"method":                        "token",
+		"part-of":                       "reference",
+		"patient":                       "reference",
+		"performer":                     "reference",
+		"specimen":                      "reference",
+		"status":                        "token",
+		"subject":                       "reference",
+		"value-concept":                 "token",
+		"value-date":                    "date",
+		"value-quantity":                "quantity",
+		"value-string":                  "string",
+	},
+	"Patient": {
+		"active":               "token",
+		"address":              "string",
+		"address-city":         "string",
+		"address-country":      "string",
+		"address-postalcode":   "string",
+		"address-state":        "string",
+		"address-use":          "token",
+		"birthdate":            "date",
+		"death-date":           "date",
+		"deceased":             "token",
+		"email":                "token",
+		"family":               "string",
+		"gender":               "token",
+		"general-practitioner": "reference",
+		"given":                "string",
+		"identifier":           "token",
+		"language":             "token",
+		"link":                 "reference",
+		"name":                 "string",
+		"organization":         "reference",
+		"phone":                "token",
+		"phonetic":             "string",
+		"telecom":              "token",
+	},
+	"PlanDefinition": {
+		"composed-of":      "reference",
+		"context":          "token",
+		"context-quantity": "quantity",
+		"context-type":     "token",
+		"date":             "date",
+		"definition":       "reference",
+		"depends-on":       "reference",
+		"derived-from":     "reference",
+		"description":      "string",
+		"effective":        "date",
+		"identifier":       "token",
+		"jurisdiction":     "token",
+		"name":             "string",
+		"predecessor":      "reference",
+		"publisher":        "string",
+		"status":           "token",
+		"successor":        "reference",
+		"title":            "string",
+		"topic":            "token",
+		"type":             "token",
+		"url":              "uri",
+		"version":          "token",
+	},
+	"Procedure": {
+		"based-on":               "reference",
+		"category":               "token",
+		"code":                   "token",
+		"date":                   "date",
+		"encounter":              "reference",
+		"identifier":             "token",
+		"instantiates-canonical": "reference",
+		"instantiates-uri":       "uri",
+		"location":               "reference",
+		"part-of":                "reference",
+		"patient":                "reference",
+		"performer":              "reference",
+		"reason-code":            "token",
+		"reason-reference":       "reference",
+		"status":                 "token",
+		"subject":                "reference",
+	},
+	"Schedule": {
+		"active":           "token",
+		"actor":            "reference",
+		"date":             "date",
+		"identifier":       "token",
+		"service-category": "token",
+		"service-type":     "token",
+		"specialty":        "token",
+	},
+	"Slot": {
+		"appointment-type": "token",
+		"identifier":       "token",
+		"schedule":         "reference",
+		"service-category": "token",
+		"service-type":     "token",
+		"specialty":        "token",
+		"start":            "date",
+		"status":           "token",
+	},
+	"Task": {
+		"authored-on":      "date",
+		"based-on":         "reference",
+		"business-status":  "token",
+		"code":             "token",
+		"encounter":        "reference",
+		"focus":            "reference",
+		"group-identifier": "token",
+		"identifier":       "token",
+		"intent":           "token",
+		"modified":         "date",
+		"owner":            "reference",
+		"part-of":          "reference",
+		"patient":          "reference",
+		"performer":        "token",
+		"period":           "date",
+		"priority":         "token",
+		"requester":        "reference",
+		"status":           "token",
+		"subject":          "reference",
+	},
+}
@@ -0,0 +1,193 @@
+package fhir
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// TreeNode is one element of a resource viewed as a tree: the resource
+// itself, an object member, or an array item. Members keep the order they
+// have in the JSON.
+type TreeNode struct {
+	Key   string // member name, or "" for the root and array items
+	Index int    // position in the parent array, or -1
+	// Kind is '{' for an object, '[' for an array, and 0 for a scalar.
+	Kind byte
+	// Value is the JSON text of a scalar, e.g. `"final"`, 120, or true.
+	Value    string
+	Children []*TreeNode
+	Parent   *TreeNode
+}
+
+// ParseTree parses a JSON document into a tree.
+func ParseTree(raw []byte) (*TreeNode, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	root := &TreeNode{Index: -1}
+	if err := root.decode(dec); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+func (n *TreeNode) decode(dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		n.Kind = byte(t)
+		for i := 0; dec.More(); i++ {
+			child := &TreeNode{Index: -1, Parent: n}
+			if n.Kind == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				child.Key, _ = key.(string)
+			} else {
+				child.Index = i
+			}
+			if err := child.decode(dec); err != nil {
+				return err
+			}
+			n.Children = append(n.Children, child)
+		}
+		_, err := dec.Token() // closing delimiter
+		return err
+	case string:
+		b, _ := json.Marshal(t)
+		n.Value = string(b)
+	case nil:
+		n.Value = "null"
+	default:
+		n.Value = fmt.Sprint(t)
+	}
+	return nil
+}
+
+// Label is how the node is named in its parent: its key, or "[i]" for an
+// array item.
+func (n *TreeNode) Label() string {
+	if n.Index >= 0 {
+		return fmt.Sprintf("[%d]", n.Index)
+	}
+	return n.Key
+}
+
+// Depth is how many levels below the root the node is.
+func (n *TreeNode) Depth() int {
+	d := 0
+	for p := n.Parent; p != nil; p = p.Parent {
+		d++
+	}
+	return d
+}
+
+// Walk calls fn for n and every node below it, depth first, in order.
+func (n *TreeNode) Walk(fn func(*TreeNode)) {
+	fn(n)
+	for _, c := range n.Children {
+		c.Walk(fn)
+	}
+}
+
+// path returns the nodes from just below the root down to n.
+func (n *TreeNode) path() []*TreeNode {
+	var nodes []*TreeNode
+	for p := n; p.Parent != nil; p = p.Parent {
+		nodes = append([]*TreeNode{p}, nodes...)
+	}
+	return nodes
+}
+
+var jsonPathIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// JSONPath returns the node's JSONPath, e.g. "$.code.coding[0].system".
+func (n *TreeNode) JSONPath() string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, p := range n.path() {
+		switch {
+		case p.Index >= 0:
+			fmt.Fprintf(&b, "[%d]", p.Index)
+		case jsonPathIdent.MatchString(p.Key):
+			b.WriteString("." + p.Key)
+		default:
+			fmt.Fprintf(&b, "['%s']", strings.ReplaceAll(p.Key, "'", `\'`))
+		}
+	}
+	return b.String()
+}
+
+// choiceBases are the names of common choice elements ([x]), such as value
+// in valueQuantity. In FHIRPath they are written as the base name filtered
+// by type: value.ofType(Quantity).
+var choiceBases = []string{
+	"value", "effective", "onset", "abatement", "deceased", "multipleBirth", "occurrence",
+	"scheduled", "timing", "performed", "serviced", "medication", "reported", "born", "age",
+	"asNeeded", "dose", "rate", "product", "allowed", "used", "defaultValue", "answer",
+}
+
+// choiceTypes are the data types a choice element's name can end in.
+// Primitive types are written in lower camel case in FHIRPath.
+var choiceTypes = map[string]string{
+	"Boolean": "boolean", "Integer": "integer", "Decimal": "decimal", "String": "string",
+	"Date": "date", "DateTime": "dateTime", "Time": "time", "Instant": "instant",
+	"Uri": "uri", "Url": "url", "Canonical": "canonical", "Code": "code", "Id": "id",
+	"PositiveInt": "positiveInt", "UnsignedInt": "unsignedInt", "Base64Binary": "base64Binary",
+	"Markdown": "markdown", "Quantity": "Quantity", "Age": "Age", "Duration": "Duration",
+	"Range": "Range", "Ratio": "Ratio", "Period": "Period", "SampledData": "SampledData",
+	"CodeableConcept": "CodeableConcept", "Coding": "Coding", "Reference": "Reference",
+	"Attachment": "Attachment", "Timing": "Timing", "Identifier": "Identifier",
+	"Annotation": "Annotation", "Address": "Address", "ContactPoint": "ContactPoint",
+	"HumanName": "HumanName", "Money": "Money",
+}
+
+// splitChoice splits a choice element name such as "valueQuantity" into its
+// base and FHIRPath type ("value", "Quantity"), or returns ok=false.
+func splitChoice(key string) (base, typ string, ok bool) {
+	for _, b := range choiceBases {
+		rest, found := strings.CutPrefix(key, b)
+		if !found || rest == "" || !unicode.IsUpper(rune(rest[0])) {
+			continue
+		}
+		if t, known := choiceTypes[rest]; known {
+			return b, t, true
+		}
+	}
+	return "", "", false
+}
+
+// FHIRPath returns the node's FHIRPath, starting from the resource type,
+// e.g. "Observation.code.coding[0].system". Choice elements are written
+// with ofType, e.g. "Observation.value.ofType(Quantity).value".
+func (n *TreeNode) FHIRPath() string {
+	root := n
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	var b strings.Builder
+	b.WriteString("%resource")
+	for _, c := range root.Children {
+		if c.Key == "resourceType" && c.Kind == 0 {
+			b.Reset()
+			b.WriteString(strings.Trim(c.Value, `"`))
+		}
+	}
+	for _, p := range n.path() {
+		if p.Index >= 0 {
+			fmt.Fprintf(&b, "[%d]", p.Index)
+		} else if base, typ, ok := splitChoice(p.Key); ok {
+			fmt.Fprintf(&b, ".%s.ofType(%s)", base, typ)
+		} else {
+			b.WriteString("." + p.Key)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package fhir
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseTreePaths(t *testing.T) {
+	raw := []byte(`{"resourceType":"Observation","status":"final",
+		"code":{"coding":[{"system":"http://loinc.org","code":"29463-7"}]},
+		"valueQuantity":{"value":70.5,"unit":"kg"},
+		"effectiveDateTime":"2026-05-01","extension":[{"url":"x","a-b":null}]}`)
+	root, err := ParseTree(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	for _, c := range root.Children {
+		keys = append(keys, c.Key)
+	}
+	if want := []string{"resourceType", "status", "code", "valueQuantity", "effectiveDateTime", "extension"}; !slices.Equal(keys, want) {
+		t.Errorf("keys = %v, want document order %v", keys, want)
+	}
+
+	find := func(path ...string) *TreeNode {
+		n := root
+		for _, p := range path {
+			var next *TreeNode
+			for _, c := range n.Children {
+				if c.Label() == p {
+					next = c
+				}
+			}
+			if next == nil {
+				t.Fatalf("no %q under %s", p, n.JSONPath())
+			}
+			n = next
+		}
+		return n
+	}
+
+	tests := []struct {
+		node           *TreeNode
+		json, fhirPath string
+		value          string
+	}{
+		{find("code", "coding", "[0]", "system"), "$.code.coding[0].system", "Observation.code.coding[0].system", `"http://loinc.org"`},
+		{find("valueQuantity", "value"), "$.valueQuantity.value", "Observation.value.ofType(Quantity).value", "70.5"},
+		{find("effectiveDateTime"), "$.effectiveDateTime", "Observation.effective.ofType(dateTime)", `"2026-05-01"`},
+		{find("extension", "[0]", "a-b"), "$.extension[0]['a-b']", "Observation.extension[0].a-b", "null"},
+		{find("status"), "$.status", "Observation.status", `"final"`},
+	}
+	for _, tt := range tests {
+		if got := tt.node.JSONPath(); got != tt.json {
+			t.Errorf("JSONPath = %q, want %q", got, tt.json)
+		}
+		if got := tt.node.FHIRPath(); got != tt.fhirPath {
+			t.Errorf("FHIRPath = %q, want %q", got, tt.fhirPath)
+		}
+		if tt.node.Value != tt.value {
+			t.Errorf("%s value = %s, want %s", tt.json, tt.node.Value, tt.value)
+		}
+	}
+}
+
+func TestSplitChoice(t *testing.T) {
+	for key, want := range map[string]string{
+		"valueCodeableConcept": "value.CodeableConcept",
+		"onsetDateTime":        "onset.dateTime",
+		"scheduledString":      "scheduled.string",
+		"birthDate":            "",
+		"valueSet":             "",
+		"value":                "",
+	} {
+		base, typ, ok := splitChoice(key)
+		got := ""
+		if ok {
+			got = base + "." + typ
+		}
+		if got != want {
+			t.Errorf("splitChoice(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package fhir
+
+import (
+	"fmt"
+	"sort"
+)
+
+// UCUMSystem is the code system of UCUM unit codes in a Quantity.
+const UCUMSystem = "http://unitsofmeasure.org"
+
+// ucumUnits is the bundled subset of UCUM codes: every unit the builders use
+// plus the common clinical units another client is likely to send.
+var ucumUnits = map[string]bool{
+	// Mass, length, volume
+	"kg": true, "g": true, "mg": true, "ug": true, "pg": true, "[lb_av]": true, "[oz_av]": true,
+	"m": true, "cm": true, "mm": true, "[in_i]": true, "[ft_i]": true,
+	"L": true, "dL": true, "mL": true, "fL": true,
+	// Time and rates
+	"a": true, "mo": true, "wk": true, "d": true, "h": true, "min": true, "s": true,
+	"/min": true, "/h": true, "{beats}/min": true, "{breaths}/min": true,
+	// Temperature, pressure, ratios, scores
+	"Cel": true, "[degF]": true, "mm[Hg]": true, "%": true, "1": true, "{score}": true, "{ratio}": true,
+	"{INR}": true, "[pH]": true, "mm/h": true,
+	"kg/m2": true, "m2": true,
+	// Concentrations
+	"mg/dL": true, "g/dL": true, "g/L": true, "mg/L": true, "ug/L": true, "ug/dL": true,
+	"ng/mL": true, "ng/dL": true, "pg/mL": true,
+	"mmol/L": true, "umol/L": true, "nmol/L": true, "pmol/L": true, "meq/L": true, "mosm/kg": true,
+	"mmol/mol": true, "mg/g": true,
+	"U/L": true, "[IU]/L": true, "m[IU]/L": true, "m[IU]/mL": true, "u[IU]/mL": true,
+	"10*3/uL": true, "10*6/uL": true, "10*9/L": true, "10*12/L": true,
+	// Flow
+	"mL/min": true, "mL/min/{1.73_m2}": true, "L/min": true,
+}
+
+// ucumMistakes maps unit strings that are often sent as UCUM codes, but are
+// not, to the code that was meant.
+var ucumMistakes = map[string]string{
+	"mmHg": "mm[Hg]", "bpm": "/min", "beats/min": "/min", "breaths/min": "/min",
+	"°C": "Cel", "°F": "[degF]", "degF": "[degF]",
+	"lb": "[lb_av]", "lbs": "[lb_av]", "in": "[in_i]", "ft": "[ft_i]",
+	"kg/m²": "kg/m2", "IU/L": "[IU]/L", "mIU/L": "m[IU]/L", "mEq/L": "meq/L",
+	"mL/min/1.73m2": "mL/min/{1.73_m2}", "days": "d", "hours": "h", "years": "a",
+}
+
+// UnitProblem is a UCUM Quantity whose code is missing or not in the bundled
+// subset.
+type UnitProblem struct {
+	// Path is where the Quantity sits, e.g. "component[0].valueQuantity".
+	Path string
+	Code string
+	// Suggest is the UCUM code that was probably meant, if known.
+	Suggest string
+}
+
+// Invalid reports whether the code is certainly wrong: missing, or a known
+// non-UCUM spelling. Other problems are codes outside the bundled subset,
+// which may still be valid UCUM.
+func (p UnitProblem) Invalid() bool {
+	return p.Code == "" || p.Suggest != ""
+}
+
+func (p UnitProblem) String() string {
+	switch {
+	case p.Code == "":
+		return fmt.Sprintf("%s: UCUM quantity has no code", p.Path)
+	case p.Suggest != "":
+		return fmt.Sprintf("%s: %q is not a UCUM code (use %q)", p.Path, p.Code, p.Suggest)
+	}
+	return fmt.Sprintf("%s: %q is not a known UCUM code", p.Path, p.Code)
+}
+
+// CheckUnits returns the problems with a resource's UCUM quantities, in path
+// order. Quantities in other code systems, or with no system, are not
+// checked.
+func CheckUnits(m map[string]any) []UnitProblem {
+	var problems []UnitProblem
+	walkQuantities(m, "", func(path string, q map[string]any) {
+		code := getString(q, "code")
+		if ucumUnits[code] {
+			return
+		}
+		problems = append(problems, UnitProblem{Path: path, Code: code, Suggest: ucumMistakes[code]})
+	})
+	return problems
+}
+
+// walkQuantities calls fn for every object under v whose system is UCUM.
+func walkQuantities(v any, path string, fn func(path string, q map[string]any)) {
+	switch t := v.(type) {
+	case map[string]any:
+		if getString(t, "system") == UCUMSystem {
+			fn(path, t)
+			return
+		}
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			walkQuantities(t[k], joinPath(path, k), fn)
+		}
+	case []any:
+		for i, child := range t {
+			walkQuantities(child, fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+	}
+}
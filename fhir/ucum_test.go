@@ -0,0 +1,89 @@
+package fhir
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestBuilderUnitsAreKnown(t *testing.T) {
+	resources := map[string]json.RawMessage{
+		"blood pressure": NewBloodPressureObservation("p1", 120, 80),
+		"weight":         NewWeightObservation("p1", 70),
+		"heart rate":     NewHeartRateObservation("p1", 70),
+		"temperature":    NewTemperatureObservation("p1", 37),
+		"spo2":           NewOxygenSaturationObservation("p1", 98),
+		"respiratory":    NewRespiratoryRateObservation("p1", 16),
+		"bmi":            NewBMIObservation("p1", 22),
+		"height":         NewHeightObservation("p1", 170),
+		"head":           NewHeadCircumferenceObservation("p1", 35),
+		"pain":           NewPainScoreObservation("p1", 3),
+		"egfr":           NewEGFRObservation("p1", 60),
+		"plan definition": NewPlanDefinition("urn:test", "test", "Test", "", []PlanAction{
+			{Title: "Follow up", DueDays: 30},
+		}),
+	}
+	for _, test := range LabTests {
+		resources[test.Text] = NewLabObservation("p1", test, 1)
+	}
+	for _, panel := range LabPanels {
+		for _, member := range panel.Members {
+			resources[panel.Text+" "+member.Text] = NewLabObservation("p1", member, 1)
+		}
+	}
+
+	for name, raw := range resources {
+		m, err := Parse(raw)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		for _, p := range CheckUnits(m) {
+			t.Errorf("%s: %s", name, p)
+		}
+	}
+}
+
+func TestCheckUnits(t *testing.T) {
+	quantity := func(system, code string) map[string]any {
+		q := map[string]any{"value": 1.0, "system": system}
+		if code != "" {
+			q["code"] = code
+		}
+		return q
+	}
+
+	tests := []struct {
+		name     string
+		resource map[string]any
+		want     []UnitProblem
+		invalid  []bool
+	}{
+		{"known code", map[string]any{"valueQuantity": quantity(UCUMSystem, "mg/dL")}, nil, nil},
+		{"misspelled code", map[string]any{"valueQuantity": quantity(UCUMSystem, "mmHg")},
+			[]UnitProblem{{Path: "valueQuantity", Code: "mmHg", Suggest: "mm[Hg]"}}, []bool{true}},
+		{"missing code", map[string]any{"valueQuantity": quantity(UCUMSystem, "")},
+			[]UnitProblem{{Path: "valueQuantity"}}, []bool{true}},
+		{"code outside the subset", map[string]any{"valueQuantity": quantity(UCUMSystem, "mg/g{creat}")},
+			[]UnitProblem{{Path: "valueQuantity", Code: "mg/g{creat}"}}, []bool{false}},
+		{"other system", map[string]any{"valueQuantity": quantity("http://example.org/units", "mmHg")}, nil, nil},
+		{"nested component", map[string]any{"component": []any{
+			map[string]any{"valueQuantity": quantity(UCUMSystem, "mm[Hg]")},
+			map[string]any{"valueQuantity": quantity(UCUMSystem, "bpm")},
+		}}, []UnitProblem{{Path: "component[1].valueQuantity", Code: "bpm", Suggest: "/min"}}, []bool{true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckUnits(tt.resource)
+			if len(got) != len(tt.want) {
+				t.Fatalf("CheckUnits = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("problem %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+				if got[i].Invalid() != tt.invalid[i] {
+					t.Errorf("problem %d Invalid() = %v, want %v", i, got[i].Invalid(), tt.invalid[i])
+				}
+			}
+		})
+	}
+}
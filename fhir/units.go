@@ -0,0 +1,133 @@
+package fhir
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Units is a choice of units for showing and entering weights and
+// temperatures. Observations always store them in UCUM kg and Cel.
+type Units string
+
+const (
+	MetricUnits Units = "metric" // kg, °C
+	USUnits     Units = "us"     // lb, °F
+)
+
+// DisplayUnits is the unit system observation values are shown in, and the
+// one a bare number is read in by ParseWeight and ParseTemperature.
+var DisplayUnits = MetricUnits
+
+const kgPerPound = 0.45359237
+
+// PoundsToKg converts a weight in pounds to kilograms, rounded to 0.1 kg.
+func PoundsToKg(lb float64) float64 {
+	return round1(lb * kgPerPound)
+}
+
+// FahrenheitToCelsius converts a temperature to °C, rounded to 0.1 °C.
+func FahrenheitToCelsius(f float64) float64 {
+	return round1((f - 32) * 5 / 9)
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+// ParseWeight reads a weight such as "70", "70 kg", or "154 lb" and returns
+// it in kilograms. A number without a unit is in DisplayUnits.
+func ParseWeight(s string) (float64, error) {
+	value, unit, err := splitQuantity(s)
+	if err != nil {
+		return 0, err
+	}
+	switch unit {
+	case "kg", "kgs":
+		return value, nil
+	case "lb", "lbs", "pound", "pounds":
+		return PoundsToKg(value), nil
+	case "":
+		if DisplayUnits == USUnits {
+			return PoundsToKg(value), nil
+		}
+		return value, nil
+	}
+	return 0, fmt.Errorf("unknown weight unit %q (use kg or lb)", unit)
+}
+
+// ParseTemperature reads a temperature such as "37", "37 C", or "98.6 °F"
+// and returns it in °C. A number without a unit is in DisplayUnits.
+func ParseTemperature(s string) (float64, error) {
+	value, unit, err := splitQuantity(s)
+	if err != nil {
+		return 0, err
+	}
+	switch strings.TrimPrefix(unit, "°") {
+	case "c", "cel":
+		return value, nil
+	case "f":
+		return FahrenheitToCelsius(value), nil
+	case "":
+		if DisplayUnits == USUnits {
+			return FahrenheitToCelsius(value), nil
+		}
+		return value, nil
+	}
+	return 0, fmt.Errorf("unknown temperature unit %q (use C or F)", unit)
+}
+
+// splitQuantity splits "98.6 °F" into 98.6 and "°f".
+func splitQuantity(s string) (float64, string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-'
+	})
+	if end < 0 {
+		end = len(s)
+	}
+	value, err := strconv.ParseFloat(s[:end], 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("must be a number")
+	}
+	return value, strings.TrimSpace(s[end:]), nil
+}
+
+// WeightUnit returns the weight unit DisplayUnits shows, for prompts.
+func WeightUnit() string {
+	if DisplayUnits == USUnits {
+		return "lb"
+	}
+	return "kg"
+}
+
+// TemperatureUnit returns the temperature unit DisplayUnits shows.
+func TemperatureUnit() string {
+	if DisplayUnits == USUnits {
+		return "°F"
+	}
+	return "°C"
+}
+
+// DisplayQuantity converts a value stored in the UCUM unit code to
+// DisplayUnits, returning it with the unit to show. Values in units other
+// than kg and Cel are returned unchanged, with unit.
+func DisplayQuantity(value float64, unit, code string) (float64, string) {
+	return displayQuantity(value, unit, code)
+}
+
+// displayQuantity converts a stored value to DisplayUnits. code is the
+// quantity's UCUM code; values in other units are returned unchanged.
+func displayQuantity(value float64, unit, code string) (float64, string) {
+	if DisplayUnits != USUnits {
+		return value, unit
+	}
+	switch code {
+	case "kg":
+		return round1(value / kgPerPound), "lb"
+	case "Cel":
+		return round1(value*9/5 + 32), "°F"
+	}
+	return value, unit
+}
@@ -0,0 +1,60 @@
+package fhir
+
+import "testing"
+
+func TestParseWeight(t *testing.T) {
+	tests := []struct {
+		in      string
+		units   Units
+		want    float64
+		wantErr bool
+	}{
+		{"70", MetricUnits, 70, false},
+		{"70 kg", MetricUnits, 70, false},
+		{" 70.5KG ", MetricUnits, 70.5, false},
+		{"154 lb", MetricUnits, 69.9, false},
+		{"154lbs", MetricUnits, 69.9, false},
+		{"154 pounds", MetricUnits, 69.9, false},
+		{"154", USUnits, 69.9, false},
+		{"70 kg", USUnits, 70, false},
+		{"70 st", MetricUnits, 0, true},
+		{"heavy", MetricUnits, 0, true},
+		{"", MetricUnits, 0, true},
+	}
+	defer func() { DisplayUnits = MetricUnits }()
+	for _, tt := range tests {
+		DisplayUnits = tt.units
+		got, err := ParseWeight(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseWeight(%q) in %s units = %g, %v; want %g, error %v", tt.in, tt.units, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseTemperature(t *testing.T) {
+	tests := []struct {
+		in      string
+		units   Units
+		want    float64
+		wantErr bool
+	}{
+		{"37", MetricUnits, 37, false},
+		{"37 C", MetricUnits, 37, false},
+		{"37.2°c", MetricUnits, 37.2, false},
+		{"37 cel", MetricUnits, 37, false},
+		{"98.6 F", MetricUnits, 37, false},
+		{"100.4 °F", MetricUnits, 38, false},
+		{"98.6", USUnits, 37, false},
+		{"37 C", USUnits, 37, false},
+		{"310 K", MetricUnits, 0, true},
+		{"warm", MetricUnits, 0, true},
+	}
+	defer func() { DisplayUnits = MetricUnits }()
+	for _, tt := range tests {
+		DisplayUnits = tt.units
+		got, err := ParseTemperature(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseTemperature(%q) in %s units = %g, %v; want %g, error %v", tt.in, tt.units, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
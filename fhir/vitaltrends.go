@@ -0,0 +1,143 @@
+package fhir
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// VitalPoint is one reading in a VitalTrend.
+type VitalPoint struct {
+	Time  time.Time
+	Value float64
+}
+
+// VitalTrend is one measurement's readings over time, oldest first.
+type VitalTrend struct {
+	Name   string
+	Unit   string
+	Points []VitalPoint
+}
+
+// mgdlPerMmolGlucose converts glucose in mmol/L to mg/dL.
+const mgdlPerMmolGlucose = 18.016
+
+// VitalTrendCodes are the LOINC codes BuildVitalTrends reads: the blood
+// pressure panel, body weight, and blood glucose in its usual codings.
+var VitalTrendCodes = []string{"85354-9", "29463-7", "2345-7", "2339-0", "1558-6", "15074-8"}
+
+// BuildVitalTrends sorts observations into systolic and diastolic blood
+// pressure, weight, and glucose series, each ordered by date. Readings
+// without a date or a number, and those entered in error, are left out.
+// Weight follows DisplayUnits; glucose is shown in mg/dL.
+func BuildVitalTrends(observations []json.RawMessage) []VitalTrend {
+	trends := []VitalTrend{
+		{Name: "Systolic BP", Unit: "mmHg"},
+		{Name: "Diastolic BP", Unit: "mmHg"},
+		{Name: "Weight", Unit: WeightUnit()},
+		{Name: "Glucose", Unit: "mg/dL"},
+	}
+	add := func(i int, t time.Time, v float64) {
+		trends[i].Points = append(trends[i].Points, VitalPoint{Time: t, Value: v})
+	}
+	for _, raw := range observations {
+		m, err := Parse(raw)
+		if err != nil || getString(m, "status") == "entered-in-error" {
+			continue
+		}
+		t, ok := effectiveTime(m)
+		if !ok {
+			continue
+		}
+		vq := getMap(m, "valueQuantity")
+		switch observationLoincCode(m) {
+		case "85354-9":
+			if v, ok := bloodPressureComponent(m, systolicCode, 0); ok {
+				add(0, t, v)
+			}
+			if v, ok := bloodPressureComponent(m, diastolicCode, 1); ok {
+				add(1, t, v)
+			}
+		case "29463-7":
+			if v, ok := numberValue(vq, "value"); ok {
+				v, _ = displayQuantity(v, getString(vq, "unit"), getString(vq, "code"))
+				add(2, t, v)
+			}
+		case "2345-7", "2339-0", "1558-6":
+			if v, ok := numberValue(vq, "value"); ok {
+				add(3, t, v)
+			}
+		case "15074-8":
+			if v, ok := numberValue(vq, "value"); ok {
+				add(3, t, round1(v*mgdlPerMmolGlucose))
+			}
+		}
+	}
+	for _, tr := range trends {
+		sort.SliceStable(tr.Points, func(i, j int) bool { return tr.Points[i].Time.Before(tr.Points[j].Time) })
+	}
+	return trends
+}
+
+// Values returns the trend's readings, oldest first.
+func (t VitalTrend) Values() []float64 {
+	values := make([]float64, len(t.Points))
+	for i, p := range t.Points {
+		values[i] = p.Value
+	}
+	return values
+}
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline draws values as a row of block characters scaled between their
+// minimum and maximum. A flat series is drawn at mid height.
+func Sparkline(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		i := len(sparkBlocks) / 2
+		if hi > lo {
+			i = int(math.Round((v - lo) / (hi - lo) * float64(len(sparkBlocks)-1)))
+		}
+		b.WriteRune(sparkBlocks[i])
+	}
+	return b.String()
+}
+
+// sparkWidth is how many of the latest readings a sparkline shows.
+const sparkWidth = 40
+
+// PrintVitalTrends prints a sparkline for each trend with its lowest,
+// highest, and latest reading. Trends without readings say so.
+func PrintVitalTrends(trends []VitalTrend) {
+	fmt.Println(headerStyle.Render("Vitals Trends"))
+	for _, t := range trends {
+		if len(t.Points) == 0 {
+			fmt.Printf("  %s %s\n", labelStyle.Render(t.Name), "no readings")
+			continue
+		}
+		values := t.Values()
+		shown := values[max(len(values)-sparkWidth, 0):]
+		lo, hi := values[0], values[0]
+		for _, v := range values {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+		last := t.Points[len(t.Points)-1]
+		// Pad by runes: the blocks are wider in bytes than on screen.
+		spark := Sparkline(shown) + strings.Repeat(" ", sparkWidth-len(shown))
+		fmt.Printf("  %s %s  min %s  max %s  latest %s %s (%s, %s)\n",
+			labelStyle.Render(t.Name), spark,
+			formatNumber(lo), formatNumber(hi), formatNumber(last.Value), t.Unit,
+			last.Time.Local().Format("2006-01-02"), plural(len(values), "reading", "readings"))
+	}
+}
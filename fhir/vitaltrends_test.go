@@ -0,0 +1,63 @@
+package fhir
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		values []float64
+		want   string
+	}{
+		{nil, ""},
+		{[]float64{5}, "▅"},
+		{[]float64{3, 3, 3}, "▅▅▅"},
+		{[]float64{0, 7}, "▁█"},
+		{[]float64{0, 1, 2, 3, 4, 5, 6, 7}, "▁▂▃▄▅▆▇█"},
+		{[]float64{120, 140, 130}, "▁█▅"},
+	}
+	for _, tt := range tests {
+		if got := Sparkline(tt.values); got != tt.want {
+			t.Errorf("Sparkline(%v) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestBuildVitalTrends(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 9, 0, 0, 0, time.UTC) }
+	si, _ := LookupLOINC("15074-8")
+	observations := []json.RawMessage{
+		WithEffective(NewBloodPressureObservation("p1", 150, 95), day(3)),
+		WithEffective(NewBloodPressureObservation("p1", 130, 85), day(1)),
+		WithEffective(NewWeightObservation("p1", 80), day(2)),
+		WithEffective(NewBloodGlucoseObservation("p1", 110), day(4)),
+		WithEffective(NewLabObservation("p1", si, 5), day(1)),
+		WithEffective(NewHeartRateObservation("p1", 70), day(1)),
+		NewWeightObservation("p1", 81), // no date
+		json.RawMessage(`{"resourceType":"Observation","status":"entered-in-error","code":{"coding":[{"code":"29463-7"}]},"effectiveDateTime":"2026-03-05","valueQuantity":{"value":999,"unit":"kg","code":"kg"}}`),
+	}
+	// NewWeightObservation sets a date; drop it to check undated readings.
+	var undated map[string]any
+	_ = json.Unmarshal(observations[6], &undated)
+	delete(undated, "effectiveDateTime")
+	observations[6], _ = json.Marshal(undated)
+
+	trends := BuildVitalTrends(observations)
+	want := map[string][]float64{
+		"Systolic BP":  {130, 150},
+		"Diastolic BP": {85, 95},
+		"Weight":       {80},
+		"Glucose":      {90.1, 110},
+	}
+	if len(trends) != len(want) {
+		t.Fatalf("got %d trends, want %d", len(trends), len(want))
+	}
+	for _, tr := range trends {
+		if got := tr.Values(); !slices.Equal(got, want[tr.Name]) {
+			t.Errorf("%s = %v, want %v", tr.Name, got, want[tr.Name])
+		}
+	}
+}
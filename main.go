@@ -1,20 +1,101 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/phenoml/phenostore-example-go/app"
 )
 
 func main() {
+	daemon := flag.Bool("daemon", false, "run headless, periodically reporting overdue care plan activities")
+	interval := flag.Duration("interval", 15*time.Minute, "how often the daemon evaluates care gaps")
+	report := flag.String("report", "care-gaps.json", "file the daemon writes each report to (empty to disable)")
+	webhook := flag.String("webhook", "", "URL the daemon POSTs each report to")
+	deidentify := flag.Bool("deidentify", false, "pseudonymize patients and shift dates in daemon reports")
+	flag.Parse()
+
 	a := &app.App{}
 	if err := a.Initialize(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
 		os.Exit(1)
 	}
 
+	if flag.Arg(0) == "serve" {
+		serveFlags := flag.NewFlagSet("serve", flag.ExitOnError)
+		addr := serveFlags.String("addr", "127.0.0.1:8080", "address the JSON API listens on")
+		deidentify := serveFlags.Bool("deidentify", false, "serve pseudonymized patients with shifted dates")
+		serveFlags.Parse(flag.Args()[1:])
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if err := a.Serve(ctx, app.ServeConfig{Addr: *addr, Deidentify: *deidentify}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "subscribe" {
+		subFlags := flag.NewFlagSet("subscribe", flag.ExitOnError)
+		addr := subFlags.String("addr", "127.0.0.1:9090", "address the notification listener binds to")
+		endpoint := subFlags.String("endpoint", "", "URL PhenoStore POSTs notifications to (default http://<addr>/notify)")
+		criteria := subFlags.String("criteria", "CarePlan", "search criteria to subscribe to, e.g. CarePlan?status=active")
+		subFlags.Parse(flag.Args()[1:])
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		err := a.Subscribe(ctx, app.SubscriptionConfig{
+			Addr:     *addr,
+			Endpoint: *endpoint,
+			Criteria: *criteria,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "report" {
+		if err := runReportCommand(a, flag.Args()[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.Arg(0) == "digest" {
+		if err := runDigestCommand(a, flag.Args()[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *daemon {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		err := a.RunDaemon(ctx, app.DaemonConfig{
+			Interval:   *interval,
+			ReportPath: *report,
+			WebhookURL: *webhook,
+			Deidentify: *deidentify,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	banner := lipgloss.NewStyle().
 		Bold(true).
 		Foreground(lipgloss.Color("12")).
@@ -25,3 +106,71 @@ func main() {
 
 	a.MainMenu()
 }
+
+// runReportCommand handles "report list" and "report run <name> [flags]".
+func runReportCommand(a *app.App, args []string) error {
+	if len(args) > 0 && args[0] == "list" {
+		names, err := app.ReportNames()
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return nil
+	}
+	if len(args) < 2 || args[0] != "run" || strings.HasPrefix(args[1], "-") {
+		return fmt.Errorf("usage: report list | report run <name> [--out dir] [--format md,csv] [--cohort name] [--deidentify]")
+	}
+
+	reportFlags := flag.NewFlagSet("report run", flag.ExitOnError)
+	out := reportFlags.String("out", ".", "directory the report files are written to")
+	format := reportFlags.String("format", "md,csv", "comma-separated output formats: md, csv")
+	cohort := reportFlags.String("cohort", "", "limit the report to a cohort or panel (Group name or ID)")
+	deidentify := reportFlags.Bool("deidentify", false, "pseudonymize patients and shift dates")
+	reportFlags.Parse(args[2:])
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	paths, err := a.RunReport(ctx, app.ReportRunConfig{
+		Name:       args[1],
+		OutDir:     *out,
+		Formats:    strings.Split(*format, ","),
+		Cohort:     *cohort,
+		Deidentify: *deidentify,
+	})
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		fmt.Println(path)
+	}
+	return nil
+}
+
+// runDigestCommand handles "digest [flags]".
+func runDigestCommand(a *app.App, args []string) error {
+	digestFlags := flag.NewFlagSet("digest", flag.ExitOnError)
+	out := digestFlags.String("out", ".", "directory the digest is written to")
+	week := digestFlags.String("week", "", "any date (YYYY-MM-DD) in the week to digest (default last week)")
+	deidentify := digestFlags.Bool("deidentify", false, "pseudonymize patients")
+	digestFlags.Parse(args)
+
+	when := time.Now().AddDate(0, 0, -7)
+	if *week != "" {
+		t, err := time.ParseInLocation("2006-01-02", *week, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid --week %q: use YYYY-MM-DD", *week)
+		}
+		when = t
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	path, err := a.WriteDigest(ctx, app.DigestConfig{OutDir: *out, Week: when, Deidentify: *deidentify})
+	if err != nil {
+		return err
+	}
+	fmt.Println(path)
+	return nil
+}